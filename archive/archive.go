@@ -0,0 +1,378 @@
+// Package archive packs files into an encrypted, and optionally signed,
+// OpenPGP message containing a tar archive, and lists or extracts them, as
+// gpg-zip does. The archives can be decrypted with any OpenPGP
+// implementation, and unpacked with tar.
+package archive
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// literalFilename is the file name of the literal data of the archives.
+const literalFilename = "archive.tar"
+
+// File describes a file or a directory of an archive.
+type File struct {
+	// Path is the slash-separated relative path of the file in the archive.
+	// The paths of directories end with a slash.
+	Path string
+	// Mode contains the permission bits of the file, and os.ModeDir for
+	// directories.
+	Mode os.FileMode
+	// Size is the size of the file in bytes, 0 for directories.
+	Size int64
+	// ModTime is the modification time of the file, as a unix timestamp.
+	ModTime int64
+}
+
+// IsDir returns true if the file is a directory.
+func (file *File) IsDir() bool {
+	return file.Mode.IsDir()
+}
+
+// Writer writes files to an encrypted archive.
+type Writer struct {
+	encryptWriter io.WriteCloser
+	tarWriter     *tar.Writer
+}
+
+// NewWriter returns a Writer encrypting an archive to encryptionKeyRing,
+// and writing it to w. If signKeyRing is not nil, the archive is signed.
+// Close must be called once all files are added.
+func NewWriter(w io.Writer, encryptionKeyRing, signKeyRing *crypto.KeyRing) (*Writer, error) {
+	encryptWriter, err := encryptionKeyRing.EncryptStream(
+		w,
+		crypto.NewPlainMessageMetadata(true, literalFilename, crypto.GetUnixTime()),
+		signKeyRing,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt archive")
+	}
+	return &Writer{
+		encryptWriter: encryptWriter,
+		tarWriter:     tar.NewWriter(encryptWriter),
+	}, nil
+}
+
+// AddFile adds a file to the archive, with the contents read from
+// contents, which must be exactly file.Size bytes long.
+// For directories, contents is ignored and can be nil.
+func (w *Writer) AddFile(file *File, contents io.Reader) error {
+	name, err := cleanPath(file.Path)
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(file.Mode.Perm()),
+		ModTime: time.Unix(file.ModTime, 0),
+		Format:  tar.FormatPAX,
+	}
+	if file.IsDir() {
+		header.Typeflag = tar.TypeDir
+		header.Name += "/"
+	} else {
+		header.Typeflag = tar.TypeReg
+		header.Size = file.Size
+	}
+	if err := w.tarWriter.WriteHeader(header); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to write archive")
+	}
+	if file.IsDir() {
+		return nil
+	}
+	written, err := io.Copy(w.tarWriter, contents)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to write archive")
+	}
+	if written != file.Size {
+		return errors.New("gopenpgp: the file contents are shorter than its size")
+	}
+	return nil
+}
+
+// AddPath adds the file or directory at localPath to the archive, as
+// archivePath. Directories are added recursively; only regular files and
+// directories are supported.
+func (w *Writer) AddPath(localPath, archivePath string) error {
+	return filepath.Walk(localPath, func(walkedPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(localPath, walkedPath)
+		if err != nil {
+			return err
+		}
+		filePath := path.Join(archivePath, filepath.ToSlash(relativePath))
+		if filePath == "." {
+			// The root of a directory added without archive path
+			return nil
+		}
+		file := &File{
+			Path:    filePath,
+			Mode:    info.Mode() & (os.ModeDir | os.ModePerm),
+			ModTime: info.ModTime().Unix(),
+		}
+		if info.IsDir() {
+			return w.AddFile(file, nil)
+		}
+		if !info.Mode().IsRegular() {
+			return errors.New("gopenpgp: unsupported file type in archive: " + walkedPath)
+		}
+		file.Size = info.Size()
+		contents, err := os.Open(walkedPath) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		defer contents.Close() //nolint:errcheck
+		return w.AddFile(file, contents)
+	})
+}
+
+// Close finishes the archive, and the encrypted message.
+func (w *Writer) Close() error {
+	if err := w.tarWriter.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to write archive")
+	}
+	if err := w.encryptWriter.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to encrypt archive")
+	}
+	return nil
+}
+
+// Reader reads the files of an encrypted archive, in order.
+type Reader struct {
+	plainMessage *crypto.PlainMessageReader
+	tarReader    *tar.Reader
+	done         bool
+}
+
+// NewReader returns a Reader decrypting the archive read from r with
+// decryptionKeyRing. If verifyKeyRing is not nil, the signature of the
+// archive is verified at verifyTime by VerifySignature, once all the files
+// have been read.
+func NewReader(r io.Reader, decryptionKeyRing, verifyKeyRing *crypto.KeyRing, verifyTime int64) (*Reader, error) {
+	plainMessage, err := decryptionKeyRing.DecryptStream(r, verifyKeyRing, verifyTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to decrypt archive")
+	}
+	return &Reader{
+		plainMessage: plainMessage,
+		tarReader:    tar.NewReader(plainMessage),
+	}, nil
+}
+
+// Next advances to the next file of the archive, whose contents are then
+// read with Read. It returns io.EOF at the end of the archive.
+// Files other than regular files and directories are skipped.
+func (r *Reader) Next() (*File, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	for {
+		header, err := r.tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			// The signature follows the padding of the archive
+			if _, err := io.Copy(ioutil.Discard, r.plainMessage); err != nil {
+				return nil, errors.Wrap(err, "gopenpgp: unable to read archive")
+			}
+			r.done = true
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to read archive")
+		}
+		name, err := cleanPath(header.Name)
+		if err != nil {
+			return nil, err
+		}
+		file := &File{
+			Path:    name,
+			Mode:    os.FileMode(header.Mode).Perm(),
+			ModTime: header.ModTime.Unix(),
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			file.Path += "/"
+			file.Mode |= os.ModeDir
+		case tar.TypeReg:
+			file.Size = header.Size
+		default:
+			continue
+		}
+		return file, nil
+	}
+}
+
+// Read reads the contents of the current file.
+func (r *Reader) Read(b []byte) (int, error) {
+	return r.tarReader.Read(b)
+}
+
+// VerifySignature verifies the signature of the archive, once Next has
+// returned io.EOF. Until then, the files read are not authenticated by the
+// signature.
+func (r *Reader) VerifySignature() error {
+	if !r.done {
+		return errors.New("gopenpgp: the archive must be read entirely to verify its signature")
+	}
+	return r.plainMessage.VerifySignature()
+}
+
+// List returns the files of the archive read from r. If verifyKeyRing is
+// not nil, the signature of the archive is verified at verifyTime.
+func List(r io.Reader, decryptionKeyRing, verifyKeyRing *crypto.KeyRing, verifyTime int64) ([]*File, error) {
+	archive, err := NewReader(r, decryptionKeyRing, verifyKeyRing, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	for {
+		file, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if verifyKeyRing != nil {
+		if err := archive.VerifySignature(); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Extract extracts the files of the archive read from r to the directory
+// dir, and returns them. If filter is not nil, only the files for which it
+// returns true are extracted. If verifyKeyRing is not nil, the signature of
+// the archive is verified at verifyTime, once the files are extracted: if
+// the verification fails, the extracted files are removed.
+// Files are never written outside of dir.
+func Extract(
+	r io.Reader,
+	decryptionKeyRing, verifyKeyRing *crypto.KeyRing,
+	verifyTime int64,
+	dir string,
+	filter func(*File) bool,
+) ([]*File, error) {
+	archive, err := NewReader(r, decryptionKeyRing, verifyKeyRing, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	var extracted []string
+	removeExtracted := func() {
+		for i := len(extracted) - 1; i >= 0; i-- {
+			_ = os.Remove(extracted[i])
+		}
+	}
+	for {
+		file, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			removeExtracted()
+			return nil, err
+		}
+		if filter != nil && !filter(file) {
+			continue
+		}
+		localPath := filepath.Join(dir, filepath.FromSlash(file.Path))
+		created, err := extractFile(localPath, file, archive)
+		extracted = append(extracted, created...)
+		if err != nil {
+			removeExtracted()
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if verifyKeyRing != nil {
+		if err := archive.VerifySignature(); err != nil {
+			removeExtracted()
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// cleanPath returns the cleaned path of a file of an archive, without
+// trailing slash, or an error if it is absolute or outside of the archive.
+func cleanPath(name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if cleaned == "." || cleaned == ".." || path.IsAbs(cleaned) || strings.HasPrefix(cleaned, "../") {
+		return "", errors.New("gopenpgp: invalid path in archive: " + name)
+	}
+	return cleaned, nil
+}
+
+// extractFile writes a file to localPath, creating its parent directories,
+// and returns the paths created, in order.
+// The permissions of a directory are only set if it is created by this
+// call: existing directories are left as is, as the archive is not verified
+// yet.
+func extractFile(localPath string, file *File, contents io.Reader) ([]string, error) {
+	created, err := makeDirectories(filepath.Dir(localPath))
+	if err != nil {
+		return created, err
+	}
+	if file.IsDir() {
+		dirs, err := makeDirectories(localPath)
+		created = append(created, dirs...)
+		if err == nil && len(dirs) > 0 {
+			err = os.Chmod(localPath, file.Mode.Perm())
+		}
+		return created, err
+	}
+
+	output, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, file.Mode.Perm()) //nolint:gosec
+	if err != nil {
+		return created, err
+	}
+	created = append(created, localPath)
+	_, err = io.Copy(output, contents)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return created, errors.Wrap(err, "gopenpgp: unable to extract file")
+	}
+	modTime := time.Unix(file.ModTime, 0)
+	return created, os.Chtimes(localPath, modTime, modTime)
+}
+
+// makeDirectories creates the directory and its missing parents, and
+// returns the directories created, parents first.
+func makeDirectories(dir string) ([]string, error) {
+	var missing []string
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(current); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		missing = append([]string{current}, missing...)
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+	for i, missingDir := range missing {
+		if err := os.Mkdir(missingDir, 0700); err != nil {
+			return missing[:i], err
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyRing(t *testing.T) *crypto.KeyRing {
+	key, err := crypto.GenerateKey("archive", "archive@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	return keyRing
+}
+
+func TestArchive(t *testing.T) {
+	keyRing := newTestKeyRing(t)
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "docs"), 0700); err != nil {
+		t.Fatal("Expected no error while creating directory, got:", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(source, "docs", "readme.md"), []byte("# Project\n"), 0600); err != nil {
+		t.Fatal("Expected no error while writing file, got:", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(source, "main.go"), []byte("package main\n"), 0640); err != nil {
+		t.Fatal("Expected no error while writing file, got:", err)
+	}
+
+	var encrypted bytes.Buffer
+	writer, err := NewWriter(&encrypted, keyRing, keyRing)
+	if err != nil {
+		t.Fatal("Expected no error while creating archive, got:", err)
+	}
+	if err = writer.AddPath(source, "project"); err != nil {
+		t.Fatal("Expected no error while adding directory, got:", err)
+	}
+	notes := "in-memory notes"
+	notesFile := &File{Path: "notes.txt", Mode: 0600, Size: int64(len(notes)), ModTime: 1700000000}
+	if err = writer.AddFile(notesFile, strings.NewReader(notes)); err != nil {
+		t.Fatal("Expected no error while adding file, got:", err)
+	}
+	assert.Error(t, writer.AddFile(&File{Path: "../escape", Mode: 0600}, strings.NewReader("")))
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing archive, got:", err)
+	}
+
+	files, err := List(bytes.NewReader(encrypted.Bytes()), keyRing, keyRing, crypto.GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while listing archive, got:", err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Exactly(t, []string{"project/", "project/docs/", "project/docs/readme.md", "project/main.go", "notes.txt"}, paths)
+	assert.Exactly(t, &File{Path: "project/main.go", Mode: 0640, Size: 13, ModTime: files[3].ModTime}, files[3])
+	assert.Exactly(t, notesFile, files[4])
+
+	destination := t.TempDir()
+	extracted, err := Extract(
+		bytes.NewReader(encrypted.Bytes()),
+		keyRing, keyRing, crypto.GetUnixTime(),
+		destination,
+		func(file *File) bool { return strings.HasPrefix(file.Path, "project/docs/") },
+	)
+	if err != nil {
+		t.Fatal("Expected no error while extracting archive, got:", err)
+	}
+	assert.Exactly(t, 2, len(extracted))
+	readme, err := ioutil.ReadFile(filepath.Join(destination, "project", "docs", "readme.md"))
+	if err != nil {
+		t.Fatal("Expected no error while reading extracted file, got:", err)
+	}
+	assert.Exactly(t, "# Project\n", string(readme))
+	_, err = os.Stat(filepath.Join(destination, "notes.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Extracted files are removed if the signature is not verified
+	otherDestination := t.TempDir()
+	_, err = Extract(bytes.NewReader(encrypted.Bytes()), keyRing, newTestKeyRing(t), crypto.GetUnixTime(), otherDestination, nil)
+	assert.Error(t, err)
+	entries, err := ioutil.ReadDir(otherDestination)
+	if err != nil {
+		t.Fatal("Expected no error while reading directory, got:", err)
+	}
+	assert.Exactly(t, 0, len(entries))
+}
+
+func TestExtractKeepsExistingDirectories(t *testing.T) {
+	keyRing := newTestKeyRing(t)
+	var encrypted bytes.Buffer
+	writer, err := NewWriter(&encrypted, keyRing, keyRing)
+	if err != nil {
+		t.Fatal("Expected no error while creating archive, got:", err)
+	}
+	if err = writer.AddFile(&File{Path: "shared", Mode: os.ModeDir | 0777}, nil); err != nil {
+		t.Fatal("Expected no error while adding directory, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing archive, got:", err)
+	}
+
+	destination := t.TempDir()
+	shared := filepath.Join(destination, "shared")
+	if err := os.Mkdir(shared, 0700); err != nil {
+		t.Fatal("Expected no error while creating directory, got:", err)
+	}
+	_, err = Extract(bytes.NewReader(encrypted.Bytes()), keyRing, newTestKeyRing(t), crypto.GetUnixTime(), destination, nil)
+	assert.Error(t, err)
+	info, err := os.Stat(shared)
+	if err != nil {
+		t.Fatal("Expected no error while reading directory, got:", err)
+	}
+	assert.Exactly(t, os.FileMode(0700), info.Mode().Perm())
+}
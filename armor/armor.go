@@ -6,6 +6,8 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"sort"
+	"strings"
 
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
@@ -13,6 +15,43 @@ import (
 	"github.com/pkg/errors"
 )
 
+// armorLineLength is the length of the base64 lines written by the armor encoder.
+const armorLineLength = 64
+
+// ErrLimitExceeded is returned when an armored input exceeds the Limits it is
+// unarmored with.
+var ErrLimitExceeded = internal.ErrArmorLimitExceeded
+
+// ErrInvalidArmor is returned, wrapped, when an input is not a valid armored
+// block, e.g. because the armor headers or the checksum are malformed.
+var ErrInvalidArmor = internal.ErrArmorInvalid
+
+// TypeError is returned, wrapped, when an armored input is not of the
+// expected armor type, e.g. a private key is given where a message is
+// expected. It can be extracted with errors.As.
+type TypeError = internal.ArmorTypeError
+
+// Limits bounds the resources used to unarmor untrusted input, so that a huge
+// armored blob can't exhaust the memory. A limit of 0 disables the check.
+type Limits struct {
+	// MaxInputSize is the maximum size in bytes of the armored input.
+	MaxInputSize int64
+	// MaxLineLength is the maximum length in bytes of a line of the armored
+	// input, including the armor headers.
+	MaxLineLength int64
+	// MaxDecodedSize is the maximum size in bytes of the unarmored data.
+	MaxDecodedSize int64
+}
+
+// NewLimits creates limits for unarmoring untrusted input.
+func NewLimits(maxInputSize, maxLineLength, maxDecodedSize int64) *Limits {
+	return &Limits{
+		MaxInputSize:   maxInputSize,
+		MaxLineLength:  maxLineLength,
+		MaxDecodedSize: maxDecodedSize,
+	}
+}
+
 // ArmorKey armors input as a public key.
 func ArmorKey(input []byte) (string, error) {
 	return ArmorWithType(input, constants.PublicKeyHeader)
@@ -26,7 +65,7 @@ func ArmorWithTypeBuffered(w io.Writer, armorType string) (io.WriteCloser, error
 
 // ArmorWithType armors input with the given armorType.
 func ArmorWithType(input []byte, armorType string) (string, error) {
-	return armorWithTypeAndHeaders(input, armorType, internal.ArmorHeaders)
+	return armorWithTypeAndHeaders(input, armorType, getDefaultHeaders())
 }
 
 // ArmorWithTypeAndCustomHeaders armors input with the given armorType and
@@ -42,11 +81,113 @@ func ArmorWithTypeAndCustomHeaders(input []byte, armorType, version, comment str
 	return armorWithTypeAndHeaders(input, armorType, headers)
 }
 
+// ArmoredSize returns the exact length of the output of ArmorWithType for an
+// input of binarySize bytes, without having to armor the data.
+func ArmoredSize(binarySize int64, armorType string) int64 {
+	return armoredSize(binarySize, armorType, getDefaultHeaders())
+}
+
+// ArmoredSizeWithCustomHeaders returns the exact length of the output of
+// ArmorWithTypeAndCustomHeaders for an input of binarySize bytes.
+// With an empty version and comment, it is also the length of the output
+// written by ArmorWithTypeBuffered.
+func ArmoredSizeWithCustomHeaders(binarySize int64, armorType, version, comment string) int64 {
+	headers := make(map[string]string)
+	if version != "" {
+		headers["Version"] = version
+	}
+	if comment != "" {
+		headers["Comment"] = comment
+	}
+	return armoredSize(binarySize, armorType, headers)
+}
+
 // Unarmor unarmors an armored input into a byte array.
 func Unarmor(input string) ([]byte, error) {
 	b, err := internal.Unarmor(input)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopengp: unable to unarmor")
+		return nil, err
+	}
+	return ioutil.ReadAll(b.Body)
+}
+
+// UnarmorWithType unarmors an armored input into a byte array, and fails with
+// a TypeError if the input is not armored with the given armorType, e.g.
+// constants.PGPMessageHeader.
+func UnarmorWithType(input string, armorType string) ([]byte, error) {
+	b, err := internal.Unarmor(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := internal.CheckArmorType(b.Type, armorType); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to unarmor")
+	}
+	return ioutil.ReadAll(b.Body)
+}
+
+// UnarmorLenient unarmors an armored input into a byte array like Unarmor,
+// but recovers from the alterations of armored data copy-pasted through chat
+// applications or mail clients instead of failing: a missing or incorrect
+// checksum, a missing end line, a missing blank line after the headers,
+// whitespace around or inside the lines and mixed line endings. The
+// alterations found are returned as warnings, constants.ArmorWarning*.
+// As the checksum isn't enforced, the data should be authenticated by other
+// means, e.g. a signature or the integrity protection of an encrypted message.
+func UnarmorLenient(input string) (data []byte, warnings []string, err error) {
+	b, warnings, err := internal.UnarmorLenient(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = ioutil.ReadAll(b.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, warnings, nil
+}
+
+// GetArmorType returns the armor type of the first armored block of the
+// input, including constants.PGPSignedMessageHeader for cleartext signed
+// messages, or an error wrapping ErrInvalidArmor if there is none.
+func GetArmorType(input string) (string, error) {
+	armorType := internal.GetArmorType(input)
+	if armorType == "" {
+		return "", errors.Wrap(ErrInvalidArmor, "gopenpgp: no armored block found")
+	}
+	return armorType, nil
+}
+
+// IsValidArmorType returns true if armorType is one of the armor types of the
+// constants package: constants.PublicKeyHeader, constants.PrivateKeyHeader,
+// constants.PGPMessageHeader, constants.PGPSignatureHeader or
+// constants.PGPSignedMessageHeader.
+func IsValidArmorType(armorType string) bool {
+	return internal.CheckArmorType(armorType, internal.ArmorTypes...) == nil
+}
+
+// UnarmorWithLimits unarmors an armored input into a byte array, and fails
+// with ErrLimitExceeded if the input exceeds the given limits.
+func UnarmorWithLimits(input string, limits *Limits) ([]byte, error) {
+	if limits == nil {
+		return Unarmor(input)
+	}
+	if limits.MaxInputSize > 0 && int64(len(input)) > limits.MaxInputSize {
+		return nil, errors.Wrap(ErrLimitExceeded, "gopenpgp: armored input too large")
+	}
+	return UnarmorReaderWithLimits(strings.NewReader(input), limits)
+}
+
+// UnarmorReaderWithLimits reads and unarmors the armored input from reader
+// into a byte array, and fails with ErrLimitExceeded as soon as the input
+// exceeds the given limits, without reading the rest of it.
+// As the input is buffered, a few bytes past the end of the armored block
+// may be read from reader and count towards MaxInputSize.
+func UnarmorReaderWithLimits(reader io.Reader, limits *Limits) ([]byte, error) {
+	if limits == nil {
+		limits = &Limits{}
+	}
+	b, err := internal.UnarmorWithLimits(reader, limits.MaxInputSize, limits.MaxLineLength, limits.MaxDecodedSize)
+	if err != nil {
+		return nil, err
 	}
 	return ioutil.ReadAll(b.Body)
 }
@@ -54,7 +195,19 @@ func Unarmor(input string) ([]byte, error) {
 func armorWithTypeAndHeaders(input []byte, armorType string, headers map[string]string) (string, error) {
 	var b bytes.Buffer
 
-	w, err := armor.Encode(&b, armorType, headers)
+	// go-crypto writes the headers in the random order of the map: they
+	// are written sorted, after the begin line.
+	beginLine := "-----BEGIN " + armorType + "-----\n"
+	b.WriteString(beginLine)
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(name + ": " + headers[name] + "\n")
+	}
+	w, err := armor.Encode(&skipWriter{writer: &b, skip: len(beginLine)}, armorType, nil)
 
 	if err != nil {
 		return "", errors.Wrap(err, "gopengp: unable to encode armoring")
@@ -67,3 +220,42 @@ func armorWithTypeAndHeaders(input []byte, armorType string, headers map[string]
 	}
 	return b.String(), nil
 }
+
+// armoredSize mirrors the layout of the armor encoder: the BEGIN line, one line
+// per header, an empty line, the base64 body in lines of 64 characters, the
+// checksum line and the END line (without trailing newline).
+func armoredSize(binarySize int64, armorType string, headers map[string]string) int64 {
+	size := int64(len("-----BEGIN ") + len(armorType) + len("-----\n"))
+	for k, v := range headers {
+		size += int64(len(k) + len(": ") + len(v) + len("\n"))
+	}
+	size += int64(len("\n"))
+
+	encodedSize := 4 * ((binarySize + 2) / 3)
+	size += encodedSize
+	if encodedSize > 0 {
+		size += (encodedSize - 1) / armorLineLength
+	}
+
+	size += int64(len("\n=") + 4 + len("\n"))
+	size += int64(len("-----END ") + len(armorType) + len("-----"))
+	return size
+}
+
+// skipWriter discards the first skip bytes written to it.
+type skipWriter struct {
+	writer io.Writer
+	skip   int
+}
+
+func (w *skipWriter) Write(b []byte) (int, error) {
+	skipped := len(b)
+	if skipped > w.skip {
+		skipped = w.skip
+	}
+	w.skip -= skipped
+	if _, err := w.writer.Write(b[skipped:]); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,36 @@
+package armor
+
+import (
+	"sync/atomic"
+
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+)
+
+// deterministic is set to 1 by SetDeterministic.
+var deterministic int32
+
+// SetDeterministic sets whether ArmorWithType, and the functions of the
+// crypto package armoring keys, messages and signatures, omit the default
+// Version and Comment headers, so that identical inputs are armored
+// byte-for-byte identically across versions of the library, e.g. for
+// content-addressed storage or reproducible builds.
+// The armored output always has LF line endings, a checksum, and its
+// headers sorted by name; it is only identical if the binary input is, and
+// encrypting or signing with v6 keys is randomized.
+func SetDeterministic(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&deterministic, value)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getDefaultHeaders returns the headers written by ArmorWithType.
+func getDefaultHeaders() map[string]string {
+	if atomic.LoadInt32(&deterministic) == 1 {
+		return nil
+	}
+	return internal.ArmorHeaders
+}
@@ -0,0 +1,78 @@
+package armor
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/pkg/errors"
+)
+
+// ArmorWithTypeFlushing returns a io.WriteCloser which, when written to,
+// writes armored data to w with the given armorType, like
+// ArmorWithTypeBuffered, and flushes w every flushLines complete armor lines,
+// and on Close, so that long-lived streaming connections, e.g. SMTP DATA or
+// chunked HTTP responses, see steady output.
+// w is flushed if it has a Flush method, as bufio.Writer and
+// http.ResponseWriter do, else the lines are only written to w.
+// If flushLines is 0 or less, w is flushed after every line.
+func ArmorWithTypeFlushing(w io.Writer, armorType string, flushLines int) (io.WriteCloser, error) {
+	if flushLines <= 0 {
+		flushLines = 1
+	}
+	flusher := &lineFlusher{writer: w, flushLines: flushLines}
+	encoder, err := armor.Encode(flusher, armorType, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to encode armoring")
+	}
+	return &flushingArmorWriter{encoder: encoder, flusher: flusher}, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// flushingArmorWriter flushes the armored output once the armor is closed.
+type flushingArmorWriter struct {
+	encoder io.WriteCloser
+	flusher *lineFlusher
+}
+
+func (w *flushingArmorWriter) Write(b []byte) (int, error) {
+	return w.encoder.Write(b)
+}
+
+func (w *flushingArmorWriter) Close() error {
+	if err := w.encoder.Close(); err != nil {
+		return err
+	}
+	return w.flusher.flush()
+}
+
+// lineFlusher writes to writer, and flushes it every flushLines lines.
+type lineFlusher struct {
+	writer     io.Writer
+	flushLines int
+	lines      int
+}
+
+func (w *lineFlusher) Write(b []byte) (n int, err error) {
+	n, err = w.writer.Write(b)
+	if err != nil {
+		return n, err
+	}
+	w.lines += bytes.Count(b[:n], []byte{'\n'})
+	if w.lines >= w.flushLines {
+		w.lines = 0
+		err = w.flush()
+	}
+	return n, err
+}
+
+func (w *lineFlusher) flush() error {
+	switch flusher := w.writer.(type) {
+	case interface{ Flush() error }:
+		return flusher.Flush()
+	case interface{ Flush() }:
+		flusher.Flush()
+	}
+	return nil
+}
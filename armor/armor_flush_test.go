@@ -0,0 +1,42 @@
+package armor
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArmorWithTypeFlushing(t *testing.T) {
+	data := bytes.Repeat([]byte{0x42}, 48*10)
+	var output bytes.Buffer
+	buffered := bufio.NewWriterSize(&output, 4096)
+
+	w, err := ArmorWithTypeFlushing(buffered, constants.PGPMessageHeader, 2)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	if _, err = w.Write(data[:48*5]); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	// The complete lines are flushed despite the buffer of the writer
+	assert.True(t, output.Len() > 4*(armorLineLength+1))
+	if _, err = w.Write(data[48*5:]); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+	assert.Exactly(t, 0, buffered.Buffered())
+
+	var expected bytes.Buffer
+	armorWriter, err := ArmorWithTypeBuffered(&expected, constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	_, _ = armorWriter.Write(data)
+	_ = armorWriter.Close()
+	assert.Exactly(t, expected.String(), output.String())
+}
@@ -0,0 +1,169 @@
+package armor
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnarmorLenient(t *testing.T) {
+	data := []byte("a message long enough to span several base64 lines of the armored output")
+	armored, err := ArmorWithTypeAndCustomHeaders(data, constants.PGPMessageHeader, "", "a comment")
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	lines := strings.Split(armored, "\n")
+	checksum := lines[len(lines)-2]
+	assert.True(t, strings.HasPrefix(checksum, "="))
+
+	unarmored, warnings, err := UnarmorLenient(armored)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	assert.Exactly(t, data, unarmored)
+	assert.Empty(t, warnings)
+
+	altered := map[string][]string{
+		strings.Replace(armored, checksum+"\n", "", 1): {constants.ArmorWarningMissingChecksum},
+		strings.Replace(armored, checksum, "=AAAA", 1): {constants.ArmorWarningInvalidChecksum},
+		strings.Join(lines[:len(lines)-1], "\n"):       {constants.ArmorWarningMissingEnd},
+		strings.Replace(armored, "comment\n\n", "comment\n", 1): {
+			constants.ArmorWarningMissingBlank,
+		},
+		strings.ReplaceAll(armored, "\n", " \n  "): {constants.ArmorWarningWhitespace},
+		strings.Replace(armored, "\n", "\r\n", 2):  {constants.ArmorWarningLineEndings},
+	}
+	for input, expected := range altered {
+		unarmored, warnings, err := UnarmorLenient(input)
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring, got:", err)
+		}
+		assert.Exactly(t, data, unarmored)
+		assert.Exactly(t, expected, warnings)
+	}
+
+	// Consistent line endings are not an alteration
+	_, warnings, err = UnarmorLenient(strings.ReplaceAll(armored, "\n", "\r\n"))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	assert.Empty(t, warnings)
+
+	_, _, err = UnarmorLenient(strings.Replace(armored, lines[3], lines[3][1:], 1))
+	assert.True(t, errors.Is(err, ErrInvalidArmor))
+	_, _, err = UnarmorLenient("not armored")
+	assert.True(t, errors.Is(err, ErrInvalidArmor))
+	assert.Exactly(t, 1, strings.Count(err.Error(), "unable to unarmor"))
+}
+
+func TestUnarmorWithType(t *testing.T) {
+	armored, err := ArmorWithType([]byte("data"), constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	data, err := UnarmorWithType(armored, constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	assert.Exactly(t, []byte("data"), data)
+
+	_, err = UnarmorWithType(armored, constants.PrivateKeyHeader)
+	var typeError TypeError
+	assert.True(t, errors.As(err, &typeError))
+	assert.Exactly(t, constants.PGPMessageHeader, typeError.Actual)
+
+	_, err = UnarmorWithType("not armored", constants.PGPMessageHeader)
+	assert.True(t, errors.Is(err, ErrInvalidArmor))
+	assert.True(t, strings.HasPrefix(err.Error(), "gopenpgp: "))
+
+	// The decoding error is kept
+	_, err = UnarmorWithType("-----BEGIN PGP MESSAGE-----\n\n!!!!\n-----END PGP MESSAGE-----\n", constants.PGPMessageHeader)
+	var corruptInputError base64.CorruptInputError
+	assert.True(t, errors.Is(err, ErrInvalidArmor))
+	assert.True(t, errors.As(err, &corruptInputError))
+}
+
+func TestUnarmorWithLimits(t *testing.T) {
+	data := []byte(strings.Repeat("data", 100))
+	armored, err := ArmorWithType(data, constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+
+	unarmored, err := UnarmorWithLimits(armored, NewLimits(int64(len(armored)), 64, int64(len(data))))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	assert.Exactly(t, data, unarmored)
+	unarmored, err = UnarmorWithLimits(armored, nil)
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring, got:", err)
+	}
+	assert.Exactly(t, data, unarmored)
+
+	_, err = UnarmorWithLimits(armored, NewLimits(int64(len(armored))-1, 0, 0))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	_, err = UnarmorReaderWithLimits(strings.NewReader(armored), NewLimits(100, 0, 0))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	_, err = UnarmorWithLimits(armored, NewLimits(0, 63, 0))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	_, err = UnarmorWithLimits(armored, NewLimits(0, 0, int64(len(data))-1))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	assert.Exactly(t, 1, strings.Count(err.Error(), "unable to unarmor"))
+
+	// A line longer than the buffer of the armor decoder, but within the
+	// limit, is invalid armor
+	longLine := strings.Repeat("A", 150)
+	input := "-----BEGIN PGP MESSAGE-----\n\n" + longLine + "\n-----END PGP MESSAGE-----\n"
+	_, err = UnarmorWithLimits(input, NewLimits(0, 200, 0))
+	assert.True(t, errors.Is(err, ErrInvalidArmor))
+	assert.Exactly(t, 1, strings.Count(err.Error(), "unable to unarmor"))
+
+	// Over the limit, with or without a line ending
+	longLine = strings.Repeat("A", 1000)
+	input = "-----BEGIN PGP MESSAGE-----\n\n" + longLine + "\n-----END PGP MESSAGE-----\n"
+	_, err = UnarmorWithLimits(input, NewLimits(0, 200, 0))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	_, err = UnarmorWithLimits("-----BEGIN PGP MESSAGE-----\n\n"+longLine, NewLimits(0, 200, 0))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	_, err = UnarmorWithLimits(longLine, NewLimits(0, 200, 0))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	assert.Exactly(t, 1, strings.Count(err.Error(), "unable to unarmor"))
+}
+
+func TestArmorDeterministic(t *testing.T) {
+	data := []byte("reproducible")
+	armored, err := ArmorWithType(data, constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	assert.Contains(t, armored, "Version: ")
+
+	SetDeterministic(true)
+	defer SetDeterministic(false)
+	armored, err = ArmorWithType(data, constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	assert.True(t, strings.HasPrefix(armored, "-----BEGIN PGP MESSAGE-----\n\n"))
+	assert.NotContains(t, armored, "\r")
+	assert.Exactly(t, ArmoredSize(int64(len(data)), constants.PGPMessageHeader), int64(len(armored)))
+	again, err := ArmorWithType(data, constants.PGPMessageHeader)
+	if err != nil {
+		t.Fatal("Expected no error while armoring, got:", err)
+	}
+	assert.Exactly(t, armored, again)
+
+	// Custom headers are always sorted
+	for i := 0; i < 10; i++ {
+		custom, err := ArmorWithTypeAndCustomHeaders(data, constants.PGPMessageHeader, "version", "comment")
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		assert.True(t, strings.HasPrefix(custom, "-----BEGIN PGP MESSAGE-----\nComment: comment\nVersion: version\n\n"))
+	}
+}
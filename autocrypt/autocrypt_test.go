@@ -0,0 +1,127 @@
+package autocrypt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAddr = "alice@example.org"
+
+func generateTestKey(t *testing.T) *crypto.Key {
+	key, err := crypto.GenerateKey("Alice", testAddr, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	return key
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	key := generateTestKey(t)
+
+	header, err := NewHeader("Alice@Example.org", key, true)
+	if err != nil {
+		t.Fatal("Expected no error while creating header, got:", err)
+	}
+	assert.Exactly(t, testAddr, header.Addr)
+	assert.False(t, header.Key.IsPrivate())
+	assert.True(t, header.Key.CanEncrypt())
+
+	value, err := header.GetValue()
+	if err != nil {
+		t.Fatal("Expected no error while formatting header, got:", err)
+	}
+	assert.True(t, strings.HasPrefix(value, "addr=alice@example.org; prefer-encrypt=mutual; keydata="))
+	for _, line := range strings.Split(value, "\r\n") {
+		assert.LessOrEqual(t, len(line), 78)
+	}
+
+	parsed, err := ParseHeader(value)
+	if err != nil {
+		t.Fatal("Expected no error while parsing header, got:", err)
+	}
+	assert.Exactly(t, testAddr, parsed.Addr)
+	assert.True(t, parsed.PreferEncrypt)
+	assert.Exactly(t, key.GetFingerprint(), parsed.Key.GetFingerprint())
+
+	gossip, err := NewHeader(testAddr, key, false)
+	if err != nil {
+		t.Fatal("Expected no error while creating header, got:", err)
+	}
+	gossipValue, err := gossip.GetValue()
+	if err != nil {
+		t.Fatal("Expected no error while formatting header, got:", err)
+	}
+	assert.NotContains(t, gossipValue, "prefer-encrypt")
+}
+
+func TestParseInvalidHeader(t *testing.T) {
+	header, err := NewHeader(testAddr, generateTestKey(t), false)
+	if err != nil {
+		t.Fatal("Expected no error while creating header, got:", err)
+	}
+	value, err := header.GetValue()
+	if err != nil {
+		t.Fatal("Expected no error while formatting header, got:", err)
+	}
+	keyData := value[strings.Index(value, "keydata="):]
+
+	_, err = ParseHeader("_comment=ignored; addr=" + testAddr + "; " + keyData)
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{
+		keyData,
+		"addr=" + testAddr,
+		"addr=" + testAddr + "; critical=yes; " + keyData,
+		"addr=" + testAddr + "; addr=bob@example.org; " + keyData,
+		"addr=Alice <" + testAddr + ">; " + keyData,
+		"addr=" + testAddr + "; keydata=notbase64!",
+	} {
+		_, err = ParseHeader(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPeerState(t *testing.T) {
+	key := generateTestKey(t)
+	now := crypto.GetUnixTime()
+
+	header, err := NewHeader(testAddr, key, true)
+	if err != nil {
+		t.Fatal("Expected no error while creating header, got:", err)
+	}
+
+	peer, err := NewPeerState(testAddr)
+	if err != nil {
+		t.Fatal("Expected no error while creating peer state, got:", err)
+	}
+	assert.Exactly(t, RecommendationDisable, peer.GetRecommendation(true))
+
+	peer.UpdateFromGossip(header, now-200)
+	assert.Exactly(t, RecommendationDiscourage, peer.GetRecommendation(true))
+
+	peer.Update(header, now-100)
+	assert.Exactly(t, PreferEncryptMutual, peer.PreferEncrypt)
+	assert.Exactly(t, now-100, peer.AutocryptTimestamp)
+	assert.Exactly(t, RecommendationEncrypt, peer.GetRecommendation(true))
+	assert.Exactly(t, RecommendationAvailable, peer.GetRecommendation(false))
+
+	// Older messages are ignored
+	peer.Update(nil, now-150)
+	assert.Exactly(t, PreferEncryptMutual, peer.PreferEncrypt)
+	assert.Exactly(t, now-100, peer.LastSeen)
+
+	// Dates in the future are clamped
+	peer.Update(nil, now+1000000)
+	assert.Exactly(t, PreferEncryptReset, peer.PreferEncrypt)
+	assert.LessOrEqual(t, peer.LastSeen, crypto.GetUnixTime())
+	assert.Exactly(t, RecommendationAvailable, peer.GetRecommendation(true))
+
+	peer.LastSeen = peer.AutocryptTimestamp + staleAutocryptPeriod + 1
+	assert.Exactly(t, RecommendationDiscourage, peer.GetRecommendation(true))
+	// A stale key is discouraged even if both sides prefer encryption
+	peer.PreferEncrypt = PreferEncryptMutual
+	assert.Exactly(t, RecommendationDiscourage, peer.GetRecommendation(true))
+}
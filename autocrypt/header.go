@@ -0,0 +1,221 @@
+// Package autocrypt implements the Autocrypt Level 1 specification
+// (https://autocrypt.org/level1.html): generation and parsing of Autocrypt
+// and Autocrypt-Gossip email headers, and management of the peer state
+// used to recommend encryption.
+package autocrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// HeaderName is the name of the header announcing the sender's key.
+	HeaderName = "Autocrypt"
+	// GossipHeaderName is the name of the headers, inside the encrypted
+	// part of a message, announcing the keys of the other recipients.
+	GossipHeaderName = "Autocrypt-Gossip"
+)
+
+const (
+	attributeAddr          = "addr"
+	attributePreferEncrypt = "prefer-encrypt"
+	attributeKeyData       = "keydata"
+	preferEncryptMutual    = "mutual"
+	// keyDataLineLength is the length of the folded base64 lines of keydata.
+	keyDataLineLength = 76
+)
+
+// addrMatchPolicy compares the addresses of headers with the user IDs of
+// keys: Autocrypt addresses are case-insensitive.
+var addrMatchPolicy = &crypto.EmailMatchPolicy{}
+
+// Header is the content of an Autocrypt or Autocrypt-Gossip header.
+type Header struct {
+	// Addr is the email address the key belongs to, in lower case.
+	Addr string
+	// PreferEncrypt is true if the sender asks to encrypt by default
+	// (prefer-encrypt=mutual). Always false in gossip headers.
+	PreferEncrypt bool
+	// Key is the public key announced by the header.
+	Key *crypto.Key
+}
+
+// NewHeader creates an Autocrypt header announcing key for addr.
+// The key is reduced to the minimal set of packets recommended by the
+// specification: the primary key, the user ID matching addr (or the primary
+// user ID if none matches), and the current encryption subkey, along with
+// their latest self-signatures.
+// For Autocrypt-Gossip headers, preferEncrypt must be false.
+func NewHeader(addr string, key *crypto.Key, preferEncrypt bool) (*Header, error) {
+	addr, err := normalizeAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	minimalKey, err := minimizeKey(key, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Header{
+		Addr:          addr,
+		PreferEncrypt: preferEncrypt,
+		Key:           minimalKey,
+	}, nil
+}
+
+// ParseHeader parses the value of an Autocrypt or Autocrypt-Gossip header.
+// Per the specification, a header with a missing addr or keydata attribute,
+// or with an unknown critical attribute (i.e. not starting with an
+// underscore), is invalid. The caller must also discard the header if Addr
+// does not match the From address, or if the message has several Autocrypt
+// headers.
+func ParseHeader(value string) (*Header, error) {
+	header := &Header{}
+	var keyData string
+	seen := make(map[string]bool)
+	for _, attribute := range strings.Split(value, ";") {
+		attribute = strings.TrimSpace(attribute)
+		if attribute == "" {
+			continue
+		}
+		separator := strings.IndexByte(attribute, '=')
+		if separator < 0 {
+			return nil, errors.New("gopenpgp: invalid autocrypt attribute " + attribute)
+		}
+		name := strings.TrimSpace(attribute[:separator])
+		attributeValue := strings.TrimSpace(attribute[separator+1:])
+		if seen[name] {
+			return nil, errors.New("gopenpgp: duplicate autocrypt attribute " + name)
+		}
+		seen[name] = true
+
+		switch name {
+		case attributeAddr:
+			addr, err := normalizeAddr(attributeValue)
+			if err != nil {
+				return nil, err
+			}
+			header.Addr = addr
+		case attributePreferEncrypt:
+			header.PreferEncrypt = attributeValue == preferEncryptMutual
+		case attributeKeyData:
+			keyData = attributeValue
+		default:
+			if !strings.HasPrefix(name, "_") {
+				return nil, errors.New("gopenpgp: unknown critical autocrypt attribute " + name)
+			}
+		}
+	}
+
+	if header.Addr == "" {
+		return nil, errors.New("gopenpgp: missing autocrypt addr attribute")
+	}
+	if keyData == "" {
+		return nil, errors.New("gopenpgp: missing autocrypt keydata attribute")
+	}
+
+	binaryKey, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(keyData), ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to decode autocrypt keydata")
+	}
+	header.Key, err = crypto.NewKey(binaryKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read autocrypt key")
+	}
+	if header.Key.IsPrivate() {
+		return nil, errors.New("gopenpgp: autocrypt keydata contains a private key")
+	}
+	return header, nil
+}
+
+// GetValue returns the header value. The keydata is folded in lines of 76
+// characters, separated by CRLF and a space, so the value can be written as
+// is after "Autocrypt: " in a message.
+func (header *Header) GetValue() (string, error) {
+	var value strings.Builder
+	value.WriteString(attributeAddr + "=" + header.Addr + "; ")
+	if header.PreferEncrypt {
+		value.WriteString(attributePreferEncrypt + "=" + preferEncryptMutual + "; ")
+	}
+	value.WriteString(attributeKeyData + "=")
+
+	publicKey, err := header.Key.GetPublicKey()
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to serialize autocrypt key")
+	}
+	keyData := base64.StdEncoding.EncodeToString(publicKey)
+	for len(keyData) > keyDataLineLength {
+		value.WriteString("\r\n " + keyData[:keyDataLineLength])
+		keyData = keyData[keyDataLineLength:]
+	}
+	value.WriteString("\r\n " + keyData)
+	return value.String(), nil
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// normalizeAddr checks that addr is a bare email address, and lower-cases it
+// as required to compare Autocrypt addresses.
+func normalizeAddr(addr string) (string, error) {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil || parsed.Address != addr {
+		return "", errors.New("gopenpgp: invalid autocrypt address " + addr)
+	}
+	return strings.ToLower(addr), nil
+}
+
+// minimizeKey returns the public key reduced to the packets included in
+// Autocrypt headers.
+func minimizeKey(key *crypto.Key, addr string) (*crypto.Key, error) {
+	entity := key.GetEntity()
+	now := crypto.GetTime()
+
+	identity := entity.PrimaryIdentity()
+	for _, candidate := range entity.Identities {
+		if addrMatchPolicy.MatchesUserID(candidate.UserId, addr) {
+			identity = candidate
+			break
+		}
+	}
+	if identity == nil || identity.SelfSignature == nil {
+		return nil, errors.New("gopenpgp: the key does not have any user ID")
+	}
+
+	encryptionKey, ok := entity.EncryptionKey(now)
+	if !ok {
+		return nil, errors.New("gopenpgp: the key cannot be used for encryption")
+	}
+
+	var buf bytes.Buffer
+	packets := []interface{ Serialize(w io.Writer) error }{
+		entity.PrimaryKey,
+	}
+	if entity.SelfSignature != nil {
+		packets = append(packets, entity.SelfSignature)
+	}
+	packets = append(packets, identity.UserId, identity.SelfSignature)
+	if encryptionKey.PublicKey != entity.PrimaryKey {
+		packets = append(packets, encryptionKey.PublicKey, encryptionKey.SelfSignature)
+	}
+	for _, p := range packets {
+		if err := p.Serialize(&buf); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to serialize autocrypt key")
+		}
+	}
+
+	entities, err := openpgp.ReadKeyRing(&buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read autocrypt key")
+	}
+	if len(entities) != 1 {
+		return nil, errors.New("gopenpgp: unable to read autocrypt key")
+	}
+	return crypto.NewKeyFromEntity(entities[0])
+}
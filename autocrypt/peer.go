@@ -0,0 +1,168 @@
+package autocrypt
+
+import (
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+)
+
+// PreferEncrypt is the encryption preference of a peer, as recorded in its state.
+type PreferEncrypt int
+
+const (
+	// PreferEncryptNoPreference means the peer did not ask to encrypt by default.
+	PreferEncryptNoPreference PreferEncrypt = iota
+	// PreferEncryptMutual means the peer asked to encrypt by default.
+	PreferEncryptMutual
+	// PreferEncryptReset means the peer sent a message without Autocrypt
+	// header after its last Autocrypt header, e.g. from another mail client.
+	PreferEncryptReset
+)
+
+// Recommendation is the encryption recommendation for a message to a peer,
+// see Autocrypt Level 1, section 2.4.
+type Recommendation int
+
+const (
+	// RecommendationDisable means encryption is not possible.
+	RecommendationDisable Recommendation = iota
+	// RecommendationDiscourage means encryption is possible, but the peer
+	// may not be able to read the message.
+	RecommendationDiscourage
+	// RecommendationAvailable means encryption is possible, and can be
+	// offered to the user.
+	RecommendationAvailable
+	// RecommendationEncrypt means the message should be encrypted by default.
+	RecommendationEncrypt
+)
+
+// staleAutocryptPeriod is the time after which an Autocrypt key is
+// considered stale, when messages without Autocrypt header were seen since.
+const staleAutocryptPeriod = 35 * 24 * 60 * 60
+
+// PeerState is the state kept about a peer to decide whether to encrypt
+// messages sent to it. Timestamps are unix times, 0 if unset.
+// Storing the states, indexed by Addr, is left to the application.
+type PeerState struct {
+	// Addr is the email address of the peer, in lower case.
+	Addr string
+	// LastSeen is the most recent effective date of messages from the peer.
+	LastSeen int64
+	// AutocryptTimestamp is the effective date of the last message from the
+	// peer with a valid Autocrypt header.
+	AutocryptTimestamp int64
+	// PublicKey is the key announced in the last Autocrypt header.
+	PublicKey *crypto.Key
+	// PreferEncrypt is the preference announced in the last Autocrypt header,
+	// or PreferEncryptReset.
+	PreferEncrypt PreferEncrypt
+	// GossipTimestamp is the effective date of the last message gossiping
+	// the key of the peer.
+	GossipTimestamp int64
+	// GossipKey is the key announced in the last Autocrypt-Gossip header.
+	GossipKey *crypto.Key
+}
+
+// NewPeerState creates an empty state for the peer with the given address.
+func NewPeerState(addr string) (*PeerState, error) {
+	addr, err := normalizeAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &PeerState{Addr: addr}, nil
+}
+
+// Update updates the state with a message sent by the peer, following
+// Autocrypt Level 1, section 2.3.
+// * header : the Autocrypt header of the message, nil if it had none or if
+// it was invalid. It is ignored if its address is not the peer's one.
+// * effectiveDate : the Date of the message, as a unix time. Dates in the
+// future are replaced by the current time.
+func (peer *PeerState) Update(header *Header, effectiveDate int64) {
+	effectiveDate = clampEffectiveDate(effectiveDate)
+	if effectiveDate < peer.AutocryptTimestamp {
+		return
+	}
+	if effectiveDate > peer.LastSeen {
+		peer.LastSeen = effectiveDate
+	}
+
+	if header == nil || header.Addr != peer.Addr {
+		if effectiveDate > peer.AutocryptTimestamp && peer.PublicKey != nil {
+			peer.PreferEncrypt = PreferEncryptReset
+		}
+		return
+	}
+
+	peer.AutocryptTimestamp = effectiveDate
+	peer.PublicKey = header.Key
+	if header.PreferEncrypt {
+		peer.PreferEncrypt = PreferEncryptMutual
+	} else {
+		peer.PreferEncrypt = PreferEncryptNoPreference
+	}
+}
+
+// UpdateFromGossip updates the state with an Autocrypt-Gossip header about
+// the peer, found in the encrypted part of a message from another sender,
+// following Autocrypt Level 1, section 2.7.
+// * header : the gossip header, ignored if its address is not the peer's one.
+// * effectiveDate : the Date of the message, as a unix time.
+func (peer *PeerState) UpdateFromGossip(header *Header, effectiveDate int64) {
+	effectiveDate = clampEffectiveDate(effectiveDate)
+	if header == nil || header.Addr != peer.Addr || effectiveDate <= peer.GossipTimestamp {
+		return
+	}
+	peer.GossipTimestamp = effectiveDate
+	peer.GossipKey = header.Key
+}
+
+// GetEncryptionKey returns the key to use to encrypt to the peer: the key of
+// its last Autocrypt header, or else the last gossiped key. Returns nil if
+// no usable key is known.
+func (peer *PeerState) GetEncryptionKey() *crypto.Key {
+	if peer.PublicKey != nil && peer.PublicKey.CanEncrypt() {
+		return peer.PublicKey
+	}
+	if peer.GossipKey != nil && peer.GossipKey.CanEncrypt() {
+		return peer.GossipKey
+	}
+	return nil
+}
+
+// GetRecommendation returns the encryption recommendation for a message to
+// the peer, following Autocrypt Level 1, section 2.4.
+// * preferEncrypt : the encryption preference of the sender, i.e. whether
+// the sender announces prefer-encrypt=mutual.
+func (peer *PeerState) GetRecommendation(preferEncrypt bool) Recommendation {
+	recommendation := peer.getPreliminaryRecommendation()
+	if recommendation == RecommendationAvailable &&
+		preferEncrypt && peer.PreferEncrypt == PreferEncryptMutual {
+		return RecommendationEncrypt
+	}
+	return recommendation
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// getPreliminaryRecommendation returns the recommendation before the
+// encryption preferences are taken into account: a gossiped or stale key
+// is discouraged.
+func (peer *PeerState) getPreliminaryRecommendation() Recommendation {
+	key := peer.GetEncryptionKey()
+	if key == nil {
+		return RecommendationDisable
+	}
+	if key != peer.PublicKey {
+		return RecommendationDiscourage
+	}
+	if peer.AutocryptTimestamp+staleAutocryptPeriod < peer.LastSeen {
+		return RecommendationDiscourage
+	}
+	return RecommendationAvailable
+}
+
+func clampEffectiveDate(effectiveDate int64) int64 {
+	if now := crypto.GetUnixTime(); effectiveDate > now {
+		return now
+	}
+	return effectiveDate
+}
@@ -0,0 +1,100 @@
+// Package bench contains reproducible benchmarks of the crypto package:
+// streaming encryption and decryption of large messages, messages with many
+// recipients, and operations on large keyrings.
+//
+// The benchmarks report throughput and allocations, and can be compared
+// across versions with benchstat:
+//
+//	go test ./bench -run '^$' -bench . -benchmem -count 10 > new.txt
+//	benchstat old.txt new.txt
+//
+// CPU and memory profiles are written with the standard -cpuprofile and
+// -memprofile flags. The 1GB benchmarks only run with the -large flag.
+package bench
+
+import (
+	"io"
+	"sync"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	kiB = 1 << 10
+	miB = 1 << 20
+	giB = 1 << 30
+)
+
+// patternReader deterministically generates size bytes of incompressible
+// looking data, without allocating, so that runs are comparable.
+type patternReader struct {
+	remaining int64
+	state     uint64
+}
+
+func newPatternReader(size int64) *patternReader {
+	return &patternReader{remaining: size, state: 0x9e3779b97f4a7c15}
+}
+
+func (reader *patternReader) Read(b []byte) (int, error) {
+	if reader.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > reader.remaining {
+		b = b[:reader.remaining]
+	}
+	for i := range b {
+		// xorshift64
+		reader.state ^= reader.state << 13
+		reader.state ^= reader.state >> 7
+		reader.state ^= reader.state << 17
+		b[i] = byte(reader.state)
+	}
+	reader.remaining -= int64(len(b))
+	return len(b), nil
+}
+
+var (
+	keysLock sync.Mutex
+	keys     []*crypto.Key
+)
+
+// getKeys returns count unlocked x25519 keys, generated once and shared by
+// the benchmarks.
+func getKeys(count int) ([]*crypto.Key, error) {
+	keysLock.Lock()
+	defer keysLock.Unlock()
+
+	for len(keys) < count {
+		key, err := crypto.GenerateKey("Benchmark", "benchmark@example.org", "x25519", 0)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to generate benchmark key")
+		}
+		keys = append(keys, key)
+	}
+	return keys[:count], nil
+}
+
+// getKeyRing returns a keyring of count keys, see getKeys.
+func getKeyRing(count int, private bool) (*crypto.KeyRing, error) {
+	keys, err := getKeys(count)
+	if err != nil {
+		return nil, err
+	}
+	keyRing, err := crypto.NewKeyRing(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if !private {
+			if key, err = key.ToPublic(); err != nil {
+				return nil, err
+			}
+		}
+		if err = keyRing.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return keyRing, nil
+}
@@ -0,0 +1,259 @@
+package bench
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+)
+
+var large = flag.Bool("large", false, "run the 1GB benchmarks")
+
+var streamSizes = []struct {
+	name  string
+	size  int64
+	large bool
+}{
+	{"1MB", miB, false},
+	{"100MB", 100 * miB, false},
+	{"1GB", giB, true},
+}
+
+func BenchmarkEncryptStream(b *testing.B) {
+	publicKeyRing, err := getKeyRing(1, false)
+	if err != nil {
+		b.Fatal("Expected no error while building keyring, got:", err)
+	}
+	for _, size := range streamSizes {
+		size := size
+		b.Run(size.name, func(b *testing.B) {
+			skipLarge(b, size.large)
+			b.SetBytes(size.size)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				encryptStream(b, ioutil.Discard, publicKeyRing, nil, size.size)
+			}
+		})
+	}
+}
+
+func BenchmarkEncryptSignStream(b *testing.B) {
+	privateKeyRing, err := getKeyRing(1, true)
+	if err != nil {
+		b.Fatal("Expected no error while building keyring, got:", err)
+	}
+	b.SetBytes(100 * miB)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		encryptStream(b, ioutil.Discard, privateKeyRing, privateKeyRing, 100*miB)
+	}
+}
+
+func BenchmarkDecryptStream(b *testing.B) {
+	privateKeyRing, err := getKeyRing(1, true)
+	if err != nil {
+		b.Fatal("Expected no error while building keyring, got:", err)
+	}
+	for _, size := range streamSizes {
+		size := size
+		b.Run(size.name, func(b *testing.B) {
+			skipLarge(b, size.large)
+			// The ciphertext is stored on disk to keep the memory profile
+			// of the large benchmarks meaningful.
+			path := filepath.Join(b.TempDir(), "message.pgp")
+			file, err := os.Create(path)
+			if err != nil {
+				b.Fatal("Expected no error while creating file, got:", err)
+			}
+			encryptStream(b, file, privateKeyRing, nil, size.size)
+			if err = file.Close(); err != nil {
+				b.Fatal("Expected no error while closing file, got:", err)
+			}
+
+			b.SetBytes(size.size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				file, err := os.Open(path)
+				if err != nil {
+					b.Fatal("Expected no error while opening file, got:", err)
+				}
+				reader, err := privateKeyRing.DecryptStream(file, nil, 0)
+				if err != nil {
+					b.Fatal("Expected no error while decrypting, got:", err)
+				}
+				if _, err = io.Copy(ioutil.Discard, reader); err != nil {
+					b.Fatal("Expected no error while reading, got:", err)
+				}
+				_ = file.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkSignDetachedStream(b *testing.B) {
+	privateKeyRing, err := getKeyRing(1, true)
+	if err != nil {
+		b.Fatal("Expected no error while building keyring, got:", err)
+	}
+	b.SetBytes(100 * miB)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err = privateKeyRing.SignDetachedStream(newPatternReader(100 * miB)); err != nil {
+			b.Fatal("Expected no error while signing, got:", err)
+		}
+	}
+}
+
+func BenchmarkEncryptManyRecipients(b *testing.B) {
+	message := crypto.NewPlainMessage(bytes.Repeat([]byte{0x42}, 10*kiB))
+	for _, count := range []int{1, 10, 100} {
+		count := count
+		b.Run(strconv.Itoa(count), func(b *testing.B) {
+			publicKeyRing, err := getKeyRing(count, false)
+			if err != nil {
+				b.Fatal("Expected no error while building keyring, got:", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = publicKeyRing.Encrypt(message, nil); err != nil {
+					b.Fatal("Expected no error while encrypting, got:", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecryptManyRecipients(b *testing.B) {
+	message := crypto.NewPlainMessage(bytes.Repeat([]byte{0x42}, 10*kiB))
+	for _, count := range []int{1, 10, 100} {
+		count := count
+		b.Run(strconv.Itoa(count), func(b *testing.B) {
+			publicKeyRing, err := getKeyRing(count, false)
+			if err != nil {
+				b.Fatal("Expected no error while building keyring, got:", err)
+			}
+			ciphertext, err := publicKeyRing.Encrypt(message, nil)
+			if err != nil {
+				b.Fatal("Expected no error while encrypting, got:", err)
+			}
+			// The worst case: the recipient's key packet is the last one
+			keys, err := getKeys(count)
+			if err != nil {
+				b.Fatal("Expected no error while generating keys, got:", err)
+			}
+			privateKeyRing, err := crypto.NewKeyRing(keys[count-1])
+			if err != nil {
+				b.Fatal("Expected no error while building keyring, got:", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = privateKeyRing.Decrypt(ciphertext, nil, 0); err != nil {
+					b.Fatal("Expected no error while decrypting, got:", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkKeyRing(b *testing.B) {
+	message := crypto.NewPlainMessage(bytes.Repeat([]byte{0x42}, kiB))
+	for _, count := range []int{10, 100, 1000} {
+		count := count
+		keys, err := getKeys(count)
+		if err != nil {
+			b.Fatal("Expected no error while generating keys, got:", err)
+		}
+		privateKeyRing, err := getKeyRing(count, true)
+		if err != nil {
+			b.Fatal("Expected no error while building keyring, got:", err)
+		}
+		// Encrypted to the last key of the keyring
+		lastKeyRing, err := crypto.NewKeyRing(keys[count-1])
+		if err != nil {
+			b.Fatal("Expected no error while building keyring, got:", err)
+		}
+		ciphertext, err := lastKeyRing.Encrypt(message, nil)
+		if err != nil {
+			b.Fatal("Expected no error while encrypting, got:", err)
+		}
+		signature, err := lastKeyRing.SignDetached(message)
+		if err != nil {
+			b.Fatal("Expected no error while signing, got:", err)
+		}
+
+		b.Run("Build/"+strconv.Itoa(count), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				keyRing, err := crypto.NewKeyRing(nil)
+				if err != nil {
+					b.Fatal("Expected no error while building keyring, got:", err)
+				}
+				for _, key := range keys {
+					if err = keyRing.AddKey(key); err != nil {
+						b.Fatal("Expected no error while adding key, got:", err)
+					}
+				}
+			}
+		})
+		b.Run("Decrypt/"+strconv.Itoa(count), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := privateKeyRing.Decrypt(ciphertext, nil, 0); err != nil {
+					b.Fatal("Expected no error while decrypting, got:", err)
+				}
+			}
+		})
+		b.Run("Verify/"+strconv.Itoa(count), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := privateKeyRing.VerifyDetached(message, signature, 0); err != nil {
+					b.Fatal("Expected no error while verifying, got:", err)
+				}
+			}
+		})
+	}
+}
+
+func TestPatternReader(t *testing.T) {
+	first, err := ioutil.ReadAll(newPatternReader(kiB + 1))
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	second, err := ioutil.ReadAll(newPatternReader(kiB + 1))
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	if len(first) != kiB+1 || !bytes.Equal(first, second) {
+		t.Fatal("Expected reproducible data of the requested size")
+	}
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+func skipLarge(b *testing.B, isLarge bool) {
+	if isLarge && !*large {
+		b.Skip("run with -large to enable")
+	}
+}
+
+func encryptStream(b *testing.B, output io.Writer, publicKeyRing, signKeyRing *crypto.KeyRing, size int64) {
+	writer, err := publicKeyRing.EncryptStream(output, nil, signKeyRing)
+	if err != nil {
+		b.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = io.Copy(writer, newPatternReader(size)); err != nil {
+		b.Fatal("Expected no error while writing, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		b.Fatal("Expected no error while closing, got:", err)
+	}
+}
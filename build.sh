@@ -102,6 +102,7 @@ import github.com/ProtonMail/gopenpgp/v2/constants
 import github.com/ProtonMail/gopenpgp/v2/models
 import github.com/ProtonMail/gopenpgp/v2/subtle
 import github.com/ProtonMail/gopenpgp/v2/helper
+import github.com/ProtonMail/gopenpgp/v2/autocrypt
 
 ######## ======== Main ===========
 
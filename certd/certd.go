@@ -0,0 +1,313 @@
+// Package certd stores certificates on disk following the shared OpenPGP
+// certificate directory specification (pgp.cert.d), so that applications can
+// share a certificate store with other OpenPGP implementations such as Sequoia.
+// See https://datatracker.ietf.org/doc/draft-nwalfield-openpgp-cert-d/.
+package certd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvironmentVariable overrides the default location of the store.
+	EnvironmentVariable = "PGP_CERT_D"
+	// TrustRootName is the special name of the trust root certificate.
+	TrustRootName = "trust-root"
+	// writeLockName is the file locked by writers.
+	writeLockName = "writelock"
+	// directoryName is the name of the store in the default data directory.
+	directoryName = "pgp.cert.d"
+)
+
+// ErrNotFound is returned when the store has no certificate for a lookup.
+var ErrNotFound = errors.New("gopenpgp: certificate not found")
+
+// Store is a certificate directory. Certificates are stored in binary form,
+// in files named after their fingerprint, split after the second hex digit,
+// e.g. "eb/85bb5fa33a75e15e944e63f231550c4f47e38e".
+// Concurrent writers, including other processes on Unix and Windows, are
+// serialized with a lock on the "writelock" file, and files are replaced
+// atomically, so readers never see partially written certificates.
+type Store struct {
+	path string
+}
+
+// NewStore opens the certificate directory at path, creating it if needed.
+func NewStore(path string) (*Store, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to create certificate directory")
+	}
+	return &Store{path: path}, nil
+}
+
+// NewDefaultStore opens the certificate directory at the default location,
+// see GetDefaultPath.
+func NewDefaultStore() (*Store, error) {
+	path, err := GetDefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(path)
+}
+
+// GetDefaultPath returns the location of the certificate directory: the value
+// of the PGP_CERT_D environment variable if it is set, and otherwise
+// "pgp.cert.d" in the platform's data directory, i.e. $XDG_DATA_HOME
+// (default ~/.local/share) on Unix, ~/Library/Application Support on macOS,
+// and %APPDATA% on Windows.
+func GetDefaultPath() (string, error) {
+	if path := os.Getenv(EnvironmentVariable); path != "" {
+		return path, nil
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, directoryName), nil
+		}
+		return "", errors.New("gopenpgp: %APPDATA% is not set")
+	case "darwin", "ios":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "gopenpgp: unable to find the home directory")
+		}
+		return filepath.Join(home, "Library", "Application Support", directoryName), nil
+	default:
+		if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+			return filepath.Join(dataHome, directoryName), nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "gopenpgp: unable to find the home directory")
+		}
+		return filepath.Join(home, ".local", "share", directoryName), nil
+	}
+}
+
+// GetPath returns the location of the certificate directory.
+func (store *Store) GetPath() string {
+	return store.path
+}
+
+// Get returns the certificate with the given hex primary key fingerprint.
+// Returns ErrNotFound if the store does not contain it.
+func (store *Store) Get(fingerprint string) (*crypto.Key, error) {
+	path, err := store.getCertificatePath(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return readCertificate(path)
+}
+
+// GetTag returns a tag identifying the current version of the certificate
+// with the given fingerprint, which changes when the certificate is updated.
+// It lets applications cache parsed certificates, as suggested by the
+// specification. Returns ErrNotFound if the store does not contain it.
+func (store *Store) GetTag(fingerprint string) (string, error) {
+	path, err := store.getCertificatePath(fingerprint)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to read certificate")
+	}
+	return strconv.FormatInt(info.ModTime().UnixNano(), 16) + "-" + strconv.FormatInt(info.Size(), 16), nil
+}
+
+// Insert adds the public part of the key to the store. If the store already
+// contains a version of the certificate, both versions are merged.
+// Returns the certificate as stored.
+func (store *Store) Insert(key *crypto.Key) (*crypto.Key, error) {
+	if key.IsPrivate() {
+		publicKey, err := key.ToPublic()
+		if err != nil {
+			return nil, err
+		}
+		key = publicKey
+	}
+
+	path, err := store.getCertificatePath(key.GetFingerprint())
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := store.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	existing, err := readCertificate(path)
+	switch {
+	case err == nil:
+		if key, err = existing.Merge(key); err != nil {
+			return nil, err
+		}
+	case err != ErrNotFound:
+		return nil, err
+	}
+
+	if err = writeCertificate(path, key, false); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetTrustRoot returns the trust root, the certificate of the local user
+// used as root for authenticating other certificates. It may contain
+// secret key material. Returns ErrNotFound if it is not set.
+func (store *Store) GetTrustRoot() (*crypto.Key, error) {
+	return readCertificate(filepath.Join(store.path, TrustRootName))
+}
+
+// SetTrustRoot replaces the trust root with key, which may be private.
+func (store *Store) SetTrustRoot(key *crypto.Key) error {
+	unlock, err := store.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return writeCertificate(filepath.Join(store.path, TrustRootName), key, key.IsPrivate())
+}
+
+// GetFingerprints returns the sorted fingerprints of the certificates in the
+// store, excluding the trust root. Files not following the layout of the
+// specification are ignored.
+func (store *Store) GetFingerprints() ([]string, error) {
+	directories, err := ioutil.ReadDir(store.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to list certificate directory")
+	}
+
+	var fingerprints []string
+	for _, directory := range directories {
+		if !directory.IsDir() || !isLowerHex(directory.Name()) || len(directory.Name()) != 2 {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(store.path, directory.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to list certificate directory")
+		}
+		for _, file := range files {
+			fingerprint := directory.Name() + file.Name()
+			if file.Mode().IsRegular() && isFingerprint(fingerprint) {
+				fingerprints = append(fingerprints, fingerprint)
+			}
+		}
+	}
+	sort.Strings(fingerprints)
+	return fingerprints, nil
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// getCertificatePath returns the path of the certificate with the given
+// fingerprint, which is normalized to lower case.
+func (store *Store) getCertificatePath(fingerprint string) (string, error) {
+	fingerprint = strings.ToLower(fingerprint)
+	if !isFingerprint(fingerprint) {
+		return "", errors.New("gopenpgp: invalid fingerprint " + fingerprint)
+	}
+	return filepath.Join(store.path, fingerprint[:2], fingerprint[2:]), nil
+}
+
+// lock takes the write lock of the store, and returns the function releasing it.
+func (store *Store) lock() (func(), error) {
+	file, err := os.OpenFile(filepath.Join(store.path, writeLockName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to open certificate directory lock")
+	}
+	if err = internal.LockFile(file); err != nil {
+		_ = file.Close()
+		return nil, errors.Wrap(err, "gopenpgp: unable to lock certificate directory")
+	}
+	return func() {
+		_ = internal.UnlockFile(file)
+		_ = file.Close()
+	}, nil
+}
+
+func readCertificate(path string) (*crypto.Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read certificate")
+	}
+	key, err := crypto.NewKey(data)
+	if err != nil {
+		// The specification allows armored certificates
+		if key, err = crypto.NewKeyFromArmored(string(data)); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to parse certificate "+path)
+		}
+	}
+	return key, nil
+}
+
+// writeCertificate atomically replaces the file at path with the binary key.
+func writeCertificate(path string, key *crypto.Key, private bool) error {
+	var data []byte
+	var err error
+	if private {
+		data, err = key.Serialize()
+	} else {
+		data, err = key.GetPublicKey()
+	}
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to serialize certificate")
+	}
+
+	directory := filepath.Dir(path)
+	if err = os.MkdirAll(directory, 0700); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to create certificate directory")
+	}
+	file, err := ioutil.TempFile(directory, ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to write certificate")
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return errors.Wrap(err, "gopenpgp: unable to write certificate")
+	}
+	return nil
+}
+
+// isFingerprint checks that s is a lower case hex v4 or v6 fingerprint.
+func isFingerprint(s string) bool {
+	return (len(s) == 40 || len(s) == 64) && isLowerHex(s)
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
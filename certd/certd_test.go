@@ -0,0 +1,102 @@
+package certd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "pgp.cert.d"))
+	if err != nil {
+		t.Fatal("Expected no error while creating store, got:", err)
+	}
+
+	privateKey, err := crypto.GenerateKey("Alice", "alice@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	fingerprint := privateKey.GetFingerprint()
+
+	_, err = store.Get(fingerprint)
+	assert.Exactly(t, ErrNotFound, err)
+	_, err = store.Get("not a fingerprint")
+	assert.Error(t, err)
+
+	stored, err := store.Insert(privateKey)
+	if err != nil {
+		t.Fatal("Expected no error while inserting key, got:", err)
+	}
+	assert.False(t, stored.IsPrivate())
+	assert.FileExists(t, filepath.Join(store.GetPath(), fingerprint[:2], fingerprint[2:]))
+
+	tag, err := store.GetTag(fingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while getting tag, got:", err)
+	}
+
+	// Inserting an update merges it with the stored certificate
+	if err = privateKey.GetEntity().RevokeKey(packet.KeyRetired, "", nil); err != nil {
+		t.Fatal("Expected no error while revoking key, got:", err)
+	}
+	if _, err = store.Insert(privateKey); err != nil {
+		t.Fatal("Expected no error while inserting key, got:", err)
+	}
+	fetched, err := store.Get(fingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while getting key, got:", err)
+	}
+	assert.True(t, fetched.IsRevoked())
+	newTag, err := store.GetTag(fingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while getting tag, got:", err)
+	}
+	assert.NotEqual(t, tag, newTag)
+
+	// Armored certificates written by other tools are accepted
+	otherKey, err := crypto.GenerateKey("Bob", "bob@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	armored, err := otherKey.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	otherFingerprint := otherKey.GetFingerprint()
+	assert.NoError(t, os.MkdirAll(filepath.Join(store.GetPath(), otherFingerprint[:2]), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(store.GetPath(), otherFingerprint[:2], otherFingerprint[2:]), []byte(armored), 0600))
+	fetched, err = store.Get(otherFingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while getting key, got:", err)
+	}
+	assert.Exactly(t, otherFingerprint, fetched.GetFingerprint())
+
+	_, err = store.GetTrustRoot()
+	assert.Exactly(t, ErrNotFound, err)
+	assert.NoError(t, store.SetTrustRoot(otherKey))
+	trustRoot, err := store.GetTrustRoot()
+	if err != nil {
+		t.Fatal("Expected no error while getting trust root, got:", err)
+	}
+	assert.True(t, trustRoot.IsPrivate())
+
+	fingerprints, err := store.GetFingerprints()
+	if err != nil {
+		t.Fatal("Expected no error while listing store, got:", err)
+	}
+	assert.ElementsMatch(t, []string{fingerprint, otherFingerprint}, fingerprints)
+}
+
+func TestGetDefaultPath(t *testing.T) {
+	defer os.Setenv(EnvironmentVariable, os.Getenv(EnvironmentVariable))
+
+	assert.NoError(t, os.Setenv(EnvironmentVariable, "/custom/pgp.cert.d"))
+	path, err := GetDefaultPath()
+	assert.NoError(t, err)
+	assert.Exactly(t, "/custom/pgp.cert.d", path)
+}
@@ -9,4 +9,18 @@ const (
 	PGPSignatureHeader = "PGP SIGNATURE"
 	PublicKeyHeader    = "PGP PUBLIC KEY BLOCK"
 	PrivateKeyHeader   = "PGP PRIVATE KEY BLOCK"
+	// PGPSignedMessageHeader starts a cleartext signed message, whose
+	// signature is armored with PGPSignatureHeader.
+	PGPSignedMessageHeader = "PGP SIGNED MESSAGE"
+)
+
+// Warnings returned by the lenient unarmoring of armored data copied through
+// channels altering it, see armor.UnarmorLenient.
+const (
+	ArmorWarningMissingChecksum = "missing checksum"
+	ArmorWarningInvalidChecksum = "invalid checksum"
+	ArmorWarningMissingEnd      = "missing armor end line"
+	ArmorWarningMissingBlank    = "missing blank line after the armor headers"
+	ArmorWarningWhitespace      = "unexpected whitespace"
+	ArmorWarningLineEndings     = "mixed line endings"
 )
@@ -0,0 +1,9 @@
+package constants
+
+// Operations of private keys reported to the auditor set with
+// crypto.SetKeyUsageAuditor.
+const (
+	KeyUsageSign    = "sign"
+	KeyUsageDecrypt = "decrypt"
+	KeyUsageCertify = "certify"
+)
@@ -0,0 +1,11 @@
+package constants
+
+// Canonicalization of text messages before signing and verification.
+const (
+	// TextCanonicalizationStrict converts the line endings to CRLF, as
+	// specified in RFC 4880, section 5.2.1. This is the default.
+	TextCanonicalizationStrict int = 0
+	// TextCanonicalizationTrimTrailingWhitespace also strips the trailing
+	// spaces and tabs of each line, like GnuPG in text mode.
+	TextCanonicalizationTrimTrailingWhitespace int = 1
+)
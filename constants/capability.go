@@ -0,0 +1,7 @@
+package constants
+
+// Key capabilities reported by crypto.KeyCapabilityError.
+const (
+	KeyCapabilityEncrypt = "encrypt"
+	KeyCapabilitySign    = "sign"
+)
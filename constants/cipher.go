@@ -10,6 +10,13 @@ const (
 	AES256    = "aes256"
 )
 
+// AEAD mode names.
+const (
+	AEADModeEAX = "eax"
+	AEADModeOCB = "ocb"
+	AEADModeGCM = "gcm"
+)
+
 const (
 	SIGNATURE_OK          int = 0
 	SIGNATURE_NOT_SIGNED  int = 1
@@ -0,0 +1,24 @@
+package constants
+
+// OpenPGP implementations checked by the compatibility reports, see
+// crypto.Key.GetCompatibilityReport. Thunderbird uses RNP.
+const (
+	ImplementationGnuPG22     = "GnuPG 2.2"
+	ImplementationGnuPG24     = "GnuPG 2.4"
+	ImplementationThunderbird = "Thunderbird 128"
+	ImplementationRNP         = "RNP 0.17"
+)
+
+// Features of keys and messages not supported by all the implementations,
+// reported by the compatibility reports.
+const (
+	CompatibilityFeatureV5Key         = "v5 key"
+	CompatibilityFeatureV6Key         = "v6 key"
+	CompatibilityFeatureCurve25519    = "X25519 or Ed25519 algorithm"
+	CompatibilityFeatureCurve448      = "X448 or Ed448 algorithm"
+	CompatibilityFeatureArgon2        = "Argon2 passphrase derivation"
+	CompatibilityFeatureAEADKey       = "AEAD secret key protection"
+	CompatibilityFeatureSHA1          = "SHA-1 self-signature"
+	CompatibilityFeatureSEIPDv2       = "SEIPDv2 encrypted data"
+	CompatibilityFeatureAEADEncrypted = "AEAD encrypted data packet"
+)
@@ -1,3 +1,22 @@
 package constants
 
 const SignatureContextName = "context@proton.ch"
+
+// OriginalSignatureTimeName is the name of the notation recording the
+// creation time of the signature replaced by a refreshed signature.
+const OriginalSignatureTimeName = "original-signature-time@proton.ch"
+
+// ChallengeNonceName is the name of the critical notation embedding the nonce
+// of a signed challenge.
+const ChallengeNonceName = "challenge-nonce@proton.ch"
+
+// LegacyVariantName is the name of the notation of the self-signatures of the
+// v6 key of a dual certificate, containing the fingerprint of its legacy v4
+// key.
+const LegacyVariantName = "legacy-variant@proton.ch"
+
+// V6VariantName is the name of the notation of the self-signatures of the
+// legacy v4 key of a dual certificate, containing the fingerprint of its v6
+// key. It cross-signs the LegacyVariantName notation, so that a v6 key can't
+// claim the v4 key of another certificate.
+const V6VariantName = "v6-variant@proton.ch"
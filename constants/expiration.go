@@ -0,0 +1,11 @@
+package constants
+
+// Components of keys reported by crypto.KeyRing.GetExpiringComponents.
+const (
+	// ExpiringComponentKey is the primary key, and the whole key with it.
+	ExpiringComponentKey = "key"
+	// ExpiringComponentSubkey is a subkey.
+	ExpiringComponentSubkey = "subkey"
+	// ExpiringComponentUserID is the self-certification of a user ID.
+	ExpiringComponentUserID = "user ID"
+)
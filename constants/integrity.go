@@ -0,0 +1,13 @@
+package constants
+
+// Reasons of the integrity warnings, see crypto.IntegrityWarning.
+const (
+	// IntegrityWarningMDCMissing is reported when the message has no
+	// integrity protection, or when its modification detection code was
+	// stripped.
+	IntegrityWarningMDCMissing = "message is not integrity protected"
+	// IntegrityWarningAEADDowngrade is reported when the message is only
+	// protected with a modification detection code, while the decryption key
+	// advertises support for SEIPDv2.
+	IntegrityWarningAEADDowngrade = "message is not AEAD protected although the decryption key supports it"
+)
@@ -0,0 +1,7 @@
+package constants
+
+// JSONSchemaVersion is the version of the JSON schema of the serialized
+// crypto.KeyInfo and crypto.VerificationResult. It is incremented on
+// incompatible changes, i.e. when a field is removed, renamed or changes
+// meaning; new fields can be added without changing the version.
+const JSONSchemaVersion int = 1
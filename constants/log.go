@@ -0,0 +1,21 @@
+package constants
+
+// Severities of the events sent to the logger set with crypto.SetLogger.
+const (
+	LOG_LEVEL_DEBUG   int = 0
+	LOG_LEVEL_INFO    int = 1
+	LOG_LEVEL_WARNING int = 2
+	LOG_LEVEL_ERROR   int = 3
+)
+
+// Names of the events sent to the logger set with crypto.SetLogger.
+const (
+	// LogEventAlgorithmSelected reports the algorithms configured for an operation.
+	LogEventAlgorithmSelected = "algorithm.selected"
+	// LogEventKeySelected reports a key selected to encrypt or sign.
+	LogEventKeySelected = "key.selected"
+	// LogEventPacketParsed reports the packets found in a decrypted message.
+	LogEventPacketParsed = "packet.parsed"
+	// LogEventSignatureChecked reports the result of a signature verification.
+	LogEventSignatureChecked = "signature.checked"
+)
@@ -0,0 +1,23 @@
+package constants
+
+// Names of the operations reported to the metrics set with crypto.SetMetrics.
+const (
+	MetricsOperationEncrypt = "encrypt"
+	MetricsOperationDecrypt = "decrypt"
+	MetricsOperationSign    = "sign"
+	MetricsOperationVerify  = "verify"
+)
+
+// Classes of the failures reported to the metrics set with crypto.SetMetrics.
+const (
+	// MetricsErrorSignature is a signature verification failure.
+	MetricsErrorSignature = "signature"
+	// MetricsErrorNoKey is a message that can't be decrypted with the keys.
+	MetricsErrorNoKey = "no key"
+	// MetricsErrorCorrupt is a malformed message, key or signature.
+	MetricsErrorCorrupt = "corrupt"
+	// MetricsErrorUnsupported is an unsupported algorithm or feature.
+	MetricsErrorUnsupported = "unsupported"
+	// MetricsErrorOther is any other failure.
+	MetricsErrorOther = "other"
+)
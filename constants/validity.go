@@ -0,0 +1,34 @@
+package constants
+
+// Validity of keys reported by crypto.Key.GetValidityAt.
+const (
+	// KeyValidityValid is a key without any problem.
+	KeyValidityValid = "valid"
+	// KeyValidityRestricted is a key that can be used, but some of its
+	// subkeys or user IDs can't, or that uses weak algorithms.
+	KeyValidityRestricted = "restricted"
+	// KeyValidityInvalid is a key that must not be used.
+	KeyValidityInvalid = "invalid"
+)
+
+// Reasons of the validity of keys reported by crypto.Key.GetValidityAt.
+const (
+	// KeyValidityReasonNotYetValid is a component created, or bound to the
+	// key, after the evaluation time.
+	KeyValidityReasonNotYetValid = "not yet valid"
+	// KeyValidityReasonExpired is an expired component.
+	KeyValidityReasonExpired = "expired"
+	// KeyValidityReasonRevoked is a revoked component.
+	KeyValidityReasonRevoked = "revoked"
+	// KeyValidityReasonInvalidBinding is a component whose self-signature
+	// is missing or doesn't verify.
+	KeyValidityReasonInvalidBinding = "invalid binding signature"
+	// KeyValidityReasonInsecureAlgorithm is a key using an algorithm
+	// deprecated by RFC 9580, DSA or ElGamal.
+	KeyValidityReasonInsecureAlgorithm = "insecure algorithm"
+	// KeyValidityReasonWeakKey is an RSA key smaller than 2048 bits.
+	KeyValidityReasonWeakKey = "weak key"
+	// KeyValidityReasonWeakHash is a self-signature made with a hash
+	// algorithm that is not collision resistant, e.g. SHA-1.
+	KeyValidityReasonWeakHash = "weak hash"
+)
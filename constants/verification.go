@@ -0,0 +1,13 @@
+package constants
+
+// Summaries of the verification of the signatures of a message, see
+// crypto.VerificationSummary. They are ordered from the best to the worst.
+const (
+	VERIFICATION_VALID                    int = 0
+	VERIFICATION_VALID_INSECURE_ALGORITHM int = 1
+	VERIFICATION_EXPIRED                  int = 2
+	VERIFICATION_REVOKED                  int = 3
+	VERIFICATION_NO_MATCHING_KEY          int = 4
+	VERIFICATION_NOT_SIGNED               int = 5
+	VERIFICATION_INVALID                  int = 6
+)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/pkg/errors"
 )
 
@@ -83,10 +84,18 @@ func (keyRing *KeyRing) newAttachmentProcessor(
 		ModTime:  time.Unix(int64(modTime), 0),
 	}
 
+	recipients, err := keyRing.getRecipients(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptionTime := newEncryptionTime(recipients.entities)
 	config := &packet.Config{
+		Rand:          getRandomSource(),
 		DefaultCipher: packet.CipherAES256,
-		Time:          getTimeGenerator(),
+		Time:          encryptionTime.now,
 	}
+	applyConfigModifier(config)
 
 	reader, writer := io.Pipe()
 
@@ -105,10 +114,12 @@ func (keyRing *KeyRing) newAttachmentProcessor(
 
 	var ew io.WriteCloser
 	var encryptErr error
-	ew, encryptErr = openpgp.Encrypt(writer, keyRing.entities, nil, hints, config)
+	ew, encryptErr = openpgp.Encrypt(writer, recipients.entities, nil, hints, config)
+	encryptionTime.keysSelected()
 	if encryptErr != nil {
 		return nil, errors.Wrap(encryptErr, "gopengpp: unable to encrypt attachment")
 	}
+	logEncryption("encrypt attachment", config, recipients, nil)
 	attachmentProc.w = &ew
 	attachmentProc.pipe = writer
 
@@ -161,24 +172,29 @@ func (keyRing *KeyRing) DecryptAttachment(message *PGPSplitMessage) (*PlainMessa
 	keyReader := bytes.NewReader(message.GetBinaryKeyPacket())
 	dataReader := bytes.NewReader(message.GetBinaryDataPacket())
 
-	encryptedReader := io.MultiReader(keyReader, dataReader)
+	encryptedReader, decompressionLimiter := newDecompressionLimiter(io.MultiReader(keyReader, dataReader))
 
 	config := &packet.Config{Time: getTimeGenerator()}
+	applyConfigModifier(config)
 
 	md, err := openpgp.ReadMessage(encryptedReader, privKeyEntries, nil, config)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopengpp: unable to read attachment")
+		logEvent(constants.LOG_LEVEL_ERROR, constants.LogEventPacketParsed, "operation", "decrypt attachment", "error", err.Error())
+		return nil, newReadError(err, "gopengpp: unable to read attachment")
 	}
+	logMessageDetails("decrypt attachment", md)
+	auditDecryption("decrypt attachment", md.DecryptedWith)
 
-	decrypted := md.UnverifiedBody
+	decrypted := decompressionLimiter.limitReader(md.UnverifiedBody)
 	b, err := ioutil.ReadAll(decrypted)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopengpp: unable to read attachment body")
+		return nil, newReadError(err, "gopengpp: unable to read attachment body")
 	}
 
 	return &PlainMessage{
 		Data:     b,
 		TextType: !md.LiteralData.IsBinary,
+		IsUTF8:   md.LiteralData.Format == 'u',
 		Filename: md.LiteralData.FileName,
 		Time:     md.LiteralData.Time,
 	}, nil
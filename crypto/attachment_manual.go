@@ -92,11 +92,19 @@ func (keyRing *KeyRing) NewManualAttachmentProcessor(
 		ModTime:  time.Unix(modTime, 0),
 	}
 
+	recipients, err := keyRing.getRecipients(nil)
+	if err != nil {
+		return nil, err
+	}
+
 	// encryption config
+	encryptionTime := newEncryptionTime(recipients.entities)
 	config := &packet.Config{
+		Rand:          getRandomSource(),
 		DefaultCipher: packet.CipherAES256,
-		Time:          getTimeGenerator(),
+		Time:          encryptionTime.now,
 	}
+	applyConfigModifier(config)
 
 	// goroutine that reads the key packet
 	// to be later returned to the caller via GetKeyPacket()
@@ -128,10 +136,12 @@ func (keyRing *KeyRing) NewManualAttachmentProcessor(
 	// We generate the encrypting writer
 	var ew io.WriteCloser
 	var encryptErr error
-	ew, encryptErr = openpgp.EncryptSplit(keyWriter, dataWriter, keyRing.entities, nil, hints, config)
+	ew, encryptErr = openpgp.EncryptSplit(keyWriter, dataWriter, recipients.entities, nil, hints, config)
+	encryptionTime.keysSelected()
 	if encryptErr != nil {
 		return nil, errors.Wrap(encryptErr, "gopengpp: unable to encrypt attachment")
 	}
+	logEncryption("encrypt attachment", config, recipients, nil)
 
 	attachmentProc.plaintextWriter = ew
 	attachmentProc.ciphertextWriter = dataWriter
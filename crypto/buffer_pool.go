@@ -0,0 +1,45 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// chunkPool recycles the chunk buffers of the encryption writers, so that
+// encrypting many small messages doesn't allocate a chunk per message.
+var chunkPool = sync.Pool{
+	New: func() interface{} {
+		chunk := make([]byte, 0, encryptionChunkSize)
+		return &chunk
+	},
+}
+
+func getChunk() []byte {
+	return (*chunkPool.Get().(*[]byte))[:0]
+}
+
+// putChunk wipes the plaintext left in a chunk, then recycles it if it comes
+// from the pool.
+func putChunk(chunk []byte) {
+	clearMem(chunk[:cap(chunk)])
+	if cap(chunk) != encryptionChunkSize {
+		return
+	}
+	chunk = chunk[:0]
+	chunkPool.Put(&chunk)
+}
+
+// readAllWithSizeHint reads reader until EOF like ioutil.ReadAll, but
+// allocates the expected size upfront, instead of growing the buffer
+// from 512 bytes. The hint is taken from the length of the encrypted
+// input, if known, which bounds the size of uncompressed messages.
+func readAllWithSizeHint(reader io.Reader, input io.Reader) ([]byte, error) {
+	var buffer bytes.Buffer
+	if sized, ok := input.(interface{ Len() int }); ok {
+		// One extra byte lets ReadFrom find EOF without growing the buffer
+		buffer.Grow(sized.Len() + 1)
+	}
+	_, err := buffer.ReadFrom(reader)
+	return buffer.Bytes(), err
+}
@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPooledChunksConcurrentEncryption(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := bytes.Repeat([]byte{byte(i)}, encryptionChunkSize+i)
+			ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessage(data), nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+			decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+			if assert.NoError(t, err) {
+				assert.Exactly(t, data, decrypted.GetBinary())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestChunkedWriteCloserWriteAfterClose(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := keyRingTestPublic.EncryptStream(&buffer, nil, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = writer.Write([]byte("hello")); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+	_, err = writer.Write([]byte("world"))
+	assert.Error(t, err)
+}
+
+func TestPutChunkWipesPlaintext(t *testing.T) {
+	chunk := append(getChunk(), "plain text"...)
+	putChunk(chunk)
+	assert.Exactly(t, make([]byte, len("plain text")), chunk)
+}
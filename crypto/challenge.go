@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// SignChallenge signs a nonce issued by a server to authenticate the owner of
+// the keyring, see VerifyChallenge. The detached signature covers the nonce,
+// which is also embedded in a critical notation named
+// constants.ChallengeNonceName, so that verifiers unaware of challenges reject
+// the signature instead of accepting it as a signature of arbitrary data.
+func (keyRing *KeyRing) SignChallenge(nonce []byte) (*PGPSignature, error) {
+	if len(nonce) == 0 {
+		return nil, errors.New("gopenpgp: empty challenge nonce")
+	}
+	notations := []*packet.Notation{{
+		Name:       constants.ChallengeNonceName,
+		Value:      nonce,
+		IsCritical: true,
+	}}
+	return signMessageDetached(keyRing, bytes.NewReader(nonce), true, notations)
+}
+
+// VerifyChallenge verifies a signed challenge, see SignChallenge: the
+// signature must be made by a valid key of the keyring over the issued
+// nonce, embed the same nonce in its notation, and be created at most maxAge
+// seconds before verifyTime, or GetUnixTime if verifyTime is 0.
+// The server must still only accept each nonce once.
+// Returns a SignatureVerificationError if the challenge is not valid.
+func (keyRing *KeyRing) VerifyChallenge(nonce []byte, signature *PGPSignature, verifyTime, maxAge int64) error {
+	if len(nonce) == 0 {
+		return errors.New("gopenpgp: empty challenge nonce")
+	}
+	if maxAge <= 0 {
+		return errors.New("gopenpgp: the maximum age of the challenge must be positive")
+	}
+	if verifyTime == 0 {
+		verifyTime = GetUnixTime()
+	}
+	sig, err := verifyChallengeSignature(keyRing, nonce, signature, verifyTime, maxAge)
+	var keyID uint64
+	if sig != nil && sig.IssuerKeyId != nil {
+		keyID = *sig.IssuerKeyId
+	}
+	logSignatureChecked("verify challenge", keyID, err)
+	return err
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// verifyChallengeSignature verifies the signature of a challenge, its nonce
+// notation and its creation time.
+func verifyChallengeSignature(
+	keyRing *KeyRing,
+	nonce []byte,
+	signature *PGPSignature,
+	verifyTime, maxAge int64,
+) (*packet.Signature, error) {
+	if err := keyRing.getVerificationLimits().checkDetachedSignature(signature.GetBinary()); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading signature")
+	}
+	config := &packet.Config{
+		Time: func() time.Time {
+			return time.Unix(verifyTime+GetTimeOffsetTolerance(), 0)
+		},
+		KnownNotations: map[string]bool{constants.ChallengeNonceName: true},
+	}
+	applyConfigModifier(config)
+	sig, signer, err := openpgp.VerifyDetachedSignatureAndHash(
+		keyRing.entities,
+		bytes.NewReader(nonce),
+		bytes.NewReader(signature.GetBinary()),
+		allowedHashes,
+		config,
+	)
+	if err != nil {
+		return sig, newSignatureFailed(err)
+	}
+	if sig == nil || signer == nil {
+		return sig, newSignatureFailed(errors.New("gopenpgp: no signer or valid signature"))
+	}
+	var signedNonce []byte
+	for _, notation := range sig.Notations {
+		if notation.Name != constants.ChallengeNonceName {
+			continue
+		}
+		if signedNonce != nil {
+			return sig, newSignatureFailed(errors.New("gopenpgp: signature has multiple challenge nonces"))
+		}
+		if !notation.IsCritical {
+			return sig, newSignatureFailed(errors.New("gopenpgp: challenge nonce notation was not set as critical"))
+		}
+		signedNonce = notation.Value
+	}
+	if signedNonce == nil {
+		return sig, newSignatureFailed(errors.New("gopenpgp: signature has no challenge nonce"))
+	}
+	if subtle.ConstantTimeCompare(signedNonce, nonce) != 1 {
+		return sig, newSignatureFailed(errors.New("gopenpgp: challenge nonce mismatch"))
+	}
+
+	created := sig.CreationTime.Unix()
+	if created < verifyTime-maxAge {
+		return sig, newSignatureFailed(errors.New("gopenpgp: challenge signature is too old"))
+	}
+	if created > verifyTime+GetTimeOffsetTolerance() {
+		return sig, newSignatureFailed(errors.New("gopenpgp: challenge signature is created in the future"))
+	}
+	return sig, keyRing.checkVerifyTimeWindow(sig)
+}
@@ -0,0 +1,46 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignChallenge(t *testing.T) {
+	nonce := []byte("server nonce 0123456789")
+	signature, err := keyRingTestPrivate.SignChallenge(nonce)
+	if err != nil {
+		t.Fatal("Expected no error while signing challenge, got:", err)
+	}
+	now := GetUnixTime()
+	assert.NoError(t, keyRingTestPublic.VerifyChallenge(nonce, signature, now, 60))
+
+	err = keyRingTestPublic.VerifyChallenge([]byte("other nonce"), signature, now, 60)
+	checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+	err = keyRingTestPublic.VerifyChallenge(nonce, signature, now+61, 60)
+	checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+
+	// The critical notation is unknown to regular verification
+	err = keyRingTestPublic.VerifyDetached(NewPlainMessage(nonce), signature, now)
+	checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+
+	// A signature of the nonce alone isn't a challenge signature
+	plainSignature, err := keyRingTestPrivate.SignDetached(NewPlainMessage(nonce))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	err = keyRingTestPublic.VerifyChallenge(nonce, plainSignature, now, 60)
+	checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	err = otherKeyRing.VerifyChallenge(nonce, signature, now, 60)
+	assert.Error(t, err)
+
+	_, err = keyRingTestPrivate.SignChallenge(nil)
+	assert.Error(t, err)
+	assert.Error(t, keyRingTestPublic.VerifyChallenge(nonce, signature, now, 0))
+}
@@ -0,0 +1,242 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+	"github.com/pkg/errors"
+)
+
+const (
+	clearTextBeginLine = "-----BEGIN " + constants.PGPSignedMessageHeader + "-----"
+	clearTextEndLine   = "-----BEGIN " + constants.PGPSignatureHeader + "-----"
+)
+
+// VerifyCleartextStream verifies a cleartext signed message read from
+// signedMessage, like NewClearTextMessageFromArmored and VerifyDetached, but
+// without holding the message in memory, e.g. for clearsigned files of
+// hundreds of megabytes.
+// The signature follows the text, so signedMessage is read twice: once to
+// find the signature, and once to verify the text, dash-unescaped line by
+// line.
+// If output is not nil, the text is written to it as it is verified, with
+// CRLF line endings and without the trailing line ending, as returned by
+// ClearTextMessage.GetBinary. It must not be trusted before
+// VerifyCleartextStream returns without error.
+func (keyRing *KeyRing) VerifyCleartextStream(signedMessage io.ReadSeeker, output Writer, verifyTime int64) error {
+	start, end, err := findClearTextBody(signedMessage)
+	if err != nil {
+		return err
+	}
+	signature, err := readClearTextSignature(signedMessage, end)
+	if err != nil {
+		return err
+	}
+	body := &clearTextBodyReader{source: signedMessage, start: start, length: end - start, output: output}
+	if err := body.reset(); err != nil {
+		return err
+	}
+	sig, err := verifySignature(keyRing.entities, keyRing.verificationLimits, body, signature, verifyTime, nil)
+	if err != nil {
+		return err
+	}
+	return keyRing.checkVerifyTimeWindow(sig)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// findClearTextBody returns the offsets of the start of the text of the
+// cleartext signed message, and of the start of its signature.
+func findClearTextBody(signedMessage io.ReadSeeker) (start, end int64, err error) {
+	if _, err := signedMessage.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+	}
+	scanner := &lineScanner{reader: bufio.NewReader(signedMessage)}
+
+	// Skip the leading garbage
+	for {
+		line, _, err := scanner.next()
+		if errors.Is(err, io.EOF) {
+			return 0, 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: no cleartext signed message found", nil)
+		} else if err != nil {
+			return 0, 0, errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+		}
+		if string(bytes.TrimRight(line, " \t")) == clearTextBeginLine {
+			break
+		}
+	}
+
+	// Only Hash headers are allowed, up to the blank line
+	for {
+		line, _, err := scanner.next()
+		if err != nil {
+			return 0, 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated cleartext message", err)
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		colon := bytes.IndexByte(line, ':')
+		if colon == -1 || string(bytes.TrimSpace(line[:colon])) != "Hash" {
+			return 0, 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: invalid cleartext message header", nil)
+		}
+	}
+
+	start = scanner.offset
+	for {
+		line, offset, err := scanner.next()
+		if err != nil {
+			return 0, 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: missing cleartext message signature", err)
+		}
+		if string(line) == clearTextEndLine {
+			return start, offset, nil
+		}
+	}
+}
+
+// readClearTextSignature reads the armored signature at the given offset.
+func readClearTextSignature(signedMessage io.ReadSeeker, offset int64) ([]byte, error) {
+	if _, err := signedMessage.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+	}
+	block, err := armor.Decode(signedMessage)
+	if err != nil {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: error in reading cleartext message signature", err)
+	}
+	if err := internal.CheckArmorType(block.Type, constants.PGPSignatureHeader); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading cleartext message signature")
+	}
+	signature, err := ioutil.ReadAll(block.Body)
+	if err != nil {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: error in reading cleartext message signature", err)
+	}
+	return signature, nil
+}
+
+// lineScanner reads the lines of an input, keeping track of their offsets.
+// Only the beginning of long lines is returned.
+type lineScanner struct {
+	reader *bufio.Reader
+	offset int64
+}
+
+// next returns the next line without its line ending, and its offset.
+func (s *lineScanner) next() (line []byte, offset int64, err error) {
+	offset = s.offset
+	chunk, err := s.reader.ReadSlice('\n')
+	s.offset += int64(len(chunk))
+	line = append(line, chunk...)
+	for errors.Is(err, bufio.ErrBufferFull) {
+		chunk, err = s.reader.ReadSlice('\n')
+		s.offset += int64(len(chunk))
+	}
+	if errors.Is(err, io.EOF) && len(line) > 0 {
+		err = nil
+	}
+	return bytes.TrimRight(line, "\r\n"), offset, err
+}
+
+// clearTextBodyReader reads the text of a cleartext signed message from its
+// source, dash-unescaping it and trimming the trailing whitespace of each
+// line, as signed.
+type clearTextBodyReader struct {
+	source io.ReadSeeker
+	start  int64
+	length int64
+	output Writer
+
+	reader *bufio.Reader
+	// pending is the processed text not read yet
+	pending []byte
+	// whitespace is held back until the rest of the line is known
+	whitespace []byte
+	lines      int
+	lineStart  bool
+	eof        bool
+}
+
+func (r *clearTextBodyReader) Read(b []byte) (int, error) {
+	for len(r.pending) == 0 && !r.eof {
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+	if len(r.pending) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Seek only supports rewinding to the start of the text, for verifySignature.
+// The text isn't written to the output again.
+func (r *clearTextBodyReader) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, errors.New("gopenpgp: cleartext message can only be rewound")
+	}
+	r.output = nil
+	return 0, r.reset()
+}
+
+func (r *clearTextBodyReader) reset() error {
+	if _, err := r.source.Seek(r.start, io.SeekStart); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+	}
+	r.reader = bufio.NewReader(io.LimitReader(r.source, r.length))
+	r.pending, r.whitespace = nil, nil
+	r.lines, r.lineStart, r.eof = 0, true, false
+	return nil
+}
+
+// fill processes the next chunk of the source.
+func (r *clearTextBodyReader) fill() error {
+	chunk, err := r.reader.ReadSlice('\n')
+	if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+		if !errors.Is(err, io.EOF) {
+			return errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+		}
+		r.eof = true
+	}
+	if len(chunk) == 0 {
+		return nil
+	}
+
+	var processed []byte
+	lineEnd := chunk[len(chunk)-1] == '\n'
+	if lineEnd {
+		chunk = chunk[:len(chunk)-1]
+	}
+	if r.lineStart {
+		// The line ending before the signature isn't part of the text
+		if r.lines > 0 {
+			processed = append(processed, '\r', '\n')
+		}
+		r.lines++
+		chunk = bytes.TrimPrefix(chunk, []byte("- "))
+	}
+	trimmed := bytes.TrimRight(chunk, " \t\r")
+	if len(trimmed) > 0 {
+		processed = append(processed, r.whitespace...)
+		processed = append(processed, trimmed...)
+		r.whitespace = nil
+	}
+	r.whitespace = append(r.whitespace, chunk[len(trimmed):]...)
+	if lineEnd {
+		r.whitespace = nil
+	}
+	r.lineStart = lineEnd
+
+	if r.output != nil {
+		if _, err := r.output.Write(processed); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in writing cleartext message")
+		}
+	}
+	r.pending = append(r.pending, processed...)
+	return nil
+}
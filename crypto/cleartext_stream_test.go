@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func clearSignForTest(t *testing.T, text string) []byte {
+	var signed bytes.Buffer
+	writer, err := clearsign.Encode(&signed, keyRingTestPrivate.entities[0].PrivateKey, &packet.Config{Time: getTimeGenerator()}, nil)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	if _, err := writer.Write([]byte(text)); err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	return signed.Bytes()
+}
+
+func TestVerifyCleartextStream(t *testing.T) {
+	text := "Origin: Debian\n- a dash-escaped line\nlong line " + strings.Repeat("a", 10000) + "  \nlast line\t\n"
+	signed := clearSignForTest(t, text)
+
+	clearTextMessage, err := NewClearTextMessageFromArmored(string(signed))
+	if err != nil {
+		t.Fatal("Expected no error while reading cleartext message, got:", err)
+	}
+	var output bytes.Buffer
+	if err := keyRingTestPublic.VerifyCleartextStream(bytes.NewReader(signed), &output, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying cleartext message, got:", err)
+	}
+	assert.Exactly(t, clearTextMessage.GetBinary(), output.Bytes())
+
+	// Garbage around the message is ignored
+	garbage := append(append([]byte("garbage\n"), signed...), "\ngarbage\n"...)
+	assert.NoError(t, keyRingTestPublic.VerifyCleartextStream(bytes.NewReader(garbage), nil, GetUnixTime()))
+
+	tampered := bytes.Replace(signed, []byte("last line"), []byte("last lane"), 1)
+	err = keyRingTestPublic.VerifyCleartextStream(bytes.NewReader(tampered), nil, GetUnixTime())
+	assert.IsType(t, SignatureVerificationError{}, err)
+
+	truncated := signed[:bytes.Index(signed, []byte(clearTextEndLine))]
+	err = keyRingTestPublic.VerifyCleartextStream(bytes.NewReader(truncated), nil, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrMessageCorrupt))
+	err = keyRingTestPublic.VerifyCleartextStream(strings.NewReader(text), nil, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrMessageCorrupt))
+}
@@ -0,0 +1,268 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+// s2kUsageAEAD is the S2K usage octet of secret keys protected with AEAD.
+const s2kUsageAEAD = 253
+
+// rnpSHA1Cutoff is the date after which RNP rejects SHA-1 key signatures.
+var rnpSHA1Cutoff = time.Date(2024, time.January, 19, 0, 0, 0, 0, time.UTC)
+
+// unsupportedFeatures lists the implementations which don't support each
+// feature, as of their version in constants.Implementation*.
+var unsupportedFeatures = map[string][]string{
+	constants.CompatibilityFeatureV5Key: {
+		constants.ImplementationGnuPG22, constants.ImplementationThunderbird, constants.ImplementationRNP,
+	},
+	constants.CompatibilityFeatureV6Key:         allImplementations,
+	constants.CompatibilityFeatureCurve25519:    allImplementations,
+	constants.CompatibilityFeatureCurve448:      allImplementations,
+	constants.CompatibilityFeatureArgon2:        allImplementations,
+	constants.CompatibilityFeatureAEADKey:       allImplementations,
+	constants.CompatibilityFeatureSEIPDv2:       allImplementations,
+	constants.CompatibilityFeatureAEADEncrypted: {constants.ImplementationGnuPG22},
+	constants.CompatibilityFeatureSHA1:          {constants.ImplementationThunderbird, constants.ImplementationRNP},
+}
+
+var allImplementations = []string{
+	constants.ImplementationGnuPG22,
+	constants.ImplementationGnuPG24,
+	constants.ImplementationThunderbird,
+	constants.ImplementationRNP,
+}
+
+// CompatibilityReport lists the features of a key or message that other
+// OpenPGP implementations don't support, e.g. to avoid encrypting to a v6
+// key a message that its owner can only read with GnuPG.
+// The report only covers the features introduced by RFC 9580 and LibrePGP,
+// and the hash algorithms rejected by policy, for the versions of
+// constants.Implementation*.
+type CompatibilityReport struct {
+	// Issues are the features not supported, for each implementation.
+	Issues []*CompatibilityIssue
+}
+
+// CompatibilityIssue is a feature not supported by an implementation.
+type CompatibilityIssue struct {
+	// Implementation is the implementation, one of constants.Implementation*.
+	Implementation string
+	// Feature is the feature, one of constants.CompatibilityFeature*.
+	Feature string
+	// Fingerprint is the hex fingerprint of the primary key or subkey using
+	// the feature, empty for messages.
+	Fingerprint string
+}
+
+// IsCompatible returns true if the implementation, one of
+// constants.Implementation*, supports all the features of the key or message.
+func (report *CompatibilityReport) IsCompatible(implementation string) bool {
+	for _, issue := range report.Issues {
+		if issue.Implementation == implementation {
+			return false
+		}
+	}
+	return true
+}
+
+// GetCompatibilityReport returns the features of the key, its subkeys and
+// its secret key protection that other implementations don't support.
+func (key *Key) GetCompatibilityReport() *CompatibilityReport {
+	report := &CompatibilityReport{}
+	entity := key.entity
+	publicKeys := []*packet.PublicKey{entity.PrimaryKey}
+	privateKeys := []*packet.PrivateKey{entity.PrivateKey}
+	selfSignatures := []*packet.Signature{entity.SelfSignature}
+	for _, identity := range entity.Identities {
+		selfSignatures = append(selfSignatures, identity.SelfSignature)
+	}
+	for _, subkey := range entity.Subkeys {
+		publicKeys = append(publicKeys, subkey.PublicKey)
+		privateKeys = append(privateKeys, subkey.PrivateKey)
+		selfSignatures = append(selfSignatures, subkey.Sig)
+	}
+
+	primaryFingerprint := hex.EncodeToString(entity.PrimaryKey.Fingerprint)
+	switch entity.PrimaryKey.Version {
+	case 5:
+		report.add(constants.CompatibilityFeatureV5Key, primaryFingerprint)
+	case 6:
+		report.add(constants.CompatibilityFeatureV6Key, primaryFingerprint)
+	}
+	for i, publicKey := range publicKeys {
+		fingerprint := hex.EncodeToString(publicKey.Fingerprint)
+		switch publicKey.PubKeyAlgo {
+		case packet.PubKeyAlgoX25519, packet.PubKeyAlgoEd25519:
+			report.add(constants.CompatibilityFeatureCurve25519, fingerprint)
+		case packet.PubKeyAlgoX448, packet.PubKeyAlgoEd448:
+			report.add(constants.CompatibilityFeatureCurve448, fingerprint)
+		}
+		if privateKeys[i] != nil {
+			usage, s2kMode := getSecretKeyProtection(privateKeys[i])
+			if usage == s2kUsageAEAD {
+				report.add(constants.CompatibilityFeatureAEADKey, fingerprint)
+			}
+			if s2kMode == s2k.Argon2S2K {
+				report.add(constants.CompatibilityFeatureArgon2, fingerprint)
+			}
+		}
+	}
+	for _, signature := range selfSignatures {
+		if signature != nil && signature.Hash == crypto.SHA1 && signature.CreationTime.After(rnpSHA1Cutoff) {
+			report.add(constants.CompatibilityFeatureSHA1, primaryFingerprint)
+		}
+	}
+	return report
+}
+
+// GetCompatibilityReport returns the features of the encrypted message that
+// other implementations don't support. Only the key packets and the
+// encrypted data packet are read, by their headers.
+func (msg *PGPMessage) GetCompatibilityReport() (*CompatibilityReport, error) {
+	report := &CompatibilityReport{}
+	for offset := 0; offset < len(msg.Data); {
+		tag, next, err := nextPacketOffset(msg.Data, offset)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case packetTagSEIPD:
+			if version, ok := getPacketVersion(msg.Data, offset); ok && version == 2 {
+				report.add(constants.CompatibilityFeatureSEIPDv2, "")
+			}
+			return report, nil
+		case packetTagAEADEncrypted:
+			report.add(constants.CompatibilityFeatureAEADEncrypted, "")
+			return report, nil
+		case packetTagSymmetricallyEncrypted, packetTagCompressed, packetTagLiteralData:
+			return report, nil
+		}
+
+		body, err := getPacketBody(msg.Data, offset, next)
+		if err != nil {
+			return nil, err
+		}
+		offset = next
+		if len(body) == 0 {
+			continue
+		}
+		switch tag {
+		case packetTagEncryptedKey:
+			// The algorithm follows the version and the key ID of v3 packets,
+			// and the version, and the length and the key version and
+			// fingerprint of v6 packets
+			algoOffset := 9
+			if body[0] == 6 && len(body) > 1 {
+				algoOffset = 2 + int(body[1])
+			}
+			if len(body) > algoOffset {
+				switch packet.PublicKeyAlgorithm(body[algoOffset]) {
+				case packet.PubKeyAlgoX25519:
+					report.add(constants.CompatibilityFeatureCurve25519, "")
+				case packet.PubKeyAlgoX448:
+					report.add(constants.CompatibilityFeatureCurve448, "")
+				}
+			}
+		case packetTagSymmetricKeyEncrypted:
+			// The S2K mode follows the version and the cipher of v4 packets,
+			// and the count, cipher, AEAD mode and S2K length of v6 packets
+			s2kOffset := 2
+			if body[0] == 6 {
+				s2kOffset = 5
+			}
+			if len(body) > s2kOffset && s2k.Mode(body[s2kOffset]) == s2k.Argon2S2K {
+				report.add(constants.CompatibilityFeatureArgon2, "")
+			}
+		}
+	}
+	return report, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// add adds an issue for each implementation not supporting the feature,
+// unless it is already reported.
+func (report *CompatibilityReport) add(feature, fingerprint string) {
+	for _, implementation := range unsupportedFeatures[feature] {
+		found := false
+		for _, issue := range report.Issues {
+			if issue.Implementation == implementation && issue.Feature == feature && issue.Fingerprint == fingerprint {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report.Issues = append(report.Issues, &CompatibilityIssue{
+				Implementation: implementation,
+				Feature:        feature,
+				Fingerprint:    fingerprint,
+			})
+		}
+	}
+}
+
+// getPacketVersion returns the first octet of the body of the packet at
+// offset, i.e. its version for most packets, also when the packet is split
+// in partial bodies.
+func getPacketVersion(data []byte, offset int) (version byte, ok bool) {
+	_, _, _, headerSize, ok, err := parsePacketHeader(data[offset:])
+	if err != nil || !ok || offset+headerSize >= len(data) {
+		return 0, false
+	}
+	return data[offset+headerSize], true
+}
+
+// getSecretKeyProtection returns the S2K usage octet and the S2K mode of a
+// secret key, read from its serialization, or 0 if it isn't protected.
+func getSecretKeyProtection(privateKey *packet.PrivateKey) (usage byte, mode s2k.Mode) {
+	var public, private bytes.Buffer
+	if privateKey.PublicKey.Serialize(&public) != nil || privateKey.Serialize(&private) != nil {
+		return 0, 0
+	}
+	_, publicEnd, err := nextPacketOffset(public.Bytes(), 0)
+	if err != nil {
+		return 0, 0
+	}
+	publicBody, err := getPacketBody(public.Bytes(), 0, publicEnd)
+	if err != nil {
+		return 0, 0
+	}
+	_, privateEnd, err := nextPacketOffset(private.Bytes(), 0)
+	if err != nil {
+		return 0, 0
+	}
+	privateBody, err := getPacketBody(private.Bytes(), 0, privateEnd)
+	if err != nil || len(privateBody) <= len(publicBody) {
+		return 0, 0
+	}
+
+	// The usage octet, the count of the following fields of v6 keys, the
+	// cipher, the AEAD mode and the S2K length of v6 keys, and the S2K mode
+	secret := privateBody[len(publicBody):]
+	usage = secret[0]
+	if usage != s2kUsageAEAD && usage != 254 && usage != 255 {
+		return usage, 0
+	}
+	offset := 2
+	if privateKey.Version == 6 {
+		offset++
+	}
+	if usage == s2kUsageAEAD {
+		offset++
+	}
+	if privateKey.Version == 6 {
+		offset++
+	}
+	if len(secret) <= offset {
+		return usage, 0
+	}
+	return usage, s2k.Mode(secret[offset])
+}
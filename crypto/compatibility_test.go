@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func hasCompatibilityIssue(report *CompatibilityReport, implementation, feature string) bool {
+	for _, issue := range report.Issues {
+		if issue.Implementation == implementation && issue.Feature == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func TestKeyCompatibilityReport(t *testing.T) {
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	report := key.GetCompatibilityReport()
+	assert.Empty(t, report.Issues)
+	assert.True(t, report.IsCompatible(constants.ImplementationGnuPG22))
+
+	locked, err := key.LockWithAEAD(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	report = locked.GetCompatibilityReport()
+	assert.True(t, hasCompatibilityIssue(report, constants.ImplementationGnuPG24, constants.CompatibilityFeatureArgon2))
+	assert.True(t, hasCompatibilityIssue(report, constants.ImplementationRNP, constants.CompatibilityFeatureAEADKey))
+
+	locked, err = key.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	assert.Empty(t, locked.GetCompatibilityReport().Issues)
+
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	report = keyV6.GetCompatibilityReport()
+	assert.False(t, report.IsCompatible(constants.ImplementationGnuPG24))
+	assert.False(t, report.IsCompatible(constants.ImplementationThunderbird))
+	assert.True(t, hasCompatibilityIssue(report, constants.ImplementationGnuPG24, constants.CompatibilityFeatureV6Key))
+	assert.True(t, hasCompatibilityIssue(report, constants.ImplementationGnuPG24, constants.CompatibilityFeatureCurve25519))
+}
+
+func TestMessageCompatibilityReport(t *testing.T) {
+	message, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("hello"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	report, err := message.GetCompatibilityReport()
+	if err != nil {
+		t.Fatal("Expected no error while checking compatibility, got:", err)
+	}
+	assert.Empty(t, report.Issues)
+
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRingV6, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	// The data packet of large messages is split in partial bodies
+	message, err = keyRingV6.Encrypt(NewPlainMessage(make([]byte, 100000)), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	report, err = message.GetCompatibilityReport()
+	if err != nil {
+		t.Fatal("Expected no error while checking compatibility, got:", err)
+	}
+	assert.True(t, hasCompatibilityIssue(report, constants.ImplementationGnuPG24, constants.CompatibilityFeatureSEIPDv2))
+	assert.True(t, hasCompatibilityIssue(report, constants.ImplementationRNP, constants.CompatibilityFeatureCurve25519))
+
+	_, err = NewPGPMessage([]byte{0xc1, 0x05}).GetCompatibilityReport()
+	assert.Error(t, err)
+}
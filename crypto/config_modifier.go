@@ -0,0 +1,34 @@
+package crypto
+
+import (
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// SetConfigModifier sets a function applied last to the go-crypto
+// configurations built by gopenpgp to encrypt, decrypt, sign and verify
+// messages and session keys, to generate, lock and certify keys, and to
+// generate random tokens, so that advanced users can set go-crypto options
+// that gopenpgp doesn't expose yet.
+// The modifier can override the choices of gopenpgp, including the security
+// related ones, and must be safe for concurrent use.
+// Passing nil removes the modifier.
+func SetConfigModifier(modifier func(config *packet.Config)) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.configModifier = modifier
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// applyConfigModifier applies the modifier set with SetConfigModifier, if
+// any, to config.
+func applyConfigModifier(config *packet.Config) {
+	pgp.lock.RLock()
+	modifier := pgp.configModifier
+	pgp.lock.RUnlock()
+
+	if modifier != nil {
+		modifier(config)
+	}
+}
@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfigModifier(t *testing.T) {
+	SetConfigModifier(func(config *packet.Config) {
+		config.DefaultCipher = packet.CipherAES128
+	})
+	defer SetConfigModifier(nil)
+
+	pgpMessage, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	split, err := pgpMessage.SplitMessage()
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	sessionKey, err := keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	assert.Exactly(t, "aes128", sessionKey.Algo)
+
+	SetConfigModifier(nil)
+	pgpMessage, err = keyRingTestPublic.Encrypt(NewPlainMessageFromString("plain text"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	split, err = pgpMessage.SplitMessage()
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	sessionKey, err = keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	assert.Exactly(t, "aes256", sessionKey.Algo)
+}
+
+func TestSetConfigModifierRandomness(t *testing.T) {
+	SetConfigModifier(func(config *packet.Config) {
+		config.Rand = bytes.NewReader(bytes.Repeat([]byte{0x42}, 16))
+	})
+	defer SetConfigModifier(nil)
+
+	token, err := RandomToken(16)
+	if err != nil {
+		t.Fatal("Expected no error while generating token, got:", err)
+	}
+	assert.Exactly(t, bytes.Repeat([]byte{0x42}, 16), token)
+
+	SetConfigModifier(nil)
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	random := &countingReader{reader: rand.Reader}
+	SetConfigModifier(func(config *packet.Config) {
+		config.Rand = random
+	})
+	if _, err = keyRingTestPublic.EncryptSessionKey(sessionKey); err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	assert.NotZero(t, random.count())
+}
@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"io"
+)
+
+// decompressionRatioThreshold is the size of the plaintext from which
+// DecompressionLimits.MaxRatio is checked, since small messages can be
+// compressed far more than large ones.
+const decompressionRatioThreshold = 1 << 20
+
+// DecompressionLimits bounds the plaintext of the decrypted messages, so that
+// a small compressed message can't exhaust the memory or the disk once
+// decompressed. A limit of 0 disables the check.
+type DecompressionLimits struct {
+	// MaxDecompressedBytes is the maximum size of the plaintext of a message.
+	MaxDecompressedBytes int64
+	// MaxRatio is the maximum ratio between the size of the plaintext read
+	// so far and the size of the message read to decompress it, checked
+	// once the plaintext exceeds 1 MiB.
+	MaxRatio int64
+}
+
+// NewDecompressionLimits creates new decompression limits, see
+// DecompressionLimits.
+func NewDecompressionLimits(maxDecompressedBytes, maxRatio int64) *DecompressionLimits {
+	return &DecompressionLimits{
+		MaxDecompressedBytes: maxDecompressedBytes,
+		MaxRatio:             maxRatio,
+	}
+}
+
+// SetDecompressionLimits sets the limits applied to the plaintext while
+// decrypting messages, with a key ring, a password or a session key, and
+// while verifying them. Reading a plaintext exceeding them fails with an
+// error wrapping ErrDecompressionBomb: the plaintext returned by the stream
+// readers up to that point, which is at most MaxDecompressedBytes, is not
+// verified and must be discarded. Passing nil removes the limits.
+func SetDecompressionLimits(limits *DecompressionLimits) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.decompressionLimits = limits
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func getDecompressionLimits() *DecompressionLimits {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+
+	return pgp.decompressionLimits
+}
+
+// decompressionLimiter applies the decompression limits to the plaintext of
+// a message.
+type decompressionLimiter struct {
+	limits  *DecompressionLimits
+	message *countingReader
+}
+
+// newDecompressionLimiter wraps the message reader to count the bytes read,
+// and returns the limiter of its plaintext, nil if no limits are set.
+func newDecompressionLimiter(message io.Reader) (io.Reader, *decompressionLimiter) {
+	limits := getDecompressionLimits()
+	if limits == nil || (limits.MaxDecompressedBytes == 0 && limits.MaxRatio == 0) {
+		return message, nil
+	}
+	message, counter := newCountingReader(message)
+	return message, &decompressionLimiter{limits: limits, message: counter}
+}
+
+// limitReader returns a reader failing once the plaintext read from reader
+// exceeds the limits, or reader itself if limiter is nil.
+func (limiter *decompressionLimiter) limitReader(reader io.Reader) io.Reader {
+	if limiter == nil {
+		return reader
+	}
+	return &decompressionLimitedReader{reader: reader, limiter: limiter}
+}
+
+type decompressionLimitedReader struct {
+	reader  io.Reader
+	limiter *decompressionLimiter
+	read    int64
+}
+
+func (r *decompressionLimitedReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.read += int64(n)
+	limits := r.limiter.limits
+	if limits.MaxDecompressedBytes > 0 && r.read > limits.MaxDecompressedBytes {
+		// Only deliver the plaintext up to the limit
+		n -= int(r.read - limits.MaxDecompressedBytes)
+		r.read = limits.MaxDecompressedBytes
+		return n, newClassifiedError(ErrDecompressionBomb, "gopenpgp: decompressed message too large", nil)
+	}
+	compressed := r.limiter.message.count()
+	if limits.MaxRatio > 0 && r.read > decompressionRatioThreshold &&
+		(compressed == 0 || r.read/compressed > limits.MaxRatio) {
+		return n, newClassifiedError(ErrDecompressionBomb, "gopenpgp: decompression ratio too high", nil)
+	}
+	return n, err
+}
@@ -0,0 +1,49 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecompressionLimits(t *testing.T) {
+	bomb := NewPlainMessage(make([]byte, 4<<20))
+	pgpMessage, err := keyRingTestPublic.EncryptWithCompression(bomb, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting plaintext, got:", err)
+	}
+	message := NewPlainMessageFromString("plain text")
+	smallMessage, err := keyRingTestPublic.EncryptWithCompression(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting plaintext, got:", err)
+	}
+	defer SetDecompressionLimits(nil)
+
+	SetDecompressionLimits(NewDecompressionLimits(0, 100))
+	_, err = keyRingTestPrivate.Decrypt(pgpMessage, nil, 0)
+	assert.True(t, errors.Is(err, ErrDecompressionBomb))
+	decrypted, err := keyRingTestPrivate.Decrypt(smallMessage, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting message, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	SetDecompressionLimits(NewDecompressionLimits(1<<20, 0))
+	reader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(pgpMessage.GetBinary()), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	partial, err := ioutil.ReadAll(reader)
+	assert.True(t, errors.Is(err, ErrDecompressionBomb))
+	assert.Exactly(t, 1<<20, len(partial))
+
+	SetDecompressionLimits(nil)
+	decrypted, err = keyRingTestPrivate.Decrypt(pgpMessage, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting message, got:", err)
+	}
+	assert.Exactly(t, bomb.GetBinary(), decrypted.GetBinary())
+}
@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// AlsoEncryptToSender makes encryption to the keyring also encrypt to the
+// sender, so that sent messages remain readable by their sender, as mail
+// clients expect. The sender is self if not nil, e.g. a dedicated encryption
+// key of the sender, else the key signing the message, if any.
+// Keys already in the keyring are not added twice.
+// Attachments and session keys, which are not signed, are only encrypted to
+// self.
+func (keyRing *KeyRing) AlsoEncryptToSender(self *KeyRing) {
+	keyRing.encryptToSender = true
+	keyRing.senderKeyRing = self
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getRecipients returns the keyring to encrypt to, with the sender set by
+// AlsoEncryptToSender and the key set by SetEncryptionSubkey.
+// signEntity is the key signing the message, or nil.
+func (keyRing *KeyRing) getRecipients(signEntity *openpgp.Entity) (*KeyRing, error) {
+	return keyRing.withSender(signEntity).withEncryptionSubkey()
+}
+
+// withSender returns the keyring to encrypt to, with the sender set by
+// AlsoEncryptToSender, or the keyring itself if it isn't set.
+// signEntity is the key signing the message, or nil.
+func (keyRing *KeyRing) withSender(signEntity *openpgp.Entity) *KeyRing {
+	if !keyRing.encryptToSender {
+		return keyRing
+	}
+	var senders openpgp.EntityList
+	if keyRing.senderKeyRing != nil {
+		senders = keyRing.senderKeyRing.entities
+	} else if signEntity != nil {
+		senders = openpgp.EntityList{signEntity}
+	}
+
+	entities := append(openpgp.EntityList{}, keyRing.entities...)
+	for _, sender := range senders {
+		found := false
+		for _, entity := range entities {
+			if bytes.Equal(entity.PrimaryKey.Fingerprint, sender.PrimaryKey.Fingerprint) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			entities = append(entities, sender)
+		}
+	}
+	return keyRing.withEntities(entities)
+}
@@ -0,0 +1,87 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlsoEncryptToSender(t *testing.T) {
+	recipient, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	recipientKeyRing, err := NewKeyRing(recipient)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	publicRecipient, err := recipient.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	publicKeyRing, err := NewKeyRing(publicRecipient)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	publicKeyRing.AlsoEncryptToSender(nil)
+
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := publicKeyRing.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	for _, keyRing := range []*KeyRing{recipientKeyRing, keyRingTestPrivate} {
+		decrypted, err := keyRing.Decrypt(ciphertext, keyRingTestPublic, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, testMessage, decrypted.GetString())
+	}
+	assert.Exactly(t, 1, publicKeyRing.CountEntities())
+
+	// Unsigned messages are only encrypted to the designated self key
+	ciphertext, err = publicKeyRing.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	keyIDs, _ := ciphertext.GetEncryptionKeyIDs()
+	assert.Exactly(t, 1, len(keyIDs))
+
+	publicKeyRing.AlsoEncryptToSender(keyRingTestPublic)
+	ciphertext, err = publicKeyRing.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	split, err := publicKeyRing.EncryptAttachment(message, "")
+	if err != nil {
+		t.Fatal("Expected no error while encrypting attachment, got:", err)
+	}
+	if _, err = keyRingTestPrivate.DecryptAttachment(split); err != nil {
+		t.Fatal("Expected no error while decrypting attachment, got:", err)
+	}
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	keyPacket, err := publicKeyRing.EncryptSessionKey(sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	decryptedSessionKey, err := keyRingTestPrivate.DecryptSessionKey(keyPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	assert.Exactly(t, sessionKey.Key, decryptedSessionKey.Key)
+
+	// The sender is not added twice
+	recipientKeyRing.AlsoEncryptToSender(nil)
+	ciphertext, err = recipientKeyRing.Encrypt(message, recipientKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	keyIDs, _ = ciphertext.GetEncryptionKeyIDs()
+	assert.Exactly(t, 1, len(keyIDs))
+}
@@ -0,0 +1,305 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"io"
+	"io/ioutil"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/elgamal"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// encryptionChunkSize is the size of the writes forwarded to the OpenPGP
+// literal data writer. Writing fixed-size chunks makes the partial length
+// framing of the message, and thus its size, only depend on the plaintext size.
+const encryptionChunkSize = 1 << 14
+
+const (
+	// Size of the version byte, the key ID and the algorithm of a v3 PKESK.
+	encryptedKeyHeaderSize = 1 + 8 + 1
+	// Size of the SEIPDv1 version byte.
+	seipdVersionSize = 1
+	// Block size of the ciphers allowed in SEIPDv1 packets.
+	seipdBlockSize = 16
+	// Size of the modification detection code packet.
+	mdcPacketSize = 2 + 20
+	// Size of the authentication tags of the AEAD modes.
+	aeadTagSize = 16
+	// Threshold above which buffered partial length data is flushed.
+	partialLengthFlushThreshold = 512
+)
+
+// EncryptedSize returns the exact size in bytes of the binary message written
+// by EncryptStream, or returned by Encrypt, for plainSize bytes of plaintext.
+// The size is computed without encrypting the data, which lets clients
+// pre-allocate buffers or set a Content-Length before streaming.
+// It only applies to messages that are neither signed nor compressed.
+// The data packet is SEIPDv2 if the keys of the keyring support it, see
+// ForceSEIPDv1.
+// For RSA and ElGamal recipients, the encrypted session key can occasionally
+// be a few bytes shorter, the returned size is then an upper bound.
+// To get the size of the armored message, use armor.ArmoredSize.
+// * plainSize : the size of the plaintext in bytes.
+// * plainMessageMetadata : (optional) the metadata passed to EncryptStream.
+func (keyRing *KeyRing) EncryptedSize(plainSize int64, plainMessageMetadata *PlainMessageMetadata) (int64, error) {
+	keyPacketSize, err := keyRing.EncryptedKeyPacketSize()
+	if err != nil {
+		return 0, err
+	}
+	aeadChunkSize, err := keyRing.getEncryptionAEADChunkSize()
+	if err != nil {
+		return 0, err
+	}
+	dataPacketSize, err := encryptedDataPacketSize(plainSize, plainMessageMetadata, aeadChunkSize)
+	if err != nil {
+		return 0, err
+	}
+	return keyPacketSize + dataPacketSize, nil
+}
+
+// EncryptedKeyPacketSize returns the size in bytes of the key packets written
+// when encrypting to this keyring, e.g. by EncryptSplitStream.
+// For RSA and ElGamal recipients, the returned size is an upper bound.
+func (keyRing *KeyRing) EncryptedKeyPacketSize() (int64, error) {
+	var keyPackets bytes.Buffer
+	hints := &openpgp.FileHints{IsBinary: true}
+	encryptWriter, err := asymmetricEncryptStream(hints, &keyPackets, ioutil.Discard, keyRing, nil, false, nil, encryptionChunkSize)
+	if err != nil {
+		return 0, err
+	}
+	if err = encryptWriter.Close(); err != nil {
+		return 0, errors.Wrap(err, "gopenpgp: error in closing message")
+	}
+
+	var size int64
+	reader := bytes.NewReader(keyPackets.Bytes())
+	for reader.Len() > 0 {
+		before := reader.Len()
+		p, err := packet.Read(reader)
+		if err != nil {
+			return 0, errors.Wrap(err, "gopenpgp: unable to read key packets")
+		}
+		packetSize := int64(before - reader.Len())
+		if ek, ok := p.(*packet.EncryptedKey); ok {
+			if maxSize, ok := keyRing.maxEncryptedKeySize(ek.KeyId); ok {
+				packetSize = maxSize
+			}
+		}
+		size += packetSize
+	}
+	return size, nil
+}
+
+// EncryptedSize returns the exact size in bytes of the data packet written by
+// EncryptStream, or returned by Encrypt, for plainSize bytes of plaintext.
+// This is also the size of the data packet written by KeyRing.EncryptSplitStream.
+// It only applies to data packets that are neither signed nor compressed.
+// * plainSize : the size of the plaintext in bytes.
+// * plainMessageMetadata : (optional) the metadata passed to EncryptStream.
+func (sk *SessionKey) EncryptedSize(plainSize int64, plainMessageMetadata *PlainMessageMetadata) (int64, error) {
+	dc, err := sk.GetCipherFunc()
+	if err != nil {
+		return 0, errors.Wrap(err, "gopenpgp: unable to compute encrypted size")
+	}
+	if sk.V6 || !dc.IsSupported() || dc < packet.CipherAES128 {
+		return 0, errors.New("gopenpgp: unable to compute encrypted size with cipher " + sk.Algo)
+	}
+	return encryptedDataPacketSize(plainSize, plainMessageMetadata, 0)
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// getEncryptionAEADChunkSize returns the chunk size of the SEIPDv2 packets
+// encrypted to the keyring, or 0 if SEIPDv1 is used, as decided by
+// asymmetricEncryptStream.
+func (keyRing *KeyRing) getEncryptionAEADChunkSize() (int64, error) {
+	recipients := keyRing.withSender(nil)
+	aeadConfig, err := recipients.getEncryptionAEADConfig()
+	if err != nil {
+		return 0, err
+	}
+	config := &packet.Config{AEADConfig: aeadConfig}
+	applyConfigModifier(config)
+	if config.AEAD() == nil {
+		return 0, nil
+	}
+	// go-crypto only uses SEIPDv2 if all the keys support it
+	for _, entity := range recipients.entities {
+		if !supportsSEIPDv2(entity) {
+			return 0, nil
+		}
+	}
+	return int64(1) << (config.AEAD().ChunkSizeByte() + 6), nil
+}
+
+// encryptedDataPacketSize replays the writes performed by the encryption
+// writers on two nested partial length counters: the outer one for the SEIPD
+// packet, the inner one for the literal data packet it contains.
+// The packet is SEIPDv2 with the given chunk size, or SEIPDv1 if it is 0.
+func encryptedDataPacketSize(plainSize int64, plainMessageMetadata *PlainMessageMetadata, aeadChunkSize int64) (int64, error) {
+	if plainSize < 0 {
+		return 0, errors.New("gopenpgp: the plaintext size can't be negative")
+	}
+	var filenameSize int
+	if plainMessageMetadata != nil {
+		filenameSize = len(plainMessageMetadata.Filename)
+		if filenameSize > 255 {
+			filenameSize = 255
+		}
+	}
+
+	size := int64(1) // SEIPD packet tag
+	seipd := &partialLengthCounter{output: func(n int64) { size += n }}
+	var literal *partialLengthCounter
+	var literalSize int64
+	if aeadChunkSize == 0 {
+		seipd.write(seipdVersionSize)
+		seipd.write(seipdBlockSize + 2) // OCFB prefix
+		seipd.write(1)                  // literal data packet tag
+		literal = &partialLengthCounter{output: seipd.write}
+	} else {
+		// The AEAD encrypter buffers the literal data packet in chunks
+		literalSize = 1 // literal data packet tag
+		literal = &partialLengthCounter{output: func(n int64) { literalSize += n }}
+	}
+	literal.write(2) // format and filename length
+	literal.write(int64(filenameSize))
+	literal.write(4) // modification time
+	for remaining := plainSize; remaining > 0; remaining -= encryptionChunkSize {
+		if remaining < encryptionChunkSize {
+			literal.write(remaining)
+		} else {
+			literal.write(encryptionChunkSize)
+		}
+	}
+	literal.close()
+
+	if aeadChunkSize == 0 {
+		seipd.write(mdcPacketSize)
+	} else {
+		seipd.write(seipdV2HeaderSize)
+		for remaining := literalSize; remaining >= aeadChunkSize; remaining -= aeadChunkSize {
+			seipd.write(aeadChunkSize + aeadTagSize)
+		}
+		if literalSize%aeadChunkSize > 0 {
+			seipd.write(literalSize%aeadChunkSize + aeadTagSize)
+		}
+		seipd.write(aeadTagSize) // final tag
+	}
+	seipd.close()
+	return size, nil
+}
+
+// partialLengthCounter counts the bytes written by a partial length packet
+// writer, given the sizes of the writes it receives.
+type partialLengthCounter struct {
+	buffered int64
+	output   func(n int64)
+}
+
+func (c *partialLengthCounter) write(n int64) {
+	if c.buffered > partialLengthFlushThreshold {
+		chunk := int64(1)
+		for chunk<<1 <= c.buffered && chunk < 1<<30 {
+			chunk <<= 1
+		}
+		c.output(1)
+		c.output(chunk)
+		c.buffered -= chunk
+	}
+	c.buffered += n
+}
+
+func (c *partialLengthCounter) close() {
+	c.output(int64(packetLengthSize(c.buffered)))
+	if c.buffered > 0 {
+		c.output(c.buffered)
+	}
+}
+
+// maxEncryptedKeySize returns the largest size of a PKESK packet encrypted to
+// the RSA or ElGamal key with the given ID. Other algorithms produce
+// fixed-size packets and are not handled.
+func (keyRing *KeyRing) maxEncryptedKeySize(keyID uint64) (int64, bool) {
+	for _, key := range keyRing.entities.KeysById(keyID) {
+		var bodySize int
+		switch pub := key.PublicKey.PublicKey.(type) {
+		case *rsa.PublicKey:
+			bodySize = encryptedKeyHeaderSize + 2 + (pub.N.BitLen()+7)/8
+		case *elgamal.PublicKey:
+			bodySize = encryptedKeyHeaderSize + 2*(2+(pub.P.BitLen()+7)/8)
+		default:
+			return 0, false
+		}
+		return int64(1 + packetLengthSize(int64(bodySize)) + bodySize), true
+	}
+	return 0, false
+}
+
+// chunkedWriteCloser forwards the data written to it in chunks of exactly
+// chunkSize bytes, except for the last one written on Close.
+type chunkedWriteCloser struct {
+	writer io.WriteCloser
+	buffer []byte
+}
+
+func newChunkedWriteCloser(writer io.WriteCloser, chunkSize int) *chunkedWriteCloser {
+	var buffer []byte
+	if chunkSize == encryptionChunkSize {
+		buffer = getChunk()
+	} else {
+		buffer = make([]byte, 0, chunkSize)
+	}
+	return &chunkedWriteCloser{
+		writer: writer,
+		buffer: buffer,
+	}
+}
+
+func (w *chunkedWriteCloser) Write(b []byte) (n int, err error) {
+	if w.buffer == nil {
+		return 0, errors.New("gopenpgp: write to a closed message writer")
+	}
+	n = len(b)
+	chunkSize := cap(w.buffer)
+	if len(w.buffer) > 0 {
+		free := chunkSize - len(w.buffer)
+		if len(b) < free {
+			w.buffer = append(w.buffer, b...)
+			return n, nil
+		}
+		w.buffer = append(w.buffer, b[:free]...)
+		b = b[free:]
+		if _, err = w.writer.Write(w.buffer); err != nil {
+			return 0, err
+		}
+		w.buffer = w.buffer[:0]
+	}
+	for len(b) >= chunkSize {
+		if _, err = w.writer.Write(b[:chunkSize]); err != nil {
+			return 0, err
+		}
+		b = b[chunkSize:]
+	}
+	w.buffer = append(w.buffer, b...)
+	return n, nil
+}
+
+func (w *chunkedWriteCloser) Close() error {
+	if w.buffer == nil {
+		return w.writer.Close()
+	}
+	var err error
+	if len(w.buffer) > 0 {
+		_, err = w.writer.Write(w.buffer)
+	}
+	putChunk(w.buffer)
+	w.buffer = nil
+	if err != nil {
+		return err
+	}
+	return w.writer.Close()
+}
@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+var encryptedSizeTestLengths = []int{0, 1, 191, 192, 511, 512, 513, 8383, 8384, encryptionChunkSize - 1, encryptionChunkSize, 3*encryptionChunkSize + 17, 200000}
+
+func encryptStreamInPieces(t *testing.T, keyRing *KeyRing, data []byte, metadata *PlainMessageMetadata, pieceSize int) []byte {
+	var ciphertext bytes.Buffer
+	writer, err := keyRing.EncryptStream(&ciphertext, metadata, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	for len(data) > 0 {
+		n := pieceSize
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err = writer.Write(data[:n]); err != nil {
+			t.Fatal("Expected no error while writing data, got:", err)
+		}
+		data = data[n:]
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing plaintext writer, got:", err)
+	}
+	return ciphertext.Bytes()
+}
+
+func TestKeyRing_EncryptedSize(t *testing.T) {
+	ecKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	for _, length := range encryptedSizeTestLengths {
+		data := make([]byte, length)
+		_, _ = rand.Read(data)
+
+		expected, err := ecKeyRing.EncryptedSize(int64(length), testMeta)
+		if err != nil {
+			t.Fatal("Expected no error while computing size, got:", err)
+		}
+		for _, pieceSize := range []int{1000, 4093, 1 << 20} {
+			ciphertext := encryptStreamInPieces(t, ecKeyRing, data, testMeta, pieceSize)
+			assert.Exactly(t, expected, int64(len(ciphertext)), "plaintext length %d, pieces of %d", length, pieceSize)
+		}
+
+		message, err := ecKeyRing.Encrypt(NewPlainMessage(data), nil)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		defaultSize, err := ecKeyRing.EncryptedSize(int64(length), nil)
+		if err != nil {
+			t.Fatal("Expected no error while computing size, got:", err)
+		}
+		assert.Exactly(t, defaultSize, int64(len(message.GetBinary())))
+
+		armored, err := message.GetArmored()
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		assert.Exactly(t, armor.ArmoredSize(defaultSize, constants.PGPMessageHeader), int64(len(armored)))
+	}
+}
+
+func TestKeyRing_EncryptedSizeSEIPDv2(t *testing.T) {
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	lengths := append(append([]int{}, encryptedSizeTestLengths...), 1<<18-30, 1<<18, 600000)
+	checkSizes := func() {
+		for _, length := range lengths {
+			data := make([]byte, length)
+			expected, err := keyRing.EncryptedSize(int64(length), testMeta)
+			if err != nil {
+				t.Fatal("Expected no error while computing size, got:", err)
+			}
+			ciphertext := encryptStreamInPieces(t, keyRing, data, testMeta, 4093)
+			assert.Exactly(t, 2, getSEIPDVersion(t, NewPGPMessage(ciphertext)))
+			assert.Exactly(t, expected, int64(len(ciphertext)), "plaintext length %d", length)
+		}
+	}
+	checkSizes()
+
+	SetConfigModifier(func(config *packet.Config) {
+		if config.AEADConfig != nil {
+			config.AEADConfig.ChunkSize = 256
+		}
+	})
+	defer SetConfigModifier(nil)
+	checkSizes()
+
+	// SEIPDv1 is used when forced
+	keyRing.ForceSEIPDv1()
+	expected, err := keyRing.EncryptedSize(1000, testMeta)
+	if err != nil {
+		t.Fatal("Expected no error while computing size, got:", err)
+	}
+	ciphertext := encryptStreamInPieces(t, keyRing, make([]byte, 1000), testMeta, 100)
+	assert.Exactly(t, 1, getSEIPDVersion(t, NewPGPMessage(ciphertext)))
+	assert.Exactly(t, expected, int64(len(ciphertext)))
+}
+
+func TestKeyRing_EncryptedSizeRSA(t *testing.T) {
+	data := make([]byte, 1000)
+	expected, err := keyRingTestPublic.EncryptedSize(int64(len(data)), testMeta)
+	if err != nil {
+		t.Fatal("Expected no error while computing size, got:", err)
+	}
+	ciphertext := encryptStreamInPieces(t, keyRingTestPublic, data, testMeta, 100)
+	assert.LessOrEqual(t, int64(len(ciphertext)), expected)
+	assert.GreaterOrEqual(t, int64(len(ciphertext)), expected-2)
+}
+
+func TestSessionKey_EncryptedSize(t *testing.T) {
+	for _, length := range encryptedSizeTestLengths {
+		data := make([]byte, length)
+		expected, err := testSessionKey.EncryptedSize(int64(length), nil)
+		if err != nil {
+			t.Fatal("Expected no error while computing size, got:", err)
+		}
+		dataPacket, err := testSessionKey.Encrypt(NewPlainMessage(data))
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		assert.Exactly(t, expected, int64(len(dataPacket)), "plaintext length %d", length)
+	}
+
+	_, err := testSessionKey.EncryptedSize(-1, nil)
+	assert.Error(t, err)
+}
+
+func TestArmoredSizeWithCustomHeaders(t *testing.T) {
+	for _, length := range []int{0, 1, 2, 3, 47, 48, 49, 96, 1000} {
+		data := make([]byte, length)
+		armored, err := armor.ArmorWithTypeAndCustomHeaders(data, constants.PGPSignatureHeader, "", "a comment")
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		assert.Exactly(t, int64(len(armored)), armor.ArmoredSizeWithCustomHeaders(int64(length), constants.PGPSignatureHeader, "", "a comment"))
+
+		var buffered bytes.Buffer
+		writer, err := armor.ArmorWithTypeBuffered(&buffered, constants.PGPMessageHeader)
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		_, _ = writer.Write(data)
+		_ = writer.Close()
+		assert.Exactly(t, int64(buffered.Len()), armor.ArmoredSizeWithCustomHeaders(int64(length), constants.PGPMessageHeader, "", ""))
+	}
+}
@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"github.com/pkg/errors"
+)
+
+// EncryptionContext binds encrypted messages to the context they are used in,
+// e.g. a protocol and a purpose, to prevent them from being replayed in
+// another context.
+// The context is set as a critical notation of the signature embedded in the
+// encrypted message, like a SigningContext, and decrypting with an
+// EncryptionContext fails unless the message is signed with the same context
+// by one of the verification keys. As the signature is encrypted and
+// integrity protected with the message, the context covers the ciphertext.
+type EncryptionContext struct {
+	Value string
+}
+
+// NewEncryptionContext creates a new encryption context.
+func NewEncryptionContext(value string) *EncryptionContext {
+	return &EncryptionContext{Value: value}
+}
+
+// EncryptWithEncryptionContext encrypts a PlainMessage, outputs a PGPMessage
+// bound to the encryption context.
+// * message           : The plaintext input as a PlainMessage.
+// * privateKey        : An unlocked private keyring to sign the message with.
+// * encryptionContext : The context to bind the message to.
+func (keyRing *KeyRing) EncryptWithEncryptionContext(
+	message *PlainMessage,
+	privateKey *KeyRing,
+	encryptionContext *EncryptionContext,
+) (*PGPMessage, error) {
+	if err := checkEncryptionContext(privateKey, encryptionContext); err != nil {
+		return nil, err
+	}
+	return asymmetricEncrypt(message, keyRing, privateKey, false, encryptionContext.getSigningContext())
+}
+
+// DecryptWithEncryptionContext decrypts encrypted string using pgp keys,
+// and checks that the message is bound to the encryption context.
+// Unlike Decrypt, no message is returned if the signature or its context
+// are not valid.
+// * message           : The encrypted input as a PGPMessage.
+// * verifyKey         : Public key for signature verification.
+// * verifyTime        : Time at verification (necessary only if verifyKey is not nil).
+// * encryptionContext : The context the message must be bound to.
+func (keyRing *KeyRing) DecryptWithEncryptionContext(
+	message *PGPMessage,
+	verifyKey *KeyRing,
+	verifyTime int64,
+	encryptionContext *EncryptionContext,
+) (*PlainMessage, error) {
+	if err := checkEncryptionContext(verifyKey, encryptionContext); err != nil {
+		return nil, err
+	}
+	if err := checkMessageLimits(message, verifyKey); err != nil {
+		return nil, err
+	}
+	plainMessage, err := asymmetricDecrypt(
+		message,
+		keyRing,
+		verifyKey,
+		verifyTime,
+		encryptionContext.getVerificationContext(),
+	)
+	return checkEncryptionContextResult(plainMessage, err)
+}
+
+// EncryptAndSignWithEncryptionContext encrypts a PlainMessage to PGPMessage
+// with a SessionKey, bound to the encryption context.
+// * message           : The plain data as a PlainMessage.
+// * signKeyRing       : The KeyRing to sign the message.
+// * encryptionContext : The context to bind the message to.
+func (sk *SessionKey) EncryptAndSignWithEncryptionContext(
+	message *PlainMessage,
+	signKeyRing *KeyRing,
+	encryptionContext *EncryptionContext,
+) ([]byte, error) {
+	if err := checkEncryptionContext(signKeyRing, encryptionContext); err != nil {
+		return nil, err
+	}
+	return encryptWithSessionKey(message, sk, signKeyRing, false, encryptionContext.getSigningContext(), nil)
+}
+
+// DecryptAndVerifyWithEncryptionContext decrypts pgp data packets using
+// directly a session key, and checks that the message is bound to the
+// encryption context.
+// * dataPacket        : The encrypted data packet.
+// * verifyKeyRing     : KeyRing with verification public keys.
+// * verifyTime        : when should the signature be valid, as timestamp. If 0 time verification is disabled.
+// * encryptionContext : The context the message must be bound to.
+func (sk *SessionKey) DecryptAndVerifyWithEncryptionContext(
+	dataPacket []byte,
+	verifyKeyRing *KeyRing,
+	verifyTime int64,
+	encryptionContext *EncryptionContext,
+) (*PlainMessage, error) {
+	if err := checkEncryptionContext(verifyKeyRing, encryptionContext); err != nil {
+		return nil, err
+	}
+	plainMessage, err := decryptWithSessionKeyAndContext(
+		sk,
+		dataPacket,
+		verifyKeyRing,
+		verifyTime,
+		encryptionContext.getVerificationContext(),
+	)
+	return checkEncryptionContextResult(plainMessage, err)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func (context *EncryptionContext) getSigningContext() *SigningContext {
+	return NewSigningContext(context.Value, true)
+}
+
+func (context *EncryptionContext) getVerificationContext() *VerificationContext {
+	return NewVerificationContext(context.Value, true, 0)
+}
+
+func checkEncryptionContext(keyRing *KeyRing, encryptionContext *EncryptionContext) error {
+	if encryptionContext == nil {
+		return errors.New("gopenpgp: no encryption context provided")
+	}
+	if keyRing == nil || keyRing.CountEntities() == 0 {
+		return errors.New("gopenpgp: an encryption context requires signing and verification keys")
+	}
+	return nil
+}
+
+// checkEncryptionContextResult turns signature verification errors into
+// ErrEncryptionContextMismatch, so that no message is returned.
+func checkEncryptionContextResult(plainMessage *PlainMessage, err error) (*PlainMessage, error) {
+	var signatureError SignatureVerificationError
+	if errors.As(err, &signatureError) {
+		return nil, newClassifiedError(ErrEncryptionContextMismatch, "gopenpgp: error in checking encryption context", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plainMessage, nil
+}
@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptionContext(t *testing.T) {
+	message := NewPlainMessageFromString("hello")
+	encryptionContext := NewEncryptionContext("test-protocol")
+
+	ciphertext, err := keyRingTestPublic.EncryptWithEncryptionContext(message, keyRingTestPrivate, encryptionContext)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.DecryptWithEncryptionContext(ciphertext, keyRingTestPublic, GetUnixTime(), encryptionContext)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+
+	decrypted, err = keyRingTestPrivate.DecryptWithEncryptionContext(ciphertext, keyRingTestPublic, GetUnixTime(), NewEncryptionContext("other-protocol"))
+	assert.True(t, errors.Is(err, ErrEncryptionContextMismatch))
+	assert.Nil(t, decrypted)
+
+	ciphertext, err = keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = keyRingTestPrivate.DecryptWithEncryptionContext(ciphertext, keyRingTestPublic, GetUnixTime(), encryptionContext)
+	assert.True(t, errors.Is(err, ErrEncryptionContextMismatch))
+
+	_, err = keyRingTestPublic.EncryptWithEncryptionContext(message, nil, encryptionContext)
+	assert.Error(t, err)
+}
+
+func TestSessionKeyEncryptionContext(t *testing.T) {
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	encryptionContext := NewEncryptionContext("test-protocol")
+
+	dataPacket, err := sessionKey.EncryptAndSignWithEncryptionContext(NewPlainMessageFromString("hello"), keyRingTestPrivate, encryptionContext)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	decrypted, err := sessionKey.DecryptAndVerifyWithEncryptionContext(dataPacket, keyRingTestPublic, GetUnixTime(), encryptionContext)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+
+	_, err = sessionKey.DecryptAndVerifyWithEncryptionContext(dataPacket, keyRingTestPublic, GetUnixTime(), NewEncryptionContext("other-protocol"))
+	assert.True(t, errors.Is(err, ErrEncryptionContextMismatch))
+}
@@ -0,0 +1,314 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// The errors below classify the failures of the package. They are returned
+// wrapped, and must be tested with errors.Is rather than by comparing the
+// error messages, e.g.
+//
+//	if errors.Is(err, crypto.ErrNoDecryptionKey) { ... }
+//
+// When the failure comes from go-crypto, its error is kept in the chain, so
+// that errors.As still finds e.g. a go-crypto StructuralError.
+
+// ErrWrongPassphrase is returned, wrapped, when a key can't be unlocked
+// because the passphrase is incorrect, or a message or session key can't be
+// decrypted with the given password.
+var ErrWrongPassphrase = errors.New("gopenpgp: wrong passphrase")
+
+// ErrKeyCorrupt is returned, wrapped, when the secret key material can't be
+// decrypted or parsed independently of the passphrase, e.g. because it is
+// truncated or uses an unknown protection mode.
+var ErrKeyCorrupt = errors.New("gopenpgp: corrupt secret key material")
+
+// ErrKeyLocked is returned, wrapped, when an operation requires the secret
+// material of a key which is not unlocked.
+var ErrKeyLocked = errors.New("gopenpgp: key is locked")
+
+// ErrKeyExpired is returned, wrapped, when the keys are expired.
+var ErrKeyExpired = errors.New("gopenpgp: key is expired")
+
+// ErrNoEncryptionKey is returned, wrapped, when a message or session key
+// can't be encrypted to a key, because it has no valid encryption subkey,
+// e.g. because it is expired or revoked.
+var ErrNoEncryptionKey = errors.New("gopenpgp: no valid encryption key")
+
+// ErrNoDecryptionKey is returned, wrapped, when none of the keys of the
+// keyring can decrypt a message or session key.
+var ErrNoDecryptionKey = errors.New("gopenpgp: no valid decryption key")
+
+// ErrUnsupportedAlgorithm is returned, wrapped, when a message, key or
+// session key uses an algorithm or feature which is not supported.
+var ErrUnsupportedAlgorithm = errors.New("gopenpgp: unsupported algorithm")
+
+// ErrMessageCorrupt is returned, wrapped, when a message is malformed,
+// truncated or fails its integrity check.
+var ErrMessageCorrupt = errors.New("gopenpgp: corrupt message")
+
+// ErrLimitExceeded is returned, wrapped, when a signature or message exceeds
+// the limits set with KeyRing.SetVerificationLimits.
+var ErrLimitExceeded = errors.New("gopenpgp: verification limit exceeded")
+
+// ErrDecompressionBomb is returned, wrapped, when the plaintext of a message
+// exceeds the limits set with SetDecompressionLimits.
+var ErrDecompressionBomb = errors.New("gopenpgp: decompression limit exceeded")
+
+// ErrEncryptionContextMismatch is returned, wrapped, when a message decrypted
+// with an encryption context is not signed with that context by the
+// verification keys, e.g. because it was encrypted for another protocol.
+var ErrEncryptionContextMismatch = errors.New("gopenpgp: message is not bound to the encryption context")
+
+// ErrInsecureLegacyAlgorithm is returned, wrapped, when encrypting to an
+// ElGamal key or signing with a DSA key, unless the keyring allows it with
+// KeyRing.AllowInsecureLegacyAlgorithms. Verification of DSA signatures
+// succeeds, see PGPSignature.HasInsecureLegacyAlgorithm.
+var ErrInsecureLegacyAlgorithm = errors.New("gopenpgp: insecure legacy algorithm")
+
+// StubKeyError is returned when signing requires a private key whose secret
+// material is not available, because it is a GNU-dummy stub (e.g. the secret
+// key is stored offline or on a smartcard).
+type StubKeyError struct {
+	// Fingerprint is the hex fingerprint of the stub key.
+	Fingerprint string
+}
+
+// Error is the base method for all errors.
+func (e StubKeyError) Error() string {
+	return "gopenpgp: the secret material of key " + e.Fingerprint + " is not available (stub key)"
+}
+
+// KeyCapabilityError is returned when a key has no valid primary key or
+// subkey with a capability required by the operation, e.g. to encrypt to a
+// key whose encryption subkey is expired. When a key with the capability is
+// only missing because it expired, the one that expired last is reported,
+// e.g. to show when the key of a recipient expired.
+// It is ErrNoEncryptionKey for errors.Is if the encryption capability is
+// missing, and ErrKeyExpired if an expired key was found.
+type KeyCapabilityError struct {
+	// Fingerprint is the hex fingerprint of the primary key.
+	Fingerprint string
+	// Capability is the missing capability, constants.KeyCapabilityEncrypt
+	// or constants.KeyCapabilitySign.
+	Capability string
+	// ExpiredFingerprint is the hex fingerprint of the primary key or subkey
+	// with the capability that expired last, or empty if there is none.
+	ExpiredFingerprint string
+	// ExpirationTime is the expiration time of the key ExpiredFingerprint,
+	// or 0 if there is none.
+	ExpirationTime int64
+}
+
+// Error is the base method for all errors.
+func (e KeyCapabilityError) Error() string {
+	message := "gopenpgp: key " + e.Fingerprint + " has no valid key to " + e.Capability
+	if e.ExpirationTime != 0 {
+		message += ", key " + e.ExpiredFingerprint + " expired on " + time.Unix(e.ExpirationTime, 0).UTC().Format(time.RFC3339)
+	}
+	return message
+}
+
+// Is reports whether target is the class of the error.
+func (e KeyCapabilityError) Is(target error) bool {
+	return (target == ErrNoEncryptionKey && e.Capability == constants.KeyCapabilityEncrypt) ||
+		(target == ErrKeyExpired && e.ExpirationTime != 0)
+}
+
+// SEIPDVersionError is returned when encrypting with SEIPDv2, see
+// KeyRing.ForceSEIPDv2, to keys which don't advertise support for it. It
+// lists all the incompatible keys, and is ErrUnsupportedAlgorithm for
+// errors.Is.
+type SEIPDVersionError struct {
+	// Fingerprints are the hex fingerprints of the primary keys which don't
+	// advertise support for SEIPDv2.
+	Fingerprints []string
+}
+
+// Error is the base method for all errors.
+func (e SEIPDVersionError) Error() string {
+	return "gopenpgp: SEIPDv2 is not supported by key " + strings.Join(e.Fingerprints, ", key ")
+}
+
+// Is reports whether target is the class of the error.
+func (e SEIPDVersionError) Is(target error) bool {
+	return target == ErrUnsupportedAlgorithm
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// classifiedError is an error of one of the classes above, which keeps the
+// underlying error, if any.
+type classifiedError struct {
+	class   error
+	message string
+	cause   error
+}
+
+func (e *classifiedError) Error() string {
+	if e.cause == nil {
+		return e.message
+	}
+	return e.message + ": " + e.cause.Error()
+}
+
+// Is reports whether target is the class of the error.
+func (e *classifiedError) Is(target error) bool {
+	return target == e.class
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.cause
+}
+
+// newClassifiedError returns an error with the given message, which is
+// class for errors.Is and wraps cause, which may be nil.
+func newClassifiedError(class error, message string, cause error) error {
+	return &classifiedError{class: class, message: message, cause: cause}
+}
+
+// newReadError classifies an error returned by go-crypto when reading or
+// decrypting a message, and prefixes it with message.
+func newReadError(err error, message string) error {
+	var structuralError pgpErrors.StructuralError
+	var unsupportedError pgpErrors.UnsupportedError
+	var aeadError pgpErrors.AEADError
+	var malformedError pgpErrors.ErrMalformedMessage
+	var unknownPacketError pgpErrors.UnknownPacketTypeError
+	var criticalPacketError pgpErrors.CriticalUnknownPacketTypeError
+	switch {
+	case errors.Is(err, pgpErrors.ErrKeyIncorrect):
+		return newClassifiedError(ErrNoDecryptionKey, message, err)
+	case errors.Is(err, pgpErrors.ErrMDCMissing),
+		errors.As(err, &unsupportedError) && string(unsupportedError) == "message is not integrity protected":
+		return newIntegrityError(message, err)
+	case errors.As(err, &unsupportedError):
+		return newClassifiedError(ErrUnsupportedAlgorithm, message, err)
+	case errors.As(err, &structuralError),
+		errors.As(err, &aeadError),
+		errors.As(err, &malformedError),
+		errors.As(err, &unknownPacketError),
+		errors.As(err, &criticalPacketError),
+		errors.Is(err, pgpErrors.ErrMDCHashMismatch),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, io.EOF):
+		return newClassifiedError(ErrMessageCorrupt, message, err)
+	default:
+		return errors.Wrap(err, message)
+	}
+}
+
+// newUnlockError classifies an error returned by go-crypto when decrypting
+// secret key material. Integrity check failures are caused by a wrong
+// passphrase, other typed errors by the key data itself.
+func newUnlockError(err error, message string) error {
+	var structuralError pgpErrors.StructuralError
+	var keyInvalidError pgpErrors.KeyInvalidError
+	var invalidArgumentError pgpErrors.InvalidArgumentError
+	var unsupportedError pgpErrors.UnsupportedError
+	switch {
+	case errors.As(err, &structuralError) && string(structuralError) == "private key checksum failure":
+		return errors.Wrap(ErrWrongPassphrase, message)
+	case errors.As(err, &structuralError),
+		errors.As(err, &keyInvalidError),
+		errors.As(err, &invalidArgumentError),
+		errors.As(err, &unsupportedError):
+		return errors.Wrap(ErrKeyCorrupt, message+": "+err.Error())
+	default:
+		// AEAD authentication failures are not typed
+		return errors.Wrap(ErrWrongPassphrase, message)
+	}
+}
+
+// newEncryptError classifies an error returned by go-crypto when encrypting
+// to publicKey, and prefixes it with message.
+func newEncryptError(err error, publicKey *KeyRing, config *packet.Config, message string) error {
+	var invalidArgumentError pgpErrors.InvalidArgumentError
+	var unsupportedError pgpErrors.UnsupportedError
+	switch {
+	case errors.As(err, &invalidArgumentError):
+		// go-crypto doesn't type the absence of encryption key
+		for _, entity := range publicKey.entities {
+			if _, ok := entity.EncryptionKey(config.Now()); !ok {
+				return errors.Wrap(newKeyCapabilityError(entity, constants.KeyCapabilityEncrypt), message)
+			}
+		}
+		return errors.Wrap(err, message)
+	case errors.As(err, &unsupportedError):
+		return newClassifiedError(ErrUnsupportedAlgorithm, message, err)
+	default:
+		return errors.Wrap(err, message)
+	}
+}
+
+// newKeyCapabilityError returns the KeyCapabilityError of an entity without
+// a valid key with the capability, with the key with the capability that
+// expired last, if any.
+func newKeyCapabilityError(entity *openpgp.Entity, capability string) KeyCapabilityError {
+	capabilityError := KeyCapabilityError{
+		Fingerprint: hex.EncodeToString(entity.PrimaryKey.Fingerprint),
+		Capability:  capability,
+	}
+	now := getNow()
+	primarySelfSignature, _ := entity.PrimarySelfSignature()
+	if expiration := bindingExpirationTime(entity.PrimaryKey, primarySelfSignature); expiration != 0 && expiration <= now.Unix() {
+		// The subkeys expire with the primary key
+		capabilityError.ExpiredFingerprint = capabilityError.Fingerprint
+		capabilityError.ExpirationTime = expiration
+		return capabilityError
+	}
+	for i := range entity.Subkeys {
+		subkey := &entity.Subkeys[i]
+		if !hasKeyCapability(subkey.PublicKey, subkey.Sig, capability) || subkey.Revoked(now) {
+			continue
+		}
+		expiration := bindingExpirationTime(subkey.PublicKey, subkey.Sig)
+		if expiration != 0 && expiration <= now.Unix() && expiration > capabilityError.ExpirationTime {
+			capabilityError.ExpiredFingerprint = hex.EncodeToString(subkey.PublicKey.Fingerprint)
+			capabilityError.ExpirationTime = expiration
+		}
+	}
+	return capabilityError
+}
+
+// bindingExpirationTime returns the time at which the key pk stops being
+// valid with the self-signature sig, the earliest of the key and signature
+// expiration times, or 0 if neither expires.
+func bindingExpirationTime(pk *packet.PublicKey, sig *packet.Signature) int64 {
+	if sig == nil {
+		return 0
+	}
+	expiration := keyExpirationTime(pk, sig)
+	if sig.SigLifetimeSecs != nil && *sig.SigLifetimeSecs != 0 {
+		sigExpiration := sig.CreationTime.Unix() + int64(*sig.SigLifetimeSecs)
+		if expiration == 0 || sigExpiration < expiration {
+			expiration = sigExpiration
+		}
+	}
+	return expiration
+}
+
+// hasKeyCapability returns whether the binding signature sig allows the key
+// to be used for the capability.
+func hasKeyCapability(publicKey *packet.PublicKey, sig *packet.Signature, capability string) bool {
+	if sig == nil || !sig.FlagsValid {
+		return false
+	}
+	switch capability {
+	case constants.KeyCapabilityEncrypt:
+		return sig.FlagEncryptCommunications && publicKey.PubKeyAlgo.CanEncrypt()
+	case constants.KeyCapabilitySign:
+		return sig.FlagSign && publicKey.PubKeyAlgo.CanSign()
+	default:
+		return false
+	}
+}
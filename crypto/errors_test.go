@@ -0,0 +1,106 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorClasses(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	_, err = otherKeyRing.Decrypt(ciphertext, nil, 0)
+	assert.True(t, errors.Is(err, ErrNoDecryptionKey))
+	assert.True(t, errors.Is(err, pgpErrors.ErrKeyIncorrect))
+
+	truncated := NewPGPMessage(ciphertext.GetBinary()[:len(ciphertext.GetBinary())-10])
+	_, err = keyRingTestPrivate.Decrypt(truncated, nil, 0)
+	assert.True(t, errors.Is(err, ErrMessageCorrupt))
+	assert.False(t, errors.Is(err, ErrNoDecryptionKey))
+
+	_, err = GenerateSessionKeyAlgo("rot13")
+	assert.True(t, errors.Is(err, ErrUnsupportedAlgorithm))
+
+	encrypted, err := EncryptMessageWithPassword(message, []byte("password"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with password, got:", err)
+	}
+	_, err = DecryptMessageWithPassword(encrypted, []byte("wrong password"))
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+
+	expiredKey, err := NewKeyFromArmored(readTestFile("key_expiredKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring expired key, got:", err)
+	}
+	expiredKeyRing, err := NewKeyRing(expiredKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	_, err = expiredKeyRing.Encrypt(message, nil)
+	assert.True(t, errors.Is(err, ErrNoEncryptionKey))
+	assert.True(t, errors.Is(err, ErrKeyExpired))
+	var capabilityError KeyCapabilityError
+	assert.True(t, errors.As(err, &capabilityError))
+	assert.Exactly(t, constants.KeyCapabilityEncrypt, capabilityError.Capability)
+	assert.Exactly(t, expiredKey.GetFingerprint(), capabilityError.ExpiredFingerprint)
+	assert.NotZero(t, capabilityError.ExpirationTime)
+
+	_, err = expiredKeyRing.EncryptSessionKey(testSessionKey)
+	assert.True(t, errors.As(err, &capabilityError))
+
+	_, err = NewPGPMessageFromArmored("-----BEGIN PGP MESSAGE-----\n\n!!!!\n-----END PGP MESSAGE-----\n")
+	assert.True(t, errors.Is(err, armor.ErrInvalidArmor))
+}
+
+func TestKeyCapabilityErrorExpiredSubkey(t *testing.T) {
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	entity := key.GetEntity()
+	subkey := &entity.Subkeys[0]
+	lifetime := uint32(3600)
+	subkey.Sig.KeyLifetimeSecs = &lifetime
+	if err := subkey.Sig.SignKey(subkey.PublicKey, entity.PrivateKey, nil); err != nil {
+		t.Fatal("Expected no error while signing subkey binding, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	expiration := subkey.PublicKey.CreationTime.Unix() + 3600
+	SetClock(func() time.Time {
+		return time.Unix(expiration+60, 0)
+	})
+	defer SetClock(nil)
+
+	_, err = keyRing.Encrypt(NewPlainMessageFromString(testMessage), nil)
+	var capabilityError KeyCapabilityError
+	if !errors.As(err, &capabilityError) {
+		t.Fatal("Expected a KeyCapabilityError while encrypting, got:", err)
+	}
+	assert.Exactly(t, key.GetFingerprint(), capabilityError.Fingerprint)
+	assert.Exactly(t, hex.EncodeToString(subkey.PublicKey.Fingerprint), capabilityError.ExpiredFingerprint)
+	assert.Exactly(t, expiration, capabilityError.ExpirationTime)
+	assert.True(t, errors.Is(err, ErrNoEncryptionKey))
+	assert.True(t, errors.Is(err, ErrKeyExpired))
+
+	// The signing capability is not affected
+	_, err = keyRing.SignDetached(NewPlainMessageFromString(testMessage))
+	assert.NoError(t, err)
+}
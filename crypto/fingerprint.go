@@ -0,0 +1,153 @@
+package crypto
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Lengths in bytes of key IDs and fingerprints.
+const (
+	keyIDLength         = 8
+	fingerprintV4Length = 20
+	fingerprintV6Length = 32
+)
+
+// FormatFingerprint formats a fingerprint or key ID for display, in upper
+// case groups of four hex digits, with two spaces between the halves of a
+// fingerprint, e.g. "E582 94F2 E9A2 2748 6E8B  061B 31CC 528F D7FA 3F19".
+// The input is normalized like in ParseFingerprint, and can be a v4 or v6
+// fingerprint, or a key ID.
+func FormatFingerprint(fingerprint string) (string, error) {
+	data, err := parseHexIdentifier(fingerprint)
+	if err != nil {
+		return "", err
+	}
+	if len(data) != keyIDLength && len(data) != fingerprintV4Length && len(data) != fingerprintV6Length {
+		return "", errors.New("gopenpgp: invalid fingerprint length")
+	}
+
+	encoded := strings.ToUpper(hex.EncodeToString(data))
+	var formatted strings.Builder
+	groups := len(encoded) / 4
+	for i := 0; i < groups; i++ {
+		switch {
+		case i == 0:
+		case i == groups/2 && len(data) != keyIDLength:
+			formatted.WriteString("  ")
+		default:
+			formatted.WriteString(" ")
+		}
+		formatted.WriteString(encoded[4*i : 4*i+4])
+	}
+	return formatted.String(), nil
+}
+
+// ParseFingerprint normalizes a user supplied v4 or v6 fingerprint to the
+// lower case hex encoding returned by Key.GetFingerprint. Whitespace, colons
+// and a "0x" prefix are ignored, and the case of the digits doesn't matter.
+func ParseFingerprint(fingerprint string) (string, error) {
+	data, err := parseHexIdentifier(fingerprint)
+	if err != nil {
+		return "", err
+	}
+	if len(data) != fingerprintV4Length && len(data) != fingerprintV6Length {
+		return "", errors.New("gopenpgp: invalid fingerprint length")
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// ParseKeyID parses a user supplied key ID, normalized like in
+// ParseFingerprint. The key ID of a v4 or v6 fingerprint is returned for
+// fingerprints.
+func ParseKeyID(keyID string) (uint64, error) {
+	data, err := parseHexIdentifier(keyID)
+	if err != nil {
+		return 0, err
+	}
+	switch len(data) {
+	case keyIDLength:
+		return binary.BigEndian.Uint64(data), nil
+	case fingerprintV4Length:
+		return binary.BigEndian.Uint64(data[fingerprintV4Length-keyIDLength:]), nil
+	case fingerprintV6Length:
+		return binary.BigEndian.Uint64(data[:keyIDLength]), nil
+	default:
+		return 0, errors.New("gopenpgp: invalid key ID length")
+	}
+}
+
+// CompareFingerprints returns true if the fingerprints are equal after
+// normalization, see ParseFingerprint. The comparison is in constant time.
+func CompareFingerprints(fingerprint, otherFingerprint string) bool {
+	data, err := parseHexIdentifier(fingerprint)
+	if err != nil {
+		return false
+	}
+	otherData, err := parseHexIdentifier(otherFingerprint)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(data, otherData) == 1
+}
+
+// MatchesFingerprint returns true if the user supplied fingerprint is the
+// fingerprint of the primary key, see CompareFingerprints.
+func (key *Key) MatchesFingerprint(fingerprint string) bool {
+	return CompareFingerprints(key.GetFingerprint(), fingerprint)
+}
+
+// GetFingerprintWords encodes a fingerprint or key ID with the PGP word list,
+// e.g. to compare fingerprints over the phone: the bytes at even positions
+// are encoded with two-syllable words, and the ones at odd positions with
+// three-syllable words, so that swapped or missing words are detected.
+func GetFingerprintWords(fingerprint string) ([]string, error) {
+	data, err := parseHexIdentifier(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]string, len(data))
+	for i, b := range data {
+		if i%2 == 0 {
+			words[i] = pgpEvenWords[b]
+		} else {
+			words[i] = pgpOddWords[b]
+		}
+	}
+	return words, nil
+}
+
+// GetFingerprintWords encodes the fingerprint of the primary key with the
+// PGP word list, see GetFingerprintWords.
+func (key *Key) GetFingerprintWords() []string {
+	words, _ := GetFingerprintWords(key.GetFingerprint())
+	return words
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// parseHexIdentifier decodes a hex fingerprint or key ID, ignoring
+// whitespace, colons and a "0x" prefix.
+func parseHexIdentifier(identifier string) ([]byte, error) {
+	identifier = strings.TrimSpace(identifier)
+	if strings.HasPrefix(identifier, "0x") || strings.HasPrefix(identifier, "0X") {
+		identifier = identifier[2:]
+	}
+	identifier = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', ':':
+			return -1
+		default:
+			return r
+		}
+	}, identifier)
+
+	data, err := hex.DecodeString(identifier)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("gopenpgp: invalid hex fingerprint or key ID")
+	}
+	return data, nil
+}
@@ -0,0 +1,76 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testFingerprint = "e58294f2e9a227486e8b061b31cc528fd7fa3f19"
+
+func TestFormatFingerprint(t *testing.T) {
+	formatted, err := FormatFingerprint(testFingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while formatting fingerprint, got:", err)
+	}
+	assert.Exactly(t, "E582 94F2 E9A2 2748 6E8B  061B 31CC 528F D7FA 3F19", formatted)
+
+	formatted, err = FormatFingerprint("0x31CC528FD7FA3F19")
+	if err != nil {
+		t.Fatal("Expected no error while formatting key ID, got:", err)
+	}
+	assert.Exactly(t, "31CC 528F D7FA 3F19", formatted)
+
+	_, err = FormatFingerprint("e58294")
+	assert.Error(t, err)
+}
+
+func TestParseFingerprint(t *testing.T) {
+	for _, input := range []string{
+		testFingerprint,
+		" 0xE58294F2E9A227486E8B061B31CC528FD7FA3F19\n",
+		"E582 94F2 E9A2 2748 6E8B  061B 31CC 528F D7FA 3F19",
+		"E5:82:94:F2:E9:A2:27:48:6E:8B:06:1B:31:CC:52:8F:D7:FA:3F:19",
+	} {
+		fingerprint, err := ParseFingerprint(input)
+		if err != nil {
+			t.Fatal("Expected no error while parsing fingerprint, got:", err)
+		}
+		assert.Exactly(t, testFingerprint, fingerprint)
+		assert.True(t, CompareFingerprints(testFingerprint, input))
+	}
+
+	for _, input := range []string{"", "0x", "e58294f2e9a227486e8b061b31cc528fd7fa3f1", "g58294f2e9a227486e8b061b31cc528fd7fa3f19"} {
+		_, err := ParseFingerprint(input)
+		assert.Error(t, err)
+	}
+	assert.False(t, CompareFingerprints(testFingerprint, strings.Replace(testFingerprint, "e5", "e6", 1)))
+
+	keyID, err := ParseKeyID(testFingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key ID, got:", err)
+	}
+	assert.Exactly(t, uint64(0x31cc528fd7fa3f19), keyID)
+
+	assert.True(t, keyTestEC.MatchesFingerprint(strings.ToUpper(keyTestEC.GetFingerprint())))
+	assert.False(t, keyTestEC.MatchesFingerprint(keyTestRSA.GetFingerprint()))
+	keyID, err = ParseKeyID(keyTestEC.GetFingerprint())
+	if err != nil {
+		t.Fatal("Expected no error while parsing key ID, got:", err)
+	}
+	assert.Exactly(t, keyTestEC.GetKeyID(), keyID)
+}
+
+func TestGetFingerprintWords(t *testing.T) {
+	words, err := GetFingerprintWords(testFingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while encoding fingerprint, got:", err)
+	}
+	assert.Exactly(t,
+		"topmost Istanbul Pluto vagabond treadmill Pacific brackish dictator goldfish Medusa "+
+			"afflict bravado chatter revolver Dupont midsummer stopwatch whimsical cowbell bottomless",
+		strings.Join(words, " "),
+	)
+	assert.Len(t, keyTestEC.GetFingerprintWords(), 20)
+}
@@ -0,0 +1,154 @@
+//go:build go1.18
+// +build go1.18
+
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+// The fuzz targets check that parsing untrusted input never panics.
+// Their seed corpora are run with the other tests; to fuzz, run e.g.
+//   go test ./crypto -run '^$' -fuzz FuzzKeyParsing
+
+var fuzzMessageSeeds = []string{
+	"issue11_message",
+	"keyring_token",
+	"message_badmdc",
+	"message_expired",
+	"message_mixedPasswordPublic",
+	"message_multipleKeyID",
+	"message_plainSignature",
+	"message_sha1_signed",
+	"message_sha256_signed",
+	"message_signed",
+	"mime_pgpMessage",
+	"sed_message",
+}
+
+var fuzzKeySeeds = []string{
+	"att_key",
+	"gpg2.3-aead-test-key.asc",
+	"issue11_privatekey",
+	"issue11_publickey",
+	"key_dummy",
+	"key_expiredKey",
+	"key_futureKey",
+	"key_mismatching_eddsa_key",
+	"key_revoked",
+	"keyring_privateKey",
+	"keyring_privateKeyLegacy",
+	"keyring_publicKey",
+	"keyring_userKey",
+	"sed_key",
+}
+
+func FuzzPacketParsing(f *testing.F) {
+	for _, name := range fuzzMessageSeeds {
+		message, err := NewPGPMessageFromArmored(readTestFile(name, false))
+		if err != nil {
+			f.Fatal("Expected no error while unarmoring seed "+name+", got:", err)
+		}
+		f.Add(message.GetBinary())
+	}
+	f.Add([]byte(readTestFile("gpg2.3-aead-pgp-message.pgp", false)))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		message := NewPGPMessage(data)
+		_, _ = message.GetEncryptionKeyIDs()
+		_, _ = message.GetSignatureKeyIDs()
+		if split, err := message.SplitMessage(); err == nil {
+			_, _ = keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket())
+		}
+		_, _ = keyRingTestPrivate.Decrypt(message, keyRingTestPublic, 0)
+		_ = keyRingTestPublic.VerifyDetached(NewPlainMessageFromString(testMessage), NewPGPSignature(data), 0)
+	})
+}
+
+func FuzzArmorDecoding(f *testing.F) {
+	for _, name := range append(append([]string{}, fuzzMessageSeeds...), fuzzKeySeeds...) {
+		f.Add(readTestFile(name, false))
+	}
+
+	f.Fuzz(func(t *testing.T, armored string) {
+		data, err := armor.Unarmor(armored)
+		if err != nil {
+			return
+		}
+		rearmored, err := armor.ArmorWithType(data, constants.PGPMessageHeader)
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		unarmored, err := armor.Unarmor(rearmored)
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring, got:", err)
+		}
+		if !bytes.Equal(data, unarmored) {
+			t.Fatal("Expected the data to survive an armor round trip")
+		}
+
+		limited, err := armor.UnarmorWithLimits(armored, armor.NewLimits(0, 0, int64(len(data))))
+		if err != nil {
+			t.Fatal("Expected no error while unarmoring with limits, got:", err)
+		}
+		if !bytes.Equal(data, limited) {
+			t.Fatal("Expected the same data when unarmoring with limits")
+		}
+	})
+}
+
+func FuzzKeyParsing(f *testing.F) {
+	for _, name := range fuzzKeySeeds {
+		f.Add(readTestFile(name, false))
+	}
+
+	// Unlocking is not fuzzed: the S2K parameters of the input make it
+	// deliberately expensive, which stalls the fuzzer.
+	f.Fuzz(func(t *testing.T, armored string) {
+		key, err := NewKeyFromArmored(armored)
+		if err != nil {
+			_, _ = NewKey([]byte(armored))
+			_, _ = NewKeyRingFromBinary([]byte(armored))
+			return
+		}
+		_ = key.GetFingerprint()
+		_ = key.IsExpired()
+		_ = key.IsRevoked()
+		_ = key.CanEncrypt()
+		_ = key.CanVerify()
+		_, _ = key.Check()
+		_, _ = key.GetArmoredPublicKey()
+		if _, err = key.Serialize(); err != nil {
+			t.Fatal("Expected no error while serializing a parsed key, got:", err)
+		}
+	})
+}
+
+func FuzzCleartextParsing(f *testing.F) {
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		f.Fatal("Expected no error while signing, got:", err)
+	}
+	armored, err := NewClearTextMessage(message.GetBinary(), signature.GetBinary()).GetArmored()
+	if err != nil {
+		f.Fatal("Expected no error while armoring, got:", err)
+	}
+	f.Add(armored)
+
+	f.Fuzz(func(t *testing.T, armored string) {
+		clearTextMessage, err := NewClearTextMessageFromArmored(armored)
+		if err != nil {
+			return
+		}
+		_ = keyRingTestPublic.VerifyDetached(
+			NewPlainMessageFromString(clearTextMessage.GetString()),
+			NewPGPSignature(clearTextMessage.GetBinarySignature()),
+			0,
+		)
+	})
+}
@@ -1,19 +1,37 @@
 // Package crypto provides a high-level API for common OpenPGP functionality.
 package crypto
 
-import "sync"
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+)
 
 // GopenPGP is used as a "namespace" for many of the functions in this package.
 // It is a struct that keeps track of time skew between server and client.
 type GopenPGP struct {
-	latestServerTime int64
-	generationOffset int64
-	lock             *sync.RWMutex
+	latestServerTime    int64
+	generationOffset    int64
+	timeTolerance       int64
+	lockedMemory        bool
+	random              io.Reader
+	clock               func() time.Time
+	logger              Logger
+	metrics             Metrics
+	keyUsageAuditor     KeyUsageAuditor
+	s2kCount            int
+	decompressionLimits *DecompressionLimits
+	configModifier      func(*packet.Config)
+	lock                *sync.RWMutex
 }
 
 var pgp = GopenPGP{
 	latestServerTime: 0,
 	generationOffset: 0,
+	timeTolerance:    internal.CreationTimeOffset,
 	lock:             &sync.RWMutex{},
 }
 
@@ -0,0 +1,205 @@
+package crypto
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// VerificationResult is the outcome of a signature verification, which can
+// be serialized to log or transport it between services.
+type VerificationResult struct {
+	// Summary is one of the constants.VERIFICATION_* statuses.
+	Summary int
+	// Status is one of the constants.SIGNATURE_* statuses.
+	Status int
+	// Message describes the verification failure, empty on success.
+	Message string
+}
+
+// NewVerificationResult creates the result of a verification from the error
+// returned by e.g. KeyRing.VerifyDetached, nil if the signature is valid.
+func NewVerificationResult(verificationErr error) *VerificationResult {
+	result := &VerificationResult{Summary: GetVerificationSummary(verificationErr)}
+	if verificationErr == nil {
+		return result
+	}
+	result.Message = verificationErr.Error()
+	if signatureError := (SignatureVerificationError{}); errors.As(verificationErr, &signatureError) {
+		result.Status = signatureError.Status
+	} else {
+		result.Status = constants.SIGNATURE_FAILED
+	}
+	return result
+}
+
+// GetJSON serializes the result with the versioned JSON schema:
+//
+//	{
+//	  "schemaVersion": 1,
+//	  "summary": "valid" | "valid insecure algorithm" | "expired" |
+//	             "revoked" | "no matching key" | "not signed" | "invalid",
+//	  "status": "ok" | "not signed" | "no verifier" | "failed" | "bad context",
+//	  "message": string, omitted on success
+//	}
+func (result *VerificationResult) GetJSON() (string, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to serialize verification result")
+	}
+	return string(data), nil
+}
+
+// NewVerificationResultFromJSON parses a result serialized with GetJSON.
+func NewVerificationResultFromJSON(data string) (*VerificationResult, error) {
+	result := &VerificationResult{}
+	if err := json.Unmarshal([]byte(data), result); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse verification result")
+	}
+	return result, nil
+}
+
+// MarshalJSON implements json.Marshaler, see GetJSON.
+func (result *VerificationResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&verificationResultJSON{
+		SchemaVersion: constants.JSONSchemaVersion,
+		Summary:       verificationSummaryToString(result.Summary),
+		Status:        signatureStatusToString(result.Status),
+		Message:       result.Message,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see GetJSON.
+func (result *VerificationResult) UnmarshalJSON(data []byte) error {
+	var decoded verificationResultJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(decoded.SchemaVersion); err != nil {
+		return err
+	}
+	summary, ok := parseStatus(decoded.Summary, verificationSummaryToString, constants.VERIFICATION_INVALID)
+	if !ok {
+		return errors.New("gopenpgp: unknown verification summary " + strconv.Quote(decoded.Summary))
+	}
+	status, ok := parseStatus(decoded.Status, signatureStatusToString, constants.SIGNATURE_BAD_CONTEXT)
+	if !ok {
+		return errors.New("gopenpgp: unknown signature status " + strconv.Quote(decoded.Status))
+	}
+	*result = VerificationResult{Summary: summary, Status: status, Message: decoded.Message}
+	return nil
+}
+
+// GetJSON serializes the key information with the versioned JSON schema:
+//
+//	{
+//	  "schemaVersion": 1,
+//	  "primaryKey": component,
+//	  "subkeys": [component, ...],
+//	  "userIDs": [string, ...],
+//	  "isPrivate": bool
+//	}
+//
+// where a component is:
+//
+//	{
+//	  "fingerprint": string, "keyID": string, "version": int,
+//	  "algorithm": string, "algorithmID": int, "bitLength": int,
+//	  "curve": string, omitted for non-ECC keys,
+//	  "creationTime": int, "expirationTime": int,
+//	  "canCertify": bool, "canSign": bool, "canEncryptCommunications": bool,
+//	  "canEncryptStorage": bool, "canAuthenticate": bool,
+//	  "isRevoked": bool, "isExpired": bool
+//	}
+//
+// Times are unix timestamps, see KeyComponentInfo.
+func (info *KeyInfo) GetJSON() (string, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to serialize key info")
+	}
+	return string(data), nil
+}
+
+// NewKeyInfoFromJSON parses key information serialized with GetJSON.
+func NewKeyInfoFromJSON(data string) (*KeyInfo, error) {
+	info := &KeyInfo{}
+	if err := json.Unmarshal([]byte(data), info); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse key info")
+	}
+	return info, nil
+}
+
+// MarshalJSON implements json.Marshaler, see GetJSON.
+func (info *KeyInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&keyInfoJSON{
+		SchemaVersion: constants.JSONSchemaVersion,
+		keyInfo:       (*keyInfo)(info),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see GetJSON.
+func (info *KeyInfo) UnmarshalJSON(data []byte) error {
+	decoded := keyInfoJSON{keyInfo: (*keyInfo)(info)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	return checkSchemaVersion(decoded.SchemaVersion)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+type verificationResultJSON struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Summary       string `json:"summary"`
+	Status        string `json:"status"`
+	Message       string `json:"message,omitempty"`
+}
+
+// keyInfo has the fields of KeyInfo, without its JSON methods.
+type keyInfo KeyInfo
+
+type keyInfoJSON struct {
+	SchemaVersion int `json:"schemaVersion"`
+	*keyInfo
+}
+
+func checkSchemaVersion(version int) error {
+	if version != constants.JSONSchemaVersion {
+		return errors.New("gopenpgp: unsupported JSON schema version " + strconv.Itoa(version))
+	}
+	return nil
+}
+
+// parseStatus returns the status in [0, maxStatus] named name by toString.
+func parseStatus(name string, toString func(int) string, maxStatus int) (int, bool) {
+	for status := 0; status <= maxStatus; status++ {
+		if toString(status) == name {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+func verificationSummaryToString(summary int) string {
+	switch summary {
+	case constants.VERIFICATION_VALID:
+		return "valid"
+	case constants.VERIFICATION_VALID_INSECURE_ALGORITHM:
+		return "valid insecure algorithm"
+	case constants.VERIFICATION_EXPIRED:
+		return "expired"
+	case constants.VERIFICATION_REVOKED:
+		return "revoked"
+	case constants.VERIFICATION_NO_MATCHING_KEY:
+		return "no matching key"
+	case constants.VERIFICATION_NOT_SIGNED:
+		return "not signed"
+	case constants.VERIFICATION_INVALID:
+		return "invalid"
+	default:
+		return "unknown"
+	}
+}
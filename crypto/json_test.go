@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationResultJSON(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	valid := NewVerificationResult(keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()))
+	serialized, err := valid.GetJSON()
+	if err != nil {
+		t.Fatal("Expected no error while serializing, got:", err)
+	}
+	assert.Exactly(t, `{"schemaVersion":1,"summary":"valid","status":"ok"}`, serialized)
+
+	invalid := NewVerificationResult(keyRingTestPublic.VerifyDetached(NewPlainMessageFromString("tampered"), signature, GetUnixTime()))
+	serialized, err = invalid.GetJSON()
+	if err != nil {
+		t.Fatal("Expected no error while serializing, got:", err)
+	}
+	parsed, err := NewVerificationResultFromJSON(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing, got:", err)
+	}
+	assert.Exactly(t, invalid, parsed)
+	assert.Exactly(t, constants.VERIFICATION_INVALID, parsed.Summary)
+	assert.Exactly(t, constants.SIGNATURE_FAILED, parsed.Status)
+	assert.NotEmpty(t, parsed.Message)
+
+	_, err = NewVerificationResultFromJSON(`{"schemaVersion":2,"summary":"valid","status":"ok"}`)
+	assert.Error(t, err)
+	_, err = NewVerificationResultFromJSON(`{"schemaVersion":1,"summary":"trusted","status":"ok"}`)
+	assert.Error(t, err)
+}
+
+func TestKeyInfoJSON(t *testing.T) {
+	info := keyTestEC.GetInfo()
+	serialized, err := info.GetJSON()
+	if err != nil {
+		t.Fatal("Expected no error while serializing, got:", err)
+	}
+	assert.Contains(t, serialized, `"schemaVersion":1`)
+	assert.Contains(t, serialized, `"curve":"Curve25519"`)
+
+	parsed, err := NewKeyInfoFromJSON(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing, got:", err)
+	}
+	assert.Exactly(t, info, parsed)
+
+	_, err = NewKeyInfoFromJSON(`{"primaryKey":null}`)
+	assert.Error(t, err)
+}
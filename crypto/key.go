@@ -7,22 +7,30 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ProtonMail/gopenpgp/v2/armor"
 	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
 	"github.com/pkg/errors"
 
 	openpgp "github.com/ProtonMail/go-crypto/openpgp"
 	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
 )
 
 // Key contains a single private or public key.
 type Key struct {
 	// PGP entities in this keyring.
 	entity *openpgp.Entity
+	// userAttributes are ignored by go-crypto, and kept separately.
+	userAttributes []*UserAttribute
+	// attestations are rejected by go-crypto, and kept separately.
+	attestations []*Attestation
 }
 
 // --- Create Key object
@@ -72,14 +80,26 @@ func GenerateRSAKeyWithPrimes(
 	bits int,
 	primeone, primetwo, primethree, primefour []byte,
 ) (*Key, error) {
-	return generateKey(name, email, "rsa", bits, primeone, primetwo, primethree, primefour)
+	return generateKey(name, email, &keyGenerationOptions{
+		keyType:   "rsa",
+		bits:      bits,
+		rsaPrimes: [][]byte{primeone, primetwo, primethree, primefour},
+	})
 }
 
 // GenerateKey generates a key of the given keyType ("rsa" or "x25519").
 // If keyType is "rsa", bits is the RSA bitsize of the key.
 // If keyType is "x25519" bits is unused.
 func GenerateKey(name, email string, keyType string, bits int) (*Key, error) {
-	return generateKey(name, email, keyType, bits, nil, nil, nil, nil)
+	return generateKey(name, email, &keyGenerationOptions{keyType: keyType, bits: bits})
+}
+
+// GenerateKeyV6 generates a version 6 key of the given keyType ("rsa" or "x25519"),
+// advertising support for AEAD encrypted data (SEIPDv2).
+// If keyType is "rsa", bits is the RSA bitsize of the key.
+// If keyType is "x25519" bits is unused.
+func GenerateKeyV6(name, email string, keyType string, bits int) (*Key, error) {
+	return generateKey(name, email, &keyGenerationOptions{keyType: keyType, bits: bits, v6: true})
 }
 
 // --- Operate on key
@@ -95,49 +115,34 @@ func (key *Key) Copy() (*Key, error) {
 }
 
 // Lock locks a copy of the key.
+// The secret key material is protected with an iterated and salted S2K and
+// CFB encryption, which all OpenPGP implementations support. The iteration
+// count can be set with SetS2KCount.
 func (key *Key) Lock(passphrase []byte) (*Key, error) {
-	unlocked, err := key.IsUnlocked()
-	if err != nil {
-		return nil, err
-	}
-
-	if !unlocked {
-		return nil, errors.New("gopenpgp: key is not unlocked")
-	}
-
-	lockedKey, err := key.Copy()
-	if err != nil {
-		return nil, err
-	}
-
-	if passphrase == nil {
-		return lockedKey, nil
-	}
-
-	if lockedKey.entity.PrivateKey != nil && !lockedKey.entity.PrivateKey.Dummy() {
-		err = lockedKey.entity.PrivateKey.Encrypt(passphrase)
-		if err != nil {
-			return nil, errors.Wrap(err, "gopenpgp: error in locking key")
-		}
-	}
-
-	for _, sub := range lockedKey.entity.Subkeys {
-		if sub.PrivateKey != nil && !sub.PrivateKey.Dummy() {
-			if err := sub.PrivateKey.Encrypt(passphrase); err != nil {
-				return nil, errors.Wrap(err, "gopenpgp: error in locking sub key")
-			}
-		}
-	}
-
-	locked, err := lockedKey.IsLocked()
-	if err != nil {
-		return nil, err
-	}
-	if !locked {
-		return nil, errors.New("gopenpgp: unable to lock key")
-	}
-
-	return lockedKey, nil
+	return key.lock(passphrase, &packet.Config{
+		Rand: getRandomSource(),
+		S2KConfig: &s2k.Config{
+			S2KMode:  s2k.IteratedSaltedS2K,
+			S2KCount: getS2KCount(defaultLockS2KCount),
+			Hash:     crypto.SHA256,
+		},
+		DefaultCipher: packet.CipherAES256,
+	})
+}
+
+// LockWithAEAD locks a copy of the key, using the secret key protection
+// introduced by RFC 9580: the passphrase is stretched with Argon2 and the
+// secret key material is encrypted with AES-256 in OCB mode.
+// Older OpenPGP implementations may not be able to unlock such a key.
+func (key *Key) LockWithAEAD(passphrase []byte) (*Key, error) {
+	return key.lock(passphrase, &packet.Config{
+		Rand: getRandomSource(),
+		S2KConfig: &s2k.Config{
+			S2KMode: s2k.Argon2S2K,
+		},
+		DefaultCipher: packet.CipherAES256,
+		AEADConfig:    &packet.AEADConfig{DefaultMode: packet.AEADModeOCB},
+	})
 }
 
 // Unlock unlocks a copy of the key.
@@ -162,14 +167,14 @@ func (key *Key) Unlock(passphrase []byte) (*Key, error) {
 	if unlockedKey.entity.PrivateKey != nil && !unlockedKey.entity.PrivateKey.Dummy() {
 		err = unlockedKey.entity.PrivateKey.Decrypt(passphrase)
 		if err != nil {
-			return nil, errors.Wrap(err, "gopenpgp: error in unlocking key")
+			return nil, newUnlockError(err, "gopenpgp: error in unlocking key")
 		}
 	}
 
 	for _, sub := range unlockedKey.entity.Subkeys {
 		if sub.PrivateKey != nil && !sub.PrivateKey.Dummy() {
 			if err := sub.PrivateKey.Decrypt(passphrase); err != nil {
-				return nil, errors.Wrap(err, "gopenpgp: error in unlocking sub key")
+				return nil, newUnlockError(err, "gopenpgp: error in unlocking sub key")
 			}
 		}
 	}
@@ -185,6 +190,37 @@ func (key *Key) Unlock(passphrase []byte) (*Key, error) {
 	return unlockedKey, nil
 }
 
+// UnlockSubkey unlocks a copy of the key, decrypting only the primary key or
+// subkey with the given hex fingerprint. This allows using keys whose subkeys
+// are protected with different passphrases: each one is unlocked in turn,
+// and the partially unlocked key can be used in a keyring for the operations
+// whose key is unlocked.
+func (key *Key) UnlockSubkey(fingerprint string, passphrase []byte) (*Key, error) {
+	if key.entity.PrivateKey == nil {
+		return nil, errors.New("gopenpgp: a public key cannot be unlocked")
+	}
+
+	unlockedKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey := unlockedKey.getPrivateKeyByFingerprint(fingerprint)
+	if privateKey == nil {
+		return nil, errors.New("gopenpgp: no private key with fingerprint " + fingerprint)
+	}
+
+	if privateKey.Dummy() || !privateKey.Encrypted {
+		return nil, errors.New("gopenpgp: key is not locked")
+	}
+
+	if err = privateKey.Decrypt(passphrase); err != nil {
+		return nil, newUnlockError(err, "gopenpgp: error in unlocking sub key")
+	}
+
+	return unlockedKey, nil
+}
+
 // --- Export key
 
 func (key *Key) Serialize() ([]byte, error) {
@@ -201,7 +237,11 @@ func (key *Key) Serialize() ([]byte, error) {
 		return nil, errors.Wrap(err, "gopenpgp: error in serializing key")
 	}
 
-	return buffer.Bytes(), nil
+	serialized, err := key.insertAttestations(buffer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return key.insertUserAttributes(serialized)
 }
 
 // Armor returns the armored key as a string with default gopenpgp headers.
@@ -257,28 +297,38 @@ func (key *Key) GetPublicKey() (b []byte, err error) {
 		return nil, errors.Wrap(err, "gopenpgp: error in serializing public key")
 	}
 
-	return outBuf.Bytes(), nil
+	serialized, err := key.insertAttestations(outBuf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return key.insertUserAttributes(serialized)
 }
 
 // --- Key object properties
 
 // CanVerify returns true if any of the subkeys can be used for verification.
 func (key *Key) CanVerify() bool {
-	_, canVerify := key.entity.SigningKey(getNow())
-	return canVerify
+	return validWithTolerance(func(now time.Time) bool {
+		_, canVerify := key.entity.SigningKey(now)
+		return canVerify
+	})
 }
 
 // CanEncrypt returns true if any of the subkeys can be used for encryption.
 func (key *Key) CanEncrypt() bool {
-	_, canEncrypt := key.entity.EncryptionKey(getNow())
-	return canEncrypt
+	return validWithTolerance(func(now time.Time) bool {
+		_, canEncrypt := key.entity.EncryptionKey(now)
+		return canEncrypt
+	})
 }
 
 // IsExpired checks whether the key is expired.
 func (key *Key) IsExpired() bool {
 	i := key.entity.PrimaryIdentity()
-	return key.entity.PrimaryKey.KeyExpired(i.SelfSignature, getNow()) || // primary key has expired
-		i.SelfSignature.SigExpired(getNow()) // user ID self-signature has expired
+	return !validWithTolerance(func(now time.Time) bool {
+		return !key.entity.PrimaryKey.KeyExpired(i.SelfSignature, now) && // primary key has not expired
+			!i.SelfSignature.SigExpired(now) // user ID self-signature has not expired
+	})
 }
 
 // IsRevoked checks whether the key or the primary identity has a valid revocation signature.
@@ -312,6 +362,23 @@ func (key *Key) IsLocked() (bool, error) {
 	return encryptedKeys > 0, nil
 }
 
+// IsStub checks if the primary key is a GNU-dummy stub, i.e. a private key
+// whose secret material is not available, because it is stored offline or
+// on a smartcard. A stub primary key can't sign nor certify.
+func (key *Key) IsStub() bool {
+	return key.entity.PrivateKey != nil && key.entity.PrivateKey.Dummy()
+}
+
+// IsSubkeyStub checks if the primary key or subkey with the given hex
+// fingerprint is a GNU-dummy stub.
+func (key *Key) IsSubkeyStub(fingerprint string) (bool, error) {
+	privateKey := key.getPrivateKeyByFingerprint(fingerprint)
+	if privateKey == nil {
+		return false, errors.New("gopenpgp: no private key with fingerprint " + fingerprint)
+	}
+	return privateKey.Dummy(), nil
+}
+
 // IsUnlocked checks if a private key is unlocked.
 func (key *Key) IsUnlocked() (bool, error) {
 	if key.entity.PrivateKey == nil {
@@ -374,6 +441,11 @@ func (key *Key) GetSHA256Fingerprints() (fingerprints []string) {
 	return
 }
 
+// GetVersion returns the version of the primary key, 4 or 6.
+func (key *Key) GetVersion() int {
+	return key.entity.PrimaryKey.Version
+}
+
 // GetEntity gets x/crypto Entity object.
 func (key *Key) GetEntity() *openpgp.Entity {
 	return key.entity
@@ -394,8 +466,93 @@ func (key *Key) ToPublic() (publicKey *Key, err error) {
 	return
 }
 
+// GetEncryptionCertificate returns a minimal public copy of the key, containing
+// only the primary key, its primary self-signature and revocations, and the
+// encryption subkey that is currently used, e.g. to reduce what needs to be
+// sent to senders.
+func (key *Key) GetEncryptionCertificate() (*Key, error) {
+	entity := key.entity
+	var encryptionKey openpgp.Key
+	ok := validWithTolerance(func(now time.Time) (ok bool) {
+		encryptionKey, ok = entity.EncryptionKey(now)
+		return ok
+	})
+	if !ok {
+		return nil, newKeyCapabilityError(entity, constants.KeyCapabilityEncrypt)
+	}
+
+	return newMinimalCertificate(entity, []openpgp.Subkey{{
+		PublicKey: encryptionKey.PublicKey,
+		Sig:       encryptionKey.SelfSignature,
+	}})
+}
+
+// ExportCompactBinary returns a minimized binary public key, e.g. to fit in a
+// QR code: it only contains the primary key, its primary user ID and the
+// subkeys which are currently valid or revoked, with their latest
+// self-signatures, and the revocations of the primary key and subkeys.
+// Revoked subkeys are kept so that the recipient learns of the revocation
+// if it already has them.
+func (key *Key) ExportCompactBinary() ([]byte, error) {
+	entity := key.entity
+	now := getNow()
+	var subkeys []openpgp.Subkey
+	for _, subkey := range entity.Subkeys {
+		if subkey.Sig == nil {
+			continue
+		}
+		revoked := subkey.Revoked(now)
+		if !revoked && subkey.PublicKey.KeyExpired(subkey.Sig, now) {
+			continue
+		}
+		compactSubkey := openpgp.Subkey{PublicKey: subkey.PublicKey, Sig: subkey.Sig}
+		if revoked {
+			compactSubkey.Revocations = subkey.Revocations
+		}
+		subkeys = append(subkeys, compactSubkey)
+	}
+
+	certificate, err := newMinimalCertificate(entity, subkeys)
+	if err != nil {
+		return nil, err
+	}
+	return certificate.GetPublicKey()
+}
+
 // --- Internal methods
 
+// newMinimalCertificate returns a public copy of the entity with only the
+// primary key, its direct and primary user ID self-signatures, its
+// revocations and the given subkeys.
+func newMinimalCertificate(entity *openpgp.Entity, subkeys []openpgp.Subkey) (*Key, error) {
+	certificate := &openpgp.Entity{
+		PrimaryKey:    entity.PrimaryKey,
+		Identities:    make(map[string]*openpgp.Identity),
+		Revocations:   entity.Revocations,
+		SelfSignature: entity.SelfSignature,
+		Subkeys:       subkeys,
+	}
+	if entity.SelfSignature != nil {
+		certificate.Signatures = []*packet.Signature{entity.SelfSignature}
+	}
+	if _, identity := entity.PrimarySelfSignature(); identity != nil {
+		certificate.Identities[identity.Name] = &openpgp.Identity{
+			Name:          identity.Name,
+			UserId:        identity.UserId,
+			SelfSignature: identity.SelfSignature,
+			Signatures:    []*packet.Signature{identity.SelfSignature},
+		}
+	}
+
+	// Serializing only writes the public packets, parse them back to obtain
+	// an independent copy
+	var serialized bytes.Buffer
+	if err := certificate.Serialize(&serialized); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing certificate")
+	}
+	return NewKey(serialized.Bytes())
+}
+
 // getSHA256FingerprintBytes computes the SHA256 fingerprint of a public key
 // object.
 func getSHA256FingerprintBytes(pk *packet.PublicKey) []byte {
@@ -407,18 +564,92 @@ func getSHA256FingerprintBytes(pk *packet.PublicKey) []byte {
 	return fingerPrint.Sum(nil)
 }
 
+// lock locks a copy of the key, protecting all its unencrypted secret keys
+// with a single key derived from the passphrase with the given config.
+func (key *Key) lock(passphrase []byte, config *packet.Config) (*Key, error) {
+	applyConfigModifier(config)
+	unlocked, err := key.IsUnlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	if !unlocked {
+		return nil, errors.New("gopenpgp: key is not unlocked")
+	}
+
+	lockedKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+
+	if passphrase == nil {
+		return lockedKey, nil
+	}
+
+	privateKeys := []*packet.PrivateKey{lockedKey.entity.PrivateKey}
+	for _, sub := range lockedKey.entity.Subkeys {
+		privateKeys = append(privateKeys, sub.PrivateKey)
+	}
+	if err = packet.EncryptPrivateKeys(privateKeys, passphrase, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in locking key")
+	}
+
+	locked, err := lockedKey.IsLocked()
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, errors.New("gopenpgp: unable to lock key")
+	}
+
+	return lockedKey, nil
+}
+
+// getPrivateKeyByFingerprint returns the private primary key or subkey with
+// the given hex fingerprint, or nil if there is none.
+func (key *Key) getPrivateKeyByFingerprint(fingerprint string) *packet.PrivateKey {
+	if strings.EqualFold(hex.EncodeToString(key.entity.PrimaryKey.Fingerprint), fingerprint) {
+		return key.entity.PrivateKey
+	}
+	for _, sub := range key.entity.Subkeys {
+		if strings.EqualFold(hex.EncodeToString(sub.PublicKey.Fingerprint), fingerprint) {
+			return sub.PrivateKey
+		}
+	}
+	return nil
+}
+
+// hasUnlockedKey checks if at least one of the primary key and subkeys is
+// an unlocked private key.
+func (key *Key) hasUnlockedKey() bool {
+	if key.entity.PrivateKey != nil && !key.entity.PrivateKey.Dummy() && !key.entity.PrivateKey.Encrypted {
+		return true
+	}
+	for _, sub := range key.entity.Subkeys {
+		if sub.PrivateKey != nil && !sub.PrivateKey.Dummy() && !sub.PrivateKey.Encrypted {
+			return true
+		}
+	}
+	return false
+}
+
 // readFrom reads unarmored and armored keys from r and adds them to the keyring.
 func (key *Key) readFrom(r io.Reader, armored bool) error {
 	var err error
-	var entities openpgp.EntityList
+	var data []byte
 	if armored {
-		entities, err = openpgp.ReadArmoredKeyRing(r)
+		data, err = readArmoredKeyData(r)
 	} else {
-		entities, err = openpgp.ReadKeyRing(r)
+		data, err = ioutil.ReadAll(r)
 	}
 	if err != nil {
 		return errors.Wrap(err, "gopenpgp: error in reading key ring")
 	}
+	data, attestations := splitAttestations(data)
+	entities, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: error in reading key ring")
+	}
 
 	if len(entities) > 1 {
 		return errors.New("gopenpgp: the key contains too many entities")
@@ -429,44 +660,56 @@ func (key *Key) readFrom(r io.Reader, armored bool) error {
 	}
 
 	key.entity = entities[0]
+	key.userAttributes = readUserAttributes(key.entity.PrimaryKey, data)
+	key.attestations = readAttestations(key, attestations)
 	return nil
 }
 
-func generateKey(
-	name, email string,
-	keyType string,
-	bits int,
-	prime1, prime2, prime3, prime4 []byte,
-) (*Key, error) {
+// readArmoredKeyData reads the content of the armored block read from r,
+// which must be a public or private key block.
+func readArmoredKeyData(r io.Reader) ([]byte, error) {
+	block, err := internal.UnarmorWithLimits(r, 0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := internal.CheckArmorType(block.Type, constants.PublicKeyHeader, constants.PrivateKeyHeader); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(block.Body)
+}
+
+// keyGenerationOptions are the parameters of the keys generated by
+// generateKey.
+type keyGenerationOptions struct {
+	// keyType is "rsa" or "x25519".
+	keyType string
+	// bits is the RSA bitsize of the key, unused for "x25519".
+	bits int
+	// v6 generates a version 6 key.
+	v6 bool
+	// notations are added to the self-signatures of the key.
+	notations []*packet.Notation
+	// rsaPrimes are the four primes of the RSA keys, used only if all are set.
+	rsaPrimes [][]byte
+	// rsaExponent is the public exponent of the RSA keys, 65537 if zero.
+	rsaExponent int
+}
+
+func generateKey(name, email string, options *keyGenerationOptions) (*Key, error) {
 	if len(email) == 0 && len(name) == 0 {
 		return nil, errors.New("gopenpgp: neither name nor email set.")
 	}
 
 	comments := ""
 
-	cfg := &packet.Config{
-		Algorithm:              packet.PubKeyAlgoRSA,
-		RSABits:                bits,
-		Time:                   getKeyGenerationTimeGenerator(),
-		DefaultHash:            crypto.SHA256,
-		DefaultCipher:          packet.CipherAES256,
-		DefaultCompressionAlgo: packet.CompressionZLIB,
-	}
+	cfg := getKeyGenerationConfig(options.keyType, options.bits, options.v6)
+	cfg.SignatureNotations = options.notations
 
-	if keyType == "x25519" {
-		cfg.Algorithm = packet.PubKeyAlgoEdDSA
-	}
-
-	if prime1 != nil && prime2 != nil && prime3 != nil && prime4 != nil {
+	if hasAllPrimes(options.rsaPrimes) {
 		var bigPrimes [4]*big.Int
-		bigPrimes[0] = new(big.Int)
-		bigPrimes[0].SetBytes(prime1)
-		bigPrimes[1] = new(big.Int)
-		bigPrimes[1].SetBytes(prime2)
-		bigPrimes[2] = new(big.Int)
-		bigPrimes[2].SetBytes(prime3)
-		bigPrimes[3] = new(big.Int)
-		bigPrimes[3].SetBytes(prime4)
+		for i := range bigPrimes {
+			bigPrimes[i] = new(big.Int).SetBytes(options.rsaPrimes[i])
+		}
 
 		cfg.RSAPrimes = bigPrimes[:]
 	}
@@ -480,9 +723,57 @@ func generateKey(
 		return nil, errors.New("gopenpgp: error in generating private key")
 	}
 
+	if options.rsaExponent != 0 && options.rsaExponent != defaultRSAExponent {
+		if err = setRSAExponent(newEntity, options.rsaExponent, cfg); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in setting RSA exponent")
+		}
+	}
+
 	return NewKeyFromEntity(newEntity)
 }
 
+// hasAllPrimes returns whether the four RSA primes are set.
+func hasAllPrimes(primes [][]byte) bool {
+	if len(primes) != 4 {
+		return false
+	}
+	for _, prime := range primes {
+		if prime == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// getKeyGenerationConfig returns the go-crypto configuration generating keys
+// of the given keyType and RSA bitsize.
+func getKeyGenerationConfig(keyType string, bits int, v6 bool) *packet.Config {
+	cfg := &packet.Config{
+		Rand:                   getRandomSource(),
+		Algorithm:              packet.PubKeyAlgoRSA,
+		RSABits:                bits,
+		Time:                   getKeyGenerationTimeGenerator(),
+		DefaultHash:            crypto.SHA256,
+		DefaultCipher:          packet.CipherAES256,
+		DefaultCompressionAlgo: packet.CompressionZLIB,
+	}
+
+	if keyType == "x25519" {
+		cfg.Algorithm = packet.PubKeyAlgoEdDSA
+	}
+
+	if v6 {
+		cfg.V6Keys = true
+		cfg.AEADConfig = &packet.AEADConfig{DefaultMode: packet.AEADModeOCB}
+		if keyType == "x25519" {
+			// The legacy EdDSA algorithm can't be used with v6 keys
+			cfg.Algorithm = packet.PubKeyAlgoEd25519
+		}
+	}
+	applyConfigModifier(cfg)
+	return cfg
+}
+
 // keyIDToHex casts a keyID to hex with the correct padding.
 func keyIDToHex(keyID uint64) string {
 	return fmt.Sprintf("%016v", strconv.FormatUint(keyID, 16))
@@ -0,0 +1,434 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// Attestation key signatures and their attested certifications subpacket,
+// see draft-ietf-openpgp-rfc4880bis-10, sections 5.2.1 and 5.2.3.30.
+const (
+	sigTypeAttestation                  packet.SignatureType = 0x16
+	subpacketTypeAttestedCertifications                      = 37
+)
+
+// packetTagUserID is the packet tag of user IDs.
+const packetTagUserID = 13
+
+// Attestation is an attestation key signature of a user ID: a
+// self-signature listing the third-party certifications of the user ID that
+// the key holder accepts to distribute with the key, so that
+// abuse-resistant keyservers only publish those, see the first-party
+// attested third-party certifications (1PA3PC) of
+// draft-ietf-openpgp-rfc4880bis-10, section 5.2.3.30.
+// go-crypto rejects keys with attestation key signatures, so they are only
+// kept by Key, and are lost in KeyRing. Only the newest attestation of each
+// user ID is kept, as it replaces the previous ones.
+type Attestation struct {
+	userID    string
+	signature *packet.Signature
+	// data is the serialized signature packet.
+	data []byte
+	// digests are the hashes of the attested certifications.
+	digests [][]byte
+}
+
+// GetUserID returns the user ID whose certifications are attested.
+func (attestation *Attestation) GetUserID() string {
+	return attestation.userID
+}
+
+// GetCreationTime returns the creation time of the attestation.
+func (attestation *Attestation) GetCreationTime() int64 {
+	return attestation.signature.CreationTime.Unix()
+}
+
+// GetAttestations returns the attestations of the user IDs of the key.
+func (key *Key) GetAttestations() []*Attestation {
+	return key.attestations
+}
+
+// GetThirdPartyCertifications returns the certifications of the user ID
+// made by other keys, as found in the key. They are not verified.
+func (key *Key) GetThirdPartyCertifications(userID string) ([]*PGPSignature, error) {
+	identity, ok := key.entity.Identities[userID]
+	if !ok {
+		return nil, errors.New("gopenpgp: user ID not found in key")
+	}
+	var certifications []*PGPSignature
+	for _, sig := range identity.Signatures {
+		if sig.CheckKeyIdOrFingerprint(key.entity.PrimaryKey) || !isCertification(sig) {
+			continue
+		}
+		certification, err := newPGPSignatureFromPacket(sig)
+		if err != nil {
+			return nil, err
+		}
+		certifications = append(certifications, certification)
+	}
+	return certifications, nil
+}
+
+// GetAttestedCertifications returns the third-party certifications of the
+// user ID attested by the newest attestation of the user ID, if any.
+func (key *Key) GetAttestedCertifications(userID string) ([]*PGPSignature, error) {
+	certifications, err := key.GetThirdPartyCertifications(userID)
+	if err != nil {
+		return nil, err
+	}
+	attestation := key.getAttestation(userID)
+	if attestation == nil {
+		return nil, nil
+	}
+	var attested []*PGPSignature
+	for _, certification := range certifications {
+		digest, err := getCertificationDigest(certification, attestation.signature.Hash.New())
+		if err != nil {
+			return nil, err
+		}
+		for _, attestedDigest := range attestation.digests {
+			if bytes.Equal(digest, attestedDigest) {
+				attested = append(attested, certification)
+				break
+			}
+		}
+	}
+	return attested, nil
+}
+
+// AttestCertifications returns a copy of the key with a new attestation of
+// the third-party certifications of the user ID, replacing the previous one.
+// Attesting no certifications withdraws the previous attestations.
+// The primary key must be unlocked, to sign the attestation.
+func (key *Key) AttestCertifications(userID string, certifications []*PGPSignature) (*Key, error) {
+	if _, ok := key.entity.Identities[userID]; !ok {
+		return nil, errors.New("gopenpgp: user ID not found in key")
+	}
+	privateKey := key.entity.PrivateKey
+	if privateKey == nil {
+		return nil, errors.New("gopenpgp: certifications can only be attested with a private key")
+	}
+	if privateKey.Dummy() {
+		return nil, StubKeyError{Fingerprint: key.GetFingerprint()}
+	}
+	if privateKey.Encrypted {
+		return nil, newClassifiedError(ErrKeyLocked, "gopenpgp: certifications can only be attested with an unlocked key", nil)
+	}
+
+	config := &packet.Config{Time: getTimeGenerator()}
+	applyConfigModifier(config)
+	publicKey := key.entity.PrimaryKey
+	signature := &packet.Signature{
+		Version:           publicKey.Version,
+		SigType:           sigTypeAttestation,
+		PubKeyAlgo:        publicKey.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      config.Now(),
+		IssuerKeyId:       &publicKey.KeyId,
+		IssuerFingerprint: publicKey.Fingerprint,
+	}
+
+	digests := make([][]byte, len(certifications))
+	for i, certification := range certifications {
+		digest, err := getCertificationDigest(certification, signature.Hash.New())
+		if err != nil {
+			return nil, err
+		}
+		digests[i] = digest
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return bytes.Compare(digests[i], digests[j]) < 0
+	})
+	subpackets, err := serializeSubpackets([]*SignatureSubpacket{{
+		Type:     subpacketTypeAttestedCertifications,
+		Hashed:   true,
+		Contents: bytes.Join(digests, nil),
+	}}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := signature.PrepareSign(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in attesting certifications")
+	}
+	if err := writeUserIDHash(h, publicKey, userID); err != nil {
+		return nil, err
+	}
+	// go-crypto doesn't know the attested certifications subpacket: it is
+	// added to the hashed area when go-crypto hashes the signature trailer.
+	injector := &subpacketInjector{Hash: h, subpackets: subpackets}
+	if err := signature.Sign(injector, privateKey, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in attesting certifications")
+	}
+	if err := injector.check(); err != nil {
+		return nil, err
+	}
+	auditKeyUsage(constants.KeyUsageCertify, "attest certifications", publicKey, publicKey)
+	var serialized bytes.Buffer
+	if err := signature.Serialize(&serialized); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in attesting certifications")
+	}
+	attestationSignature, err := replaceSubpacketAreas(NewPGPSignature(serialized.Bytes()), injector.hashedArea, nil)
+	if err != nil {
+		return nil, err
+	}
+	attestation, err := readAttestation(publicKey, userID, attestationSignature.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+
+	newKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	newKey.attestations = mergeAttestations(newKey.attestations, []*Attestation{attestation})
+	return newKey, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func (key *Key) getAttestation(userID string) *Attestation {
+	for _, attestation := range key.attestations {
+		if attestation.userID == userID {
+			return attestation
+		}
+	}
+	return nil
+}
+
+func isCertification(sig *packet.Signature) bool {
+	switch sig.SigType {
+	case packet.SigTypeGenericCert, packet.SigTypePersonaCert, packet.SigTypeCasualCert, packet.SigTypePositiveCert:
+		return true
+	default:
+		return false
+	}
+}
+
+// rawAttestation is an attestation key signature packet split from a
+// serialized key, with the user ID it follows.
+type rawAttestation struct {
+	userID string
+	data   []byte
+}
+
+// splitAttestations returns the serialized keys without their attestation
+// key signatures, and the removed signatures. The data is returned
+// unchanged if it can't be parsed.
+func splitAttestations(data []byte) ([]byte, []*rawAttestation) {
+	var attestations []*rawAttestation
+	var stripped bytes.Buffer
+	userID, inUserID := "", false
+	for offset := 0; offset < len(data); {
+		tag, next, err := nextPacketOffset(data, offset)
+		if err != nil {
+			return data, nil
+		}
+		body, err := getPacketBody(data, offset, next)
+		if err != nil {
+			return data, nil
+		}
+		switch {
+		case tag == packetTagUserID:
+			userID, inUserID = string(body), true
+		case tag == packetTagSignature:
+			if inUserID && isAttestationSignature(body) {
+				attestations = append(attestations, &rawAttestation{userID: userID, data: data[offset:next]})
+				offset = next
+				continue
+			}
+		default:
+			inUserID = false
+		}
+		stripped.Write(data[offset:next])
+		offset = next
+	}
+	if len(attestations) == 0 {
+		return data, nil
+	}
+	return stripped.Bytes(), attestations
+}
+
+// isAttestationSignature returns true if the body of a signature packet is
+// an attestation key signature.
+func isAttestationSignature(body []byte) bool {
+	return len(body) > 1 && (body[0] == 4 || body[0] == 6) && packet.SignatureType(body[1]) == sigTypeAttestation
+}
+
+// readAttestations returns the valid attestations of the user IDs of the
+// key, the newest of each user ID.
+func readAttestations(key *Key, raw []*rawAttestation) []*Attestation {
+	var attestations []*Attestation
+	for _, rawAttestation := range raw {
+		if _, ok := key.entity.Identities[rawAttestation.userID]; !ok {
+			continue
+		}
+		attestation, err := readAttestation(key.entity.PrimaryKey, rawAttestation.userID, rawAttestation.data)
+		if err != nil {
+			continue
+		}
+		attestations = mergeAttestations(attestations, []*Attestation{attestation})
+	}
+	return attestations
+}
+
+// readAttestation parses and verifies an attestation key signature of the
+// user ID.
+func readAttestation(primaryKey *packet.PublicKey, userID string, data []byte) (*Attestation, error) {
+	p, err := packet.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading attestation")
+	}
+	signature, ok := p.(*packet.Signature)
+	if !ok || signature.SigType != sigTypeAttestation {
+		return nil, errors.New("gopenpgp: not an attestation key signature")
+	}
+	if !signature.CheckKeyIdOrFingerprint(primaryKey) {
+		return nil, errors.New("gopenpgp: the attestation is not a self-signature")
+	}
+	h, err := signature.PrepareVerify()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in verifying attestation")
+	}
+	if err := writeUserIDHash(h, primaryKey, userID); err != nil {
+		return nil, err
+	}
+	if err := primaryKey.VerifySignature(h, signature); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid attestation")
+	}
+
+	subpackets, err := NewPGPSignature(data).GetHashedSubpackets()
+	if err != nil {
+		return nil, err
+	}
+	attestation := &Attestation{userID: userID, signature: signature, data: data}
+	digestSize := signature.Hash.Size()
+	for _, subpacket := range subpackets {
+		if subpacket.Type != subpacketTypeAttestedCertifications {
+			continue
+		}
+		if len(subpacket.Contents)%digestSize != 0 {
+			return nil, errors.New("gopenpgp: invalid attested certifications")
+		}
+		for i := 0; i < len(subpacket.Contents); i += digestSize {
+			attestation.digests = append(attestation.digests, subpacket.Contents[i:i+digestSize])
+		}
+	}
+	return attestation, nil
+}
+
+// getCertificationDigest returns the hash of a certification listed in
+// attestations: the signature packet with an old format header with a
+// four-octet length, without its unhashed subpackets.
+func getCertificationDigest(certification *PGPSignature, h hash.Hash) ([]byte, error) {
+	data := certification.GetBinary()
+	if len(data) == 0 {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: empty signature", nil)
+	}
+	tag, next, err := nextPacketOffset(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if tag != packetTagSignature {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: not a signature packet", nil)
+	}
+	body, err := getPacketBody(data, 0, next)
+	if err != nil {
+		return nil, err
+	}
+	areas, err := findSubpacketAreas(body)
+	if err != nil {
+		return nil, err
+	}
+	if areas == nil {
+		return nil, errors.New("gopenpgp: only v4 and v6 certifications can be attested")
+	}
+
+	unhashedLength := make([]byte, areas.lengthSize)
+	length := areas.unhashedStart + len(body) - areas.unhashedEnd
+	var header [5]byte
+	header[0] = 0x88
+	binary.BigEndian.PutUint32(header[1:], uint32(length))
+	_, _ = h.Write(header[:])
+	_, _ = h.Write(body[:areas.hashedEnd])
+	_, _ = h.Write(unhashedLength)
+	_, _ = h.Write(body[areas.unhashedEnd:])
+	return h.Sum(nil), nil
+}
+
+// writeUserIDHash writes the data certified by user ID signatures, see
+// RFC 4880, section 5.2.4.
+func writeUserIDHash(h hash.Hash, primaryKey *packet.PublicKey, userID string) error {
+	if err := primaryKey.SerializeForHash(h); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in hashing user ID")
+	}
+	var header [5]byte
+	header[0] = 0xb4
+	binary.BigEndian.PutUint32(header[1:], uint32(len(userID)))
+	_, _ = h.Write(header[:])
+	_, _ = h.Write([]byte(userID))
+	return nil
+}
+
+// insertAttestations inserts the attestation key signatures in the
+// serialized key, after the other signatures of their user ID.
+func (key *Key) insertAttestations(serialized []byte) ([]byte, error) {
+	if len(key.attestations) == 0 {
+		return serialized, nil
+	}
+
+	var inserted bytes.Buffer
+	var pending []byte
+	for offset := 0; offset < len(serialized); {
+		tag, next, err := nextPacketOffset(serialized, offset)
+		if err != nil {
+			return nil, err
+		}
+		if tag != packetTagSignature {
+			inserted.Write(pending)
+			pending = nil
+		}
+		if tag == packetTagUserID {
+			body, err := getPacketBody(serialized, offset, next)
+			if err != nil {
+				return nil, err
+			}
+			if attestation := key.getAttestation(string(body)); attestation != nil {
+				pending = attestation.data
+			}
+		}
+		inserted.Write(serialized[offset:next])
+		offset = next
+	}
+	inserted.Write(pending)
+	return inserted.Bytes(), nil
+}
+
+// mergeAttestations returns the newest attestation of each user ID of a
+// and b.
+func mergeAttestations(a, b []*Attestation) []*Attestation {
+	merged := append([]*Attestation{}, a...)
+	for _, update := range b {
+		found := false
+		for i, attestation := range merged {
+			if attestation.userID == update.userID {
+				if !update.signature.CreationTime.Before(attestation.signature.CreationTime) {
+					merged[i] = update
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, update)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttestCertifications(t *testing.T) {
+	key, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	userID := key.entity.PrimaryIdentity().Name
+	if err = key.entity.SignIdentity(userID, keyTestRSA.entity, &packet.Config{}); err != nil {
+		t.Fatal("Expected no error while certifying user ID, got:", err)
+	}
+	if key, err = key.Copy(); err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+
+	certifications, err := key.GetThirdPartyCertifications(userID)
+	if err != nil {
+		t.Fatal("Expected no error while getting certifications, got:", err)
+	}
+	assert.Exactly(t, 1, len(certifications))
+	attested, err := key.GetAttestedCertifications(userID)
+	if err != nil {
+		t.Fatal("Expected no error while getting attested certifications, got:", err)
+	}
+	assert.Exactly(t, 0, len(attested))
+
+	attestedKey, err := key.AttestCertifications(userID, certifications)
+	if err != nil {
+		t.Fatal("Expected no error while attesting certifications, got:", err)
+	}
+	publicKey, err := attestedKey.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	_, err = openpgp.ReadKeyRing(bytes.NewReader(publicKey))
+	assert.Error(t, err, "go-crypto rejects attestation key signatures")
+
+	parsedKey, err := NewKey(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	assert.Exactly(t, 1, len(parsedKey.GetAttestations()))
+	assert.Exactly(t, userID, parsedKey.GetAttestations()[0].GetUserID())
+	attested, err = parsedKey.GetAttestedCertifications(userID)
+	if err != nil {
+		t.Fatal("Expected no error while getting attested certifications, got:", err)
+	}
+	assert.Exactly(t, 1, len(attested))
+	assert.Exactly(t, certifications[0].GetBinary(), attested[0].GetBinary())
+
+	keyRing, err := NewKeyRingFromBinary(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while reading keyring, got:", err)
+	}
+	assert.Exactly(t, 1, keyRing.CountEntities())
+
+	withdrawnKey, err := attestedKey.AttestCertifications(userID, nil)
+	if err != nil {
+		t.Fatal("Expected no error while withdrawing attestation, got:", err)
+	}
+	assert.Exactly(t, 1, len(withdrawnKey.GetAttestations()))
+	attested, err = withdrawnKey.GetAttestedCertifications(userID)
+	if err != nil {
+		t.Fatal("Expected no error while getting attested certifications, got:", err)
+	}
+	assert.Exactly(t, 0, len(attested))
+
+	lockedKey, err := key.Lock([]byte("passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	_, err = lockedKey.AttestCertifications(userID, certifications)
+	assert.True(t, errors.Is(err, ErrKeyLocked))
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+// KeyUsageAuditor receives the operations performed with private keys, e.g.
+// to build an audit trail of the usage of keys embedded in a service.
+// KeyUsageAuditor must be safe for concurrent use, and should return
+// quickly, as it is called synchronously by the operations.
+type KeyUsageAuditor interface {
+	// OnKeyUsage is called once a private key signed, decrypted or
+	// certified successfully. For streams, it is called when the key
+	// decrypts the session key, or when the signing stream is created.
+	// Certifications are reported for the photo IDs, the attestations and
+	// the certification revocations: the self-signatures made while
+	// generating a key are not reported.
+	OnKeyUsage(event *KeyUsageEvent)
+}
+
+// KeyUsageEvent is an operation performed with a private key.
+type KeyUsageEvent struct {
+	// Operation is one of the constants.KeyUsage* operations.
+	Operation string
+	// Fingerprint is the hex fingerprint of the primary key.
+	Fingerprint string
+	// KeyFingerprint is the hex fingerprint of the key used, the primary
+	// key or one of its subkeys.
+	KeyFingerprint string
+	// Context is the function performing the operation, e.g.
+	// "sign detached" or "decrypt session key".
+	Context string
+}
+
+// SetKeyUsageAuditor sets the receiver of the operations performed with
+// private keys. Passing nil disables the audit, which is the default.
+func SetKeyUsageAuditor(auditor KeyUsageAuditor) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.keyUsageAuditor = auditor
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func getKeyUsageAuditor() KeyUsageAuditor {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+
+	return pgp.keyUsageAuditor
+}
+
+// auditKeyUsage reports an operation performed with key, belonging to the
+// primary key primaryKey.
+func auditKeyUsage(operation, context string, primaryKey, key *packet.PublicKey) {
+	auditor := getKeyUsageAuditor()
+	if auditor == nil || primaryKey == nil || key == nil {
+		return
+	}
+	auditor.OnKeyUsage(&KeyUsageEvent{
+		Operation:      operation,
+		Fingerprint:    hex.EncodeToString(primaryKey.Fingerprint),
+		KeyFingerprint: hex.EncodeToString(key.Fingerprint),
+		Context:        context,
+	})
+}
+
+// auditSigning reports a signature made with the signing key of signEntity,
+// selected at the time of the signature.
+func auditSigning(context string, signEntity *openpgp.Entity, now time.Time) {
+	if signEntity == nil {
+		return
+	}
+	if key, ok := signEntity.SigningKey(now); ok {
+		auditKeyUsage(constants.KeyUsageSign, context, signEntity.PrimaryKey, key.PublicKey)
+	}
+}
+
+// auditDecryption reports a message decrypted with key.
+func auditDecryption(context string, key openpgp.Key) {
+	if key.Entity == nil {
+		return
+	}
+	auditKeyUsage(constants.KeyUsageDecrypt, context, key.Entity.PrimaryKey, key.PublicKey)
+}
@@ -0,0 +1,67 @@
+package crypto
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+type testKeyUsageAuditor struct {
+	lock   sync.Mutex
+	events []*KeyUsageEvent
+}
+
+func (auditor *testKeyUsageAuditor) OnKeyUsage(event *KeyUsageEvent) {
+	auditor.lock.Lock()
+	defer auditor.lock.Unlock()
+
+	auditor.events = append(auditor.events, event)
+}
+
+func TestKeyUsageAuditor(t *testing.T) {
+	auditor := &testKeyUsageAuditor{}
+	SetKeyUsageAuditor(auditor)
+	defer SetKeyUsageAuditor(nil)
+
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	split, err := ciphertext.SplitMessage()
+	if err != nil {
+		t.Fatal("Expected no error while splitting, got:", err)
+	}
+	if _, err = keyRingTestPrivate.DecryptSessionKey(split.GetBinaryKeyPacket()); err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	// Verification and encryption alone don't use private keys
+	if err = keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+	if _, err = keyRingTestPublic.Encrypt(message, nil); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	fingerprint := keyRingTestPrivate.GetKeys()[0].GetFingerprint()
+	var operations, contexts []string
+	for _, event := range auditor.events {
+		assert.Exactly(t, fingerprint, event.Fingerprint)
+		assert.NotEmpty(t, event.KeyFingerprint)
+		operations = append(operations, event.Operation)
+		contexts = append(contexts, event.Context)
+	}
+	assert.Exactly(t, []string{
+		constants.KeyUsageSign, constants.KeyUsageDecrypt, constants.KeyUsageSign, constants.KeyUsageDecrypt,
+	}, operations)
+	assert.Exactly(t, []string{"encrypt and sign", "decrypt", "sign detached", "decrypt session key"}, contexts)
+}
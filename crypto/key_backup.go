@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/pkg/errors"
+)
+
+// ExportEncrypted returns a password protected backup of the private key,
+// e.g. to store it in a cloud drive: an OpenPGP message encrypted with the
+// password, using Argon2 and AEAD encrypted data (SEIPDv2), containing the
+// locked key.
+// If the key is unlocked, it is first locked with the same password, see
+// LockWithAEAD; a locked key is exported as is.
+// Older OpenPGP implementations may not be able to decrypt the backup.
+func (key *Key) ExportEncrypted(password []byte) (*PGPMessage, error) {
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: only private keys can be exported encrypted")
+	}
+
+	isLocked, err := key.IsLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	lockedKey := key
+	if !isLocked {
+		lockedKey, err = key.LockWithAEAD(password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	serialized, err := lockedKey.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &packet.Config{
+		Rand:          getRandomSource(),
+		DefaultCipher: packet.CipherAES256,
+		Time:          getTimeGenerator(),
+		S2KConfig: &s2k.Config{
+			S2KMode: s2k.Argon2S2K,
+		},
+		AEADConfig: &packet.AEADConfig{DefaultMode: packet.AEADModeOCB},
+	}
+
+	encrypted, err := passwordEncryptWithConfig(NewPlainMessage(serialized), password, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewPGPMessage(encrypted), nil
+}
+
+// ImportEncryptedKey reads a key backup created with Key.ExportEncrypted.
+// The returned key is locked, and can be unlocked with Key.Unlock.
+func ImportEncryptedKey(backup *PGPMessage, password []byte) (*Key, error) {
+	decrypted, err := DecryptMessageWithPassword(backup, password)
+	if err != nil {
+		return nil, err
+	}
+	defer clearMem(decrypted.Data)
+
+	key, err := NewKey(decrypted.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: the backup does not contain a private key")
+	}
+	return key, nil
+}
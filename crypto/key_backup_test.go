@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyBackup(t *testing.T) {
+	password := []byte("backup password")
+
+	backup, err := keyTestEC.ExportEncrypted(password)
+	if err != nil {
+		t.Fatal("Expected no error while exporting key, got:", err)
+	}
+
+	_, err = ImportEncryptedKey(backup, []byte("wrong password"))
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+
+	key, err := ImportEncryptedKey(backup, password)
+	if err != nil {
+		t.Fatal("Expected no error while importing key, got:", err)
+	}
+	isLocked, err := key.IsLocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking key, got:", err)
+	}
+	assert.True(t, isLocked)
+
+	unlockedKey, err := key.Unlock(password)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	assert.Exactly(t, keyTestEC.GetFingerprint(), unlockedKey.GetFingerprint())
+
+	lockedKey, err := keyTestEC.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	backup, err = lockedKey.ExportEncrypted(password)
+	if err != nil {
+		t.Fatal("Expected no error while exporting key, got:", err)
+	}
+	key, err = ImportEncryptedKey(backup, password)
+	if err != nil {
+		t.Fatal("Expected no error while importing key, got:", err)
+	}
+	_, err = key.Unlock(keyTestPassphrase)
+	assert.NoError(t, err)
+
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	_, err = publicKey.ExportEncrypted(password)
+	assert.Error(t, err)
+}
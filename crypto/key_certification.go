@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// RevokeCertification returns a certification revocation signature, issued
+// by the key, withdrawing the certifications the key made of the user ID of
+// the certified key, see RFC 4880, section 5.2.1. The revocation applies to
+// the certifications created up to its creation time: the user ID can be
+// certified again afterwards.
+// If userIDInvalid is true, the revocation states that the user ID is no
+// longer valid, otherwise no reason is given. reasonText is an optional
+// human-readable explanation.
+// The revocation is distributed with the certified key, see
+// AddCertificationRevocation. The primary key must be unlocked.
+func (key *Key) RevokeCertification(certified *Key, userID string, userIDInvalid bool, reasonText string) (*PGPSignature, error) {
+	if _, ok := certified.entity.Identities[userID]; !ok {
+		return nil, errors.New("gopenpgp: user ID not found in certified key")
+	}
+	privateKey := key.entity.PrivateKey
+	if privateKey == nil {
+		return nil, errors.New("gopenpgp: certifications can only be revoked with a private key")
+	}
+	if privateKey.Dummy() {
+		return nil, StubKeyError{Fingerprint: key.GetFingerprint()}
+	}
+	if privateKey.Encrypted {
+		return nil, newClassifiedError(ErrKeyLocked, "gopenpgp: certifications can only be revoked with an unlocked key", nil)
+	}
+
+	config := &packet.Config{Time: getTimeGenerator()}
+	applyConfigModifier(config)
+	publicKey := key.entity.PrimaryKey
+	reason := packet.NoReason
+	if userIDInvalid {
+		reason = packet.UserIDNotValid
+	}
+	revocation := &packet.Signature{
+		Version:              publicKey.Version,
+		SigType:              packet.SigTypeCertificationRevocation,
+		PubKeyAlgo:           publicKey.PubKeyAlgo,
+		Hash:                 config.Hash(),
+		CreationTime:         config.Now(),
+		IssuerKeyId:          &publicKey.KeyId,
+		IssuerFingerprint:    publicKey.Fingerprint,
+		RevocationReason:     &reason,
+		RevocationReasonText: reasonText,
+	}
+	if err := revocation.SignUserId(userID, certified.entity.PrimaryKey, privateKey, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in revoking certification")
+	}
+	auditKeyUsage(constants.KeyUsageCertify, "revoke certification", publicKey, publicKey)
+	return newPGPSignatureFromPacket(revocation)
+}
+
+// AddCertificationRevocation returns a copy of the key with the
+// certification revocation of the user ID, e.g. received from the issuer of
+// a certification, see RevokeCertification. Adding a revocation already in
+// the key has no effect.
+// Third-party revocations are verified when the certifications are
+// evaluated, see IsCertificationRevoked, as their issuer is needed.
+func (key *Key) AddCertificationRevocation(userID string, revocation *PGPSignature) (*Key, error) {
+	if _, ok := key.entity.Identities[userID]; !ok {
+		return nil, errors.New("gopenpgp: user ID not found in key")
+	}
+	revocationPacket, err := parseSignaturePacket(revocation)
+	if err != nil {
+		return nil, err
+	}
+	if revocationPacket.SigType != packet.SigTypeCertificationRevocation {
+		return nil, errors.New("gopenpgp: the signature is not a certification revocation")
+	}
+
+	newKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	identity := newKey.entity.Identities[userID]
+	for _, sig := range identity.Signatures {
+		if isSameSignature(sig, revocation) {
+			return newKey, nil
+		}
+	}
+	identity.Signatures = append(identity.Signatures, revocationPacket)
+	// The key is parsed again, so that go-crypto verifies the revocations
+	// issued by the key itself, and revokes the user ID.
+	return newKey.Copy()
+}
+
+// IsCertificationRevoked returns true if a certification revocation of the
+// user ID issued by issuer, and created after the certification, is found
+// in the key. The revocations are verified with the primary key of issuer.
+func (key *Key) IsCertificationRevoked(userID string, certification *PGPSignature, issuer *Key) (bool, error) {
+	identity, ok := key.entity.Identities[userID]
+	if !ok {
+		return false, errors.New("gopenpgp: user ID not found in key")
+	}
+	certificationPacket, err := parseSignaturePacket(certification)
+	if err != nil {
+		return false, err
+	}
+	if !isCertification(certificationPacket) {
+		return false, errors.New("gopenpgp: the signature is not a certification")
+	}
+	issuerKey := issuer.entity.PrimaryKey
+	for _, sig := range identity.Signatures {
+		if sig.SigType == packet.SigTypeCertificationRevocation &&
+			sig.CheckKeyIdOrFingerprint(issuerKey) &&
+			!sig.CreationTime.Before(certificationPacket.CreationTime) &&
+			issuerKey.VerifyUserIdSignature(userID, key.entity.PrimaryKey, sig) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// isSameSignature returns true if the signature packet serializes to the
+// packet of signature.
+func isSameSignature(sig *packet.Signature, signature *PGPSignature) bool {
+	var serialized bytes.Buffer
+	if err := sig.Serialize(&serialized); err != nil {
+		return false
+	}
+	return bytes.Equal(serialized.Bytes(), signature.GetBinary())
+}
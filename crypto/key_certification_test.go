@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificationRevocation(t *testing.T) {
+	certified, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	issuer, err := GenerateKey("issuer", "issuer@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	var userID string
+	for name := range certified.entity.Identities {
+		userID = name
+	}
+	if err := certified.entity.SignIdentity(userID, issuer.entity, &packet.Config{Time: getTimeGenerator()}); err != nil {
+		t.Fatal("Expected no error while certifying user ID, got:", err)
+	}
+	certifications, err := certified.GetThirdPartyCertifications(userID)
+	if err != nil {
+		t.Fatal("Expected no error while getting certifications, got:", err)
+	}
+	assert.Len(t, certifications, 1)
+
+	revoked, err := certified.IsCertificationRevoked(userID, certifications[0], issuer)
+	if err != nil {
+		t.Fatal("Expected no error while checking certification, got:", err)
+	}
+	assert.False(t, revoked)
+
+	revocation, err := issuer.RevokeCertification(certified, userID, true, "wrong address")
+	if err != nil {
+		t.Fatal("Expected no error while revoking certification, got:", err)
+	}
+	revocationPacket, err := parseSignaturePacket(revocation)
+	if err != nil {
+		t.Fatal("Expected no error while parsing revocation, got:", err)
+	}
+	assert.Exactly(t, packet.SigTypeCertificationRevocation, revocationPacket.SigType)
+	assert.Exactly(t, packet.UserIDNotValid, *revocationPacket.RevocationReason)
+	assert.Exactly(t, "wrong address", revocationPacket.RevocationReasonText)
+
+	withRevocation, err := certified.AddCertificationRevocation(userID, revocation)
+	if err != nil {
+		t.Fatal("Expected no error while adding revocation, got:", err)
+	}
+	withRevocation, err = withRevocation.AddCertificationRevocation(userID, revocation)
+	if err != nil {
+		t.Fatal("Expected no error while adding revocation, got:", err)
+	}
+	assert.Len(t, withRevocation.entity.Identities[userID].Signatures, 3)
+	// Third-party revocations don't revoke the user ID
+	assert.Empty(t, withRevocation.entity.Identities[userID].Revocations)
+
+	revoked, err = withRevocation.IsCertificationRevoked(userID, certifications[0], issuer)
+	if err != nil {
+		t.Fatal("Expected no error while checking certification, got:", err)
+	}
+	assert.True(t, revoked)
+	// The revocation is only valid if issued by the certifier
+	revoked, err = withRevocation.IsCertificationRevoked(userID, certifications[0], certified)
+	if err != nil {
+		t.Fatal("Expected no error while checking certification, got:", err)
+	}
+	assert.False(t, revoked)
+
+	_, err = certified.AddCertificationRevocation(userID, certifications[0])
+	assert.Error(t, err)
+	_, err = certified.AddCertificationRevocation("unknown", revocation)
+	assert.Error(t, err)
+}
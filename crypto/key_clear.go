@@ -16,8 +16,15 @@ import (
 	"github.com/ProtonMail/go-crypto/openpgp/x448"
 )
 
+// Clear zeroizes the session key. If the key is stored in locked memory,
+// the memory is released and Key is replaced by a zeroed slice.
 func (sk *SessionKey) Clear() (ok bool) {
 	clearMem(sk.Key)
+	if sk.lockedBuffer != nil {
+		sk.Key = make([]byte, len(sk.Key))
+		sk.lockedBuffer.Destroy()
+		sk.lockedBuffer = nil
+	}
 	return true
 }
 
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"crypto/dsa" //nolint:staticcheck
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/ecdh"
+	"github.com/ProtonMail/go-crypto/openpgp/ecdsa"
+	"github.com/ProtonMail/go-crypto/openpgp/ed25519"
+	"github.com/ProtonMail/go-crypto/openpgp/ed448"
+	"github.com/ProtonMail/go-crypto/openpgp/eddsa"
+	"github.com/ProtonMail/go-crypto/openpgp/elgamal"
+	"github.com/ProtonMail/go-crypto/openpgp/x25519"
+	"github.com/ProtonMail/go-crypto/openpgp/x448"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Derivation domains, separating the keys derived from the private key
+// material for different uses.
+const (
+	sessionKeyDerivationDomain  = "gopenpgp session key derivation"
+	searchTokenDerivationDomain = "gopenpgp search token derivation"
+)
+
+// DeriveSessionKey deterministically derives an AES-256 session key from the
+// unlocked primary private key, with HKDF-SHA256: the same key, context and
+// info always give the same session key, e.g. to deduplicate documents
+// encrypted with per-document keys in a storage.
+// * context : used as HKDF salt, e.g. to separate applications.
+// * info    : identifies the document the session key is derived for.
+// Anyone with the private key can derive the session keys, and the session
+// keys do not change when the key is locked with a new passphrase.
+func (key *Key) DeriveSessionKey(context []byte, info []byte) (*SessionKey, error) {
+	secret, err := key.getDerivationSecret("session keys")
+	if err != nil {
+		return nil, err
+	}
+	defer clearMem(secret)
+
+	derivationInfo := append([]byte(sessionKeyDerivationDomain), info...)
+	token := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, context, derivationInfo), token); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in deriving session key")
+	}
+	return NewSessionKeyFromToken(token, constants.AES256), nil
+}
+
+// DeriveSearchToken deterministically derives a search token for a term from
+// the unlocked primary private key, with HMAC-SHA256 keyed with a key derived
+// from the private key material: the same key and term always give the same
+// token, so that a server storing the tokens of encrypted fields can find the
+// fields equal to a term, given its token, without learning the plaintext.
+// The tokens of different keys are unrelated, and the tokens do not change
+// when the key is locked with a new passphrase. Normalizing the terms, e.g.
+// their case, is left to the application.
+// Equal terms give equal tokens, so the server learns which fields are equal.
+func (key *Key) DeriveSearchToken(term []byte) ([]byte, error) {
+	secret, err := key.getDerivationSecret("search tokens")
+	if err != nil {
+		return nil, err
+	}
+	defer clearMem(secret)
+
+	tokenKey := make([]byte, 32)
+	defer clearMem(tokenKey)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(searchTokenDerivationDomain)), tokenKey); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in deriving search token key")
+	}
+	mac := hmac.New(sha256.New, tokenKey)
+	_, _ = mac.Write(term)
+	return mac.Sum(nil), nil
+}
+
+// getDerivationSecret returns the secret material of the unlocked primary
+// private key, the input of the key derivations, to be cleared after use.
+// derived names what is derived in the errors.
+// The secret is the public key algorithm ID, followed by the secret key
+// material in a fixed encoding, that doesn't depend on how the key is
+// serialized or locked:
+//   - RSA: the private exponent d,
+//   - DSA and ElGamal: the private exponent x,
+//   - ECDSA, ECDH and EdDSA (legacy): the private scalar or seed,
+//   - X25519, X448, Ed25519 and Ed448: the native secret key or seed,
+//
+// with integers as unsigned big-endian bytes without leading zeros, and the
+// other values as stored in the OpenPGP secret key packet, without length.
+func (key *Key) getDerivationSecret(derived string) ([]byte, error) {
+	privateKey := key.entity.PrivateKey
+	if privateKey == nil {
+		return nil, errors.New("gopenpgp: " + derived + " can only be derived from a private key")
+	}
+	if privateKey.Dummy() {
+		return nil, StubKeyError{Fingerprint: key.GetFingerprint()}
+	}
+	if privateKey.Encrypted {
+		return nil, newClassifiedError(ErrKeyLocked, "gopenpgp: "+derived+" can only be derived from an unlocked key", nil)
+	}
+
+	material, err := getSecretKeyMaterial(privateKey.PrivateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: "+derived+" can't be derived from this key")
+	}
+	defer clearMem(material)
+	return append([]byte{byte(privateKey.PubKeyAlgo)}, material...), nil
+}
+
+// getSecretKeyMaterial returns a copy of the secret key material of a
+// private key, see getDerivationSecret.
+func getSecretKeyMaterial(privateKey interface{}) ([]byte, error) {
+	switch priv := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return priv.D.Bytes(), nil
+	case *dsa.PrivateKey:
+		return priv.X.Bytes(), nil
+	case *elgamal.PrivateKey:
+		return priv.X.Bytes(), nil
+	case *ecdsa.PrivateKey:
+		return priv.D.Bytes(), nil
+	case *eddsa.PrivateKey:
+		return append([]byte{}, priv.D...), nil
+	case *ecdh.PrivateKey:
+		return append([]byte{}, priv.D...), nil
+	case *x25519.PrivateKey:
+		return append([]byte{}, priv.Secret...), nil
+	case *ed25519.PrivateKey:
+		return append([]byte{}, priv.Key[:ed25519.SeedSize]...), nil
+	case *x448.PrivateKey:
+		return append([]byte{}, priv.Secret...), nil
+	case *ed448.PrivateKey:
+		return append([]byte{}, priv.Key[:ed448.SeedSize]...), nil
+	default:
+		return nil, errors.New("gopenpgp: unknown private key")
+	}
+}
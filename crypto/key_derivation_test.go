@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveSessionKey(t *testing.T) {
+	sessionKey, err := keyTestEC.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.Len(t, sessionKey.Key, 32)
+
+	sameSessionKey, err := keyTestEC.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.Exactly(t, sessionKey.Key, sameSessionKey.Key)
+
+	otherSessionKey, err := keyTestEC.DeriveSessionKey([]byte("context"), []byte("document 2"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.NotEqual(t, sessionKey.Key, otherSessionKey.Key)
+
+	otherSessionKey, err = keyTestRSA.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.NotEqual(t, sessionKey.Key, otherSessionKey.Key)
+
+	dataPacket, err := sessionKey.Encrypt(NewPlainMessageFromString("hello"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := sameSessionKey.Decrypt(dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+
+	lockedKey, err := keyTestEC.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	_, err = lockedKey.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	assert.True(t, errors.Is(err, ErrKeyLocked))
+
+	unlockedKey, err := lockedKey.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	sameSessionKey, err = unlockedKey.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.Exactly(t, sessionKey.Key, sameSessionKey.Key)
+
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	_, err = publicKey.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	assert.Error(t, err)
+}
+
+func TestDeriveSessionKeyVector(t *testing.T) {
+	key := getDerivationTestKey(t)
+	sessionKey, err := key.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.Exactly(t, "3448115a31303b21e17fe9970976b73de6df28a9b61112acaf44ee0e17089c7d", hex.EncodeToString(sessionKey.Key))
+
+	// The derived keys don't depend on how the key is locked
+	lockedKey, err := key.Lock([]byte("other passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	unlockedKey, err := lockedKey.Unlock([]byte("other passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	sameSessionKey, err := unlockedKey.DeriveSessionKey([]byte("context"), []byte("document 1"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving session key, got:", err)
+	}
+	assert.Exactly(t, sessionKey.Key, sameSessionKey.Key)
+}
+
+func TestDeriveSearchToken(t *testing.T) {
+	token, err := keyTestEC.DeriveSearchToken([]byte("alice@example.org"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving search token, got:", err)
+	}
+	assert.Len(t, token, 32)
+
+	sameToken, err := keyTestEC.DeriveSearchToken([]byte("alice@example.org"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving search token, got:", err)
+	}
+	assert.Exactly(t, token, sameToken)
+
+	otherToken, err := keyTestEC.DeriveSearchToken([]byte("bob@example.org"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving search token, got:", err)
+	}
+	assert.NotEqual(t, token, otherToken)
+
+	otherToken, err = keyTestRSA.DeriveSearchToken([]byte("alice@example.org"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving search token, got:", err)
+	}
+	assert.NotEqual(t, token, otherToken)
+
+	lockedKey, err := keyTestEC.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	_, err = lockedKey.DeriveSearchToken([]byte("alice@example.org"))
+	assert.True(t, errors.Is(err, ErrKeyLocked))
+
+	unlockedKey, err := lockedKey.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	sameToken, err = unlockedKey.DeriveSearchToken([]byte("alice@example.org"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving search token, got:", err)
+	}
+	assert.Exactly(t, token, sameToken)
+}
+
+func TestDeriveSearchTokenVector(t *testing.T) {
+	token, err := getDerivationTestKey(t).DeriveSearchToken([]byte("alice@example.org"))
+	if err != nil {
+		t.Fatal("Expected no error while deriving search token, got:", err)
+	}
+	assert.Exactly(t, "42b490eddf75663b254c28abda5011b0c0f92c70272c34305283f3ebfaad5125", hex.EncodeToString(token))
+}
+
+// getDerivationTestKey returns a fixed unlocked RSA key, for the test
+// vectors of the key derivations.
+func getDerivationTestKey(t *testing.T) *Key {
+	lockedKey, err := NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	key, err := lockedKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	return key
+}
@@ -0,0 +1,255 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// KeyDiff describes the changes between two versions of the same certificate,
+// e.g. a local copy and the one returned by a keyserver. It is returned by Key.Diff.
+type KeyDiff struct {
+	// AddedSubkeys contains the hex fingerprints of the subkeys that are only
+	// present in the new version.
+	AddedSubkeys []string
+	// RemovedSubkeys contains the hex fingerprints of the subkeys that are only
+	// present in the old version.
+	RemovedSubkeys []string
+	// AddedUserIDs contains the user IDs that are only present in the new version.
+	AddedUserIDs []string
+	// RemovedUserIDs contains the user IDs that are only present in the old version.
+	RemovedUserIDs []string
+	// NewSignatures contains the signatures that are only present in the new
+	// version, including third-party certifications and revocations.
+	NewSignatures []*KeyDiffSignature
+	// Revocations contains the new revocation signatures, a subset of NewSignatures.
+	Revocations []*KeyDiffSignature
+	// ExpirationChanges contains the primary key and the subkeys present in
+	// both versions whose expiration time changed.
+	ExpirationChanges []*KeyDiffExpiration
+}
+
+// KeyDiffSignature describes a signature reported by Key.Diff.
+type KeyDiffSignature struct {
+	// Target is the hex fingerprint of the signed primary key or subkey,
+	// or the signed user ID.
+	Target string
+	// Type is the OpenPGP signature type, see packet.SignatureType.
+	Type int
+	// CreationTime is the creation time of the signature, as a unix timestamp.
+	CreationTime int64
+	// IssuerKeyID is the hex key ID of the issuer, empty if unknown.
+	IssuerKeyID string
+}
+
+// KeyDiffExpiration describes a change of key expiration reported by Key.Diff.
+// Expiration times are unix timestamps, 0 means the key does not expire.
+type KeyDiffExpiration struct {
+	Fingerprint       string
+	OldExpirationTime int64
+	NewExpirationTime int64
+}
+
+// Diff compares the key with a newer version of the same certificate, and
+// reports the subkeys, user IDs and signatures added or removed, and the
+// changes of expiration time. Signatures are compared as they are, they are
+// not verified: the certificates should be merged or parsed by a trusted
+// source before acting on the diff.
+// Returns an error if the keys do not have the same primary key.
+func (key *Key) Diff(other *Key) (*KeyDiff, error) {
+	if !bytes.Equal(key.entity.PrimaryKey.Fingerprint, other.entity.PrimaryKey.Fingerprint) {
+		return nil, errors.New("gopenpgp: cannot diff keys with different primary keys")
+	}
+
+	diff := &KeyDiff{}
+
+	oldSubkeys := subkeysByFingerprint(key.entity)
+	newSubkeys := subkeysByFingerprint(other.entity)
+	for _, sub := range other.entity.Subkeys {
+		fingerprint := hex.EncodeToString(sub.PublicKey.Fingerprint)
+		oldSub, ok := oldSubkeys[fingerprint]
+		if !ok {
+			diff.AddedSubkeys = append(diff.AddedSubkeys, fingerprint)
+			continue
+		}
+		diff.addExpirationChange(
+			fingerprint,
+			keyExpirationTime(oldSub.PublicKey, oldSub.Sig),
+			keyExpirationTime(sub.PublicKey, sub.Sig),
+		)
+	}
+	for _, sub := range key.entity.Subkeys {
+		fingerprint := hex.EncodeToString(sub.PublicKey.Fingerprint)
+		if _, ok := newSubkeys[fingerprint]; !ok {
+			diff.RemovedSubkeys = append(diff.RemovedSubkeys, fingerprint)
+		}
+	}
+
+	diff.AddedUserIDs = missingIdentities(other.entity, key.entity)
+	diff.RemovedUserIDs = missingIdentities(key.entity, other.entity)
+
+	diff.addExpirationChange(
+		other.GetFingerprint(),
+		primaryKeyExpirationTime(key.entity),
+		primaryKeyExpirationTime(other.entity),
+	)
+
+	oldSignatures := make(map[string]bool)
+	for _, sig := range entitySignatures(key.entity) {
+		oldSignatures[sig.id] = true
+	}
+	for _, sig := range entitySignatures(other.entity) {
+		if oldSignatures[sig.id] {
+			continue
+		}
+		diff.NewSignatures = append(diff.NewSignatures, sig.description)
+		if isRevocation(sig.signature) {
+			diff.Revocations = append(diff.Revocations, sig.description)
+		}
+	}
+
+	return diff, nil
+}
+
+// IsEmpty returns true if the two versions of the certificate are equivalent.
+func (diff *KeyDiff) IsEmpty() bool {
+	return len(diff.AddedSubkeys) == 0 &&
+		len(diff.RemovedSubkeys) == 0 &&
+		len(diff.AddedUserIDs) == 0 &&
+		len(diff.RemovedUserIDs) == 0 &&
+		len(diff.NewSignatures) == 0 &&
+		len(diff.ExpirationChanges) == 0
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+type keyDiffSignature struct {
+	id          string
+	signature   *packet.Signature
+	description *KeyDiffSignature
+}
+
+func (diff *KeyDiff) addExpirationChange(fingerprint string, oldExpiration, newExpiration int64) {
+	if oldExpiration == newExpiration {
+		return
+	}
+	diff.ExpirationChanges = append(diff.ExpirationChanges, &KeyDiffExpiration{
+		Fingerprint:       fingerprint,
+		OldExpirationTime: oldExpiration,
+		NewExpirationTime: newExpiration,
+	})
+}
+
+func subkeysByFingerprint(entity *openpgp.Entity) map[string]*openpgp.Subkey {
+	subkeys := make(map[string]*openpgp.Subkey, len(entity.Subkeys))
+	for i := range entity.Subkeys {
+		subkeys[hex.EncodeToString(entity.Subkeys[i].PublicKey.Fingerprint)] = &entity.Subkeys[i]
+	}
+	return subkeys
+}
+
+// missingIdentities returns the sorted user IDs of entity that other lacks.
+func missingIdentities(entity, other *openpgp.Entity) (missing []string) {
+	for name := range entity.Identities {
+		if _, ok := other.Identities[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// keyExpirationTime returns the expiration time set by the self-signature
+// sig on the key pk, or 0 if the key does not expire.
+func keyExpirationTime(pk *packet.PublicKey, sig *packet.Signature) int64 {
+	if sig == nil || sig.KeyLifetimeSecs == nil || *sig.KeyLifetimeSecs == 0 {
+		return 0
+	}
+	return pk.CreationTime.Unix() + int64(*sig.KeyLifetimeSecs)
+}
+
+func primaryKeyExpirationTime(entity *openpgp.Entity) int64 {
+	if entity.SelfSignature != nil {
+		return keyExpirationTime(entity.PrimaryKey, entity.SelfSignature)
+	}
+	if identity := entity.PrimaryIdentity(); identity != nil {
+		return keyExpirationTime(entity.PrimaryKey, identity.SelfSignature)
+	}
+	return 0
+}
+
+func isRevocation(sig *packet.Signature) bool {
+	return sig.SigType == packet.SigTypeKeyRevocation ||
+		sig.SigType == packet.SigTypeSubkeyRevocation ||
+		sig.SigType == packet.SigTypeCertificationRevocation
+}
+
+// entitySignatures lists the signatures of the entity, identified by their
+// serialization, in a deterministic order.
+func entitySignatures(entity *openpgp.Entity) []*keyDiffSignature {
+	var signatures []*keyDiffSignature
+	seen := make(map[string]bool)
+	add := func(target string, sigs ...*packet.Signature) {
+		for _, sig := range sigs {
+			if sig == nil {
+				continue
+			}
+			id := signatureID(sig)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			description := &KeyDiffSignature{
+				Target:       target,
+				Type:         int(sig.SigType),
+				CreationTime: sig.CreationTime.Unix(),
+			}
+			if sig.IssuerKeyId != nil {
+				description.IssuerKeyID = keyIDToHex(*sig.IssuerKeyId)
+			}
+			signatures = append(signatures, &keyDiffSignature{id, sig, description})
+		}
+	}
+
+	primaryFingerprint := hex.EncodeToString(entity.PrimaryKey.Fingerprint)
+	add(primaryFingerprint, entity.SelfSignature)
+	add(primaryFingerprint, entity.Signatures...)
+	add(primaryFingerprint, entity.Revocations...)
+
+	names := make([]string, 0, len(entity.Identities))
+	for name := range entity.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		identity := entity.Identities[name]
+		add(name, identity.SelfSignature)
+		add(name, identity.Signatures...)
+		add(name, identity.Revocations...)
+	}
+
+	for _, sub := range entity.Subkeys {
+		fingerprint := hex.EncodeToString(sub.PublicKey.Fingerprint)
+		add(fingerprint, sub.Sig)
+		add(fingerprint, sub.Revocations...)
+	}
+	return signatures
+}
+
+// signatureID identifies a signature by its serialization, falling back to
+// its main fields if it can't be serialized.
+func signatureID(sig *packet.Signature) string {
+	var buf bytes.Buffer
+	if err := sig.Serialize(&buf); err == nil {
+		return buf.String()
+	}
+	var issuer uint64
+	if sig.IssuerKeyId != nil {
+		issuer = *sig.IssuerKeyId
+	}
+	return keyIDToHex(issuer) + sig.CreationTime.String() + string(rune(sig.SigType))
+}
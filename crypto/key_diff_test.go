@@ -0,0 +1,80 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyDiff(t *testing.T) {
+	oldKey, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	newKey, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+
+	diff, err := oldKey.Diff(newKey)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.True(t, diff.IsEmpty())
+
+	config := &packet.Config{Time: func() time.Time { return time.Unix(testTime+10, 0) }}
+	oldSubkeyFingerprint := hex.EncodeToString(newKey.entity.Subkeys[0].PublicKey.Fingerprint)
+	if err = newKey.entity.RevokeSubkey(&newKey.entity.Subkeys[0], packet.KeySuperseded, "", config); err != nil {
+		t.Fatal("Expected no error while revoking subkey, got:", err)
+	}
+	if err = newKey.entity.AddEncryptionSubkey(config); err != nil {
+		t.Fatal("Expected no error while adding subkey, got:", err)
+	}
+	newSubkeyFingerprint := hex.EncodeToString(newKey.entity.Subkeys[1].PublicKey.Fingerprint)
+
+	identity := newKey.entity.PrimaryIdentity()
+	lifetime := uint32(86400)
+	selfSignature := *identity.SelfSignature
+	selfSignature.KeyLifetimeSecs = &lifetime
+	selfSignature.CreationTime = config.Now()
+	if err = selfSignature.SignUserId(identity.Name, newKey.entity.PrimaryKey, newKey.entity.PrivateKey, config); err != nil {
+		t.Fatal("Expected no error while signing user ID, got:", err)
+	}
+	identity.SelfSignature = &selfSignature
+	identity.Signatures = append(identity.Signatures, &selfSignature)
+
+	diff, err = oldKey.Diff(newKey)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.False(t, diff.IsEmpty())
+	assert.Exactly(t, []string{newSubkeyFingerprint}, diff.AddedSubkeys)
+	assert.Empty(t, diff.RemovedSubkeys)
+	assert.Empty(t, diff.AddedUserIDs)
+	assert.Empty(t, diff.RemovedUserIDs)
+	assert.Len(t, diff.NewSignatures, 3)
+
+	assert.Len(t, diff.Revocations, 1)
+	assert.Exactly(t, oldSubkeyFingerprint, diff.Revocations[0].Target)
+	assert.Exactly(t, int(packet.SigTypeSubkeyRevocation), diff.Revocations[0].Type)
+	assert.Exactly(t, int64(testTime+10), diff.Revocations[0].CreationTime)
+	assert.Exactly(t, newKey.GetHexKeyID(), diff.Revocations[0].IssuerKeyID)
+
+	assert.Len(t, diff.ExpirationChanges, 1)
+	assert.Exactly(t, newKey.GetFingerprint(), diff.ExpirationChanges[0].Fingerprint)
+	assert.Exactly(t, int64(0), diff.ExpirationChanges[0].OldExpirationTime)
+	assert.Exactly(t, newKey.entity.PrimaryKey.CreationTime.Unix()+86400, diff.ExpirationChanges[0].NewExpirationTime)
+
+	reverseDiff, err := newKey.Diff(oldKey)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.Exactly(t, []string{newSubkeyFingerprint}, reverseDiff.RemovedSubkeys)
+	assert.Empty(t, reverseDiff.NewSignatures)
+
+	_, err = oldKey.Diff(keyTestRSA)
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package crypto
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// GenerateDualKey generates a dual certificate: a version 6 key, and a
+// version 4 key with the same user ID for senders and recipients that don't
+// support v6 keys yet. Both keys are returned in the same keyring, the v6 key
+// first. The self-signatures of the v6 key link it to the v4 key, with a
+// constants.LegacyVariantName notation containing the v4 fingerprint, and the
+// self-signatures of the v4 key link it back to the v6 key, with a
+// constants.V6VariantName notation containing the v6 fingerprint.
+// If keyType is "rsa", bits is the RSA bitsize of the keys.
+// If keyType is "x25519" bits is unused.
+func GenerateDualKey(name, email string, keyType string, bits int) (*KeyRing, error) {
+	keyV4, err := GenerateKey(name, email, keyType, bits)
+	if err != nil {
+		return nil, err
+	}
+	notations := []*packet.Notation{{
+		Name:  constants.LegacyVariantName,
+		Value: keyV4.entity.PrimaryKey.Fingerprint,
+	}}
+	keyV6, err := generateKey(name, email, &keyGenerationOptions{
+		keyType:   keyType,
+		bits:      bits,
+		v6:        true,
+		notations: notations,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = linkToV6Variant(keyV4, keyV6); err != nil {
+		return nil, err
+	}
+
+	keyRing, err := NewKeyRing(keyV6)
+	if err != nil {
+		return nil, err
+	}
+	if err = keyRing.AddKey(keyV4); err != nil {
+		return nil, err
+	}
+	return keyRing, nil
+}
+
+// EncryptWithCompatibleVariant encrypts a PlainMessage like Encrypt, but for
+// recipients with a dual certificate, i.e. a v6 key and a legacy v4 key
+// linked to each other as by GenerateDualKey, it only encrypts to the variant matching the
+// advertised features: the v6 key if it can encrypt and advertises AEAD
+// encrypted data (SEIPDv2), the legacy v4 key otherwise.
+// * message    : The plaintext input as a PlainMessage.
+// * privateKey : (optional) an unlocked private keyring to include signature in the message.
+func (keyRing *KeyRing) EncryptWithCompatibleVariant(message *PlainMessage, privateKey *KeyRing) (*PGPMessage, error) {
+	return asymmetricEncrypt(message, keyRing.getCompatibleVariants(), privateKey, false, nil)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getCompatibleVariants returns a keyring with a single variant of each dual
+// certificate in the keyring, other keys are kept as is.
+func (keyRing *KeyRing) getCompatibleVariants() *KeyRing {
+	// The variants left out of each dual certificate
+	skipped := make(map[*openpgp.Entity]bool)
+	for _, entity := range keyRing.entities {
+		legacyVariant := keyRing.findLegacyVariant(entity)
+		if legacyVariant == nil {
+			continue
+		}
+		if supportsV6Encryption(entity) {
+			skipped[legacyVariant] = true
+		} else {
+			skipped[entity] = true
+		}
+	}
+
+	var entities openpgp.EntityList
+	for _, entity := range keyRing.entities {
+		if !skipped[entity] {
+			entities = append(entities, entity)
+		}
+	}
+	return keyRing.withEntities(entities)
+}
+
+// findLegacyVariant returns the v4 entity of the keyring the v6 entity is
+// linked to by a constants.LegacyVariantName notation, or nil. The v4 entity
+// must link back to the v6 entity with a constants.V6VariantName notation.
+func (keyRing *KeyRing) findLegacyVariant(entity *openpgp.Entity) *openpgp.Entity {
+	if entity.PrimaryKey.Version != 6 {
+		return nil
+	}
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if selfSignature == nil {
+		return nil
+	}
+	for _, notation := range selfSignature.Notations {
+		if notation.Name != constants.LegacyVariantName {
+			continue
+		}
+		for _, candidate := range keyRing.entities {
+			if candidate.PrimaryKey.Version == 4 &&
+				bytes.Equal(candidate.PrimaryKey.Fingerprint, notation.Value) &&
+				hasVariantNotation(candidate, constants.V6VariantName, entity.PrimaryKey.Fingerprint) {
+				return candidate
+			}
+		}
+	}
+	return nil
+}
+
+// hasVariantNotation returns whether the primary self-signature of the
+// entity has a notation with the given name and fingerprint.
+func hasVariantNotation(entity *openpgp.Entity, name string, fingerprint []byte) bool {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	if selfSignature == nil {
+		return false
+	}
+	for _, notation := range selfSignature.Notations {
+		if notation.Name == name && bytes.Equal(notation.Value, fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkToV6Variant replaces the user ID self-signatures of the freshly
+// generated v4 key with self-signatures adding a constants.V6VariantName
+// notation with the fingerprint of the v6 key.
+func linkToV6Variant(keyV4, keyV6 *Key) error {
+	config := &packet.Config{Time: getTimeGenerator()}
+	applyConfigModifier(config)
+	primaryKey := keyV4.entity.PrivateKey
+	for _, identity := range keyV4.entity.Identities {
+		selfSignature := *identity.SelfSignature
+		selfSignature.Notations = append(append([]*packet.Notation{}, selfSignature.Notations...), &packet.Notation{
+			Name:  constants.V6VariantName,
+			Value: keyV6.entity.PrimaryKey.Fingerprint,
+		})
+		if err := selfSignature.SignUserId(identity.Name, &primaryKey.PublicKey, primaryKey, config); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in linking the dual certificate")
+		}
+		identity.SelfSignature = &selfSignature
+		identity.Signatures = []*packet.Signature{&selfSignature}
+	}
+	return nil
+}
+
+// supportsV6Encryption returns whether the v6 entity can be used for
+// encryption, and advertises support for SEIPDv2.
+func supportsV6Encryption(entity *openpgp.Entity) bool {
+	return validWithTolerance(func(now time.Time) bool {
+		_, canEncrypt := entity.EncryptionKey(now)
+		return canEncrypt && supportsSEIPDv2(entity)
+	})
+}
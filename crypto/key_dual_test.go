@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDualKey(t *testing.T) {
+	keyRing, err := GenerateDualKey(keyTestName, keyTestDomain, "x25519", 256)
+	if err != nil {
+		t.Fatal("Expected no error while generating dual key, got:", err)
+	}
+	assert.Exactly(t, 2, keyRing.CountEntities())
+	assert.Exactly(t, 6, keyRing.GetKeys()[0].GetVersion())
+	assert.Exactly(t, 4, keyRing.GetKeys()[1].GetVersion())
+
+	selfSignature, _ := keyRing.entities[0].PrimarySelfSignature()
+	assert.Exactly(t, constants.LegacyVariantName, selfSignature.Notations[0].Name)
+	assert.Exactly(t, keyRing.entities[1].PrimaryKey.Fingerprint, selfSignature.Notations[0].Value)
+
+	legacySelfSignature, _ := keyRing.entities[1].PrimarySelfSignature()
+	assert.Exactly(t, constants.V6VariantName, legacySelfSignature.Notations[0].Name)
+	assert.Exactly(t, keyRing.entities[0].PrimaryKey.Fingerprint, legacySelfSignature.Notations[0].Value)
+
+	variants := keyRing.getCompatibleVariants()
+	assert.Exactly(t, 1, variants.CountEntities())
+	assert.Exactly(t, 6, variants.GetKeys()[0].GetVersion())
+
+	ciphertext, err := keyRing.EncryptWithCompatibleVariant(NewPlainMessageFromString("hello"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	keyV4, err := keyRing.GetKey(1)
+	if err != nil {
+		t.Fatal("Expected no error while getting key, got:", err)
+	}
+	keyRingV4, err := NewKeyRing(keyV4)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	_, err = keyRingV4.Decrypt(ciphertext, nil, 0)
+	assert.Error(t, err)
+
+	decrypted, err := keyRing.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+
+	// The link survives serialization
+	var reparsed []*Key
+	for _, key := range keyRing.GetKeys() {
+		serialized, err := key.GetPublicKey()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		publicKey, err := NewKey(serialized)
+		if err != nil {
+			t.Fatal("Expected no error while parsing key, got:", err)
+		}
+		reparsed = append(reparsed, publicKey)
+	}
+	publicKeyRing, err := NewKeyRing(reparsed[0])
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = publicKeyRing.AddKey(reparsed[1]); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	assert.Exactly(t, 1, publicKeyRing.getCompatibleVariants().CountEntities())
+}
+
+func TestCompatibleVariantsWithoutCrossSignature(t *testing.T) {
+	keyV4, err := GenerateKey("other", "other@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	// A v6 key claiming the v4 key of another certificate
+	keyV6, err := generateKey(keyTestName, keyTestDomain, &keyGenerationOptions{
+		keyType: "x25519",
+		v6:      true,
+		notations: []*packet.Notation{{
+			Name:  constants.LegacyVariantName,
+			Value: keyV4.entity.PrimaryKey.Fingerprint,
+		}},
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = keyRing.AddKey(keyV4); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	assert.Exactly(t, 2, keyRing.getCompatibleVariants().CountEntities())
+}
+
+func TestCompatibleVariantsWithoutDualCertificate(t *testing.T) {
+	keyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	keyV6, err := GenerateKeyV6("other", "other@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	if err = keyRing.AddKey(keyV6); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	assert.Exactly(t, 2, keyRing.getCompatibleVariants().CountEntities())
+
+	// Unrelated keys with the same user ID are not variants
+	keyV4, err := GenerateKey("other", "other@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	if err = keyRing.AddKey(keyV4); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	assert.Exactly(t, 3, keyRing.getCompatibleVariants().CountEntities())
+
+	// Nor keys without user IDs
+	var withoutUserIDs openpgp.EntityList
+	for _, entity := range keyRing.entities {
+		stripped := *entity
+		stripped.Identities = nil
+		withoutUserIDs = append(withoutUserIDs, &stripped)
+	}
+	assert.Exactly(t, 3, keyRing.withEntities(withoutUserIDs).getCompatibleVariants().CountEntities())
+}
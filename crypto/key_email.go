@@ -0,0 +1,226 @@
+package crypto
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// EmailMatchPolicy defines how the email addresses of user IDs are compared
+// when keys are selected by email, see Key.MatchesEmail.
+// The zero value, used for a nil policy, compares the local parts
+// case-insensitively, and the domains after converting their punycode
+// labels to Unicode, so that "Alice@xn--bcher-kva.example" matches
+// "alice@bücher.example".
+type EmailMatchPolicy struct {
+	// CaseSensitiveLocalPart compares the local parts case-sensitively, as
+	// allowed by RFC 5321. Domains are always compared case-insensitively.
+	CaseSensitiveLocalPart bool
+	// IgnoreSubaddress ignores the subaddress of the local parts, starting
+	// at the first of SubaddressSeparators, so that
+	// "alice+lists@example.com" matches "alice@example.com".
+	IgnoreSubaddress bool
+	// SubaddressSeparators are the characters starting a subaddress, "+" if
+	// empty.
+	SubaddressSeparators string
+	// CompareRawDomains compares the domains as written, without
+	// converting their punycode labels.
+	CompareRawDomains bool
+}
+
+// MatchesEmail returns true if a user ID of the key has an email address
+// matching email with the policy, or the default policy if nil.
+// User IDs consisting of a bare email address are matched too.
+func (key *Key) MatchesEmail(email string, policy *EmailMatchPolicy) bool {
+	return entityMatchesEmail(key.entity, email, policy)
+}
+
+// MatchesUserID returns true if the email address of the user ID, or the
+// user ID itself if it is a bare email address, matches email with the
+// policy.
+func (policy *EmailMatchPolicy) MatchesUserID(userID *packet.UserId, email string) bool {
+	normalized, err := policy.NormalizeEmail(email)
+	if err != nil {
+		return false
+	}
+	return policy.matchesUserID(userID, normalized)
+}
+
+// NormalizeEmail returns the form of email compared by the policy, e.g. to
+// index keys by email address. It returns an error if email is not an
+// email address.
+func (policy *EmailMatchPolicy) NormalizeEmail(email string) (string, error) {
+	if policy == nil {
+		policy = &EmailMatchPolicy{}
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 {
+		return "", errors.New("gopenpgp: invalid email address " + email)
+	}
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	if policy.IgnoreSubaddress {
+		separators := policy.SubaddressSeparators
+		if separators == "" {
+			separators = "+"
+		}
+		if i := strings.IndexAny(local, separators); i > 0 {
+			local = local[:i]
+		}
+	}
+	if !policy.CaseSensitiveLocalPart {
+		local = strings.ToLower(local)
+	}
+	domain = strings.ToLower(domain)
+	if !policy.CompareRawDomains {
+		domain = strings.ToLower(getUnicodeDomain(domain))
+	}
+	return local + "@" + domain, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// entityMatchesEmail returns true if a user ID of the entity matches email
+// with the policy.
+func entityMatchesEmail(entity *openpgp.Entity, email string, policy *EmailMatchPolicy) bool {
+	normalized, err := policy.NormalizeEmail(email)
+	if err != nil {
+		return false
+	}
+	for _, identity := range entity.Identities {
+		if policy.matchesUserID(identity.UserId, normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesUserID returns true if the user ID matches the email address
+// already normalized with the policy.
+func (policy *EmailMatchPolicy) matchesUserID(userID *packet.UserId, normalized string) bool {
+	if userID == nil {
+		return false
+	}
+	candidate := userID.Email
+	if candidate == "" && !strings.ContainsAny(userID.Name, " <>") {
+		// A bare email address is parsed as a name by go-crypto
+		candidate = userID.Name
+	}
+	normalizedCandidate, err := policy.NormalizeEmail(candidate)
+	return err == nil && normalizedCandidate == normalized
+}
+
+// getUnicodeDomain converts the punycode labels of domain, starting with
+// "xn--", to Unicode. Invalid labels are kept as is.
+func getUnicodeDomain(domain string) string {
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, "xn--") {
+			continue
+		}
+		if decoded, err := decodePunycode(label[len("xn--"):]); err == nil {
+			labels[i] = decoded
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// Parameters of the punycode encoding of domain names, see RFC 3492.
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+// decodePunycode decodes a punycode label, see RFC 3492, section 6.2.
+func decodePunycode(encoded string) (string, error) {
+	errInvalid := errors.New("gopenpgp: invalid punycode label")
+	var output []rune
+	position := 0
+	if delimiter := strings.LastIndexByte(encoded, '-'); delimiter >= 0 {
+		for _, r := range encoded[:delimiter] {
+			if r >= 0x80 {
+				return "", errInvalid
+			}
+			output = append(output, r)
+		}
+		position = delimiter + 1
+	}
+
+	n, bias, i := punycodeInitialN, punycodeInitialBias, 0
+	for position < len(encoded) {
+		oldI, weight := i, 1
+		for k := punycodeBase; ; k += punycodeBase {
+			if position >= len(encoded) {
+				return "", errInvalid
+			}
+			digit := decodePunycodeDigit(encoded[position])
+			position++
+			if digit < 0 || digit > (math.MaxInt32-i)/weight {
+				return "", errInvalid
+			}
+			i += digit * weight
+			threshold := k - bias
+			if threshold < punycodeTMin {
+				threshold = punycodeTMin
+			} else if threshold > punycodeTMax {
+				threshold = punycodeTMax
+			}
+			if digit < threshold {
+				break
+			}
+			if weight > math.MaxInt32/(punycodeBase-threshold) {
+				return "", errInvalid
+			}
+			weight *= punycodeBase - threshold
+		}
+		bias = adaptPunycodeBias(i-oldI, len(output)+1, oldI == 0)
+		if i/(len(output)+1) > math.MaxInt32-n {
+			return "", errInvalid
+		}
+		n += i / (len(output) + 1)
+		i %= len(output) + 1
+		if n > 0x10ffff {
+			return "", errInvalid
+		}
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = rune(n)
+		i++
+	}
+	return string(output), nil
+}
+
+func decodePunycodeDigit(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a')
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A')
+	}
+	return -1
+}
+
+func adaptPunycodeBias(delta, points int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
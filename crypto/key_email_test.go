@@ -0,0 +1,45 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyMatchesEmail(t *testing.T) {
+	key, err := GenerateKey("Alice", "alice+lists@xn--bcher-kva.example", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+
+	assert.True(t, key.MatchesEmail("alice+lists@xn--bcher-kva.example", nil))
+	assert.True(t, key.MatchesEmail("ALICE+lists@Bücher.example", nil))
+	assert.False(t, key.MatchesEmail("alice@bücher.example", nil))
+	assert.False(t, key.MatchesEmail("alice+lists", nil))
+
+	assert.True(t, key.MatchesEmail("alice@bücher.example", &EmailMatchPolicy{IgnoreSubaddress: true}))
+	assert.True(t, key.MatchesEmail("alice-news@bücher.example", &EmailMatchPolicy{
+		IgnoreSubaddress:     true,
+		SubaddressSeparators: "+-",
+	}))
+	assert.False(t, key.MatchesEmail("Alice+lists@bücher.example", &EmailMatchPolicy{CaseSensitiveLocalPart: true}))
+	assert.False(t, key.MatchesEmail("alice+lists@bücher.example", &EmailMatchPolicy{CompareRawDomains: true}))
+
+	normalized, err := (&EmailMatchPolicy{IgnoreSubaddress: true}).NormalizeEmail("Bob+x@XN--MNCHEN-3YA.example.")
+	if err != nil {
+		t.Fatal("Expected no error while normalizing email, got:", err)
+	}
+	assert.Exactly(t, "bob@münchen.example", normalized)
+	_, err = decodePunycode("aé-b")
+	assert.Error(t, err)
+}
+
+func TestEmailMatchPolicyMatchesUserID(t *testing.T) {
+	policy := &EmailMatchPolicy{IgnoreSubaddress: true}
+	assert.True(t, policy.MatchesUserID(packet.NewUserId("Alice", "", "Alice+work@example.org"), "alice@example.org"))
+	assert.True(t, policy.MatchesUserID(packet.NewUserId("alice@example.org", "", ""), "ALICE@example.org"))
+	assert.False(t, policy.MatchesUserID(packet.NewUserId("Alice", "", "bob@example.org"), "alice@example.org"))
+	assert.False(t, policy.MatchesUserID(nil, "alice@example.org"))
+	assert.False(t, (*EmailMatchPolicy)(nil).MatchesUserID(packet.NewUserId("Alice", "", "alice+work@example.org"), "alice@example.org"))
+}
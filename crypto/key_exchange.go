@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyExchangeScheme is the scheme of the key exchange URIs, as used in QR
+// codes by e.g. OpenKeychain and Delta Chat.
+const keyExchangeScheme = "OPENPGP4FPR:"
+
+// KeyExchangePayload is the content of an OPENPGP4FPR key exchange URI,
+// e.g. scanned from a QR code to verify a key in person.
+type KeyExchangePayload struct {
+	// Fingerprint is the lower case hex fingerprint of the primary key.
+	Fingerprint string
+	// Email is the optional email address of the key holder.
+	Email string
+	// Name is the optional name of the key holder.
+	Name string
+}
+
+// GetKeyExchangeURI returns the OPENPGP4FPR URI of the key, with the
+// fingerprint of the primary key and the email address and name of its
+// primary user ID, e.g. to display it as a QR code:
+//
+//	OPENPGP4FPR:E58294F2E9A227486E8B061B31CC528FD7FA3F19#a=alice%40example.com&n=Alice
+func (key *Key) GetKeyExchangeURI() string {
+	payload := &KeyExchangePayload{Fingerprint: key.GetFingerprint()}
+	if _, identity := key.entity.PrimarySelfSignature(); identity != nil && identity.UserId != nil {
+		payload.Email = identity.UserId.Email
+		payload.Name = identity.UserId.Name
+	}
+	return payload.GetURI()
+}
+
+// GetURI returns the OPENPGP4FPR URI of the payload.
+func (payload *KeyExchangePayload) GetURI() string {
+	uri := keyExchangeScheme + strings.ToUpper(payload.Fingerprint)
+	parameters := url.Values{}
+	if payload.Email != "" {
+		parameters.Set("a", payload.Email)
+	}
+	if payload.Name != "" {
+		parameters.Set("n", payload.Name)
+	}
+	if len(parameters) > 0 {
+		uri += "#" + parameters.Encode()
+	}
+	return uri
+}
+
+// ParseKeyExchangeURI parses an OPENPGP4FPR URI, e.g. scanned from a QR code.
+// The fingerprint is normalized as in ParseFingerprint, and the unknown
+// parameters are ignored.
+func ParseKeyExchangeURI(uri string) (*KeyExchangePayload, error) {
+	uri = strings.TrimSpace(uri)
+	if len(uri) < len(keyExchangeScheme) || !strings.EqualFold(uri[:len(keyExchangeScheme)], keyExchangeScheme) {
+		return nil, errors.New("gopenpgp: not an " + keyExchangeScheme + " URI")
+	}
+	uri = uri[len(keyExchangeScheme):]
+
+	encodedFingerprint, encodedParameters := uri, ""
+	if index := strings.IndexByte(uri, '#'); index >= 0 {
+		encodedFingerprint, encodedParameters = uri[:index], uri[index+1:]
+	}
+	fingerprint, err := ParseFingerprint(encodedFingerprint)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid key exchange URI")
+	}
+	parameters, err := url.ParseQuery(encodedParameters)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid key exchange URI parameters")
+	}
+
+	return &KeyExchangePayload{
+		Fingerprint: fingerprint,
+		Email:       parameters.Get("a"),
+		Name:        parameters.Get("n"),
+	}, nil
+}
+
+// Matches returns true if the payload contains the fingerprint of the primary
+// key, e.g. to verify a key received by email against a scanned QR code.
+func (payload *KeyExchangePayload) Matches(key *Key) bool {
+	return key.MatchesFingerprint(payload.Fingerprint)
+}
@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCompactBinary(t *testing.T) {
+	compact, err := keyTestEC.ExportCompactBinary()
+	if err != nil {
+		t.Fatal("Expected no error while exporting compact key, got:", err)
+	}
+	full, err := keyTestEC.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while exporting key, got:", err)
+	}
+	assert.LessOrEqual(t, len(compact), len(full))
+
+	key, err := NewKey(compact)
+	if err != nil {
+		t.Fatal("Expected no error while reading compact key, got:", err)
+	}
+	assert.Exactly(t, keyTestEC.GetFingerprint(), key.GetFingerprint())
+	assert.True(t, key.CanEncrypt())
+	assert.True(t, key.CanVerify())
+}
+
+func TestExportCompactBinaryRevokedSubkey(t *testing.T) {
+	key, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	entity := key.GetEntity()
+	if err = entity.RevokeSubkey(&entity.Subkeys[0], packet.KeyCompromised, "", nil); err != nil {
+		t.Fatal("Expected no error while revoking subkey, got:", err)
+	}
+
+	compact, err := key.ExportCompactBinary()
+	if err != nil {
+		t.Fatal("Expected no error while exporting compact key, got:", err)
+	}
+	compactKey, err := NewKey(compact)
+	if err != nil {
+		t.Fatal("Expected no error while reading compact key, got:", err)
+	}
+	subkeys := compactKey.GetEntity().Subkeys
+	assert.Len(t, subkeys, 1)
+	assert.Len(t, subkeys[0].Revocations, 1)
+	assert.True(t, subkeys[0].Revoked(time.Now()))
+}
+
+func TestKeyExchangeURI(t *testing.T) {
+	uri := keyTestEC.GetKeyExchangeURI()
+	assert.Contains(t, uri, "OPENPGP4FPR:")
+
+	payload, err := ParseKeyExchangeURI(uri)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key exchange URI, got:", err)
+	}
+	assert.Exactly(t, keyTestEC.GetFingerprint(), payload.Fingerprint)
+	assert.Exactly(t, keyTestDomain, payload.Email)
+	assert.Exactly(t, keyTestName, payload.Name)
+	assert.True(t, payload.Matches(keyTestEC))
+	assert.False(t, payload.Matches(keyTestRSA))
+
+	payload, err = ParseKeyExchangeURI("openpgp4fpr:e58294f2e9a227486e8b061b31cc528fd7fa3f19")
+	if err != nil {
+		t.Fatal("Expected no error while parsing key exchange URI, got:", err)
+	}
+	assert.Exactly(t, testFingerprint, payload.Fingerprint)
+	assert.Exactly(t, "OPENPGP4FPR:E58294F2E9A227486E8B061B31CC528FD7FA3F19", payload.GetURI())
+
+	_, err = ParseKeyExchangeURI("mailto:alice@example.com")
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+// ExpiringComponent is a part of a key that is still valid, but expires
+// soon, see KeyRing.GetExpiringComponents.
+type ExpiringComponent struct {
+	// Fingerprint is the hex fingerprint of the primary key.
+	Fingerprint string
+	// Type is the expiring component, one of the
+	// constants.ExpiringComponent* values.
+	Type string
+	// Target is the hex fingerprint of the primary key or subkey, or the
+	// user ID.
+	Target string
+	// ExpirationTime is the expiration time, as a unix timestamp.
+	ExpirationTime int64
+}
+
+// GetExpiringComponents returns the components of the keys of the keyring
+// that are currently valid and expire within the given number of seconds,
+// sorted by expiration time, e.g. to remind the owner to extend or rotate
+// them: the primary keys, the subkeys, and the self-certifications of the
+// user IDs. Keys expire at the earlier of their expiration time and the
+// expiration of their self-signature. Revoked components are ignored.
+func (keyRing *KeyRing) GetExpiringComponents(withinSeconds int64) []*ExpiringComponent {
+	now := getNow()
+	deadline := now.Unix() + withinSeconds
+
+	var components []*ExpiringComponent
+	add := func(entity *openpgp.Entity, componentType, target string, expiration int64) {
+		if expiration != 0 && expiration > now.Unix() && expiration <= deadline {
+			components = append(components, &ExpiringComponent{
+				Fingerprint:    hex.EncodeToString(entity.PrimaryKey.Fingerprint),
+				Type:           componentType,
+				Target:         target,
+				ExpirationTime: expiration,
+			})
+		}
+	}
+
+	for _, entity := range keyRing.entities {
+		primarySelfSignature, _ := entity.PrimarySelfSignature()
+		if primarySelfSignature == nil || entity.Revoked(now) {
+			continue
+		}
+		fingerprint := hex.EncodeToString(entity.PrimaryKey.Fingerprint)
+		add(entity, constants.ExpiringComponentKey, fingerprint, bindingExpirationTime(entity.PrimaryKey, primarySelfSignature))
+
+		for _, identity := range entity.Identities {
+			signature := identity.SelfSignature
+			if signature == nil || identity.Revoked(now) || signature.SigLifetimeSecs == nil || *signature.SigLifetimeSecs == 0 {
+				continue
+			}
+			add(entity, constants.ExpiringComponentUserID, identity.Name, signature.CreationTime.Unix()+int64(*signature.SigLifetimeSecs))
+		}
+
+		for i := range entity.Subkeys {
+			subkey := &entity.Subkeys[i]
+			if subkey.Revoked(now) {
+				continue
+			}
+			add(entity, constants.ExpiringComponentSubkey, hex.EncodeToString(subkey.PublicKey.Fingerprint), bindingExpirationTime(subkey.PublicKey, subkey.Sig))
+		}
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].ExpirationTime < components[j].ExpirationTime
+	})
+	return components
+}
@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetExpiringComponents(t *testing.T) {
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	entity := key.GetEntity()
+	creationTime := entity.PrimaryKey.CreationTime.Unix()
+
+	subkey := &entity.Subkeys[0]
+	subkeyLifetime := uint32(3600)
+	subkey.Sig.KeyLifetimeSecs = &subkeyLifetime
+	if err := subkey.Sig.SignKey(subkey.PublicKey, entity.PrivateKey, nil); err != nil {
+		t.Fatal("Expected no error while signing subkey binding, got:", err)
+	}
+	for name, identity := range entity.Identities {
+		keyLifetime := uint32(7200)
+		identity.SelfSignature.KeyLifetimeSecs = &keyLifetime
+		signatureLifetime := uint32(5400)
+		identity.SelfSignature.SigLifetimeSecs = &signatureLifetime
+		if err := identity.SelfSignature.SignUserId(name, entity.PrimaryKey, entity.PrivateKey, nil); err != nil {
+			t.Fatal("Expected no error while signing user ID, got:", err)
+		}
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	assert.Empty(t, keyRing.GetExpiringComponents(60))
+
+	components := keyRing.GetExpiringComponents(3600)
+	assert.Len(t, components, 1)
+	assert.Exactly(t, &ExpiringComponent{
+		Fingerprint:    key.GetFingerprint(),
+		Type:           constants.ExpiringComponentSubkey,
+		Target:         hex.EncodeToString(subkey.PublicKey.Fingerprint),
+		ExpirationTime: creationTime + 3600,
+	}, components[0])
+
+	// The primary key expires with its self-signature, before its lifetime
+	components = keyRing.GetExpiringComponents(86400)
+	assert.Len(t, components, 3)
+	assert.Exactly(t, constants.ExpiringComponentKey, components[1].Type)
+	assert.Exactly(t, key.GetFingerprint(), components[1].Target)
+	assert.Exactly(t, creationTime+5400, components[1].ExpirationTime)
+	assert.Exactly(t, constants.ExpiringComponentUserID, components[2].Type)
+	assert.Exactly(t, creationTime+5400, components[2].ExpirationTime)
+}
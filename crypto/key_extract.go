@@ -0,0 +1,114 @@
+package crypto
+
+import (
+	"io/ioutil"
+	"mime/quotedprintable"
+	"strings"
+
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/pkg/errors"
+)
+
+// ExtractKeys scans free-form text, e.g. an email body or a chat message,
+// for any number of armored public or private key blocks, and returns the
+// keys they contain, in order. Keys found several times, e.g. in a quoted
+// reply, are returned once.
+// The common alterations of pasted keys are repaired: "> " quoting,
+// indentation, quoted-printable encoding, mixed line endings, and the
+// alterations accepted by armor.UnarmorLenient, e.g. a missing checksum.
+// Blocks that can't be read are skipped: an error is only returned if no
+// key could be read.
+func ExtractKeys(text string) ([]*Key, error) {
+	var keys []*Key
+	fingerprints := make(map[string]bool)
+	var firstErr error
+	for _, block := range findKeyBlocks(text) {
+		blockKeys, err := readKeyBlock(block)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, key := range blockKeys {
+			if !fingerprints[key.GetFingerprint()] {
+				fingerprints[key.GetFingerprint()] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		if firstErr != nil {
+			return nil, errors.Wrap(firstErr, "gopenpgp: no key could be extracted")
+		}
+		return nil, errors.New("gopenpgp: no armored key found")
+	}
+	return keys, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// findKeyBlocks returns the armored key blocks of text, without the quoting
+// and indentation of their lines.
+func findKeyBlocks(text string) []string {
+	var blocks []string
+	var block strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(text, "\n") {
+		// Armor lines never contain '>' nor whitespace
+		line = strings.TrimSpace(strings.TrimLeft(line, "> \t"))
+		if !inBlock {
+			if isKeyArmorLine(line, "BEGIN") {
+				inBlock = true
+				block.Reset()
+				block.WriteString(line + "\n")
+			}
+			continue
+		}
+		if isKeyArmorLine(line, "BEGIN") {
+			// The previous block has no end line, start again
+			block.Reset()
+		}
+		block.WriteString(line + "\n")
+		if isKeyArmorLine(line, "END") {
+			blocks = append(blocks, block.String())
+			inBlock = false
+		}
+	}
+	if inBlock {
+		// UnarmorLenient accepts a missing end line
+		blocks = append(blocks, block.String())
+	}
+	return blocks
+}
+
+// readKeyBlock reads the keys of an armored block, decoding it first if it
+// can't be read as is and looks quoted-printable encoded. The block is
+// tried as is first, as the checksum line of an armored block can start
+// with "=3D".
+func readKeyBlock(block string) ([]*Key, error) {
+	keys, err := readArmoredKeys(block)
+	if err == nil || !strings.Contains(strings.ToUpper(block), "=3D") {
+		return keys, err
+	}
+	decoded, qpErr := ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(block)))
+	if qpErr != nil {
+		return nil, err
+	}
+	if keys, qpErr := readArmoredKeys(string(decoded)); qpErr == nil {
+		return keys, nil
+	}
+	return nil, err
+}
+
+func readArmoredKeys(block string) ([]*Key, error) {
+	data, _, err := armor.UnarmorLenient(block)
+	if err != nil {
+		return nil, err
+	}
+	keyRing, err := NewKeyRingFromBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	return keyRing.GetKeys(), nil
+}
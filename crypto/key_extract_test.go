@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"bytes"
+	"mime/quotedprintable"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractKeys(t *testing.T) {
+	armoredEC, err := keyTestEC.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	armoredRSA, err := keyTestRSA.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+
+	// Quoted-printable encoded, then quoted in a reply
+	var encoded bytes.Buffer
+	qpWriter := quotedprintable.NewWriter(&encoded)
+	if _, err = qpWriter.Write([]byte(armoredRSA)); err != nil {
+		t.Fatal("Expected no error while encoding key, got:", err)
+	}
+	if err = qpWriter.Close(); err != nil {
+		t.Fatal("Expected no error while encoding key, got:", err)
+	}
+	assert.Contains(t, encoded.String(), "=3D")
+	quotedRSA := "> " + strings.ReplaceAll(encoded.String(), "\n", "\n> ")
+
+	text := "Hi, here is my key:\r\n\r\n" + strings.ReplaceAll(armoredEC, "\n", "\r\n") +
+		"\r\nand the one you sent me:\n\n" + quotedRSA +
+		"\n\nMine again, indented:\n\n    " + strings.ReplaceAll(armoredEC, "\n", "\n    ")
+	keys, err := ExtractKeys(text)
+	if err != nil {
+		t.Fatal("Expected no error while extracting keys, got:", err)
+	}
+	assert.Exactly(t, 2, len(keys))
+	assert.Exactly(t, keyTestEC.GetFingerprint(), keys[0].GetFingerprint())
+	assert.Exactly(t, keyTestRSA.GetFingerprint(), keys[1].GetFingerprint())
+	assert.False(t, keys[0].IsPrivate())
+
+	// Unreadable blocks are skipped
+	lines := strings.Split(armoredRSA, "\n")
+	corrupted := strings.Join(append(lines[:4:4], lines[6:]...), "\n")
+	keys, err = ExtractKeys(corrupted + "\n" + armoredEC)
+	if err != nil {
+		t.Fatal("Expected no error while extracting keys, got:", err)
+	}
+	assert.Exactly(t, 1, len(keys))
+
+	_, err = ExtractKeys(corrupted)
+	assert.Error(t, err)
+	_, err = ExtractKeys("no key here")
+	assert.Error(t, err)
+
+	// A checksum line starting with "=3D" is not quoted-printable
+	lines = strings.Split(strings.TrimSpace(armoredEC), "\n")
+	lines[len(lines)-2] = "=3Dab"
+	keys, err = ExtractKeys(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal("Expected no error while extracting keys, got:", err)
+	}
+	assert.Exactly(t, keyTestEC.GetFingerprint(), keys[0].GetFingerprint())
+}
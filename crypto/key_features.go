@@ -0,0 +1,182 @@
+package crypto
+
+import (
+	"encoding/hex"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// SEIPD versions used when encrypting to a keyring.
+const (
+	seipdVersionAuto = iota
+	seipdVersion1
+	seipdVersion2
+)
+
+// SupportsSEIPDv1 returns true if the key advertises support for
+// integrity protected encrypted data with a modification detection code (SEIPDv1).
+func (key *Key) SupportsSEIPDv1() bool {
+	selfSignature, _ := key.entity.PrimarySelfSignature()
+	return selfSignature != nil && selfSignature.SEIPDv1
+}
+
+// SupportsSEIPDv2 returns true if the key advertises support for
+// AEAD encrypted data (SEIPDv2).
+func (key *Key) SupportsSEIPDv2() bool {
+	return supportsSEIPDv2(key.entity)
+}
+
+// GetAEADModes returns the names of the AEAD modes the key advertises for
+// SEIPDv2, in order of preference, e.g. constants.AEADModeOCB.
+func (key *Key) GetAEADModes() []string {
+	selfSignature, _ := key.entity.PrimarySelfSignature()
+	if selfSignature == nil {
+		return nil
+	}
+
+	var modes []string
+	seen := make(map[uint8]bool)
+	for _, cipherSuite := range selfSignature.PreferredCipherSuites {
+		mode := cipherSuite[1]
+		if seen[mode] {
+			continue
+		}
+		seen[mode] = true
+		modes = append(modes, getAEADModeName(packet.AEADMode(mode)))
+	}
+	return modes
+}
+
+// ForceSEIPDv1 makes encryption to the keyring always use SEIPDv1,
+// e.g. for recipients whose implementation wrongly advertises SEIPDv2.
+// By default, SEIPDv2 is used if all the keys advertise support for it.
+func (keyRing *KeyRing) ForceSEIPDv1() {
+	keyRing.seipdVersion = seipdVersion1
+}
+
+// ForceSEIPDv2 makes encryption to the keyring always use SEIPDv2:
+// encryption fails with a SEIPDVersionError listing the keys which don't
+// advertise support for it.
+// By default, SEIPDv2 is used if all the keys advertise support for it.
+func (keyRing *KeyRing) ForceSEIPDv2() {
+	keyRing.seipdVersion = seipdVersion2
+}
+
+// SEIPDVariant is one of the messages returned by EncryptPerSEIPDVersion,
+// encrypted to a part of the recipients.
+type SEIPDVariant struct {
+	// Message is the encrypted message.
+	Message *PGPMessage
+	// SEIPDVersion is the version of the encrypted data packet, 1 or 2.
+	SEIPDVersion int
+	// Fingerprints are the hex fingerprints of the primary keys of the
+	// recipients of the message.
+	Fingerprints []string
+}
+
+// EncryptPerSEIPDVersion encrypts a PlainMessage like Encrypt, but instead
+// of falling back to SEIPDv1 for all the recipients when a key doesn't
+// advertise support for SEIPDv2, returns a message encrypted with SEIPDv2 to
+// the keys supporting it, and a message encrypted with SEIPDv1 to the other
+// keys, in this order. A single message is returned if all the keys are
+// in the same case. ForceSEIPDv1 and ForceSEIPDv2 are ignored.
+// The sender set by AlsoEncryptToSender is grouped like the other keys.
+// * message    : The plaintext input as a PlainMessage.
+// * privateKey : (optional) an unlocked private keyring to include signature in the message.
+func (keyRing *KeyRing) EncryptPerSEIPDVersion(message *PlainMessage, privateKey *KeyRing) ([]*SEIPDVariant, error) {
+	var signEntity *openpgp.Entity
+	if privateKey != nil && len(privateKey.entities) > 0 {
+		var err error
+		signEntity, err = privateKey.getSigningEntity()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entitiesV1, entitiesV2 openpgp.EntityList
+	for _, entity := range keyRing.withSender(signEntity).entities {
+		if supportsSEIPDv2(entity) {
+			entitiesV2 = append(entitiesV2, entity)
+		} else {
+			entitiesV1 = append(entitiesV1, entity)
+		}
+	}
+
+	var variants []*SEIPDVariant
+	for _, group := range []struct {
+		entities     openpgp.EntityList
+		seipdVersion int
+	}{{entitiesV2, seipdVersion2}, {entitiesV1, seipdVersion1}} {
+		if len(group.entities) == 0 {
+			continue
+		}
+		recipients := keyRing.withEntities(group.entities)
+		recipients.seipdVersion = group.seipdVersion
+		// The sender is already in its group
+		recipients.encryptToSender = false
+		ciphertext, err := recipients.Encrypt(message, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		variant := &SEIPDVariant{Message: ciphertext, SEIPDVersion: group.seipdVersion}
+		for _, entity := range group.entities {
+			variant.Fingerprints = append(variant.Fingerprints, hex.EncodeToString(entity.PrimaryKey.Fingerprint))
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getEncryptionAEADConfig returns the AEAD configuration to use when
+// encrypting to the keyring. Encryption falls back to SEIPDv1 if a key
+// doesn't advertise SEIPDv2, unless ForceSEIPDv2 was called.
+func (keyRing *KeyRing) getEncryptionAEADConfig() (*packet.AEADConfig, error) {
+	switch keyRing.seipdVersion {
+	case seipdVersion1:
+		return nil, nil
+	case seipdVersion2:
+		var incompatible []string
+		for _, entity := range keyRing.entities {
+			if !supportsSEIPDv2(entity) {
+				incompatible = append(incompatible, hex.EncodeToString(entity.PrimaryKey.Fingerprint))
+			}
+		}
+		if len(incompatible) > 0 {
+			return nil, errors.Wrap(SEIPDVersionError{Fingerprints: incompatible}, "gopenpgp: error in encrypting")
+		}
+	}
+	return &packet.AEADConfig{DefaultMode: packet.AEADModeOCB}, nil
+}
+
+// withEntities returns a keyring with the given entities and the settings of
+// the keyring.
+func (keyRing *KeyRing) withEntities(entities openpgp.EntityList) *KeyRing {
+	return &KeyRing{
+		entities:        entities,
+		FirstKeyID:      keyRing.FirstKeyID,
+		keyRingSettings: keyRing.keyRingSettings,
+	}
+}
+
+func supportsSEIPDv2(entity *openpgp.Entity) bool {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	return selfSignature != nil && selfSignature.SEIPDv2
+}
+
+func getAEADModeName(mode packet.AEADMode) string {
+	switch mode {
+	case packet.AEADModeEAX:
+		return constants.AEADModeEAX
+	case packet.AEADModeOCB:
+		return constants.AEADModeOCB
+	case packet.AEADModeGCM:
+		return constants.AEADModeGCM
+	default:
+		return "unknown"
+	}
+}
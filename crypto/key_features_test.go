@@ -0,0 +1,148 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFeatures(t *testing.T) {
+	assert.True(t, keyTestEC.SupportsSEIPDv1())
+	assert.False(t, keyTestEC.SupportsSEIPDv2())
+
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.True(t, keyV6.SupportsSEIPDv2())
+	assert.Contains(t, keyV6.GetAEADModes(), constants.AEADModeOCB)
+}
+
+func TestEncryptSEIPDVersion(t *testing.T) {
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRingV6, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	keyRingV4, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	message := NewPlainMessageFromString("hello")
+
+	ciphertext, err := keyRingV6.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Exactly(t, 2, getSEIPDVersion(t, ciphertext))
+	decrypted, err := keyRingV6.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+
+	ciphertext, err = keyRingV4.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Exactly(t, 1, getSEIPDVersion(t, ciphertext))
+
+	keyRingV6.ForceSEIPDv1()
+	ciphertext, err = keyRingV6.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Exactly(t, 1, getSEIPDVersion(t, ciphertext))
+
+	keyRingV4.ForceSEIPDv2()
+	_, err = keyRingV4.Encrypt(message, nil)
+	assert.True(t, errors.Is(err, ErrUnsupportedAlgorithm))
+}
+
+func getSEIPDVersion(t *testing.T, message *PGPMessage) int {
+	packets := packet.NewReader(bytes.NewReader(message.GetBinary()))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			t.Fatal("Expected no error while reading packets, got:", err)
+		}
+		if encrypted, ok := p.(*packet.SymmetricallyEncrypted); ok {
+			return encrypted.Version
+		}
+	}
+}
+
+func TestEncryptPerSEIPDVersion(t *testing.T) {
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err := keyRing.AddKey(keyTestEC); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	message := NewPlainMessageFromString("hello")
+
+	variants, err := keyRing.EncryptPerSEIPDVersion(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Len(t, variants, 2)
+	assert.Exactly(t, 2, variants[0].SEIPDVersion)
+	assert.Exactly(t, 2, getSEIPDVersion(t, variants[0].Message))
+	assert.Exactly(t, []string{keyV6.GetFingerprint()}, variants[0].Fingerprints)
+	assert.Exactly(t, 1, variants[1].SEIPDVersion)
+	assert.Exactly(t, 1, getSEIPDVersion(t, variants[1].Message))
+	assert.Exactly(t, []string{keyTestEC.GetFingerprint()}, variants[1].Fingerprints)
+
+	keyRingV6, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	decrypted, err := keyRingV6.Decrypt(variants[0].Message, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+
+	keyRing.ForceSEIPDv2()
+	_, err = keyRing.Encrypt(message, nil)
+	var versionError SEIPDVersionError
+	assert.True(t, errors.As(err, &versionError))
+	assert.Exactly(t, []string{keyTestEC.GetFingerprint()}, versionError.Fingerprints)
+
+	variants, err = keyRingV6.EncryptPerSEIPDVersion(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Len(t, variants, 1)
+	assert.Exactly(t, 2, variants[0].SEIPDVersion)
+
+	// The sender is encrypted to with the keys of its SEIPD version
+	keyRingV6.AlsoEncryptToSender(nil)
+	variants, err = keyRingV6.EncryptPerSEIPDVersion(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Len(t, variants, 2)
+	assert.Exactly(t, []string{keyV6.GetFingerprint()}, variants[0].Fingerprints)
+	assert.Exactly(t, 1, variants[1].SEIPDVersion)
+	senderFingerprint := hex.EncodeToString(keyRingTestPrivate.entities[0].PrimaryKey.Fingerprint)
+	assert.Exactly(t, []string{senderFingerprint}, variants[1].Fingerprints)
+	decrypted, err = keyRingTestPrivate.Decrypt(variants[1].Message, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+}
@@ -0,0 +1,153 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// KeyInfo describes a key, its user IDs and its subkeys. It is returned by
+// Key.GetInfo, and serialized with the versioned JSON schema described in
+// KeyInfo.GetJSON.
+type KeyInfo struct {
+	// PrimaryKey describes the primary key.
+	PrimaryKey *KeyComponentInfo `json:"primaryKey"`
+	// Subkeys describes the subkeys, in the order of the key.
+	Subkeys []*KeyComponentInfo `json:"subkeys"`
+	// UserIDs contains the user IDs, the primary one first.
+	UserIDs []string `json:"userIDs"`
+	// IsPrivate is set if the key contains secret key material.
+	IsPrivate bool `json:"isPrivate"`
+}
+
+// KeyComponentInfo describes the primary key or a subkey of a key.
+// Times are unix timestamps.
+type KeyComponentInfo struct {
+	// Fingerprint is the hex fingerprint of the key.
+	Fingerprint string `json:"fingerprint"`
+	// KeyID is the hex key ID of the key.
+	KeyID string `json:"keyID"`
+	// Version is the version of the key packet, 4 or 6.
+	Version int `json:"version"`
+	// Algorithm is the name of the public key algorithm, e.g. "rsa" or
+	// "ed25519", see the algorithm IDs of RFC 9580, section 9.1.
+	Algorithm string `json:"algorithm"`
+	// AlgorithmID is the OpenPGP ID of the public key algorithm.
+	AlgorithmID int `json:"algorithmID"`
+	// BitLength is the size of the public key in bits, 0 if unknown.
+	BitLength int `json:"bitLength"`
+	// Curve is the elliptic curve of ECC keys, e.g. "Curve25519" or "P256",
+	// empty for other keys.
+	Curve string `json:"curve,omitempty"`
+	// CreationTime is the creation time of the key.
+	CreationTime int64 `json:"creationTime"`
+	// ExpirationTime is the expiration time of the key, 0 if it does not
+	// expire.
+	ExpirationTime int64 `json:"expirationTime"`
+	// CanCertify, CanSign, CanEncryptCommunications, CanEncryptStorage and
+	// CanAuthenticate are the key flags of the self-signature of the key.
+	CanCertify               bool `json:"canCertify"`
+	CanSign                  bool `json:"canSign"`
+	CanEncryptCommunications bool `json:"canEncryptCommunications"`
+	CanEncryptStorage        bool `json:"canEncryptStorage"`
+	CanAuthenticate          bool `json:"canAuthenticate"`
+	// IsRevoked is set if the key has a valid revocation signature.
+	IsRevoked bool `json:"isRevoked"`
+	// IsExpired is set if the key or its self-signature is expired.
+	IsExpired bool `json:"isExpired"`
+}
+
+// GetInfo returns a structured description of the key and its subkeys:
+// algorithms, sizes, creation and expiration times, key flags and
+// fingerprints.
+func (key *Key) GetInfo() *KeyInfo {
+	now := getNow()
+	entity := key.entity
+	selfSignature, primaryIdentity := entity.PrimarySelfSignature()
+
+	info := &KeyInfo{
+		PrimaryKey: newKeyComponentInfo(entity.PrimaryKey, selfSignature, now),
+		IsPrivate:  key.IsPrivate(),
+	}
+	info.PrimaryKey.IsRevoked = entity.Revoked(now)
+
+	var otherUserIDs []string
+	for name := range entity.Identities {
+		if primaryIdentity == nil || name != primaryIdentity.Name {
+			otherUserIDs = append(otherUserIDs, name)
+		}
+	}
+	sort.Strings(otherUserIDs)
+	if primaryIdentity != nil {
+		info.UserIDs = append(info.UserIDs, primaryIdentity.Name)
+	}
+	info.UserIDs = append(info.UserIDs, otherUserIDs...)
+
+	for i := range entity.Subkeys {
+		subkey := &entity.Subkeys[i]
+		subkeyInfo := newKeyComponentInfo(subkey.PublicKey, subkey.Sig, now)
+		subkeyInfo.IsRevoked = subkey.Revoked(now)
+		info.Subkeys = append(info.Subkeys, subkeyInfo)
+	}
+	return info
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func newKeyComponentInfo(publicKey *packet.PublicKey, selfSignature *packet.Signature, now time.Time) *KeyComponentInfo {
+	info := &KeyComponentInfo{
+		Fingerprint:  hex.EncodeToString(publicKey.Fingerprint),
+		KeyID:        keyIDToHex(publicKey.KeyId),
+		Version:      publicKey.Version,
+		Algorithm:    getPublicKeyAlgorithmName(publicKey.PubKeyAlgo),
+		AlgorithmID:  int(publicKey.PubKeyAlgo),
+		CreationTime: publicKey.CreationTime.Unix(),
+	}
+	if bitLength, err := publicKey.BitLength(); err == nil {
+		info.BitLength = int(bitLength)
+	}
+	if curve, err := publicKey.Curve(); err == nil {
+		info.Curve = string(curve)
+	}
+	if selfSignature != nil {
+		info.ExpirationTime = keyExpirationTime(publicKey, selfSignature)
+		info.IsExpired = publicKey.KeyExpired(selfSignature, now) || selfSignature.SigExpired(now)
+		if selfSignature.FlagsValid {
+			info.CanCertify = selfSignature.FlagCertify
+			info.CanSign = selfSignature.FlagSign
+			info.CanEncryptCommunications = selfSignature.FlagEncryptCommunications
+			info.CanEncryptStorage = selfSignature.FlagEncryptStorage
+			info.CanAuthenticate = selfSignature.FlagAuthenticate
+		}
+	}
+	return info
+}
+
+func getPublicKeyAlgorithmName(algorithm packet.PublicKeyAlgorithm) string {
+	switch algorithm {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+		return "rsa"
+	case packet.PubKeyAlgoElGamal:
+		return "elgamal"
+	case packet.PubKeyAlgoDSA:
+		return "dsa"
+	case packet.PubKeyAlgoECDH:
+		return "ecdh"
+	case packet.PubKeyAlgoECDSA:
+		return "ecdsa"
+	case packet.PubKeyAlgoEdDSA:
+		return "eddsa"
+	case packet.PubKeyAlgoX25519:
+		return "x25519"
+	case packet.PubKeyAlgoX448:
+		return "x448"
+	case packet.PubKeyAlgoEd25519:
+		return "ed25519"
+	case packet.PubKeyAlgoEd448:
+		return "ed448"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,42 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyInfo(t *testing.T) {
+	info := keyTestEC.GetInfo()
+	assert.True(t, info.IsPrivate)
+	assert.Exactly(t, []string{keyTestName + " <" + keyTestDomain + ">"}, info.UserIDs)
+
+	primary := info.PrimaryKey
+	assert.Exactly(t, keyTestEC.GetFingerprint(), primary.Fingerprint)
+	assert.Exactly(t, keyTestEC.GetHexKeyID(), primary.KeyID)
+	assert.Exactly(t, 4, primary.Version)
+	assert.Exactly(t, "eddsa", primary.Algorithm)
+	assert.Exactly(t, "Curve25519", primary.Curve)
+	assert.True(t, primary.CanSign)
+	assert.True(t, primary.CanCertify)
+	assert.False(t, primary.CanEncryptStorage)
+	assert.Exactly(t, keyTestEC.entity.PrimaryKey.CreationTime.Unix(), primary.CreationTime)
+	assert.False(t, primary.IsExpired)
+	assert.False(t, primary.IsRevoked)
+
+	assert.Len(t, info.Subkeys, 1)
+	assert.Exactly(t, "ecdh", info.Subkeys[0].Algorithm)
+	assert.True(t, info.Subkeys[0].CanEncryptCommunications)
+	assert.False(t, info.Subkeys[0].CanSign)
+
+	rsaInfo := keyTestRSA.GetInfo()
+	assert.Exactly(t, "rsa", rsaInfo.PrimaryKey.Algorithm)
+	assert.Exactly(t, 1024, rsaInfo.PrimaryKey.BitLength)
+	assert.Empty(t, rsaInfo.PrimaryKey.Curve)
+
+	expiredKey, err := NewKeyFromArmored(readTestFile("key_expiredKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring expired key, got:", err)
+	}
+	assert.True(t, expiredKey.GetInfo().PrimaryKey.IsExpired)
+}
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// AllowInsecureLegacyAlgorithms allows encrypting to the ElGamal keys and
+// signing with the DSA keys of the keyring.
+// By default, these algorithms deprecated by RFC 9580 are only used to
+// decrypt and verify, e.g. archived messages, and encrypting or signing
+// with them returns an error wrapping ErrInsecureLegacyAlgorithm.
+func (keyRing *KeyRing) AllowInsecureLegacyAlgorithms() {
+	keyRing.allowInsecureLegacy = true
+}
+
+// HasInsecureLegacyAlgorithm returns true if the message was decrypted with
+// an ElGamal key, or its verified signature was made with a DSA key.
+func (msg *PlainMessage) HasInsecureLegacyAlgorithm() bool {
+	return msg.insecureLegacyAlgorithm
+}
+
+// HasInsecureLegacyAlgorithm returns true if the message was decrypted with
+// an ElGamal key, or, once VerifySignature succeeded, its signature was made
+// with a DSA key.
+func (msg *PlainMessageReader) HasInsecureLegacyAlgorithm() bool {
+	return hasInsecureLegacyAlgorithm(msg.details)
+}
+
+// HasInsecureLegacyAlgorithm returns true if the signature was made with a
+// DSA key. Such signatures are still verified, e.g. for archived messages,
+// but should be displayed with a warning.
+func (sig *PGPSignature) HasInsecureLegacyAlgorithm() bool {
+	signature, err := parseSignaturePacket(sig)
+	return err == nil && isInsecureLegacyAlgorithm(signature.PubKeyAlgo)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func isInsecureLegacyAlgorithm(algorithm packet.PublicKeyAlgorithm) bool {
+	return algorithm == packet.PubKeyAlgoElGamal || algorithm == packet.PubKeyAlgoDSA
+}
+
+func isDecryptedWithInsecureLegacyAlgorithm(md *openpgp.MessageDetails) bool {
+	return md.DecryptedWith.PublicKey != nil && isInsecureLegacyAlgorithm(md.DecryptedWith.PublicKey.PubKeyAlgo)
+}
+
+// hasInsecureLegacyAlgorithm returns true if the message was decrypted with
+// an ElGamal key, or has a valid signature made with a DSA key.
+func hasInsecureLegacyAlgorithm(md *openpgp.MessageDetails) bool {
+	return isDecryptedWithInsecureLegacyAlgorithm(md) ||
+		(md.Signature != nil && md.SignatureError == nil && isInsecureLegacyAlgorithm(md.Signature.PubKeyAlgo))
+}
+
+// checkInsecureLegacyKey returns an error if publicKey uses a legacy
+// algorithm, and the keyring doesn't allow them.
+func (keyRing *KeyRing) checkInsecureLegacyKey(publicKey *packet.PublicKey) error {
+	if keyRing.allowInsecureLegacy || !isInsecureLegacyAlgorithm(publicKey.PubKeyAlgo) {
+		return nil
+	}
+	return newClassifiedError(
+		ErrInsecureLegacyAlgorithm,
+		"gopenpgp: refusing to use the legacy algorithm of key id "+strconv.FormatUint(publicKey.KeyId, 16),
+		nil,
+	)
+}
+
+// checkInsecureLegacyEncryption checks the encryption keys selected at the
+// given time, see checkInsecureLegacyKey.
+func (keyRing *KeyRing) checkInsecureLegacyEncryption(now time.Time) error {
+	for _, entity := range keyRing.entities {
+		encryptionKey, ok := entity.EncryptionKey(now)
+		if !ok {
+			// go-crypto reports the missing encryption key
+			continue
+		}
+		if err := keyRing.checkInsecureLegacyKey(encryptionKey.PublicKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
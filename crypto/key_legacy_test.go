@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsecureLegacyAlgorithms(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_legacyDSAElGamal", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading legacy key, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	ciphertext, err := NewPGPMessageFromArmored(readTestFile("message_legacyDSAElGamal", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+	decrypted, err := keyRing.Decrypt(ciphertext, keyRing, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting legacy message, got:", err)
+	}
+	assert.Exactly(t, constants.VERIFICATION_VALID, GetVerificationSummary(err))
+	assert.Exactly(t, "legacy archived message\n", decrypted.GetString())
+	assert.True(t, decrypted.HasInsecureLegacyAlgorithm())
+
+	message := NewPlainMessageFromString("hello")
+	_, err = keyRing.Encrypt(message, nil)
+	assert.True(t, errors.Is(err, ErrInsecureLegacyAlgorithm))
+	_, err = keyRingTestPublic.Encrypt(message, keyRing)
+	assert.True(t, errors.Is(err, ErrInsecureLegacyAlgorithm))
+	_, err = keyRing.SignDetached(message)
+	assert.True(t, errors.Is(err, ErrInsecureLegacyAlgorithm))
+	_, err = keyRing.EncryptSessionKey(testSessionKey)
+	assert.True(t, errors.Is(err, ErrInsecureLegacyAlgorithm))
+
+	keyRing.AllowInsecureLegacyAlgorithms()
+	ciphertext, err = keyRing.Encrypt(message, keyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with allowed legacy key, got:", err)
+	}
+	signature, err := keyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing with allowed legacy key, got:", err)
+	}
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.True(t, signature.HasInsecureLegacyAlgorithm())
+
+	decrypted, err = keyRing.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+	assert.True(t, decrypted.HasInsecureLegacyAlgorithm())
+
+	ciphertext, err = keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.False(t, decrypted.HasInsecureLegacyAlgorithm())
+
+	// A signing key is searched past the legacy keys
+	modernKey, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err = NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err := keyRing.AddKey(modernKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	signature, err = keyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	assert.False(t, signature.HasInsecureLegacyAlgorithm())
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+}
@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// Merge returns a copy of the public key updated with the information of
+// other, a version of the same certificate e.g. fetched from a keyserver:
+// the user IDs, subkeys and signatures of both keys are combined, and the
+// most recent self-signatures are kept.
+// Both keys must be public, and their self-signatures are verified when
+// the merged key is parsed.
+func (key *Key) Merge(other *Key) (*Key, error) {
+	if key.IsPrivate() || other.IsPrivate() {
+		return nil, errors.New("gopenpgp: only public keys can be merged")
+	}
+	if !bytes.Equal(key.entity.PrimaryKey.Fingerprint, other.entity.PrimaryKey.Fingerprint) {
+		return nil, errors.New("gopenpgp: cannot merge keys with different primary keys")
+	}
+
+	merged, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	entity, update := merged.entity, other.entity
+
+	entity.Revocations = mergeSignatures(entity.Revocations, update.Revocations)
+	entity.Signatures = mergeSignatures(entity.Signatures, update.Signatures)
+	entity.SelfSignature = newestSignature(entity.SelfSignature, update.SelfSignature)
+
+	for name, updateIdentity := range update.Identities {
+		identity, ok := entity.Identities[name]
+		if !ok {
+			entity.Identities[name] = updateIdentity
+			continue
+		}
+		identity.Signatures = mergeSignatures(identity.Signatures, updateIdentity.Signatures)
+		identity.Revocations = mergeSignatures(identity.Revocations, updateIdentity.Revocations)
+		identity.SelfSignature = newestSignature(identity.SelfSignature, updateIdentity.SelfSignature)
+	}
+
+	// The subkeys are pointers into entity.Subkeys, append new ones afterwards
+	subkeys := subkeysByFingerprint(entity)
+	var newSubkeys []openpgp.Subkey
+	for _, updateSubkey := range update.Subkeys {
+		subkey, ok := subkeys[hex.EncodeToString(updateSubkey.PublicKey.Fingerprint)]
+		if !ok {
+			newSubkeys = append(newSubkeys, updateSubkey)
+			continue
+		}
+		subkey.Revocations = mergeSignatures(subkey.Revocations, updateSubkey.Revocations)
+		subkey.Sig = newestSignature(subkey.Sig, updateSubkey.Sig)
+	}
+	entity.Subkeys = append(entity.Subkeys, newSubkeys...)
+
+	// Parse the merged key again to verify the signatures added by other
+	var serialized bytes.Buffer
+	if err = entity.Serialize(&serialized); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to serialize merged key")
+	}
+	entities, err := openpgp.ReadKeyRing(&serialized)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read merged key")
+	}
+	if len(entities) != 1 {
+		return nil, errors.New("gopenpgp: unable to read merged key")
+	}
+	return &Key{
+		entity:         entities[0],
+		userAttributes: mergeUserAttributes(key.userAttributes, other.userAttributes),
+		attestations:   mergeAttestations(key.attestations, other.attestations),
+	}, nil
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// mergeSignatures returns the signatures of a followed by those of b that
+// are not in a.
+func mergeSignatures(a, b []*packet.Signature) []*packet.Signature {
+	seen := make(map[string]bool, len(a))
+	for _, sig := range a {
+		seen[signatureID(sig)] = true
+	}
+	for _, sig := range b {
+		if id := signatureID(sig); !seen[id] {
+			seen[id] = true
+			a = append(a, sig)
+		}
+	}
+	return a
+}
+
+func newestSignature(a, b *packet.Signature) *packet.Signature {
+	if a == nil || (b != nil && b.CreationTime.After(a.CreationTime)) {
+		return b
+	}
+	return a
+}
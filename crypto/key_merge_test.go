@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyMerge(t *testing.T) {
+	oldKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+
+	updatedKey, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	config := &packet.Config{Time: func() time.Time { return time.Unix(testTime+10, 0) }}
+	if err = updatedKey.entity.RevokeSubkey(&updatedKey.entity.Subkeys[0], packet.KeySuperseded, "", config); err != nil {
+		t.Fatal("Expected no error while revoking subkey, got:", err)
+	}
+	if err = updatedKey.entity.AddEncryptionSubkey(config); err != nil {
+		t.Fatal("Expected no error while adding subkey, got:", err)
+	}
+	newKey, err := updatedKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+
+	merged, err := oldKey.Merge(newKey)
+	if err != nil {
+		t.Fatal("Expected no error while merging keys, got:", err)
+	}
+	assert.Len(t, merged.entity.Subkeys, 2)
+	assert.Len(t, merged.entity.Subkeys[0].Revocations, 1)
+
+	diff, err := merged.Diff(newKey)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.True(t, diff.IsEmpty())
+
+	// Merging is idempotent, and the merged key keeps the old information
+	remerged, err := merged.Merge(oldKey)
+	if err != nil {
+		t.Fatal("Expected no error while merging keys, got:", err)
+	}
+	diff, err = merged.Diff(remerged)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.True(t, diff.IsEmpty())
+
+	_, err = oldKey.Merge(updatedKey)
+	assert.Error(t, err)
+
+	otherKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	_, err = oldKey.Merge(otherKey)
+	assert.Error(t, err)
+}
@@ -0,0 +1,200 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// Bounds of the parameters of the generated RSA keys.
+const (
+	// defaultRSABits and defaultRSAExponent are used for zero parameters.
+	defaultRSABits     = 2048
+	defaultRSAExponent = 65537
+	// minSecureRSABits and minSecureRSAExponent are the smallest parameters
+	// accepted without RSAKeyParameters.AllowInsecure.
+	minSecureRSABits     = 2048
+	minSecureRSAExponent = 65537
+	// minRSABits, maxRSABits and maxRSAExponent are the bounds of the keys
+	// OpenPGP implementations, including go-crypto, can use.
+	minRSABits     = 1024
+	maxRSABits     = 16384
+	maxRSAExponent = 1<<31 - 1
+	// rsaPrimeDistanceMargin makes the primes of a key differ in their
+	// top bits, see FIPS 186-5, appendix A.1.3.
+	rsaPrimeDistanceMargin = 100
+)
+
+// RSAKeyParameters are the parameters of the RSA keys generated by
+// GenerateRSAKey, for users whose compliance requirements set them.
+type RSAKeyParameters struct {
+	// Bits is the size of the modulus, 2048 if zero. Sizes beyond 4096 bits
+	// are accepted, up to 16384 bits.
+	Bits int
+	// PublicExponent is the public exponent, an odd number, 65537 if zero.
+	PublicExponent int
+	// AllowInsecure allows generating keys with a modulus smaller than 2048
+	// bits, down to 1024 bits, or a public exponent smaller than 65537.
+	AllowInsecure bool
+}
+
+// GenerateRSAKey generates a RSA key with the given parameters, or the
+// defaults of GenerateKey if parameters is nil.
+// Parameters producing weak keys are refused, unless AllowInsecure is set.
+// The primes of the keys with a custom public exponent are generated by
+// gopenpgp following FIPS 186-5, appendix A.1.3: they differ in their top
+// 100 bits and the private exponent is larger than 2^(bits/2).
+func GenerateRSAKey(name, email string, parameters *RSAKeyParameters) (*Key, error) {
+	if parameters == nil {
+		parameters = &RSAKeyParameters{}
+	}
+	bits, exponent, err := parameters.get()
+	if err != nil {
+		return nil, err
+	}
+	if exponent == defaultRSAExponent {
+		return generateKey(name, email, &keyGenerationOptions{keyType: "rsa", bits: bits})
+	}
+
+	options := &keyGenerationOptions{keyType: "rsa", bits: bits, rsaExponent: exponent}
+	for i := 0; i < 2; i++ {
+		p, q, err := generateRSAPrimes(rand.Reader, bits, exponent)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in generating RSA primes")
+		}
+		options.rsaPrimes = append(options.rsaPrimes, p.Bytes(), q.Bytes())
+	}
+	return generateKey(name, email, options)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// get returns the modulus size and the public exponent, once validated.
+func (parameters *RSAKeyParameters) get() (bits, exponent int, err error) {
+	bits, exponent = parameters.Bits, parameters.PublicExponent
+	if bits == 0 {
+		bits = defaultRSABits
+	}
+	if exponent == 0 {
+		exponent = defaultRSAExponent
+	}
+
+	if bits < minRSABits || bits > maxRSABits {
+		return 0, 0, errors.New("gopenpgp: RSA key size must be between " +
+			strconv.Itoa(minRSABits) + " and " + strconv.Itoa(maxRSABits) + " bits")
+	}
+	if exponent < 3 || exponent > maxRSAExponent || exponent%2 == 0 {
+		return 0, 0, errors.New("gopenpgp: invalid RSA public exponent " + strconv.Itoa(exponent))
+	}
+	if !parameters.AllowInsecure {
+		if bits < minSecureRSABits {
+			return 0, 0, errors.New("gopenpgp: insecure RSA key size " + strconv.Itoa(bits))
+		}
+		if exponent < minSecureRSAExponent {
+			return 0, 0, errors.New("gopenpgp: insecure RSA public exponent " + strconv.Itoa(exponent))
+		}
+	}
+	return bits, exponent, nil
+}
+
+// generateRSAPrimes generates the two primes of a RSA key with the given
+// modulus size and public exponent, as FIPS 186-5, appendix A.1.3 requires:
+// the primes differ in their top 100 bits and the private exponent is larger
+// than 2^(bits/2). The primes also suit the exponent 65537, which go-crypto
+// uses to build the key before setRSAExponent replaces it.
+func generateRSAPrimes(random io.Reader, bits, exponent int) (p, q *big.Int, err error) {
+	minPrivateExponent := new(big.Int).Lsh(big.NewInt(1), uint(bits/2))
+	for {
+		p, err = rand.Prime(random, bits-bits/2)
+		if err != nil {
+			return nil, nil, err
+		}
+		q, err = rand.Prime(random, bits/2)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		distance := new(big.Int).Sub(p, q)
+		if distance.Abs(distance).BitLen() <= bits/2-rsaPrimeDistanceMargin {
+			continue
+		}
+		if new(big.Int).Mul(p, q).BitLen() != bits {
+			continue
+		}
+		d := rsaPrivateExponent(p, q, exponent)
+		if d == nil || d.Cmp(minPrivateExponent) <= 0 || rsaPrivateExponent(p, q, defaultRSAExponent) == nil {
+			continue
+		}
+		return p, q, nil
+	}
+}
+
+// setRSAExponent replaces the RSA keys of an entity generated by go-crypto,
+// which only uses the exponent 65537, by keys with the same primes and the
+// given exponent, and renews the self-signatures they invalidate.
+func setRSAExponent(entity *openpgp.Entity, exponent int, cfg *packet.Config) error {
+	primary, err := newRSAPrivateKeyWithExponent(entity.PrivateKey, exponent)
+	if err != nil {
+		return err
+	}
+	entity.PrimaryKey, entity.PrivateKey = &primary.PublicKey, primary
+	for _, identity := range entity.Identities {
+		identity.SelfSignature.IssuerKeyId = &primary.KeyId
+		if err = identity.SelfSignature.SignUserId(identity.UserId.Id, &primary.PublicKey, primary, cfg); err != nil {
+			return err
+		}
+	}
+
+	for i := range entity.Subkeys {
+		subkey, err := newRSAPrivateKeyWithExponent(entity.Subkeys[i].PrivateKey, exponent)
+		if err != nil {
+			return err
+		}
+		subkey.IsSubkey = true
+		entity.Subkeys[i].PublicKey, entity.Subkeys[i].PrivateKey = &subkey.PublicKey, subkey
+		entity.Subkeys[i].Sig.IssuerKeyId = &primary.KeyId
+		if err = entity.Subkeys[i].Sig.SignKey(&subkey.PublicKey, primary, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRSAPrivateKeyWithExponent returns a key with the primes and creation
+// time of the given RSA key, and the given public exponent.
+func newRSAPrivateKeyWithExponent(key *packet.PrivateKey, exponent int) (*packet.PrivateKey, error) {
+	rsaKey, ok := key.PrivateKey.(*rsa.PrivateKey)
+	if !ok || len(rsaKey.Primes) != 2 {
+		return nil, errors.New("gopenpgp: unexpected RSA key")
+	}
+	p, q := rsaKey.Primes[0], rsaKey.Primes[1]
+	d := rsaPrivateExponent(p, q, exponent)
+	if d == nil {
+		return nil, errors.New("gopenpgp: invalid RSA public exponent " + strconv.Itoa(exponent))
+	}
+
+	newKey := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(p, q), E: exponent},
+		D:         d,
+		Primes:    []*big.Int{p, q},
+	}
+	newKey.Precompute()
+	if err := newKey.Validate(); err != nil {
+		return nil, err
+	}
+	return packet.NewRSAPrivateKey(key.CreationTime, newKey), nil
+}
+
+// rsaPrivateExponent returns the private exponent matching the public one,
+// or nil if it is not coprime with the totient of the primes.
+func rsaPrivateExponent(p, q *big.Int, exponent int) *big.Int {
+	one := big.NewInt(1)
+	totient := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	return new(big.Int).ModInverse(big.NewInt(int64(exponent)), totient)
+}
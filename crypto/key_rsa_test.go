@@ -0,0 +1,65 @@
+package crypto
+
+import (
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRSAKey(t *testing.T) {
+	for _, parameters := range []*RSAKeyParameters{
+		{Bits: 2048, PublicExponent: 65539},
+		{Bits: 1024, PublicExponent: 3, AllowInsecure: true},
+	} {
+		key, err := GenerateRSAKey(keyTestName, keyTestDomain, parameters)
+		if err != nil {
+			t.Fatal("Expected no error while generating RSA key, got:", err)
+		}
+		publicKey, ok := key.GetEntity().PrimaryKey.PublicKey.(*rsa.PublicKey)
+		assert.True(t, ok)
+		assert.Exactly(t, parameters.PublicExponent, publicKey.E)
+		assert.Exactly(t, parameters.Bits, publicKey.N.BitLen())
+		privateKey, ok := key.GetEntity().PrivateKey.PrivateKey.(*rsa.PrivateKey)
+		assert.True(t, ok)
+		assert.Greater(t, privateKey.D.BitLen(), parameters.Bits/2)
+		assert.Exactly(t, parameters.PublicExponent, key.GetEntity().Subkeys[0].PublicKey.PublicKey.(*rsa.PublicKey).E)
+
+		armored, err := key.Armor()
+		if err != nil {
+			t.Fatal("Expected no error while armoring key, got:", err)
+		}
+		key, err = NewKeyFromArmored(armored)
+		if err != nil {
+			t.Fatal("Expected no error while parsing key, got:", err)
+		}
+		keyRing, err := NewKeyRing(key)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+
+		message := NewPlainMessageFromString("plain text")
+		pgpMessage, err := keyRing.Encrypt(message, keyRing)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		decrypted, err := keyRing.Decrypt(pgpMessage, keyRing, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, message.GetString(), decrypted.GetString())
+	}
+}
+
+func TestGenerateRSAKeyInvalidParameters(t *testing.T) {
+	for _, parameters := range []*RSAKeyParameters{
+		{Bits: 1024},
+		{PublicExponent: 3},
+		{PublicExponent: 65536},
+		{Bits: 512, AllowInsecure: true},
+		{Bits: 32768, AllowInsecure: true},
+	} {
+		_, err := GenerateRSAKey(keyTestName, keyTestDomain, parameters)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,358 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// KeySanitizePolicy sets what SanitizeKey keeps of a key, besides its
+// self-signed components.
+type KeySanitizePolicy struct {
+	// Issuers are the keys whose certifications of the user IDs are kept,
+	// if they are valid. The other third-party signatures are dropped.
+	Issuers *KeyRing
+	// MaxNotationSize is the maximum size in bytes of the notations of a
+	// signature: signatures with larger notations are dropped, e.g. a
+	// self-signature of a user ID, which drops the user ID if it has no other
+	// self-signature. A limit of 0 means no limit.
+	MaxNotationSize int
+	// KeepPhotoIDs keeps the self-signed user attributes, e.g. photo IDs.
+	KeepPhotoIDs bool
+}
+
+// NewKeySanitizePolicy creates a new policy for SanitizeKey, see
+// KeySanitizePolicy.
+func NewKeySanitizePolicy(issuers *KeyRing, maxNotationSize int, keepPhotoIDs bool) *KeySanitizePolicy {
+	return &KeySanitizePolicy{
+		Issuers:         issuers,
+		MaxNotationSize: maxNotationSize,
+		KeepPhotoIDs:    keepPhotoIDs,
+	}
+}
+
+// SanitizeKey sanitizes and canonicalizes an untrusted binary public key,
+// e.g. before storing it in a key directory, and returns the binary
+// sanitized key. It drops:
+// * secret key material, packets that don't belong to a key, and packets
+// that can't be parsed;
+// * duplicated packets: the signatures of duplicated user IDs, user
+// attributes and subkeys are merged;
+// * signatures that are invalid, or don't apply where they are found;
+// * third-party signatures, except valid certifications of user IDs by the
+// policy issuers;
+// * signatures with notations larger than allowed by the policy;
+// * user attributes, e.g. photo IDs, unless allowed by the policy;
+// * user IDs, user attributes and subkeys without a valid self-signature.
+// The packets are then written in a canonical order: the primary key and
+// its signatures, the user IDs sorted by user ID, the user attributes and
+// the subkeys sorted by creation time, each followed by its signatures, the
+// self-signatures first, sorted by creation time.
+// If policy is nil, the strictest policy is used.
+// An error is returned if no usable key is left.
+func SanitizeKey(data []byte, policy *KeySanitizePolicy) ([]byte, error) {
+	if policy == nil {
+		policy = &KeySanitizePolicy{}
+	}
+	key, err := readSanitizedKey(data)
+	if err != nil {
+		return nil, err
+	}
+	key.filter(policy)
+	key.sort()
+
+	var sanitized bytes.Buffer
+	if err := key.serialize(&sanitized); err != nil {
+		return nil, err
+	}
+	if _, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(sanitized.Bytes()))); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: no usable key left after sanitization")
+	}
+	return sanitized.Bytes(), nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// sanitizedKey is a key split into its components, each with the
+// signatures following it.
+type sanitizedKey struct {
+	primaryKey *packet.PublicKey
+	signatures []*packet.Signature
+	components []*sanitizedComponent
+}
+
+// sanitizedComponent is a user ID, user attribute or subkey.
+type sanitizedComponent struct {
+	userID     *packet.UserId
+	attribute  *packet.UserAttribute
+	subkey     *packet.PublicKey
+	signatures []*packet.Signature
+}
+
+// readSanitizedKey splits the packets of a key, dropping the packets that
+// can't be parsed or don't belong to a key, and merging the duplicated
+// components.
+func readSanitizedKey(data []byte) (*sanitizedKey, error) {
+	key := &sanitizedKey{}
+	var current *sanitizedComponent
+	for offset := 0; offset < len(data); {
+		_, next, err := nextPacketOffset(data, offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in reading key")
+		}
+		p, err := packet.Read(bytes.NewReader(data[offset:next]))
+		offset = next
+		if err != nil {
+			continue
+		}
+
+		var publicKey *packet.PublicKey
+		switch p := p.(type) {
+		case *packet.PublicKey:
+			publicKey = p
+		case *packet.PrivateKey:
+			publicKey = &p.PublicKey
+		case *packet.UserId:
+			current = key.addComponent(&sanitizedComponent{userID: p})
+		case *packet.UserAttribute:
+			current = key.addComponent(&sanitizedComponent{attribute: p})
+		case *packet.Signature:
+			if current != nil {
+				current.signatures = append(current.signatures, p)
+			} else if key.primaryKey != nil {
+				key.signatures = append(key.signatures, p)
+			}
+		}
+		switch {
+		case publicKey == nil:
+		case publicKey.IsSubkey && key.primaryKey != nil:
+			current = key.addComponent(&sanitizedComponent{subkey: publicKey})
+		case !publicKey.IsSubkey && key.primaryKey == nil:
+			key.primaryKey = publicKey
+		case !publicKey.IsSubkey:
+			return nil, errors.New("gopenpgp: error in reading key: more than one key found")
+		}
+	}
+	if key.primaryKey == nil {
+		return nil, errors.New("gopenpgp: error in reading key: no primary key found")
+	}
+	return key, nil
+}
+
+// addComponent adds a component to the key, or returns the same component
+// if it is already in the key.
+func (key *sanitizedKey) addComponent(component *sanitizedComponent) *sanitizedComponent {
+	for _, existing := range key.components {
+		if existing.isSame(component) {
+			return existing
+		}
+	}
+	key.components = append(key.components, component)
+	return component
+}
+
+func (component *sanitizedComponent) isSame(other *sanitizedComponent) bool {
+	switch {
+	case component.userID != nil && other.userID != nil:
+		return component.userID.Id == other.userID.Id
+	case component.attribute != nil && other.attribute != nil:
+		return isSameUserAttribute(component.attribute, other.attribute)
+	case component.subkey != nil && other.subkey != nil:
+		return bytes.Equal(component.subkey.Fingerprint, other.subkey.Fingerprint)
+	default:
+		return false
+	}
+}
+
+// filter drops the invalid, duplicated and disallowed signatures, and the
+// components left without self-signature.
+func (key *sanitizedKey) filter(policy *KeySanitizePolicy) {
+	key.signatures = filterSignatures(key.signatures, policy, func(sig *packet.Signature) bool {
+		switch sig.SigType {
+		case packet.SigTypeKeyRevocation:
+			return key.primaryKey.VerifyRevocationSignature(sig) == nil
+		case packet.SigTypeDirectSignature:
+			return key.primaryKey.VerifyDirectKeySignature(sig) == nil
+		default:
+			return false
+		}
+	})
+
+	components := key.components[:0]
+	for _, component := range key.components {
+		if component.attribute != nil && !policy.KeepPhotoIDs {
+			continue
+		}
+		component.signatures = filterSignatures(component.signatures, policy, func(sig *packet.Signature) bool {
+			return key.verifyComponentSignature(component, sig, policy)
+		})
+		if component.isSelfSigned(key.primaryKey) {
+			components = append(components, component)
+		}
+	}
+	key.components = components
+}
+
+// verifyComponentSignature returns whether sig is a valid signature of the
+// component, by the primary key or, for certifications of user IDs, by one
+// of the policy issuers.
+func (key *sanitizedKey) verifyComponentSignature(component *sanitizedComponent, sig *packet.Signature, policy *KeySanitizePolicy) bool {
+	isSelfSignature := sig.CheckKeyIdOrFingerprint(key.primaryKey)
+	switch {
+	case component.subkey != nil && isSelfSignature && sig.SigType == packet.SigTypeSubkeyBinding:
+		return key.primaryKey.VerifyKeySignature(component.subkey, sig) == nil
+	case component.subkey != nil && isSelfSignature && sig.SigType == packet.SigTypeSubkeyRevocation:
+		return key.primaryKey.VerifySubkeyRevocationSignature(sig, component.subkey) == nil
+	case component.subkey != nil || !isUserIDSignature(sig):
+		return false
+	case component.attribute != nil:
+		return isSelfSignature && verifyUserAttributeSignature(key.primaryKey, component.attribute, sig) == nil
+	case isSelfSignature:
+		return key.primaryKey.VerifyUserIdSignature(component.userID.Id, key.primaryKey, sig) == nil
+	case policy.Issuers == nil:
+		return false
+	}
+	for _, issuer := range policy.Issuers.entities {
+		if sig.CheckKeyIdOrFingerprint(issuer.PrimaryKey) &&
+			issuer.PrimaryKey.VerifyUserIdSignature(component.userID.Id, key.primaryKey, sig) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isSelfSigned returns whether the component has a self-signature other than
+// a revocation.
+func (component *sanitizedComponent) isSelfSigned(primaryKey *packet.PublicKey) bool {
+	for _, sig := range component.signatures {
+		if sig.CheckKeyIdOrFingerprint(primaryKey) && !isRevocation(sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// sort sorts the components and their signatures in the canonical order.
+func (key *sanitizedKey) sort() {
+	sortSignatures(key.signatures, key.primaryKey)
+	for _, component := range key.components {
+		sortSignatures(component.signatures, key.primaryKey)
+	}
+	sort.SliceStable(key.components, func(i, j int) bool {
+		a, b := key.components[i], key.components[j]
+		if a.rank() != b.rank() {
+			return a.rank() < b.rank()
+		}
+		switch {
+		case a.userID != nil:
+			return a.userID.Id < b.userID.Id
+		case a.subkey != nil && !a.subkey.CreationTime.Equal(b.subkey.CreationTime):
+			return a.subkey.CreationTime.Before(b.subkey.CreationTime)
+		case a.subkey != nil:
+			return bytes.Compare(a.subkey.Fingerprint, b.subkey.Fingerprint) < 0
+		default:
+			return a.signatures[0].CreationTime.Before(b.signatures[0].CreationTime)
+		}
+	})
+}
+
+// rank orders the kinds of components: user IDs, user attributes, subkeys.
+func (component *sanitizedComponent) rank() int {
+	switch {
+	case component.userID != nil:
+		return 0
+	case component.attribute != nil:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (key *sanitizedKey) serialize(w io.Writer) error {
+	if err := key.primaryKey.Serialize(w); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in serializing key")
+	}
+	if err := serializeSignatures(w, key.signatures); err != nil {
+		return err
+	}
+	for _, component := range key.components {
+		var err error
+		switch {
+		case component.userID != nil:
+			err = component.userID.Serialize(w)
+		case component.attribute != nil:
+			err = component.attribute.Serialize(w)
+		default:
+			err = component.subkey.Serialize(w)
+		}
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: error in serializing key")
+		}
+		if err := serializeSignatures(w, component.signatures); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterSignatures returns the valid signatures without duplicates and
+// without notations larger than allowed by the policy.
+func filterSignatures(signatures []*packet.Signature, policy *KeySanitizePolicy, isValid func(*packet.Signature) bool) []*packet.Signature {
+	var filtered []*packet.Signature
+	seen := make(map[string]bool)
+	for _, sig := range signatures {
+		id := signatureID(sig)
+		if seen[id] || !isValid(sig) || (policy.MaxNotationSize > 0 && getNotationSize(sig) > policy.MaxNotationSize) {
+			continue
+		}
+		seen[id] = true
+		filtered = append(filtered, sig)
+	}
+	return filtered
+}
+
+// sortSignatures sorts the self-signatures first, then by creation time.
+func sortSignatures(signatures []*packet.Signature, primaryKey *packet.PublicKey) {
+	sort.SliceStable(signatures, func(i, j int) bool {
+		a, b := signatures[i], signatures[j]
+		aSelf, bSelf := a.CheckKeyIdOrFingerprint(primaryKey), b.CheckKeyIdOrFingerprint(primaryKey)
+		if aSelf != bSelf {
+			return aSelf
+		}
+		if !a.CreationTime.Equal(b.CreationTime) {
+			return a.CreationTime.Before(b.CreationTime)
+		}
+		return signatureID(a) < signatureID(b)
+	})
+}
+
+func serializeSignatures(w io.Writer, signatures []*packet.Signature) error {
+	for _, sig := range signatures {
+		if err := sig.Serialize(w); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in serializing key")
+		}
+	}
+	return nil
+}
+
+func isUserIDSignature(sig *packet.Signature) bool {
+	switch sig.SigType {
+	case packet.SigTypeGenericCert, packet.SigTypePersonaCert, packet.SigTypeCasualCert,
+		packet.SigTypePositiveCert, packet.SigTypeCertificationRevocation:
+		return true
+	default:
+		return false
+	}
+}
+
+func getNotationSize(sig *packet.Signature) int {
+	size := 0
+	for _, notation := range sig.Notations {
+		size += len(notation.Name) + len(notation.Value)
+	}
+	return size
+}
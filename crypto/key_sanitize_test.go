@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeKey(t *testing.T) {
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, image.NewGray(image.Rect(0, 0, 1, 1)), nil); err != nil {
+		t.Fatal("Expected no error while encoding photo, got:", err)
+	}
+	key, err = key.AddPhotoID(photo.Bytes())
+	if err != nil {
+		t.Fatal("Expected no error while adding photo ID, got:", err)
+	}
+	issuer := keyRingTestPrivate.entities[0]
+	for name := range key.entity.Identities {
+		if err := key.entity.SignIdentity(name, issuer, &packet.Config{Time: getTimeGenerator()}); err != nil {
+			t.Fatal("Expected no error while certifying user ID, got:", err)
+		}
+	}
+	privateKey, err := key.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	publicKey, err := key.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	_, primaryKeyEnd, err := nextPacketOffset(publicKey, 0)
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	// A trust packet, and the rest of the key again
+	altered := append(append([]byte{}, publicKey[:primaryKeyEnd]...), 0xcc, 0x02, 0x00, 0x00)
+	altered = append(append(altered, publicKey[primaryKeyEnd:]...), publicKey[primaryKeyEnd:]...)
+
+	sanitized, err := SanitizeKey(publicKey, nil)
+	if err != nil {
+		t.Fatal("Expected no error while sanitizing key, got:", err)
+	}
+	sanitizedKey, err := NewKey(sanitized)
+	if err != nil {
+		t.Fatal("Expected no error while reading sanitized key, got:", err)
+	}
+	assert.Exactly(t, key.GetFingerprint(), sanitizedKey.GetFingerprint())
+	assert.False(t, sanitizedKey.IsPrivate())
+	assert.Empty(t, sanitizedKey.GetUserAttributes())
+	for _, identity := range sanitizedKey.entity.Identities {
+		assert.Len(t, identity.Signatures, 1)
+	}
+	assert.Len(t, sanitizedKey.entity.Subkeys, 1)
+
+	for _, input := range [][]byte{privateKey, altered, sanitized} {
+		resanitized, err := SanitizeKey(input, nil)
+		if err != nil {
+			t.Fatal("Expected no error while sanitizing key, got:", err)
+		}
+		assert.Exactly(t, sanitized, resanitized)
+	}
+
+	issuers, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	sanitized, err = SanitizeKey(altered, NewKeySanitizePolicy(issuers, 0, true))
+	if err != nil {
+		t.Fatal("Expected no error while sanitizing key, got:", err)
+	}
+	sanitizedKey, err = NewKey(sanitized)
+	if err != nil {
+		t.Fatal("Expected no error while reading sanitized key, got:", err)
+	}
+	assert.Len(t, sanitizedKey.GetUserAttributes(), 1)
+	for _, identity := range sanitizedKey.entity.Identities {
+		assert.Len(t, identity.Signatures, 2)
+	}
+
+	_, err = SanitizeKey([]byte{0xcc, 0x02, 0x00, 0x00}, nil)
+	assert.Error(t, err)
+	_, err = SanitizeKey(append(append([]byte{}, publicKey...), publicKey...), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"encoding/hex"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// SelectedKey is the primary key or subkey of a key used by an operation,
+// e.g. to log which subkey of a recipient a message is encrypted to.
+type SelectedKey struct {
+	// Fingerprint is the hex fingerprint of the primary key.
+	Fingerprint string
+	// SubkeyFingerprint is the hex fingerprint of the key used, either the
+	// primary key or a subkey.
+	SubkeyFingerprint string
+}
+
+// GetEncryptionSubkeys returns the keys that encryption to the keyring uses,
+// one per key of the keyring, in order: the newest valid encryption subkey,
+// or the key set with SetEncryptionSubkey.
+// Returns a KeyCapabilityError if a key has no valid encryption key, like
+// encryption.
+func (keyRing *KeyRing) GetEncryptionSubkeys() ([]*SelectedKey, error) {
+	recipients, err := keyRing.withEncryptionSubkey()
+	if err != nil {
+		return nil, err
+	}
+	now := getEncryptionTimeGenerator(recipients.entities)()
+	selected := make([]*SelectedKey, 0, len(recipients.entities))
+	for _, entity := range recipients.entities {
+		encryptionKey, ok := entity.EncryptionKey(now)
+		if !ok {
+			return nil, newKeyCapabilityError(entity, constants.KeyCapabilityEncrypt)
+		}
+		selected = append(selected, newSelectedKey(encryptionKey))
+	}
+	return selected, nil
+}
+
+// GetSigningSubkey returns the key that signing with the keyring uses: the
+// newest valid signing subkey of the first key with an unlocked signing key,
+// or the key set with SetSigningSubkey.
+func (keyRing *KeyRing) GetSigningSubkey() (*SelectedKey, error) {
+	entity, err := keyRing.getSigningEntity()
+	if err != nil {
+		return nil, err
+	}
+	signingKey, ok := entity.SigningKey(getNow())
+	if !ok {
+		return nil, errors.Wrap(newKeyCapabilityError(entity, constants.KeyCapabilitySign), "gopenpgp: error in signing")
+	}
+	return newSelectedKey(signingKey), nil
+}
+
+// GetRecipientSubkeys returns the keys of keyRing the message is encrypted
+// to, as found from the key IDs of its key packets, e.g. to check which key
+// of a recipient who can't decrypt the message was used. The key packets to
+// other keys, or without key ID, are ignored.
+func (msg *PGPMessage) GetRecipientSubkeys(keyRing *KeyRing) []*SelectedKey {
+	keyIDs, _ := msg.GetEncryptionKeyIDs()
+	return keyRing.getKeysByID(keyIDs)
+}
+
+// GetSignerSubkeys returns the keys of keyRing which made the signature, as
+// found from the issuer key IDs of its signature packets.
+func (sig *PGPSignature) GetSignerSubkeys(keyRing *KeyRing) []*SelectedKey {
+	keyIDs, _ := sig.GetSignatureKeyIDs()
+	return keyRing.getKeysByID(keyIDs)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func (keyRing *KeyRing) getKeysByID(keyIDs []uint64) []*SelectedKey {
+	var selected []*SelectedKey
+	for _, keyID := range keyIDs {
+		if keyID == 0 {
+			continue
+		}
+		for _, key := range keyRing.entities.KeysById(keyID) {
+			selected = append(selected, newSelectedKey(key))
+		}
+	}
+	return selected
+}
+
+func newSelectedKey(key openpgp.Key) *SelectedKey {
+	return &SelectedKey{
+		Fingerprint:       hex.EncodeToString(key.Entity.PrimaryKey.Fingerprint),
+		SubkeyFingerprint: hex.EncodeToString(key.PublicKey.Fingerprint),
+	}
+}
@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySelection(t *testing.T) {
+	entity := keyRingTestPrivate.entities[0]
+	fingerprint := hex.EncodeToString(entity.PrimaryKey.Fingerprint)
+
+	encryptionSubkeys, err := keyRingTestPublic.GetEncryptionSubkeys()
+	if err != nil {
+		t.Fatal("Expected no error while getting encryption subkeys, got:", err)
+	}
+	assert.Exactly(t, []*SelectedKey{{
+		Fingerprint:       fingerprint,
+		SubkeyFingerprint: hex.EncodeToString(entity.Subkeys[0].PublicKey.Fingerprint),
+	}}, encryptionSubkeys)
+
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Exactly(t, encryptionSubkeys, ciphertext.GetRecipientSubkeys(keyRingTestPublic))
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Empty(t, ciphertext.GetRecipientSubkeys(otherKeyRing))
+
+	signingSubkey, err := keyRingTestPrivate.GetSigningSubkey()
+	if err != nil {
+		t.Fatal("Expected no error while getting signing subkey, got:", err)
+	}
+	assert.Exactly(t, fingerprint, signingSubkey.Fingerprint)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	assert.Exactly(t, []*SelectedKey{signingSubkey}, signature.GetSignerSubkeys(keyRingTestPublic))
+
+	_, err = keyRingTestPublic.GetSigningSubkey()
+	assert.Error(t, err)
+}
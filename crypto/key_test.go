@@ -3,6 +3,8 @@ package crypto
 import (
 	"crypto/rsa"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"io/ioutil"
 	"regexp"
 	"strings"
@@ -402,6 +404,49 @@ func TestToPublic(t *testing.T) {
 	assert.True(t, privateKey.IsPrivate())
 }
 
+func TestGetEncryptionCertificate(t *testing.T) {
+	privateKey, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 256)
+	if err != nil {
+		t.Fatal("Cannot generate key:", err)
+	}
+	if err = privateKey.entity.AddUserId("other", "", "other@example.com", nil); err != nil {
+		t.Fatal("Cannot add user ID:", err)
+	}
+	if err = privateKey.entity.AddSigningSubkey(nil); err != nil {
+		t.Fatal("Cannot add subkey:", err)
+	}
+
+	certificate, err := privateKey.GetEncryptionCertificate()
+	if err != nil {
+		t.Fatal("Cannot get encryption certificate:", err)
+	}
+
+	assert.False(t, certificate.IsPrivate())
+	assert.True(t, certificate.CanEncrypt())
+	assert.Exactly(t, privateKey.GetFingerprint(), certificate.GetFingerprint())
+	assert.Len(t, certificate.entity.Identities, 1)
+	assert.Len(t, certificate.entity.Subkeys, 1)
+	assert.Exactly(t, privateKey.entity.Subkeys[0].PublicKey.Fingerprint, certificate.entity.Subkeys[0].PublicKey.Fingerprint)
+
+	publicKeyRing, err := NewKeyRing(certificate)
+	if err != nil {
+		t.Fatal("Cannot create key ring:", err)
+	}
+	privateKeyRing, err := NewKeyRing(privateKey)
+	if err != nil {
+		t.Fatal("Cannot create key ring:", err)
+	}
+	ciphertext, err := publicKeyRing.Encrypt(NewPlainMessageFromString("hello"), nil)
+	if err != nil {
+		t.Fatal("Cannot encrypt message:", err)
+	}
+	decrypted, err := privateKeyRing.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Cannot decrypt message:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+}
+
 func TestKeyCapabilities(t *testing.T) {
 	assert.True(t, keyTestEC.CanVerify())
 	assert.True(t, keyTestEC.CanEncrypt())
@@ -445,6 +490,129 @@ func TestUnlockMismatchingKey(t *testing.T) {
 	}
 }
 
+func TestLockWithAEAD(t *testing.T) {
+	lockedKey, err := keyTestEC.LockWithAEAD(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Cannot lock key:", err)
+	}
+
+	locked, err := lockedKey.IsLocked()
+	if err != nil {
+		t.Fatal("Cannot check if key is locked:", err)
+	}
+	assert.True(t, locked)
+
+	armored, err := lockedKey.Armor()
+	if err != nil {
+		t.Fatal("Cannot armor key:", err)
+	}
+	parsedKey, err := NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+
+	_, err = parsedKey.Unlock([]byte("wrong passphrase"))
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+
+	relockedKey, err := parsedKey.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Cannot unlock key:", err)
+	}
+	unlocked, err := relockedKey.IsUnlocked()
+	if err != nil {
+		t.Fatal("Cannot check if key is unlocked:", err)
+	}
+	assert.True(t, unlocked)
+}
+
+func TestUnlockWrongPassphrase(t *testing.T) {
+	for _, armored := range []string{keyTestArmoredEC, keyTestArmoredRSA} {
+		lockedKey, err := NewKeyFromArmored(armored)
+		if err != nil {
+			t.Fatal("Cannot unarmor key:", err)
+		}
+		_, err = lockedKey.Unlock([]byte("wrong passphrase"))
+		assert.True(t, errors.Is(err, ErrWrongPassphrase))
+		assert.False(t, errors.Is(err, ErrKeyCorrupt))
+	}
+}
+
+func TestUnlockSubkey(t *testing.T) {
+	primaryPassphrase, subkeyPassphrase := []byte("primary"), []byte("subkey")
+	lockedKey, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy key:", err)
+	}
+	if err = lockedKey.entity.PrivateKey.Encrypt(primaryPassphrase); err != nil {
+		t.Fatal("Cannot lock primary key:", err)
+	}
+	if err = lockedKey.entity.Subkeys[0].PrivateKey.Encrypt(subkeyPassphrase); err != nil {
+		t.Fatal("Cannot lock subkey:", err)
+	}
+	primaryFingerprint := lockedKey.GetFingerprint()
+	subkeyFingerprint := hex.EncodeToString(lockedKey.entity.Subkeys[0].PublicKey.Fingerprint)
+
+	_, err = lockedKey.Unlock(primaryPassphrase)
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+
+	_, err = NewKeyRing(lockedKey)
+	assert.Error(t, err)
+
+	_, err = lockedKey.UnlockSubkey(subkeyFingerprint, primaryPassphrase)
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+
+	_, err = lockedKey.UnlockSubkey("0123456789abcdef", subkeyPassphrase)
+	assert.Error(t, err)
+
+	// Only the encryption subkey is unlocked: decryption works, signing doesn't
+	partialKey, err := lockedKey.UnlockSubkey(subkeyFingerprint, subkeyPassphrase)
+	if err != nil {
+		t.Fatal("Cannot unlock subkey:", err)
+	}
+	partialKeyRing, err := NewKeyRing(partialKey)
+	if err != nil {
+		t.Fatal("Cannot create keyring with partially unlocked key:", err)
+	}
+
+	message := NewPlainMessageFromString("partially unlocked")
+	ciphertext, err := partialKeyRing.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Cannot encrypt message:", err)
+	}
+	decrypted, err := partialKeyRing.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Cannot decrypt message:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	_, err = partialKeyRing.SignDetached(message)
+	assert.Error(t, err)
+
+	// Unlocking the primary key as well makes the key fully unlocked
+	unlockedKey, err := partialKey.UnlockSubkey(strings.ToUpper(primaryFingerprint), primaryPassphrase)
+	if err != nil {
+		t.Fatal("Cannot unlock primary key:", err)
+	}
+	unlocked, err := unlockedKey.IsUnlocked()
+	if err != nil {
+		t.Fatal("Cannot check if key is unlocked:", err)
+	}
+	assert.True(t, unlocked)
+
+	unlockedKeyRing, err := NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Cannot create keyring:", err)
+	}
+	signature, err := unlockedKeyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Cannot sign message:", err)
+	}
+	assert.NoError(t, unlockedKeyRing.VerifyDetached(message, signature, 0))
+
+	_, err = unlockedKey.UnlockSubkey(subkeyFingerprint, subkeyPassphrase)
+	assert.Error(t, err)
+}
+
 func TestKeyCompression(t *testing.T) {
 	assert.Equal(
 		t,
@@ -0,0 +1,285 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// Packet tags of the key packets following the user attributes.
+const (
+	packetTagPrivateSubkey = 7
+	packetTagPublicSubkey  = 14
+)
+
+// jpegImageHeader is the header of JPEG image subpackets of user attributes,
+// see RFC 4880, section 5.12.1.
+var jpegImageHeader = []byte{
+	0x10, 0x00, // Little-endian header length
+	0x01, // Header version
+	0x01, // JPEG
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+}
+
+// UserAttribute is a user attribute of a key, e.g. a photo ID, with its
+// self-certification.
+// go-crypto ignores user attributes, so they are only kept by Key: they are
+// not used for any operation, and are lost in KeyRing.
+type UserAttribute struct {
+	attribute   *packet.UserAttribute
+	signature   *packet.Signature
+	revocations []*packet.Signature
+}
+
+// GetImages returns the JPEG images of the user attribute.
+func (attribute *UserAttribute) GetImages() [][]byte {
+	return attribute.attribute.ImageData()
+}
+
+// GetCreationTime returns the creation time of the self-certification of the
+// user attribute.
+func (attribute *UserAttribute) GetCreationTime() int64 {
+	return attribute.signature.CreationTime.Unix()
+}
+
+// IsRevoked returns true if the user attribute is revoked.
+func (attribute *UserAttribute) IsRevoked() bool {
+	return len(attribute.revocations) > 0
+}
+
+// GetUserAttributes returns the self-certified user attributes of the key,
+// e.g. photo IDs.
+func (key *Key) GetUserAttributes() []*UserAttribute {
+	return key.userAttributes
+}
+
+// AddPhotoID returns a copy of the key with a new photo ID, a user attribute
+// with a JPEG image, e.g. to be shown as the avatar of the key holder.
+// The primary key must be unlocked, to certify the photo ID.
+func (key *Key) AddPhotoID(jpeg []byte) (*Key, error) {
+	if len(jpeg) < 3 || !bytes.Equal(jpeg[:3], []byte{0xff, 0xd8, 0xff}) {
+		return nil, errors.New("gopenpgp: the photo ID is not a JPEG image")
+	}
+	privateKey := key.entity.PrivateKey
+	if privateKey == nil {
+		return nil, errors.New("gopenpgp: photo IDs can only be added to a private key")
+	}
+	if privateKey.Dummy() {
+		return nil, StubKeyError{Fingerprint: key.GetFingerprint()}
+	}
+	if privateKey.Encrypted {
+		return nil, newClassifiedError(ErrKeyLocked, "gopenpgp: photo IDs can only be added to an unlocked key", nil)
+	}
+
+	image := append(append([]byte{}, jpegImageHeader...), jpeg...)
+	attribute := packet.NewUserAttribute(&packet.OpaqueSubpacket{
+		SubType:       packet.UserAttrImageSubpacket,
+		EncodedLength: encodeSubpacketLength(len(image) + 1),
+		Contents:      image,
+	})
+
+	config := &packet.Config{Time: getTimeGenerator()}
+	applyConfigModifier(config)
+	publicKey := key.entity.PrimaryKey
+	signature := &packet.Signature{
+		Version:           publicKey.Version,
+		SigType:           packet.SigTypePositiveCert,
+		PubKeyAlgo:        publicKey.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      config.Now(),
+		IssuerKeyId:       &publicKey.KeyId,
+		IssuerFingerprint: publicKey.Fingerprint,
+	}
+	h, err := signature.PrepareSign(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in certifying photo ID")
+	}
+	if err := writeUserAttributeHash(h, publicKey, attribute); err != nil {
+		return nil, err
+	}
+	if err := signature.Sign(h, privateKey, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in certifying photo ID")
+	}
+	auditKeyUsage(constants.KeyUsageCertify, "certify photo ID", publicKey, publicKey)
+
+	newKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	newKey.userAttributes = append(newKey.userAttributes, &UserAttribute{
+		attribute: attribute,
+		signature: signature,
+	})
+	return newKey, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// readUserAttributes returns the user attributes of the serialized key, with
+// a valid self-certification.
+func readUserAttributes(primaryKey *packet.PublicKey, data []byte) []*UserAttribute {
+	var attributes []*UserAttribute
+	var current *UserAttribute
+	packets := packet.NewReader(bytes.NewReader(data))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			break
+		}
+		switch p := p.(type) {
+		case *packet.UserAttribute:
+			current = &UserAttribute{attribute: p}
+			attributes = append(attributes, current)
+		case *packet.Signature:
+			if current == nil || verifyUserAttributeSignature(primaryKey, current.attribute, p) != nil {
+				continue
+			}
+			switch p.SigType {
+			case packet.SigTypeGenericCert, packet.SigTypePersonaCert, packet.SigTypeCasualCert, packet.SigTypePositiveCert:
+				if current.signature == nil || p.CreationTime.After(current.signature.CreationTime) {
+					current.signature = p
+				}
+			case packet.SigTypeCertificationRevocation:
+				current.revocations = append(current.revocations, p)
+			}
+		default:
+			current = nil
+		}
+	}
+
+	var certified []*UserAttribute
+	for _, attribute := range attributes {
+		if attribute.signature != nil {
+			certified = append(certified, attribute)
+		}
+	}
+	return certified
+}
+
+// verifyUserAttributeSignature verifies a self-signature of a user attribute.
+func verifyUserAttributeSignature(primaryKey *packet.PublicKey, attribute *packet.UserAttribute, signature *packet.Signature) error {
+	if !signature.CheckKeyIdOrFingerprint(primaryKey) {
+		return errors.New("gopenpgp: the user attribute signature is not a self-signature")
+	}
+	h, err := signature.PrepareVerify()
+	if err != nil {
+		return err
+	}
+	if err := writeUserAttributeHash(h, primaryKey, attribute); err != nil {
+		return err
+	}
+	return primaryKey.VerifySignature(h, signature)
+}
+
+// writeUserAttributeHash writes the data certified by user attribute
+// signatures, see RFC 4880, section 5.2.4.
+func writeUserAttributeHash(h hash.Hash, primaryKey *packet.PublicKey, attribute *packet.UserAttribute) error {
+	var body bytes.Buffer
+	for _, subpacket := range attribute.Contents {
+		if err := subpacket.Serialize(&body); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in serializing user attribute")
+		}
+	}
+	if err := primaryKey.SerializeForHash(h); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in hashing user attribute")
+	}
+	var header [5]byte
+	header[0] = 0xd1
+	binary.BigEndian.PutUint32(header[1:], uint32(body.Len()))
+	_, _ = h.Write(header[:])
+	_, _ = h.Write(body.Bytes())
+	return nil
+}
+
+// encodeSubpacketLength encodes the length of a subpacket, see RFC 4880,
+// section 5.2.3.1.
+func encodeSubpacketLength(length int) []byte {
+	switch {
+	case length < 192:
+		return []byte{byte(length)}
+	case length < 8384:
+		length -= 192
+		return []byte{byte(length>>8) + 192, byte(length)}
+	default:
+		encoded := []byte{0xff, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(encoded[1:], uint32(length))
+		return encoded
+	}
+}
+
+// insertUserAttributes inserts the packets of the user attributes in the
+// serialized key, after the user IDs and before the subkeys.
+func (key *Key) insertUserAttributes(serialized []byte) ([]byte, error) {
+	if len(key.userAttributes) == 0 {
+		return serialized, nil
+	}
+
+	var attributes bytes.Buffer
+	for _, attribute := range key.userAttributes {
+		if err := attribute.attribute.Serialize(&attributes); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in serializing user attribute")
+		}
+		for _, signature := range append([]*packet.Signature{attribute.signature}, attribute.revocations...) {
+			if err := signature.Serialize(&attributes); err != nil {
+				return nil, errors.Wrap(err, "gopenpgp: error in serializing user attribute")
+			}
+		}
+	}
+
+	offset := 0
+	for offset < len(serialized) {
+		tag, next, err := nextPacketOffset(serialized, offset)
+		if err != nil {
+			return nil, err
+		}
+		if tag == packetTagPrivateSubkey || tag == packetTagPublicSubkey {
+			break
+		}
+		offset = next
+	}
+	inserted := make([]byte, 0, len(serialized)+attributes.Len())
+	inserted = append(inserted, serialized[:offset]...)
+	inserted = append(inserted, attributes.Bytes()...)
+	return append(inserted, serialized[offset:]...), nil
+}
+
+// mergeUserAttributes returns the user attributes of a followed by those of
+// b that are not in a, with the newest self-certification and all
+// revocations of both.
+func mergeUserAttributes(a, b []*UserAttribute) []*UserAttribute {
+	merged := make([]*UserAttribute, 0, len(a)+len(b))
+	for _, attribute := range a {
+		merged = append(merged, &UserAttribute{
+			attribute:   attribute.attribute,
+			signature:   attribute.signature,
+			revocations: attribute.revocations,
+		})
+	}
+	for _, update := range b {
+		found := false
+		for _, attribute := range merged {
+			if isSameUserAttribute(attribute.attribute, update.attribute) {
+				attribute.signature = newestSignature(attribute.signature, update.signature)
+				attribute.revocations = mergeSignatures(attribute.revocations, update.revocations)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, update)
+		}
+	}
+	return merged
+}
+
+func isSameUserAttribute(a, b *packet.UserAttribute) bool {
+	var aData, bData bytes.Buffer
+	if a.Serialize(&aData) != nil || b.Serialize(&bData) != nil {
+		return false
+	}
+	return bytes.Equal(aData.Bytes(), bData.Bytes())
+}
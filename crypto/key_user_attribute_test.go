@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAttributesFromGnuPG(t *testing.T) {
+	key, err := NewKeyFromArmored(readTestFile("key_photoID", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	attributes := key.GetUserAttributes()
+	if len(attributes) != 1 {
+		t.Fatal("Expected one user attribute, got:", len(attributes))
+	}
+	images := attributes[0].GetImages()
+	assert.Len(t, images, 1)
+	_, err = jpeg.Decode(bytes.NewReader(images[0]))
+	if err != nil {
+		t.Fatal("Expected no error while decoding photo, got:", err)
+	}
+	assert.False(t, attributes[0].IsRevoked())
+
+	// The user attribute is kept when the key is serialized
+	copied, err := key.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	assert.Len(t, copied.GetUserAttributes(), 1)
+	serialized, err := key.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	original, err := NewKeyFromArmored(readTestFile("key_photoID", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	originalSerialized, err := original.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	assert.Exactly(t, originalSerialized, serialized)
+}
+
+func TestAddPhotoID(t *testing.T) {
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal("Expected no error while encoding photo, got:", err)
+	}
+
+	key, err := keyTestEC.AddPhotoID(photo.Bytes())
+	if err != nil {
+		t.Fatal("Expected no error while adding photo ID, got:", err)
+	}
+	assert.Empty(t, keyTestEC.GetUserAttributes())
+
+	// The photo ID is verified when the key is parsed again
+	publicKey, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	parsedKey, err := NewKeyFromArmored(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	attributes := parsedKey.GetUserAttributes()
+	if len(attributes) != 1 {
+		t.Fatal("Expected one user attribute, got:", len(attributes))
+	}
+	assert.Exactly(t, [][]byte{photo.Bytes()}, attributes[0].GetImages())
+	assert.Len(t, parsedKey.entity.Subkeys, len(keyTestEC.entity.Subkeys))
+
+	lockedKey, err := key.Lock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	assert.Len(t, lockedKey.GetUserAttributes(), 1)
+	_, err = lockedKey.AddPhotoID(photo.Bytes())
+	assert.Error(t, err)
+
+	_, err = keyTestEC.AddPhotoID([]byte("not a JPEG image"))
+	assert.Error(t, err)
+}
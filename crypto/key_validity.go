@@ -0,0 +1,206 @@
+package crypto
+
+import (
+	"crypto"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+// KeyValidity is the validity of a key at a given time, returned by
+// Key.GetValidityAt.
+type KeyValidity struct {
+	// Validity is constants.KeyValidityValid, KeyValidityRestricted or
+	// KeyValidityInvalid.
+	Validity string
+	// Reasons are the problems found, the primary key first, then the user
+	// IDs and the subkeys.
+	Reasons []*KeyValidityReason
+}
+
+// KeyValidityReason is a problem of a component of a key.
+type KeyValidityReason struct {
+	// Reason is one of the constants.KeyValidityReason* values.
+	Reason string
+	// Type is the component, one of the constants.ExpiringComponent*
+	// values.
+	Type string
+	// Target is the hex fingerprint of the primary key or subkey, or the
+	// user ID.
+	Target string
+	// Time is the creation, expiration or revocation time of the
+	// component, as a unix timestamp, for the reasons related to time.
+	Time int64
+}
+
+// GetValidityAt evaluates the validity of the key at the given unix time,
+// without any network access, and returns the reasons why it is not fully
+// valid: creation, expiration and revocation of the primary key, user IDs
+// and subkeys, correctness of their binding signatures, and the algorithm
+// policy of the library.
+// The key is invalid if its primary key, or its primary user ID, has a
+// problem other than a weak algorithm; it is restricted if only other user
+// IDs or subkeys have problems, or if it uses weak algorithms.
+func (key *Key) GetValidityAt(unixTime int64) *KeyValidity {
+	now := time.Unix(unixTime, 0)
+	entity := key.entity
+	primaryKey := entity.PrimaryKey
+	fingerprint := hex.EncodeToString(primaryKey.Fingerprint)
+	validity := &KeyValidity{Validity: constants.KeyValidityValid}
+	add := func(invalid bool, reason, componentType, target string, reasonTime int64) {
+		validity.Reasons = append(validity.Reasons, &KeyValidityReason{
+			Reason: reason,
+			Type:   componentType,
+			Target: target,
+			Time:   reasonTime,
+		})
+		if invalid {
+			validity.Validity = constants.KeyValidityInvalid
+		} else if validity.Validity == constants.KeyValidityValid {
+			validity.Validity = constants.KeyValidityRestricted
+		}
+	}
+
+	// Primary key
+	selfSignature, primaryIdentity := entity.PrimarySelfSignature()
+	if primaryKey.CreationTime.After(now) {
+		add(true, constants.KeyValidityReasonNotYetValid, constants.ExpiringComponentKey, fingerprint, primaryKey.CreationTime.Unix())
+	}
+	if revocationTime, ok := getRevocationTime(entity.Revocations, now); ok {
+		add(true, constants.KeyValidityReasonRevoked, constants.ExpiringComponentKey, fingerprint, revocationTime)
+	}
+	if primaryKey.Version == 6 {
+		if selfSignature == nil || primaryKey.VerifyDirectKeySignature(selfSignature) != nil {
+			add(true, constants.KeyValidityReasonInvalidBinding, constants.ExpiringComponentKey, fingerprint, 0)
+		}
+	} else if primaryIdentity == nil {
+		add(true, constants.KeyValidityReasonInvalidBinding, constants.ExpiringComponentKey, fingerprint, 0)
+	}
+	if selfSignature != nil {
+		if expiration := keyExpirationTime(primaryKey, selfSignature); isExpiredAt(expiration, unixTime) {
+			add(true, constants.KeyValidityReasonExpired, constants.ExpiringComponentKey, fingerprint, expiration)
+		}
+		if isWeakHash(selfSignature.Hash) {
+			add(false, constants.KeyValidityReasonWeakHash, constants.ExpiringComponentKey, fingerprint, 0)
+		}
+	}
+	addAlgorithmReasons(add, primaryKey, constants.ExpiringComponentKey)
+
+	// User IDs, the primary one invalidates the key like in IsRevoked and
+	// IsExpired
+	names := make([]string, 0, len(entity.Identities))
+	for name := range entity.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		identity := entity.Identities[name]
+		isPrimary := identity == primaryIdentity
+		signature := identity.SelfSignature
+		if signature == nil || primaryKey.VerifyUserIdSignature(name, primaryKey, signature) != nil {
+			add(isPrimary, constants.KeyValidityReasonInvalidBinding, constants.ExpiringComponentUserID, name, 0)
+			continue
+		}
+		if signature.CreationTime.After(now) {
+			add(isPrimary, constants.KeyValidityReasonNotYetValid, constants.ExpiringComponentUserID, name, signature.CreationTime.Unix())
+		}
+		if revocationTime, ok := getRevocationTime(identity.Revocations, now); ok {
+			add(isPrimary, constants.KeyValidityReasonRevoked, constants.ExpiringComponentUserID, name, revocationTime)
+		}
+		if expiration := signatureExpirationTime(signature); isExpiredAt(expiration, unixTime) {
+			add(isPrimary, constants.KeyValidityReasonExpired, constants.ExpiringComponentUserID, name, expiration)
+		}
+		if isWeakHash(signature.Hash) && (!isPrimary || primaryKey.Version == 6) {
+			add(false, constants.KeyValidityReasonWeakHash, constants.ExpiringComponentUserID, name, 0)
+		}
+	}
+
+	// Subkeys
+	for i := range entity.Subkeys {
+		subkey := &entity.Subkeys[i]
+		subkeyFingerprint := hex.EncodeToString(subkey.PublicKey.Fingerprint)
+		addSubkeyReason := func(reason string, reasonTime int64) {
+			add(false, reason, constants.ExpiringComponentSubkey, subkeyFingerprint, reasonTime)
+		}
+		if subkey.Sig == nil || primaryKey.VerifyKeySignature(subkey.PublicKey, subkey.Sig) != nil {
+			addSubkeyReason(constants.KeyValidityReasonInvalidBinding, 0)
+			continue
+		}
+		if subkey.PublicKey.CreationTime.After(now) || subkey.Sig.CreationTime.After(now) {
+			addSubkeyReason(constants.KeyValidityReasonNotYetValid, subkey.PublicKey.CreationTime.Unix())
+		}
+		if revocationTime, ok := getRevocationTime(subkey.Revocations, now); ok {
+			addSubkeyReason(constants.KeyValidityReasonRevoked, revocationTime)
+		}
+		if expiration := bindingExpirationTime(subkey.PublicKey, subkey.Sig); isExpiredAt(expiration, unixTime) {
+			addSubkeyReason(constants.KeyValidityReasonExpired, expiration)
+		}
+		if isWeakHash(subkey.Sig.Hash) {
+			addSubkeyReason(constants.KeyValidityReasonWeakHash, 0)
+		}
+		addAlgorithmReasons(func(_ bool, reason, _, _ string, reasonTime int64) {
+			addSubkeyReason(reason, reasonTime)
+		}, subkey.PublicKey, constants.ExpiringComponentSubkey)
+	}
+	return validity
+}
+
+// IsValid returns true if the key can be used, possibly with restrictions.
+func (validity *KeyValidity) IsValid() bool {
+	return validity.Validity != constants.KeyValidityInvalid
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getRevocationTime returns the creation time of the revocation signature
+// revoking a component at the given time, following go-crypto: compromised
+// keys are revoked even before the revocation.
+func getRevocationTime(revocations []*packet.Signature, now time.Time) (int64, bool) {
+	for _, revocation := range revocations {
+		compromised := revocation.RevocationReason != nil && *revocation.RevocationReason == packet.KeyCompromised
+		if compromised || !revocation.SigExpired(now) {
+			return revocation.CreationTime.Unix(), true
+		}
+	}
+	return 0, false
+}
+
+// addAlgorithmReasons reports the public key algorithms rejected by the
+// policy of the library.
+func addAlgorithmReasons(
+	add func(invalid bool, reason, componentType, target string, reasonTime int64),
+	publicKey *packet.PublicKey,
+	componentType string,
+) {
+	target := hex.EncodeToString(publicKey.Fingerprint)
+	if isInsecureLegacyAlgorithm(publicKey.PubKeyAlgo) {
+		add(false, constants.KeyValidityReasonInsecureAlgorithm, componentType, target, 0)
+	}
+	if publicKey.PubKeyAlgo == packet.PubKeyAlgoRSA ||
+		publicKey.PubKeyAlgo == packet.PubKeyAlgoRSAEncryptOnly ||
+		publicKey.PubKeyAlgo == packet.PubKeyAlgoRSASignOnly {
+		if bitLength, err := publicKey.BitLength(); err == nil && int(bitLength) < minSecureRSABits {
+			add(false, constants.KeyValidityReasonWeakKey, componentType, target, 0)
+		}
+	}
+}
+
+// signatureExpirationTime returns the expiration time of sig, or 0 if it
+// does not expire.
+func signatureExpirationTime(sig *packet.Signature) int64 {
+	if sig.SigLifetimeSecs == nil || *sig.SigLifetimeSecs == 0 {
+		return 0
+	}
+	return sig.CreationTime.Unix() + int64(*sig.SigLifetimeSecs)
+}
+
+func isExpiredAt(expiration, unixTime int64) bool {
+	return expiration != 0 && unixTime > expiration
+}
+
+func isWeakHash(hash crypto.Hash) bool {
+	return hash == crypto.SHA1 || hash == crypto.MD5 || hash == crypto.RIPEMD160
+}
@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyGetValidityAt(t *testing.T) {
+	now := time.Now().Unix()
+	validity := keyTestEC.GetValidityAt(now)
+	assert.Exactly(t, &KeyValidity{Validity: constants.KeyValidityValid}, validity)
+	assert.True(t, validity.IsValid())
+
+	// Before its creation, the key and its components are not yet valid
+	creationTime := keyTestEC.entity.PrimaryKey.CreationTime.Unix()
+	validity = keyTestEC.GetValidityAt(creationTime - 1)
+	assert.Exactly(t, constants.KeyValidityInvalid, validity.Validity)
+	assert.False(t, validity.IsValid())
+	assert.Exactly(t, &KeyValidityReason{
+		Reason: constants.KeyValidityReasonNotYetValid,
+		Type:   constants.ExpiringComponentKey,
+		Target: keyTestEC.GetFingerprint(),
+		Time:   creationTime,
+	}, validity.Reasons[0])
+
+	expiredKey, err := NewKeyFromArmored(readTestFile("key_expiredKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	validity = expiredKey.GetValidityAt(now)
+	assert.Exactly(t, constants.KeyValidityInvalid, validity.Validity)
+	assert.Exactly(t, []*KeyValidityReason{
+		{Reason: constants.KeyValidityReasonWeakKey, Type: constants.ExpiringComponentKey, Target: expiredKey.GetFingerprint()},
+		{Reason: constants.KeyValidityReasonExpired, Type: constants.ExpiringComponentUserID, Target: "test1 <a@b.com>", Time: 1338},
+	}, validity.Reasons)
+
+	revokedKey, err := NewKeyFromArmored(readTestFile("key_revoked", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	validity = revokedKey.GetValidityAt(now)
+	assert.Exactly(t, constants.KeyValidityInvalid, validity.Validity)
+	assert.Exactly(t, constants.KeyValidityReasonRevoked, validity.Reasons[0].Reason)
+
+	// Legacy algorithms restrict the key, like broken subkey bindings
+	legacyKey, err := NewKeyFromArmored(readTestFile("key_legacyDSAElGamal", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	validity = legacyKey.GetValidityAt(now)
+	assert.Exactly(t, constants.KeyValidityRestricted, validity.Validity)
+	assert.Exactly(t, 2, len(validity.Reasons))
+	assert.Exactly(t, constants.KeyValidityReasonInsecureAlgorithm, validity.Reasons[1].Reason)
+	assert.Exactly(t, constants.ExpiringComponentSubkey, validity.Reasons[1].Type)
+
+	brokenKey, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	brokenKey.entity.Subkeys[0].PublicKey = keyTestRSA.entity.Subkeys[0].PublicKey
+	validity = brokenKey.GetValidityAt(now)
+	assert.Exactly(t, []*KeyValidityReason{{
+		Reason: constants.KeyValidityReasonInvalidBinding,
+		Type:   constants.ExpiringComponentSubkey,
+		Target: hex.EncodeToString(keyTestRSA.entity.Subkeys[0].PublicKey.Fingerprint),
+	}}, validity.Reasons)
+}
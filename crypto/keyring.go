@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"bytes"
+	"encoding/hex"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -16,6 +17,37 @@ type KeyRing struct {
 
 	// FirstKeyID as obtained from API to match salt
 	FirstKeyID string
+
+	keyRingSettings
+}
+
+// keyRingSettings are the settings of a keyring, kept by the keyrings
+// derived from it, e.g. by Copy.
+type keyRingSettings struct {
+	// verificationCache, if set, remembers successful detached verifications.
+	verificationCache *VerificationCache
+
+	// seipdVersion, if set, overrides the SEIPD version used when encrypting.
+	seipdVersion int
+
+	// allowInsecureLegacy allows encrypting and signing with ElGamal and DSA keys.
+	allowInsecureLegacy bool
+
+	// verificationLimits, if set, bounds the signatures verified with the keyring.
+	verificationLimits *VerificationLimits
+
+	// verifyTimeWindow, if set, bounds the creation time of the verified signatures.
+	verifyTimeWindow *verifyTimeWindow
+
+	// encryptToSender, if set, adds the sender, senderKeyRing or else the
+	// signing key, to the recipients when encrypting.
+	encryptToSender bool
+	senderKeyRing   *KeyRing
+
+	// signingSubkey and encryptionSubkey, if set, are the hex fingerprints
+	// of the keys to sign with and to encrypt to.
+	signingSubkey    string
+	encryptionSubkey string
 }
 
 // Identity contains the name and the email of a key holder.
@@ -37,11 +69,12 @@ func NewKeyRing(key *Key) (*KeyRing, error) {
 }
 
 // AddKey adds the given key to the keyring.
+// Private keys must be at least partially unlocked, see Key.UnlockSubkey.
 func (keyRing *KeyRing) AddKey(key *Key) error {
 	if key.IsPrivate() {
 		unlocked, err := key.IsUnlocked()
-		if err != nil || !unlocked {
-			return errors.New("gopenpgp: unable to add locked key to a keyring")
+		if err != nil || (!unlocked && !key.hasUnlockedKey()) {
+			return newClassifiedError(ErrKeyLocked, "gopenpgp: unable to add locked key to a keyring", err)
 		}
 	}
 
@@ -52,6 +85,8 @@ func (keyRing *KeyRing) AddKey(key *Key) error {
 // NewKeyRingFromBinary creates a new keyring with all the keys contained in the unarmored binary data.
 // Note that it accepts only unlocked or public keys, as KeyRing cannot contain locked keys.
 func NewKeyRingFromBinary(binKeys []byte) (*KeyRing, error) {
+	// The attestations of user IDs are not kept in keyrings
+	binKeys, _ = splitAttestations(binKeys)
 	entities, err := openpgp.ReadKeyRing(bytes.NewReader(binKeys))
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in reading keyring")
@@ -78,7 +113,7 @@ func NewKeyRingFromBinary(binKeys []byte) (*KeyRing, error) {
 func (keyRing *KeyRing) GetKeys() []*Key {
 	keys := make([]*Key, keyRing.CountEntities())
 	for i, entity := range keyRing.entities {
-		keys[i] = &Key{entity}
+		keys[i] = &Key{entity: entity}
 	}
 	return keys
 }
@@ -88,24 +123,54 @@ func (keyRing *KeyRing) GetKey(n int) (*Key, error) {
 	if n >= keyRing.CountEntities() {
 		return nil, errors.New("gopenpgp: out of bound when fetching key")
 	}
-	return &Key{keyRing.entities[n]}, nil
+	return &Key{entity: keyRing.entities[n]}, nil
 }
 
-// getSigningEntity returns first private unlocked signing entity from keyring.
+// getSigningEntity returns first private signing entity from keyring whose
+// signing key is unlocked, or the entity of the key set by SetSigningSubkey.
 func (keyRing *KeyRing) getSigningEntity() (*openpgp.Entity, error) {
+	if keyRing.signingSubkey != "" {
+		return keyRing.getSigningSubkeyEntity()
+	}
+
 	var signEntity *openpgp.Entity
+	var stubErr, legacyErr error
 
 	for _, e := range keyRing.entities {
-		// Entity.PrivateKey must be a signing key
-		if e.PrivateKey != nil {
-			if !e.PrivateKey.Encrypted {
+		if e.PrivateKey == nil {
+			continue
+		}
+		// The key selected by go-crypto to sign must be unlocked
+		if signingKey, ok := e.SigningKey(getNow()); ok {
+			if signingKey.PrivateKey != nil && signingKey.PrivateKey.Dummy() {
+				if stubErr == nil {
+					stubErr = StubKeyError{Fingerprint: hex.EncodeToString(signingKey.PublicKey.Fingerprint)}
+				}
+				continue
+			}
+			if signingKey.PrivateKey != nil && !signingKey.PrivateKey.Encrypted {
+				if err := keyRing.checkInsecureLegacyKey(signingKey.PublicKey); err != nil {
+					if legacyErr == nil {
+						legacyErr = err
+					}
+					continue
+				}
 				signEntity = e
 				break
 			}
+		} else if !e.PrivateKey.Encrypted && signEntity == nil {
+			// Let go-crypto report why the entity can't sign
+			signEntity = e
 		}
 	}
+	if signEntity == nil && legacyErr != nil {
+		return nil, legacyErr
+	}
+	if signEntity == nil && stubErr != nil {
+		return nil, stubErr
+	}
 	if signEntity == nil {
-		return nil, errors.New("gopenpgp: cannot sign message, unable to unlock signer key")
+		return nil, newClassifiedError(ErrKeyLocked, "gopenpgp: cannot sign message, unable to unlock signer key", nil)
 	}
 
 	return signEntity, nil
@@ -229,7 +294,7 @@ func FilterExpiredKeys(contactKeys []*KeyRing) (filteredKeys []*KeyRing, err err
 	}
 
 	if len(filteredKeys) == 0 && hasExpiredEntity {
-		return filteredKeys, errors.New("gopenpgp: all contacts keys are expired")
+		return filteredKeys, newClassifiedError(ErrKeyExpired, "gopenpgp: all contacts keys are expired", nil)
 	}
 
 	return filteredKeys, nil
@@ -274,6 +339,7 @@ func (keyRing *KeyRing) Copy() (*KeyRing, error) {
 	}
 	newKeyRing.entities = entities
 	newKeyRing.FirstKeyID = keyRing.FirstKeyID
+	newKeyRing.keyRingSettings = keyRing.keyRingSettings
 
 	return newKeyRing, nil
 }
@@ -0,0 +1,163 @@
+package crypto
+
+import (
+	"strings"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// KeyRingFile is an armored keyring file, as kept in git by teams: a list of
+// armored keys, each preceded by free-form comment lines, e.g. the name and
+// role of its owner. Unlike a KeyRing, it keeps the comments and the order of
+// the keys, so that the file survives round-trips unchanged.
+type KeyRingFile struct {
+	entries []*KeyRingFileEntry
+	// trailer is the text following the last key.
+	trailer string
+}
+
+// KeyRingFileEntry is a key of a KeyRingFile, with its comment.
+// The key can only be replaced with KeyRingFile.AddKey, which invalidates
+// the armored key read from the file.
+type KeyRingFileEntry struct {
+	// Comment is the text preceding the armored key in the file, including
+	// its line endings and blank lines.
+	Comment string
+
+	key *Key
+	// armored is the armored key as read from the file.
+	armored string
+}
+
+// NewKeyRingFileFromArmored parses an armored keyring file. The text outside
+// of the armored keys is kept as the comment of the following key.
+func NewKeyRingFileFromArmored(armored string) (*KeyRingFile, error) {
+	file := &KeyRingFile{}
+	var text, block strings.Builder
+	inBlock := false
+	for _, line := range strings.SplitAfter(armored, "\n") {
+		trimmed := strings.TrimRight(line, "\r\n")
+		if !inBlock {
+			if isKeyArmorLine(trimmed, "BEGIN") {
+				inBlock = true
+				block.WriteString(line)
+			} else {
+				text.WriteString(line)
+			}
+			continue
+		}
+		block.WriteString(line)
+		if isKeyArmorLine(trimmed, "END") {
+			key, err := NewKeyFromArmored(block.String())
+			if err != nil {
+				return nil, errors.Wrap(err, "gopenpgp: error in reading keyring file")
+			}
+			file.entries = append(file.entries, &KeyRingFileEntry{
+				Comment: text.String(),
+				key:     key,
+				armored: block.String(),
+			})
+			text.Reset()
+			block.Reset()
+			inBlock = false
+		}
+	}
+	if inBlock {
+		return nil, errors.New("gopenpgp: error in reading keyring file: missing armor end line")
+	}
+	file.trailer = text.String()
+	return file, nil
+}
+
+// GetEntries returns the keys of the file, in order, with their comments.
+func (file *KeyRingFile) GetEntries() []*KeyRingFileEntry {
+	return file.entries
+}
+
+// AddKey appends a key to the file, preceded by comment. The comment is
+// written as is, and must end with a line ending if not empty.
+// A key already in the file is replaced, in place, keeping its comment if
+// comment is empty.
+func (file *KeyRingFile) AddKey(key *Key, comment string) error {
+	if key == nil {
+		return errors.New("gopenpgp: no key provided")
+	}
+	for _, entry := range file.entries {
+		if entry.key.GetFingerprint() == key.GetFingerprint() {
+			entry.key = key
+			entry.armored = ""
+			if comment != "" {
+				entry.Comment = comment
+			}
+			return nil
+		}
+	}
+	file.entries = append(file.entries, &KeyRingFileEntry{Comment: comment, key: key})
+	return nil
+}
+
+// RemoveKey removes the key with the given hex fingerprint, and its comment,
+// from the file. It returns false if the file does not contain the key.
+func (file *KeyRingFile) RemoveKey(fingerprint string) bool {
+	fingerprint = strings.ToLower(fingerprint)
+	for i, entry := range file.entries {
+		if entry.key.GetFingerprint() == fingerprint {
+			file.entries = append(file.entries[:i], file.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// GetKeyRing returns a keyring with the keys of the file.
+// Note that, as for NewKeyRing, private keys must be unlocked.
+func (file *KeyRingFile) GetKeyRing() (*KeyRing, error) {
+	keyRing := &KeyRing{}
+	for _, entry := range file.entries {
+		if err := keyRing.AddKey(entry.key); err != nil {
+			return nil, err
+		}
+	}
+	return keyRing, nil
+}
+
+// Armor returns the keyring file. The keys read from the file, and not
+// replaced since, are written as they were read, the others are armored
+// with the default gopenpgp headers.
+func (file *KeyRingFile) Armor() (string, error) {
+	var armored strings.Builder
+	for i, entry := range file.entries {
+		armored.WriteString(entry.Comment)
+		if entry.armored != "" {
+			armored.WriteString(entry.armored)
+			// Only the last key of a file can lack its line ending
+			if !strings.HasSuffix(entry.armored, "\n") && i < len(file.entries)-1 {
+				armored.WriteString("\n")
+			}
+			continue
+		}
+		armoredKey, err := entry.key.Armor()
+		if err != nil {
+			return "", err
+		}
+		armored.WriteString(armoredKey + "\n")
+	}
+	armored.WriteString(file.trailer)
+	return armored.String(), nil
+}
+
+// GetKey returns the key of the entry.
+func (entry *KeyRingFileEntry) GetKey() *Key {
+	return entry.key
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// isKeyArmorLine returns true if line is the BEGIN or END line, depending on
+// kind, of an armored public or private key.
+func isKeyArmorLine(line, kind string) bool {
+	line = strings.TrimSpace(line)
+	return line == "-----"+kind+" "+constants.PublicKeyHeader+"-----" ||
+		line == "-----"+kind+" "+constants.PrivateKeyHeader+"-----"
+}
@@ -0,0 +1,67 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRingFile(t *testing.T) {
+	otherKey, err := keyTestEC.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	armored := "# Ops team keys\n\n# Alice, on-call\n" + readTestFile("keyring_publicKey", false) +
+		"\n# Bob\n" + otherKey + "\n# end of file\n"
+
+	file, err := NewKeyRingFileFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading keyring file, got:", err)
+	}
+	entries := file.GetEntries()
+	assert.Exactly(t, 2, len(entries))
+	assert.Exactly(t, "# Ops team keys\n\n# Alice, on-call\n", entries[0].Comment)
+	assert.Exactly(t, "# Bob\n", strings.TrimPrefix(entries[1].Comment, "\n"))
+	assert.Exactly(t, keyTestEC.GetFingerprint(), entries[1].GetKey().GetFingerprint())
+
+	rearmored, err := file.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring keyring file, got:", err)
+	}
+	assert.Exactly(t, armored, rearmored)
+
+	keyRing, err := file.GetKeyRing()
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Exactly(t, 2, keyRing.CountEntities())
+
+	removed := entries[0].GetKey().GetFingerprint()
+	assert.True(t, file.RemoveKey(strings.ToUpper(removed)))
+	assert.False(t, file.RemoveKey(removed))
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	if err = file.AddKey(publicKey, "# Carol\n"); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	rearmored, err = file.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring keyring file, got:", err)
+	}
+	file, err = NewKeyRingFileFromArmored(rearmored)
+	if err != nil {
+		t.Fatal("Expected no error while reading keyring file, got:", err)
+	}
+	entries = file.GetEntries()
+	assert.Exactly(t, 2, len(entries))
+	assert.Exactly(t, keyTestEC.GetFingerprint(), entries[0].GetKey().GetFingerprint())
+	assert.Exactly(t, "# Carol\n", entries[1].Comment)
+	assert.True(t, strings.HasSuffix(rearmored, "-----\n# end of file\n"))
+	assert.Exactly(t, publicKey.GetFingerprint(), entries[1].GetKey().GetFingerprint())
+
+	_, err = NewKeyRingFileFromArmored("# truncated\n-----BEGIN PGP PUBLIC KEY BLOCK-----\n")
+	assert.Error(t, err)
+}
@@ -3,7 +3,6 @@ package crypto
 import (
 	"bytes"
 	"io"
-	"io/ioutil"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -57,7 +56,10 @@ func (keyRing *KeyRing) EncryptWithContextAndCompression(message *PlainMessage,
 func (keyRing *KeyRing) Decrypt(
 	message *PGPMessage, verifyKey *KeyRing, verifyTime int64,
 ) (*PlainMessage, error) {
-	return asymmetricDecrypt(message.NewReader(), keyRing, verifyKey, verifyTime, nil)
+	if err := checkMessageLimits(message, verifyKey); err != nil {
+		return nil, err
+	}
+	return asymmetricDecrypt(message, keyRing, verifyKey, verifyTime, nil)
 }
 
 // DecryptWithContext decrypts encrypted string using pgp keys, returning a PlainMessage
@@ -74,7 +76,10 @@ func (keyRing *KeyRing) DecryptWithContext(
 	verifyTime int64,
 	verificationContext *VerificationContext,
 ) (*PlainMessage, error) {
-	return asymmetricDecrypt(message.NewReader(), keyRing, verifyKey, verifyTime, verificationContext)
+	if err := checkMessageLimits(message, verifyKey); err != nil {
+		return nil, err
+	}
+	return asymmetricDecrypt(message, keyRing, verifyKey, verifyTime, verificationContext)
 }
 
 // SignDetached generates and returns a PGPSignature for a given PlainMessage.
@@ -90,20 +95,14 @@ func (keyRing *KeyRing) SignDetachedWithContext(message *PlainMessage, context *
 		keyRing,
 		message.NewReader(),
 		message.IsBinary(),
-		context,
+		context.getNotations(),
 	)
 }
 
 // VerifyDetached verifies a PlainMessage with a detached PGPSignature
 // and returns a SignatureVerificationError if fails.
 func (keyRing *KeyRing) VerifyDetached(message *PlainMessage, signature *PGPSignature, verifyTime int64) error {
-	_, err := verifySignature(
-		keyRing.entities,
-		message.NewReader(),
-		signature.GetBinary(),
-		verifyTime,
-		nil,
-	)
+	_, err := keyRing.verifyMessageSignature(message, signature, verifyTime, nil)
 	return err
 }
 
@@ -112,16 +111,39 @@ func (keyRing *KeyRing) VerifyDetached(message *PlainMessage, signature *PGPSign
 // If a context is provided, it verifies that the signature is valid in the given context, using
 // the signature notation with name the name set in `constants.SignatureContextName`.
 func (keyRing *KeyRing) VerifyDetachedWithContext(message *PlainMessage, signature *PGPSignature, verifyTime int64, verificationContext *VerificationContext) error {
-	_, err := verifySignature(
-		keyRing.entities,
-		message.NewReader(),
-		signature.GetBinary(),
-		verifyTime,
-		verificationContext,
-	)
+	_, err := keyRing.verifyMessageSignature(message, signature, verifyTime, verificationContext)
 	return err
 }
 
+// SignDetachedWithCanonicalization generates and returns a PGPSignature for a
+// given PlainMessage. Text messages are canonicalized before signing as set by
+// canonicalization, one of the constants.TextCanonicalization* values;
+// binary messages are signed as is.
+func (keyRing *KeyRing) SignDetachedWithCanonicalization(message *PlainMessage, canonicalization int) (*PGPSignature, error) {
+	canonicalMessage, err := message.canonicalizeText(canonicalization)
+	if err != nil {
+		return nil, err
+	}
+	return keyRing.SignDetached(canonicalMessage)
+}
+
+// VerifyDetachedWithCanonicalization verifies a PlainMessage with a detached
+// PGPSignature and returns a SignatureVerificationError if fails.
+// Text messages are canonicalized before verification as set by
+// canonicalization, which must match the canonicalization used for signing.
+func (keyRing *KeyRing) VerifyDetachedWithCanonicalization(
+	message *PlainMessage,
+	signature *PGPSignature,
+	verifyTime int64,
+	canonicalization int,
+) error {
+	canonicalMessage, err := message.canonicalizeText(canonicalization)
+	if err != nil {
+		return err
+	}
+	return keyRing.VerifyDetached(canonicalMessage, signature, verifyTime)
+}
+
 // SignDetachedEncrypted generates and returns a PGPMessage
 // containing an encrypted detached signature for a given PlainMessage.
 func (keyRing *KeyRing) SignDetachedEncrypted(message *PlainMessage, encryptionKeyRing *KeyRing) (encryptedSignature *PGPMessage, err error) {
@@ -156,13 +178,7 @@ func (keyRing *KeyRing) VerifyDetachedEncrypted(message *PlainMessage, encrypted
 // returns the creation time of the signature if it succeeds
 // and returns a SignatureVerificationError if fails.
 func (keyRing *KeyRing) GetVerifiedSignatureTimestamp(message *PlainMessage, signature *PGPSignature, verifyTime int64) (int64, error) {
-	sigPacket, err := verifySignature(
-		keyRing.entities,
-		message.NewReader(),
-		signature.GetBinary(),
-		verifyTime,
-		nil,
-	)
+	sigPacket, err := keyRing.verifyMessageSignature(message, signature, verifyTime, nil)
 	if err != nil {
 		return 0, err
 	}
@@ -180,13 +196,7 @@ func (keyRing *KeyRing) GetVerifiedSignatureTimestampWithContext(
 	verifyTime int64,
 	verificationContext *VerificationContext,
 ) (int64, error) {
-	sigPacket, err := verifySignature(
-		keyRing.entities,
-		message.NewReader(),
-		signature.GetBinary(),
-		verifyTime,
-		verificationContext,
-	)
+	sigPacket, err := keyRing.verifyMessageSignature(message, signature, verifyTime, verificationContext)
 	if err != nil {
 		return 0, err
 	}
@@ -202,6 +212,10 @@ func asymmetricEncrypt(
 	compress bool,
 	signingContext *SigningContext,
 ) (*PGPMessage, error) {
+	if err := plainMessage.checkUTF8(); err != nil {
+		return nil, err
+	}
+
 	var outBuf bytes.Buffer
 	var encryptWriter io.WriteCloser
 	var err error
@@ -212,7 +226,7 @@ func asymmetricEncrypt(
 		ModTime:  plainMessage.getFormattedTime(),
 	}
 
-	encryptWriter, err = asymmetricEncryptStream(hints, &outBuf, &outBuf, publicKey, privateKey, compress, signingContext)
+	encryptWriter, err = asymmetricEncryptStream(hints, &outBuf, &outBuf, publicKey, privateKey, compress, signingContext, encryptionChunkSize)
 	if err != nil {
 		return nil, err
 	}
@@ -238,10 +252,47 @@ func asymmetricEncryptStream(
 	publicKey, privateKey *KeyRing,
 	compress bool,
 	signingContext *SigningContext,
+	chunkSize int,
 ) (encryptWriter io.WriteCloser, err error) {
+	timer := startOperation(constants.MetricsOperationEncrypt)
+	defer func() {
+		if err != nil {
+			timer.finish(0, err)
+		}
+	}()
+
+	var signEntity *openpgp.Entity
+	if privateKey != nil && len(privateKey.entities) > 0 {
+		var err error
+		signEntity, err = privateKey.getSigningEntity()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	publicKey, err = publicKey.getRecipients(signEntity)
+	if err != nil {
+		return nil, err
+	}
+	aeadConfig, err := publicKey.getEncryptionAEADConfig()
+	if err != nil {
+		return nil, err
+	}
+	if aeadConfig != nil && chunkSize == armoredEncryptionChunkSize {
+		// SEIPDv2 only outputs complete chunks, see EncryptStreamArmored.
+		aeadConfig.ChunkSize = armoredEncryptionChunkSize
+	}
+
+	encryptionTime := newEncryptionTime(publicKey.entities)
 	config := &packet.Config{
+		Rand:          getRandomSource(),
 		DefaultCipher: packet.CipherAES256,
-		Time:          getTimeGenerator(),
+		Time:          encryptionTime.now,
+		AEADConfig:    aeadConfig,
+	}
+
+	if err := publicKey.checkInsecureLegacyEncryption(config.Now()); err != nil {
+		return nil, err
 	}
 
 	if compress {
@@ -253,34 +304,36 @@ func asymmetricEncryptStream(
 		config.SignatureNotations = append(config.SignatureNotations, signingContext.getNotation())
 	}
 
-	var signEntity *openpgp.Entity
-	if privateKey != nil && len(privateKey.entities) > 0 {
-		var err error
-		signEntity, err = privateKey.getSigningEntity()
-		if err != nil {
-			return nil, err
-		}
-	}
+	applyConfigModifier(config)
+	logEncryption("encrypt", config, publicKey, signEntity)
 
 	if hints.IsBinary {
 		encryptWriter, err = openpgp.EncryptSplit(keyPacketWriter, dataPacketWriter, publicKey.entities, signEntity, hints, config)
 	} else {
 		encryptWriter, err = openpgp.EncryptTextSplit(keyPacketWriter, dataPacketWriter, publicKey.entities, signEntity, hints, config)
 	}
+	encryptionTime.keysSelected()
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in encrypting asymmetrically")
+		return nil, newEncryptError(err, publicKey, config, "gopenpgp: error in encrypting asymmetrically")
+	}
+	auditSigning("encrypt and sign", signEntity, config.Now())
+	encryptWriter = newChunkedWriteCloser(encryptWriter, chunkSize)
+	if timer != nil {
+		encryptWriter = &meteredWriteCloser{writer: encryptWriter, timer: timer}
 	}
 	return encryptWriter, nil
 }
 
 // Core for decryption+verification (non streaming) functions.
 func asymmetricDecrypt(
-	encryptedIO io.Reader,
+	encrypted *PGPMessage,
 	privateKey *KeyRing,
 	verifyKey *KeyRing,
 	verifyTime int64,
 	verificationContext *VerificationContext,
 ) (message *PlainMessage, err error) {
+	timer := startOperation(constants.MetricsOperationDecrypt)
+	encryptedIO := encrypted.NewReader()
 	messageDetails, err := asymmetricDecryptStream(
 		encryptedIO,
 		privateKey,
@@ -289,24 +342,37 @@ func asymmetricDecrypt(
 		verificationContext,
 	)
 	if err != nil {
+		timer.finish(0, err)
 		return nil, err
 	}
 
-	body, err := ioutil.ReadAll(messageDetails.UnverifiedBody)
+	body, err := readAllWithSizeHint(messageDetails.UnverifiedBody, encryptedIO)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in reading message body")
+		timer.finish(int64(len(body)), err)
+		return nil, newReadError(err, "gopenpgp: error in reading message body")
 	}
 
+	var signature *packet.Signature
+	var unverifiedSignatures []*packet.Signature
+	var signer *SignatureSigner
 	if verifyKey != nil {
-		processSignatureExpiration(messageDetails, verifyTime)
-		err = verifyDetailsSignature(messageDetails, verifyKey, verificationContext)
+		signature, signer, err = verifyEmbeddedSignature(messageDetails, body, verifyKey, verifyTime, verificationContext)
+		unverifiedSignatures = messageDetails.UnverifiedSignatures
 	}
+	timer.finish(int64(len(body)), err)
 
 	return &PlainMessage{
 		Data:     body,
 		TextType: !messageDetails.LiteralData.IsBinary,
+		IsUTF8:   messageDetails.LiteralData.Format == 'u',
 		Filename: messageDetails.LiteralData.FileName,
 		Time:     messageDetails.LiteralData.Time,
+
+		insecureLegacyAlgorithm: hasInsecureLegacyAlgorithm(messageDetails),
+		signature:               signature,
+		unverifiedSignatures:    unverifiedSignatures,
+		signer:                  signer,
+		integrityWarning:        getIntegrityWarning(encrypted.Data, messageDetails),
 	}, err
 }
 
@@ -347,9 +413,16 @@ func asymmetricDecryptStream(
 		config.KnownNotations = map[string]bool{constants.SignatureContextName: true}
 	}
 
+	applyConfigModifier(config)
+	encryptedIO, decompressionLimiter := newDecompressionLimiter(encryptedIO)
 	messageDetails, err = openpgp.ReadMessage(encryptedIO, privKeyEntries, nil, config)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in reading message")
+		logEvent(constants.LOG_LEVEL_ERROR, constants.LogEventPacketParsed, "operation", "decrypt", "error", err.Error())
+		return nil, newReadError(err, "gopenpgp: error in reading message")
 	}
+	messageDetails.UnverifiedBody = decompressionLimiter.limitReader(messageDetails.UnverifiedBody)
+	messageDetails.UnverifiedBody = verifyKey.getVerificationLimits().limitReader(messageDetails.UnverifiedBody)
+	logMessageDetails("decrypt", messageDetails)
+	auditDecryption("decrypt", messageDetails.DecryptedWith)
 	return messageDetails, err
 }
@@ -2,11 +2,11 @@ package crypto
 
 import (
 	"bytes"
-	"strconv"
 
 	"github.com/pkg/errors"
 
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
 )
 
 // DecryptSessionKey returns the decrypted session key from one or multiple binary encrypted session key packets.
@@ -39,6 +39,7 @@ Loop:
 				}
 
 				if decryptErr = ek.Decrypt(priv, nil); decryptErr == nil {
+					auditDecryption("decrypt session key", key)
 					break Loop
 				}
 			}
@@ -63,11 +64,11 @@ Loop:
 	}
 
 	if decryptErr != nil {
-		return nil, errors.Wrap(decryptErr, "gopenpgp: error in decrypting")
+		return nil, newClassifiedError(ErrNoDecryptionKey, "gopenpgp: error in decrypting", decryptErr)
 	}
 
 	if ek == nil || ek.Key == nil {
-		return nil, errors.New("gopenpgp: unable to decrypt session key: no valid decryption key")
+		return nil, newClassifiedError(ErrNoDecryptionKey, "gopenpgp: unable to decrypt session key: no valid decryption key", nil)
 	}
 
 	return newSessionKeyFromEncrypted(ek)
@@ -82,11 +83,19 @@ func (keyRing *KeyRing) EncryptSessionKey(sk *SessionKey) ([]byte, error) {
 		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt session key")
 	}
 
-	pubKeys := make([]*packet.PublicKey, 0, len(keyRing.entities))
-	for _, e := range keyRing.entities {
-		encryptionKey, ok := e.EncryptionKey(getNow())
+	recipients, err := keyRing.getRecipients(nil)
+	if err != nil {
+		return nil, err
+	}
+	now := getEncryptionTimeGenerator(recipients.entities)()
+	pubKeys := make([]*packet.PublicKey, 0, len(recipients.entities))
+	for _, e := range recipients.entities {
+		encryptionKey, ok := e.EncryptionKey(now)
 		if !ok {
-			return nil, errors.New("gopenpgp: encryption key is unavailable for key id " + strconv.FormatUint(e.PrimaryKey.KeyId, 16))
+			return nil, newKeyCapabilityError(e, constants.KeyCapabilityEncrypt)
+		}
+		if err := keyRing.checkInsecureLegacyKey(encryptionKey.PublicKey); err != nil {
+			return nil, err
 		}
 		pubKeys = append(pubKeys, encryptionKey.PublicKey)
 	}
@@ -94,8 +103,10 @@ func (keyRing *KeyRing) EncryptSessionKey(sk *SessionKey) ([]byte, error) {
 		return nil, errors.New("cannot set key: no public key available")
 	}
 
+	config := &packet.Config{Rand: getRandomSource()}
+	applyConfigModifier(config)
 	for _, pub := range pubKeys {
-		if err := packet.SerializeEncryptedKey(outbuf, pub, cf, sk.Key, nil); err != nil {
+		if err := packet.SerializeEncryptedKey(outbuf, pub, cf, sk.Key, config); err != nil {
 			return nil, errors.Wrap(err, "gopenpgp: cannot set key")
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/pkg/errors"
 )
 
@@ -24,6 +25,8 @@ type WriteCloser interface {
 
 type PlainMessageMetadata struct {
 	IsBinary bool
+	// IsUTF8 is set when the data is marked as UTF-8 text ('u' format).
+	IsUTF8   bool
 	Filename string
 	ModTime  int64
 }
@@ -123,26 +126,27 @@ func encryptStream(
 	compress bool,
 	signingContext *SigningContext,
 ) (plainMessageWriter WriteCloser, err error) {
+	hints := getFileHints(plainMessageMetadata)
+	plainMessageWriter, err = asymmetricEncryptStream(hints, keyPacketWriter, dataPacketWriter, encryptionKeyRing, signKeyRing, compress, signingContext, encryptionChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	return newUTF8WriteCloser(plainMessageWriter, plainMessageMetadata), nil
+}
+
+// getFileHints returns the file hints of the literal data of the encrypted
+// message.
+func getFileHints(plainMessageMetadata *PlainMessageMetadata) *openpgp.FileHints {
 	if plainMessageMetadata == nil {
 		// Use sensible default metadata
-		plainMessageMetadata = &PlainMessageMetadata{
-			IsBinary: true,
-			Filename: "",
-			ModTime:  GetUnixTime(),
-		}
+		plainMessageMetadata = NewDefaultPlainMessageMetadata()
 	}
 
-	hints := &openpgp.FileHints{
+	return &openpgp.FileHints{
 		FileName: plainMessageMetadata.Filename,
 		IsBinary: plainMessageMetadata.IsBinary,
 		ModTime:  time.Unix(plainMessageMetadata.ModTime, 0),
 	}
-
-	plainMessageWriter, err = asymmetricEncryptStream(hints, keyPacketWriter, dataPacketWriter, encryptionKeyRing, signKeyRing, compress, signingContext)
-	if err != nil {
-		return nil, err
-	}
-	return plainMessageWriter, nil
 }
 
 // EncryptSplitResult is used to wrap the encryption writecloser while storing the key packet.
@@ -296,6 +300,10 @@ type PlainMessageReader struct {
 	verifyTime          int64
 	readAll             bool
 	verificationContext *VerificationContext
+	timer               *operationTimer
+	bytesRead           int64
+	detached            *detachedVerification
+	readAhead           *readAheadReader
 }
 
 // GetMetadata returns the metadata of the decrypted message.
@@ -303,6 +311,7 @@ func (msg *PlainMessageReader) GetMetadata() *PlainMessageMetadata {
 	return &PlainMessageMetadata{
 		Filename: msg.details.LiteralData.FileName,
 		IsBinary: msg.details.LiteralData.IsBinary,
+		IsUTF8:   msg.details.LiteralData.Format == 'u',
 		ModTime:  int64(msg.details.LiteralData.Time),
 	}
 }
@@ -310,27 +319,62 @@ func (msg *PlainMessageReader) GetMetadata() *PlainMessageMetadata {
 // Read is used to access the message decrypted data.
 // Makes PlainMessageReader implement the Reader interface.
 func (msg *PlainMessageReader) Read(b []byte) (n int, err error) {
-	n, err = msg.details.UnverifiedBody.Read(b)
+	if msg.readAhead != nil {
+		n, err = msg.readAhead.Read(b)
+	} else {
+		n, err = msg.details.UnverifiedBody.Read(b)
+	}
+	msg.bytesRead += int64(n)
+	if msg.detached != nil {
+		msg.detached.write(b[:n], err)
+	}
 	if errors.Is(err, io.EOF) {
 		msg.readAll = true
+		if msg.verifyKeyRing == nil {
+			msg.timer.finish(msg.bytesRead, nil)
+		}
+	} else if err != nil {
+		msg.timer.finish(msg.bytesRead, err)
 	}
 	return
 }
 
+// Close releases the goroutines of the reader, started by SetReadAhead or
+// DecryptStreamWithDetachedSignature, when the message isn't read entirely.
+// The signature can't be verified once the reader is closed early.
+func (msg *PlainMessageReader) Close() error {
+	if msg.readAhead != nil {
+		msg.readAhead.Close()
+	}
+	if msg.detached != nil && !msg.readAll {
+		msg.detached.write(nil, errors.New("gopenpgp: message reader closed"))
+	}
+	if msg.readAll {
+		// The signature was not verified before closing
+		msg.timer.finish(msg.bytesRead, nil)
+	}
+	return nil
+}
+
 // VerifySignature is used to verify that the signature is valid.
 // This method needs to be called once all the data has been read.
 // It will return an error if the signature is invalid
 // or if the message hasn't been read entirely.
+// With a verify keyring, the decryption is reported to the metrics, see
+// SetMetrics, once the signature is verified, with the verification error.
 func (msg *PlainMessageReader) VerifySignature() (err error) {
 	if !msg.readAll {
 		return errors.New("gopenpgp: can't verify the signature until the message reader has been read entirely")
 	}
-	if msg.verifyKeyRing != nil {
+	if msg.detached != nil {
+		err = msg.detached.getResult()
+	} else if msg.verifyKeyRing != nil {
 		processSignatureExpiration(msg.details, msg.verifyTime)
 		err = verifyDetailsSignature(msg.details, msg.verifyKeyRing, msg.verificationContext)
 	} else {
-		err = errors.New("gopenpgp: no verify keyring was provided before decryption")
+		return errors.New("gopenpgp: no verify keyring was provided before decryption")
 	}
+	msg.timer.finish(msg.bytesRead, err)
 	return
 }
 
@@ -381,6 +425,7 @@ func decryptStream(
 	verifyTime int64,
 	verificationContext *VerificationContext,
 ) (plainMessage *PlainMessageReader, err error) {
+	timer := startOperation(constants.MetricsOperationDecrypt)
 	messageDetails, err := asymmetricDecryptStream(
 		message,
 		decryptionKeyRing,
@@ -389,15 +434,17 @@ func decryptStream(
 		verificationContext,
 	)
 	if err != nil {
+		timer.finish(0, err)
 		return nil, err
 	}
 
 	return &PlainMessageReader{
-		messageDetails,
-		verifyKeyRing,
-		verifyTime,
-		false,
-		verificationContext,
+		details:             messageDetails,
+		verifyKeyRing:       verifyKeyRing,
+		verifyTime:          verifyTime,
+		readAll:             false,
+		verificationContext: verificationContext,
+		timer:               timer,
 	}, err
 }
 
@@ -459,7 +506,7 @@ func (keyRing *KeyRing) SignDetachedStreamWithContext(message Reader, context *S
 		keyRing,
 		message,
 		true,
-		context,
+		context.getNotations(),
 	)
 }
 
@@ -470,14 +517,18 @@ func (keyRing *KeyRing) VerifyDetachedStream(
 	signature *PGPSignature,
 	verifyTime int64,
 ) error {
-	_, err := verifySignature(
+	sig, err := verifySignature(
 		keyRing.entities,
+		keyRing.verificationLimits,
 		message,
 		signature.GetBinary(),
 		verifyTime,
 		nil,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return keyRing.checkVerifyTimeWindow(sig)
 }
 
 // VerifyDetachedStreamWithContext verifies a message reader with a detached PGPSignature
@@ -490,14 +541,18 @@ func (keyRing *KeyRing) VerifyDetachedStreamWithContext(
 	verifyTime int64,
 	verificationContext *VerificationContext,
 ) error {
-	_, err := verifySignature(
+	sig, err := verifySignature(
 		keyRing.entities,
+		keyRing.verificationLimits,
 		message,
 		signature.GetBinary(),
 		verifyTime,
 		verificationContext,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return keyRing.checkVerifyTimeWindow(sig)
 }
 
 // SignDetachedEncryptedStream generates and returns a PGPMessage
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"io"
+
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+// armoredEncryptionChunkSize is the size of the writes forwarded to the
+// OpenPGP literal data writer by EncryptStreamArmored, smaller than
+// encryptionChunkSize so that the output follows the input closely. It is
+// also the AEAD chunk size of the SEIPDv2 packets it writes.
+const armoredEncryptionChunkSize = 1 << 10
+
+// EncryptStreamArmored is used to encrypt data as a Writer, like
+// EncryptStream, and writes the armored message to pgpMessageWriter for
+// long-lived streaming connections, e.g. SMTP DATA or chunked HTTP responses.
+// The plaintext is encrypted in chunks of 1 KiB instead of 16 KiB, SEIPDv2
+// messages included, and pgpMessageWriter is flushed every flushLines
+// complete armor lines, and once the returned writer is closed, if it has a
+// Flush method, see armor.ArmorWithTypeFlushing.
+// The plaintext is never compressed, as the compressor would hold it back.
+// If signKeyRing is not nil, it is used to do an embedded signature.
+func (keyRing *KeyRing) EncryptStreamArmored(
+	pgpMessageWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	signKeyRing *KeyRing,
+	flushLines int,
+) (plainMessageWriter WriteCloser, err error) {
+	return keyRing.EncryptStreamArmoredWithContext(pgpMessageWriter, plainMessageMetadata, signKeyRing, nil, flushLines)
+}
+
+// EncryptStreamArmoredWithContext is used to encrypt data as a Writer, like
+// EncryptStreamArmored.
+// If signKeyRing is not nil, it is used to do an embedded signature.
+// * signingContext : (optional) a context for the embedded signature.
+func (keyRing *KeyRing) EncryptStreamArmoredWithContext(
+	pgpMessageWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	signKeyRing *KeyRing,
+	signingContext *SigningContext,
+	flushLines int,
+) (plainMessageWriter WriteCloser, err error) {
+	armorWriter, err := armor.ArmorWithTypeFlushing(pgpMessageWriter, constants.PGPMessageHeader, flushLines)
+	if err != nil {
+		return nil, err
+	}
+	encryptWriter, err := asymmetricEncryptStream(
+		getFileHints(plainMessageMetadata),
+		armorWriter,
+		armorWriter,
+		keyRing,
+		signKeyRing,
+		false,
+		signingContext,
+		armoredEncryptionChunkSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return newUTF8WriteCloser(
+		&armoredWriteCloser{encryptWriter: encryptWriter, armorWriter: armorWriter},
+		plainMessageMetadata,
+	), nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// armoredWriteCloser closes the armor once the message is encrypted.
+type armoredWriteCloser struct {
+	encryptWriter io.WriteCloser
+	armorWriter   io.WriteCloser
+}
+
+func (w *armoredWriteCloser) Write(b []byte) (int, error) {
+	return w.encryptWriter.Write(b)
+}
+
+func (w *armoredWriteCloser) Close() error {
+	if err := w.encryptWriter.Close(); err != nil {
+		return err
+	}
+	return w.armorWriter.Close()
+}
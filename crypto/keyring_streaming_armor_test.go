@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing_EncryptStreamArmored(t *testing.T) {
+	var output bytes.Buffer
+	buffered := bufio.NewWriterSize(&output, 1<<16)
+	messageWriter, err := keyRingTestPublic.EncryptStreamArmored(buffered, testMeta, keyRingTestPrivate, 1)
+	if err != nil {
+		t.Fatal("Expected no error while calling encrypting stream with key ring, got:", err)
+	}
+
+	plaintext := bytes.Repeat([]byte("Hello World!\n"), 1000)
+	for written := 0; written < 4096; written += 512 {
+		if _, err = messageWriter.Write(plaintext[written : written+512]); err != nil {
+			t.Fatal("Expected no error while writing plaintext, got:", err)
+		}
+	}
+	// The output follows the input instead of waiting for 16 KiB of plaintext
+	assert.True(t, output.Len() > 2048)
+
+	if _, err = messageWriter.Write(plaintext[4096:]); err != nil {
+		t.Fatal("Expected no error while writing plaintext, got:", err)
+	}
+	if err = messageWriter.Close(); err != nil {
+		t.Fatal("Expected no error while closing plaintext writer, got:", err)
+	}
+	assert.Exactly(t, 0, buffered.Buffered())
+
+	pgpMessage, err := NewPGPMessageFromArmored(output.String())
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring message, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(pgpMessage, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting message, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted.GetBinary())
+}
+
+func TestKeyRing_EncryptStreamArmoredSEIPDv2(t *testing.T) {
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building key ring, got:", err)
+	}
+
+	var output bytes.Buffer
+	messageWriter, err := keyRing.EncryptStreamArmoredWithContext(
+		&output,
+		testMeta,
+		keyRing,
+		NewSigningContext(testContext, true),
+		1,
+	)
+	if err != nil {
+		t.Fatal("Expected no error while calling encrypting stream with key ring, got:", err)
+	}
+
+	plaintext := bytes.Repeat([]byte("Hello World!\n"), 1000)
+	if _, err = messageWriter.Write(plaintext[:4096]); err != nil {
+		t.Fatal("Expected no error while writing plaintext, got:", err)
+	}
+	// The AEAD chunks are smaller than the 4 KiB of plaintext written
+	assert.True(t, output.Len() > 2048)
+
+	if _, err = messageWriter.Write(plaintext[4096:]); err != nil {
+		t.Fatal("Expected no error while writing plaintext, got:", err)
+	}
+	if err = messageWriter.Close(); err != nil {
+		t.Fatal("Expected no error while closing plaintext writer, got:", err)
+	}
+
+	pgpMessage, err := NewPGPMessageFromArmored(output.String())
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring message, got:", err)
+	}
+	decrypted, err := keyRing.DecryptWithContext(pgpMessage, keyRing, GetUnixTime(), NewVerificationContext(testContext, true, 0))
+	if err != nil {
+		t.Fatal("Expected no error while decrypting message, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted.GetBinary())
+}
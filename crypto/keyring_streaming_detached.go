@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"io"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// DecryptStreamWithDetachedSignature is used to decrypt a pgp message as a
+// Reader, and to verify a detached signature of its plaintext while it is
+// read, in a single pass over the message.
+// If encrypted is true, signature is an encrypted detached signature, as
+// returned by SignDetachedEncrypted, which is decrypted with the keyring,
+// else it is a binary detached signature.
+// PlainMessageReader.VerifySignature() verifies the detached signature,
+// instead of the embedded one, with the given key ring and verification time.
+// The verification goroutine stops once the message is read entirely, or
+// the reader is closed, see PlainMessageReader.Close, or garbage collected.
+func (keyRing *KeyRing) DecryptStreamWithDetachedSignature(
+	message Reader,
+	signature []byte,
+	encrypted bool,
+	verifyKeyRing *KeyRing,
+	verifyTime int64,
+) (plainMessage *PlainMessageReader, err error) {
+	if verifyKeyRing == nil {
+		return nil, errors.New("gopenpgp: no verify keyring provided")
+	}
+	if encrypted {
+		decryptedSignature, err := keyRing.Decrypt(NewPGPMessage(signature), nil, 0)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt detached signature")
+		}
+		signature = decryptedSignature.GetBinary()
+	}
+
+	plainMessage, err = decryptStream(keyRing, message, nil, verifyTime, nil)
+	if err != nil {
+		return nil, err
+	}
+	plainMessage.verifyKeyRing = verifyKeyRing
+	plainMessage.detached = startDetachedVerification(verifyKeyRing, signature, verifyTime)
+	// The goroutine only references the verification, not the reader.
+	runtime.SetFinalizer(plainMessage, func(msg *PlainMessageReader) {
+		msg.detached.write(nil, errors.New("gopenpgp: message reader released"))
+	})
+	return plainMessage, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// detachedVerification verifies a detached signature of the data written to
+// it, in a goroutine reading from a pipe.
+type detachedVerification struct {
+	writer *io.PipeWriter
+	result chan error
+	closed bool
+	err    error
+}
+
+func startDetachedVerification(verifyKeyRing *KeyRing, signature []byte, verifyTime int64) *detachedVerification {
+	reader, writer := io.Pipe()
+	verification := &detachedVerification{
+		writer: writer,
+		result: make(chan error, 1),
+	}
+	go func() {
+		err := verifyKeyRing.VerifyDetachedStream(reader, NewPGPSignature(signature), verifyTime)
+		// Unblocks the writes if the verification stops before the end of the data.
+		_ = reader.CloseWithError(io.ErrClosedPipe)
+		verification.result <- err
+	}()
+	return verification
+}
+
+// write passes the data read from the message, and the read error, if any,
+// to the verification.
+func (verification *detachedVerification) write(data []byte, readErr error) {
+	if verification.closed {
+		return
+	}
+	if len(data) > 0 {
+		// A write error means the verification already returned its result.
+		_, _ = verification.writer.Write(data)
+	}
+	if errors.Is(readErr, io.EOF) {
+		verification.closed = true
+		_ = verification.writer.Close()
+	} else if readErr != nil {
+		verification.closed = true
+		_ = verification.writer.CloseWithError(readErr)
+	}
+}
+
+// getResult waits for the verification to complete, and returns its result.
+func (verification *detachedVerification) getResult() error {
+	if verification.result != nil {
+		verification.err = <-verification.result
+		verification.result = nil
+	}
+	return verification.err
+}
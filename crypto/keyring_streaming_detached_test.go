@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing_DecryptStreamWithDetachedSignature(t *testing.T) {
+	message := NewPlainMessage(bytes.Repeat([]byte("Hello World!\n"), 10000))
+	pgpMessage, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting plaintext, got:", err)
+	}
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing plaintext, got:", err)
+	}
+	encryptedSignature, err := keyRingTestPrivate.SignDetachedEncrypted(message, keyRingTestPublic)
+	if err != nil {
+		t.Fatal("Expected no error while signing plaintext, got:", err)
+	}
+
+	for _, test := range []struct {
+		name      string
+		signature []byte
+		encrypted bool
+	}{
+		{"plain", signature.GetBinary(), false},
+		{"encrypted", encryptedSignature.GetBinary(), true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			decryptedReader, err := keyRingTestPrivate.DecryptStreamWithDetachedSignature(
+				bytes.NewReader(pgpMessage.GetBinary()),
+				test.signature,
+				test.encrypted,
+				keyRingTestPublic,
+				GetUnixTime(),
+			)
+			if err != nil {
+				t.Fatal("Expected no error while decrypting stream, got:", err)
+			}
+			assert.Error(t, decryptedReader.VerifySignature())
+			decryptedBytes, err := ioutil.ReadAll(decryptedReader)
+			if err != nil {
+				t.Fatal("Expected no error while reading the decrypted data, got:", err)
+			}
+			assert.Exactly(t, message.GetBinary(), decryptedBytes)
+			if err = decryptedReader.VerifySignature(); err != nil {
+				t.Fatal("Expected no error while verifying the detached signature, got:", err)
+			}
+		})
+	}
+
+	otherSignature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("other"))
+	if err != nil {
+		t.Fatal("Expected no error while signing plaintext, got:", err)
+	}
+	decryptedReader, err := keyRingTestPrivate.DecryptStreamWithDetachedSignature(
+		bytes.NewReader(pgpMessage.GetBinary()),
+		otherSignature.GetBinary(),
+		false,
+		keyRingTestPublic,
+		GetUnixTime(),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	if _, err = ioutil.ReadAll(decryptedReader); err != nil {
+		t.Fatal("Expected no error while reading the decrypted data, got:", err)
+	}
+	assert.Error(t, decryptedReader.VerifySignature())
+}
+
+func TestKeyRing_DecryptStreamWithDetachedSignatureReleased(t *testing.T) {
+	message := NewPlainMessage(bytes.Repeat([]byte("Hello World!\n"), 10000))
+	pgpMessage, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting plaintext, got:", err)
+	}
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing plaintext, got:", err)
+	}
+	// The reader is partially read, then dropped without being closed
+	result := func() chan error {
+		decryptedReader, err := keyRingTestPrivate.DecryptStreamWithDetachedSignature(
+			bytes.NewReader(pgpMessage.GetBinary()),
+			signature.GetBinary(),
+			false,
+			keyRingTestPublic,
+			GetUnixTime(),
+		)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting stream, got:", err)
+		}
+		if _, err = decryptedReader.Read(make([]byte, 1024)); err != nil {
+			t.Fatal("Expected no error while reading the decrypted data, got:", err)
+		}
+		return decryptedReader.detached.result
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		runtime.GC()
+		select {
+		case err = <-result:
+			assert.Error(t, err)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("Expected the verification to stop once the reader is released")
+}
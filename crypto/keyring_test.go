@@ -163,6 +163,23 @@ func TestSerializeParse(t *testing.T) {
 	}
 }
 
+func TestKeyRingCopyKeepsSettings(t *testing.T) {
+	keyRing, err := keyRingTestMultiple.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	keyRing.ForceSEIPDv2()
+	keyRing.AllowInsecureLegacyAlgorithms()
+	keyRing.SetVerifyTimeWindow(1, 2)
+
+	keyRingCopy, err := keyRing.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	assert.Exactly(t, keyRing.keyRingSettings, keyRingCopy.keyRingSettings)
+	assert.Exactly(t, keyRing.keyRingSettings, keyRing.withEntities(nil).keyRingSettings)
+}
+
 func TestClearPrivateKey(t *testing.T) {
 	keyRingCopy, err := keyRingTestMultiple.Copy()
 	if err != nil {
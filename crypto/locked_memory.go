@@ -0,0 +1,55 @@
+package crypto
+
+import (
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+)
+
+// SetLockedMemory enables or disables the allocation of session keys in
+// locked memory. When enabled, the session keys generated or decrypted by
+// this package are stored outside of the Go heap, in memory locked into RAM
+// so that it is never swapped to disk, where the platform allows it.
+// The locked memory is pooled: SessionKey.Clear zeroizes the key and
+// returns its memory to the pool. It is only returned by Clear, not when
+// the session key is garbage collected, as SessionKey.Key may still be in
+// use: session keys that are not cleared keep their locked memory for the
+// lifetime of the process. After Clear, SessionKey.Key no longer refers to
+// the pooled memory, callers that keep the key must copy it first.
+// Private key material is allocated by the OpenPGP library and is not
+// affected, use Key.ClearPrivateParams to zeroize it.
+func SetLockedMemory(enabled bool) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.lockedMemory = enabled
+}
+
+// IsInLockedMemory reports whether the session key is stored in locked memory.
+func (sk *SessionKey) IsInLockedMemory() bool {
+	return sk.lockedBuffer != nil && sk.lockedBuffer.IsLocked()
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func isLockedMemoryEnabled() bool {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+
+	return pgp.lockedMemory
+}
+
+// newSessionKey creates a session key, copying the key into locked memory
+// if enabled. The given key is then zeroized.
+func newSessionKey(key []byte, algo string, v6 bool) *SessionKey {
+	sk := &SessionKey{
+		V6:   v6,
+		Key:  key,
+		Algo: algo,
+	}
+	if isLockedMemoryEnabled() && len(key) > 0 {
+		sk.lockedBuffer = internal.NewLockedBuffer(len(key))
+		sk.Key = sk.lockedBuffer.Bytes()
+		copy(sk.Key, key)
+		internal.Wipe(key)
+	}
+	return sk
+}
@@ -0,0 +1,201 @@
+package crypto
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// Logger receives structured events about the operations of the package,
+// e.g. to debug interoperability issues in production.
+// The logger must be safe for concurrent use, and should return quickly,
+// as it is called synchronously by the operations.
+type Logger interface {
+	Log(event *LogEvent)
+}
+
+// LogEvent is an event sent to the Logger.
+type LogEvent struct {
+	// Level is one of the constants.LOG_LEVEL_* severities.
+	Level int
+	// Name is one of the constants.LogEvent* event names.
+	Name string
+	// Fields holds the details of the event, e.g. "keyID" or "cipher".
+	Fields map[string]string
+}
+
+// GetField returns the value of a field of the event, or "" if it is not set.
+func (event *LogEvent) GetField(name string) string {
+	return event.Fields[name]
+}
+
+// GetFieldNames returns the sorted names of the fields of the event,
+// separated by commas.
+func (event *LogEvent) GetFieldNames() string {
+	names := make([]string, 0, len(event.Fields))
+	for name := range event.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// SetLogger sets the logger receiving the events of the operations of the
+// package, see the constants.LogEvent* names. Passing nil disables logging,
+// which is the default.
+// The events never contain secret material or message contents.
+func SetLogger(logger Logger) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.logger = logger
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func getLogger() Logger {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+
+	return pgp.logger
+}
+
+// logEvent sends an event to the logger, if any. fields is a list of
+// alternating names and values.
+func logEvent(level int, name string, fields ...string) {
+	logger := getLogger()
+	if logger == nil {
+		return
+	}
+	event := &LogEvent{Level: level, Name: name, Fields: make(map[string]string, len(fields)/2)}
+	for i := 0; i+1 < len(fields); i += 2 {
+		event.Fields[fields[i]] = fields[i+1]
+	}
+	logger.Log(event)
+}
+
+// isLogging avoids computing the fields of events when there is no logger.
+func isLogging() bool {
+	return getLogger() != nil
+}
+
+// logMessageDetails reports the packets found while decrypting a message.
+func logMessageDetails(operation string, md *openpgp.MessageDetails) {
+	if !isLogging() {
+		return
+	}
+	encryptedTo := make([]string, len(md.EncryptedToKeyIds))
+	for i, keyID := range md.EncryptedToKeyIds {
+		encryptedTo[i] = keyIDToHex(keyID)
+	}
+	fields := []string{
+		"operation", operation,
+		"encrypted", strconv.FormatBool(md.IsEncrypted),
+		"encryptedTo", strings.Join(encryptedTo, ","),
+		"signed", strconv.FormatBool(md.IsSigned),
+	}
+	if md.DecryptedWith.PublicKey != nil {
+		fields = append(fields, "decryptedWith", keyIDToHex(md.DecryptedWith.PublicKey.KeyId))
+	}
+	if md.IsSigned {
+		fields = append(fields, "signedBy", keyIDToHex(md.SignedByKeyId))
+	}
+	if md.LiteralData != nil {
+		fields = append(fields, "literalFormat", string(rune(md.LiteralData.Format)))
+	}
+	logEvent(constants.LOG_LEVEL_DEBUG, constants.LogEventPacketParsed, fields...)
+}
+
+// logSignatureChecked reports the result of a signature verification.
+func logSignatureChecked(operation string, keyID uint64, err error) {
+	if !isLogging() {
+		return
+	}
+	fields := []string{"operation", operation}
+	if keyID != 0 {
+		fields = append(fields, "keyID", keyIDToHex(keyID))
+	}
+	if err == nil {
+		logEvent(constants.LOG_LEVEL_INFO, constants.LogEventSignatureChecked, append(fields, "status", "valid")...)
+		return
+	}
+	fields = append(fields, "status", "invalid", "error", err.Error())
+	if verificationError := (SignatureVerificationError{}); errors.As(err, &verificationError) {
+		fields = append(fields, "signatureStatus", signatureStatusToString(verificationError.Status))
+	}
+	logEvent(constants.LOG_LEVEL_WARNING, constants.LogEventSignatureChecked, fields...)
+}
+
+// logEncryption reports the algorithms and keys selected to encrypt a message,
+// at the time of the encryption.
+func logEncryption(operation string, config *packet.Config, publicKey *KeyRing, signEntity *openpgp.Entity) {
+	if !isLogging() {
+		return
+	}
+	logEncryptionAlgorithms(operation, config)
+	now := config.Now()
+	for _, entity := range publicKey.entities {
+		if key, ok := entity.EncryptionKey(now); ok {
+			logEvent(
+				constants.LOG_LEVEL_DEBUG, constants.LogEventKeySelected,
+				"operation", operation,
+				"keyID", keyIDToHex(key.PublicKey.KeyId),
+				"algorithm", strconv.Itoa(int(key.PublicKey.PubKeyAlgo)),
+			)
+		}
+	}
+	if signEntity != nil {
+		logSigning(operation, signEntity, config)
+	}
+}
+
+// logEncryptionAlgorithms reports the algorithms selected to encrypt a message.
+func logEncryptionAlgorithms(operation string, config *packet.Config) {
+	if !isLogging() {
+		return
+	}
+	logEvent(
+		constants.LOG_LEVEL_DEBUG, constants.LogEventAlgorithmSelected,
+		"operation", operation,
+		"cipher", getAlgo(config.Cipher()),
+		"compression", strconv.Itoa(int(config.Compression())),
+	)
+}
+
+// logSigning reports the algorithms and key selected to sign, at the time of
+// the signature.
+func logSigning(operation string, signEntity *openpgp.Entity, config *packet.Config) {
+	if !isLogging() {
+		return
+	}
+	fields := []string{"operation", operation, "hash", config.Hash().String()}
+	if key, ok := signEntity.SigningKey(config.Now()); ok {
+		fields = append(fields,
+			"keyID", keyIDToHex(key.PublicKey.KeyId),
+			"algorithm", strconv.Itoa(int(key.PublicKey.PubKeyAlgo)),
+		)
+	}
+	logEvent(constants.LOG_LEVEL_DEBUG, constants.LogEventKeySelected, fields...)
+}
+
+func signatureStatusToString(status int) string {
+	switch status {
+	case constants.SIGNATURE_OK:
+		return "ok"
+	case constants.SIGNATURE_NOT_SIGNED:
+		return "not signed"
+	case constants.SIGNATURE_NO_VERIFIER:
+		return "no verifier"
+	case constants.SIGNATURE_FAILED:
+		return "failed"
+	case constants.SIGNATURE_BAD_CONTEXT:
+		return "bad context"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogger struct {
+	lock   sync.Mutex
+	events []*LogEvent
+}
+
+func (logger *testLogger) Log(event *LogEvent) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	logger.events = append(logger.events, event)
+}
+
+func (logger *testLogger) find(name string) []*LogEvent {
+	var events []*LogEvent
+	for _, event := range logger.events {
+		if event.Name == name {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestLogger(t *testing.T) {
+	logger := &testLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = keyRingTestPrivate.Decrypt(ciphertext, keyRingTestPublic, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+
+	algorithms := logger.find(constants.LogEventAlgorithmSelected)
+	assert.Len(t, algorithms, 1)
+	assert.Exactly(t, constants.AES256, algorithms[0].GetField("cipher"))
+
+	keys := logger.find(constants.LogEventKeySelected)
+	assert.Len(t, keys, 2)
+	assert.Exactly(t, "encrypt", keys[0].GetField("operation"))
+	assert.NotEmpty(t, keys[1].GetField("hash"))
+
+	packets := logger.find(constants.LogEventPacketParsed)
+	assert.Len(t, packets, 1)
+	assert.Exactly(t, "true", packets[0].GetField("signed"))
+	assert.Exactly(t, "decryptedWith,encrypted,encryptedTo,literalFormat,operation,signed,signedBy", packets[0].GetFieldNames())
+	assert.Exactly(t, keys[0].GetField("keyID"), packets[0].GetField("decryptedWith"))
+
+	signatures := logger.find(constants.LogEventSignatureChecked)
+	assert.Len(t, signatures, 1)
+	assert.Exactly(t, constants.LOG_LEVEL_INFO, signatures[0].Level)
+	assert.Exactly(t, "valid", signatures[0].GetField("status"))
+
+	// Failed verifications are reported as warnings
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	assert.Error(t, keyRingTestPublic.VerifyDetached(NewPlainMessageFromString("wrong"), signature, GetUnixTime()))
+	signatures = logger.find(constants.LogEventSignatureChecked)
+	last := signatures[len(signatures)-1]
+	assert.Exactly(t, constants.LOG_LEVEL_WARNING, last.Level)
+	assert.Exactly(t, "failed", last.GetField("signatureStatus"))
+
+	// No events without logger
+	SetLogger(nil)
+	count := len(logger.events)
+	_, _ = keyRingTestPublic.Encrypt(message, nil)
+	assert.Len(t, logger.events, count)
+}
+
+func TestLoggerStreams(t *testing.T) {
+	logger := &testLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	var dataPacket bytes.Buffer
+	writer, err := sessionKey.EncryptStream(&dataPacket, nil, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	if _, err = writer.Write([]byte(testMessage)); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+	reader, err := sessionKey.DecryptStream(&dataPacket, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+
+	split, err := keyRingTestPublic.EncryptAttachment(NewPlainMessageFromString(testMessage), "file.txt")
+	if err != nil {
+		t.Fatal("Expected no error while encrypting attachment, got:", err)
+	}
+	if _, err = keyRingTestPrivate.DecryptAttachment(split); err != nil {
+		t.Fatal("Expected no error while decrypting attachment, got:", err)
+	}
+
+	var operations []string
+	for _, event := range logger.find(constants.LogEventAlgorithmSelected) {
+		operations = append(operations, event.GetField("operation"))
+	}
+	assert.Exactly(t, []string{"encrypt with session key", "encrypt attachment"}, operations)
+	assert.Len(t, logger.find(constants.LogEventKeySelected), 2)
+
+	operations = nil
+	for _, event := range logger.find(constants.LogEventPacketParsed) {
+		operations = append(operations, event.GetField("operation"))
+	}
+	assert.Exactly(t, []string{"decrypt with session key", "decrypt attachment"}, operations)
+}
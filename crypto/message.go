@@ -27,10 +27,26 @@ type PlainMessage struct {
 	Data []byte
 	// If the content is text or binary
 	TextType bool
+	// If the content is marked as UTF-8 text
+	IsUTF8 bool
 	// The file's latest modification time
 	Time uint32
 	// The encrypted message's filename
 	Filename string
+
+	// insecureLegacyAlgorithm is set when the message was decrypted with
+	// an ElGamal key.
+	insecureLegacyAlgorithm bool
+	// signature is the embedded signature checked during decryption.
+	signature *packet.Signature
+	// unverifiedSignatures are the other embedded signatures, which are
+	// not checked during decryption.
+	unverifiedSignatures []*packet.Signature
+	// signer is the key that made the embedded signature, if valid.
+	signer *SignatureSigner
+	// integrityWarning is set when the message may have been downgraded
+	// from AEAD.
+	integrityWarning *IntegrityWarning
 }
 
 // PGPMessage stores a PGP-encrypted message.
@@ -91,10 +107,12 @@ func NewPlainMessageFromFile(data []byte, filename string, time uint32) *PlainMe
 // This will encrypt the message with the text flag, canonicalize the line endings
 // (i.e. set all of them to \r\n) and strip the trailing spaces for each line.
 // This allows seamless conversion to clear text signed messages (see RFC 4880 5.2.1 and 7.1).
+// Invalid UTF-8 sequences are replaced, as the message is marked as UTF-8 text.
 func NewPlainMessageFromString(text string) *PlainMessage {
 	return &PlainMessage{
-		Data:     []byte(internal.Canonicalize(text)),
+		Data:     []byte(internal.Canonicalize(sanitizeString(text))),
 		TextType: true,
+		IsUTF8:   true,
 		Filename: "",
 		Time:     uint32(GetUnixTime()),
 	}
@@ -113,6 +131,9 @@ func NewPGPMessageFromArmored(armored string) (*PGPMessage, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in unarmoring message")
 	}
+	if err := internal.CheckArmorType(encryptedIO.Type, constants.PGPMessageHeader); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in unarmoring message")
+	}
 
 	message, err := ioutil.ReadAll(encryptedIO.Body)
 	if err != nil {
@@ -158,6 +179,9 @@ func NewPGPSignatureFromArmored(armored string) (*PGPSignature, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in unarmoring signature")
 	}
+	if err := internal.CheckArmorType(encryptedIO.Type, constants.PGPSignatureHeader); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in unarmoring signature")
+	}
 
 	signature, err := ioutil.ReadAll(encryptedIO.Body)
 	if err != nil {
@@ -182,8 +206,16 @@ func NewClearTextMessage(data []byte, signature []byte) *ClearTextMessage {
 // signature from a clearsigned message.
 func NewClearTextMessageFromArmored(signedMessage string) (*ClearTextMessage, error) {
 	modulusBlock, rest := clearsign.Decode([]byte(signedMessage))
+	if modulusBlock == nil {
+		if armorType := internal.GetArmorType(signedMessage); armorType != "" {
+			if err := internal.CheckArmorType(armorType, constants.PGPSignedMessageHeader); err != nil {
+				return nil, errors.Wrap(err, "gopenpgp: error in reading cleartext message")
+			}
+		}
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: no cleartext signed message found", nil)
+	}
 	if len(rest) != 0 {
-		return nil, errors.New("gopenpgp: extra data after modulus")
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: extra data after modulus", nil)
 	}
 
 	signature, err := ioutil.ReadAll(modulusBlock.ArmoredSignature.Body)
@@ -227,6 +259,25 @@ func (msg *PlainMessage) IsBinary() bool {
 	return !msg.TextType
 }
 
+// canonicalizeText returns the message as signed with the given text
+// canonicalization. Binary messages are returned unchanged.
+func (msg *PlainMessage) canonicalizeText(canonicalization int) (*PlainMessage, error) {
+	switch canonicalization {
+	case constants.TextCanonicalizationStrict:
+		// The line endings are converted when hashing
+		return msg, nil
+	case constants.TextCanonicalizationTrimTrailingWhitespace:
+		if msg.IsBinary() {
+			return msg, nil
+		}
+		canonicalMessage := *msg
+		canonicalMessage.Data = []byte(internal.Canonicalize(internal.TrimEachLine(string(msg.Data))))
+		return &canonicalMessage, nil
+	default:
+		return nil, errors.New("gopenpgp: unknown text canonicalization")
+	}
+}
+
 // getFormattedTime returns the message (latest modification) Time as time.Time.
 func (msg *PlainMessage) getFormattedTime() time.Time {
 	return time.Unix(int64(msg.Time), 0)
@@ -338,7 +389,12 @@ func (msg *PGPSplitMessage) GetBinaryKeyPacket() []byte {
 }
 
 // GetBinary returns the unarmored binary joined packets as a []byte.
+// If the packets are contiguous in memory, e.g. after SplitMessageInPlace,
+// they are joined without copying.
 func (msg *PGPSplitMessage) GetBinary() []byte {
+	if joined := joinContiguous(msg.KeyPacket, msg.DataPacket); joined != nil {
+		return joined
+	}
 	return append(msg.KeyPacket, msg.DataPacket...)
 }
 
@@ -351,7 +407,7 @@ func (msg *PGPSplitMessage) GetArmored() (string, error) {
 // GetPGPMessage joins asymmetric session key packet with the symmetric data
 // packet to obtain a PGP message.
 func (msg *PGPSplitMessage) GetPGPMessage() *PGPMessage {
-	return NewPGPMessage(append(msg.KeyPacket, msg.DataPacket...))
+	return NewPGPMessage(msg.GetBinary())
 }
 
 // GetNumberOfKeyPackets returns the number of keys packets in this message.
@@ -430,6 +486,17 @@ func (sig *PGPSignature) GetHexSignatureKeyIDs() ([]string, bool) {
 	return getHexKeyIDs(sig.GetSignatureKeyIDs())
 }
 
+// GetSalt returns the salt of the first signature packet, hashed before the
+// signed data, or nil if it is not a v6 signature. Its length depends on the
+// hash algorithm of the signature.
+func (sig *PGPSignature) GetSalt() ([]byte, error) {
+	signature, err := parseSignaturePacket(sig)
+	if err != nil {
+		return nil, err
+	}
+	return signature.Salt(), nil
+}
+
 // GetBinary returns the unarmored signed data as a []byte.
 func (msg *ClearTextMessage) GetBinary() []byte {
 	return msg.Data
@@ -514,3 +581,16 @@ func getHexKeyIDs(keyIDs []uint64, ok bool) ([]string, bool) {
 
 	return hexIDs, ok
 }
+
+// parseSignaturePacket parses the first packet of a detached signature.
+func parseSignaturePacket(signature *PGPSignature) (*packet.Signature, error) {
+	p, err := packet.Read(bytes.NewReader(signature.GetBinary()))
+	if err != nil {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: error in reading signature", err)
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: not a signature packet", nil)
+	}
+	return sig, nil
+}
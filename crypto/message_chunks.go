@@ -0,0 +1,126 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ChunkManifest lists the chunks of a message uploaded in chunks, in order,
+// e.g. to resume an upload and check that no chunk is missing, reordered or
+// modified before reassembling them.
+type ChunkManifest struct {
+	// ChunkHashes contains the hex SHA-256 hashes of the data packets of the
+	// chunks, in order.
+	ChunkHashes []string
+}
+
+// NewChunkManifest creates an empty manifest.
+func NewChunkManifest() *ChunkManifest {
+	return &ChunkManifest{}
+}
+
+// AddChunk appends the data packet of the next chunk to the manifest.
+func (manifest *ChunkManifest) AddChunk(dataPacket []byte) {
+	manifest.ChunkHashes = append(manifest.ChunkHashes, getChunkHash(dataPacket))
+}
+
+// CountChunks returns the number of chunks of the manifest.
+func (manifest *ChunkManifest) CountChunks() int {
+	return len(manifest.ChunkHashes)
+}
+
+// ChunkReassembler reassembles the chunks of a message uploaded in chunks,
+// e.g. of 4 MB, into a single data packet. Each chunk is a data packet
+// encrypted independently with the same session key, e.g. with
+// SessionKey.Encrypt. The reassembled data packet is encrypted with the
+// session key, so that it forms a valid OpenPGP message with the key
+// packets of the session key.
+// Each chunk is decrypted and its integrity checked before its plaintext is
+// added to the reassembled message.
+type ChunkReassembler struct {
+	sessionKey *SessionKey
+	writer     WriteCloser
+	manifest   *ChunkManifest
+	count      int
+}
+
+// NewChunkReassembler creates a reassembler writing the reassembled data
+// packet to dataPacketWriter, with the given metadata.
+func NewChunkReassembler(
+	sessionKey *SessionKey,
+	dataPacketWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+) (*ChunkReassembler, error) {
+	return NewChunkReassemblerWithManifest(sessionKey, dataPacketWriter, plainMessageMetadata, nil)
+}
+
+// NewChunkReassemblerWithManifest creates a reassembler writing the
+// reassembled data packet to dataPacketWriter, with the given metadata.
+// The chunks are checked against manifest, if not nil: they must be added
+// in the order of the manifest, and the reassembly fails if a chunk is
+// missing or differs from the manifest.
+func NewChunkReassemblerWithManifest(
+	sessionKey *SessionKey,
+	dataPacketWriter Writer,
+	plainMessageMetadata *PlainMessageMetadata,
+	manifest *ChunkManifest,
+) (*ChunkReassembler, error) {
+	writer, err := sessionKey.EncryptStream(dataPacketWriter, plainMessageMetadata, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to reassemble chunks")
+	}
+	return &ChunkReassembler{sessionKey: sessionKey, writer: writer, manifest: manifest}, nil
+}
+
+// AddChunk decrypts the data packet of the next chunk, and adds its
+// plaintext to the reassembled message. Nothing is added if the chunk
+// can't be decrypted or authenticated: the chunk can then be added again.
+func (reassembler *ChunkReassembler) AddChunk(dataPacket []byte) error {
+	if reassembler.manifest != nil {
+		if reassembler.count >= reassembler.manifest.CountChunks() {
+			return newClassifiedError(ErrMessageCorrupt, "gopenpgp: chunk not in the manifest", nil)
+		}
+		if reassembler.manifest.ChunkHashes[reassembler.count] != getChunkHash(dataPacket) {
+			return newClassifiedError(
+				ErrMessageCorrupt,
+				"gopenpgp: chunk "+strconv.Itoa(reassembler.count)+" does not match the manifest",
+				nil,
+			)
+		}
+	}
+
+	// The chunk is authenticated entirely before any of its plaintext is
+	// written, so that a tampered chunk can be retried.
+	plainMessage, err := reassembler.sessionKey.Decrypt(dataPacket)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to decrypt chunk "+strconv.Itoa(reassembler.count))
+	}
+	if _, err = reassembler.writer.Write(plainMessage.GetBinary()); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to reassemble chunk "+strconv.Itoa(reassembler.count))
+	}
+	reassembler.count++
+	return nil
+}
+
+// Close finalizes the reassembled data packet. It fails if chunks of the
+// manifest are missing, in which case the reassembled data packet must be
+// discarded.
+func (reassembler *ChunkReassembler) Close() error {
+	if reassembler.manifest != nil && reassembler.count != reassembler.manifest.CountChunks() {
+		return newClassifiedError(ErrMessageCorrupt, "gopenpgp: chunks of the manifest are missing", nil)
+	}
+	if err := reassembler.writer.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to finalize reassembled message")
+	}
+	return nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func getChunkHash(dataPacket []byte) string {
+	hash := sha256.Sum256(dataPacket)
+	return hex.EncodeToString(hash[:])
+}
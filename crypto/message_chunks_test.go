@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkReassembler(t *testing.T) {
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+
+	plainChunks := []string{"first chunk, ", "second chunk, ", "third chunk"}
+	manifest := NewChunkManifest()
+	var chunks [][]byte
+	for _, plainChunk := range plainChunks {
+		chunk, err := sessionKey.Encrypt(NewPlainMessage([]byte(plainChunk)))
+		if err != nil {
+			t.Fatal("Expected no error while encrypting chunk, got:", err)
+		}
+		manifest.AddChunk(chunk)
+		chunks = append(chunks, chunk)
+	}
+
+	var dataPacket bytes.Buffer
+	metadata := NewPlainMessageMetadata(true, "chunks.bin", GetUnixTime())
+	reassembler, err := NewChunkReassemblerWithManifest(sessionKey, &dataPacket, metadata, manifest)
+	if err != nil {
+		t.Fatal("Expected no error while creating reassembler, got:", err)
+	}
+	for _, chunk := range chunks {
+		if err = reassembler.AddChunk(chunk); err != nil {
+			t.Fatal("Expected no error while adding chunk, got:", err)
+		}
+	}
+	if err = reassembler.Close(); err != nil {
+		t.Fatal("Expected no error while closing reassembler, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.Decrypt(NewPGPSplitMessage(keyPacket, dataPacket.Bytes()).GetPGPMessage(), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting reassembled message, got:", err)
+	}
+	assert.Exactly(t, "first chunk, second chunk, third chunk", decrypted.GetString())
+	assert.Exactly(t, "chunks.bin", decrypted.GetFilename())
+
+	reassembler, err = NewChunkReassemblerWithManifest(sessionKey, &bytes.Buffer{}, nil, manifest)
+	if err != nil {
+		t.Fatal("Expected no error while creating reassembler, got:", err)
+	}
+	err = reassembler.AddChunk(chunks[1])
+	assert.True(t, errors.Is(err, ErrMessageCorrupt))
+	assert.NoError(t, reassembler.AddChunk(chunks[0]))
+	assert.True(t, errors.Is(reassembler.Close(), ErrMessageCorrupt))
+
+	tampered := append([]byte{}, chunks[0]...)
+	tampered[len(tampered)-1] ^= 1
+	var retried bytes.Buffer
+	reassembler, err = NewChunkReassembler(sessionKey, &retried, nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating reassembler, got:", err)
+	}
+	assert.Error(t, reassembler.AddChunk(tampered))
+	// Nothing of the tampered chunk is added, the chunk can be retried
+	assert.NoError(t, reassembler.AddChunk(chunks[0]))
+	assert.NoError(t, reassembler.AddChunk(chunks[1]))
+	if err = reassembler.Close(); err != nil {
+		t.Fatal("Expected no error while closing reassembler, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.Decrypt(NewPGPSplitMessage(keyPacket, retried.Bytes()).GetPGPMessage(), nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting reassembled message, got:", err)
+	}
+	assert.Exactly(t, "first chunk, second chunk, ", decrypted.GetString())
+}
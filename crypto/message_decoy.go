@@ -0,0 +1,215 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// AddDecoyRecipients returns a copy of the message with count additional
+// public key encrypted session key packets, for random key IDs, so that
+// observers can't count the real recipients of the message.
+// Each decoy copies the format of a real key packet of the message, with
+// random key ID and encrypted session key, and the key packets are shuffled.
+// Decryption skips the decoys, as no key matches their key IDs.
+func (msg *PGPMessage) AddDecoyRecipients(count int) (*PGPMessage, error) {
+	splitPoint, err := msg.getSplitPoint()
+	if err != nil {
+		return nil, err
+	}
+
+	var keyPackets, templates [][]byte
+	for offset := 0; offset < splitPoint; {
+		tag, next, err := nextPacketOffset(msg.Data, offset)
+		if err != nil {
+			return nil, err
+		}
+		keyPackets = append(keyPackets, msg.Data[offset:next])
+		if tag == packetTagEncryptedKey {
+			body, err := getPacketBody(msg.Data, offset, next)
+			if err != nil {
+				return nil, err
+			}
+			templates = append(templates, body)
+		}
+		offset = next
+	}
+	if len(templates) == 0 {
+		return nil, errors.New("gopenpgp: the message has no public key encrypted session key")
+	}
+
+	config := &packet.Config{Rand: getRandomSource()}
+	applyConfigModifier(config)
+	random := config.Random()
+	for i := 0; i < count; i++ {
+		index, err := rand.Int(random, big.NewInt(int64(len(templates))))
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in generating decoy")
+		}
+		decoy, err := newDecoyEncryptedKey(templates[index.Int64()], random)
+		if err != nil {
+			return nil, err
+		}
+		var decoyPacket bytes.Buffer
+		writePacketHeader(&decoyPacket, packetTagEncryptedKey, len(decoy))
+		decoyPacket.Write(decoy)
+		keyPackets = append(keyPackets, decoyPacket.Bytes())
+	}
+
+	if err := shuffle(keyPackets, random); err != nil {
+		return nil, err
+	}
+
+	var data bytes.Buffer
+	for _, keyPacket := range keyPackets {
+		data.Write(keyPacket)
+	}
+	data.Write(msg.Data[splitPoint:])
+	return NewPGPMessage(data.Bytes()), nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// newDecoyEncryptedKey returns a copy of the body of a v3 or v6 public key
+// encrypted session key packet, with a random recipient and random
+// encrypted session key of the same format.
+func newDecoyEncryptedKey(template []byte, random io.Reader) ([]byte, error) {
+	errTruncated := newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated key packet", nil)
+	decoy := clone(template)
+	if len(decoy) == 0 {
+		return nil, errTruncated
+	}
+
+	var recipient []byte
+	var fieldsStart int
+	switch decoy[0] {
+	case 3:
+		// version, key ID, algorithm
+		if len(decoy) < 10 {
+			return nil, errTruncated
+		}
+		recipient, fieldsStart = decoy[1:9], 10
+	case 6:
+		// version, length, key version and fingerprint, algorithm
+		if len(decoy) < 2 || len(decoy) < 3+int(decoy[1]) {
+			return nil, errTruncated
+		}
+		if decoy[1] > 0 {
+			recipient = decoy[3 : 2+int(decoy[1])]
+		}
+		fieldsStart = 3 + int(decoy[1])
+	default:
+		return nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: unsupported key packet version", nil)
+	}
+
+	if _, err := io.ReadFull(random, recipient); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in generating decoy")
+	}
+	algorithm := packet.PublicKeyAlgorithm(decoy[fieldsStart-1])
+	if err := randomizeEncryptedKeyFields(decoy[fieldsStart:], algorithm, decoy[0] == 3, random); err != nil {
+		return nil, err
+	}
+	return decoy, nil
+}
+
+// randomizeEncryptedKeyFields replaces the algorithm specific fields of a
+// public key encrypted session key with random values of the same format.
+func randomizeEncryptedKeyFields(fields []byte, algorithm packet.PublicKeyAlgorithm, v3 bool, random io.Reader) error {
+	errTruncated := newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated key packet", nil)
+	offset := 0
+	switch algorithm {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoElGamal:
+		for offset < len(fields) {
+			next, err := randomizeMPI(fields, offset, false, random)
+			if err != nil {
+				return err
+			}
+			offset = next
+		}
+		return nil
+	case packet.PubKeyAlgoECDH:
+		if len(fields) < 3 {
+			return errTruncated
+		}
+		next, err := randomizeMPI(fields, offset, true, random)
+		if err != nil {
+			return err
+		}
+		offset = next
+	case packet.PubKeyAlgoX25519, packet.PubKeyAlgoX448:
+		offset = 32
+		if algorithm == packet.PubKeyAlgoX448 {
+			offset = 56
+		}
+		if len(fields) < offset {
+			return errTruncated
+		}
+		if _, err := io.ReadFull(random, fields[:offset]); err != nil {
+			return errors.Wrap(err, "gopenpgp: error in generating decoy")
+		}
+	default:
+		return newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: unsupported key packet algorithm", nil)
+	}
+
+	// Length prefixed wrapped session key
+	if offset >= len(fields) || offset+1+int(fields[offset]) != len(fields) {
+		return errTruncated
+	}
+	wrapped := fields[offset+1:]
+	if v3 && algorithm != packet.PubKeyAlgoECDH && len(wrapped) > 0 {
+		// Keep the cleartext cipher algorithm
+		wrapped = wrapped[1:]
+	}
+	if _, err := io.ReadFull(random, wrapped); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in generating decoy")
+	}
+	return nil
+}
+
+// randomizeMPI replaces the value of the MPI starting at offset with a
+// random value of the same bit length, and returns the offset of the end of
+// the MPI. If isPoint is true, the first octet, the prefix of the encoded
+// curve point, is kept.
+func randomizeMPI(data []byte, offset int, isPoint bool, random io.Reader) (int, error) {
+	if offset+2 > len(data) {
+		return 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated key packet", nil)
+	}
+	bitLength := int(binary.BigEndian.Uint16(data[offset:]))
+	start, end := offset+2, offset+2+(bitLength+7)/8
+	if end > len(data) || start == end {
+		return 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated key packet", nil)
+	}
+
+	if isPoint {
+		start++
+	}
+	if _, err := io.ReadFull(random, data[start:end]); err != nil {
+		return 0, errors.Wrap(err, "gopenpgp: error in generating decoy")
+	}
+	if !isPoint {
+		// Keep the bit length
+		topBits := bitLength % 8
+		if topBits == 0 {
+			topBits = 8
+		}
+		data[start] = data[start]&byte(1<<topBits-1) | byte(1<<(topBits-1))
+	}
+	return end, nil
+}
+
+// shuffle shuffles the packets with the Fisher-Yates algorithm.
+func shuffle(packets [][]byte, random io.Reader) error {
+	for i := len(packets) - 1; i > 0; i-- {
+		j, err := rand.Int(random, big.NewInt(int64(i+1)))
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: error in shuffling key packets")
+		}
+		packets[i], packets[j.Int64()] = packets[j.Int64()], packets[i]
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddDecoyRecipients(t *testing.T) {
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+
+	for _, key := range []*Key{keyTestEC, keyTestRSA, keyV6} {
+		keyRing, err := NewKeyRing(key)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		ciphertext, err := keyRing.Encrypt(NewPlainMessageFromString("hello"), nil)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+
+		withDecoys, err := ciphertext.AddDecoyRecipients(3)
+		if err != nil {
+			t.Fatal("Expected no error while adding decoys, got:", err)
+		}
+
+		keyIDs := make(map[uint64]bool)
+		packets := packet.NewReader(bytes.NewReader(withDecoys.GetBinary()))
+		for {
+			p, err := packets.Next()
+			if err != nil {
+				t.Fatal("Expected no error while parsing key packets, got:", err)
+			}
+			encryptedKey, ok := p.(*packet.EncryptedKey)
+			if !ok {
+				break
+			}
+			keyIDs[encryptedKey.KeyId] = true
+		}
+		assert.Len(t, keyIDs, 4)
+
+		decrypted, err := keyRing.Decrypt(withDecoys, nil, 0)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, "hello", decrypted.GetString())
+	}
+
+	_, err = NewPGPMessage([]byte{}).AddDecoyRecipients(1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+// IntegrityWarning reports that a message lacks the integrity protection it
+// should have, like the warnings of GnuPG.
+// Messages without integrity protection, or whose modification detection
+// code was stripped, are never decrypted: decryption fails with an error
+// wrapping an IntegrityWarning, which is ErrMessageCorrupt for errors.Is.
+// Messages which may have been downgraded from AEAD to a modification
+// detection code are decrypted, and the warning is returned by
+// PlainMessage.GetIntegrityWarning.
+type IntegrityWarning struct {
+	// Reason is constants.IntegrityWarningMDCMissing or
+	// constants.IntegrityWarningAEADDowngrade.
+	Reason string
+
+	cause error
+}
+
+// Error is the base method for all errors.
+func (w IntegrityWarning) Error() string {
+	return "gopenpgp: " + w.Reason
+}
+
+// Is reports whether target is the class of the error.
+func (w IntegrityWarning) Is(target error) bool {
+	return target == ErrMessageCorrupt && w.Reason == constants.IntegrityWarningMDCMissing
+}
+
+func (w IntegrityWarning) Unwrap() error {
+	return w.cause
+}
+
+// HasIntegrityWarning returns true if the decrypted message has an integrity
+// warning, see GetIntegrityWarning.
+func (msg *PlainMessage) HasIntegrityWarning() bool {
+	return msg.integrityWarning != nil
+}
+
+// GetIntegrityWarning returns a warning if the message, decrypted with a
+// key advertising support for SEIPDv2, was only protected with a
+// modification detection code (SEIPDv1). This may be a downgrade by an
+// attacker, or an implementation of the sender not supporting SEIPDv2.
+// Returns nil otherwise, or if the message was not decrypted by Decrypt or
+// DecryptWithContext.
+func (msg *PlainMessage) GetIntegrityWarning() *IntegrityWarning {
+	return msg.integrityWarning
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getIntegrityWarning returns the integrity warning of the message data
+// decrypted as md, or nil if there is none.
+func getIntegrityWarning(data []byte, md *openpgp.MessageDetails) *IntegrityWarning {
+	if md.DecryptedWith.Entity == nil || !supportsSEIPDv2(md.DecryptedWith.Entity) {
+		return nil
+	}
+	for offset := 0; offset < len(data); {
+		tag, next, err := nextPacketOffset(data, offset)
+		if err != nil {
+			return nil
+		}
+		switch tag {
+		case packetTagSEIPD:
+			if version, ok := getPacketVersion(data, offset); ok && version == 1 {
+				return &IntegrityWarning{Reason: constants.IntegrityWarningAEADDowngrade}
+			}
+			return nil
+		case packetTagAEADEncrypted, packetTagSymmetricallyEncrypted, packetTagCompressed, packetTagLiteralData:
+			return nil
+		}
+		offset = next
+	}
+	return nil
+}
+
+// newIntegrityError returns the error of a message without integrity
+// protection, or whose modification detection code was stripped.
+func newIntegrityError(message string, cause error) error {
+	return newClassifiedError(
+		ErrMessageCorrupt,
+		message,
+		IntegrityWarning{Reason: constants.IntegrityWarningMDCMissing, cause: cause},
+	)
+}
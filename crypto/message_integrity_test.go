@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrityWarning(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.False(t, decrypted.HasIntegrityWarning())
+
+	keyV6, err := GenerateKeyV6(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRingV6, err := NewKeyRing(keyV6)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	ciphertext, err = keyRingV6.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err = keyRingV6.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.False(t, decrypted.HasIntegrityWarning())
+
+	// SEIPDv1 while the key advertises SEIPDv2
+	keyRingV6.ForceSEIPDv1()
+	ciphertext, err = keyRingV6.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err = keyRingV6.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessage, decrypted.GetString())
+	assert.True(t, decrypted.HasIntegrityWarning())
+	assert.Exactly(t, constants.IntegrityWarningAEADDowngrade, decrypted.GetIntegrityWarning().Reason)
+}
+
+func TestIntegrityProtectionMissing(t *testing.T) {
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	// Symmetrically encrypted data packet, without integrity protection
+	dataPacket := append([]byte{0xc9, 0x20}, make([]byte, 32)...)
+
+	_, err = keyRingTestPrivate.Decrypt(NewPGPMessage(append(keyPacket, dataPacket...)), nil, 0)
+	checkIntegrityWarning(t, err)
+	_, err = sessionKey.Decrypt(dataPacket)
+	checkIntegrityWarning(t, err)
+}
+
+func checkIntegrityWarning(t *testing.T, err error) {
+	var warning IntegrityWarning
+	if !errors.As(err, &warning) {
+		t.Fatal("Expected an integrity warning, got:", err)
+	}
+	assert.Exactly(t, constants.IntegrityWarningMDCMissing, warning.Reason)
+	assert.True(t, errors.Is(err, ErrMessageCorrupt))
+}
@@ -0,0 +1,188 @@
+package crypto
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// maxFilenameLength is the maximum length in bytes of the filename of a
+// literal data packet.
+const maxFilenameLength = 255
+
+// NewDefaultPlainMessageMetadata returns the metadata used when none is given:
+// binary data, without filename, modified now.
+// The returned metadata can be customized with the With* methods, e.g.
+//
+//	NewDefaultPlainMessageMetadata().WithFilename("notes.txt").WithUTF8()
+func NewDefaultPlainMessageMetadata() *PlainMessageMetadata {
+	return &PlainMessageMetadata{
+		IsBinary: true,
+		Filename: "",
+		ModTime:  GetUnixTime(),
+	}
+}
+
+// WithFilename sets the filename of the message. The name is sanitized:
+// directory components and control characters are removed, invalid UTF-8 is
+// replaced, and the name is truncated to the 255 bytes that fit in the
+// literal data packet, without splitting a character.
+func (metadata *PlainMessageMetadata) WithFilename(filename string) *PlainMessageMetadata {
+	metadata.Filename = sanitizeFilename(filename)
+	return metadata
+}
+
+// WithModTime sets the modification time of the message, in unix seconds.
+func (metadata *PlainMessageMetadata) WithModTime(modTime int64) *PlainMessageMetadata {
+	metadata.ModTime = modTime
+	return metadata
+}
+
+// WithBinary marks the message as binary data ('b' format).
+func (metadata *PlainMessageMetadata) WithBinary() *PlainMessageMetadata {
+	metadata.IsBinary = true
+	metadata.IsUTF8 = false
+	return metadata
+}
+
+// WithUTF8 marks the message as UTF-8 text ('u' format).
+// Text messages are always written with the 'u' format, as the legacy 't'
+// format is deprecated; it is only reported when decrypting.
+// Encrypting data that is not valid UTF-8 with this metadata fails.
+func (metadata *PlainMessageMetadata) WithUTF8() *PlainMessageMetadata {
+	metadata.IsBinary = false
+	metadata.IsUTF8 = true
+	return metadata
+}
+
+// GetMetadata returns the literal metadata of the message.
+func (msg *PlainMessage) GetMetadata() *PlainMessageMetadata {
+	return &PlainMessageMetadata{
+		IsBinary: !msg.TextType,
+		IsUTF8:   msg.IsUTF8,
+		Filename: msg.Filename,
+		ModTime:  int64(msg.Time),
+	}
+}
+
+// NewPlainMessageWithMetadata generates a new PlainMessage from the
+// unencrypted data, with the given literal metadata. Unlike
+// NewPlainMessageFromString, text data is not canonicalized, and encrypting
+// text data that is not valid UTF-8 fails.
+func NewPlainMessageWithMetadata(data []byte, metadata *PlainMessageMetadata) *PlainMessage {
+	if metadata == nil {
+		metadata = NewDefaultPlainMessageMetadata()
+	}
+	return &PlainMessage{
+		Data:     clone(data),
+		TextType: !metadata.IsBinary,
+		IsUTF8:   !metadata.IsBinary,
+		Filename: sanitizeFilename(metadata.Filename),
+		Time:     uint32(metadata.ModTime),
+	}
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// sanitizeFilename keeps only the base name of filename, without control
+// characters, and truncates it to maxFilenameLength bytes.
+func sanitizeFilename(filename string) string {
+	filename = sanitizeString(filename)
+	if i := strings.LastIndexAny(filename, `/\`); i >= 0 {
+		filename = filename[i+1:]
+	}
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filename)
+	if filename == "." || filename == ".." {
+		return ""
+	}
+	for len(filename) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(filename)
+		filename = filename[:len(filename)-size]
+	}
+	return filename
+}
+
+// errInvalidUTF8 is returned when encrypting data marked as UTF-8 text that
+// is not valid UTF-8.
+var errInvalidUTF8 = errors.New("gopenpgp: data marked as UTF-8 is not valid UTF-8")
+
+// checkUTF8 returns an error if the message is marked as UTF-8 text but its
+// data is not valid UTF-8.
+func (msg *PlainMessage) checkUTF8() error {
+	if msg.IsUTF8 && !utf8.Valid(msg.Data) {
+		return errInvalidUTF8
+	}
+	return nil
+}
+
+// newUTF8WriteCloser wraps the plaintext writer so that it rejects data that
+// is not valid UTF-8 if the metadata marks it as UTF-8 text.
+func newUTF8WriteCloser(writer WriteCloser, plainMessageMetadata *PlainMessageMetadata) WriteCloser {
+	if plainMessageMetadata == nil || !plainMessageMetadata.IsUTF8 {
+		return writer
+	}
+	return &utf8WriteCloser{writer: writer}
+}
+
+// utf8WriteCloser checks that the written data is valid UTF-8, keeping the
+// start of a character split across writes until it is complete.
+type utf8WriteCloser struct {
+	writer  WriteCloser
+	partial []byte
+}
+
+func (w *utf8WriteCloser) Write(b []byte) (int, error) {
+	data := b
+	if len(w.partial) > 0 {
+		n := utf8.UTFMax - len(w.partial)
+		if n > len(b) {
+			n = len(b)
+		}
+		head := append(w.partial, b[:n]...)
+		r, size := utf8.DecodeRune(head)
+		switch {
+		case !utf8.FullRune(head):
+			w.partial = head
+			data = nil
+		case r == utf8.RuneError && size == 1:
+			return 0, errInvalidUTF8
+		default:
+			data = b[size-len(w.partial):]
+			w.partial = nil
+		}
+	}
+	end := len(data)
+	// Only the last utf8.UTFMax-1 bytes can be the start of a split character
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				end = len(data) - i
+			}
+			break
+		}
+	}
+	if !utf8.Valid(data[:end]) {
+		return 0, errInvalidUTF8
+	}
+	if end < len(data) {
+		w.partial = append([]byte(nil), data[end:]...)
+	}
+	if _, err := w.writer.Write(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *utf8WriteCloser) Close() error {
+	if len(w.partial) > 0 {
+		return errInvalidUTF8
+	}
+	return w.writer.Close()
+}
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainMessageMetadataBuilder(t *testing.T) {
+	metadata := NewDefaultPlainMessageMetadata().
+		WithFilename("../secret/notes\x00.txt").
+		WithModTime(1234567890).
+		WithUTF8()
+	assert.Exactly(t, "notes.txt", metadata.Filename)
+	assert.Exactly(t, int64(1234567890), metadata.ModTime)
+	assert.False(t, metadata.IsBinary)
+	assert.True(t, metadata.IsUTF8)
+
+	assert.Exactly(t, "report.pdf", NewDefaultPlainMessageMetadata().WithFilename(`C:\Users\alice\report.pdf`).Filename)
+	assert.Exactly(t, "", NewDefaultPlainMessageMetadata().WithFilename("..").Filename)
+
+	long := NewDefaultPlainMessageMetadata().WithFilename(strings.Repeat("é", 200)).Filename
+	assert.Exactly(t, strings.Repeat("é", 127), long)
+
+	binary := NewDefaultPlainMessageMetadata().WithUTF8().WithBinary()
+	assert.True(t, binary.IsBinary)
+	assert.False(t, binary.IsUTF8)
+}
+
+func TestPlainMessageMetadataRoundTrip(t *testing.T) {
+	metadata := NewDefaultPlainMessageMetadata().WithFilename("notes.txt").WithModTime(1234567890).WithUTF8()
+
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessageWithMetadata([]byte("hello"), metadata), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, metadata, decrypted.GetMetadata())
+
+	var buffer bytes.Buffer
+	writer, err := keyRingTestPublic.EncryptStream(&buffer, metadata, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	if _, err = writer.Write([]byte("hello")); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+	reader, err := keyRingTestPrivate.DecryptStream(&buffer, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	assert.Exactly(t, metadata, reader.GetMetadata())
+}
+
+func TestPlainMessageMetadataInvalidUTF8(t *testing.T) {
+	metadata := NewDefaultPlainMessageMetadata().WithUTF8()
+
+	if _, err := keyRingTestPublic.Encrypt(NewPlainMessageWithMetadata([]byte("h\xffllo"), metadata), nil); err == nil {
+		t.Fatal("Expected an error while encrypting invalid UTF-8 text")
+	}
+	assert.Exactly(t, "h�llo", NewPlainMessageFromString("h\xffllo").GetString())
+
+	var buffer bytes.Buffer
+	writer, err := keyRingTestPublic.EncryptStream(&buffer, metadata, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	// A character split across writes is accepted
+	for _, chunk := range []string{"h\xc3", "\xa9llo \xe2\x82", "\xac"} {
+		if _, err = writer.Write([]byte(chunk)); err != nil {
+			t.Fatal("Expected no error while writing, got:", err)
+		}
+	}
+	if _, err = writer.Write([]byte("\xff")); err == nil {
+		t.Fatal("Expected an error while writing invalid UTF-8 text")
+	}
+
+	writer, err = keyRingTestPublic.EncryptStream(&buffer, metadata, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+	if _, err = writer.Write([]byte("h\xc3")); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	if err = writer.Close(); err == nil {
+		t.Fatal("Expected an error while closing a truncated character")
+	}
+}
@@ -0,0 +1,91 @@
+package crypto
+
+// OpenPGP packet tags relevant to splitting concatenated messages.
+const (
+	packetTagOnePassSignature = 4
+	packetTagCompressed       = 8
+	packetTagLiteralData      = 11
+)
+
+// MessageResult is the result of the decryption or verification of one of
+// several concatenated messages, see KeyRing.DecryptAll.
+type MessageResult struct {
+	// Message is the plaintext of the message, or nil if it couldn't be
+	// decrypted.
+	Message *PlainMessage
+	// Error is the error of the decryption, or of the verification of the
+	// signature, as returned by KeyRing.Decrypt: the message is set along
+	// with a SignatureVerificationError.
+	Error error
+}
+
+// SplitMessages splits an input made of several concatenated OpenPGP
+// messages, e.g. appended to the same file, into the individual messages.
+// A message ends after its data packet, an encrypted, compressed or literal
+// data packet, and the signatures matching its one-pass signatures.
+// Only the packet headers are read. An input with a single message is
+// returned as is.
+func (msg *PGPMessage) SplitMessages() ([]*PGPMessage, error) {
+	var messages []*PGPMessage
+	start := 0
+	onePassSignatures := 0
+	// Signatures expected after the data packet, -1 before it
+	remainingSignatures := -1
+	for offset := 0; offset < len(msg.Data); {
+		if remainingSignatures == 0 {
+			messages = append(messages, &PGPMessage{Data: msg.Data[start:offset]})
+			start, onePassSignatures, remainingSignatures = offset, 0, -1
+		}
+		tag, next, err := nextPacketOffset(msg.Data, offset)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case remainingSignatures > 0:
+			if tag != packetTagSignature {
+				return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: missing signature of one-pass signed message", nil)
+			}
+			remainingSignatures--
+		case tag == packetTagOnePassSignature:
+			onePassSignatures++
+		case tag == packetTagLiteralData,
+			tag == packetTagCompressed,
+			tag == packetTagSymmetricallyEncrypted,
+			tag == packetTagSEIPD,
+			tag == packetTagAEADEncrypted:
+			remainingSignatures = onePassSignatures
+		}
+		offset = next
+	}
+	if start < len(msg.Data) {
+		messages = append(messages, &PGPMessage{Data: msg.Data[start:]})
+	}
+	return messages, nil
+}
+
+// DecryptAll decrypts an input made of several concatenated messages, which
+// Decrypt would silently stop at the end of the first one, and returns the
+// result of each message, in order. The messages are decrypted and verified
+// independently, as with Decrypt: a message that can't be decrypted doesn't
+// prevent the decryption of the next ones. An error is only returned if the
+// input can't be split into messages, see PGPMessage.SplitMessages.
+func (keyRing *KeyRing) DecryptAll(message *PGPMessage, verifyKey *KeyRing, verifyTime int64) ([]*MessageResult, error) {
+	messages, err := message.SplitMessages()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*MessageResult, len(messages))
+	for i, message := range messages {
+		plainMessage, err := keyRing.Decrypt(message, verifyKey, verifyTime)
+		results[i] = &MessageResult{Message: plainMessage, Error: err}
+	}
+	return results, nil
+}
+
+// VerifyAll verifies the embedded signatures of an input made of several
+// concatenated signed, unencrypted messages, and returns the result of each
+// message, in order, see DecryptAll. Encrypted messages can't be decrypted,
+// and are reported with an error.
+func (keyRing *KeyRing) VerifyAll(message *PGPMessage, verifyTime int64) ([]*MessageResult, error) {
+	return (&KeyRing{}).DecryptAll(message, keyRing, verifyTime)
+}
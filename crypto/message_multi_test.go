@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptAll(t *testing.T) {
+	signed, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("first"), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	unsigned, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString("second"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	messages, err := signed.SplitMessages()
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	assert.Len(t, messages, 1)
+	assert.Exactly(t, signed.GetBinary(), messages[0].GetBinary())
+
+	concatenated := NewPGPMessage(append(append([]byte{}, signed.GetBinary()...), unsigned.GetBinary()...))
+	results, err := keyRingTestPrivate.DecryptAll(concatenated, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting all messages, got:", err)
+	}
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0].Error)
+	assert.Exactly(t, "first", results[0].Message.GetString())
+	assert.IsType(t, SignatureVerificationError{}, results[1].Error)
+	assert.Exactly(t, "second", results[1].Message.GetString())
+}
+
+func TestVerifyAll(t *testing.T) {
+	var concatenated bytes.Buffer
+	for _, text := range []string{"first", "second"} {
+		writer, err := openpgp.Sign(&concatenated, keyRingTestPrivate.entities[0], nil, &packet.Config{Time: getTimeGenerator()})
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		if _, err := writer.Write([]byte(text)); err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+	}
+
+	message := NewPGPMessage(concatenated.Bytes())
+	results, err := keyRingTestPublic.VerifyAll(message, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying all messages, got:", err)
+	}
+	assert.Len(t, results, 2)
+	for i, text := range []string{"first", "second"} {
+		assert.NoError(t, results[i].Error)
+		assert.Exactly(t, text, results[i].Message.GetString())
+	}
+
+	// The signature of the first message is missing
+	messages, err := message.SplitMessages()
+	if err != nil {
+		t.Fatal("Expected no error while splitting message, got:", err)
+	}
+	_, signatureOffset, err := nextPacketOffset(messages[0].GetBinary(), 0)
+	if err != nil {
+		t.Fatal("Expected no error while reading packet, got:", err)
+	}
+	_, signatureOffset, err = nextPacketOffset(messages[0].GetBinary(), signatureOffset)
+	if err != nil {
+		t.Fatal("Expected no error while reading packet, got:", err)
+	}
+	truncated := append(append([]byte{}, messages[0].GetBinary()[:signatureOffset]...), messages[1].GetBinary()...)
+	_, err = keyRingTestPublic.VerifyAll(NewPGPMessage(truncated), GetUnixTime())
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// GetSignature returns the embedded signature that was checked when the
+// message was decrypted and verified, as a detached signature of the message
+// data, e.g. to store or forward it separately from the message.
+// A message can have several embedded signatures, but only one of them is
+// checked: the one issued by the key of the last one-pass signature packet,
+// the closest to the data, if that key is in the verification keyring.
+// All the signatures are returned by GetSignatures.
+// It returns nil if the message was not verified, or has no signature from
+// the verification keyring. The signature is returned even if it is invalid:
+// the verification error must be checked first.
+func (msg *PlainMessage) GetSignature() (*PGPSignature, error) {
+	return newPGPSignatureFromPacket(msg.signature)
+}
+
+// GetSignatures returns all the embedded signatures of a message that was
+// decrypted and verified, as detached signatures of the message data.
+// The signature returned by GetSignature comes first, if any; the other
+// signatures were not checked, and must be verified separately, e.g. with
+// KeyRing.VerifyDetached.
+// It returns nil if the message was not verified.
+func (msg *PlainMessage) GetSignatures() ([]*PGPSignature, error) {
+	return newPGPSignaturesFromPackets(msg.signature, msg.unverifiedSignatures)
+}
+
+// GetSignature returns the embedded signature of the message, as
+// PlainMessage.GetSignature, once the message has been read entirely.
+func (msg *PlainMessageReader) GetSignature() (*PGPSignature, error) {
+	if !msg.readAll {
+		return nil, errors.New("gopenpgp: can't get the signature until the message reader has been read entirely")
+	}
+	if msg.verifyKeyRing == nil {
+		return nil, nil
+	}
+	return newPGPSignatureFromPacket(msg.details.Signature)
+}
+
+// GetSignatures returns all the embedded signatures of the message, as
+// PlainMessage.GetSignatures, once the message has been read entirely.
+func (msg *PlainMessageReader) GetSignatures() ([]*PGPSignature, error) {
+	if !msg.readAll {
+		return nil, errors.New("gopenpgp: can't get the signatures until the message reader has been read entirely")
+	}
+	if msg.verifyKeyRing == nil {
+		return nil, nil
+	}
+	return newPGPSignaturesFromPackets(msg.details.Signature, msg.details.UnverifiedSignatures)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// newPGPSignatureFromPacket serializes a signature packet, or returns nil
+// if it is nil.
+func newPGPSignatureFromPacket(signature *packet.Signature) (*PGPSignature, error) {
+	if signature == nil {
+		return nil, nil
+	}
+	var buffer bytes.Buffer
+	if err := signature.Serialize(&buffer); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in serializing signature")
+	}
+	return NewPGPSignature(buffer.Bytes()), nil
+}
+
+// newPGPSignaturesFromPackets serializes the checked signature packet, if not
+// nil, followed by the unverified ones.
+func newPGPSignaturesFromPackets(signature *packet.Signature, unverified []*packet.Signature) ([]*PGPSignature, error) {
+	var signatures []*PGPSignature
+	for _, sig := range append([]*packet.Signature{signature}, unverified...) {
+		if sig == nil {
+			continue
+		}
+		pgpSignature, err := newPGPSignatureFromPacket(sig)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, pgpSignature)
+	}
+	return signatures, nil
+}
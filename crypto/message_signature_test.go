@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainMessageGetSignature(t *testing.T) {
+	message := NewPlainMessageFromString("signed and encrypted")
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	signature, err := decrypted.GetSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting signature, got:", err)
+	}
+	if err := keyRingTestPublic.VerifyDetached(decrypted, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying extracted signature, got:", err)
+	}
+	armored, err := signature.GetArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring signature, got:", err)
+	}
+	assert.Regexp(t, signatureTest, armored)
+
+	// Without verification, no signature is checked
+	decrypted, err = keyRingTestPrivate.Decrypt(encrypted, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	signature, err = decrypted.GetSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting signature, got:", err)
+	}
+	assert.Nil(t, signature)
+
+	// Session key decryption
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	dataPacket, err := sessionKey.EncryptAndSign(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err = sessionKey.DecryptAndVerify(dataPacket, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	signature, err = decrypted.GetSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting signature, got:", err)
+	}
+	if err := keyRingTestPublic.VerifyDetached(decrypted, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying extracted signature, got:", err)
+	}
+}
+
+func TestPlainMessageReaderGetSignature(t *testing.T) {
+	message := NewPlainMessage([]byte("signed and encrypted stream"))
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	reader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(encrypted.GetBinary()), keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	_, err = reader.GetSignature()
+	assert.Error(t, err)
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	if err := reader.VerifySignature(); err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+	signature, err := reader.GetSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting signature, got:", err)
+	}
+	if err := keyRingTestPublic.VerifyDetached(NewPlainMessage(data), signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying extracted signature, got:", err)
+	}
+}
+
+func TestPlainMessageGetSignatures(t *testing.T) {
+	message := NewPlainMessageFromString("signed and encrypted")
+	encrypted, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	decrypted, err := keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	signature, err := decrypted.GetSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting signature, got:", err)
+	}
+	signatures, err := decrypted.GetSignatures()
+	if err != nil {
+		t.Fatal("Expected no error while getting signatures, got:", err)
+	}
+	assert.Exactly(t, []*PGPSignature{signature}, signatures)
+
+	// A signature from another key is not checked, but still returned
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	encrypted, err = keyRingTestPublic.Encrypt(message, otherKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err = keyRingTestPrivate.Decrypt(encrypted, keyRingTestPublic, GetUnixTime())
+	assert.Error(t, err)
+	signature, err = decrypted.GetSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting signature, got:", err)
+	}
+	assert.Nil(t, signature)
+	signatures, err = decrypted.GetSignatures()
+	if err != nil {
+		t.Fatal("Expected no error while getting signatures, got:", err)
+	}
+	assert.Len(t, signatures, 1)
+	if err := otherKeyRing.VerifyDetached(decrypted, signatures[0], GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying extracted signature, got:", err)
+	}
+}
@@ -0,0 +1,115 @@
+package crypto
+
+// OpenPGP packet tags relevant to splitting a message.
+const (
+	packetTagEncryptedKey           = 1
+	packetTagSymmetricKeyEncrypted  = 3
+	packetTagSymmetricallyEncrypted = 9
+	packetTagSEIPD                  = 18
+	packetTagAEADEncrypted          = 20
+	// packetTagPadding is the tag of the padding packets of RFC 9580.
+	packetTagPadding = 21
+)
+
+// GetBinaryKeyPacket returns the key packets of the message, as a subslice
+// of the message data. Only the packet headers are read to find the key
+// packets, their content is neither parsed nor copied.
+// The returned slice shares memory with the message and must not be modified.
+func (msg *PGPMessage) GetBinaryKeyPacket() ([]byte, error) {
+	splitPoint, err := msg.getSplitPoint()
+	if err != nil {
+		return nil, err
+	}
+	return msg.Data[:splitPoint], nil
+}
+
+// GetBinaryDataPacket returns the data packet of the message, as a subslice
+// of the message data. Only the packet headers are read to find the data
+// packet, its content is neither parsed nor copied.
+// The returned slice shares memory with the message and must not be modified.
+func (msg *PGPMessage) GetBinaryDataPacket() ([]byte, error) {
+	splitPoint, err := msg.getSplitPoint()
+	if err != nil {
+		return nil, err
+	}
+	return msg.Data[splitPoint:], nil
+}
+
+// SplitMessageInPlace splits the message into key and data packet(s) like
+// SplitMessage, but without copying them: the packets of the returned
+// PGPSplitMessage are subslices of the message data, which avoids
+// duplicating large messages in memory.
+// The split message shares memory with the message and must not be modified.
+func (msg *PGPMessage) SplitMessageInPlace() (*PGPSplitMessage, error) {
+	splitPoint, err := msg.getSplitPoint()
+	if err != nil {
+		return nil, err
+	}
+	return &PGPSplitMessage{
+		KeyPacket:  msg.Data[:splitPoint],
+		DataPacket: msg.Data[splitPoint:],
+	}, nil
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// getSplitPoint returns the offset of the end of the last session key packet
+// preceding the encrypted data packet, by walking the packet headers only.
+func (msg *PGPMessage) getSplitPoint() (int, error) {
+	splitPoint := 0
+	for offset := 0; offset < len(msg.Data); {
+		tag, next, err := nextPacketOffset(msg.Data, offset)
+		if err != nil {
+			return 0, err
+		}
+		switch tag {
+		case packetTagEncryptedKey, packetTagSymmetricKeyEncrypted:
+			splitPoint = next
+		case packetTagSEIPD, packetTagAEADEncrypted, packetTagSymmetricallyEncrypted:
+			return splitPoint, nil
+		}
+		offset = next
+	}
+	return splitPoint, nil
+}
+
+// nextPacketOffset reads the header of the packet starting at offset in data,
+// and returns its tag and the offset of the next packet.
+func nextPacketOffset(data []byte, offset int) (tag int, next int, err error) {
+	errTruncated := newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated packet", nil)
+
+	tag, length, partial, headerSize, ok, err := parsePacketHeader(data[offset:])
+	if err != nil {
+		return 0, 0, err
+	}
+	for {
+		if !ok {
+			return 0, 0, errTruncated
+		}
+		offset += headerSize
+		if length < 0 {
+			// Indeterminate length, the packet extends to the end of the data
+			return tag, len(data), nil
+		}
+		if length > int64(len(data)-offset) {
+			return 0, 0, errTruncated
+		}
+		offset += int(length)
+		if !partial {
+			return tag, offset, nil
+		}
+		length, partial, headerSize, ok = parseNewFormatLength(data[offset:])
+	}
+}
+
+// joinContiguous returns the concatenation of a and b without copying when
+// b directly follows a in memory, and nil otherwise.
+func joinContiguous(a, b []byte) []byte {
+	if len(a) == 0 || len(b) == 0 || cap(a) < len(a)+len(b) {
+		return nil
+	}
+	if &a[:len(a)+1][len(a)] != &b[0] {
+		return nil
+	}
+	return a[:len(a)+len(b)]
+}
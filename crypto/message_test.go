@@ -3,6 +3,7 @@ package crypto
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -92,6 +95,58 @@ func TestTextMixedMessageDecryptionWithPassword(t *testing.T) {
 	assert.Exactly(t, expected, decrypted.GetBinary())
 }
 
+func TestMessageDecryptionWithPasswords(t *testing.T) {
+	message := NewPlainMessageFromString("The secret code is... 1, 2, 3, 4, 5")
+	oldPassword, newPassword := []byte("old password"), []byte("new password")
+
+	// Key packets for both passwords, as during a password migration
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error when generating session key, got:", err)
+	}
+	dataPacket, err := sessionKey.Encrypt(message)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+	var keyPackets []byte
+	for _, password := range [][]byte{oldPassword, newPassword} {
+		keyPacket, err := EncryptSessionKeyWithPassword(sessionKey, password)
+		if err != nil {
+			t.Fatal("Expected no error when encrypting session key, got:", err)
+		}
+		keyPackets = append(keyPackets, keyPacket...)
+	}
+	encrypted := NewPGPSplitMessage(keyPackets, dataPacket).GetPGPMessage()
+
+	passwords := [][]byte{[]byte("wrong password"), newPassword, oldPassword}
+	decrypted, index, err := DecryptMessageWithPasswords(encrypted, passwords, 0)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, 1, index)
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	decryptedSessionKey, index, err := DecryptSessionKeyWithPasswords(keyPackets, passwords[2:], 0)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting session key, got:", err)
+	}
+	assert.Exactly(t, 0, index)
+	assert.Exactly(t, sessionKey.Key, decryptedSessionKey.Key)
+
+	// The wrong password is tried on both key packets, the new one on the
+	// first key packet only, before the limit
+	_, _, err = DecryptMessageWithPasswords(encrypted, passwords, 3)
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+	_, index, err = DecryptMessageWithPasswords(encrypted, passwords, 4)
+	if err != nil {
+		t.Fatal("Expected no error when decrypting, got:", err)
+	}
+	assert.Exactly(t, 1, index)
+
+	_, _, err = DecryptMessageWithPasswords(encrypted, passwords[:1], 0)
+	assert.True(t, errors.Is(err, ErrWrongPassphrase))
+}
+
 func TestTextMessageEncryption(t *testing.T) {
 	var message = NewPlainMessageFromString(
 		"The secret code is... 1, 2, 3, 4, 5. I repeat: the secret code is... 1, 2, 3, 4, 5",
@@ -302,6 +357,19 @@ func TestDummy(t *testing.T) {
 		t.Fatal("Expected no error when decrypting, got:", err)
 	}
 	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	assert.True(t, dummyKey.IsStub())
+	isStub, err := dummyKey.IsSubkeyStub(dummyKey.GetFingerprint())
+	assert.NoError(t, err)
+	assert.True(t, isStub)
+	isStub, err = dummyKey.IsSubkeyStub(hex.EncodeToString(dummyKey.entity.Subkeys[0].PublicKey.Fingerprint))
+	assert.NoError(t, err)
+	assert.False(t, isStub)
+
+	_, err = dummyKeyRing.SignDetached(message)
+	var stubErr StubKeyError
+	assert.True(t, errors.As(err, &stubErr))
+	assert.Exactly(t, dummyKey.GetFingerprint(), stubErr.Fingerprint)
 }
 
 func TestSignedMessageDecryption(t *testing.T) {
@@ -500,3 +568,65 @@ vA==
 		t.Error("Data packet was nil")
 	}
 }
+
+func TestPGPMessageSplitInPlace(t *testing.T) {
+	ciphertext, err := keyRingTestMultiple.Encrypt(NewPlainMessage(make([]byte, 100000)), nil)
+	if err != nil {
+		t.Fatal("Expected no error when encrypting, got:", err)
+	}
+
+	split, err := ciphertext.SplitMessage()
+	if err != nil {
+		t.Fatal("Expected no error when splitting, got:", err)
+	}
+	splitInPlace, err := ciphertext.SplitMessageInPlace()
+	if err != nil {
+		t.Fatal("Expected no error when splitting in place, got:", err)
+	}
+	assert.Exactly(t, split.GetBinaryKeyPacket(), splitInPlace.GetBinaryKeyPacket())
+	assert.Exactly(t, split.GetBinaryDataPacket(), splitInPlace.GetBinaryDataPacket())
+
+	keyPacket, err := ciphertext.GetBinaryKeyPacket()
+	if err != nil {
+		t.Fatal("Expected no error when getting the key packet, got:", err)
+	}
+	dataPacket, err := ciphertext.GetBinaryDataPacket()
+	if err != nil {
+		t.Fatal("Expected no error when getting the data packet, got:", err)
+	}
+	assert.Exactly(t, split.GetBinaryKeyPacket(), keyPacket)
+	assert.Exactly(t, split.GetBinaryDataPacket(), dataPacket)
+
+	// The packets and the joined message share the memory of the original message
+	assert.True(t, &ciphertext.Data[len(keyPacket)] == &dataPacket[0])
+	assert.True(t, &ciphertext.Data[0] == &splitInPlace.GetBinary()[0])
+	assert.Exactly(t, ciphertext.GetBinary(), split.GetBinary())
+
+	_, err = NewPGPMessage(ciphertext.Data[:len(keyPacket)-1]).SplitMessageInPlace()
+	assert.Error(t, err)
+}
+
+func TestArmorTypeMismatch(t *testing.T) {
+	privateKey := readTestFile("keyring_privateKey", false)
+	var typeError armor.TypeError
+
+	_, err := NewPGPMessageFromArmored(privateKey)
+	assert.True(t, errors.As(err, &typeError))
+	assert.Exactly(t, constants.PrivateKeyHeader, typeError.Actual)
+	assert.Exactly(t, []string{constants.PGPMessageHeader}, typeError.Expected)
+
+	_, err = NewPGPSignatureFromArmored(readTestFile("message_signed", false))
+	assert.True(t, errors.As(err, &typeError))
+
+	_, err = NewKeyFromArmored(readTestFile("message_signed", false))
+	assert.True(t, errors.As(err, &typeError))
+
+	_, err = NewClearTextMessageFromArmored(privateKey)
+	assert.True(t, errors.As(err, &typeError))
+	assert.Exactly(t, []string{constants.PGPSignedMessageHeader}, typeError.Expected)
+
+	_, err = NewPGPMessageFromArmored(readTestFile("message_signed", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+}
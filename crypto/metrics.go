@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"io"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// Metrics receives the measurements of the keyring operations, i.e.
+// encryption, decryption, detached signature and verification, both
+// streaming and not, e.g. to export them to a monitoring system.
+// Metrics must be safe for concurrent use, and should return quickly,
+// as it is called synchronously by the operations.
+type Metrics interface {
+	// OnOperation is called once per operation, when it completes or fails.
+	// * operation : one of the constants.MetricsOperation* names.
+	// * durationNanos : the duration of the operation; for streams, from
+	//   the creation of the stream until it is closed or read entirely.
+	// * bytes : the size of the plaintext processed.
+	// * errorClass : "" on success, otherwise one of the
+	//   constants.MetricsError* classes.
+	OnOperation(operation string, durationNanos int64, bytes int64, errorClass string)
+}
+
+// SetMetrics sets the receiver of the measurements of the operations.
+// Passing nil disables the measurements, which is the default.
+func SetMetrics(metrics Metrics) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.metrics = metrics
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// operationTimer measures one operation. A nil timer, returned when no
+// metrics are set, ignores the measurements.
+type operationTimer struct {
+	metrics   Metrics
+	operation string
+	start     time.Time
+	done      bool
+}
+
+func startOperation(operation string) *operationTimer {
+	pgp.lock.RLock()
+	metrics := pgp.metrics
+	pgp.lock.RUnlock()
+
+	if metrics == nil {
+		return nil
+	}
+	// The system clock is used, as the clock set with SetClock may be fixed
+	return &operationTimer{metrics: metrics, operation: operation, start: time.Now()}
+}
+
+// finish reports the operation, only once.
+func (timer *operationTimer) finish(bytes int64, err error) {
+	if timer == nil || timer.done {
+		return
+	}
+	timer.done = true
+	timer.metrics.OnOperation(timer.operation, time.Since(timer.start).Nanoseconds(), bytes, getErrorClass(err))
+}
+
+// getErrorClass classifies err for the metrics.
+func getErrorClass(err error) string {
+	var signatureError SignatureVerificationError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &signatureError):
+		return constants.MetricsErrorSignature
+	case errors.Is(err, ErrNoDecryptionKey):
+		return constants.MetricsErrorNoKey
+	case errors.Is(err, ErrMessageCorrupt):
+		return constants.MetricsErrorCorrupt
+	case errors.Is(err, ErrUnsupportedAlgorithm):
+		return constants.MetricsErrorUnsupported
+	default:
+		return constants.MetricsErrorOther
+	}
+}
+
+// meteredWriteCloser reports the operation of a stream when it is closed.
+type meteredWriteCloser struct {
+	writer io.WriteCloser
+	timer  *operationTimer
+	bytes  int64
+}
+
+func (w *meteredWriteCloser) Write(b []byte) (int, error) {
+	n, err := w.writer.Write(b)
+	w.bytes += int64(n)
+	if err != nil {
+		w.timer.finish(w.bytes, err)
+	}
+	return n, err
+}
+
+func (w *meteredWriteCloser) Close() error {
+	err := w.writer.Close()
+	w.timer.finish(w.bytes, err)
+	return err
+}
+
+// countingReader counts the bytes read.
+type countingReader struct {
+	reader io.Reader
+	bytes  int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// count returns the bytes read, or 0 for a nil counter.
+func (r *countingReader) count() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.bytes
+}
+
+// countingReadSeeker is a countingReader which keeps the reader seekable,
+// restarting the count when rewound.
+type countingReadSeeker struct {
+	*countingReader
+	seeker io.Seeker
+}
+
+func (r *countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	position, err := r.seeker.Seek(offset, whence)
+	if err == nil {
+		r.bytes = position
+	}
+	return position, err
+}
+
+// newCountingReader wraps reader to count the bytes read, preserving the
+// io.Seeker interface.
+func newCountingReader(reader io.Reader) (io.Reader, *countingReader) {
+	counter := &countingReader{reader: reader}
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		return &countingReadSeeker{countingReader: counter, seeker: seeker}, counter
+	}
+	return counter, counter
+}
@@ -0,0 +1,144 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOperation struct {
+	operation  string
+	bytes      int64
+	errorClass string
+}
+
+type testMetrics struct {
+	lock       sync.Mutex
+	operations []testOperation
+}
+
+func (metrics *testMetrics) OnOperation(operation string, durationNanos int64, bytes int64, errorClass string) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	metrics.operations = append(metrics.operations, testOperation{operation, bytes, errorClass})
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := &testMetrics{}
+	SetMetrics(metrics)
+	defer SetMetrics(nil)
+
+	message := NewPlainMessageFromString(testMessage)
+	size := int64(len(message.GetBinary()))
+	ciphertext, err := keyRingTestPublic.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	if err = keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+	err = keyRingTestPublic.VerifyDetached(NewPlainMessageFromString("tampered"), signature, GetUnixTime())
+	assert.Error(t, err)
+
+	assert.Exactly(t, []testOperation{
+		{constants.MetricsOperationEncrypt, size, ""},
+		{constants.MetricsOperationDecrypt, size, ""},
+		{constants.MetricsOperationSign, size, ""},
+		{constants.MetricsOperationVerify, size, ""},
+		{constants.MetricsOperationVerify, int64(len("tampered")), constants.MetricsErrorSignature},
+	}, metrics.operations)
+}
+
+func TestMetricsStream(t *testing.T) {
+	metrics := &testMetrics{}
+	SetMetrics(metrics)
+	defer SetMetrics(nil)
+
+	var ciphertext bytes.Buffer
+	writer, err := keyRingTestPublic.EncryptStream(&ciphertext, nil, nil)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = writer.Write([]byte(testMessage)); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	assert.Empty(t, metrics.operations)
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+
+	reader, err := keyRingTestPrivate.DecryptStream(&ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+
+	_, err = keyRingTestPublic.Decrypt(NewPGPMessage([]byte{0xc1, 0x00}), nil, 0)
+	assert.Error(t, err)
+
+	size := int64(len(testMessage))
+	assert.Len(t, metrics.operations, 3)
+	assert.Exactly(t, testOperation{constants.MetricsOperationEncrypt, size, ""}, metrics.operations[0])
+	assert.Exactly(t, testOperation{constants.MetricsOperationDecrypt, size, ""}, metrics.operations[1])
+	assert.Exactly(t, constants.MetricsOperationDecrypt, metrics.operations[2].operation)
+	assert.NotEmpty(t, metrics.operations[2].errorClass)
+}
+
+func TestMetricsStreamVerification(t *testing.T) {
+	metrics := &testMetrics{}
+	SetMetrics(metrics)
+	defer SetMetrics(nil)
+
+	ciphertext, err := keyRingTestPublic.Encrypt(NewPlainMessageFromString(testMessage), keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	otherKey, err := GenerateKey("other", "other@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	otherKeyRing, err := NewKeyRing(otherKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	metrics.operations = nil
+
+	reader, err := keyRingTestPrivate.DecryptStream(bytes.NewReader(ciphertext.GetBinary()), otherKeyRing, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	assert.Empty(t, metrics.operations)
+	assert.Error(t, reader.VerifySignature())
+
+	reader, err = keyRingTestPrivate.DecryptStream(bytes.NewReader(ciphertext.GetBinary()), keyRingTestPublic, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal("Expected no error while reading, got:", err)
+	}
+	assert.NoError(t, reader.VerifySignature())
+
+	size := int64(len(testMessage))
+	assert.Exactly(t, []testOperation{
+		{constants.MetricsOperationDecrypt, size, constants.MetricsErrorSignature},
+		{constants.MetricsOperationDecrypt, size, ""},
+	}, metrics.operations)
+}
@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"encoding/binary"
+)
+
+// parsePacketHeader parses the packet header at the start of data. The
+// length is -1 for old format packets of indeterminate length. ok is false
+// if data is too short to contain the header.
+// See RFC 4880, section 4.2.
+func parsePacketHeader(data []byte) (tag int, length int64, partial bool, headerSize int, ok bool, err error) {
+	if len(data) == 0 {
+		return 0, 0, false, 0, false, nil
+	}
+	if data[0]&0x80 == 0 {
+		return 0, 0, false, 0, false, newClassifiedError(ErrMessageCorrupt, "gopenpgp: invalid packet header", nil)
+	}
+	if data[0]&0x40 != 0 {
+		length, partial, lengthSize, ok := parseNewFormatLength(data[1:])
+		return int(data[0] & 0x3f), length, partial, 1 + lengthSize, ok, nil
+	}
+
+	// Old format packet
+	tag = int(data[0]&0x3f) >> 2
+	lengthSize := [4]int{1, 2, 4, 0}[data[0]&3]
+	if lengthSize == 0 {
+		return tag, -1, false, 1, true, nil
+	}
+	if len(data) < 1+lengthSize {
+		return 0, 0, false, 0, false, nil
+	}
+	for _, b := range data[1 : 1+lengthSize] {
+		length = length<<8 | int64(b)
+	}
+	return tag, length, false, 1 + lengthSize, true, nil
+}
+
+// parseNewFormatLength parses the new format body length at the start of
+// data. ok is false if data is too short to contain the length.
+func parseNewFormatLength(data []byte) (length int64, partial bool, lengthSize int, ok bool) {
+	if len(data) == 0 {
+		return 0, false, 0, false
+	}
+	switch first := data[0]; {
+	case first < 192:
+		return int64(first), false, 1, true
+	case first < 224:
+		if len(data) < 2 {
+			return 0, false, 0, false
+		}
+		return int64(first-192)<<8 + int64(data[1]) + 192, false, 2, true
+	case first < 255:
+		return 1 << (first & 0x1f), true, 1, true
+	default:
+		if len(data) < 5 {
+			return 0, false, 0, false
+		}
+		return int64(binary.BigEndian.Uint32(data[1:5])), false, 5, true
+	}
+}
+
+// packetLengthSize returns the size of a new format packet length.
+func packetLengthSize(length int64) int {
+	switch {
+	case length < 192:
+		return 1
+	case length < 8384:
+		return 2
+	default:
+		return 5
+	}
+}
@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"io"
+	"math/bits"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// PaddingPolicy defines the length encrypted messages are padded to, with a
+// padding packet in the encrypted data, so that the size of the message
+// leaks less about the length of its content.
+// Padding packets were introduced by RFC 9580, older OpenPGP implementations
+// may warn about them or fail to decrypt padded messages.
+type PaddingPolicy struct {
+	// BucketSize, if positive, pads messages to a multiple of BucketSize
+	// bytes. Otherwise, messages are padded with Padmé, which leaks at most
+	// O(log log L) bits about the length L, with an overhead under 12%.
+	BucketSize int64
+}
+
+// NewPaddingPolicy creates a padding policy padding messages to a multiple of
+// bucketSize bytes, or with Padmé if bucketSize is 0.
+func NewPaddingPolicy(bucketSize int64) *PaddingPolicy {
+	return &PaddingPolicy{BucketSize: bucketSize}
+}
+
+// EncryptWithPadding encrypts a PlainMessage to PGPMessage like Encrypt, and
+// pads the encrypted data according to the padding policy.
+// Padded messages are always encrypted with SEIPDv1.
+// * message       : The plaintext input as a PlainMessage.
+// * privateKey    : (optional) an unlocked private keyring to include signature in the message.
+// * paddingPolicy : The length to pad the encrypted data to.
+func (keyRing *KeyRing) EncryptWithPadding(
+	message *PlainMessage,
+	privateKey *KeyRing,
+	paddingPolicy *PaddingPolicy,
+) (*PGPMessage, error) {
+	if keyRing.seipdVersion == seipdVersion2 {
+		return nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: padding is not supported with SEIPDv2", nil)
+	}
+
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		return nil, err
+	}
+	defer sessionKey.Clear()
+
+	keyPacket, err := keyRing.EncryptSessionKey(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if privateKey != nil && len(privateKey.entities) == 0 {
+		privateKey = nil
+	}
+	dataPacket, err := encryptWithSessionKey(message, sessionKey, privateKey, false, nil, paddingPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPGPSplitMessage(keyPacket, dataPacket).GetPGPMessage(), nil
+}
+
+// EncryptAndSignWithPadding encrypts a PlainMessage to PGPMessage with a
+// SessionKey, and pads the encrypted data according to the padding policy.
+// * message       : The plain data as a PlainMessage.
+// * signKeyRing   : (optional) the KeyRing to sign the message.
+// * paddingPolicy : The length to pad the encrypted data to.
+func (sk *SessionKey) EncryptAndSignWithPadding(
+	message *PlainMessage,
+	signKeyRing *KeyRing,
+	paddingPolicy *PaddingPolicy,
+) ([]byte, error) {
+	return encryptWithSessionKey(message, sk, signKeyRing, false, nil, paddingPolicy)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getPaddedLength returns the length to pad a content of the given length to.
+func (policy *PaddingPolicy) getPaddedLength(length int64) int64 {
+	if policy.BucketSize > 0 {
+		return (length + policy.BucketSize - 1) / policy.BucketSize * policy.BucketSize
+	}
+	if length < 2 {
+		return length
+	}
+
+	// Padmé: keep the log2(E) + 1 most significant bits of the length,
+	// where E is the position of its most significant bit.
+	exponent := bits.Len64(uint64(length)) - 1
+	significantBits := bits.Len64(uint64(exponent))
+	mask := int64(1)<<uint(exponent-significantBits) - 1
+	return (length + mask) &^ mask
+}
+
+// paddingWriteCloser buffers the content of the encrypted data, and writes
+// it with a padding packet on close. Writing everything at once keeps the
+// partial length headers of the encrypted data packet, which depend on the
+// sizes of the writes, from leaking the length of the content.
+// The buffer holds plaintext, so it is wiped when it grows and on close.
+type paddingWriteCloser struct {
+	writer io.WriteCloser
+	policy *PaddingPolicy
+	rand   io.Reader
+	buffer []byte
+}
+
+func (w *paddingWriteCloser) Write(p []byte) (int, error) {
+	if len(w.buffer)+len(p) > cap(w.buffer) {
+		grown := make([]byte, len(w.buffer), 2*cap(w.buffer)+len(p))
+		copy(grown, w.buffer)
+		clearMem(w.buffer[:cap(w.buffer)])
+		w.buffer = grown
+	}
+	w.buffer = append(w.buffer, p...)
+	return len(p), nil
+}
+
+func (w *paddingWriteCloser) Close() error {
+	defer func() {
+		clearMem(w.buffer[:cap(w.buffer)])
+		w.buffer = nil
+	}()
+	padding := w.getPaddingLength()
+	if err := packet.Padding(padding).SerializePadding(w, w.rand); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in writing padding")
+	}
+	if _, err := w.writer.Write(w.buffer); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in writing padded data")
+	}
+	return w.writer.Close()
+}
+
+// getPaddingLength returns the length of the padding packet body, such that
+// the content and the padding packet add up to a padded length.
+func (w *paddingWriteCloser) getPaddingLength() int {
+	// A padding packet is at least two bytes long
+	written := int64(len(w.buffer))
+	target := w.policy.getPaddedLength(written + 2)
+	for {
+		for _, headerLength := range []int64{2, 3, 6} {
+			padding := target - written - headerLength
+			if padding >= 0 && int64(1+packetLengthSize(padding)) == headerLength {
+				return int(padding)
+			}
+		}
+		// The header length changes at this length, use the next one
+		target = w.policy.getPaddedLength(target + 1)
+	}
+}
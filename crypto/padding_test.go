@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaddedLength(t *testing.T) {
+	padme := NewPaddingPolicy(0)
+	assert.Exactly(t, int64(10), padme.getPaddedLength(9))
+	assert.Exactly(t, int64(1024), padme.getPaddedLength(1000))
+	assert.Exactly(t, int64(1024), padme.getPaddedLength(1024))
+
+	bucket := NewPaddingPolicy(512)
+	assert.Exactly(t, int64(512), bucket.getPaddedLength(1))
+	assert.Exactly(t, int64(1024), bucket.getPaddedLength(513))
+}
+
+func TestEncryptWithPadding(t *testing.T) {
+	policy := NewPaddingPolicy(4096)
+
+	var dataPacketLength int
+	for _, length := range []int{0, 10, 1000, 3000} {
+		message := NewPlainMessageFromString(strings.Repeat("a", length))
+		ciphertext, err := keyRingTestPublic.EncryptWithPadding(message, keyRingTestPrivate, policy)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+
+		split, err := ciphertext.SplitMessage()
+		if err != nil {
+			t.Fatal("Expected no error while splitting, got:", err)
+		}
+		if dataPacketLength == 0 {
+			dataPacketLength = len(split.GetBinaryDataPacket())
+		}
+		assert.Exactly(t, dataPacketLength, len(split.GetBinaryDataPacket()))
+
+		decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, keyRingTestPublic, GetUnixTime())
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, message.GetString(), decrypted.GetString())
+	}
+}
+
+func TestSessionKeyEncryptWithPadding(t *testing.T) {
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	message := NewPlainMessageFromString("hello")
+
+	dataPacket, err := sessionKey.EncryptAndSignWithPadding(message, nil, NewPaddingPolicy(0))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	unpadded, err := sessionKey.Encrypt(message)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Greater(t, len(dataPacket), len(unpadded))
+
+	decrypted, err := sessionKey.Decrypt(dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, "hello", decrypted.GetString())
+}
+
+func TestPaddingWriteCloserWipesPlaintext(t *testing.T) {
+	var output bytes.Buffer
+	writer := &paddingWriteCloser{
+		writer: nopWriteCloser{&output},
+		policy: NewPaddingPolicy(0),
+		rand:   strings.NewReader(strings.Repeat("r", 1024)),
+	}
+	if _, err := writer.Write([]byte("plain")); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	first := writer.buffer
+	if _, err := writer.Write([]byte(" text")); err != nil {
+		t.Fatal("Expected no error while writing, got:", err)
+	}
+	last := writer.buffer
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing, got:", err)
+	}
+	assert.True(t, bytes.HasPrefix(output.Bytes(), []byte("plain text")))
+	assert.Exactly(t, make([]byte, len(first)), first)
+	assert.Exactly(t, make([]byte, len(last)), last)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -35,43 +35,50 @@ func DecryptMessageWithPassword(message *PGPMessage, password []byte) (*PlainMes
 // DecryptSessionKeyWithPassword decrypts the binary symmetrically encrypted
 // session key packet and returns the session key.
 func DecryptSessionKeyWithPassword(keyPacket, password []byte) (*SessionKey, error) {
-	keyReader := bytes.NewReader(keyPacket)
-	packets := packet.NewReader(keyReader)
-
-	var symKeys []*packet.SymmetricKeyEncrypted
-	for {
-		var p packet.Packet
-		var err error
-		if p, err = packets.Next(); err != nil {
-			break
-		}
-
-		if p, ok := p.(*packet.SymmetricKeyEncrypted); ok {
-			symKeys = append(symKeys, p)
-		}
-	}
-
 	// Try the symmetric passphrase first
-	if len(symKeys) != 0 && password != nil {
-		for _, s := range symKeys {
-			key, cipherFunc, err := s.Decrypt(password)
-			if err == nil {
-				sk := &SessionKey{
-					V6:   s.Version == 6,
-					Key:  key,
-					Algo: getAlgo(cipherFunc),
-				}
-
-				if err = sk.checkSize(); err != nil {
-					return nil, errors.Wrap(err, "gopenpgp: unable to decrypt session key with password")
-				}
-
-				return sk, nil
+	if password != nil {
+		for _, s := range readSymmetricKeyPackets(keyPacket) {
+			sk, err := decryptSymmetricKeyPacket(s, password)
+			if sk != nil || err != nil {
+				return sk, err
 			}
 		}
 	}
 
-	return nil, errors.New("gopenpgp: unable to decrypt any packet")
+	return nil, newClassifiedError(ErrWrongPassphrase, "gopenpgp: unable to decrypt any packet", nil)
+}
+
+// DecryptSessionKeyWithPasswords decrypts the binary symmetrically encrypted
+// session key packets with candidate passwords, e.g. the current password
+// followed by the old ones kept for migration, tried in order, and returns
+// the session key and the index of the password that decrypted it.
+// * maxAttempts : caps the number of password derivations (S2K), each
+// password being tried on each packet, or 0 for no limit.
+// Note that a packet without encrypted session key decrypts with any
+// password, as the derived key is the session key: use
+// DecryptMessageWithPasswords to check it on the encrypted data.
+func DecryptSessionKeyWithPasswords(keyPacket []byte, passwords [][]byte, maxAttempts int) (*SessionKey, int, error) {
+	return decryptWithPasswords(keyPacket, passwords, maxAttempts, nil)
+}
+
+// DecryptMessageWithPasswords decrypts a password protected message with
+// candidate passwords, tried in order, see DecryptSessionKeyWithPasswords,
+// and returns the decrypted message and the index of the password that
+// decrypted it.
+func DecryptMessageWithPasswords(message *PGPMessage, passwords [][]byte, maxAttempts int) (*PlainMessage, int, error) {
+	split, err := message.SplitMessage()
+	if err != nil {
+		return nil, -1, err
+	}
+	var plainMessage *PlainMessage
+	_, index, err := decryptWithPasswords(split.KeyPacket, passwords, maxAttempts, func(sk *SessionKey) bool {
+		plainMessage, err = sk.Decrypt(split.DataPacket)
+		return err == nil
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+	return plainMessage, index, nil
 }
 
 // EncryptSessionKeyWithPassword encrypts the session key with the password and
@@ -93,9 +100,12 @@ func EncryptSessionKeyWithPassword(sk *SessionKey, password []byte) ([]byte, err
 	}
 
 	config := &packet.Config{
+		Rand:          getRandomSource(),
 		DefaultCipher: cf,
+		S2KConfig:     getPasswordS2KConfig(),
 	}
 
+	applyConfigModifier(config)
 	err = packet.SerializeSymmetricKeyEncryptedReuseKey(outbuf, sk.Key, password, config)
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: unable to encrypt session key with password")
@@ -106,13 +116,23 @@ func EncryptSessionKeyWithPassword(sk *SessionKey, password []byte) ([]byte, err
 // ----- INTERNAL FUNCTIONS ------
 
 func passwordEncrypt(message *PlainMessage, password []byte) ([]byte, error) {
-	var outBuf bytes.Buffer
-
 	config := &packet.Config{
+		Rand:          getRandomSource(),
 		DefaultCipher: packet.CipherAES256,
 		Time:          getTimeGenerator(),
+		S2KConfig:     getPasswordS2KConfig(),
 	}
 
+	return passwordEncryptWithConfig(message, password, config)
+}
+
+func passwordEncryptWithConfig(message *PlainMessage, password []byte, config *packet.Config) ([]byte, error) {
+	if err := message.checkUTF8(); err != nil {
+		return nil, err
+	}
+	applyConfigModifier(config)
+	var outBuf bytes.Buffer
+
 	hints := &openpgp.FileHints{
 		IsBinary: message.IsBinary(),
 		FileName: message.Filename,
@@ -136,6 +156,70 @@ func passwordEncrypt(message *PlainMessage, password []byte) ([]byte, error) {
 	return outBuf.Bytes(), nil
 }
 
+// readSymmetricKeyPackets returns the symmetrically encrypted session key
+// packets of the binary key packets.
+func readSymmetricKeyPackets(keyPacket []byte) []*packet.SymmetricKeyEncrypted {
+	packets := packet.NewReader(bytes.NewReader(keyPacket))
+	var symKeys []*packet.SymmetricKeyEncrypted
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			break
+		}
+		if p, ok := p.(*packet.SymmetricKeyEncrypted); ok {
+			symKeys = append(symKeys, p)
+		}
+	}
+	return symKeys
+}
+
+// decryptSymmetricKeyPacket decrypts a symmetrically encrypted session key
+// packet, and returns nil without error if the password is wrong.
+func decryptSymmetricKeyPacket(s *packet.SymmetricKeyEncrypted, password []byte) (*SessionKey, error) {
+	key, cipherFunc, err := s.Decrypt(password)
+	if err != nil {
+		return nil, nil
+	}
+	sk := newSessionKey(key, getAlgo(cipherFunc), s.Version == 6)
+	if err = sk.checkSize(); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to decrypt session key with password")
+	}
+	return sk, nil
+}
+
+// decryptWithPasswords tries the passwords in order on the symmetrically
+// encrypted session key packets, at most maxAttempts times if positive, and
+// returns the first session key accepted by check, if not nil, and the index
+// of its password. Each attempt is a call to go-crypto, which derives the
+// password again for each packet, as it has no cache for session key packets.
+func decryptWithPasswords(
+	keyPacket []byte,
+	passwords [][]byte,
+	maxAttempts int,
+	check func(*SessionKey) bool,
+) (*SessionKey, int, error) {
+	symKeys := readSymmetricKeyPackets(keyPacket)
+	if len(symKeys) == 0 {
+		return nil, -1, errors.New("gopenpgp: no symmetrically encrypted session key packet")
+	}
+
+	attempts := 0
+	for index, password := range passwords {
+		for _, s := range symKeys {
+			if maxAttempts > 0 && attempts >= maxAttempts {
+				return nil, -1, newClassifiedError(ErrWrongPassphrase, "gopenpgp: maximum number of password attempts reached", nil)
+			}
+			attempts++
+			// A wrong password can give a session key of invalid size
+			sk, err := decryptSymmetricKeyPacket(s, password)
+			if err == nil && sk != nil && (check == nil || check(sk)) {
+				return sk, index, nil
+			}
+		}
+	}
+	return nil, -1, newClassifiedError(ErrWrongPassphrase, "gopenpgp: unable to decrypt any packet with the passwords", nil)
+}
+
 func passwordDecrypt(encryptedIO io.Reader, password []byte) (*PlainMessage, error) {
 	firstTimeCalled := true
 	var prompt = func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
@@ -145,35 +229,42 @@ func passwordDecrypt(encryptedIO io.Reader, password []byte) (*PlainMessage, err
 		}
 		// Re-prompt still occurs if SKESK pasrsing fails (i.e. when decrypted cipher algo is invalid).
 		// For most (but not all) cases, inputting a wrong passwords is expected to trigger this error.
-		return nil, errors.New("gopenpgp: wrong password in symmetric decryption")
+		return nil, newClassifiedError(ErrWrongPassphrase, "gopenpgp: wrong password in symmetric decryption", nil)
 	}
 
 	config := &packet.Config{
 		Time: getTimeGenerator(),
 	}
 
+	applyConfigModifier(config)
+
 	var emptyKeyRing openpgp.EntityList
+	encryptedIO, decompressionLimiter := newDecompressionLimiter(encryptedIO)
 	md, err := openpgp.ReadMessage(encryptedIO, emptyKeyRing, prompt, config)
 	if err != nil {
 		// Parsing errors when reading the message are most likely caused by incorrect password, but we cannot know for sure
-		return nil, errors.New("gopenpgp: error in reading password protected message: wrong password or malformed message")
+		return nil, newClassifiedError(ErrWrongPassphrase, "gopenpgp: error in reading password protected message: wrong password or malformed message", nil)
 	}
 
 	messageBuf := bytes.NewBuffer(nil)
-	_, err = io.Copy(messageBuf, md.UnverifiedBody)
+	_, err = io.Copy(messageBuf, decompressionLimiter.limitReader(md.UnverifiedBody))
+	if errors.Is(err, ErrDecompressionBomb) {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading password protected message")
+	}
 	if errors.Is(err, pgpErrors.ErrMDCHashMismatch) {
 		// This MDC error may also be triggered if the password is correct, but the encrypted data was corrupted.
 		// To avoid confusion, we do not inform the user about the second possibility.
-		return nil, errors.New("gopenpgp: wrong password in symmetric decryption")
+		return nil, newClassifiedError(ErrWrongPassphrase, "gopenpgp: wrong password in symmetric decryption", nil)
 	}
 	if err != nil {
 		// Parsing errors after decryption, triggered before parsing the MDC packet, are also usually the result of wrong password
-		return nil, errors.New("gopenpgp: error in reading password protected message: wrong password or malformed message")
+		return nil, newClassifiedError(ErrWrongPassphrase, "gopenpgp: error in reading password protected message: wrong password or malformed message", nil)
 	}
 
 	return &PlainMessage{
 		Data:     messageBuf.Bytes(),
 		TextType: !md.LiteralData.IsBinary,
+		IsUTF8:   md.LiteralData.Format == 'u',
 		Filename: md.LiteralData.FileName,
 		Time:     md.LiteralData.Time,
 	}, nil
@@ -0,0 +1,28 @@
+package crypto
+
+import (
+	"io"
+)
+
+// SetRandomSource sets the source of randomness used to generate keys,
+// session keys, salts and encryption nonces, e.g. a hardware RNG.
+// Passing nil restores the default, crypto/rand.
+// The reader must be safe for concurrent use and must only return errors
+// for unrecoverable failures.
+func SetRandomSource(random Reader) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.random = random
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getRandomSource returns the configured source of randomness, or nil to let
+// go-crypto use crypto/rand.
+func getRandomSource() io.Reader {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+
+	return pgp.random
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// readAheadChunkSize is the size of the plaintext chunks decrypted ahead.
+const readAheadChunkSize = 1 << 16
+
+// SetReadAhead makes the reader read and decrypt up to chunks chunks of
+// 64 KiB of plaintext ahead, in a background goroutine, so that reading the
+// message from a remote source and decrypting it overlap with the processing
+// of the plaintext, instead of each Read waiting for them.
+// It must be called before the first Read. If the message isn't read
+// entirely, Close must be called to stop the goroutine.
+func (msg *PlainMessageReader) SetReadAhead(chunks int) error {
+	if chunks <= 0 {
+		return errors.New("gopenpgp: the read-ahead must be at least one chunk")
+	}
+	if msg.readAhead != nil || msg.bytesRead > 0 || msg.readAll {
+		return errors.New("gopenpgp: the read-ahead must be set before reading the message")
+	}
+	msg.readAhead = newReadAheadReader(msg.details.UnverifiedBody, chunks)
+	return nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// readAheadReader reads chunks from a reader in a goroutine, up to a number
+// of chunks ahead of its own reads.
+type readAheadReader struct {
+	chunks    chan readAheadChunk
+	done      chan struct{}
+	closeOnce sync.Once
+	current   []byte
+	err       error
+}
+
+type readAheadChunk struct {
+	data []byte
+	err  error
+}
+
+func newReadAheadReader(reader io.Reader, chunks int) *readAheadReader {
+	readAhead := &readAheadReader{
+		chunks: make(chan readAheadChunk, chunks),
+		done:   make(chan struct{}),
+	}
+	go readAhead.prefetch(reader)
+	return readAhead
+}
+
+func (r *readAheadReader) prefetch(reader io.Reader) {
+	defer close(r.chunks)
+	for {
+		buffer := make([]byte, readAheadChunkSize)
+		n, err := io.ReadFull(reader, buffer)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
+		select {
+		case r.chunks <- readAheadChunk{data: buffer[:n], err: err}:
+		case <-r.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (r *readAheadReader) Read(b []byte) (int, error) {
+	for len(r.current) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		chunk, ok := <-r.chunks
+		if !ok {
+			return 0, errors.New("gopenpgp: read from a closed message reader")
+		}
+		r.current, r.err = chunk.data, chunk.err
+	}
+	n := copy(b, r.current)
+	r.current = r.current[n:]
+	return n, nil
+}
+
+// Close stops the goroutine.
+func (r *readAheadReader) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainMessageReader_SetReadAhead(t *testing.T) {
+	message := NewPlainMessage(bytes.Repeat([]byte("Hello World!\n"), 30000))
+	pgpMessage, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting plaintext, got:", err)
+	}
+
+	decryptedReader, err := keyRingTestPrivate.DecryptStream(
+		bytes.NewReader(pgpMessage.GetBinary()),
+		keyRingTestPublic,
+		GetUnixTime(),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	assert.Error(t, decryptedReader.SetReadAhead(0))
+	if err = decryptedReader.SetReadAhead(2); err != nil {
+		t.Fatal("Expected no error while setting the read-ahead, got:", err)
+	}
+	assert.Error(t, decryptedReader.SetReadAhead(2))
+	decryptedBytes, err := ioutil.ReadAll(decryptedReader)
+	if err != nil {
+		t.Fatal("Expected no error while reading the decrypted data, got:", err)
+	}
+	assert.Exactly(t, message.GetBinary(), decryptedBytes)
+	if err = decryptedReader.VerifySignature(); err != nil {
+		t.Fatal("Expected no error while verifying the signature, got:", err)
+	}
+
+	decryptedReader, err = keyRingTestPrivate.DecryptStream(
+		bytes.NewReader(pgpMessage.GetBinary()),
+		keyRingTestPublic,
+		GetUnixTime(),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	if err = decryptedReader.SetReadAhead(1); err != nil {
+		t.Fatal("Expected no error while setting the read-ahead, got:", err)
+	}
+	if _, err = decryptedReader.Read(make([]byte, 1024)); err != nil {
+		t.Fatal("Expected no error while reading the decrypted data, got:", err)
+	}
+	assert.NoError(t, decryptedReader.Close())
+	assert.Error(t, decryptedReader.VerifySignature())
+}
@@ -0,0 +1,110 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+)
+
+// Iteration counts of the iterated and salted S2K.
+const (
+	// minS2KCount and maxS2KCount are the counts go-crypto encodes,
+	// see RFC 9580, section 3.7.1.3.
+	minS2KCount = 65536
+	maxS2KCount = 65011712
+	// defaultLockS2KCount is the count used by Key.Lock by default.
+	defaultLockS2KCount = 65536
+	// s2kCalibrationTime is the time spent measuring the hash speed.
+	s2kCalibrationTime = 50 * time.Millisecond
+)
+
+// SetS2KCount sets the iteration count of the iterated and salted S2K used
+// by Key.Lock and by the password encryption functions, e.g. to the value
+// returned by CalibrateS2KCount on the device, since a fixed count is too
+// low for servers and too high for wearables.
+// The count is rounded up to a count that OpenPGP can encode, between 65536
+// and 65011712. Passing 0 restores the defaults: 65536 for Key.Lock, and
+// 16777216 for the password encryption.
+// Key.LockWithAEAD and the key backups use Argon2, and ignore the count.
+func SetS2KCount(count int) {
+	if count > 0 {
+		count = roundS2KCount(count)
+	}
+
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.s2kCount = count
+}
+
+// CalibrateS2KCount returns the iteration count of the iterated and salted
+// S2K that takes about duration to derive a key from a passphrase on this
+// device, as measured by hashing with SHA-256 for 50 milliseconds.
+// The count is rounded to a count that OpenPGP can encode, between 65536 and
+// 65011712, and can be passed to SetS2KCount.
+func CalibrateS2KCount(duration time.Duration) int {
+	var buffer [4096]byte
+	hash := sha256.New()
+	hashed := 0
+	start := time.Now()
+	elapsed := time.Duration(0)
+	for elapsed < s2kCalibrationTime {
+		for i := 0; i < 16; i++ {
+			_, _ = hash.Write(buffer[:])
+		}
+		hashed += 16 * len(buffer)
+		elapsed = time.Since(start)
+	}
+
+	count := float64(hashed) * float64(duration) / float64(elapsed)
+	if count > maxS2KCount {
+		return maxS2KCount
+	}
+	return roundS2KCount(int(count))
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getS2KCount returns the count set with SetS2KCount, or defaultCount.
+func getS2KCount(defaultCount int) int {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+
+	if pgp.s2kCount <= 0 {
+		return defaultCount
+	}
+	return pgp.s2kCount
+}
+
+// getPasswordS2KConfig returns the S2K configuration of the password
+// encryption, or nil for the default of go-crypto.
+func getPasswordS2KConfig() *s2k.Config {
+	count := getS2KCount(0)
+	if count == 0 {
+		return nil
+	}
+	return &s2k.Config{
+		S2KMode:  s2k.IteratedSaltedS2K,
+		S2KCount: count,
+		Hash:     crypto.SHA256,
+	}
+}
+
+// roundS2KCount returns the smallest count that OpenPGP can encode which is
+// at least count, within the encodable range.
+func roundS2KCount(count int) int {
+	for encoded := 0; encoded < 256; encoded++ {
+		if decoded := decodeS2KCount(uint8(encoded)); decoded >= count && decoded >= minS2KCount {
+			return decoded
+		}
+	}
+	return maxS2KCount
+}
+
+// decodeS2KCount returns the count of an encoded count, see RFC 9580,
+// section 3.7.1.3.
+func decodeS2KCount(encoded uint8) int {
+	return (16 + int(encoded&15)) << (uint32(encoded>>4) + 6)
+}
@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetS2KCount(t *testing.T) {
+	SetS2KCount(1 << 20)
+	defer SetS2KCount(0)
+
+	locked, err := keyTestEC.Lock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	assert.Exactly(t, 1<<20, getLockedS2KCount(t, locked))
+	if _, err = locked.Unlock(testMailboxPassword); err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := EncryptMessageWithPassword(message, testSymmetricKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting with password, got:", err)
+	}
+	decrypted, err := DecryptMessageWithPassword(ciphertext, testSymmetricKey)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with password, got:", err)
+	}
+	assert.Exactly(t, testMessage, decrypted.GetString())
+
+	SetS2KCount(0)
+	locked, err = keyTestEC.Lock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	assert.Exactly(t, defaultLockS2KCount, getLockedS2KCount(t, locked))
+}
+
+func TestCalibrateS2KCount(t *testing.T) {
+	count := CalibrateS2KCount(10 * time.Millisecond)
+	assert.True(t, count >= minS2KCount && count <= maxS2KCount)
+	assert.Exactly(t, count, roundS2KCount(count))
+	assert.Exactly(t, maxS2KCount, CalibrateS2KCount(time.Hour))
+
+	assert.Exactly(t, minS2KCount, roundS2KCount(1))
+	assert.Exactly(t, 69632, roundS2KCount(minS2KCount+1))
+	assert.Exactly(t, maxS2KCount, roundS2KCount(maxS2KCount))
+}
+
+// getLockedS2KCount returns the S2K count of the primary key of a key locked
+// with Key.Lock.
+func getLockedS2KCount(t *testing.T, key *Key) int {
+	var public, private bytes.Buffer
+	if err := key.entity.PrimaryKey.Serialize(&public); err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	if err := key.entity.PrivateKey.Serialize(&private); err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	// The usage, cipher, S2K mode, hash and salt precede the count
+	return decodeS2KCount(private.Bytes()[public.Len()+12])
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
 	"github.com/pkg/errors"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -22,6 +23,8 @@ type SessionKey struct {
 	Key []byte
 	// The symmetric encryption algorithm used with this key.
 	Algo string
+
+	lockedBuffer *internal.LockedBuffer
 }
 
 var symKeyAlgos = map[string]packet.CipherFunction{
@@ -63,7 +66,7 @@ func (sk *SessionKey) GetCipherFunc() (packet.CipherFunction, error) {
 	}
 	cf, ok := symKeyAlgos[sk.Algo]
 	if !ok {
-		return cf, errors.New("gopenpgp: unsupported cipher function: " + sk.Algo)
+		return cf, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: unsupported cipher function: "+sk.Algo, nil)
 	}
 	return cf, nil
 }
@@ -75,7 +78,8 @@ func (sk *SessionKey) GetBase64Key() string {
 
 // RandomToken generates a random token with the specified key size.
 func RandomToken(size int) ([]byte, error) {
-	config := &packet.Config{DefaultCipher: packet.CipherAES256}
+	config := &packet.Config{Rand: getRandomSource(), DefaultCipher: packet.CipherAES256}
+	applyConfigModifier(config)
 	symKey := make([]byte, size)
 	if _, err := io.ReadFull(config.Random(), symKey); err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in generating random token")
@@ -88,18 +92,14 @@ func RandomToken(size int) ([]byte, error) {
 func GenerateSessionKeyAlgo(algo string) (sk *SessionKey, err error) {
 	cf, ok := symKeyAlgos[algo]
 	if !ok {
-		return nil, errors.New("gopenpgp: unknown symmetric key generation algorithm")
+		return nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: unknown symmetric key generation algorithm", nil)
 	}
 	r, err := RandomToken(cf.KeySize())
 	if err != nil {
 		return nil, err
 	}
 
-	sk = &SessionKey{
-		Key:  r,
-		Algo: algo,
-	}
-	return sk, nil
+	return newSessionKey(r, algo, false), nil
 }
 
 // GenerateSessionKey generates a random key for the default cipher.
@@ -108,11 +108,7 @@ func GenerateSessionKey() (*SessionKey, error) {
 }
 
 func NewSessionKeyFromToken(token []byte, algo string) *SessionKey {
-	return &SessionKey{
-		Key:  clone(token),
-		Algo: algo,
-		V6:   algo == "",
-	}
+	return newSessionKey(clone(token), algo, algo == "")
 }
 
 func newSessionKeyFromEncrypted(ek *packet.EncryptedKey) (*SessionKey, error) {
@@ -127,11 +123,7 @@ func newSessionKeyFromEncrypted(ek *packet.EncryptedKey) (*SessionKey, error) {
 		return nil, fmt.Errorf("gopenpgp: unsupported cipher function: %v", ek.CipherFunc)
 	}
 
-	sk := &SessionKey{
-		Key:  ek.Key,
-		Algo: algo,
-		V6:   ek.Version == 6,
-	}
+	sk := newSessionKey(ek.Key, algo, ek.Version == 6)
 
 	if err := sk.checkSize(); err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: unable to decrypt session key")
@@ -144,7 +136,7 @@ func newSessionKeyFromEncrypted(ek *packet.EncryptedKey) (*SessionKey, error) {
 // * message : The plain data as a PlainMessage.
 // * output  : The encrypted data as PGPMessage.
 func (sk *SessionKey) Encrypt(message *PlainMessage) ([]byte, error) {
-	return encryptWithSessionKey(message, sk, nil, false, nil)
+	return encryptWithSessionKey(message, sk, nil, false, nil, nil)
 }
 
 // EncryptAndSign encrypts a PlainMessage to PGPMessage with a SessionKey and signs it with a Private key.
@@ -152,7 +144,7 @@ func (sk *SessionKey) Encrypt(message *PlainMessage) ([]byte, error) {
 // * signKeyRing: The KeyRing to sign the message
 // * output  : The encrypted data as PGPMessage.
 func (sk *SessionKey) EncryptAndSign(message *PlainMessage, signKeyRing *KeyRing) ([]byte, error) {
-	return encryptWithSessionKey(message, sk, signKeyRing, false, nil)
+	return encryptWithSessionKey(message, sk, signKeyRing, false, nil, nil)
 }
 
 // EncryptAndSignWithContext encrypts a PlainMessage to PGPMessage with a SessionKey and signs it with a Private key.
@@ -161,14 +153,14 @@ func (sk *SessionKey) EncryptAndSign(message *PlainMessage, signKeyRing *KeyRing
 // * output  : The encrypted data as PGPMessage.
 // * signingContext : (optional) the context for the signature.
 func (sk *SessionKey) EncryptAndSignWithContext(message *PlainMessage, signKeyRing *KeyRing, signingContext *SigningContext) ([]byte, error) {
-	return encryptWithSessionKey(message, sk, signKeyRing, false, signingContext)
+	return encryptWithSessionKey(message, sk, signKeyRing, false, signingContext, nil)
 }
 
 // EncryptWithCompression encrypts with compression support a PlainMessage to PGPMessage with a SessionKey.
 // * message : The plain data as a PlainMessage.
 // * output  : The encrypted data as PGPMessage.
 func (sk *SessionKey) EncryptWithCompression(message *PlainMessage) ([]byte, error) {
-	return encryptWithSessionKey(message, sk, nil, true, nil)
+	return encryptWithSessionKey(message, sk, nil, true, nil, nil)
 }
 
 func encryptWithSessionKey(
@@ -177,7 +169,11 @@ func encryptWithSessionKey(
 	signKeyRing *KeyRing,
 	compress bool,
 	signingContext *SigningContext,
+	paddingPolicy *PaddingPolicy,
 ) ([]byte, error) {
+	if err := message.checkUTF8(); err != nil {
+		return nil, err
+	}
 	var encBuf = new(bytes.Buffer)
 
 	encryptWriter, signWriter, err := encryptStreamWithSessionKey(
@@ -191,6 +187,7 @@ func encryptWithSessionKey(
 		signKeyRing,
 		compress,
 		signingContext,
+		paddingPolicy,
 	)
 	if err != nil {
 		return nil, err
@@ -224,6 +221,7 @@ func encryptStreamWithSessionKey(
 	signKeyRing *KeyRing,
 	compress bool,
 	signingContext *SigningContext,
+	paddingPolicy *PaddingPolicy,
 ) (encryptWriter, signWriter io.WriteCloser, err error) {
 	dc, err := sk.GetCipherFunc()
 	if err != nil {
@@ -231,6 +229,7 @@ func encryptStreamWithSessionKey(
 	}
 
 	config := &packet.Config{
+		Rand:          getRandomSource(),
 		Time:          getTimeGenerator(),
 		DefaultCipher: dc,
 	}
@@ -252,13 +251,11 @@ func encryptStreamWithSessionKey(
 		config.SignatureNotations = append(config.SignatureNotations, signingContext.getNotation())
 	}
 
+	applyConfigModifier(config)
+
 	if plainMessageMetadata == nil {
 		// Use sensible default metadata
-		plainMessageMetadata = &PlainMessageMetadata{
-			IsBinary: true,
-			Filename: "",
-			ModTime:  GetUnixTime(),
-		}
+		plainMessageMetadata = NewDefaultPlainMessageMetadata()
 	}
 
 	return encryptStreamWithSessionKeyAndConfig(
@@ -269,6 +266,7 @@ func encryptStreamWithSessionKey(
 		sk,
 		signEntity,
 		config,
+		paddingPolicy,
 	)
 }
 
@@ -280,6 +278,7 @@ func encryptStreamWithSessionKeyAndConfig(
 	sk *SessionKey,
 	signEntity *openpgp.Entity,
 	config *packet.Config,
+	paddingPolicy *PaddingPolicy,
 ) (encryptWriter, signWriter io.WriteCloser, err error) {
 	encryptWriter, err = packet.SerializeSymmetricallyEncrypted(
 		dataPacketWriter,
@@ -293,6 +292,11 @@ func encryptStreamWithSessionKeyAndConfig(
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "gopenpgp: unable to encrypt")
 	}
+	logEncryptionAlgorithms("encrypt with session key", config)
+
+	if paddingPolicy != nil {
+		encryptWriter = &paddingWriteCloser{writer: encryptWriter, policy: paddingPolicy, rand: config.Random()}
+	}
 
 	if algo := config.Compression(); algo != packet.CompressionNone {
 		encryptWriter, err = packet.SerializeCompressed(encryptWriter, algo, config.CompressionConfig)
@@ -312,6 +316,8 @@ func encryptStreamWithSessionKeyAndConfig(
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "gopenpgp: unable to sign")
 		}
+		logSigning("encrypt with session key", signEntity, config)
+		auditSigning("encrypt and sign with session key", signEntity, config.Now())
 	} else {
 		encryptWriter, err = packet.SerializeLiteral(
 			encryptWriter,
@@ -322,6 +328,7 @@ func encryptStreamWithSessionKeyAndConfig(
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "gopenpgp: unable to serialize")
 		}
+		encryptWriter = newChunkedWriteCloser(encryptWriter, encryptionChunkSize)
 	}
 	return encryptWriter, signWriter, nil
 }
@@ -380,19 +387,27 @@ func decryptWithSessionKeyAndContext(
 	messageBuf := new(bytes.Buffer)
 	_, err = messageBuf.ReadFrom(md.UnverifiedBody)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in reading message body")
+		return nil, newReadError(err, "gopenpgp: error in reading message body")
 	}
 
+	var signature *packet.Signature
+	var unverifiedSignatures []*packet.Signature
+	var signer *SignatureSigner
 	if verifyKeyRing != nil {
-		processSignatureExpiration(md, verifyTime)
-		err = verifyDetailsSignature(md, verifyKeyRing, verificationContext)
+		signature, signer, err = verifyEmbeddedSignature(md, messageBuf.Bytes(), verifyKeyRing, verifyTime, verificationContext)
+		unverifiedSignatures = md.UnverifiedSignatures
 	}
 
 	return &PlainMessage{
-		Data:     messageBuf.Bytes(),
-		TextType: !md.LiteralData.IsBinary,
-		Filename: md.LiteralData.FileName,
-		Time:     md.LiteralData.Time,
+		Data:      messageBuf.Bytes(),
+		TextType:  !md.LiteralData.IsBinary,
+		IsUTF8:    md.LiteralData.Format == 'u',
+		Filename:  md.LiteralData.FileName,
+		Time:      md.LiteralData.Time,
+		signature: signature,
+		signer:    signer,
+
+		unverifiedSignatures: unverifiedSignatures,
 	}, err
 }
 
@@ -409,7 +424,7 @@ func decryptStreamWithSessionKey(
 	packets := packet.NewReader(messageReader)
 	p, err := packets.Next()
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: unable to read symmetric packet")
+		return nil, newReadError(err, "gopenpgp: unable to read symmetric packet")
 	}
 
 	// Decrypt data packet
@@ -417,7 +432,7 @@ func decryptStreamWithSessionKey(
 	case *packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
 		if symPacket, ok := p.(*packet.SymmetricallyEncrypted); ok {
 			if !symPacket.IntegrityProtected {
-				return nil, errors.New("gopenpgp: message is not authenticated")
+				return nil, newIntegrityError("gopenpgp: message is not authenticated", nil)
 			}
 		}
 		dc, err := sk.GetCipherFunc()
@@ -430,10 +445,10 @@ func decryptStreamWithSessionKey(
 		}
 		decrypted, err = encryptedDataPacket.Decrypt(dc, sk.Key)
 		if err != nil {
-			return nil, errors.Wrap(err, "gopenpgp: unable to decrypt symmetric packet")
+			return nil, newReadError(err, "gopenpgp: unable to decrypt symmetric packet")
 		}
 	default:
-		return nil, errors.New("gopenpgp: invalid packet type")
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: invalid packet type", nil)
 	}
 
 	config := &packet.Config{
@@ -451,12 +466,17 @@ func decryptStreamWithSessionKey(
 		keyring = openpgp.EntityList{}
 	}
 
-	md, err := openpgp.ReadMessage(decrypted, keyring, nil, config)
+	applyConfigModifier(config)
+	decryptedPackets, decompressionLimiter := newDecompressionLimiter(decrypted)
+	md, err := openpgp.ReadMessage(decryptedPackets, keyring, nil, config)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: unable to decode symmetric packet")
+		logEvent(constants.LOG_LEVEL_ERROR, constants.LogEventPacketParsed, "operation", "decrypt with session key", "error", err.Error())
+		return nil, newReadError(err, "gopenpgp: unable to decode symmetric packet")
 	}
+	logMessageDetails("decrypt with session key", md)
 
-	md.UnverifiedBody = checkReader{decrypted, md.UnverifiedBody}
+	body := decompressionLimiter.limitReader(checkReader{decrypted, md.UnverifiedBody})
+	md.UnverifiedBody = verifyKeyRing.getVerificationLimits().limitReader(body)
 	return md, nil
 }
 
@@ -469,7 +489,7 @@ func (sk *SessionKey) checkSize() error {
 	}
 	cf, ok := symKeyAlgos[sk.Algo]
 	if !ok {
-		return errors.New("unknown symmetric key algorithm")
+		return newClassifiedError(ErrUnsupportedAlgorithm, "unknown symmetric key algorithm", nil)
 	}
 
 	if cf.KeySize() != len(sk.Key) {
@@ -0,0 +1,564 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/eax"
+	"github.com/ProtonMail/go-crypto/ocb"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// Size of the version, cipher, AEAD mode and chunk size octets, and of
+	// the salt of a SEIPDv2 packet.
+	seipdV2HeaderSize = 4 + 32
+)
+
+// DecryptToWriterAt decrypts the AEAD encrypted data packet (SEIPDv2) of
+// dataPacketSize bytes read from dataPacket, e.g. a downloaded file, and
+// writes the data of its literal data packet to output, each chunk at its
+// offset in the data. The chunks are read, decrypted and written by workers
+// goroutines in parallel, or runtime.NumCPU() goroutines if workers is not
+// positive, which is much faster than DecryptStream on multi-core machines.
+// Truncation is detected before anything is written, and each chunk is
+// authenticated before being written. On error, the output is incomplete
+// and must be discarded.
+// Signed or compressed messages, and messages not encrypted with SEIPDv2,
+// are not supported: they must be decrypted with DecryptStream.
+// It returns the metadata of the literal data packet, and the size of the
+// data written.
+func (sk *SessionKey) DecryptToWriterAt(
+	dataPacket io.ReaderAt,
+	dataPacketSize int64,
+	output io.WriterAt,
+	workers int,
+) (*PlainMessageMetadata, int64, error) {
+	body, err := newPacketBodyReader(dataPacket, dataPacketSize, packetTagSEIPD)
+	if err != nil {
+		return nil, 0, err
+	}
+	decrypter, err := newParallelDecrypter(sk, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if err := decrypter.decrypt(output, workers); err != nil {
+		return nil, 0, err
+	}
+	return decrypter.parser.metadata, decrypter.parser.size, nil
+}
+
+// DecryptToWriterAt decrypts the session key of keyPacket with the keyring,
+// and the AEAD encrypted data packet read from dataPacket to output, as
+// SessionKey.DecryptToWriterAt.
+func (keyRing *KeyRing) DecryptToWriterAt(
+	keyPacket []byte,
+	dataPacket io.ReaderAt,
+	dataPacketSize int64,
+	output io.WriterAt,
+	workers int,
+) (*PlainMessageMetadata, int64, error) {
+	sessionKey, err := keyRing.DecryptSessionKey(keyPacket)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer sessionKey.Clear()
+	return sessionKey.DecryptToWriterAt(dataPacket, dataPacketSize, output, workers)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// packetBodyReader reads the body of a packet, which may be split in partial
+// bodies, at any offset.
+type packetBodyReader struct {
+	reader io.ReaderAt
+	// segments are the parts of the body, in order.
+	segments []packetBodySegment
+	size     int64
+}
+
+type packetBodySegment struct {
+	// offset is the offset of the segment in reader.
+	offset int64
+	// bodyOffset is the offset of the segment in the body.
+	bodyOffset int64
+	length     int64
+}
+
+// newPacketBodyReader reads the headers of the packet of the given tag
+// filling the size bytes of reader, without reading its body.
+func newPacketBodyReader(reader io.ReaderAt, size int64, tag int) (*packetBodyReader, error) {
+	errTruncated := newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated packet", nil)
+	header, err := readHeaderAt(reader, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	packetTag, length, partial, headerSize, ok, err := parsePacketHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errTruncated
+	}
+	if packetTag != tag {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: invalid packet type", nil)
+	}
+
+	body := &packetBodyReader{reader: reader}
+	offset := int64(headerSize)
+	for {
+		if length < 0 {
+			// Indeterminate length, the packet extends to the end of the data
+			length = size - offset
+		}
+		if length > size-offset {
+			return nil, errTruncated
+		}
+		body.segments = append(body.segments, packetBodySegment{offset: offset, bodyOffset: body.size, length: length})
+		body.size += length
+		offset += length
+		if !partial {
+			break
+		}
+		header, err = readHeaderAt(reader, offset, size)
+		if err != nil {
+			return nil, err
+		}
+		var lengthSize int
+		length, partial, lengthSize, ok = parseNewFormatLength(header)
+		if !ok {
+			return nil, errTruncated
+		}
+		offset += int64(lengthSize)
+	}
+	if offset != size {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: unexpected data after the packet", nil)
+	}
+	return body, nil
+}
+
+// ReadAt reads len(b) bytes of the body at offset.
+func (body *packetBodyReader) ReadAt(b []byte, offset int64) (int, error) {
+	if offset+int64(len(b)) > body.size {
+		return 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated packet", nil)
+	}
+	index := sort.Search(len(body.segments), func(i int) bool {
+		segment := body.segments[i]
+		return segment.bodyOffset+segment.length > offset
+	})
+	read := 0
+	for read < len(b) {
+		segment := body.segments[index]
+		start := offset + int64(read) - segment.bodyOffset
+		length := segment.length - start
+		if length > int64(len(b)-read) {
+			length = int64(len(b) - read)
+		}
+		if _, err := body.reader.ReadAt(b[read:read+int(length)], segment.offset+start); err != nil {
+			return read, errors.Wrap(err, "gopenpgp: unable to read encrypted data")
+		}
+		read += int(length)
+		index++
+	}
+	return read, nil
+}
+
+// readHeaderAt reads the bytes of the packet or length header at offset,
+// which may be less than a full header at the end of the data.
+func readHeaderAt(reader io.ReaderAt, offset, size int64) ([]byte, error) {
+	header := make([]byte, 6)
+	if size-offset < int64(len(header)) {
+		if offset >= size {
+			return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated packet", nil)
+		}
+		header = header[:size-offset]
+	}
+	if _, err := reader.ReadAt(header, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read encrypted data")
+	}
+	return header, nil
+}
+
+// parallelDecrypter decrypts the chunks of a SEIPDv2 packet in parallel.
+type parallelDecrypter struct {
+	body               *packetBodyReader
+	mode               packet.AEADMode
+	key                []byte
+	nonce              []byte
+	associatedData     []byte
+	chunkSize          int64
+	encryptedChunkSize int64
+	chunks             int64
+
+	// parser and failed are guarded by mutex, the workers wait on turn
+	// until the chunk they decrypted is the next one to parse.
+	mutex  sync.Mutex
+	turn   *sync.Cond
+	parser *literalDataParser
+	failed error
+}
+
+// newParallelDecrypter reads the header of the SEIPDv2 packet body, derives
+// the message key from the session key, and checks the final tag.
+func newParallelDecrypter(sk *SessionKey, body *packetBodyReader) (*parallelDecrypter, error) {
+	header := make([]byte, seipdV2HeaderSize)
+	if _, err := body.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	if header[0] != 2 {
+		return nil, newClassifiedError(
+			ErrUnsupportedAlgorithm,
+			"gopenpgp: only AEAD encrypted messages (SEIPDv2) can be decrypted in parallel",
+			nil,
+		)
+	}
+	cipherFunc, mode, chunkSizeByte := packet.CipherFunction(header[1]), packet.AEADMode(header[2]), header[3]
+	switch cipherFunc {
+	case packet.CipherAES128, packet.CipherAES192, packet.CipherAES256:
+	default:
+		return nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: unsupported cipher function for AEAD", nil)
+	}
+	if !mode.IsSupported() {
+		return nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: unsupported AEAD mode", nil)
+	}
+	if chunkSizeByte > 16 {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: invalid AEAD chunk size", nil)
+	}
+	if len(sk.Key) != cipherFunc.KeySize() {
+		return nil, errors.New("gopenpgp: wrong session key size")
+	}
+
+	decrypter := &parallelDecrypter{
+		body:           body,
+		mode:           mode,
+		key:            make([]byte, cipherFunc.KeySize()),
+		nonce:          make([]byte, mode.IvLength()-8),
+		associatedData: []byte{0xc0 | packetTagSEIPD, header[0], header[1], header[2], header[3]},
+		chunkSize:      int64(1) << (chunkSizeByte + 6),
+		parser:         &literalDataParser{},
+	}
+	decrypter.turn = sync.NewCond(&decrypter.mutex)
+	hkdfReader := hkdf.New(sha256.New, sk.Key, header[4:], decrypter.associatedData)
+	if _, err := io.ReadFull(hkdfReader, decrypter.key); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to derive message key")
+	}
+	if _, err := io.ReadFull(hkdfReader, decrypter.nonce); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to derive message key")
+	}
+
+	// Each chunk, including the last one, is followed by its tag, and the
+	// final tag ends the packet
+	tagSize := int64(mode.TagLength())
+	encryptedSize := body.size - seipdV2HeaderSize - tagSize
+	decrypter.encryptedChunkSize = decrypter.chunkSize + tagSize
+	decrypter.chunks = (encryptedSize + decrypter.encryptedChunkSize - 1) / decrypter.encryptedChunkSize
+	if encryptedSize < tagSize || encryptedSize-(decrypter.chunks-1)*decrypter.encryptedChunkSize < tagSize {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated encrypted data", nil)
+	}
+
+	aead, err := decrypter.newAEAD()
+	if err != nil {
+		return nil, err
+	}
+	finalTag := make([]byte, tagSize)
+	if _, err := body.ReadAt(finalTag, body.size-tagSize); err != nil {
+		return nil, err
+	}
+	plainSize := make([]byte, 8)
+	binary.BigEndian.PutUint64(plainSize, uint64(encryptedSize-decrypter.chunks*tagSize))
+	finalAssociatedData := append(append([]byte{}, decrypter.associatedData...), plainSize...)
+	if _, err := aead.Open(nil, decrypter.getNonce(decrypter.chunks), finalTag, finalAssociatedData); err != nil {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: invalid final authentication tag", err)
+	}
+	return decrypter, nil
+}
+
+// decrypt decrypts the chunks with the given number of workers, and writes
+// their data to output.
+func (decrypter *parallelDecrypter) decrypt(output io.WriterAt, workers int) error {
+	indices := make(chan int64)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := decrypter.work(indices, output); err != nil {
+				decrypter.fail(err)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indices)
+		for index := int64(0); index < decrypter.chunks; index++ {
+			select {
+			case indices <- index:
+			case <-done:
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+
+	if decrypter.failed != nil {
+		return decrypter.failed
+	}
+	return decrypter.parser.finish()
+}
+
+// work decrypts the chunks received from indices, in order, until a worker
+// fails.
+func (decrypter *parallelDecrypter) work(indices <-chan int64, output io.WriterAt) error {
+	aead, err := decrypter.newAEAD()
+	if err != nil {
+		return err
+	}
+	encrypted := make([]byte, decrypter.encryptedChunkSize)
+	plaintext := make([]byte, decrypter.chunkSize)
+	for index := range indices {
+		offset := seipdV2HeaderSize + index*decrypter.encryptedChunkSize
+		size := decrypter.encryptedChunkSize
+		if index == decrypter.chunks-1 {
+			size = decrypter.body.size - int64(aead.Overhead()) - offset
+		}
+		if _, err := decrypter.body.ReadAt(encrypted[:size], offset); err != nil {
+			return err
+		}
+		plaintext, err = aead.Open(plaintext[:0], decrypter.getNonce(index), encrypted[:size], decrypter.associatedData)
+		if err != nil {
+			return newClassifiedError(ErrMessageCorrupt, "gopenpgp: unable to authenticate chunk", err)
+		}
+
+		// The packet framing of the plaintext is parsed in order
+		decrypter.mutex.Lock()
+		for decrypter.failed == nil && decrypter.parser.chunks != index {
+			decrypter.turn.Wait()
+		}
+		if decrypter.failed != nil {
+			decrypter.mutex.Unlock()
+			return nil
+		}
+		segments, err := decrypter.parser.parse(plaintext)
+		decrypter.turn.Broadcast()
+		decrypter.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+
+		for _, segment := range segments {
+			if _, err := output.WriteAt(segment.data, segment.offset); err != nil {
+				return errors.Wrap(err, "gopenpgp: unable to write decrypted data")
+			}
+		}
+	}
+	return nil
+}
+
+// fail stops the workers with the first error.
+func (decrypter *parallelDecrypter) fail(err error) {
+	decrypter.mutex.Lock()
+	defer decrypter.mutex.Unlock()
+	if decrypter.failed == nil {
+		decrypter.failed = err
+	}
+	decrypter.turn.Broadcast()
+}
+
+func (decrypter *parallelDecrypter) newAEAD() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(decrypter.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to create cipher")
+	}
+	var aead cipher.AEAD
+	switch decrypter.mode {
+	case packet.AEADModeEAX:
+		aead, err = eax.NewEAX(block)
+	case packet.AEADModeOCB:
+		aead, err = ocb.NewOCB(block)
+	default:
+		aead, err = cipher.NewGCM(block)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to create cipher")
+	}
+	return aead, nil
+}
+
+// getNonce returns the nonce of the chunk with the given index.
+func (decrypter *parallelDecrypter) getNonce(index int64) []byte {
+	nonce := make([]byte, len(decrypter.nonce)+8)
+	copy(nonce, decrypter.nonce)
+	binary.BigEndian.PutUint64(nonce[len(decrypter.nonce):], uint64(index))
+	return nonce
+}
+
+// literalDataSegment is a part of the data of the literal data packet in a
+// chunk, and its offset in the data.
+type literalDataSegment struct {
+	data   []byte
+	offset int64
+}
+
+// literalDataParser parses the packet framing of the decrypted chunks, in
+// order, to find the data of the literal data packet. The literal data
+// packet may only be followed by padding packets.
+type literalDataParser struct {
+	// chunks is the number of chunks parsed.
+	chunks int64
+	// header holds the bytes of the packet or length header being read.
+	header        []byte
+	readingLength bool
+	// tag is the tag of the current packet, 0 between packets.
+	tag int
+	// remaining is the number of bytes left in the current body segment,
+	// -1 until the end of the data.
+	remaining int64
+	partial   bool
+	// literalHeader holds the format, file name and date of the literal
+	// data packet, while they are read.
+	literalHeader []byte
+	metadata      *PlainMessageMetadata
+	size          int64
+}
+
+// parse parses the next chunk, and returns the literal data it contains.
+func (parser *literalDataParser) parse(chunk []byte) ([]literalDataSegment, error) {
+	parser.chunks++
+	var segments []literalDataSegment
+	for len(chunk) > 0 {
+		if parser.tag == 0 || parser.readingLength {
+			consumed, err := parser.parseHeader(chunk)
+			if err != nil {
+				return nil, err
+			}
+			chunk = chunk[consumed:]
+		} else {
+			body := chunk
+			if parser.remaining >= 0 && int64(len(body)) > parser.remaining {
+				body = body[:parser.remaining]
+			}
+			chunk = chunk[len(body):]
+			if parser.remaining >= 0 {
+				parser.remaining -= int64(len(body))
+			}
+			if parser.tag == packetTagLiteralData {
+				if data := parser.parseLiteralHeader(body); len(data) > 0 {
+					segments = append(segments, literalDataSegment{data: data, offset: parser.size})
+					parser.size += int64(len(data))
+				}
+			}
+		}
+
+		if parser.tag == 0 || parser.readingLength || parser.remaining != 0 {
+			continue
+		}
+		if parser.partial {
+			parser.readingLength = true
+			continue
+		}
+		if parser.tag == packetTagLiteralData && parser.metadata == nil {
+			return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated literal data packet", nil)
+		}
+		parser.tag = 0
+	}
+	return segments, nil
+}
+
+// parseHeader reads the bytes of a packet or partial length header from
+// chunk, and returns the number of bytes consumed.
+func (parser *literalDataParser) parseHeader(chunk []byte) (int, error) {
+	// A header is at most 6 bytes long
+	previous := len(parser.header)
+	if len(chunk) > 6-previous {
+		chunk = chunk[:6-previous]
+	}
+	parser.header = append(parser.header, chunk...)
+
+	var headerSize int
+	var ok bool
+	if parser.readingLength {
+		parser.remaining, parser.partial, headerSize, ok = parseNewFormatLength(parser.header)
+		if !ok {
+			return len(parser.header) - previous, nil
+		}
+		parser.readingLength = false
+	} else {
+		var tag int
+		var err error
+		tag, parser.remaining, parser.partial, headerSize, ok, err = parsePacketHeader(parser.header)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return len(parser.header) - previous, nil
+		}
+		switch {
+		case tag == packetTagLiteralData && parser.metadata == nil && parser.literalHeader == nil:
+			parser.literalHeader = []byte{}
+		case tag == packetTagPadding:
+		case tag == packetTagCompressed || tag == packetTagOnePassSignature:
+			return 0, newClassifiedError(
+				ErrUnsupportedAlgorithm,
+				"gopenpgp: signed or compressed messages can't be decrypted in parallel",
+				nil,
+			)
+		default:
+			return 0, newClassifiedError(ErrMessageCorrupt, "gopenpgp: unexpected packet in encrypted data", nil)
+		}
+		parser.tag = tag
+	}
+	parser.header = parser.header[:0]
+	return headerSize - previous, nil
+}
+
+// parseLiteralHeader reads the metadata of the literal data packet from the
+// start of body, and returns the data following it.
+func (parser *literalDataParser) parseLiteralHeader(body []byte) []byte {
+	for parser.metadata == nil && len(body) > 0 {
+		// Format, file name length, file name and date
+		size := 6
+		if len(parser.literalHeader) >= 2 {
+			size += int(parser.literalHeader[1])
+		}
+		missing := size - len(parser.literalHeader)
+		if missing > len(body) {
+			missing = len(body)
+		}
+		parser.literalHeader = append(parser.literalHeader, body[:missing]...)
+		body = body[missing:]
+		if len(parser.literalHeader) < 2 || len(parser.literalHeader) < 6+int(parser.literalHeader[1]) {
+			continue
+		}
+
+		header := parser.literalHeader
+		parser.metadata = &PlainMessageMetadata{
+			IsBinary: header[0] == 'b',
+			IsUTF8:   header[0] == 'u',
+			Filename: string(header[2 : len(header)-4]),
+			ModTime:  int64(binary.BigEndian.Uint32(header[len(header)-4:])),
+		}
+	}
+	return body
+}
+
+// finish checks that the literal data packet was read entirely.
+func (parser *literalDataParser) finish() error {
+	complete := parser.tag == 0 || parser.remaining < 0
+	if parser.metadata == nil || !complete || parser.readingLength || len(parser.header) > 0 {
+		return newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated literal data packet", nil)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func encryptWithSessionKeyStream(t *testing.T, sessionKey *SessionKey, data []byte, signKeyRing *KeyRing) []byte {
+	var dataPacket bytes.Buffer
+	writer, err := sessionKey.EncryptStream(&dataPacket, NewPlainMessageMetadata(true, "data.bin", 1700000000), signKeyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if _, err = writer.Write(data); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	return dataPacket.Bytes()
+}
+
+func TestDecryptToWriterAt(t *testing.T) {
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	data := make([]byte, 100000)
+	if _, err = rand.Read(data); err != nil {
+		t.Fatal("Expected no error while generating data, got:", err)
+	}
+
+	seipdV1 := encryptWithSessionKeyStream(t, sessionKey, data, nil)
+	SetConfigModifier(func(config *packet.Config) {
+		config.AEADConfig = &packet.AEADConfig{DefaultMode: packet.AEADModeOCB, ChunkSize: 256}
+	})
+	defer SetConfigModifier(nil)
+	dataPacket := encryptWithSessionKeyStream(t, sessionKey, data, nil)
+	signed := encryptWithSessionKeyStream(t, sessionKey, data, keyRingTestPrivate)
+
+	decryptToFile := func(dataPacket []byte) ([]byte, *PlainMessageMetadata, int64, error) {
+		output, err := os.Create(filepath.Join(t.TempDir(), "output"))
+		if err != nil {
+			t.Fatal("Expected no error while creating file, got:", err)
+		}
+		defer output.Close()
+		metadata, size, err := sessionKey.DecryptToWriterAt(bytes.NewReader(dataPacket), int64(len(dataPacket)), output, 4)
+		written, readErr := ioutil.ReadFile(output.Name())
+		if readErr != nil {
+			t.Fatal("Expected no error while reading file, got:", readErr)
+		}
+		return written, metadata, size, err
+	}
+
+	decrypted, metadata, size, err := decryptToFile(dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data, decrypted)
+	assert.Exactly(t, int64(len(data)), size)
+	assert.Exactly(t, NewPlainMessageMetadata(true, "data.bin", 1700000000), metadata)
+
+	tampered := clone(dataPacket)
+	tampered[len(tampered)/2] ^= 1
+	_, _, _, err = decryptToFile(tampered)
+	assert.True(t, errors.Is(err, ErrMessageCorrupt))
+
+	// Truncation is detected before anything is written
+	written, _, _, err := decryptToFile(dataPacket[:len(dataPacket)-300])
+	assert.True(t, errors.Is(err, ErrMessageCorrupt))
+	assert.Empty(t, written)
+
+	_, _, _, err = decryptToFile(seipdV1)
+	assert.True(t, errors.Is(err, ErrUnsupportedAlgorithm))
+	_, _, _, err = decryptToFile(signed)
+	assert.True(t, errors.Is(err, ErrUnsupportedAlgorithm))
+}
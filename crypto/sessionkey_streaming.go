@@ -1,9 +1,5 @@
 package crypto
 
-import (
-	"github.com/pkg/errors"
-)
-
 type signAndEncryptWriteCloser struct {
 	signWriter    WriteCloser
 	encryptWriter WriteCloser
@@ -109,6 +105,7 @@ func (sk *SessionKey) encryptStream(
 		signKeyRing,
 		compress,
 		signingContext,
+		nil,
 	)
 
 	if err != nil {
@@ -119,7 +116,7 @@ func (sk *SessionKey) encryptStream(
 	} else {
 		plainMessageWriter = encryptWriter
 	}
-	return plainMessageWriter, err
+	return newUTF8WriteCloser(plainMessageWriter, plainMessageMetadata), err
 }
 
 // DecryptStream is used to decrypt a data packet as a Reader.
@@ -176,14 +173,14 @@ func decryptStreamWithSessionKeyAndContext(
 		verificationContext,
 	)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: error in reading message")
+		return nil, newReadError(err, "gopenpgp: error in reading message")
 	}
 
 	return &PlainMessageReader{
-		messageDetails,
-		verifyKeyRing,
-		verifyTime,
-		false,
-		verificationContext,
+		details:             messageDetails,
+		verifyKeyRing:       verifyKeyRing,
+		verifyTime:          verifyTime,
+		readAll:             false,
+		verificationContext: verificationContext,
 	}, err
 }
@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// GetString returns the session key in the format of GnuPG's
+// --show-session-key and --override-session-key options, the decimal
+// OpenPGP identifier of its algorithm and the hex key, e.g. "9:3A...", so
+// that a single message can be decrypted with a disclosed session key
+// across tools.
+// Session keys of v6 messages, whose algorithm is stored with the data, are
+// returned as the hex key only, as Sequoia does.
+func (sk *SessionKey) GetString() (string, error) {
+	key := strings.ToUpper(hex.EncodeToString(sk.Key))
+	if sk.V6 {
+		return key, nil
+	}
+	cf, err := sk.GetCipherFunc()
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(int(cf)) + ":" + key, nil
+}
+
+// NewSessionKeyFromString parses a session key of a v4 message in the
+// format returned by SessionKey.GetString, e.g. by GnuPG's
+// --show-session-key option. A hex key without algorithm is rejected, as it
+// can't be told apart from a truncated input: the session keys of v6
+// messages are parsed with NewV6SessionKeyFromString.
+func NewSessionKeyFromString(s string) (*SessionKey, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("gopenpgp: invalid session key: missing algorithm")
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid session key algorithm")
+	}
+	algo := getAlgo(packet.CipherFunction(id))
+	if id <= 0 || id > 255 || symKeyAlgos[algo] != packet.CipherFunction(id) {
+		return nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: unsupported cipher function: "+parts[0], nil)
+	}
+	return parseSessionKeyString(parts[1], algo)
+}
+
+// NewV6SessionKeyFromString parses a session key of a v6 message in the
+// format returned by SessionKey.GetString, the hex key only, as Sequoia does.
+func NewV6SessionKeyFromString(s string) (*SessionKey, error) {
+	return parseSessionKeyString(strings.TrimSpace(s), "")
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// parseSessionKeyString parses a hex session key, of a v6 message if algo
+// is empty.
+func parseSessionKeyString(encodedKey, algo string) (*SessionKey, error) {
+	key, err := hex.DecodeString(encodedKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid session key")
+	}
+	sk := newSessionKey(key, algo, algo == "")
+	if err := sk.checkSize(); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid session key")
+	}
+	return sk, nil
+}
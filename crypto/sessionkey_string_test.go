@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionKeyString(t *testing.T) {
+	sessionKey, err := GenerateSessionKeyAlgo("aes128")
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	encoded, err := sessionKey.GetString()
+	if err != nil {
+		t.Fatal("Expected no error while encoding session key, got:", err)
+	}
+	assert.Regexp(t, "^7:[0-9A-F]{32}$", encoded)
+
+	decoded, err := NewSessionKeyFromString(encoded)
+	if err != nil {
+		t.Fatal("Expected no error while parsing session key, got:", err)
+	}
+	assert.Exactly(t, sessionKey.Algo, decoded.Algo)
+	assert.Exactly(t, sessionKey.Key, decoded.Key)
+	assert.False(t, decoded.V6)
+
+	decoded, err = NewSessionKeyFromString("9:" + strings.Repeat("A5", 32))
+	if err != nil {
+		t.Fatal("Expected no error while parsing session key, got:", err)
+	}
+	assert.Exactly(t, "aes256", decoded.Algo)
+
+	// A hex key alone is only accepted for v6 messages
+	_, err = NewSessionKeyFromString(strings.Repeat("A5", 32))
+	assert.Error(t, err)
+	decoded, err = NewV6SessionKeyFromString(strings.Repeat("A5", 32))
+	if err != nil {
+		t.Fatal("Expected no error while parsing session key, got:", err)
+	}
+	assert.True(t, decoded.V6)
+	encoded, err = decoded.GetString()
+	if err != nil {
+		t.Fatal("Expected no error while encoding session key, got:", err)
+	}
+	assert.Exactly(t, strings.Repeat("A5", 32), encoded)
+
+	_, err = NewSessionKeyFromString("9:" + strings.Repeat("A5", 16))
+	assert.Error(t, err)
+	_, err = NewSessionKeyFromString("100:" + strings.Repeat("A5", 16))
+	assert.True(t, errors.Is(err, ErrUnsupportedAlgorithm))
+	_, err = NewSessionKeyFromString("9:XYZ")
+	assert.Error(t, err)
+	_, err = NewV6SessionKeyFromString("9:" + strings.Repeat("A5", 32))
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -444,3 +445,79 @@ func TestSEDDecryption(t *testing.T) {
 		t.Fatal("sed packets without authentication should not be allowed", err)
 	}
 }
+
+func TestSessionKeyLockedMemory(t *testing.T) {
+	SetLockedMemory(true)
+	defer SetLockedMemory(false)
+
+	sk, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	assert.NotNil(t, sk.lockedBuffer)
+
+	message := NewPlainMessageFromString("locked")
+	dataPacket, err := sk.Encrypt(message)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	keyPacket, err := keyRingTestPublic.EncryptSessionKey(sk)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+
+	decryptedSk, err := keyRingTestPrivate.DecryptSessionKey(keyPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	assert.Exactly(t, sk.Key, decryptedSk.Key)
+	assert.Exactly(t, sk.IsInLockedMemory(), decryptedSk.IsInLockedMemory())
+
+	decrypted, err := decryptedSk.Decrypt(dataPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+
+	sk.Clear()
+	decryptedSk.Clear()
+	assertMemCleared(t, sk.Key)
+	assert.Len(t, sk.Key, 32)
+	assert.False(t, sk.IsInLockedMemory())
+}
+
+func TestSessionKeyLockedMemoryPool(t *testing.T) {
+	SetLockedMemory(true)
+	defer SetLockedMemory(false)
+
+	// More keys than fit in a single locked page
+	for i := 0; i < 200; i++ {
+		sk, err := GenerateSessionKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating session key, got:", err)
+		}
+		key := sk.Key
+		sk.Clear()
+		// The memory stays mapped after clearing
+		assertMemCleared(t, key)
+	}
+}
+
+func TestSetRandomSource(t *testing.T) {
+	SetRandomSource(bytes.NewReader(bytes.Repeat([]byte{0x42}, 32)))
+	defer SetRandomSource(nil)
+
+	sk, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	assert.Exactly(t, bytes.Repeat([]byte{0x42}, 32), sk.Key)
+
+	// The source is exhausted
+	_, err = GenerateSessionKey()
+	assert.Error(t, err)
+
+	SetRandomSource(nil)
+	_, err = GenerateSessionKey()
+	assert.NoError(t, err)
+}
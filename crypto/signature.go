@@ -14,7 +14,6 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ProtonMail/gopenpgp/v2/constants"
-	"github.com/ProtonMail/gopenpgp/v2/internal"
 )
 
 var allowedHashes = []crypto.Hash{
@@ -30,6 +29,9 @@ type SignatureVerificationError struct {
 	Status  int
 	Message string
 	Cause   error
+	// insecure is set when the signature is valid, but made with an
+	// insecure hash algorithm.
+	insecure bool
 }
 
 // Error is the base method for all errors.
@@ -71,8 +73,9 @@ func newSignatureFailed(cause error) SignatureVerificationError {
 // SignatureFailed, with a message describing the signature as insecure.
 func newSignatureInsecure() SignatureVerificationError {
 	return SignatureVerificationError{
-		Status:  constants.SIGNATURE_FAILED,
-		Message: "Insecure signature",
+		Status:   constants.SIGNATURE_FAILED,
+		Message:  "Insecure signature",
+		insecure: true,
 	}
 }
 
@@ -110,13 +113,19 @@ func processSignatureExpiration(md *openpgp.MessageDetails, verifyTime int64) {
 	if md.Signature.SigLifetimeSecs != nil {
 		expires = int64(*md.Signature.SigLifetimeSecs) + created
 	}
-	if created-internal.CreationTimeOffset <= verifyTime && verifyTime <= expires {
+	if created-GetTimeOffsetTolerance() <= verifyTime && verifyTime <= expires {
 		md.SignatureError = nil
 	}
 }
 
 // verifyDetailsSignature verifies signature from message details.
 func verifyDetailsSignature(md *openpgp.MessageDetails, verifierKey *KeyRing, verificationContext *VerificationContext) error {
+	err := checkDetailsSignature(md, verifierKey, verificationContext)
+	logSignatureChecked("verify embedded", md.SignedByKeyId, err)
+	return err
+}
+
+func checkDetailsSignature(md *openpgp.MessageDetails, verifierKey *KeyRing, verificationContext *VerificationContext) error {
 	if !md.IsSigned {
 		return newSignatureNotSigned()
 	}
@@ -128,10 +137,8 @@ func verifyDetailsSignature(md *openpgp.MessageDetails, verifierKey *KeyRing, ve
 	if md.SignatureError != nil {
 		return newSignatureFailed(md.SignatureError)
 	}
-	if md.Signature == nil ||
-		md.Signature.Hash < allowedHashes[0] ||
-		md.Signature.Hash > allowedHashes[len(allowedHashes)-1] {
-		return newSignatureInsecure()
+	if md.Signature == nil {
+		return newSignatureFailed(errors.New("gopenpgp: no signature"))
 	}
 	if verificationContext != nil {
 		err := verificationContext.verifyContext(md.Signature)
@@ -139,7 +146,14 @@ func verifyDetailsSignature(md *openpgp.MessageDetails, verifierKey *KeyRing, ve
 			return newSignatureBadContext(err)
 		}
 	}
-
+	if err := verifierKey.checkVerifyTimeWindow(md.Signature); err != nil {
+		return err
+	}
+	// Only a signature passing all the other checks is merely insecure
+	if md.Signature.Hash < allowedHashes[0] ||
+		md.Signature.Hash > allowedHashes[len(allowedHashes)-1] {
+		return newSignatureInsecure()
+	}
 	return nil
 }
 
@@ -166,6 +180,14 @@ func (context *SigningContext) getNotation() *packet.Notation {
 	}
 }
 
+// getNotations returns the notation of the context, if not nil.
+func (context *SigningContext) getNotations() []*packet.Notation {
+	if context == nil {
+		return nil
+	}
+	return []*packet.Notation{context.getNotation()}
+}
+
 // VerificationContext gives the context that will be
 // used to verify the signature.
 type VerificationContext struct {
@@ -227,6 +249,67 @@ func (context *VerificationContext) verifyContext(sig *packet.Signature) error {
 
 // verifySignature verifies if a signature is valid with the entity list.
 func verifySignature(
+	pubKeyEntries openpgp.EntityList,
+	limits *VerificationLimits,
+	origText io.Reader,
+	signature []byte,
+	verifyTime int64,
+	verificationContext *VerificationContext,
+) (*packet.Signature, error) {
+	if err := limits.checkDetachedSignature(signature); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading signature")
+	}
+	origText = limits.limitReader(origText)
+	timer := startOperation(constants.MetricsOperationVerify)
+	var counter *countingReader
+	if timer != nil {
+		origText, counter = newCountingReader(origText)
+	}
+	sig, err := checkSignature(pubKeyEntries, origText, signature, verifyTime, verificationContext)
+	timer.finish(counter.count(), err)
+	var keyID uint64
+	if sig != nil && sig.IssuerKeyId != nil {
+		keyID = *sig.IssuerKeyId
+	}
+	logSignatureChecked("verify detached", keyID, err)
+	return sig, err
+}
+
+func checkSignature(
+	pubKeyEntries openpgp.EntityList,
+	origText io.Reader,
+	signature []byte,
+	verifyTime int64,
+	verificationContext *VerificationContext,
+) (*packet.Signature, error) {
+	signers := getCollidingSigners(pubKeyEntries, signature)
+	if len(signers) < 2 {
+		return checkSignatureWithEntities(pubKeyEntries, origText, signature, verifyTime, verificationContext)
+	}
+	// go-crypto checks the keys sharing the issuer key ID with the same hash
+	// of the data, consumed by the first one: the data is kept to check the
+	// signature with each of them.
+	data, err := io.ReadAll(origText)
+	if err != nil {
+		return nil, newSignatureFailed(err)
+	}
+	for _, signer := range signers {
+		var sig *packet.Signature
+		sig, err = checkSignatureWithEntities(
+			openpgp.EntityList{signer},
+			bytes.NewReader(data),
+			signature,
+			verifyTime,
+			verificationContext,
+		)
+		if err == nil {
+			return sig, nil
+		}
+	}
+	return nil, err
+}
+
+func checkSignatureWithEntities(
 	pubKeyEntries openpgp.EntityList,
 	origText io.Reader,
 	signature []byte,
@@ -240,13 +323,14 @@ func verifySignature(
 		}
 	} else {
 		config.Time = func() time.Time {
-			return time.Unix(verifyTime+internal.CreationTimeOffset, 0)
+			return time.Unix(verifyTime+GetTimeOffsetTolerance(), 0)
 		}
 	}
 
 	if verificationContext != nil {
 		config.KnownNotations = map[string]bool{constants.SignatureContextName: true}
 	}
+	applyConfigModifier(config)
 	signatureReader := bytes.NewReader(signature)
 
 	sig, signer, err := openpgp.VerifyDetachedSignatureAndHash(pubKeyEntries, origText, signatureReader, allowedHashes, config)
@@ -302,9 +386,26 @@ func signMessageDetached(
 	signKeyRing *KeyRing,
 	messageReader io.Reader,
 	isBinary bool,
-	context *SigningContext,
+	notations []*packet.Notation,
+) (*PGPSignature, error) {
+	timer := startOperation(constants.MetricsOperationSign)
+	var counter *countingReader
+	if timer != nil {
+		messageReader, counter = newCountingReader(messageReader)
+	}
+	signature, err := createDetachedSignature(signKeyRing, messageReader, isBinary, notations)
+	timer.finish(counter.count(), err)
+	return signature, err
+}
+
+func createDetachedSignature(
+	signKeyRing *KeyRing,
+	messageReader io.Reader,
+	isBinary bool,
+	notations []*packet.Notation,
 ) (*PGPSignature, error) {
 	config := &packet.Config{
+		Rand:        getRandomSource(),
 		DefaultHash: crypto.SHA512,
 		Time:        getTimeGenerator(),
 	}
@@ -314,9 +415,10 @@ func signMessageDetached(
 		return nil, err
 	}
 
-	if context != nil {
-		config.SignatureNotations = append(config.SignatureNotations, context.getNotation())
-	}
+	config.SignatureNotations = append(config.SignatureNotations, notations...)
+
+	applyConfigModifier(config)
+	logSigning("sign detached", signEntity, config)
 
 	var outBuf bytes.Buffer
 	if isBinary {
@@ -327,6 +429,7 @@ func signMessageDetached(
 	if err != nil {
 		return nil, errors.Wrap(err, "gopenpgp: error in signing")
 	}
+	auditSigning("sign detached", signEntity, config.Now())
 
 	return NewPGPSignature(outBuf.Bytes()), nil
 }
@@ -0,0 +1,205 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"hash"
+	"strconv"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// SignatureHasher computes a detached signature incrementally: the message is
+// written to it, e.g. with an io.MultiWriter together with a checksum or an
+// upload, so that the message is processed in a single pass, and the
+// signature is created by FinalizeSignature.
+// The signature is the same as with SignDetachedStream, including the salt
+// of v6 signatures, which is hashed before the message.
+type SignatureHasher struct {
+	primaryKey *packet.PublicKey
+	signingKey *packet.PrivateKey
+	signature  *packet.Signature
+	config     *packet.Config
+	hash       hash.Hash
+	writer     hash.Hash
+	timer      *operationTimer
+	written    int64
+	finalized  bool
+}
+
+// NewSignatureHasher returns a SignatureHasher signing with the keyring, as
+// SignDetachedStreamWithContext. If isBinary is false, the message is signed
+// as text, and its line endings are canonicalized while hashing.
+// If a context is provided, it is added to the signature as notation data
+// with the name set in `constants.SignatureContextName`.
+func (keyRing *KeyRing) NewSignatureHasher(isBinary bool, context *SigningContext) (*SignatureHasher, error) {
+	return keyRing.NewSignatureHasherWithSalt(isBinary, context, nil)
+}
+
+// NewSignatureHasherWithSalt returns a SignatureHasher like
+// NewSignatureHasher, hashing the given salt instead of a random one, e.g. a
+// salt committed to beforehand, or a fixed salt in tests. Only v6 signatures
+// are salted: an error is returned if the signing key is not a v6 key, or if
+// the salt doesn't have the length of the salts of the hash algorithm of the
+// signature, returned by GetSignatureSaltSize.
+// A salt must never be reused: the salt prevents chosen-prefix attacks on the
+// hash only if it is unpredictable by the signed party.
+// If salt is nil, a random salt is used, from the source of randomness set
+// with SetRandomSource.
+func (keyRing *KeyRing) NewSignatureHasherWithSalt(isBinary bool, context *SigningContext, salt []byte) (*SignatureHasher, error) {
+	timer := startOperation(constants.MetricsOperationSign)
+	hasher, err := newSignatureHasher(keyRing, isBinary, context, salt)
+	if err != nil {
+		timer.finish(0, err)
+		return nil, err
+	}
+	hasher.timer = timer
+	return hasher, nil
+}
+
+// GetSignatureSaltSize returns the length of the salt of the v6 signatures
+// created by NewSignatureHasher, which depends on the hash algorithm of the
+// signatures, e.g. 32 bytes for SHA-512, or 0 if the signing key is not a v6
+// key.
+func (keyRing *KeyRing) GetSignatureSaltSize() (int, error) {
+	config, signingKey, _, err := getSignatureHasherConfig(keyRing, nil)
+	if err != nil {
+		return 0, err
+	}
+	if signingKey.PublicKey.Version != 6 {
+		return 0, nil
+	}
+	return packet.SaltLengthForHash(config.Hash())
+}
+
+// Write hashes the next part of the message.
+func (hasher *SignatureHasher) Write(p []byte) (int, error) {
+	if hasher.finalized {
+		return 0, errors.New("gopenpgp: the signature is already finalized")
+	}
+	n, err := hasher.writer.Write(p)
+	hasher.written += int64(n)
+	return n, err
+}
+
+// GetSalt returns the salt of the signature, hashed before the message, or
+// nil if the signing key is not a v6 key.
+func (hasher *SignatureHasher) GetSalt() []byte {
+	if salt := hasher.signature.Salt(); salt != nil {
+		return clone(salt)
+	}
+	return nil
+}
+
+// FinalizeSignature creates the signature of the message written so far.
+// The hasher can't be used anymore afterwards.
+func (hasher *SignatureHasher) FinalizeSignature() (*PGPSignature, error) {
+	if hasher.finalized {
+		return nil, errors.New("gopenpgp: the signature is already finalized")
+	}
+	hasher.finalized = true
+
+	signature, err := hasher.sign()
+	hasher.timer.finish(hasher.written, err)
+	return signature, err
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getSignatureHasherConfig returns the configuration and key of the
+// signatures created by a SignatureHasher.
+func getSignatureHasherConfig(keyRing *KeyRing, context *SigningContext) (*packet.Config, openpgp.Key, *openpgp.Entity, error) {
+	config := &packet.Config{
+		Rand:        getRandomSource(),
+		DefaultHash: crypto.SHA512,
+		Time:        getTimeGenerator(),
+	}
+
+	signEntity, err := keyRing.getSigningEntity()
+	if err != nil {
+		return nil, openpgp.Key{}, nil, err
+	}
+	signingKey, ok := signEntity.SigningKey(config.Now())
+	if !ok {
+		return nil, openpgp.Key{}, nil, errors.Wrap(newKeyCapabilityError(signEntity, constants.KeyCapabilitySign), "gopenpgp: error in signing")
+	}
+	if signingKey.PrivateKey == nil || signingKey.PrivateKey.Encrypted {
+		return nil, openpgp.Key{}, nil, errors.New("gopenpgp: error in signing: no valid signing keys")
+	}
+
+	if context != nil {
+		config.SignatureNotations = append(config.SignatureNotations, context.getNotation())
+	}
+
+	applyConfigModifier(config)
+	return config, signingKey, signEntity, nil
+}
+
+func newSignatureHasher(keyRing *KeyRing, isBinary bool, context *SigningContext, salt []byte) (*SignatureHasher, error) {
+	config, signingKey, signEntity, err := getSignatureHasherConfig(keyRing, context)
+	if err != nil {
+		return nil, err
+	}
+	logSigning("sign detached incrementally", signEntity, config)
+
+	sigType := packet.SigTypeBinary
+	if !isBinary {
+		sigType = packet.SigTypeText
+	}
+	sigLifetimeSecs := config.SigLifetime()
+	signature := &packet.Signature{
+		Version:           signingKey.PublicKey.Version,
+		SigType:           sigType,
+		PubKeyAlgo:        signingKey.PublicKey.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      config.Now(),
+		IssuerKeyId:       &signingKey.PublicKey.KeyId,
+		IssuerFingerprint: signingKey.PublicKey.Fingerprint,
+		Notations:         config.Notations(),
+		SigLifetimeSecs:   &sigLifetimeSecs,
+	}
+
+	if salt != nil {
+		if signature.Version != 6 {
+			return nil, errors.New("gopenpgp: error in signing: only v6 signatures are salted")
+		}
+		if err := signature.SetSalt(clone(salt)); err != nil {
+			saltSize, _ := packet.SaltLengthForHash(signature.Hash)
+			return nil, errors.Wrap(err, "gopenpgp: error in signing: the salt must be "+strconv.Itoa(saltSize)+" bytes long")
+		}
+	}
+
+	// Hashes the salt of v6 signatures
+	h, err := signature.PrepareSign(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+	writer := h
+	if !isBinary {
+		writer = openpgp.NewCanonicalTextHash(h)
+	}
+
+	return &SignatureHasher{
+		primaryKey: signEntity.PrimaryKey,
+		signingKey: signingKey.PrivateKey,
+		signature:  signature,
+		config:     config,
+		hash:       h,
+		writer:     writer,
+	}, nil
+}
+
+func (hasher *SignatureHasher) sign() (*PGPSignature, error) {
+	if err := hasher.signature.Sign(hasher.hash, hasher.signingKey, hasher.config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+	auditKeyUsage(constants.KeyUsageSign, "sign detached incrementally", hasher.primaryKey, &hasher.signingKey.PublicKey)
+	var outBuf bytes.Buffer
+	if err := hasher.signature.Serialize(&outBuf); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+	return NewPGPSignature(outBuf.Bytes()), nil
+}
@@ -0,0 +1,170 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureHasher(t *testing.T) {
+	data := []byte(strings.Repeat("incrementally hashed data\n", 1000))
+
+	hasher, err := keyRingTestPrivate.NewSignatureHasher(true, nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating hasher, got:", err)
+	}
+	checksum := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(hasher, checksum), strings.NewReader(string(data))); err != nil {
+		t.Fatal("Expected no error while hashing, got:", err)
+	}
+	signature, err := hasher.FinalizeSignature()
+	if err != nil {
+		t.Fatal("Expected no error while finalizing signature, got:", err)
+	}
+	assert.Exactly(t, sha256.Sum256(data), *(*[sha256.Size]byte)(checksum.Sum(nil)))
+
+	sigType, err := getSignatureType(signature)
+	if err != nil {
+		t.Fatal("Expected no error while parsing signature, got:", err)
+	}
+	assert.Exactly(t, packet.SigTypeBinary, sigType)
+	if err := keyRingTestPublic.VerifyDetached(NewPlainMessage(data), signature, testTime); err != nil {
+		t.Fatal("Expected no error while verifying signature, got:", err)
+	}
+
+	_, err = hasher.Write(data)
+	assert.Error(t, err)
+	_, err = hasher.FinalizeSignature()
+	assert.Error(t, err)
+}
+
+func TestSignatureHasherTextWithContext(t *testing.T) {
+	hasher, err := keyRingTestPrivate.NewSignatureHasher(false, NewSigningContext(testContext, true))
+	if err != nil {
+		t.Fatal("Expected no error while creating hasher, got:", err)
+	}
+	// Line endings are canonicalized, even when split between writes
+	for _, part := range []string{"line 1\r", "\nline 2\n", "line 3"} {
+		if _, err := hasher.Write([]byte(part)); err != nil {
+			t.Fatal("Expected no error while hashing, got:", err)
+		}
+	}
+	signature, err := hasher.FinalizeSignature()
+	if err != nil {
+		t.Fatal("Expected no error while finalizing signature, got:", err)
+	}
+
+	message := NewPlainMessageFromString("line 1\nline 2\nline 3")
+	verificationContext := NewVerificationContext(testContext, true, 0)
+	if err := keyRingTestPublic.VerifyDetachedWithContext(message, signature, testTime, verificationContext); err != nil {
+		t.Fatal("Expected no error while verifying signature, got:", err)
+	}
+}
+
+func TestSignatureHasherV6(t *testing.T) {
+	key, err := GenerateKeyV6("v6", "v6@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	hasher, err := keyRing.NewSignatureHasher(true, nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating hasher, got:", err)
+	}
+	if _, err := hasher.Write([]byte(testMessage)); err != nil {
+		t.Fatal("Expected no error while hashing, got:", err)
+	}
+	signature, err := hasher.FinalizeSignature()
+	if err != nil {
+		t.Fatal("Expected no error while finalizing signature, got:", err)
+	}
+	sigPacket, err := getSignaturePacket(signature)
+	if err != nil {
+		t.Fatal("Expected no error while parsing signature, got:", err)
+	}
+	assert.Exactly(t, 6, sigPacket.Version)
+	if err := keyRing.VerifyDetached(NewPlainMessageFromString(testMessage), signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying signature, got:", err)
+	}
+}
+
+func TestSignatureHasherWithSalt(t *testing.T) {
+	key, err := GenerateKeyV6("v6", "v6@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	saltSize, err := keyRing.GetSignatureSaltSize()
+	if err != nil {
+		t.Fatal("Expected no error while getting salt size, got:", err)
+	}
+	assert.Exactly(t, 32, saltSize)
+	salt := []byte(strings.Repeat("s", saltSize))
+	hasher, err := keyRing.NewSignatureHasherWithSalt(true, nil, salt)
+	if err != nil {
+		t.Fatal("Expected no error while creating hasher, got:", err)
+	}
+	assert.Exactly(t, salt, hasher.GetSalt())
+	if _, err := hasher.Write([]byte(testMessage)); err != nil {
+		t.Fatal("Expected no error while hashing, got:", err)
+	}
+	signature, err := hasher.FinalizeSignature()
+	if err != nil {
+		t.Fatal("Expected no error while finalizing signature, got:", err)
+	}
+	signatureSalt, err := signature.GetSalt()
+	if err != nil {
+		t.Fatal("Expected no error while reading salt, got:", err)
+	}
+	assert.Exactly(t, salt, signatureSalt)
+	if err := keyRing.VerifyDetached(NewPlainMessageFromString(testMessage), signature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying signature, got:", err)
+	}
+
+	_, err = keyRing.NewSignatureHasherWithSalt(true, nil, salt[:16])
+	assert.Error(t, err)
+	_, err = keyRingTestPrivate.NewSignatureHasherWithSalt(true, nil, salt)
+	assert.Error(t, err)
+	saltSize, err = keyRingTestPrivate.GetSignatureSaltSize()
+	if err != nil {
+		t.Fatal("Expected no error while getting salt size, got:", err)
+	}
+	assert.Zero(t, saltSize)
+
+	// The salt size follows the hash algorithm set by the config modifier
+	SetConfigModifier(func(config *packet.Config) { config.DefaultHash = crypto.SHA256 })
+	saltSize, err = keyRing.GetSignatureSaltSize()
+	SetConfigModifier(nil)
+	if err != nil {
+		t.Fatal("Expected no error while getting salt size, got:", err)
+	}
+	assert.Exactly(t, 16, saltSize)
+
+	hasher, err = keyRingTestPrivate.NewSignatureHasher(true, nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating hasher, got:", err)
+	}
+	assert.Nil(t, hasher.GetSalt())
+	signature, err = keyRingTestPrivate.SignDetached(NewPlainMessageFromString(testMessage))
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	signatureSalt, err = signature.GetSalt()
+	if err != nil {
+		t.Fatal("Expected no error while reading salt, got:", err)
+	}
+	assert.Nil(t, signatureSalt)
+}
@@ -0,0 +1,250 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+)
+
+const (
+	packetTagSignature = 2
+
+	subpacketTypeIssuer            = 16
+	subpacketTypeIssuerFingerprint = 33
+)
+
+// Normalize returns a normalized copy of the signature packets, so that two
+// copies of the same signatures can be compared byte for byte, e.g. when
+// storing them in a database:
+// * the unhashed subpackets, which are not covered by the signature and
+// can be modified by anyone, are removed, except the issuer key ID and
+// fingerprint when needed to find the verification key;
+// * the packets are serialized with new format headers with the shortest
+// length encoding.
+// The signatures stay valid, as only unsigned data is changed. A v4
+// signature can't be converted to a v6 signature or the reverse, as the
+// salt and the trailer of v6 signatures are signed.
+func (sig *PGPSignature) Normalize() (*PGPSignature, error) {
+	var normalized bytes.Buffer
+	for offset := 0; offset < len(sig.Data); {
+		tag, next, err := nextPacketOffset(sig.Data, offset)
+		if err != nil {
+			return nil, err
+		}
+		if tag != packetTagSignature {
+			return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: not a signature packet", nil)
+		}
+		body, err := getPacketBody(sig.Data, offset, next)
+		if err != nil {
+			return nil, err
+		}
+		body, err = stripUnhashedSubpackets(body)
+		if err != nil {
+			return nil, err
+		}
+		writePacketHeader(&normalized, packetTagSignature, len(body))
+		normalized.Write(body)
+		offset = next
+	}
+	return &PGPSignature{Data: normalized.Bytes()}, nil
+}
+
+// GetCanonicalArmored returns the armored signature without the optional
+// armor headers, e.g. Version and Comment, which differ between
+// implementations and versions.
+func (sig *PGPSignature) GetCanonicalArmored() (string, error) {
+	return armor.ArmorWithTypeAndCustomHeaders(sig.Data, constants.PGPSignatureHeader, "", "")
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getPacketBody returns the body of the packet starting at offset and ending
+// at next, see nextPacketOffset. Packets with partial body lengths are not
+// supported.
+func getPacketBody(data []byte, offset, next int) ([]byte, error) {
+	_, _, partial, headerSize, _, err := parsePacketHeader(data[offset:next])
+	if err != nil {
+		return nil, err
+	}
+	if partial {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: unexpected partial body length", nil)
+	}
+	return data[offset+headerSize : next], nil
+}
+
+// writePacketHeader writes a new format packet header with the shortest
+// length encoding, see RFC 4880, section 4.2.2.
+func writePacketHeader(buffer *bytes.Buffer, tag int, length int) {
+	buffer.WriteByte(0xc0 | byte(tag))
+	switch {
+	case length < 192:
+		buffer.WriteByte(byte(length))
+	case length < 8384:
+		length -= 192
+		buffer.WriteByte(192 + byte(length>>8))
+		buffer.WriteByte(byte(length))
+	default:
+		buffer.WriteByte(255)
+		_ = binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+}
+
+// stripUnhashedSubpackets removes the unhashed subpackets of the body of a
+// v4 or v6 signature packet, except the issuer subpackets if the hashed
+// area doesn't contain any. Other versions are returned unchanged.
+func stripUnhashedSubpackets(body []byte) ([]byte, error) {
+	areas, err := findSubpacketAreas(body)
+	if err != nil || areas == nil {
+		return body, err
+	}
+
+	hashedSubpackets, err := splitSubpackets(body[areas.hashedStart:areas.hashedEnd])
+	if err != nil {
+		return nil, err
+	}
+	unhashedSubpackets, err := splitSubpackets(body[areas.unhashedStart:areas.unhashedEnd])
+	if err != nil {
+		return nil, err
+	}
+	var kept [][]byte
+	if !hasIssuerSubpacket(hashedSubpackets) {
+		for _, subpacket := range unhashedSubpackets {
+			if isIssuerSubpacket(subpacket) {
+				kept = append(kept, subpacket)
+			}
+		}
+	}
+
+	stripped := make([]byte, 0, len(body))
+	stripped = append(stripped, body[:areas.hashedEnd]...)
+	stripped = append(stripped, make([]byte, areas.lengthSize)...)
+	keptLength := 0
+	for _, subpacket := range kept {
+		stripped = append(stripped, subpacket...)
+		keptLength += len(subpacket)
+	}
+	writeSubpacketsLength(stripped[areas.hashedEnd:], areas.lengthSize, keptLength)
+	return append(stripped, body[areas.unhashedEnd:]...), nil
+}
+
+// subpacketAreas are the offsets of the subpacket areas in the body of a
+// signature packet, each preceded by its length on lengthSize bytes.
+type subpacketAreas struct {
+	lengthSize                 int
+	hashedStart, hashedEnd     int
+	unhashedStart, unhashedEnd int
+}
+
+// findSubpacketAreas returns the subpacket areas of the body of a v4 or v6
+// signature packet, or nil for other versions.
+func findSubpacketAreas(body []byte) (*subpacketAreas, error) {
+	errTruncated := newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated signature packet", nil)
+	if len(body) == 0 {
+		return nil, errTruncated
+	}
+	lengthSize := 2
+	switch body[0] {
+	case 4:
+	case 6:
+		lengthSize = 4
+	default:
+		return nil, nil
+	}
+
+	// version, type, public key algorithm and hash algorithm
+	offset := 4
+	hashedStart := offset + lengthSize
+	if hashedStart > len(body) {
+		return nil, errTruncated
+	}
+	hashedEnd := hashedStart + readSubpacketsLength(body[offset:], lengthSize)
+	unhashedStart := hashedEnd + lengthSize
+	if hashedEnd < hashedStart || unhashedStart > len(body) {
+		return nil, errTruncated
+	}
+	unhashedEnd := unhashedStart + readSubpacketsLength(body[hashedEnd:], lengthSize)
+	if unhashedEnd < unhashedStart || unhashedEnd > len(body) {
+		return nil, errTruncated
+	}
+	return &subpacketAreas{
+		lengthSize:    lengthSize,
+		hashedStart:   hashedStart,
+		hashedEnd:     hashedEnd,
+		unhashedStart: unhashedStart,
+		unhashedEnd:   unhashedEnd,
+	}, nil
+}
+
+func readSubpacketsLength(data []byte, lengthSize int) int {
+	if lengthSize == 4 {
+		return int(binary.BigEndian.Uint32(data))
+	}
+	return int(binary.BigEndian.Uint16(data))
+}
+
+func writeSubpacketsLength(data []byte, lengthSize int, length int) {
+	if lengthSize == 4 {
+		binary.BigEndian.PutUint32(data, uint32(length))
+		return
+	}
+	binary.BigEndian.PutUint16(data, uint16(length))
+}
+
+// splitSubpackets splits the raw signature subpackets of an area, see
+// RFC 4880, section 5.2.3.1.
+func splitSubpackets(area []byte) ([][]byte, error) {
+	errTruncated := newClassifiedError(ErrMessageCorrupt, "gopenpgp: truncated signature subpacket", nil)
+	var subpackets [][]byte
+	for offset := 0; offset < len(area); {
+		var length, lengthSize int
+		switch first := area[offset]; {
+		case first < 192:
+			length, lengthSize = int(first), 1
+		case first < 255:
+			if offset+2 > len(area) {
+				return nil, errTruncated
+			}
+			length, lengthSize = (int(first)-192)<<8+int(area[offset+1])+192, 2
+		default:
+			if offset+5 > len(area) {
+				return nil, errTruncated
+			}
+			length, lengthSize = int(binary.BigEndian.Uint32(area[offset+1:])), 5
+		}
+		end := offset + lengthSize + length
+		if length == 0 || end < offset || end > len(area) {
+			return nil, errTruncated
+		}
+		subpackets = append(subpackets, area[offset:end])
+		offset = end
+	}
+	return subpackets, nil
+}
+
+// subpacketType returns the type of a raw subpacket, without the critical bit.
+func subpacketType(subpacket []byte) byte {
+	switch first := subpacket[0]; {
+	case first < 192:
+		return subpacket[1] & 0x7f
+	case first < 255:
+		return subpacket[2] & 0x7f
+	default:
+		return subpacket[5] & 0x7f
+	}
+}
+
+func isIssuerSubpacket(subpacket []byte) bool {
+	subpacketType := subpacketType(subpacket)
+	return subpacketType == subpacketTypeIssuer || subpacketType == subpacketTypeIssuerFingerprint
+}
+
+func hasIssuerSubpacket(subpackets [][]byte) bool {
+	for _, subpacket := range subpackets {
+		if isIssuerSubpacket(subpacket) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureNormalize(t *testing.T) {
+	signatureArmored, err := ioutil.ReadFile("testdata/signature/detachedSigSignedTwice")
+	if err != nil {
+		t.Fatal("Expected no error while reading signature, got:", err)
+	}
+	signature, err := NewPGPSignatureFromArmored(string(signatureArmored))
+	if err != nil {
+		t.Fatal("Expected no error while unarmoring signature, got:", err)
+	}
+
+	// The unhashed area only contains the issuer subpackets, which are kept
+	normalized, err := signature.Normalize()
+	if err != nil {
+		t.Fatal("Expected no error while normalizing signature, got:", err)
+	}
+	assert.Exactly(t, signature.GetBinary(), normalized.GetBinary())
+
+	// Re-encode the first packet with an old format header, and an
+	// additional unhashed subpacket
+	packet := signature.GetBinary()[:119]
+	body := append([]byte{}, packet[2:]...)
+	unhashedLengthOffset := 6 + int(binary.BigEndian.Uint16(body[4:]))
+	unhashedLength := binary.BigEndian.Uint16(body[unhashedLengthOffset:])
+	binary.BigEndian.PutUint16(body[unhashedLengthOffset:], unhashedLength+4)
+	unhashedStart := unhashedLengthOffset + 2
+	body = append(body[:unhashedStart], append([]byte{3, 100, 0xaa, 0xbb}, body[unhashedStart:]...)...)
+	modified := NewPGPSignature(append([]byte{0x89, 0, byte(len(body))}, body...))
+
+	normalized, err = modified.Normalize()
+	if err != nil {
+		t.Fatal("Expected no error while normalizing signature, got:", err)
+	}
+	assert.Exactly(t, packet, normalized.GetBinary())
+
+	publicKey, err := NewKeyFromArmored(readTestFile("signature/publicKey1", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading public key, got:", err)
+	}
+	keyRing, err := NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.NoError(t, keyRing.VerifyDetached(NewPlainMessageFromString("hello world"), normalized, 0))
+
+	armored, err := normalized.GetCanonicalArmored()
+	if err != nil {
+		t.Fatal("Expected no error while armoring signature, got:", err)
+	}
+	assert.NotContains(t, armored, "Version")
+
+	_, err = NewPGPSignature([]byte{0xc1, 0x00}).Normalize()
+	assert.Error(t, err)
+}
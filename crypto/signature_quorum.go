@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// SignatureQuorum requires valid signatures from several distinct keys of a
+// designated set, e.g. for release signing policies requiring the signatures
+// of two maintainers out of five.
+type SignatureQuorum struct {
+	// Signers are the designated keys. Signatures made by a key outside the
+	// set are ignored, and all the signatures made by the same primary key or
+	// its subkeys count once.
+	Signers *KeyRing
+	// Threshold is the minimum number of distinct signers.
+	Threshold int
+}
+
+// NewSignatureQuorum creates a new quorum of threshold signers from the keys
+// of signers, see SignatureQuorum.
+func NewSignatureQuorum(signers *KeyRing, threshold int) *SignatureQuorum {
+	return &SignatureQuorum{
+		Signers:   signers,
+		Threshold: threshold,
+	}
+}
+
+// VerifyDetached verifies each signature of a detached signature made of
+// several signature packets, e.g. concatenated by the maintainers, and
+// returns the hex fingerprints of the primary keys of the distinct signers,
+// in the order of their first valid signature.
+// Invalid signatures and signatures of unknown keys are skipped. The
+// verification settings of the signers keyring, e.g. its limits, apply.
+// If fewer than Threshold keys signed the message, the valid signers are
+// returned along with a SignatureVerificationError.
+func (quorum *SignatureQuorum) VerifyDetached(
+	message *PlainMessage,
+	signature *PGPSignature,
+	verifyTime int64,
+) ([]string, error) {
+	if quorum.Signers == nil || quorum.Threshold <= 0 {
+		return nil, errors.New("gopenpgp: the quorum needs signers and a positive threshold")
+	}
+	if err := quorum.Signers.getVerificationLimits().checkDetachedSignature(signature.GetBinary()); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading signature")
+	}
+	signatures, err := splitSignaturePackets(signature.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+
+	var signers []string
+	seen := make(map[string]bool)
+	for _, binary := range signatures {
+		sig, err := quorum.Signers.verifyMessageSignature(message, NewPGPSignature(binary), verifyTime, nil)
+		if err != nil {
+			continue
+		}
+		// Several signers may share the key ID of the signature
+		key, _, err := getSignatureSigningKey(quorum.Signers, sig, message.GetBinary())
+		if err != nil {
+			continue
+		}
+		fingerprint := hex.EncodeToString(key.Entity.PrimaryKey.Fingerprint)
+		if !seen[fingerprint] {
+			seen[fingerprint] = true
+			signers = append(signers, fingerprint)
+		}
+	}
+	if len(signers) < quorum.Threshold {
+		return signers, newSignatureFailed(errors.Errorf(
+			"gopenpgp: %d distinct signers out of the %d required",
+			len(signers), quorum.Threshold,
+		))
+	}
+	return signers, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// splitSignaturePackets splits a detached signature into its signature
+// packets.
+func splitSignaturePackets(data []byte) ([][]byte, error) {
+	var signatures [][]byte
+	for offset := 0; offset < len(data); {
+		tag, next, err := nextPacketOffset(data, offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: error in reading signature")
+		}
+		if tag != packetTagSignature {
+			return nil, errors.New("gopenpgp: error in reading signature: not a signature packet")
+		}
+		signatures = append(signatures, data[offset:next])
+		offset = next
+	}
+	if len(signatures) == 0 {
+		return nil, errors.New("gopenpgp: error in reading signature: no signature packet")
+	}
+	return signatures, nil
+}
@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureQuorum(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	var keys []*Key
+	var signatures [][]byte
+	for i := 0; i < 3; i++ {
+		key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		keyRing, err := NewKeyRing(key)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		signature, err := keyRing.SignDetached(message)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		keys = append(keys, key)
+		signatures = append(signatures, signature.GetBinary())
+	}
+
+	signers, err := NewKeyRing(nil)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	for _, key := range keys[:2] {
+		publicKey, err := key.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while extracting public key, got:", err)
+		}
+		if err := signers.AddKey(publicKey); err != nil {
+			t.Fatal("Expected no error while adding key, got:", err)
+		}
+	}
+	quorum := NewSignatureQuorum(signers, 2)
+
+	// The signature of the third key is ignored
+	all := NewPGPSignature(append(append(append([]byte{}, signatures[2]...), signatures[0]...), signatures[1]...))
+	fingerprints, err := quorum.VerifyDetached(message, all, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying quorum, got:", err)
+	}
+	assert.Exactly(t, []string{keys[0].GetFingerprint(), keys[1].GetFingerprint()}, fingerprints)
+
+	// The same signer counts once
+	twice := NewPGPSignature(append(append([]byte{}, signatures[0]...), signatures[0]...))
+	fingerprints, err = quorum.VerifyDetached(message, twice, GetUnixTime())
+	checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+	assert.Exactly(t, []string{keys[0].GetFingerprint()}, fingerprints)
+
+	_, err = quorum.VerifyDetached(NewPlainMessageFromString("wrong"), all, GetUnixTime())
+	checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+
+	_, err = NewSignatureQuorum(signers, 0).VerifyDetached(message, all, GetUnixTime())
+	assert.Error(t, err)
+}
+
+func TestSignatureQuorumKeyIDCollision(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	keyIDs, ok := getSignatureKeyIDs(signature.GetBinary())
+	if !ok || len(keyIDs) != 1 {
+		t.Fatal("Expected a signature key ID")
+	}
+
+	// A key crafted to collide with the key ID of the signer, first in the keyring.
+	colliding, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	colliding.entity.PrimaryKey.KeyId = keyIDs[0]
+	signer, err := keyRingTestPublic.GetKey(0)
+	if err != nil {
+		t.Fatal("Expected no error while getting key, got:", err)
+	}
+	signers, err := NewKeyRing(colliding)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = signers.AddKey(signer); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	fingerprints, err := NewSignatureQuorum(signers, 1).VerifyDetached(message, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying quorum, got:", err)
+	}
+	assert.Exactly(t, []string{signer.GetFingerprint()}, fingerprints)
+}
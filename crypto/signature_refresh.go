@@ -0,0 +1,120 @@
+package crypto
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// RefreshDetachedSignature re-signs the message of an old detached
+// signature, e.g. a historical release signature made with SHA-1 or a short
+// key, with the current algorithms and the keyring's signing key.
+// The old signature must be a valid signature of the message by a key of
+// verifyKey, at its creation time: keys that have since expired are
+// accepted, as are the legacy hash algorithms rejected by VerifyDetached.
+// The new signature has the type, binary or text, of the old signature, and
+// the notations of the old signature named in keptNotations. It records the
+// creation time of the old signature in a notation named
+// constants.OriginalSignatureTimeName, kept from the old signature if it was
+// itself refreshed, see GetOriginalSignatureTime.
+func (keyRing *KeyRing) RefreshDetachedSignature(
+	message *PlainMessage,
+	oldSignature *PGPSignature,
+	verifyKey *KeyRing,
+	keptNotations []string,
+) (*PGPSignature, error) {
+	sig, err := parseSignaturePacket(oldSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	var isBinary bool
+	switch sig.SigType {
+	case packet.SigTypeBinary:
+		isBinary = true
+	case packet.SigTypeText:
+		isBinary = false
+	default:
+		return nil, errors.New("gopenpgp: the old signature is not a detached signature of a message")
+	}
+	if err := verifyOldSignature(message, oldSignature, sig, verifyKey); err != nil {
+		return nil, err
+	}
+
+	notations := make([]*packet.Notation, 0, len(keptNotations)+1)
+	hasOriginalTime := false
+	for _, notation := range sig.Notations {
+		if notation.Name == constants.OriginalSignatureTimeName {
+			hasOriginalTime = true
+			notations = append(notations, notation)
+			continue
+		}
+		for _, name := range keptNotations {
+			if notation.Name == name {
+				notations = append(notations, notation)
+				break
+			}
+		}
+	}
+	if !hasOriginalTime {
+		notations = append(notations, &packet.Notation{
+			Name:            constants.OriginalSignatureTimeName,
+			Value:           []byte(sig.CreationTime.UTC().Format(time.RFC3339)),
+			IsHumanReadable: true,
+		})
+	}
+
+	return signMessageDetached(keyRing, message.NewReader(), isBinary, notations)
+}
+
+// GetOriginalSignatureTime returns the creation time of the original
+// signature replaced by a refreshed signature, see RefreshDetachedSignature,
+// and false if the signature was not refreshed.
+// The notation is only meaningful once the signature is verified.
+func GetOriginalSignatureTime(signature *PGPSignature) (int64, bool, error) {
+	sig, err := parseSignaturePacket(signature)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, notation := range sig.Notations {
+		if notation.Name != constants.OriginalSignatureTimeName {
+			continue
+		}
+		originalTime, err := time.Parse(time.RFC3339, string(notation.Value))
+		if err != nil {
+			return 0, false, errors.Wrap(err, "gopenpgp: invalid original signature time")
+		}
+		return originalTime.Unix(), true, nil
+	}
+	return 0, false, nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// verifyOldSignature verifies the signature of the message to refresh, with
+// the keys of verifyKey at the creation time of the signature.
+func verifyOldSignature(message *PlainMessage, oldSignature *PGPSignature, sig *packet.Signature, verifyKey *KeyRing) error {
+	if verifyKey == nil || len(verifyKey.entities) == 0 {
+		return newSignatureNoVerifier()
+	}
+	config := &packet.Config{
+		Time: func() time.Time {
+			return sig.CreationTime
+		},
+	}
+	applyConfigModifier(config)
+	_, _, err := openpgp.VerifyDetachedSignature(
+		verifyKey.entities,
+		message.NewReader(),
+		bytes.NewReader(oldSignature.GetBinary()),
+		config,
+	)
+	if err != nil {
+		return newSignatureFailed(err)
+	}
+	return nil
+}
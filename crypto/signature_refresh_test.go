@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshDetachedSignature(t *testing.T) {
+	message := NewPlainMessageFromString("release 1.0.0\n")
+
+	// Text signature with SHA-1 and RSA-1024, and a notation
+	oldSignature, err := NewPGPSignatureFromArmored(readTestFile("signature_sha1", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading signature, got:", err)
+	}
+	oldTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, refreshed, err := GetOriginalSignatureTime(oldSignature)
+	if err != nil {
+		t.Fatal("Expected no error while reading original time, got:", err)
+	}
+	assert.False(t, refreshed)
+
+	oldKey, err := NewKeyFromArmored(readTestFile("signature_sha1_publicKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	oldKeyRing, err := NewKeyRing(oldKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	keyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	// The old signature must be verified
+	_, err = keyRing.RefreshDetachedSignature(message, oldSignature, keyRing, nil)
+	assert.Error(t, err)
+	_, err = keyRing.RefreshDetachedSignature(NewPlainMessageFromString("release 6.6.6\n"), oldSignature, oldKeyRing, nil)
+	assert.Error(t, err)
+
+	// Only the allowed notations are kept
+	newSignature, err := keyRing.RefreshDetachedSignature(message, oldSignature, oldKeyRing, nil)
+	if err != nil {
+		t.Fatal("Expected no error while refreshing signature, got:", err)
+	}
+	sig, err := parseSignaturePacket(newSignature)
+	if err != nil {
+		t.Fatal("Expected no error while parsing signature, got:", err)
+	}
+	assert.Len(t, sig.Notations, 1)
+
+	newSignature, err = keyRing.RefreshDetachedSignature(message, oldSignature, oldKeyRing, []string{"release@example.org"})
+	if err != nil {
+		t.Fatal("Expected no error while refreshing signature, got:", err)
+	}
+	if err := keyRing.VerifyDetached(message, newSignature, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying refreshed signature, got:", err)
+	}
+
+	sig, err = parseSignaturePacket(newSignature)
+	if err != nil {
+		t.Fatal("Expected no error while parsing signature, got:", err)
+	}
+	assert.Exactly(t, crypto.SHA512, sig.Hash)
+	assert.Exactly(t, packet.SigTypeText, sig.SigType)
+	assert.Exactly(t, "release@example.org", sig.Notations[0].Name)
+	assert.Exactly(t, []byte("1.0.0"), sig.Notations[0].Value)
+
+	originalTime, refreshed, err := GetOriginalSignatureTime(newSignature)
+	if err != nil {
+		t.Fatal("Expected no error while reading original time, got:", err)
+	}
+	assert.True(t, refreshed)
+	assert.Exactly(t, oldTime.Unix(), originalTime)
+
+	// Refreshing again keeps the time of the original signature
+	newSignature, err = keyRing.RefreshDetachedSignature(message, newSignature, keyRing, []string{"release@example.org"})
+	if err != nil {
+		t.Fatal("Expected no error while refreshing signature, got:", err)
+	}
+	originalTime, _, err = GetOriginalSignatureTime(newSignature)
+	if err != nil {
+		t.Fatal("Expected no error while reading original time, got:", err)
+	}
+	assert.Exactly(t, oldTime.Unix(), originalTime)
+	sig, err = parseSignaturePacket(newSignature)
+	if err != nil {
+		t.Fatal("Expected no error while parsing signature, got:", err)
+	}
+	assert.Len(t, sig.Notations, 2)
+
+	_, err = keyRing.RefreshDetachedSignature(message, NewPGPSignature([]byte("not a signature")), oldKeyRing, nil)
+	assert.Error(t, err)
+}
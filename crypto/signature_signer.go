@@ -0,0 +1,191 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// SignatureSigner identifies the key that made a valid signature.
+type SignatureSigner struct {
+	// Fingerprint is the hex fingerprint of the key, or subkey, whose
+	// signature was validated.
+	Fingerprint string
+	// KeyIDCollision is set when several keys of the verification keyring
+	// share the 64-bit key ID of the signature. All of them are tried, and
+	// Fingerprint is the one that validated the signature.
+	KeyIDCollision bool
+
+	// Version is the version of the signature packet, 4 or 6.
+	Version int
+	// HashAlgorithm is the name of the hash algorithm of the signature,
+	// e.g. "SHA-256".
+	HashAlgorithm string
+	// PublicKeyAlgorithm is the name of the public key algorithm of the
+	// signature, as in KeyComponentInfo.Algorithm, e.g. "rsa".
+	PublicKeyAlgorithm string
+	// BitLength is the size in bits of the key that made the signature, 0 if
+	// unknown.
+	BitLength int
+	// IsSalted is set if a salt is hashed before the signed data, as in v6
+	// signatures.
+	IsSalted bool
+}
+
+// VerifyDetachedWithSigner verifies a PlainMessage with a detached
+// PGPSignature, as VerifyDetached, and returns the key that made it.
+func (keyRing *KeyRing) VerifyDetachedWithSigner(
+	message *PlainMessage,
+	signature *PGPSignature,
+	verifyTime int64,
+) (*SignatureSigner, error) {
+	sig, err := keyRing.verifyMessageSignature(message, signature, verifyTime, nil)
+	if err != nil {
+		return nil, err
+	}
+	return getSignatureSigner(keyRing, sig, message.GetBinary())
+}
+
+// GetDescription returns a short description of the algorithms of the
+// signature, for display, e.g. "RSA-2048/SHA-256, v4" or
+// "ED25519/SHA-512, v6".
+func (signer *SignatureSigner) GetDescription() string {
+	algorithm := strings.ToUpper(signer.PublicKeyAlgorithm)
+	if signer.BitLength > 0 && (signer.PublicKeyAlgorithm == "rsa" || signer.PublicKeyAlgorithm == "dsa") {
+		algorithm += "-" + strconv.Itoa(signer.BitLength)
+	}
+	return algorithm + "/" + signer.HashAlgorithm + ", v" + strconv.Itoa(signer.Version)
+}
+
+// GetSigner returns the key that made the embedded signature verified when
+// the message was decrypted. It returns nil if the message was not
+// verified, or if its signature is invalid.
+func (msg *PlainMessage) GetSigner() *SignatureSigner {
+	return msg.signer
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// verifyEmbeddedSignature verifies the embedded signature of a message read
+// entirely, as verifyDetailsSignature, and returns the signature and its
+// signer. go-crypto only checks the signature with the first key having its
+// key ID: on failure, all the keys with that key ID are tried.
+func verifyEmbeddedSignature(
+	md *openpgp.MessageDetails,
+	body []byte,
+	verifyKey *KeyRing,
+	verifyTime int64,
+	verificationContext *VerificationContext,
+) (*packet.Signature, *SignatureSigner, error) {
+	processSignatureExpiration(md, verifyTime)
+	err := verifyDetailsSignature(md, verifyKey, verificationContext)
+	sig := md.Signature
+	if sig == nil || sig.IssuerKeyId == nil {
+		return sig, nil, err
+	}
+	if err != nil {
+		if md.SignatureError == nil || len(getSignatureCandidates(verifyKey, sig)) < 2 {
+			return sig, nil, err
+		}
+		detached, serializeErr := newPGPSignatureFromPacket(sig)
+		if serializeErr != nil {
+			return sig, nil, err
+		}
+		if _, retryErr := verifyKey.verifyMessageSignature(
+			NewPlainMessage(body),
+			detached,
+			verifyTime,
+			verificationContext,
+		); retryErr != nil {
+			return sig, nil, err
+		}
+	}
+	signer, err := getSignatureSigner(verifyKey, sig, body)
+	return sig, signer, err
+}
+
+// getSignatureCandidates returns the signing keys of the keyring with the
+// issuer key ID of the signature.
+func getSignatureCandidates(keyRing *KeyRing, sig *packet.Signature) []openpgp.Key {
+	return keyRing.entities.KeysByIdUsage(*sig.IssuerKeyId, packet.KeyFlagSign)
+}
+
+// getCollidingSigners returns the entities of the keyring with a signing key
+// with the issuer key ID of the signature, as selected by go-crypto.
+func getCollidingSigners(entities openpgp.EntityList, signature []byte) []*openpgp.Entity {
+	keyIDs, _ := getSignatureKeyIDs(signature)
+	for _, keyID := range keyIDs {
+		keys := entities.KeysByIdUsage(keyID, packet.KeyFlagSign)
+		if len(keys) == 0 {
+			continue
+		}
+		var signers []*openpgp.Entity
+		for _, key := range keys {
+			if len(signers) == 0 || signers[len(signers)-1] != key.Entity {
+				signers = append(signers, key.Entity)
+			}
+		}
+		return signers
+	}
+	return nil
+}
+
+// getSignatureSigner returns the key of the keyring that made a verified
+// signature of data. When several keys share the issuer key ID, the
+// signature is checked again with each of them to find the right one.
+func getSignatureSigner(keyRing *KeyRing, sig *packet.Signature, data []byte) (*SignatureSigner, error) {
+	key, collision, err := getSignatureSigningKey(keyRing, sig, data)
+	if err != nil {
+		return nil, err
+	}
+	return newSignatureSigner(key.PublicKey, sig, collision), nil
+}
+
+// getSignatureSigningKey returns the signing key of the keyring that made a
+// verified signature of data, and whether other keys share its key ID.
+func getSignatureSigningKey(keyRing *KeyRing, sig *packet.Signature, data []byte) (*openpgp.Key, bool, error) {
+	if sig.IssuerKeyId == nil {
+		return nil, false, errors.New("gopenpgp: the signature has no issuer key ID")
+	}
+	candidates := getSignatureCandidates(keyRing, sig)
+	if len(candidates) == 1 {
+		return &candidates[0], false, nil
+	}
+	for i, candidate := range candidates {
+		hash, err := sig.PrepareVerify()
+		if err != nil {
+			return nil, false, errors.Wrap(err, "gopenpgp: unable to hash signed data")
+		}
+		if sig.SigType == packet.SigTypeText {
+			_, err = openpgp.NewCanonicalTextHash(hash).Write(data)
+		} else {
+			_, err = hash.Write(data)
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, "gopenpgp: unable to hash signed data")
+		}
+		if candidate.PublicKey.VerifySignature(hash, sig) == nil {
+			return &candidates[i], true, nil
+		}
+	}
+	return nil, false, errors.New("gopenpgp: no key of the keyring made the signature")
+}
+
+func newSignatureSigner(publicKey *packet.PublicKey, sig *packet.Signature, collision bool) *SignatureSigner {
+	signer := &SignatureSigner{
+		Fingerprint:        hex.EncodeToString(publicKey.Fingerprint),
+		KeyIDCollision:     collision,
+		Version:            sig.Version,
+		HashAlgorithm:      sig.Hash.String(),
+		PublicKeyAlgorithm: getPublicKeyAlgorithmName(sig.PubKeyAlgo),
+		IsSalted:           sig.Salt() != nil,
+	}
+	if bitLength, err := publicKey.BitLength(); err == nil {
+		signer.BitLength = int(bitLength)
+	}
+	return signer
+}
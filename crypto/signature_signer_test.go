@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureSignerKeyIDCollision(t *testing.T) {
+	message := NewPlainMessageFromString("signed by one of two keys sharing a key ID")
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	keyIDs, ok := getSignatureKeyIDs(signature.GetBinary())
+	if !ok || len(keyIDs) != 1 {
+		t.Fatal("Expected a signature key ID")
+	}
+
+	// A key crafted to collide with the key ID of the signer, first in the keyring.
+	colliding, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	colliding.entity.PrimaryKey.KeyId = keyIDs[0]
+	signer, err := keyRingTestPublic.GetKey(0)
+	if err != nil {
+		t.Fatal("Expected no error while getting key, got:", err)
+	}
+	verifyKeyRing, err := NewKeyRing(colliding)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err = verifyKeyRing.AddKey(signer); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+
+	result, err := verifyKeyRing.VerifyDetachedWithSigner(message, signature, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while verifying detached signature, got:", err)
+	}
+	assert.True(t, result.KeyIDCollision)
+	signingKeys := keyRingTestPublic.entities.KeysByIdUsage(keyIDs[0], packet.KeyFlagSign)
+	assert.Exactly(t, hex.EncodeToString(signingKeys[0].PublicKey.Fingerprint), result.Fingerprint)
+	assert.Exactly(t, "RSA-2048/SHA-512, v4", result.GetDescription())
+	assert.False(t, result.IsSalted)
+
+	_, err = verifyKeyRing.VerifyDetachedWithSigner(NewPlainMessageFromString("tampered"), signature, GetUnixTime())
+	assert.Error(t, err)
+
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, verifyKeyRing, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting and verifying, got:", err)
+	}
+	embedded := decrypted.GetSigner()
+	assert.Exactly(t, result.Fingerprint, embedded.Fingerprint)
+	assert.True(t, embedded.KeyIDCollision)
+	assert.Exactly(t, "RSA-2048/SHA-256, v4", embedded.GetDescription())
+
+	decrypted, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Nil(t, decrypted.GetSigner())
+}
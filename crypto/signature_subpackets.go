@@ -0,0 +1,281 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// SignatureSubpacket is a raw subpacket of a signature, see RFC 4880,
+// section 5.2.3.1.
+type SignatureSubpacket struct {
+	// Type is the subpacket type, without the critical bit.
+	Type int
+	// Critical is true if implementations that don't know the subpacket
+	// type must consider the signature invalid.
+	Critical bool
+	// Hashed is true if the subpacket is in the hashed area, covered by the
+	// signature, and false if it is in the unhashed area, which can be
+	// modified by anyone.
+	Hashed bool
+	// Contents is the subpacket data, after its length and type.
+	Contents []byte
+}
+
+// GetHashedSubpackets returns the subpackets of the hashed area of the first
+// signature of sig, in order, including the ones not known by this library.
+func (sig *PGPSignature) GetHashedSubpackets() ([]*SignatureSubpacket, error) {
+	return sig.getSubpackets(true)
+}
+
+// GetUnhashedSubpackets returns the subpackets of the unhashed area of the
+// first signature of sig, in order. They are not covered by the signature.
+func (sig *PGPSignature) GetUnhashedSubpackets() ([]*SignatureSubpacket, error) {
+	return sig.getSubpackets(false)
+}
+
+// UnsafeSignDetachedWithSubpackets generates a detached signature of the
+// message as SignDetached, with the additional raw subpackets appended to
+// its hashed or unhashed area, e.g. to experiment with new subpacket types.
+// The subpackets are not validated: they can contradict the ones set by the
+// library, and critical subpackets of unknown types make the signature
+// invalid for every implementation, including this one.
+// Only v4 and v6 signatures are supported.
+func (keyRing *KeyRing) UnsafeSignDetachedWithSubpackets(message *PlainMessage, subpackets []*SignatureSubpacket) (*PGPSignature, error) {
+	timer := startOperation(constants.MetricsOperationSign)
+	signature, err := unsafeSignDetachedWithSubpackets(keyRing, message, subpackets)
+	timer.finish(int64(len(message.Data)), err)
+	return signature, err
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func (sig *PGPSignature) getSubpackets(hashed bool) ([]*SignatureSubpacket, error) {
+	if len(sig.Data) == 0 {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: empty signature", nil)
+	}
+	tag, next, err := nextPacketOffset(sig.Data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if tag != packetTagSignature {
+		return nil, newClassifiedError(ErrMessageCorrupt, "gopenpgp: not a signature packet", nil)
+	}
+	body, err := getPacketBody(sig.Data, 0, next)
+	if err != nil {
+		return nil, err
+	}
+	areas, err := findSubpacketAreas(body)
+	if err != nil {
+		return nil, err
+	}
+	if areas == nil {
+		return nil, errors.New("gopenpgp: subpackets of v3 signatures are not supported")
+	}
+
+	area := body[areas.unhashedStart:areas.unhashedEnd]
+	if hashed {
+		area = body[areas.hashedStart:areas.hashedEnd]
+	}
+	rawSubpackets, err := splitSubpackets(area)
+	if err != nil {
+		return nil, err
+	}
+	subpackets := make([]*SignatureSubpacket, len(rawSubpackets))
+	for i, raw := range rawSubpackets {
+		subpackets[i] = parseSubpacket(raw, hashed)
+	}
+	return subpackets, nil
+}
+
+// parseSubpacket parses a raw subpacket, as split by splitSubpackets.
+func parseSubpacket(raw []byte, hashed bool) *SignatureSubpacket {
+	lengthSize := 1
+	switch first := raw[0]; {
+	case first >= 255:
+		lengthSize = 5
+	case first >= 192:
+		lengthSize = 2
+	}
+	return &SignatureSubpacket{
+		Type:     int(raw[lengthSize] & 0x7f),
+		Critical: raw[lengthSize]&0x80 != 0,
+		Hashed:   hashed,
+		Contents: append([]byte{}, raw[lengthSize+1:]...),
+	}
+}
+
+// serializeSubpackets serializes the subpackets of one area.
+func serializeSubpackets(subpackets []*SignatureSubpacket, hashed bool) ([]byte, error) {
+	var area bytes.Buffer
+	for _, subpacket := range subpackets {
+		if subpacket.Hashed != hashed {
+			continue
+		}
+		if subpacket.Type <= 0 || subpacket.Type > 0x7f {
+			return nil, errors.New("gopenpgp: invalid signature subpacket type")
+		}
+		subpacketType := byte(subpacket.Type)
+		if subpacket.Critical {
+			subpacketType |= 0x80
+		}
+		area.Write(encodeSubpacketLength(len(subpacket.Contents) + 1))
+		area.WriteByte(subpacketType)
+		area.Write(subpacket.Contents)
+	}
+	return area.Bytes(), nil
+}
+
+func unsafeSignDetachedWithSubpackets(keyRing *KeyRing, message *PlainMessage, subpackets []*SignatureSubpacket) (*PGPSignature, error) {
+	hashedSubpackets, err := serializeSubpackets(subpackets, true)
+	if err != nil {
+		return nil, err
+	}
+	unhashedSubpackets, err := serializeSubpackets(subpackets, false)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := newSignatureHasher(keyRing, message.IsBinary(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if hasher.signature.Version != 4 && hasher.signature.Version != 6 {
+		return nil, errors.New("gopenpgp: custom subpackets are only supported in v4 and v6 signatures")
+	}
+	if _, err := hasher.writer.Write(message.GetBinary()); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+
+	// go-crypto builds the hashed area itself: the injector appends the
+	// custom subpackets to it when go-crypto hashes the signature trailer.
+	injector := &subpacketInjector{Hash: hasher.hash, subpackets: hashedSubpackets}
+	hasher.hash = injector
+	signature, err := hasher.sign()
+	if err != nil {
+		return nil, err
+	}
+	if err := injector.check(); err != nil {
+		return nil, err
+	}
+	return replaceSubpacketAreas(signature, injector.hashedArea, unhashedSubpackets)
+}
+
+// subpacketInjector is a hash that appends subpackets to the hashed area of
+// the signature trailer written by go-crypto, see RFC 4880, section 5.2.4.
+// go-crypto writes the trailer once, after the message, before computing
+// the digest: it is buffered until complete, however it is split in writes.
+type subpacketInjector struct {
+	hash.Hash
+	subpackets []byte
+	trailer    []byte
+	hashedArea []byte
+	err        error
+}
+
+func (injector *subpacketInjector) Write(p []byte) (int, error) {
+	if injector.err != nil {
+		return 0, injector.err
+	}
+	if injector.hashedArea != nil {
+		injector.err = errors.New("gopenpgp: unexpected signature trailer")
+		return 0, injector.err
+	}
+	injector.trailer = append(injector.trailer, p...)
+	if len(injector.trailer) == 0 {
+		return len(p), nil
+	}
+
+	// version, type, public key algorithm and hash algorithm, followed by
+	// the hashed area, and the version, 0xff and the hashed length
+	trailer := injector.trailer
+	lengthSize := 2
+	if trailer[0] == 6 {
+		lengthSize = 4
+	}
+	hashedStart := 4 + lengthSize
+	if len(trailer) < hashedStart {
+		return len(p), nil
+	}
+	hashedEnd := hashedStart + readSubpacketsLength(trailer[4:], lengthSize)
+	if len(trailer) < hashedEnd+6 {
+		return len(p), nil
+	}
+	if hashedEnd+6 != len(trailer) {
+		injector.err = errors.New("gopenpgp: unexpected signature trailer")
+		return 0, injector.err
+	}
+	hashedArea := make([]byte, 0, hashedEnd-hashedStart+len(injector.subpackets))
+	hashedArea = append(hashedArea, trailer[hashedStart:hashedEnd]...)
+	hashedArea = append(hashedArea, injector.subpackets...)
+	if lengthSize == 2 && len(hashedArea) > 0xffff {
+		injector.err = errors.New("gopenpgp: the hashed subpackets are too long")
+		return 0, injector.err
+	}
+	injector.hashedArea = hashedArea
+
+	header := make([]byte, hashedStart)
+	copy(header, trailer[:4])
+	writeSubpacketsLength(header[4:], lengthSize, len(injector.hashedArea))
+	var footer [6]byte
+	footer[0] = trailer[0]
+	footer[1] = 0xff
+	binary.BigEndian.PutUint32(footer[2:], uint32(hashedStart+len(injector.hashedArea)))
+
+	_, _ = injector.Hash.Write(header)
+	_, _ = injector.Hash.Write(injector.hashedArea)
+	_, _ = injector.Hash.Write(footer[:])
+	return len(p), nil
+}
+
+// check returns an error if the trailer was not injected entirely.
+func (injector *subpacketInjector) check() error {
+	if injector.err != nil {
+		return injector.err
+	}
+	if injector.hashedArea == nil {
+		return errors.New("gopenpgp: incomplete signature trailer")
+	}
+	return nil
+}
+
+// replaceSubpacketAreas returns the signature with the hashed area replaced,
+// and the subpackets appended to its unhashed area.
+func replaceSubpacketAreas(signature *PGPSignature, hashedArea, unhashedSubpackets []byte) (*PGPSignature, error) {
+	_, next, err := nextPacketOffset(signature.Data, 0)
+	if err != nil {
+		return nil, err
+	}
+	body, err := getPacketBody(signature.Data, 0, next)
+	if err != nil {
+		return nil, err
+	}
+	areas, err := findSubpacketAreas(body)
+	if err != nil {
+		return nil, err
+	}
+	unhashedLength := areas.unhashedEnd - areas.unhashedStart + len(unhashedSubpackets)
+	if areas.lengthSize == 2 && unhashedLength > 0xffff {
+		return nil, errors.New("gopenpgp: the unhashed subpackets are too long")
+	}
+
+	length := make([]byte, areas.lengthSize)
+	var replaced bytes.Buffer
+	replaced.Write(body[:4])
+	writeSubpacketsLength(length, areas.lengthSize, len(hashedArea))
+	replaced.Write(length)
+	replaced.Write(hashedArea)
+	writeSubpacketsLength(length, areas.lengthSize, unhashedLength)
+	replaced.Write(length)
+	replaced.Write(body[areas.unhashedStart:areas.unhashedEnd])
+	replaced.Write(unhashedSubpackets)
+	replaced.Write(body[areas.unhashedEnd:])
+
+	var serialized bytes.Buffer
+	writePacketHeader(&serialized, packetTagSignature, replaced.Len())
+	serialized.Write(replaced.Bytes())
+	return NewPGPSignature(serialized.Bytes()), nil
+}
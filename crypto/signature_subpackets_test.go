@@ -0,0 +1,109 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureSubpackets(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+
+	hashed, err := signature.GetHashedSubpackets()
+	if err != nil {
+		t.Fatal("Expected no error while reading hashed subpackets, got:", err)
+	}
+	// The creation time is always hashed
+	assert.Exactly(t, 2, hashed[0].Type)
+	assert.Len(t, hashed[0].Contents, 4)
+	assert.True(t, hashed[0].Hashed)
+
+	unhashed, err := signature.GetUnhashedSubpackets()
+	if err != nil {
+		t.Fatal("Expected no error while reading unhashed subpackets, got:", err)
+	}
+	for _, subpacket := range unhashed {
+		assert.False(t, subpacket.Hashed)
+	}
+
+	_, err = NewPGPSignature([]byte{0xc1, 0x01, 0x00}).GetHashedSubpackets()
+	assert.Error(t, err)
+}
+
+func TestUnsafeSignDetachedWithSubpackets(t *testing.T) {
+	key, err := GenerateKeyV6("v6", "v6@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRingV6, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	custom := []*SignatureSubpacket{
+		{Type: 100, Hashed: true, Contents: []byte("experimental")},
+		{Type: 101, Contents: []byte("not signed")},
+	}
+	message := NewPlainMessageFromString(testMessage)
+	for _, test := range []struct {
+		keyRing    *KeyRing
+		verifyTime int64
+	}{
+		{keyRingTestPrivate, testTime},
+		{keyRingV6, GetUnixTime()},
+	} {
+		signature, err := test.keyRing.UnsafeSignDetachedWithSubpackets(message, custom)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		if err := test.keyRing.VerifyDetached(message, signature, test.verifyTime); err != nil {
+			t.Fatal("Expected no error while verifying signature, got:", err)
+		}
+
+		hashed, err := signature.GetHashedSubpackets()
+		if err != nil {
+			t.Fatal("Expected no error while reading hashed subpackets, got:", err)
+		}
+		assert.Exactly(t, custom[0], hashed[len(hashed)-1])
+		unhashed, err := signature.GetUnhashedSubpackets()
+		if err != nil {
+			t.Fatal("Expected no error while reading unhashed subpackets, got:", err)
+		}
+		assert.Exactly(t, custom[1], unhashed[len(unhashed)-1])
+	}
+
+	_, err = keyRingTestPrivate.UnsafeSignDetachedWithSubpackets(message, []*SignatureSubpacket{{Type: 128}})
+	assert.Error(t, err)
+}
+
+func TestSubpacketInjectorSplitTrailer(t *testing.T) {
+	// v4 trailer with a 3 bytes hashed area
+	trailer := []byte{4, 0x00, 22, 8, 0x00, 0x03, 0x02, 0x1b, 0x03, 4, 0xff, 0x00, 0x00, 0x00, 0x09}
+	subpackets := []byte{0x02, 0x7f, 0x01}
+
+	whole := &subpacketInjector{Hash: sha256.New(), subpackets: subpackets}
+	if _, err := whole.Write(trailer); err != nil {
+		t.Fatal("Expected no error while writing trailer, got:", err)
+	}
+	split := &subpacketInjector{Hash: sha256.New(), subpackets: subpackets}
+	for i := range trailer {
+		if _, err := split.Write(trailer[i : i+1]); err != nil {
+			t.Fatal("Expected no error while writing trailer, got:", err)
+		}
+	}
+	assert.NoError(t, whole.check())
+	assert.NoError(t, split.check())
+	assert.Exactly(t, []byte{0x02, 0x1b, 0x03, 0x02, 0x7f, 0x01}, split.hashedArea)
+	assert.Exactly(t, whole.Sum(nil), split.Sum(nil))
+
+	incomplete := &subpacketInjector{Hash: sha256.New(), subpackets: subpackets}
+	if _, err := incomplete.Write(trailer[:8]); err != nil {
+		t.Fatal("Expected no error while writing trailer, got:", err)
+	}
+	assert.Error(t, incomplete.check())
+}
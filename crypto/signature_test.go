@@ -104,6 +104,28 @@ func TestVerifyTextDetachedSigWrong(t *testing.T) {
 	assert.Exactly(t, constants.SIGNATURE_FAILED, err.Status)
 }
 
+func TestSignTextDetachedWithCanonicalization(t *testing.T) {
+	message := NewPlainMessageFromString("Hello  \nworld!\t\n")
+	signature, err := keyRingTestPrivate.SignDetachedWithCanonicalization(message, constants.TextCanonicalizationTrimTrailingWhitespace)
+	if err != nil {
+		t.Fatal("Cannot generate signature:", err)
+	}
+
+	// Trailing whitespace is ignored, mixed line endings are normalized
+	transported := NewPlainMessageFromString("Hello\r\nworld! \n")
+	assert.NoError(t, keyRingTestPublic.VerifyDetachedWithCanonicalization(
+		transported, signature, testTime, constants.TextCanonicalizationTrimTrailingWhitespace,
+	))
+	checkVerificationError(
+		t,
+		keyRingTestPublic.VerifyDetachedWithCanonicalization(transported, signature, testTime, constants.TextCanonicalizationStrict),
+		constants.SIGNATURE_FAILED,
+	)
+
+	_, err = keyRingTestPrivate.SignDetachedWithCanonicalization(message, 42)
+	assert.Error(t, err)
+}
+
 func TestSignBinDetached(t *testing.T) {
 	var err error
 
@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/pkg/errors"
+)
+
+// SetSigningSubkey makes signing with the keyring use the primary key or
+// subkey with the given hex fingerprint, e.g. a subkey stored on a
+// particular smartcard, instead of the newest valid signing subkey.
+// Signing fails if the key is not a valid signing key, or is not unlocked.
+// Passing an empty fingerprint restores the automatic selection.
+func (keyRing *KeyRing) SetSigningSubkey(fingerprint string) error {
+	fingerprint = strings.ToLower(fingerprint)
+	if fingerprint != "" && keyRing.findEntityWithKey(fingerprint) == nil {
+		return errors.New("gopenpgp: no key with fingerprint " + fingerprint + " in the keyring")
+	}
+	keyRing.signingSubkey = fingerprint
+	return nil
+}
+
+// SetEncryptionSubkey makes encryption to the keyring use the primary key
+// or subkey with the given hex fingerprint for its key, instead of the
+// newest valid encryption subkey. The other keys of the keyring are not
+// affected.
+// Encryption fails if the key is not a valid encryption key.
+// Passing an empty fingerprint restores the automatic selection.
+func (keyRing *KeyRing) SetEncryptionSubkey(fingerprint string) error {
+	fingerprint = strings.ToLower(fingerprint)
+	if fingerprint != "" && keyRing.findEntityWithKey(fingerprint) == nil {
+		return errors.New("gopenpgp: no key with fingerprint " + fingerprint + " in the keyring")
+	}
+	keyRing.encryptionSubkey = fingerprint
+	return nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getSigningSubkeyEntity returns the entity to sign with the key set by
+// SetSigningSubkey.
+func (keyRing *KeyRing) getSigningSubkeyEntity() (*openpgp.Entity, error) {
+	entity := keyRing.findEntityWithKey(keyRing.signingSubkey)
+	if entity == nil || entity.PrivateKey == nil {
+		return nil, errors.New("gopenpgp: no private key with fingerprint " + keyRing.signingSubkey + " in the keyring")
+	}
+	entity = withSubkey(entity, keyRing.signingSubkey)
+	signingKey, ok := entity.SigningKey(getNow())
+	if !ok || hex.EncodeToString(signingKey.PublicKey.Fingerprint) != keyRing.signingSubkey {
+		return nil, errors.New("gopenpgp: key " + keyRing.signingSubkey + " is not a valid signing key")
+	}
+	if signingKey.PrivateKey == nil || signingKey.PrivateKey.Dummy() {
+		return nil, StubKeyError{Fingerprint: keyRing.signingSubkey}
+	}
+	if signingKey.PrivateKey.Encrypted {
+		return nil, newClassifiedError(ErrKeyLocked, "gopenpgp: cannot sign message, key "+keyRing.signingSubkey+" is locked", nil)
+	}
+	if err := keyRing.checkInsecureLegacyKey(signingKey.PublicKey); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// withEncryptionSubkey returns the keyring to encrypt to, with the key set
+// by SetEncryptionSubkey, or the keyring itself if it isn't set.
+func (keyRing *KeyRing) withEncryptionSubkey() (*KeyRing, error) {
+	if keyRing.encryptionSubkey == "" {
+		return keyRing, nil
+	}
+	entities := make(openpgp.EntityList, len(keyRing.entities))
+	for i, entity := range keyRing.entities {
+		entities[i] = entity
+		if !hasKey(entity, keyRing.encryptionSubkey) {
+			continue
+		}
+		entities[i] = withSubkey(entity, keyRing.encryptionSubkey)
+		encryptionKey, ok := entities[i].EncryptionKey(getEncryptionTimeGenerator(entities[i : i+1])())
+		if !ok || hex.EncodeToString(encryptionKey.PublicKey.Fingerprint) != keyRing.encryptionSubkey {
+			return nil, errors.New("gopenpgp: key " + keyRing.encryptionSubkey + " is not a valid encryption key")
+		}
+	}
+	return keyRing.withEntities(entities), nil
+}
+
+// findEntityWithKey returns the entity whose primary key or subkey has the
+// given hex fingerprint, or nil.
+func (keyRing *KeyRing) findEntityWithKey(fingerprint string) *openpgp.Entity {
+	for _, entity := range keyRing.entities {
+		if hasKey(entity, fingerprint) {
+			return entity
+		}
+	}
+	return nil
+}
+
+func hasKey(entity *openpgp.Entity, fingerprint string) bool {
+	if hex.EncodeToString(entity.PrimaryKey.Fingerprint) == fingerprint {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if hex.EncodeToString(subkey.PublicKey.Fingerprint) == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// withSubkey returns a shallow copy of entity with only the subkey with the
+// given hex fingerprint, or without subkeys if it is the primary key, so
+// that go-crypto selects that key.
+func withSubkey(entity *openpgp.Entity, fingerprint string) *openpgp.Entity {
+	filtered := *entity
+	filtered.Subkeys = nil
+	for _, subkey := range entity.Subkeys {
+		if hex.EncodeToString(subkey.PublicKey.Fingerprint) == fingerprint {
+			filtered.Subkeys = []openpgp.Subkey{subkey}
+		}
+	}
+	return &filtered
+}
@@ -0,0 +1,82 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubkeySelection(t *testing.T) {
+	key, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	config := &packet.Config{Time: getTimeGenerator()}
+	if err = key.entity.AddSigningSubkey(config); err != nil {
+		t.Fatal("Expected no error while adding subkey, got:", err)
+	}
+	if err = key.entity.AddEncryptionSubkey(config); err != nil {
+		t.Fatal("Expected no error while adding subkey, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	// By default, the new signing subkey and the first encryption subkey are
+	// used: force the primary key and the second encryption subkey
+	entity := key.entity
+	signingKey, _ := entity.SigningKey(getNow())
+	assert.Exactly(t, entity.Subkeys[1].PublicKey.KeyId, signingKey.PublicKey.KeyId)
+	encryptionKey, _ := entity.EncryptionKey(getNow())
+	assert.Exactly(t, entity.Subkeys[0].PublicKey.KeyId, encryptionKey.PublicKey.KeyId)
+
+	signingSubkey := entity.PrimaryKey.KeyId
+	encryptionSubkey := entity.Subkeys[2].PublicKey.KeyId
+	if err = keyRing.SetSigningSubkey(key.GetFingerprint()); err != nil {
+		t.Fatal("Expected no error while setting signing subkey, got:", err)
+	}
+	if err = keyRing.SetEncryptionSubkey(hex.EncodeToString(entity.Subkeys[2].PublicKey.Fingerprint)); err != nil {
+		t.Fatal("Expected no error while setting encryption subkey, got:", err)
+	}
+
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	keyIDs, _ := signature.GetSignatureKeyIDs()
+	assert.Exactly(t, []uint64{signingSubkey}, keyIDs)
+
+	ciphertext, err := keyRing.Encrypt(message, keyRing)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	keyIDs, _ = ciphertext.GetEncryptionKeyIDs()
+	assert.Exactly(t, []uint64{encryptionSubkey}, keyIDs)
+	decrypted, err := keyRing.Decrypt(ciphertext, keyRing, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessage, decrypted.GetString())
+
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	keyPacket, err := keyRing.EncryptSessionKey(sessionKey)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting session key, got:", err)
+	}
+	keyIDs, _ = NewPGPMessage(keyPacket).GetEncryptionKeyIDs()
+	assert.Exactly(t, []uint64{encryptionSubkey}, keyIDs)
+
+	// Keys without the capability, or not in the keyring, are rejected
+	if err = keyRing.SetEncryptionSubkey(hex.EncodeToString(entity.Subkeys[1].PublicKey.Fingerprint)); err != nil {
+		t.Fatal("Expected no error while setting encryption subkey, got:", err)
+	}
+	_, err = keyRing.Encrypt(message, nil)
+	assert.Error(t, err)
+	assert.Error(t, keyRing.SetSigningSubkey(keyRingTestPrivate.GetKeys()[0].GetFingerprint()))
+}
@@ -0,0 +1,171 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"hash"
+	"io"
+	"math/bits"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/ed25519"
+	"github.com/ProtonMail/go-crypto/openpgp/eddsa"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// ThresholdSigner produces Ed25519 signatures with a key whose secret is
+// split between several parties, e.g. with FROST (RFC 9591), so that no
+// single holder can sign. The coordination of the parties and their shares
+// is left to the implementation, e.g. a custody provider.
+type ThresholdSigner interface {
+	// Sign returns the 64-byte Ed25519 signature of message, made with the
+	// shares of the key. For OpenPGP signatures, message is the digest of
+	// the signed data and of the signature metadata.
+	Sign(message []byte) ([]byte, error)
+}
+
+// SignDetachedThreshold generates a detached signature of message, as
+// SignDetachedWithContext, with the signing key of the public key key,
+// whose secret is held by the parties of signer. The signing key must be an
+// Ed25519 key, either a v4 EdDSA key or a v6 Ed25519 key. The signature
+// returned by signer is checked before it is used.
+// This API is experimental: the certificate of the threshold key, and its
+// self-signatures, must be created beforehand with the same parties.
+func (key *Key) SignDetachedThreshold(
+	message *PlainMessage,
+	context *SigningContext,
+	signer ThresholdSigner,
+) (*PGPSignature, error) {
+	config := &packet.Config{
+		Rand:        getRandomSource(),
+		DefaultHash: crypto.SHA512,
+		Time:        getTimeGenerator(),
+	}
+	applyConfigModifier(config)
+	signingKey, ok := key.entity.SigningKey(config.Now())
+	if !ok {
+		return nil, errors.Wrap(newKeyCapabilityError(key.entity, constants.KeyCapabilitySign), "gopenpgp: error in signing")
+	}
+	publicKey := signingKey.PublicKey
+	point, stubKey, err := getThresholdSigningKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sigType := packet.SigTypeBinary
+	if !message.IsBinary() {
+		sigType = packet.SigTypeText
+	}
+	sigLifetimeSecs := config.SigLifetime()
+	signature := &packet.Signature{
+		Version:           publicKey.Version,
+		SigType:           sigType,
+		PubKeyAlgo:        publicKey.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      config.Now(),
+		IssuerKeyId:       &publicKey.KeyId,
+		IssuerFingerprint: publicKey.Fingerprint,
+		Notations:         context.getNotations(),
+		SigLifetimeSecs:   &sigLifetimeSecs,
+	}
+	h, err := signature.PrepareSign(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+	recorder := &digestRecorder{Hash: h}
+	var writer io.Writer = recorder
+	if !message.IsBinary() {
+		writer = openpgp.NewCanonicalTextHash(recorder)
+	}
+	if _, err := writer.Write(message.GetBinary()); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+
+	// The stub key lets go-crypto build the subpackets and the digest of the
+	// signature, whose value is then replaced.
+	if err := signature.Sign(recorder, stubKey, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+	value, err := signer.Sign(recorder.digest)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in threshold signing")
+	}
+	if len(value) != stded25519.SignatureSize || !stded25519.Verify(point, recorder.digest, value) {
+		return nil, errors.New("gopenpgp: error in threshold signing: invalid signature from the signer")
+	}
+	if publicKey.PubKeyAlgo == packet.PubKeyAlgoEd25519 {
+		signature.EdSig = value
+	} else if err := setEdDSASignature(signature, value); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+
+	var outBuf bytes.Buffer
+	if err := signature.Serialize(&outBuf); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+	auditKeyUsage(constants.KeyUsageSign, "sign detached with threshold signer", key.entity.PrimaryKey, publicKey)
+	return NewPGPSignature(outBuf.Bytes()), nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getThresholdSigningKey returns the Ed25519 point of an Ed25519 public key,
+// and a private key with the same public key but a random secret.
+func getThresholdSigningKey(publicKey *packet.PublicKey) (point []byte, stubKey *packet.PrivateKey, err error) {
+	var secret interface{}
+	switch pub := publicKey.PublicKey.(type) {
+	case *ed25519.PublicKey:
+		point = pub.Point
+		secret, err = ed25519.GenerateKey(getRandomSource())
+	case *eddsa.PublicKey:
+		if pub.GetCurve().GetCurveName() != "ed25519" {
+			return nil, nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: threshold signing requires an Ed25519 key", nil)
+		}
+		point = pub.X
+		secret, err = eddsa.GenerateKey(getRandomSource(), pub.GetCurve())
+	default:
+		return nil, nil, newClassifiedError(ErrUnsupportedAlgorithm, "gopenpgp: threshold signing requires an Ed25519 key", nil)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "gopenpgp: error in signing")
+	}
+	if len(point) != stded25519.PublicKeySize {
+		return nil, nil, errors.New("gopenpgp: error in signing: invalid Ed25519 key")
+	}
+	return point, &packet.PrivateKey{PublicKey: *publicKey, PrivateKey: secret}, nil
+}
+
+// setEdDSASignature sets the R and S fields of a v4 EdDSA signature to the
+// halves of an Ed25519 signature, read as MPIs into the fields set by Sign.
+func setEdDSASignature(signature *packet.Signature, value []byte) error {
+	if _, err := signature.EdDSASigR.ReadFrom(bytes.NewReader(encodeMPI(value[:32]))); err != nil {
+		return err
+	}
+	_, err := signature.EdDSASigS.ReadFrom(bytes.NewReader(encodeMPI(value[32:])))
+	return err
+}
+
+// encodeMPI encodes a big-endian integer as an OpenPGP MPI.
+func encodeMPI(value []byte) []byte {
+	value = bytes.TrimLeft(value, "\x00")
+	bitLength := 0
+	if len(value) > 0 {
+		bitLength = 8*(len(value)-1) + bits.Len8(value[0])
+	}
+	return append([]byte{byte(bitLength >> 8), byte(bitLength)}, value...)
+}
+
+// digestRecorder is a hash keeping the digest computed by Sum.
+type digestRecorder struct {
+	hash.Hash
+	digest []byte
+}
+
+func (h *digestRecorder) Sum(b []byte) []byte {
+	sum := h.Hash.Sum(b)
+	h.digest = append([]byte{}, sum[len(b):]...)
+	return sum
+}
@@ -0,0 +1,75 @@
+package crypto
+
+import (
+	stded25519 "crypto/ed25519"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/ed25519"
+	"github.com/ProtonMail/go-crypto/openpgp/eddsa"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// testThresholdSigner simulates the parties of a threshold key with the
+// whole secret.
+type testThresholdSigner struct {
+	secret stded25519.PrivateKey
+}
+
+func (signer *testThresholdSigner) Sign(message []byte) ([]byte, error) {
+	return stded25519.Sign(signer.secret, message), nil
+}
+
+func newTestThresholdSigner(t *testing.T, key *Key) *testThresholdSigner {
+	signingKey, ok := key.entity.SigningKey(getNow())
+	if !ok {
+		t.Fatal("Expected a signing key")
+	}
+	var seed []byte
+	switch secret := signingKey.PrivateKey.PrivateKey.(type) {
+	case *ed25519.PrivateKey:
+		seed = secret.Seed()
+	case *eddsa.PrivateKey:
+		seed = secret.D
+	default:
+		t.Fatal("Expected an Ed25519 signing key")
+	}
+	return &testThresholdSigner{secret: stded25519.NewKeyFromSeed(seed)}
+}
+
+func TestSignDetachedThreshold(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	for _, generate := range []func(string, string, string, int) (*Key, error){GenerateKey, GenerateKeyV6} {
+		key, err := generate(keyTestName, keyTestDomain, "x25519", 0)
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		signer := newTestThresholdSigner(t, key)
+		publicKey, err := key.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while extracting public key, got:", err)
+		}
+		keyRing, err := NewKeyRing(publicKey)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+
+		signature, err := publicKey.SignDetachedThreshold(message, NewSigningContext("test", true), signer)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		err = keyRing.VerifyDetachedWithContext(message, signature, GetUnixTime(), NewVerificationContext("test", true, 0))
+		assert.NoError(t, err)
+
+		// A signer holding another key is rejected
+		otherKey, err := generate(keyTestName, keyTestDomain, "x25519", 0)
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		_, err = publicKey.SignDetachedThreshold(message, nil, newTestThresholdSigner(t, otherKey))
+		assert.Error(t, err)
+	}
+
+	_, err := keyTestRSA.SignDetachedThreshold(message, nil, &testThresholdSigner{})
+	assert.True(t, errors.Is(err, ErrUnsupportedAlgorithm))
+}
@@ -2,6 +2,8 @@ package crypto
 
 import (
 	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
 )
 
 // UpdateTime updates cached time.
@@ -14,6 +16,43 @@ func UpdateTime(newTime int64) {
 	}
 }
 
+// SetClock sets the clock used as current time, instead of the system clock
+// and of the time set with UpdateTime, e.g. a skew-corrected server clock
+// or a fixed time in tests. Passing nil restores the default behaviour.
+func SetClock(clock func() time.Time) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	pgp.clock = clock
+}
+
+// SetTimeOffsetTolerance sets the amount of seconds that signatures and keys
+// may be created in the future, to compensate for clock skew between the
+// signer and the verifier. It applies to signature verification, to the key
+// validity checks, i.e. CanVerify, CanEncrypt and IsExpired, and to the
+// selection of the encryption keys when encrypting: if a recipient key is
+// only valid within the tolerance, its encryption key is selected at the
+// current time plus the tolerance. Messages are still signed at the current
+// time. The default tolerance is two days.
+func SetTimeOffsetTolerance(tolerance int64) {
+	pgp.lock.Lock()
+	defer pgp.lock.Unlock()
+
+	if tolerance < 0 {
+		tolerance = 0
+	}
+	pgp.timeTolerance = tolerance
+}
+
+// GetTimeOffsetTolerance returns the amount of seconds that signatures and
+// keys may be created in the future.
+func GetTimeOffsetTolerance() int64 {
+	pgp.lock.RLock()
+	defer pgp.lock.RUnlock()
+
+	return pgp.timeTolerance
+}
+
 // SetKeyGenerationOffset updates the offset when generating keys.
 func SetKeyGenerationOffset(offset int64) {
 	pgp.lock.Lock()
@@ -39,6 +78,10 @@ func getNow() time.Time {
 	pgp.lock.RLock()
 	defer pgp.lock.RUnlock()
 
+	if pgp.clock != nil {
+		return pgp.clock()
+	}
+
 	if pgp.latestServerTime == 0 {
 		return time.Now()
 	}
@@ -56,6 +99,10 @@ func getNowKeyGenerationOffset() time.Time {
 	pgp.lock.RLock()
 	defer pgp.lock.RUnlock()
 
+	if pgp.clock != nil {
+		return time.Unix(pgp.clock().Unix()+pgp.generationOffset, 0)
+	}
+
 	if pgp.latestServerTime == 0 {
 		return time.Unix(time.Now().Unix()+pgp.generationOffset, 0)
 	}
@@ -67,3 +114,55 @@ func getNowKeyGenerationOffset() time.Time {
 func getKeyGenerationTimeGenerator() func() time.Time {
 	return getNowKeyGenerationOffset
 }
+
+// validWithTolerance reports whether isValid holds at the current time or,
+// for objects created slightly in the future, at the current time plus the
+// time offset tolerance.
+func validWithTolerance(isValid func(now time.Time) bool) bool {
+	now := getNow()
+	if isValid(now) {
+		return true
+	}
+	tolerance := GetTimeOffsetTolerance()
+	return tolerance > 0 && isValid(now.Add(time.Duration(tolerance)*time.Second))
+}
+
+// getEncryptionTimeGenerator returns the time at which the encryption keys of
+// entities are selected: the current time or, if a key is only valid within
+// the time offset tolerance, the current time plus the tolerance.
+func getEncryptionTimeGenerator(entities openpgp.EntityList) func() time.Time {
+	return newEncryptionTime(entities).now
+}
+
+// encryptionTime is the time of the config of an encryption to entities.
+// go-crypto selects the encryption keys at the config time, but also uses it
+// for the signature, so the time is only shifted by the tolerance until the
+// keys are selected.
+type encryptionTime struct {
+	offset time.Duration
+}
+
+func newEncryptionTime(entities openpgp.EntityList) *encryptionTime {
+	canEncrypt := func(now time.Time) bool {
+		for _, entity := range entities {
+			if _, ok := entity.EncryptionKey(now); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	if canEncrypt(getNow()) || !validWithTolerance(canEncrypt) {
+		return &encryptionTime{}
+	}
+	return &encryptionTime{offset: time.Duration(GetTimeOffsetTolerance()) * time.Second}
+}
+
+func (encryptionTime *encryptionTime) now() time.Time {
+	return getNow().Add(encryptionTime.offset)
+}
+
+// keysSelected resets the time to the current time, once go-crypto has
+// selected the encryption keys.
+func (encryptionTime *encryptionTime) keysSelected() {
+	encryptionTime.offset = 0
+}
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
@@ -15,3 +16,104 @@ func TestTime(t *testing.T) {
 	assert.Exactly(t, int64(1571072494), now) // Use latest server time
 	UpdateTime(testTime)
 }
+
+func TestSetClock(t *testing.T) {
+	serverTime := GetUnixTime()
+	SetClock(func() time.Time {
+		return time.Unix(1700000000, 0)
+	})
+	defer SetClock(nil)
+
+	assert.Exactly(t, int64(1700000000), GetUnixTime()) // Clock takes precedence over server time
+
+	signature, err := keyRingTestPrivate.SignDetached(NewPlainMessageFromString("clock"))
+	if err != nil {
+		t.Fatal("Cannot sign:", err)
+	}
+	signed, err := keyRingTestPublic.GetVerifiedSignatureTimestamp(NewPlainMessageFromString("clock"), signature, 0)
+	if err != nil {
+		t.Fatal("Cannot verify:", err)
+	}
+	assert.Exactly(t, int64(1700000000), signed)
+
+	SetClock(nil)
+	assert.Exactly(t, serverTime, GetUnixTime())
+}
+
+func TestTimeOffsetTolerance(t *testing.T) {
+	now := GetUnixTime()
+	SetClock(func() time.Time {
+		return time.Unix(now+3600, 0)
+	})
+	futureKey, err := GenerateKey(keyTestName, keyTestDomain, "x25519", 256)
+	if err != nil {
+		t.Fatal("Cannot generate key:", err)
+	}
+	futureKeyRing, err := NewKeyRing(futureKey)
+	if err != nil {
+		t.Fatal("Cannot create keyring:", err)
+	}
+	message := NewPlainMessageFromString("skewed clock")
+	signature, err := futureKeyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Cannot sign:", err)
+	}
+	SetClock(nil)
+
+	assert.True(t, futureKey.CanEncrypt())
+	assert.False(t, futureKey.IsExpired())
+	assert.NoError(t, futureKeyRing.VerifyDetached(message, signature, now))
+
+	// Encryption selects the keys with the same tolerance
+	ciphertext, err := futureKeyRing.Encrypt(message, nil)
+	if err != nil {
+		t.Fatal("Cannot encrypt:", err)
+	}
+	var output bytes.Buffer
+	writer, err := futureKeyRing.EncryptStream(&output, nil, nil)
+	if err != nil {
+		t.Fatal("Cannot encrypt stream:", err)
+	}
+	assert.NoError(t, writer.Close())
+	_, err = futureKeyRing.EncryptAttachment(message, "attachment")
+	assert.NoError(t, err)
+	_, err = futureKeyRing.GetEncryptionSubkeys()
+	assert.NoError(t, err)
+
+	// The message is still signed at the current time
+	signedCiphertext, err := futureKeyRing.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Cannot encrypt and sign:", err)
+	}
+	signedDecrypted, err := futureKeyRing.Decrypt(signedCiphertext, keyRingTestPublic, now)
+	if err != nil {
+		t.Fatal("Cannot decrypt and verify:", err)
+	}
+	embeddedSignature, err := signedDecrypted.GetSignature()
+	if err != nil {
+		t.Fatal("Cannot get signature:", err)
+	}
+	signatureTime, err := keyRingTestPublic.GetVerifiedSignatureTimestamp(signedDecrypted, embeddedSignature, now)
+	if err != nil {
+		t.Fatal("Cannot verify signature:", err)
+	}
+	assert.Exactly(t, now, signatureTime)
+
+	SetTimeOffsetTolerance(60)
+	defer SetTimeOffsetTolerance(2 * 24 * 60 * 60)
+
+	assert.Exactly(t, int64(60), GetTimeOffsetTolerance())
+	assert.False(t, futureKey.CanEncrypt())
+	assert.True(t, futureKey.IsExpired())
+	assert.Error(t, futureKeyRing.VerifyDetached(message, signature, now))
+	_, err = futureKeyRing.Encrypt(message, nil)
+	assert.Error(t, err)
+	_, err = futureKeyRing.EncryptAttachment(message, "attachment")
+	assert.Error(t, err)
+
+	decrypted, err := futureKeyRing.Decrypt(ciphertext, nil, 0)
+	if err != nil {
+		t.Fatal("Cannot decrypt:", err)
+	}
+	assert.Exactly(t, message.GetString(), decrypted.GetString())
+}
@@ -0,0 +1,320 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// VerificationCache stores the outcome of successful detached signature
+// verifications, so that verifying the same (message, signature, key) triple
+// again within the TTL skips the public key operation.
+// Only successful verifications are cached, failures are always recomputed.
+// A cached verification remembers the time window in which the signature and
+// the signing key are valid, and is only used for verification times within
+// that window. Signatures of keys with revocations are not cached, and
+// changes to the keys themselves (e.g. a new revocation) are only taken into
+// account once the entry has expired.
+// A VerificationCache is safe for concurrent use and can be shared among keyrings.
+type VerificationCache struct {
+	// Accessed atomically, kept first for 64-bit alignment on 32-bit platforms.
+	hits   int64
+	misses int64
+
+	ttl        time.Duration
+	maxEntries int
+
+	lock    sync.Mutex
+	entries map[[sha256.Size]byte]*verificationCacheEntry
+}
+
+type verificationCacheEntry struct {
+	signature *packet.Signature
+	window    verificationWindow
+	expires   time.Time
+}
+
+// verificationWindow is the range of verification times, in seconds since
+// the epoch, at which a verified signature and its signing key are valid.
+type verificationWindow struct {
+	notBefore int64
+	notAfter  int64
+}
+
+// NewVerificationCache creates a new verification cache.
+// * ttlSeconds : how long a successful verification is remembered, in seconds.
+// * maxEntries : the maximum number of cached verifications, 0 means unlimited.
+func NewVerificationCache(ttlSeconds int64, maxEntries int) *VerificationCache {
+	return &VerificationCache{
+		ttl:        time.Duration(ttlSeconds) * time.Second,
+		maxEntries: maxEntries,
+		entries:    make(map[[sha256.Size]byte]*verificationCacheEntry),
+	}
+}
+
+// GetHits returns the number of verifications answered from the cache.
+func (cache *VerificationCache) GetHits() int64 {
+	return atomic.LoadInt64(&cache.hits)
+}
+
+// GetMisses returns the number of verifications that had to be computed.
+func (cache *VerificationCache) GetMisses() int64 {
+	return atomic.LoadInt64(&cache.misses)
+}
+
+// Len returns the number of entries currently held by the cache,
+// including expired entries that have not been evicted yet.
+func (cache *VerificationCache) Len() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return len(cache.entries)
+}
+
+// Purge removes all entries from the cache and resets the hit and miss counters.
+func (cache *VerificationCache) Purge() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.entries = make(map[[sha256.Size]byte]*verificationCacheEntry)
+	atomic.StoreInt64(&cache.hits, 0)
+	atomic.StoreInt64(&cache.misses, 0)
+}
+
+// SetVerificationCache attaches a verification cache to the keyring.
+// Detached verifications of PlainMessages performed with this keyring
+// (VerifyDetached, VerifyDetachedWithContext, GetVerifiedSignatureTimestamp
+// and GetVerifiedSignatureTimestampWithContext) are then looked up in the cache first.
+// Passing nil disables caching.
+func (keyRing *KeyRing) SetVerificationCache(cache *VerificationCache) {
+	keyRing.verificationCache = cache
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+func (cache *VerificationCache) get(id [sha256.Size]byte, verifyTime int64) (*packet.Signature, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry, ok := cache.entries[id]
+	if ok && !getNow().Before(entry.expires) {
+		delete(cache.entries, id)
+		ok = false
+	}
+	if !ok || !entry.window.contains(verifyTime) {
+		atomic.AddInt64(&cache.misses, 1)
+		return nil, false
+	}
+	atomic.AddInt64(&cache.hits, 1)
+	return entry.signature, true
+}
+
+func (cache *VerificationCache) put(id [sha256.Size]byte, sig *packet.Signature, window verificationWindow) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	now := getNow()
+	if cache.maxEntries > 0 && len(cache.entries) >= cache.maxEntries {
+		cache.evict(now)
+	}
+	cache.entries[id] = &verificationCacheEntry{
+		signature: sig,
+		window:    window,
+		expires:   now.Add(cache.ttl),
+	}
+}
+
+// evict removes the expired entries, and if the cache is still full,
+// the entry closest to expiration. Must be called with the lock held.
+func (cache *VerificationCache) evict(now time.Time) {
+	var oldestID [sha256.Size]byte
+	var oldest *verificationCacheEntry
+	for id, entry := range cache.entries {
+		if !now.Before(entry.expires) {
+			delete(cache.entries, id)
+			continue
+		}
+		if oldest == nil || entry.expires.Before(oldest.expires) {
+			oldestID, oldest = id, entry
+		}
+	}
+	if oldest != nil && len(cache.entries) >= cache.maxEntries {
+		delete(cache.entries, oldestID)
+	}
+}
+
+// verificationCacheID binds a cache entry to the signed data, the signature,
+// and the fingerprints of the verification keys.
+func verificationCacheID(
+	keyRing *KeyRing,
+	message *PlainMessage,
+	signature []byte,
+) (id [sha256.Size]byte) {
+	fingerprints := make([]string, 0, len(keyRing.entities))
+	for _, e := range keyRing.entities {
+		fingerprints = append(fingerprints, string(e.PrimaryKey.Fingerprint))
+	}
+	sort.Strings(fingerprints)
+
+	h := sha256.New()
+	writeField := func(field []byte) {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+		_, _ = h.Write(length[:])
+		_, _ = h.Write(field)
+	}
+
+	dataHash := sha256.Sum256(message.GetBinary())
+	writeField(dataHash[:])
+	writeField(signature)
+	for _, fingerprint := range fingerprints {
+		writeField([]byte(fingerprint))
+	}
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+// getVerificationWindow returns the verification times at which the
+// signature and the key that made it are valid, as checked by go-crypto: all
+// of the signature, the self-signatures and the keys must be created, and
+// not expired. It returns false for keys with revocations, which are not
+// cached.
+func getVerificationWindow(key *openpgp.Key, sig *packet.Signature) (verificationWindow, bool) {
+	window := verificationWindow{notBefore: math.MinInt64, notAfter: math.MaxInt64}
+	entity := key.Entity
+	primarySelfSignature, primaryIdentity := entity.PrimarySelfSignature()
+	signedBySubkey := key.PublicKey != entity.PrimaryKey
+	if len(entity.Revocations) > 0 ||
+		(signedBySubkey && len(key.Revocations) > 0) ||
+		(primaryIdentity != nil && len(primaryIdentity.Revocations) > 0) {
+		return window, false
+	}
+
+	window.restrict(entity.PrimaryKey.CreationTime, primarySelfSignature.KeyLifetimeSecs)
+	signatures := []*packet.Signature{sig, primarySelfSignature}
+	if signedBySubkey {
+		window.restrict(key.PublicKey.CreationTime, key.SelfSignature.KeyLifetimeSecs)
+		signatures = append(signatures, key.SelfSignature, key.SelfSignature.EmbeddedSignature)
+	}
+	for _, signature := range signatures {
+		if signature != nil {
+			window.restrict(signature.CreationTime, signature.SigLifetimeSecs)
+		}
+	}
+	return window, true
+}
+
+// restrict narrows the window to the lifetime of a key or signature.
+func (window *verificationWindow) restrict(creationTime time.Time, lifetimeSecs *uint32) {
+	if creationTime.Unix() > window.notBefore {
+		window.notBefore = creationTime.Unix()
+	}
+	if lifetimeSecs != nil && *lifetimeSecs != 0 {
+		if expiry := creationTime.Unix() + int64(*lifetimeSecs); expiry < window.notAfter {
+			window.notAfter = expiry
+		}
+	}
+}
+
+// contains returns whether a verification at verifyTime would succeed, as by
+// checkSignatureWithEntities: the expiration checks are disabled for a zero
+// verifyTime, and otherwise pass at the verification time with or without
+// the time offset tolerance.
+func (window verificationWindow) contains(verifyTime int64) bool {
+	if verifyTime == 0 {
+		return true
+	}
+	in := func(t int64) bool {
+		return window.notBefore <= t && t <= window.notAfter
+	}
+	return in(verifyTime+GetTimeOffsetTolerance()) || in(verifyTime)
+}
+
+// hasCriticalNotations returns whether the signature has critical
+// notations, which are only accepted when verifying with a context.
+func hasCriticalNotations(sig *packet.Signature) bool {
+	for _, notation := range sig.Notations {
+		if notation.IsCritical {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyMessageSignature verifies a detached signature over a PlainMessage,
+// consulting the keyring's verification cache if one is set, and checks its
+// creation time against the keyring's time window.
+func (keyRing *KeyRing) verifyMessageSignature(
+	message *PlainMessage,
+	signature *PGPSignature,
+	verifyTime int64,
+	verificationContext *VerificationContext,
+) (*packet.Signature, error) {
+	sig, err := keyRing.verifyCachedMessageSignature(message, signature, verifyTime, verificationContext)
+	if err != nil {
+		return nil, err
+	}
+	if err := keyRing.checkVerifyTimeWindow(sig); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func (keyRing *KeyRing) verifyCachedMessageSignature(
+	message *PlainMessage,
+	signature *PGPSignature,
+	verifyTime int64,
+	verificationContext *VerificationContext,
+) (*packet.Signature, error) {
+	cache := keyRing.verificationCache
+	if cache == nil {
+		return verifySignature(
+			keyRing.entities,
+			keyRing.verificationLimits,
+			message.NewReader(),
+			signature.GetBinary(),
+			verifyTime,
+			verificationContext,
+		)
+	}
+
+	id := verificationCacheID(keyRing, message, signature.GetBinary())
+	if sig, ok := cache.get(id, verifyTime); ok {
+		if verificationContext != nil {
+			if err := verificationContext.verifyContext(sig); err != nil {
+				return nil, newSignatureBadContext(err)
+			}
+			return sig, nil
+		}
+		if !hasCriticalNotations(sig) {
+			return sig, nil
+		}
+	}
+
+	sig, err := verifySignature(
+		keyRing.entities,
+		keyRing.verificationLimits,
+		message.NewReader(),
+		signature.GetBinary(),
+		verifyTime,
+		verificationContext,
+	)
+	if err != nil {
+		return nil, err
+	}
+	key, _, err := getSignatureSigningKey(keyRing, sig, message.GetBinary())
+	if err != nil {
+		return sig, nil
+	}
+	if window, ok := getVerificationWindow(key, sig); ok {
+		cache.put(id, sig, window)
+	}
+	return sig, nil
+}
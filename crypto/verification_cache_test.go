@@ -0,0 +1,131 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationCacheHitMiss(t *testing.T) {
+	message := NewPlainMessageFromString("cached token")
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Cannot generate signature:", err)
+	}
+
+	verifyKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy keyring:", err)
+	}
+	cache := NewVerificationCache(60, 0)
+	verifyKeyRing.SetVerificationCache(cache)
+
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Exactly(t, int64(0), cache.GetHits())
+	assert.Exactly(t, int64(1), cache.GetMisses())
+
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Exactly(t, int64(1), cache.GetHits())
+	assert.Exactly(t, 1, cache.Len())
+
+	timestamp, err := verifyKeyRing.GetVerifiedSignatureTimestamp(message, signature, GetUnixTime())
+	assert.Nil(t, err)
+	assert.Exactly(t, GetUnixTime(), timestamp)
+	assert.Exactly(t, int64(2), cache.GetHits())
+
+	// A different message must not be served from the cache
+	tampered := NewPlainMessageFromString("tampered token")
+	assert.NotNil(t, verifyKeyRing.VerifyDetached(tampered, signature, GetUnixTime()))
+	assert.Exactly(t, int64(2), cache.GetMisses())
+	assert.Exactly(t, 1, cache.Len())
+
+	// A later verification time is served from the cache
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()+3600))
+	assert.Exactly(t, int64(3), cache.GetHits())
+
+	// A verification time outside of the validity of the signature and the
+	// key must not be served from the cache
+	assert.NotNil(t, verifyKeyRing.VerifyDetached(message, signature, 1000000))
+	assert.Exactly(t, int64(3), cache.GetHits())
+	assert.Exactly(t, int64(3), cache.GetMisses())
+
+	// The context is checked against the cached signature
+	err = verifyKeyRing.VerifyDetachedWithContext(message, signature, GetUnixTime(), NewVerificationContext("ctx", true, 0))
+	assert.NotNil(t, err)
+	assert.Exactly(t, int64(4), cache.GetHits())
+
+	cache.Purge()
+	assert.Exactly(t, 0, cache.Len())
+	assert.Exactly(t, int64(0), cache.GetHits())
+}
+
+func TestVerificationCacheExpiry(t *testing.T) {
+	message := NewPlainMessageFromString("short lived")
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Cannot generate signature:", err)
+	}
+
+	cache := NewVerificationCache(0, 1)
+	verifyKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy keyring:", err)
+	}
+	verifyKeyRing.SetVerificationCache(cache)
+
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+	assert.Exactly(t, int64(0), cache.GetHits())
+	assert.Exactly(t, int64(2), cache.GetMisses())
+	assert.Exactly(t, 1, cache.Len())
+}
+
+func TestVerificationCacheExpiryWithClock(t *testing.T) {
+	message := NewPlainMessageFromString("clock")
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Cannot generate signature:", err)
+	}
+
+	cache := NewVerificationCache(60, 1)
+	verifyKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy keyring:", err)
+	}
+	verifyKeyRing.SetVerificationCache(cache)
+
+	now := GetUnixTime()
+	SetClock(func() time.Time { return time.Unix(now, 0) })
+	defer SetClock(nil)
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, now))
+	SetClock(func() time.Time { return time.Unix(now+59, 0) })
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, now))
+	assert.Exactly(t, int64(1), cache.GetHits())
+	SetClock(func() time.Time { return time.Unix(now+60, 0) })
+	assert.Nil(t, verifyKeyRing.VerifyDetached(message, signature, now))
+	assert.Exactly(t, int64(1), cache.GetHits())
+	assert.Exactly(t, int64(2), cache.GetMisses())
+}
+
+func TestVerificationCacheWithContext(t *testing.T) {
+	message := NewPlainMessageFromString("context token")
+	signature, err := keyRingTestPrivate.SignDetachedWithContext(message, NewSigningContext("ctx", true))
+	if err != nil {
+		t.Fatal("Cannot generate signature:", err)
+	}
+
+	verifyKeyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Cannot copy keyring:", err)
+	}
+	cache := NewVerificationCache(60, 0)
+	verifyKeyRing.SetVerificationCache(cache)
+
+	assert.Nil(t, verifyKeyRing.VerifyDetachedWithContext(message, signature, GetUnixTime(), NewVerificationContext("ctx", true, 0)))
+	assert.Nil(t, verifyKeyRing.VerifyDetachedWithContext(message, signature, GetUnixTime(), NewVerificationContext("ctx", true, 0)))
+	assert.Exactly(t, int64(1), cache.GetHits())
+
+	// The critical context notation is rejected without a context
+	assert.NotNil(t, verifyKeyRing.VerifyDetached(message, signature, GetUnixTime()))
+}
@@ -0,0 +1,149 @@
+package crypto
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// VerificationLimits restricts the signatures verified with a keyring, to
+// reject inputs with too many signatures or packets, or too much signed data.
+// A limit of 0 means no limit.
+// The counts are checked before any work is done, so they only apply to the
+// packets that can be counted before go-crypto parses them: the ones of
+// detached signatures, and the ones outside of the encrypted data of
+// messages. The signatures embedded in encrypted messages are parsed by
+// go-crypto while decrypting, and are not counted: limit the size of the
+// messages accepted instead.
+type VerificationLimits struct {
+	// DetachedSignatureCountLimit is the maximum number of signatures of a
+	// detached signature, checked before it is verified.
+	DetachedSignatureCountLimit int
+	// PacketCountLimit is the maximum number of packets of a detached
+	// signature, checked before it is verified, or of the packets outside of
+	// the encrypted data of an encrypted message, e.g. its key packets,
+	// checked before it is decrypted. The packets of the encrypted data, and
+	// of streamed messages, are not counted.
+	PacketCountLimit int
+	// MaxLiteralBytes is the maximum size of the signed data: the message
+	// of a detached signature, or the plaintext of a signed message.
+	MaxLiteralBytes int64
+}
+
+// NewVerificationLimits creates new verification limits, see
+// VerificationLimits.
+func NewVerificationLimits(detachedSignatureCountLimit, packetCountLimit int, maxLiteralBytes int64) *VerificationLimits {
+	return &VerificationLimits{
+		DetachedSignatureCountLimit: detachedSignatureCountLimit,
+		PacketCountLimit:            packetCountLimit,
+		MaxLiteralBytes:             maxLiteralBytes,
+	}
+}
+
+// SetVerificationLimits sets the limits applied when verifying signatures
+// with the keyring, as a detached signature or while decrypting a signed
+// message. The inputs exceeding them are rejected with an error wrapping
+// ErrLimitExceeded. Passing nil removes the limits.
+func (keyRing *KeyRing) SetVerificationLimits(limits *VerificationLimits) {
+	keyRing.verificationLimits = limits
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// getVerificationLimits returns the limits of the keyring, or nil if the
+// keyring is nil.
+func (keyRing *KeyRing) getVerificationLimits() *VerificationLimits {
+	if keyRing == nil {
+		return nil
+	}
+	return keyRing.verificationLimits
+}
+
+// checkDetachedSignature checks the number of packets of a detached
+// signature, and the number of signature packets among them.
+func (limits *VerificationLimits) checkDetachedSignature(signature []byte) error {
+	if limits == nil {
+		return nil
+	}
+	return limits.checkPackets(signature, limits.DetachedSignatureCountLimit)
+}
+
+// checkPackets checks the number of packets of data, and the number of
+// signature packets among them if signatureCountLimit is positive.
+func (limits *VerificationLimits) checkPackets(data []byte, signatureCountLimit int) error {
+	if limits == nil || (limits.PacketCountLimit == 0 && signatureCountLimit == 0) {
+		return nil
+	}
+	packets, signatures := 0, 0
+	for offset := 0; offset < len(data); {
+		tag, next, err := nextPacketOffset(data, offset)
+		if err != nil {
+			return err
+		}
+		packets++
+		if tag == packetTagSignature {
+			signatures++
+		}
+		if limits.PacketCountLimit > 0 && packets > limits.PacketCountLimit {
+			return newClassifiedError(ErrLimitExceeded, "gopenpgp: too many packets", nil)
+		}
+		if signatureCountLimit > 0 && signatures > signatureCountLimit {
+			return newClassifiedError(ErrLimitExceeded, "gopenpgp: too many signatures", nil)
+		}
+		offset = next
+	}
+	return nil
+}
+
+// limitReader returns a reader failing once more than MaxLiteralBytes are
+// read from reader, or reader itself if there is no limit.
+func (limits *VerificationLimits) limitReader(reader io.Reader) io.Reader {
+	if limits == nil || limits.MaxLiteralBytes == 0 {
+		return reader
+	}
+	limited := &limitedReader{reader: reader, remaining: limits.MaxLiteralBytes}
+	if seeker, ok := reader.(io.Seeker); ok {
+		return &limitedReadSeeker{limitedReader: limited, seeker: seeker, limit: limits.MaxLiteralBytes}
+	}
+	return limited
+}
+
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *limitedReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, newClassifiedError(ErrLimitExceeded, "gopenpgp: signed data too large", nil)
+	}
+	return n, err
+}
+
+// limitedReadSeeker is a limitedReader which keeps the reader seekable,
+// restarting the count from the new position.
+type limitedReadSeeker struct {
+	*limitedReader
+	seeker io.Seeker
+	limit  int64
+}
+
+func (r *limitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	position, err := r.seeker.Seek(offset, whence)
+	if err == nil {
+		r.remaining = r.limit - position
+	}
+	return position, err
+}
+
+// checkMessageLimits checks the packets of an encrypted message to be
+// verified with verifyKey, without the packets of its encrypted data which
+// can't be counted before go-crypto decrypts and parses them.
+func checkMessageLimits(message *PGPMessage, verifyKey *KeyRing) error {
+	if err := verifyKey.getVerificationLimits().checkPackets(message.Data, 0); err != nil {
+		return errors.Wrap(err, "gopenpgp: error in reading message")
+	}
+	return nil
+}
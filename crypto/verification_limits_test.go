@@ -0,0 +1,75 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationLimitsDetached(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	signedTwice := NewPGPSignature(append(signature.GetBinary(), signature.GetBinary()...))
+
+	keyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	if err := keyRing.VerifyDetached(message, signedTwice, GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying without limits, got:", err)
+	}
+
+	keyRing.SetVerificationLimits(NewVerificationLimits(1, 0, 0))
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+	err = keyRing.VerifyDetached(message, signedTwice, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+	keyRing.SetVerificationLimits(NewVerificationLimits(0, 1, 0))
+	err = keyRing.VerifyDetached(message, signedTwice, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+	keyRing.SetVerificationLimits(NewVerificationLimits(0, 0, int64(len(testMessage))))
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+	keyRing.SetVerificationLimits(NewVerificationLimits(0, 0, int64(len(testMessage)-1)))
+	err = keyRing.VerifyDetached(message, signature, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	err = keyRing.VerifyDetachedStream(bytes.NewReader(message.GetBinary()), signature, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+}
+
+func TestVerificationLimitsMessage(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	keyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	keyRing.SetVerificationLimits(NewVerificationLimits(1, 2, int64(len(testMessage))))
+	decrypted, err := keyRingTestPrivate.Decrypt(ciphertext, keyRing, GetUnixTime())
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessage, decrypted.GetString())
+
+	// A key packet and a data packet
+	keyRing.SetVerificationLimits(NewVerificationLimits(0, 1, 0))
+	_, err = keyRingTestPrivate.Decrypt(ciphertext, keyRing, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+	keyRing.SetVerificationLimits(NewVerificationLimits(0, 0, int64(len(testMessage)-1)))
+	_, err = keyRingTestPrivate.Decrypt(ciphertext, keyRing, GetUnixTime())
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+	// The limits only apply to verification
+	_, err = keyRingTestPrivate.Decrypt(ciphertext, nil, 0)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	pgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+// VerificationPolicy configures how a VerificationSummary aggregates the
+// results of the verification of several signatures.
+type VerificationPolicy struct {
+	// RequireAllValid makes the summary the worst of the results, instead of
+	// the best one, so that a message is only valid if all its signatures are.
+	RequireAllValid bool
+	// RejectInsecureAlgorithms summarizes the valid signatures made with an
+	// insecure hash algorithm as invalid, instead of
+	// VERIFICATION_VALID_INSECURE_ALGORITHM.
+	RejectInsecureAlgorithms bool
+}
+
+// VerificationSummary aggregates the results of the verification of the
+// signatures of a message into a single status, e.g. to display one badge.
+type VerificationSummary struct {
+	policy  VerificationPolicy
+	summary int
+	empty   bool
+}
+
+// NewVerificationSummary creates an empty summary with the given policy,
+// or the default policy if nil: the message is valid if any of its
+// signatures is.
+func NewVerificationSummary(policy *VerificationPolicy) *VerificationSummary {
+	summary := &VerificationSummary{summary: constants.VERIFICATION_NOT_SIGNED, empty: true}
+	if policy != nil {
+		summary.policy = *policy
+	}
+	return summary
+}
+
+// AddResult adds the result of a verification, i.e. the error returned by
+// e.g. KeyRing.VerifyDetached, Decrypt or PlainMessageReader.VerifySignature,
+// which is nil if the signature is valid.
+func (summary *VerificationSummary) AddResult(verificationErr error) {
+	result := getVerificationSummary(verificationErr, summary.policy.RejectInsecureAlgorithms)
+	switch {
+	case summary.empty:
+		summary.summary = result
+	case summary.policy.RequireAllValid && result > summary.summary:
+		summary.summary = result
+	case !summary.policy.RequireAllValid && result < summary.summary:
+		summary.summary = result
+	}
+	summary.empty = false
+}
+
+// Summary returns one of the constants.VERIFICATION_* statuses,
+// VERIFICATION_NOT_SIGNED if no result was added.
+func (summary *VerificationSummary) Summary() int {
+	return summary.summary
+}
+
+// GetVerificationSummary returns the constants.VERIFICATION_* status of the
+// result of a single verification, see VerificationSummary.AddResult.
+func GetVerificationSummary(verificationErr error) int {
+	return getVerificationSummary(verificationErr, false)
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func getVerificationSummary(verificationErr error, rejectInsecure bool) int {
+	if verificationErr == nil {
+		return constants.VERIFICATION_VALID
+	}
+	var signatureError SignatureVerificationError
+	if !errors.As(verificationErr, &signatureError) {
+		return constants.VERIFICATION_INVALID
+	}
+	switch {
+	case signatureError.insecure && !rejectInsecure:
+		return constants.VERIFICATION_VALID_INSECURE_ALGORITHM
+	case signatureError.Status == constants.SIGNATURE_NOT_SIGNED:
+		return constants.VERIFICATION_NOT_SIGNED
+	case signatureError.Status == constants.SIGNATURE_NO_VERIFIER,
+		errors.Is(signatureError.Cause, pgpErrors.ErrUnknownIssuer):
+		return constants.VERIFICATION_NO_MATCHING_KEY
+	case errors.Is(signatureError.Cause, pgpErrors.ErrKeyRevoked):
+		return constants.VERIFICATION_REVOKED
+	case errors.Is(signatureError.Cause, pgpErrors.ErrSignatureExpired),
+		errors.Is(signatureError.Cause, pgpErrors.ErrKeyExpired):
+		return constants.VERIFICATION_EXPIRED
+	default:
+		return constants.VERIFICATION_INVALID
+	}
+}
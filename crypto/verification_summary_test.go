@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationSummary(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	otherKeyRing, err := NewKeyRing(keyTestEC)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	valid := keyRingTestPublic.VerifyDetached(message, signature, GetUnixTime())
+	noKey := otherKeyRing.VerifyDetached(message, signature, GetUnixTime())
+	invalid := keyRingTestPublic.VerifyDetached(NewPlainMessageFromString("tampered"), signature, GetUnixTime())
+
+	assert.Exactly(t, constants.VERIFICATION_VALID, GetVerificationSummary(valid))
+	assert.Exactly(t, constants.VERIFICATION_NO_MATCHING_KEY, GetVerificationSummary(noKey))
+	assert.Exactly(t, constants.VERIFICATION_INVALID, GetVerificationSummary(invalid))
+
+	pgpMessage, err := NewPGPMessageFromArmored(readTestFile("message_sha1_signed", false))
+	if err != nil {
+		t.Fatal("Expected no error when unarmoring, got:", err)
+	}
+	_, insecure := keyRingTestPrivate.Decrypt(pgpMessage, keyRingTestPrivate, 0)
+	assert.Exactly(t, constants.VERIFICATION_VALID_INSECURE_ALGORITHM, GetVerificationSummary(insecure))
+
+	// The other checks take precedence over the insecure hash
+	_, badContext := keyRingTestPrivate.DecryptWithContext(pgpMessage, keyRingTestPrivate, 0, NewVerificationContext("ctx", true, 0))
+	assert.Exactly(t, constants.VERIFICATION_INVALID, GetVerificationSummary(badContext))
+	var signatureError SignatureVerificationError
+	assert.True(t, errors.As(badContext, &signatureError))
+	assert.Exactly(t, constants.SIGNATURE_BAD_CONTEXT, signatureError.Status)
+
+	summary := NewVerificationSummary(nil)
+	assert.Exactly(t, constants.VERIFICATION_NOT_SIGNED, summary.Summary())
+	summary.AddResult(invalid)
+	summary.AddResult(valid)
+	assert.Exactly(t, constants.VERIFICATION_VALID, summary.Summary())
+
+	summary = NewVerificationSummary(&VerificationPolicy{RequireAllValid: true})
+	summary.AddResult(valid)
+	summary.AddResult(noKey)
+	assert.Exactly(t, constants.VERIFICATION_NO_MATCHING_KEY, summary.Summary())
+
+	summary = NewVerificationSummary(&VerificationPolicy{RejectInsecureAlgorithms: true})
+	summary.AddResult(insecure)
+	assert.Exactly(t, constants.VERIFICATION_INVALID, summary.Summary())
+}
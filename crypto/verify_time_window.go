@@ -0,0 +1,55 @@
+package crypto
+
+import (
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// verifyTimeWindow bounds the creation time of the accepted signatures.
+type verifyTimeWindow struct {
+	notBefore int64
+	notAfter  int64
+}
+
+// SetVerifyTimeWindow only accepts signatures created between the unix times
+// notBefore and notAfter, inclusive, when verifying with the keyring, e.g. in
+// challenge-response protocols where stale signatures must not be replayed.
+// A bound of 0 means no bound, and passing 0 for both removes the window.
+// Signatures created outside the window fail with a
+// SignatureVerificationError of status SIGNATURE_FAILED.
+// Unlike verifyTime, the window is applied without the time offset
+// tolerance.
+func (keyRing *KeyRing) SetVerifyTimeWindow(notBefore, notAfter int64) {
+	if notBefore == 0 && notAfter == 0 {
+		keyRing.verifyTimeWindow = nil
+		return
+	}
+	keyRing.verifyTimeWindow = &verifyTimeWindow{notBefore: notBefore, notAfter: notAfter}
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// checkVerifyTimeWindow checks that a verified signature was created in the
+// window of the keyring, if any.
+func (keyRing *KeyRing) checkVerifyTimeWindow(sig *packet.Signature) error {
+	if keyRing == nil || keyRing.verifyTimeWindow == nil || sig == nil {
+		return nil
+	}
+	window := keyRing.verifyTimeWindow
+	created := sig.CreationTime.Unix()
+	if window.notBefore != 0 && created < window.notBefore {
+		return newSignatureFailed(errors.Errorf(
+			"gopenpgp: signature created at %s, before the verification window",
+			sig.CreationTime.UTC().Format(time.RFC3339),
+		))
+	}
+	if window.notAfter != 0 && created > window.notAfter {
+		return newSignatureFailed(errors.Errorf(
+			"gopenpgp: signature created at %s, after the verification window",
+			sig.CreationTime.UTC().Format(time.RFC3339),
+		))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyTimeWindow(t *testing.T) {
+	message := NewPlainMessageFromString(testMessage)
+	signature, err := keyRingTestPrivate.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	created := GetUnixTime()
+	ciphertext, err := keyRingTestPublic.Encrypt(message, keyRingTestPrivate)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	keyRing, err := keyRingTestPublic.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying keyring, got:", err)
+	}
+	keyRing.SetVerifyTimeWindow(created, created)
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+	_, err = keyRingTestPrivate.Decrypt(ciphertext, keyRing, GetUnixTime())
+	assert.NoError(t, err)
+
+	for _, window := range [][2]int64{{created + 1, 0}, {0, created - 1}} {
+		keyRing.SetVerifyTimeWindow(window[0], window[1])
+		err = keyRing.VerifyDetached(message, signature, GetUnixTime())
+		checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+		err = keyRing.VerifyDetachedStream(bytes.NewReader(message.GetBinary()), signature, GetUnixTime())
+		checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+		_, err = keyRingTestPrivate.Decrypt(ciphertext, keyRing, GetUnixTime())
+		checkVerificationError(t, err, constants.SIGNATURE_FAILED)
+	}
+
+	keyRing.SetVerifyTimeWindow(0, 0)
+	assert.NoError(t, keyRing.VerifyDetached(message, signature, GetUnixTime()))
+}
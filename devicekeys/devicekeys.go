@@ -0,0 +1,286 @@
+// Package devicekeys manages a hierarchy of per-device keys certified by an
+// identity key, in the style of Keybase: each device generates its own key,
+// which never leaves the device, and the identity key binds it to the
+// identity certificate as a signing subkey. Contacts only need to know the
+// identity certificate to verify signatures of any current device, and a
+// lost device is removed by revoking its subkey, without rotating the
+// identity key or the other devices.
+package devicekeys
+
+import (
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// NotationName is the name of the notation carrying the name of the device
+// in the binding signature of device subkeys. Subkeys without it, e.g. the
+// encryption subkey of the identity, are not devices.
+const NotationName = "device@proton.ch"
+
+// Device is a device key bound to an identity certificate.
+type Device struct {
+	// Name is the name of the device, given when it was provisioned.
+	Name string
+	// Fingerprint is the lower case hex fingerprint of the device key.
+	Fingerprint string
+	// CreationTime is the time the device was provisioned.
+	CreationTime int64
+	// Revoked is true if the device key is revoked.
+	Revoked bool
+}
+
+// Hierarchy is an identity key and its certificate, with the device keys
+// bound to it. The certificate is the public identity key with one subkey
+// per device, to be published to contacts, see GetCertificate.
+type Hierarchy struct {
+	identity    *crypto.Key
+	certificate *crypto.Key
+}
+
+// NewHierarchy creates a hierarchy from the unlocked private identity key
+// and its current certificate, e.g. as returned by GetCertificate. The
+// certificate can be nil for an identity without devices yet.
+func NewHierarchy(identity, certificate *crypto.Key) (*Hierarchy, error) {
+	if !identity.IsPrivate() {
+		return nil, errors.New("gopenpgp: the identity key must be private")
+	}
+	unlocked, err := identity.IsUnlocked()
+	if err != nil {
+		return nil, err
+	}
+	if !unlocked {
+		return nil, errors.New("gopenpgp: the identity key must be unlocked")
+	}
+
+	if certificate == nil {
+		certificate, err = identity.ToPublic()
+	} else if certificate.GetFingerprint() != identity.GetFingerprint() {
+		err = errors.New("gopenpgp: the certificate doesn't belong to the identity key")
+	} else if certificate.IsPrivate() {
+		certificate, err = certificate.ToPublic()
+	} else {
+		certificate, err = certificate.Copy()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Hierarchy{identity: identity, certificate: certificate}, nil
+}
+
+// GetCertificate returns the public identity certificate, with the subkeys
+// and revocations of the devices.
+func (h *Hierarchy) GetCertificate() (*crypto.Key, error) {
+	return h.certificate.Copy()
+}
+
+// ProvisionDevice generates a key for a new device, and binds it to the
+// identity, see AddDevice. keyType and bits are as in crypto.GenerateKey.
+// The returned private key is to be moved to the device.
+func (h *Hierarchy) ProvisionDevice(name, keyType string, bits int) (*crypto.Key, *Device, error) {
+	deviceKey, err := crypto.GenerateKey(name, "", keyType, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	device, err := h.AddDevice(name, deviceKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return deviceKey, device, nil
+}
+
+// AddDevice binds the primary key of an unlocked device key, e.g. generated
+// on the device, to the identity as a signing subkey. The key is
+// cross-certified: the identity key signs the subkey binding, with the name
+// of the device, and the device key signs the embedded primary key binding,
+// proving that the device accepts the identity.
+func (h *Hierarchy) AddDevice(name string, deviceKey *crypto.Key) (*Device, error) {
+	if name == "" {
+		return nil, errors.New("gopenpgp: the device name is empty")
+	}
+	devicePrivateKey := deviceKey.GetEntity().PrivateKey
+	if devicePrivateKey == nil || devicePrivateKey.Encrypted || devicePrivateKey.Dummy() {
+		return nil, errors.New("gopenpgp: the device key must be unlocked")
+	}
+	if !devicePrivateKey.CanSign() {
+		return nil, errors.New("gopenpgp: the device key can't sign")
+	}
+	if h.certificate.MatchesFingerprint(deviceKey.GetFingerprint()) || h.findSubkey(deviceKey.GetFingerprint()) != nil {
+		return nil, errors.New("gopenpgp: the device key is already bound to the identity")
+	}
+
+	identityEntity := h.identity.GetEntity()
+	config := &packet.Config{Time: crypto.GetTime}
+	subkeyPublicKey := devicePrivateKey.PublicKey
+	subkeyPublicKey.IsSubkey = true
+
+	binding := newSignature(identityEntity.PrimaryKey, packet.SigTypeSubkeyBinding, config)
+	binding.FlagsValid = true
+	binding.FlagSign = true
+	binding.Notations = []*packet.Notation{{
+		Name:            NotationName,
+		Value:           []byte(name),
+		IsHumanReadable: true,
+	}}
+	binding.EmbeddedSignature = newSignature(&devicePrivateKey.PublicKey, packet.SigTypePrimaryKeyBinding, config)
+	err := binding.EmbeddedSignature.CrossSignKey(&subkeyPublicKey, identityEntity.PrimaryKey, devicePrivateKey, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to cross-certify device key")
+	}
+	if err := binding.SignKey(&subkeyPublicKey, identityEntity.PrivateKey, config); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to certify device key")
+	}
+
+	certificateEntity := h.certificate.GetEntity()
+	certificateEntity.Subkeys = append(certificateEntity.Subkeys, openpgp.Subkey{
+		PublicKey: &subkeyPublicKey,
+		Sig:       binding,
+	})
+	return newDevice(&certificateEntity.Subkeys[len(certificateEntity.Subkeys)-1], name, config.Now()), nil
+}
+
+// RevokeDevice revokes the key of a lost or retired device, given its
+// fingerprint. The key of a lost device is considered compromised, and
+// its signatures become invalid, including the ones made before the
+// revocation. Retired devices are only invalid from the revocation on.
+func (h *Hierarchy) RevokeDevice(fingerprint string, lost bool) error {
+	subkey := h.findSubkey(fingerprint)
+	if subkey == nil {
+		return errors.New("gopenpgp: no device with this fingerprint")
+	}
+
+	reason := packet.KeyRetired
+	if lost {
+		reason = packet.KeyCompromised
+	}
+	config := &packet.Config{Time: crypto.GetTime}
+	revocation := newSignature(h.identity.GetEntity().PrimaryKey, packet.SigTypeSubkeyRevocation, config)
+	revocation.RevocationReason = &reason
+	if err := revocation.RevokeSubkey(subkey.PublicKey, h.identity.GetEntity().PrivateKey, config); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to revoke device key")
+	}
+	subkey.Revocations = append(subkey.Revocations, revocation)
+	return nil
+}
+
+// GetDevices returns the devices of the hierarchy, see GetDevices.
+func (h *Hierarchy) GetDevices(verifyTime int64) []*Device {
+	return GetDevices(h.certificate, verifyTime)
+}
+
+// GetValidDevices returns the devices of the hierarchy that are valid at
+// verifyTime, see GetValidDevices.
+func (h *Hierarchy) GetValidDevices(verifyTime int64) []*Device {
+	return GetValidDevices(h.certificate, verifyTime)
+}
+
+// GetDevices returns the devices bound to an identity certificate, sorted by
+// provisioning time, including the revoked ones. Revocation is evaluated at
+// verifyTime, or at the current time if verifyTime is 0. Subkeys whose
+// binding or cross-certification is invalid are ignored.
+func GetDevices(certificate *crypto.Key, verifyTime int64) []*Device {
+	now := getVerifyTime(verifyTime)
+
+	entity := certificate.GetEntity()
+	var devices []*Device
+	for i := range entity.Subkeys {
+		subkey := &entity.Subkeys[i]
+		name, ok := getDeviceName(subkey.Sig)
+		if !ok || entity.PrimaryKey.VerifyKeySignature(subkey.PublicKey, subkey.Sig) != nil {
+			continue
+		}
+		devices = append(devices, newDevice(subkey, name, now))
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		return devices[i].CreationTime < devices[j].CreationTime
+	})
+	return devices
+}
+
+// GetValidDevices returns the devices bound to an identity certificate that
+// are valid at verifyTime, or at the current time if verifyTime is 0: the
+// devices provisioned before verifyTime and not revoked, if the identity
+// itself is not revoked. Signatures of other device keys must be rejected.
+func GetValidDevices(certificate *crypto.Key, verifyTime int64) []*Device {
+	now := getVerifyTime(verifyTime)
+	if certificate.GetEntity().Revoked(now) {
+		return nil
+	}
+
+	var devices []*Device
+	for _, device := range GetDevices(certificate, verifyTime) {
+		if !device.Revoked && device.CreationTime <= now.Unix() {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// findSubkey returns the device subkey of the certificate with the
+// fingerprint, or nil.
+func (h *Hierarchy) findSubkey(fingerprint string) *openpgp.Subkey {
+	entity := h.certificate.GetEntity()
+	for i := range entity.Subkeys {
+		subkey := &entity.Subkeys[i]
+		if _, ok := getDeviceName(subkey.Sig); ok && crypto.CompareFingerprints(fingerprintOf(subkey), fingerprint) {
+			return subkey
+		}
+	}
+	return nil
+}
+
+// newSignature returns a signature of the key, to be signed, with the
+// subpackets set by go-crypto for self-signatures.
+func newSignature(signer *packet.PublicKey, sigType packet.SignatureType, config *packet.Config) *packet.Signature {
+	return &packet.Signature{
+		Version:           signer.Version,
+		SigType:           sigType,
+		PubKeyAlgo:        signer.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      config.Now(),
+		IssuerKeyId:       &signer.KeyId,
+		IssuerFingerprint: signer.Fingerprint,
+	}
+}
+
+// getVerifyTime returns the time of verifyTime, or the current time if it is 0.
+func getVerifyTime(verifyTime int64) time.Time {
+	if verifyTime == 0 {
+		return crypto.GetTime()
+	}
+	return time.Unix(verifyTime, 0)
+}
+
+func newDevice(subkey *openpgp.Subkey, name string, now time.Time) *Device {
+	return &Device{
+		Name:         name,
+		Fingerprint:  fingerprintOf(subkey),
+		CreationTime: subkey.Sig.CreationTime.Unix(),
+		Revoked:      subkey.Revoked(now),
+	}
+}
+
+// getDeviceName returns the name of the device from the subkey binding
+// signature, and false if the subkey is not a device key.
+func getDeviceName(binding *packet.Signature) (string, bool) {
+	if binding == nil || !binding.FlagSign {
+		return "", false
+	}
+	for _, notation := range binding.Notations {
+		if notation.Name == NotationName {
+			return string(notation.Value), true
+		}
+	}
+	return "", false
+}
+
+func fingerprintOf(subkey *openpgp.Subkey) string {
+	return hex.EncodeToString(subkey.PublicKey.Fingerprint)
+}
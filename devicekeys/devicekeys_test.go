@@ -0,0 +1,123 @@
+package devicekeys
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierarchy(t *testing.T) {
+	identity, err := crypto.GenerateKey("Alice", "alice@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating identity key, got:", err)
+	}
+	hierarchy, err := NewHierarchy(identity, nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating hierarchy, got:", err)
+	}
+	assert.Empty(t, hierarchy.GetDevices(0))
+
+	laptopKey, laptop, err := hierarchy.ProvisionDevice("laptop", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while provisioning device, got:", err)
+	}
+	assert.Exactly(t, "laptop", laptop.Name)
+	assert.Exactly(t, laptopKey.GetFingerprint(), laptop.Fingerprint)
+	phoneKey, _, err := hierarchy.ProvisionDevice("phone", "rsa", 2048)
+	if err != nil {
+		t.Fatal("Expected no error while provisioning device, got:", err)
+	}
+	_, err = hierarchy.AddDevice("phone", phoneKey)
+	assert.Error(t, err)
+
+	// The devices are read back from the published certificate, and their
+	// signatures verify with it
+	certificate, err := hierarchy.GetCertificate()
+	if err != nil {
+		t.Fatal("Expected no error while getting certificate, got:", err)
+	}
+	armored, err := certificate.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring certificate, got:", err)
+	}
+	certificate, err = crypto.NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading certificate, got:", err)
+	}
+	devices := GetValidDevices(certificate, 0)
+	assert.Len(t, devices, 2)
+	assert.Exactly(t, "laptop", devices[0].Name)
+	assert.Exactly(t, "phone", devices[1].Name)
+	assert.Exactly(t, phoneKey.GetFingerprint(), devices[1].Fingerprint)
+
+	phoneKeyRing, err := crypto.NewKeyRing(phoneKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	message := crypto.NewPlainMessageFromString("signed on the phone")
+	signature, err := phoneKeyRing.SignDetached(message)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	certificateKeyRing, err := crypto.NewKeyRing(certificate)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	if err := certificateKeyRing.VerifyDetached(message, signature, crypto.GetUnixTime()); err != nil {
+		t.Fatal("Expected no error while verifying device signature, got:", err)
+	}
+
+	// A lost device is revoked, and can't be found anymore
+	hierarchy, err = NewHierarchy(identity, certificate)
+	if err != nil {
+		t.Fatal("Expected no error while creating hierarchy, got:", err)
+	}
+	if err := hierarchy.RevokeDevice(phoneKey.GetFingerprint(), true); err != nil {
+		t.Fatal("Expected no error while revoking device, got:", err)
+	}
+	assert.Error(t, hierarchy.RevokeDevice(identity.GetFingerprint(), true))
+	devices = hierarchy.GetDevices(0)
+	assert.Len(t, devices, 2)
+	assert.True(t, devices[1].Revoked)
+	devices = hierarchy.GetValidDevices(0)
+	assert.Len(t, devices, 1)
+	assert.Exactly(t, laptopKey.GetFingerprint(), devices[0].Fingerprint)
+
+	certificate, err = hierarchy.GetCertificate()
+	if err != nil {
+		t.Fatal("Expected no error while getting certificate, got:", err)
+	}
+	certificateKeyRing, err = crypto.NewKeyRing(certificate)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.Error(t, certificateKeyRing.VerifyDetached(message, signature, crypto.GetUnixTime()))
+}
+
+func TestHierarchyRequiresUnlockedIdentity(t *testing.T) {
+	identity, err := crypto.GenerateKey("Alice", "alice@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating identity key, got:", err)
+	}
+	lockedIdentity, err := identity.Lock([]byte("passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking identity key, got:", err)
+	}
+	_, err = NewHierarchy(lockedIdentity, nil)
+	assert.Error(t, err)
+
+	publicIdentity, err := identity.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public identity key, got:", err)
+	}
+	_, err = NewHierarchy(publicIdentity, nil)
+	assert.Error(t, err)
+
+	other, err := crypto.GenerateKey("Bob", "bob@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	_, err = NewHierarchy(identity, other)
+	assert.Error(t, err)
+}
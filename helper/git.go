@@ -0,0 +1,101 @@
+package helper
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// gitSignatureHeaders are the commit headers containing signatures, for the
+// SHA-1 and SHA-256 object formats.
+var gitSignatureHeaders = [][]byte{[]byte("gpgsig "), []byte("gpgsig-sha256 ")}
+
+// gitSignatureStart starts the signatures appended to git tag objects.
+var gitSignatureStart = []byte("-----BEGIN PGP SIGNATURE-----")
+
+// VerifyGitObject verifies the PGP signature of a raw git commit or tag
+// object, as printed by e.g. `git cat-file commit <id>`, with the armored
+// public key of the signer, and returns the result of the verification.
+// The signature is read from the gpgsig header of commits or from the end of
+// the message of tags, unless an armored detached signature is given.
+// The signed payload is the object without its signature, as signed by git.
+// An error is returned if the object has no signature or the key can't be
+// parsed, and invalid signatures are reported in the result.
+func VerifyGitObject(publicKey string, object []byte, armoredSignature string, verifyTime int64) (*crypto.VerificationResult, error) {
+	payload, embeddedSignature := splitGitObject(object)
+	if armoredSignature == "" {
+		if embeddedSignature == "" {
+			return nil, errors.New("gopenpgp: the git object has no PGP signature")
+		}
+		armoredSignature = embeddedSignature
+	}
+
+	publicKeyRing, err := createPublicKeyRing(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.NewPGPSignatureFromArmored(armoredSignature)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to unarmor git signature")
+	}
+
+	verifyErr := publicKeyRing.VerifyDetached(crypto.NewPlainMessage(payload), signature, verifyTime)
+	return crypto.NewVerificationResult(verifyErr), nil
+}
+
+// splitGitObject returns the signed payload of a git object and its
+// embedded armored signature, empty if it has none.
+func splitGitObject(object []byte) (payload []byte, signature string) {
+	// Commits: the signatures are headers, continued on the lines starting
+	// with a space, which are all removed from the payload
+	var payloadBuffer, signatureBuffer bytes.Buffer
+	signatures := 0
+	inHeaders, inSignature := true, false
+	for _, line := range bytes.SplitAfter(object, []byte("\n")) {
+		if inHeaders {
+			if inSignature && bytes.HasPrefix(line, []byte(" ")) {
+				if signatures == 1 {
+					signatureBuffer.Write(line[1:])
+				}
+				continue
+			}
+			inSignature = isGitSignatureHeader(line)
+			if inSignature {
+				// Keep the first signature, for the object format of the repository
+				signatures++
+				if signatures == 1 {
+					signatureBuffer.Write(line[bytes.IndexByte(line, ' ')+1:])
+				}
+				continue
+			}
+			inHeaders = len(bytes.TrimRight(line, "\r\n")) > 0
+		}
+		payloadBuffer.Write(line)
+	}
+	if signatures > 0 {
+		return payloadBuffer.Bytes(), signatureBuffer.String()
+	}
+
+	// Tags: the signature is appended to the message, on its own lines
+	for offset := len(object); offset >= 0; {
+		index := bytes.LastIndex(object[:offset], gitSignatureStart)
+		if index < 0 {
+			break
+		}
+		if index == 0 || object[index-1] == '\n' {
+			return object[:index], string(object[index:])
+		}
+		offset = index
+	}
+	return object, ""
+}
+
+func isGitSignatureHeader(line []byte) bool {
+	for _, header := range gitSignatureHeaders {
+		if bytes.HasPrefix(line, header) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyGitObject(t *testing.T) {
+	publicKey := readTestFile("git_publicKey", false)
+	for _, name := range []string{"git_commit", "git_tag"} {
+		object := []byte(readTestFile(name, false))
+		result, err := VerifyGitObject(publicKey, object, "", testTime)
+		if err != nil {
+			t.Fatal("Expected no error while verifying git object, got:", err)
+		}
+		assert.Exactly(t, constants.VERIFICATION_VALID, result.Summary)
+
+		tampered := []byte(strings.Replace(string(object), "signed", "tampered", 1))
+		result, err = VerifyGitObject(publicKey, tampered, "", testTime)
+		if err != nil {
+			t.Fatal("Expected no error while verifying git object, got:", err)
+		}
+		assert.Exactly(t, constants.VERIFICATION_INVALID, result.Summary)
+
+		payload, signature := splitGitObject(object)
+		result, err = VerifyGitObject(publicKey, payload, signature, testTime)
+		if err != nil {
+			t.Fatal("Expected no error while verifying detached git signature, got:", err)
+		}
+		assert.Exactly(t, constants.VERIFICATION_VALID, result.Summary)
+	}
+
+	_, err := VerifyGitObject(publicKey, []byte("tree ca07e59a8d12cc946f2c19f61964b626ffb44dc3\n\nunsigned\n"), "", testTime)
+	assert.Error(t, err)
+}
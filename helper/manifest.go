@@ -0,0 +1,120 @@
+//go:build !ios && !android
+// +build !ios,!android
+
+package helper
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// ManifestReport is the consolidated result of the verification of the
+// files of a manifest, see VerifyManifest.
+type ManifestReport struct {
+	// Entries are the results for each file, sorted by path.
+	Entries []*ManifestEntry
+	// Summary is the worst of the constants.VERIFICATION_* statuses of the
+	// entries, VERIFICATION_VALID only if all the files are validly signed.
+	Summary int
+}
+
+// ManifestEntry is the result of the verification of a file of a manifest.
+type ManifestEntry struct {
+	// Path is the path of the file.
+	Path string
+	// SignaturePath is the path of its detached signature, armored or not.
+	SignaturePath string
+	// Status is one of the constants.VERIFICATION_* statuses,
+	// VERIFICATION_INVALID if a file couldn't be read.
+	Status int
+	// Error is the error of the verification, or of reading the files, nil
+	// if the signature is valid.
+	Error error
+}
+
+// IsValid returns true if all the files of the manifest are validly signed.
+func (report *ManifestReport) IsValid() bool {
+	return report.Summary == constants.VERIFICATION_VALID
+}
+
+// VerifyManifest verifies the detached signatures of the files of a
+// manifest, mapping the path of each file to the path of its signature, e.g.
+// to scan an artifact repository.
+// The files are streamed, and verified concurrently by the given number of
+// workers, or one per CPU if concurrency is not positive. The keyring must
+// not be modified during the verification.
+func VerifyManifest(keyRing *crypto.KeyRing, manifest map[string]string, verifyTime int64, concurrency int) *ManifestReport {
+	report := &ManifestReport{Entries: make([]*ManifestEntry, 0, len(manifest))}
+	for path, signaturePath := range manifest {
+		report.Entries = append(report.Entries, &ManifestEntry{Path: path, SignaturePath: signaturePath})
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Path < report.Entries[j].Path
+	})
+
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	entries := make(chan *ManifestEntry)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range entries {
+				entry.Error = verifyManifestEntry(keyRing, entry, verifyTime)
+				entry.Status = crypto.GetVerificationSummary(entry.Error)
+			}
+		}()
+	}
+	for _, entry := range report.Entries {
+		entries <- entry
+	}
+	close(entries)
+	wg.Wait()
+
+	summary := crypto.NewVerificationSummary(&crypto.VerificationPolicy{RequireAllValid: true})
+	for _, entry := range report.Entries {
+		summary.AddResult(entry.Error)
+	}
+	report.Summary = summary.Summary()
+	return report
+}
+
+// verifyManifestEntry verifies the file of an entry with its signature.
+func verifyManifestEntry(keyRing *crypto.KeyRing, entry *ManifestEntry, verifyTime int64) error {
+	signature, err := readDetachedSignatureFile(entry.SignaturePath)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(entry.Path)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to open file")
+	}
+	defer file.Close()
+	return keyRing.VerifyDetachedStream(file, signature, verifyTime)
+}
+
+// readDetachedSignatureFile reads an armored or binary detached signature.
+func readDetachedSignatureFile(path string) (*crypto.PGPSignature, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read signature file")
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		signature, err := crypto.NewPGPSignatureFromArmored(string(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to read signature file")
+		}
+		return signature, nil
+	}
+	return crypto.NewPGPSignature(data), nil
+}
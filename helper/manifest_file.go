@@ -0,0 +1,176 @@
+//go:build !ios && !android
+// +build !ios,!android
+
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// FileManifestEntry is a file listed in a file manifest, see
+// SignFileManifest.
+type FileManifestEntry struct {
+	// Name is the path of the file relative to the directory of the
+	// manifest, with slashes.
+	Name string
+	// Size is the size of the file in bytes.
+	Size int64
+	// SHA256 is the hex SHA-256 digest of the file.
+	SHA256 string
+	// Error is set by VerifyFileManifest if the file is missing, or doesn't
+	// match its size or digest.
+	Error error
+}
+
+// SignFileManifest builds the manifest of the files of dir with the given
+// names, i.e. paths relative to dir with slashes, and returns it
+// clearsigned, e.g. to sign the artifacts of a release with a single
+// signature. The manifest lists the SHA-256 digest, the size and the name of
+// each file, sorted by name, one file per line:
+//
+//	2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  5  hello.txt
+func SignFileManifest(keyRing *crypto.KeyRing, dir string, names []string) (string, error) {
+	names = append([]string{}, names...)
+	sort.Strings(names)
+
+	var manifest strings.Builder
+	for _, name := range names {
+		if err := checkManifestFileName(name); err != nil {
+			return "", err
+		}
+		size, digest, err := hashManifestFile(dir, name)
+		if err != nil {
+			return "", err
+		}
+		manifest.WriteString(digest + "  " + strconv.FormatInt(size, 10) + "  " + name + "\n")
+	}
+	return SignCleartextMessage(keyRing, manifest.String())
+}
+
+// VerifyFileManifest verifies the signature of a clearsigned file manifest,
+// see SignFileManifest, then checks the size and SHA-256 digest of each
+// listed file of dir.
+// Returns the entries of the manifest, and an error if the signature is not
+// valid, in which case no entry is returned, or if a file doesn't match, in
+// which case the Error of its entry is set.
+func VerifyFileManifest(keyRing *crypto.KeyRing, manifest string, dir string, verifyTime int64) ([]*FileManifestEntry, error) {
+	text, err := VerifyCleartextMessage(keyRing, manifest, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseFileManifest(text)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := 0
+	for _, entry := range entries {
+		size, digest, err := hashManifestFile(dir, entry.Name)
+		switch {
+		case err != nil:
+			entry.Error = err
+		case size != entry.Size:
+			entry.Error = errors.New("gopenpgp: size of " + entry.Name + " doesn't match the manifest")
+		case digest != entry.SHA256:
+			entry.Error = errors.New("gopenpgp: digest of " + entry.Name + " doesn't match the manifest")
+		}
+		if entry.Error != nil {
+			mismatches++
+		}
+	}
+	if mismatches > 0 {
+		return entries, errors.New("gopenpgp: " + strconv.Itoa(mismatches) + " files don't match the manifest")
+	}
+	return entries, nil
+}
+
+// parseFileManifest parses the lines of a manifest.
+func parseFileManifest(text string) ([]*FileManifestEntry, error) {
+	var entries []*FileManifestEntry
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "  ", 3)
+		if len(fields) != 3 {
+			return nil, errors.New("gopenpgp: invalid manifest line: " + line)
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size < 0 {
+			return nil, errors.New("gopenpgp: invalid size in manifest line: " + line)
+		}
+		if len(fields[0]) != 2*sha256.Size {
+			return nil, errors.New("gopenpgp: invalid digest in manifest line: " + line)
+		}
+		if err := checkManifestFileName(fields[2]); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &FileManifestEntry{
+			Name:   fields[2],
+			Size:   size,
+			SHA256: strings.ToLower(fields[0]),
+		})
+	}
+	return entries, nil
+}
+
+// checkManifestFileName checks that name is a relative path inside the
+// directory of the manifest, which fits on a line of the signed text.
+// Backslashes and volume names are rejected, so that a name has the same
+// meaning on every platform.
+func checkManifestFileName(name string) error {
+	clean := path.Clean(name)
+	local := filepath.FromSlash(name)
+	if name == "" || path.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") ||
+		strings.ContainsAny(name, "\\\r\n") || strings.TrimRight(name, " \t") != name ||
+		filepath.IsAbs(local) || filepath.VolumeName(local) != "" {
+		return errors.New("gopenpgp: invalid file name in manifest: " + strconv.Quote(name))
+	}
+	return nil
+}
+
+// manifestFilePath returns the local path of a file of a manifest, and an
+// error if it isn't under dir.
+func manifestFilePath(dir, name string) (string, error) {
+	if err := checkManifestFileName(name); err != nil {
+		return "", err
+	}
+	localPath := filepath.Join(dir, filepath.FromSlash(name))
+	relative, err := filepath.Rel(filepath.Clean(dir), localPath)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) ||
+		filepath.IsAbs(relative) {
+		return "", errors.New("gopenpgp: file outside of the manifest directory: " + strconv.Quote(name))
+	}
+	return localPath, nil
+}
+
+// hashManifestFile returns the size and the hex SHA-256 digest of a file.
+func hashManifestFile(dir, name string) (int64, string, error) {
+	localPath, err := manifestFilePath(dir, name)
+	if err != nil {
+		return 0, "", err
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "gopenpgp: unable to open file")
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "gopenpgp: unable to read file")
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
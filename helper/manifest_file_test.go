@@ -0,0 +1,70 @@
+//go:build !ios && !android
+// +build !ios,!android
+
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileManifest(t *testing.T) {
+	keyRing := newTestSigningKeyRing(t)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "bin"), 0o700); err != nil {
+		t.Fatal("Expected no error while creating directory, got:", err)
+	}
+	for name, data := range map[string]string{"hello.txt": "hello", "bin/tool": "-binary-"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0o600); err != nil {
+			t.Fatal("Expected no error while writing file, got:", err)
+		}
+	}
+
+	manifest, err := SignFileManifest(keyRing, dir, []string{"hello.txt", "bin/tool"})
+	if err != nil {
+		t.Fatal("Expected no error while signing manifest, got:", err)
+	}
+	assert.Contains(t, manifest, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  5  hello.txt")
+
+	entries, err := VerifyFileManifest(keyRing, manifest, dir, testTime)
+	if err != nil {
+		t.Fatal("Expected no error while verifying manifest, got:", err)
+	}
+	if assert.Len(t, entries, 2) {
+		assert.Exactly(t, "bin/tool", entries[0].Name)
+		assert.Exactly(t, int64(8), entries[0].Size)
+		assert.NoError(t, entries[1].Error)
+	}
+
+	// Modified file
+	if err = ioutil.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hellO"), 0o600); err != nil {
+		t.Fatal("Expected no error while writing file, got:", err)
+	}
+	entries, err = VerifyFileManifest(keyRing, manifest, dir, testTime)
+	assert.Error(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.NoError(t, entries[0].Error)
+		assert.Error(t, entries[1].Error)
+	}
+
+	// Modified manifest
+	tampered := strings.Replace(manifest, "  5  hello.txt", "  5  hello.exe", 1)
+	entries, err = VerifyFileManifest(keyRing, tampered, dir, testTime)
+	assert.Error(t, err)
+	assert.Nil(t, entries)
+
+	_, err = SignFileManifest(keyRing, dir, []string{"../outside"})
+	assert.Error(t, err)
+}
+
+func TestCheckManifestFileName(t *testing.T) {
+	assert.NoError(t, checkManifestFileName("bin/tool"))
+	for _, name := range []string{"../outside", "/etc/passwd", "bin/../../outside", `bin\tool`, `C:\outside`, `\\host\share`, "tool\n"} {
+		assert.Error(t, checkManifestFileName(name), name)
+	}
+}
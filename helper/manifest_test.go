@@ -0,0 +1,63 @@
+package helper
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyManifest(t *testing.T) {
+	keyRing := newTestSigningKeyRing(t)
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal("Expected no error while writing file, got:", err)
+		}
+		return path
+	}
+
+	manifest := make(map[string]string)
+	for _, name := range []string{"a.tar.gz", "b.tar.gz", "c.tar.gz"} {
+		data := []byte("artifact " + name)
+		signature, err := keyRing.SignDetached(crypto.NewPlainMessage(data))
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		signatureData := signature.GetBinary()
+		if name == "b.tar.gz" {
+			armored, err := signature.GetArmored()
+			if err != nil {
+				t.Fatal("Expected no error while armoring, got:", err)
+			}
+			signatureData = []byte(armored)
+		}
+		if name == "c.tar.gz" {
+			data = []byte("tampered")
+		}
+		manifest[writeFile(name, data)] = writeFile(name+".sig", signatureData)
+	}
+
+	report := VerifyManifest(keyRing, manifest, testTime, 2)
+	assert.False(t, report.IsValid())
+	assert.Exactly(t, constants.VERIFICATION_INVALID, report.Summary)
+	if assert.Len(t, report.Entries, 3) {
+		assert.Exactly(t, filepath.Join(dir, "a.tar.gz"), report.Entries[0].Path)
+		assert.NoError(t, report.Entries[0].Error)
+		assert.NoError(t, report.Entries[1].Error)
+		assert.Error(t, report.Entries[2].Error)
+		assert.Exactly(t, constants.VERIFICATION_INVALID, report.Entries[2].Status)
+	}
+
+	delete(manifest, filepath.Join(dir, "c.tar.gz"))
+	report = VerifyManifest(keyRing, manifest, testTime, 0)
+	assert.True(t, report.IsValid())
+
+	manifest[filepath.Join(dir, "missing")] = filepath.Join(dir, "a.tar.gz.sig")
+	report = VerifyManifest(keyRing, manifest, testTime, 0)
+	assert.False(t, report.IsValid())
+}
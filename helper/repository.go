@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+	"github.com/pkg/errors"
+)
+
+// Layout of RPM packages: a lead, followed by the signature header and the
+// header, which start with rpmHeaderMagic.
+const (
+	rpmLeadSize       = 96
+	rpmHeaderIntro    = 16
+	rpmIndexEntrySize = 16
+)
+
+var (
+	rpmLeadMagic   = []byte{0xed, 0xab, 0xee, 0xdb}
+	rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+)
+
+// SignDebianInRelease clearsigns the content of a Debian repository Release
+// file, and returns the content of the corresponding InRelease file.
+// Trailing whitespace is removed from the lines, as it is not signed, and
+// lines starting with a dash are dash-escaped.
+func SignDebianInRelease(keyRing *crypto.KeyRing, release []byte) (string, error) {
+	text := strings.TrimSuffix(internal.TrimEachLine(strings.ReplaceAll(string(release), "\r\n", "\n")), "\n")
+
+	signature, err := keyRing.SignDetached(crypto.NewPlainMessageFromString(text))
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to sign Release file")
+	}
+	hashName, err := getSignatureHashName(signature)
+	if err != nil {
+		return "", err
+	}
+	armoredSignature, err := armor.ArmorWithTypeAndCustomHeaders(signature.GetBinary(), constants.PGPSignatureHeader, "", "")
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to armor Release signature")
+	}
+
+	var inRelease strings.Builder
+	inRelease.WriteString("-----BEGIN " + constants.PGPSignedMessageHeader + "-----\n")
+	inRelease.WriteString("Hash: " + hashName + "\n\n")
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "-") {
+			inRelease.WriteString("- ")
+		}
+		inRelease.WriteString(line + "\n")
+	}
+	inRelease.WriteString(armoredSignature + "\n")
+	return inRelease.String(), nil
+}
+
+// SignDebianReleaseDetached returns the armored detached signature of a
+// Debian repository Release file, i.e. the content of the Release.gpg file.
+func SignDebianReleaseDetached(keyRing *crypto.KeyRing, release []byte) (string, error) {
+	signature, err := keyRing.SignDetached(crypto.NewPlainMessage(release))
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to sign Release file")
+	}
+	return armor.ArmorWithTypeAndCustomHeaders(signature.GetBinary(), constants.PGPSignatureHeader, "", "")
+}
+
+// GetRPMHeader returns the header of an RPM package, i.e. the input of the
+// header-only OpenPGP signatures of the package, see SignRPMHeader.
+func GetRPMHeader(rpm []byte) ([]byte, error) {
+	if len(rpm) < rpmLeadSize || !bytes.Equal(rpm[:len(rpmLeadMagic)], rpmLeadMagic) {
+		return nil, errors.New("gopenpgp: not an RPM package")
+	}
+
+	// The signature header is padded to a multiple of 8 bytes
+	signatureHeaderSize, err := getRPMHeaderSize(rpm[rpmLeadSize:])
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid RPM signature header")
+	}
+	headerOffset := rpmLeadSize + (signatureHeaderSize+7)/8*8
+	if headerOffset > len(rpm) {
+		return nil, errors.New("gopenpgp: truncated RPM package")
+	}
+
+	headerSize, err := getRPMHeaderSize(rpm[headerOffset:])
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid RPM header")
+	}
+	return rpm[headerOffset : headerOffset+headerSize], nil
+}
+
+// SignRPMHeader returns the binary detached signature of the header of an
+// RPM package, to be stored in the RPMSIGTAG_RSA or RPMSIGTAG_DSA tag of its
+// signature header, depending on the algorithm of the signing key.
+func SignRPMHeader(keyRing *crypto.KeyRing, rpm []byte) ([]byte, error) {
+	header, err := GetRPMHeader(rpm)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := keyRing.SignDetached(crypto.NewPlainMessage(header))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to sign RPM header")
+	}
+	return signature.GetBinary(), nil
+}
+
+// getRPMHeaderSize returns the size of the RPM header structure starting
+// data: its intro, index entries and data store.
+func getRPMHeaderSize(data []byte) (int, error) {
+	if len(data) < rpmHeaderIntro || !bytes.Equal(data[:len(rpmHeaderMagic)], rpmHeaderMagic) {
+		return 0, errors.New("gopenpgp: missing RPM header magic")
+	}
+	indexCount := uint64(binary.BigEndian.Uint32(data[8:12]))
+	dataSize := uint64(binary.BigEndian.Uint32(data[12:16]))
+	size := rpmHeaderIntro + indexCount*rpmIndexEntrySize + dataSize
+	if size > uint64(len(data)) {
+		return 0, errors.New("gopenpgp: truncated RPM header")
+	}
+	return int(size), nil
+}
+
+// getSignatureHashName returns the name of the hash algorithm of the
+// signature, as written in the Hash header of cleartext signed messages.
+func getSignatureHashName(signature *crypto.PGPSignature) (string, error) {
+	p, err := packet.Read(bytes.NewReader(signature.GetBinary()))
+	if err != nil {
+		return "", errors.Wrap(err, "gopenpgp: unable to parse signature")
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return "", errors.New("gopenpgp: unable to parse signature")
+	}
+	// e.g. "SHA-512" is written "SHA512"
+	return strings.ReplaceAll(sig.Hash.String(), "-", ""), nil
+}
@@ -0,0 +1,118 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRelease = `Origin: Example
+Label: Example
+Suite: stable
+Codename: example
+Components: main
+Architectures: amd64
+SHA256:
+ e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855        0 main/binary-amd64/Packages
+-Trailing: whitespace   
+`
+
+func newTestSigningKeyRing(t *testing.T) *crypto.KeyRing {
+	key, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	unlockedKey, err := key.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	return keyRing
+}
+
+func TestSignDebianRelease(t *testing.T) {
+	keyRing := newTestSigningKeyRing(t)
+
+	inRelease, err := SignDebianInRelease(keyRing, []byte(testRelease))
+	if err != nil {
+		t.Fatal("Expected no error while signing InRelease, got:", err)
+	}
+	assert.True(t, strings.HasPrefix(inRelease, "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA512\n\nOrigin: Example\n"))
+	assert.Contains(t, inRelease, "\n- -Trailing: whitespace\n-----BEGIN PGP SIGNATURE-----\n")
+
+	clearTextMessage, err := crypto.NewClearTextMessageFromArmored(inRelease)
+	if err != nil {
+		t.Fatal("Expected no error while reading InRelease, got:", err)
+	}
+	assert.Exactly(
+		t,
+		strings.TrimSuffix(strings.ReplaceAll(testRelease, "   \n", "\n"), "\n"),
+		strings.ReplaceAll(clearTextMessage.GetString(), "\r\n", "\n"),
+	)
+	_, err = VerifyCleartextMessage(keyRing, inRelease, testTime)
+	if err != nil {
+		t.Fatal("Expected no error while verifying InRelease, got:", err)
+	}
+
+	releaseSignature, err := SignDebianReleaseDetached(keyRing, []byte(testRelease))
+	if err != nil {
+		t.Fatal("Expected no error while signing Release, got:", err)
+	}
+	assert.True(t, strings.HasPrefix(releaseSignature, "-----BEGIN PGP SIGNATURE-----\n\n"))
+	signature, err := crypto.NewPGPSignatureFromArmored(releaseSignature)
+	if err != nil {
+		t.Fatal("Expected no error while reading Release.gpg, got:", err)
+	}
+	if err := keyRing.VerifyDetached(crypto.NewPlainMessage([]byte(testRelease)), signature, testTime); err != nil {
+		t.Fatal("Expected no error while verifying Release.gpg, got:", err)
+	}
+}
+
+func TestSignRPMHeader(t *testing.T) {
+	keyRing := newTestSigningKeyRing(t)
+
+	// Header with one index entry and a 5 bytes data store
+	header := make([]byte, 16+16+5)
+	copy(header, []byte{0x8e, 0xad, 0xe8, 0x01})
+	binary.BigEndian.PutUint32(header[8:], 1)
+	binary.BigEndian.PutUint32(header[12:], 5)
+	copy(header[32:], "data\x00")
+
+	// Lead, signature header with a 1 byte data store padded to 8 bytes,
+	// header and payload
+	var rpm bytes.Buffer
+	rpm.Write([]byte{0xed, 0xab, 0xee, 0xdb})
+	rpm.Write(make([]byte, 92))
+	rpm.Write([]byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+	rpm.Write(make([]byte, 8))
+	rpm.Write(header)
+	rpm.WriteString("payload")
+	data := rpm.Bytes()
+
+	extracted, err := GetRPMHeader(data)
+	if err != nil {
+		t.Fatal("Expected no error while reading RPM header, got:", err)
+	}
+	assert.Exactly(t, header, extracted)
+
+	_, err = GetRPMHeader(data[:96+24+16])
+	assert.Error(t, err)
+
+	rpmSignature, err := SignRPMHeader(keyRing, data)
+	if err != nil {
+		t.Fatal("Expected no error while signing RPM header, got:", err)
+	}
+	if err := keyRing.VerifyDetached(crypto.NewPlainMessage(header), crypto.NewPGPSignature(rpmSignature), testTime); err != nil {
+		t.Fatal("Expected no error while verifying RPM header signature, got:", err)
+	}
+
+	_, err = GetRPMHeader([]byte("not an rpm"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,136 @@
+//go:build !ios && !android
+// +build !ios,!android
+
+package helper
+
+import (
+	"context"
+	"io"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// EncryptStream encrypts the data read from src to publicKey, signs it with
+// privateKey if not nil, and writes the message to dst. metadata may be nil.
+// The message is only finalized if all of src was encrypted: on error, dst
+// holds a truncated message which must be discarded.
+// Reading src stops with the error of ctx once it is done. If src is an
+// io.Closer, it is closed once ctx is done, to interrupt a pending Read. If
+// src is an io.PipeReader, it is closed with the error, to unblock the
+// writing end.
+// To produce the message on an io.Pipe:
+//
+//	go func() {
+//		pipeWriter.CloseWithError(helper.EncryptStream(ctx, pipeWriter, src, publicKey, nil, nil))
+//	}()
+func EncryptStream(
+	ctx context.Context,
+	dst io.Writer,
+	src io.Reader,
+	publicKey, privateKey *crypto.KeyRing,
+	metadata *crypto.PlainMessageMetadata,
+) (err error) {
+	defer func() {
+		closeWithError(src, err)
+	}()
+
+	reader, stop := newContextReader(ctx, src)
+	defer stop()
+	plainMessageWriter, err := publicKey.EncryptStream(dst, metadata, privateKey)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(plainMessageWriter, reader); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to encrypt stream")
+	}
+	if err = plainMessageWriter.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to finalize encrypted stream")
+	}
+	return nil
+}
+
+// DecryptStream decrypts the message read from src with privateKey, writes
+// the plaintext to dst, and returns its metadata. If verifyKey is not nil,
+// the embedded signature is verified with it at verifyTime once the message
+// has been read, and a crypto.SignatureVerificationError is returned along
+// with the metadata if it is not valid.
+// As the plaintext is written to dst before the end of the message is
+// authenticated, dst must discard it on error.
+// Reading src stops with the error of ctx once it is done. If src is an
+// io.Closer, it is closed once ctx is done, to interrupt a pending Read. If
+// src is an io.PipeReader, it is closed with the error, to unblock the
+// writing end.
+func DecryptStream(
+	ctx context.Context,
+	dst io.Writer,
+	src io.Reader,
+	privateKey, verifyKey *crypto.KeyRing,
+	verifyTime int64,
+) (metadata *crypto.PlainMessageMetadata, err error) {
+	defer func() {
+		closeWithError(src, err)
+	}()
+
+	reader, stop := newContextReader(ctx, src)
+	defer stop()
+	plainMessageReader, err := privateKey.DecryptStream(reader, verifyKey, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(dst, plainMessageReader); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to decrypt stream")
+	}
+	if verifyKey != nil {
+		err = plainMessageReader.VerifySignature()
+	}
+	return plainMessageReader.GetMetadata(), err
+}
+
+// contextReader stops reading once its context is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// newContextReader returns a reader of src which stops reading once ctx is
+// done. A Read blocked in src can only be interrupted by closing src, so
+// src is closed on cancellation if it is an io.Closer. The returned function
+// stops watching ctx, and must be called once src isn't read anymore.
+func newContextReader(ctx context.Context, src io.Reader) (*contextReader, func()) {
+	reader := &contextReader{ctx: ctx, reader: src}
+	stop := make(chan struct{})
+	if _, ok := src.(io.Closer); ok && ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				if pipeReader, ok := src.(*io.PipeReader); ok {
+					_ = pipeReader.CloseWithError(ctx.Err())
+				} else {
+					_ = src.(io.Closer).Close()
+				}
+			case <-stop:
+			}
+		}()
+	}
+	return reader, func() { close(stop) }
+}
+
+func (r *contextReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.reader.Read(b)
+	if err != nil && r.ctx.Err() != nil {
+		// The error comes from closing the reader on cancellation
+		return n, r.ctx.Err()
+	}
+	return n, err
+}
+
+// closeWithError closes src with err if it is an io.PipeReader.
+func closeWithError(src io.Reader, err error) {
+	if pipeReader, ok := src.(*io.PipeReader); ok && err != nil {
+		_ = pipeReader.CloseWithError(err)
+	}
+}
@@ -0,0 +1,85 @@
+//go:build !ios && !android
+// +build !ios,!android
+
+package helper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecryptStream(t *testing.T) {
+	key, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	unlockedKey, err := key.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	plaintext := strings.Repeat("Hello streams!\n", 10000)
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		metadata := crypto.NewPlainMessageMetadata(true, "hello.txt", testTime)
+		_ = pipeWriter.CloseWithError(EncryptStream(context.Background(), pipeWriter, strings.NewReader(plaintext), keyRing, keyRing, metadata))
+	}()
+
+	var decrypted bytes.Buffer
+	metadata, err := DecryptStream(context.Background(), &decrypted, pipeReader, keyRing, keyRing, testTime)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting stream, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted.String())
+	assert.Exactly(t, "hello.txt", metadata.Filename)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var ciphertext bytes.Buffer
+	err = EncryptStream(ctx, &ciphertext, strings.NewReader(plaintext), keyRing, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDecryptStreamCancelBlockedRead(t *testing.T) {
+	key, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	unlockedKey, err := key.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(unlockedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	// The writing end never writes, so the read only returns once cancelled
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeWriter.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := DecryptStream(ctx, ioutil.Discard, pipeReader, keyRing, nil, 0)
+		result <- err
+	}()
+	cancel()
+	select {
+	case err = <-result:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the blocked read to be interrupted")
+	}
+}
@@ -0,0 +1,128 @@
+//go:build !ios && !android
+// +build !ios,!android
+
+package helper
+
+import (
+	"context"
+	"io"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// TranscryptOptions are the signature options of NewTranscryptingReader.
+type TranscryptOptions struct {
+	// VerifyKey verifies the embedded signature of the source message at
+	// VerifyTime, if not nil. The re-encrypted message is not finalized if
+	// the signature is invalid, and reading it fails with a
+	// crypto.SignatureVerificationError.
+	VerifyKey  *crypto.KeyRing
+	VerifyTime int64
+	// SignKey signs the re-encrypted message, if not nil.
+	SignKey *crypto.KeyRing
+}
+
+// TranscryptingReader reads a message re-encrypted on the fly, see
+// NewTranscryptingReader.
+type TranscryptingReader struct {
+	pipeReader *io.PipeReader
+	done       chan struct{}
+	signature  *crypto.PGPSignature
+}
+
+// NewTranscryptingReader returns a reader of the message read from src,
+// decrypted with privateKey and re-encrypted to publicKey, e.g. to migrate
+// stored messages to new keys. The message is processed in constant memory,
+// as it is read, and its metadata is kept.
+// As with EncryptStream, the re-encrypted message is only finalized if all
+// of src was decrypted: on error, the data read so far must be discarded.
+// options may be nil: the original signature is then neither verified nor
+// kept, and the new message is not signed. When verified, the original
+// signature is still valid as a detached signature of the message, see
+// GetOriginalSignature.
+// Reading src stops with the error of ctx once it is done, and src is
+// closed if it is an io.Closer, see EncryptStream. Close must be called if
+// the reader is not read entirely.
+func NewTranscryptingReader(
+	ctx context.Context,
+	src io.Reader,
+	privateKey, publicKey *crypto.KeyRing,
+	options *TranscryptOptions,
+) *TranscryptingReader {
+	if options == nil {
+		options = &TranscryptOptions{}
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	reader := &TranscryptingReader{
+		pipeReader: pipeReader,
+		done:       make(chan struct{}),
+	}
+	go func() {
+		err := reader.transcrypt(ctx, pipeWriter, src, privateKey, publicKey, options)
+		close(reader.done)
+		_ = pipeWriter.CloseWithError(err)
+	}()
+	return reader
+}
+
+// Read reads the re-encrypted message.
+func (reader *TranscryptingReader) Read(b []byte) (int, error) {
+	return reader.pipeReader.Read(b)
+}
+
+// Close stops the re-encryption.
+func (reader *TranscryptingReader) Close() error {
+	return reader.pipeReader.Close()
+}
+
+// GetOriginalSignature returns the verified embedded signature of the
+// source message, as a detached signature of the message, once the
+// re-encrypted message has been read entirely. It returns nil if no
+// VerifyKey was given.
+func (reader *TranscryptingReader) GetOriginalSignature() (*crypto.PGPSignature, error) {
+	select {
+	case <-reader.done:
+		return reader.signature, nil
+	default:
+		return nil, errors.New("gopenpgp: can't get the signature until the message has been read entirely")
+	}
+}
+
+func (reader *TranscryptingReader) transcrypt(
+	ctx context.Context,
+	dst io.Writer,
+	src io.Reader,
+	privateKey, publicKey *crypto.KeyRing,
+	options *TranscryptOptions,
+) (err error) {
+	defer func() {
+		closeWithError(src, err)
+	}()
+
+	contextReader, stop := newContextReader(ctx, src)
+	defer stop()
+	plainMessageReader, err := privateKey.DecryptStream(contextReader, options.VerifyKey, options.VerifyTime)
+	if err != nil {
+		return err
+	}
+	plainMessageWriter, err := publicKey.EncryptStream(dst, plainMessageReader.GetMetadata(), options.SignKey)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(plainMessageWriter, plainMessageReader); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to transcrypt stream")
+	}
+	if options.VerifyKey != nil {
+		if err = plainMessageReader.VerifySignature(); err != nil {
+			return err
+		}
+		if reader.signature, err = plainMessageReader.GetSignature(); err != nil {
+			return err
+		}
+	}
+	if err = plainMessageWriter.Close(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to finalize transcrypted stream")
+	}
+	return nil
+}
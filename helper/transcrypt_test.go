@@ -0,0 +1,88 @@
+//go:build !ios && !android
+// +build !ios,!android
+
+package helper
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranscryptingReader(t *testing.T) {
+	oldKey, err := crypto.NewKeyFromArmored(readTestFile("keyring_privateKey", false))
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	oldKey, err = oldKey.Unlock(testMailboxPassword)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	oldKeyRing, err := crypto.NewKeyRing(oldKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	newKey, err := crypto.GenerateKey("new", "new@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	newKeyRing, err := crypto.NewKeyRing(newKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	plaintext := strings.Repeat("Hello transcryption!\n", 10000)
+	var ciphertext bytes.Buffer
+	metadata := crypto.NewPlainMessageMetadata(true, "mail.eml", testTime)
+	if err := EncryptStream(context.Background(), &ciphertext, strings.NewReader(plaintext), oldKeyRing, oldKeyRing, metadata); err != nil {
+		t.Fatal("Expected no error while encrypting stream, got:", err)
+	}
+
+	reader := NewTranscryptingReader(
+		context.Background(),
+		bytes.NewReader(ciphertext.Bytes()),
+		oldKeyRing,
+		newKeyRing,
+		&TranscryptOptions{VerifyKey: oldKeyRing, VerifyTime: testTime, SignKey: newKeyRing},
+	)
+	_, err = reader.GetOriginalSignature()
+	assert.Error(t, err)
+	transcrypted, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while transcrypting, got:", err)
+	}
+
+	var decrypted bytes.Buffer
+	decryptedMetadata, err := DecryptStream(context.Background(), &decrypted, bytes.NewReader(transcrypted), newKeyRing, newKeyRing, testTime)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting transcrypted stream, got:", err)
+	}
+	assert.Exactly(t, plaintext, decrypted.String())
+	assert.Exactly(t, metadata, decryptedMetadata)
+
+	// The original signature is kept as a detached signature
+	signature, err := reader.GetOriginalSignature()
+	if err != nil {
+		t.Fatal("Expected no error while getting original signature, got:", err)
+	}
+	if err := oldKeyRing.VerifyDetached(crypto.NewPlainMessage([]byte(plaintext)), signature, testTime); err != nil {
+		t.Fatal("Expected no error while verifying original signature, got:", err)
+	}
+
+	// The message is not finalized if the original signature is invalid
+	reader = NewTranscryptingReader(
+		context.Background(),
+		bytes.NewReader(ciphertext.Bytes()),
+		oldKeyRing,
+		newKeyRing,
+		&TranscryptOptions{VerifyKey: newKeyRing, VerifyTime: testTime},
+	)
+	_, err = ioutil.ReadAll(reader)
+	assert.Error(t, err)
+	assert.IsType(t, crypto.SignatureVerificationError{}, err)
+}
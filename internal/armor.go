@@ -1,18 +1,220 @@
 package internal
 
 import (
+	"bufio"
+	"io"
 	"strings"
 
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
 	"github.com/pkg/errors"
 )
 
+// ErrArmorLimitExceeded is returned when an armored input exceeds the limits
+// given to UnarmorWithLimits.
+var ErrArmorLimitExceeded = errors.New("gopenpgp: armor limit exceeded")
+
+// ErrArmorInvalid is returned when an input is not a valid armored block.
+var ErrArmorInvalid = errors.New("gopenpgp: invalid armor")
+
+// ArmorTypes lists the known armor types.
+var ArmorTypes = []string{
+	constants.PublicKeyHeader,
+	constants.PrivateKeyHeader,
+	constants.PGPMessageHeader,
+	constants.PGPSignatureHeader,
+	constants.PGPSignedMessageHeader,
+}
+
+// ArmorTypeError is returned when an armored block is not of the expected
+// type, e.g. a private key is given where a message is expected.
+type ArmorTypeError struct {
+	// Expected are the armor types accepted by the operation.
+	Expected []string
+	// Actual is the armor type of the input.
+	Actual string
+}
+
+// Error is the base method for all errors.
+func (e ArmorTypeError) Error() string {
+	return "gopenpgp: expected armored " + strings.Join(e.Expected, " or ") + ", got " + e.Actual
+}
+
+// CheckArmorType returns an ArmorTypeError if armorType is not one of the
+// expected types.
+func CheckArmorType(armorType string, expected ...string) error {
+	for _, expectedType := range expected {
+		if armorType == expectedType {
+			return nil
+		}
+	}
+	return ArmorTypeError{Expected: expected, Actual: armorType}
+}
+
+// GetArmorType returns the type of the first armored block of the input,
+// including cleartext signed messages, or an empty string if the input
+// contains no armor header line.
+func GetArmorType(input string) string {
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Buffer(nil, len(input)+1)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "-----BEGIN ") && strings.HasSuffix(line, "-----") && len(line) > 16 {
+			return line[len("-----BEGIN ") : len(line)-len("-----")]
+		}
+	}
+	return ""
+}
+
 // Unarmor unarmors an armored string.
 func Unarmor(input string) (*armor.Block, error) {
 	io := strings.NewReader(input)
 	b, err := armor.Decode(io)
 	if err != nil {
-		return nil, errors.Wrap(err, "gopenpgp: unable to unarmor")
+		return nil, newArmorError(err)
 	}
+	b.Body = &armorBodyReader{reader: b.Body}
 	return b, nil
 }
+
+// UnarmorWithLimits unarmors the armored block read from input, failing with
+// ErrArmorLimitExceeded once more than maxInputSize bytes have been read from
+// input, a line is longer than maxLineLength, or the body decodes to more
+// than maxDecodedSize bytes. A limit of 0 disables the check.
+func UnarmorWithLimits(input io.Reader, maxInputSize, maxLineLength, maxDecodedSize int64) (*armor.Block, error) {
+	limitedInput := &limitedInputReader{
+		reader:        input,
+		maxSize:       maxInputSize,
+		maxLineLength: maxLineLength,
+	}
+	b, err := armor.Decode(limitedInput)
+	if err != nil {
+		return nil, newArmorError(limitedInput.checkLineLength(err))
+	}
+	if maxDecodedSize > 0 {
+		b.Body = &limitedDecodedReader{reader: b.Body, remaining: maxDecodedSize}
+	}
+	b.Body = &armorBodyReader{reader: b.Body, input: limitedInput}
+	return b, nil
+}
+
+// limitedInputReader enforces the size and line length limits on the
+// armored input.
+type limitedInputReader struct {
+	reader        io.Reader
+	size          int64
+	maxSize       int64
+	lineLength    int64
+	maxLineLength int64
+}
+
+func (r *limitedInputReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.size += int64(n)
+	if r.maxSize > 0 && r.size > r.maxSize {
+		return 0, errors.Wrap(ErrArmorLimitExceeded, "gopenpgp: armored input too large")
+	}
+	if r.maxLineLength > 0 {
+		for _, c := range b[:n] {
+			if c == '\n' {
+				r.lineLength = 0
+				continue
+			}
+			r.lineLength++
+			if r.lineLength > r.maxLineLength {
+				return 0, errors.Wrap(ErrArmorLimitExceeded, "gopenpgp: armored line too long")
+			}
+		}
+	}
+	return n, err
+}
+
+// checkLineLength returns an ErrArmorLimitExceeded error instead of err if
+// the line being read exceeds the line length limit. The armor decoder
+// rejects the lines longer than its buffer before the limit is reached:
+// the rest of the line is read, up to the limit, to tell them apart.
+func (r *limitedInputReader) checkLineLength(err error) error {
+	if r.maxLineLength <= 0 || errors.Is(err, ErrArmorLimitExceeded) || r.lineLength == 0 {
+		return err
+	}
+	buf := make([]byte, 1)
+	for {
+		if _, readErr := r.Read(buf); readErr != nil {
+			if errors.Is(readErr, ErrArmorLimitExceeded) {
+				return readErr
+			}
+			return err
+		}
+		if buf[0] == '\n' {
+			return err
+		}
+	}
+}
+
+// limitedDecodedReader enforces the limit on the size of the decoded body.
+type limitedDecodedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *limitedDecodedReader) Read(b []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, errors.Wrap(ErrArmorLimitExceeded, "gopenpgp: armored data too large")
+	}
+	// Read one more byte than allowed, to detect the overflow
+	if int64(len(b)) > r.remaining+1 {
+		b = b[:r.remaining+1]
+	}
+	n, err := r.reader.Read(b)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return 0, errors.Wrap(ErrArmorLimitExceeded, "gopenpgp: armored data too large")
+	}
+	return n, err
+}
+
+// armorBodyReader classifies the errors found while decoding the body of an
+// armored block, e.g. a bad checksum.
+type armorBodyReader struct {
+	reader io.Reader
+	// input is the armored input, if read with limits.
+	input *limitedInputReader
+}
+
+func (r *armorBodyReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if err != nil && !errors.Is(err, io.EOF) {
+		if r.input != nil {
+			err = r.input.checkLineLength(err)
+		}
+		err = newArmorError(err)
+	}
+	return n, err
+}
+
+// armorError is an ErrArmorInvalid error, which keeps the error returned when
+// decoding the armored block.
+type armorError struct {
+	cause error
+}
+
+func (e *armorError) Error() string {
+	return "gopenpgp: unable to unarmor: " + e.cause.Error()
+}
+
+// Is reports whether target is ErrArmorInvalid.
+func (e *armorError) Is(target error) bool {
+	return target == ErrArmorInvalid
+}
+
+func (e *armorError) Unwrap() error {
+	return e.cause
+}
+
+// newArmorError classifies an error returned when decoding an armored block.
+func newArmorError(err error) error {
+	if errors.Is(err, ErrArmorLimitExceeded) {
+		return errors.Wrap(err, "gopenpgp: unable to unarmor")
+	}
+	return &armorError{cause: err}
+}
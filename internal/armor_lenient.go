@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/gopenpgp/v2/constants"
+	"github.com/pkg/errors"
+)
+
+const (
+	crc24Init = 0xb704ce
+	crc24Poly = 0x1864cfb
+)
+
+// UnarmorLenient unarmors the first armored block of input, tolerating the
+// alterations of armored data copied through chat applications or mail
+// clients: a missing or incorrect checksum, a missing end line, a missing
+// blank line after the headers, whitespace around the lines and mixed line
+// endings. Each alteration found is reported with one of the
+// constants.ArmorWarning* warnings. The headers and the base64 body must
+// still be well-formed.
+func UnarmorLenient(input string) (*armor.Block, []string, error) {
+	lines, mixedLineEndings := splitLines(input)
+	warnings := newWarnings()
+	if mixedLineEndings {
+		warnings.add(constants.ArmorWarningLineEndings)
+	}
+
+	// Skip the leading garbage
+	i := 0
+	var begin string
+	for ; i < len(lines); i++ {
+		begin = strings.TrimSpace(lines[i])
+		if strings.HasPrefix(begin, "-----BEGIN ") && strings.HasSuffix(begin, "-----") && len(begin) > 16 {
+			break
+		}
+	}
+	if i == len(lines) {
+		return nil, nil, errors.Wrap(ErrArmorInvalid, "gopenpgp: unable to unarmor: no armored block found")
+	}
+	if begin != lines[i] {
+		warnings.add(constants.ArmorWarningWhitespace)
+	}
+	block := &armor.Block{
+		Type:   begin[len("-----BEGIN ") : len(begin)-len("-----")],
+		Header: make(map[string]string),
+	}
+	i++
+
+	// Headers, up to the blank line, or the first line without a colon as
+	// base64 has none
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line != lines[i] {
+			warnings.add(constants.ArmorWarningWhitespace)
+		}
+		if line == "" {
+			i++
+			break
+		}
+		colon := strings.Index(line, ":")
+		if colon == -1 {
+			warnings.add(constants.ArmorWarningMissingBlank)
+			break
+		}
+		block.Header[line[:colon]] = strings.TrimSpace(line[colon+1:])
+	}
+
+	// Body, up to the checksum or the end line
+	var body strings.Builder
+	var checksum string
+	end := false
+	for ; i < len(lines) && !end; i++ {
+		line := strings.TrimSpace(lines[i])
+		if line != lines[i] || line == "" {
+			warnings.add(constants.ArmorWarningWhitespace)
+		}
+		switch {
+		case strings.HasPrefix(line, "-----END "):
+			end = true
+		case strings.HasPrefix(line, "="):
+			checksum = line
+		case checksum != "":
+			return nil, nil, errors.Wrap(ErrArmorInvalid, "gopenpgp: unable to unarmor: data after the checksum")
+		default:
+			fields := strings.Join(strings.Fields(line), "")
+			if fields != line {
+				warnings.add(constants.ArmorWarningWhitespace)
+			}
+			body.WriteString(fields)
+		}
+	}
+	if !end {
+		warnings.add(constants.ArmorWarningMissingEnd)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrArmorInvalid, "gopenpgp: unable to unarmor: "+err.Error())
+	}
+	switch {
+	case checksum == "":
+		warnings.add(constants.ArmorWarningMissingChecksum)
+	case !checkCRC24(data, checksum[1:]):
+		warnings.add(constants.ArmorWarningInvalidChecksum)
+	}
+	block.Body = bytes.NewReader(data)
+	return block, warnings.list, nil
+}
+
+// warnings is a list of warnings without duplicates.
+type warnings struct {
+	list []string
+	seen map[string]bool
+}
+
+func newWarnings() *warnings {
+	return &warnings{seen: make(map[string]bool)}
+}
+
+func (w *warnings) add(warning string) {
+	if !w.seen[warning] {
+		w.seen[warning] = true
+		w.list = append(w.list, warning)
+	}
+}
+
+// splitLines splits input on "\r\n", "\n" and "\r", and reports whether
+// different line endings are used.
+func splitLines(input string) (lines []string, mixed bool) {
+	var lineEnding string
+	start := 0
+	for i := 0; i < len(input); i++ {
+		var current string
+		switch {
+		case input[i] == '\r' && i+1 < len(input) && input[i+1] == '\n':
+			current = "\r\n"
+		case input[i] == '\r', input[i] == '\n':
+			current = input[i : i+1]
+		default:
+			continue
+		}
+		if lineEnding != "" && current != lineEnding {
+			mixed = true
+		}
+		lineEnding = current
+		lines = append(lines, input[start:i])
+		i += len(current) - 1
+		start = i + 1
+	}
+	if start < len(input) {
+		lines = append(lines, input[start:])
+	}
+	return lines, mixed
+}
+
+// checkCRC24 checks the base64 encoded CRC-24 checksum of data.
+func checkCRC24(data []byte, checksum string) bool {
+	expected, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(expected) != 3 {
+		return false
+	}
+	crc := uint32(crc24Init)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc&0xffffff == uint32(expected[0])<<16|uint32(expected[1])<<8|uint32(expected[2])
+}
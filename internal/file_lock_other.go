@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!windows
+
+package internal
+
+import "os"
+
+// LockFile does not lock on this platform: writers of the same process or
+// of other processes are not serialized.
+func LockFile(_ *os.File) error {
+	return nil
+}
+
+// UnlockFile releases the lock taken by LockFile.
+func UnlockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"sync"
+)
+
+const (
+	// lockedSlotSize is the size of the slots of the locked arena, large
+	// enough for the session keys of all supported ciphers.
+	lockedSlotSize = 64
+	// lockedPageSize is the size of the memory regions locked at once.
+	lockedPageSize = 4096
+)
+
+// LockedBuffer holds secret data in memory that is, where supported, locked
+// into RAM so that it is never written to swap, and that is zeroized when
+// the buffer is destroyed.
+// The locked memory is a slot of a shared arena, which is never unmapped:
+// the slot is zeroized and returned to the arena by Destroy only. The slice
+// returned by Bytes must not be used after that, as the slot may be handed
+// out again. As the garbage collector doesn't track the arena, a slot is not
+// recycled when the buffer becomes unreachable: it stays allocated until
+// Destroy is called, since the slice may still be in use.
+type LockedBuffer struct {
+	data   []byte
+	slot   []byte
+	locked bool
+}
+
+// NewLockedBuffer allocates a zeroed buffer of the given size.
+// If the memory can't be locked, e.g. because the process exceeds its
+// locked memory limit, the platform doesn't support it or the buffer is
+// larger than a slot of the arena, the buffer is allocated on the heap and
+// IsLocked returns false.
+func NewLockedBuffer(size int) *LockedBuffer {
+	if size > 0 && size <= lockedSlotSize {
+		if slot := arena.get(); slot != nil {
+			return &LockedBuffer{data: slot[:size:size], slot: slot, locked: true}
+		}
+	}
+	return &LockedBuffer{data: make([]byte, size)}
+}
+
+// Bytes returns the content of the buffer.
+func (b *LockedBuffer) Bytes() []byte {
+	return b.data
+}
+
+// IsLocked reports whether the buffer is locked into RAM.
+func (b *LockedBuffer) IsLocked() bool {
+	return b.locked
+}
+
+// Destroy zeroizes the buffer and returns its memory to the arena.
+// It is safe to call Destroy more than once.
+func (b *LockedBuffer) Destroy() {
+	if b.data == nil {
+		return
+	}
+	Wipe(b.data)
+	if b.slot != nil {
+		arena.put(b.slot)
+	}
+	b.data = nil
+	b.slot = nil
+	b.locked = false
+}
+
+// Wipe overwrites data with zeros.
+func Wipe(data []byte) {
+	for i := range data {
+		data[i] = 0x00
+	}
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// lockedArena hands out fixed-size slots of locked memory. The memory is
+// locked one page at a time, when no slot is free, and is kept for the
+// lifetime of the process, so that the locked memory used is bounded by
+// the peak number of live buffers.
+type lockedArena struct {
+	lock sync.Mutex
+	free [][]byte
+}
+
+var arena lockedArena
+
+// get returns a free slot, or nil if no memory could be locked.
+func (a *lockedArena) get() []byte {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.free) == 0 {
+		page, err := allocLocked(lockedPageSize)
+		if err != nil {
+			return nil
+		}
+		for offset := 0; offset+lockedSlotSize <= len(page); offset += lockedSlotSize {
+			a.free = append(a.free, page[offset:offset+lockedSlotSize:offset+lockedSlotSize])
+		}
+	}
+	slot := a.free[len(a.free)-1]
+	a.free = a.free[:len(a.free)-1]
+	return slot
+}
+
+// put zeroizes the slot and makes it available again.
+func (a *lockedArena) put(slot []byte) {
+	Wipe(slot)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.free = append(a.free, slot)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package internal
+
+import "errors"
+
+func allocLocked(_ int) ([]byte, error) {
+	return nil, errors.New("gopenpgp: locked memory is not supported on this platform")
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package internal
+
+import "syscall"
+
+// allocLocked maps anonymous memory outside of the Go heap, so that it is
+// neither moved nor copied by the runtime, and locks it into RAM.
+func allocLocked(size int) ([]byte, error) {
+	data, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Mlock(data); err != nil {
+		_ = syscall.Munmap(data)
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,20 @@
+// Package interop contains an optional test suite checking that messages,
+// signatures and keys round-trip between gopenpgp and other OpenPGP
+// implementations, and that gopenpgp decrypts interoperability test vectors.
+//
+// The suite is behind the interop build tag, and uses the implementations
+// found on the PATH (gpg, sq), or given by environment variable:
+//
+//	go test -tags interop ./interop -v
+//
+//	GPG=/usr/local/bin/gpg      path of the gpg binary
+//	SQ=/usr/local/bin/sq        path of the sq binary
+//	SOP=/usr/local/bin/sqop     path of a Stateless OpenPGP (SOP) binary
+//	OPENPGP_TEST_VECTORS=dir    additional test vectors, one directory per
+//	                            vector with key.asc, message.pgp or
+//	                            message.asc, plaintext and optionally
+//	                            passphrase
+//
+// Missing implementations are skipped. At the end of the run, the suite
+// prints a compatibility matrix of the operations per implementation.
+package interop
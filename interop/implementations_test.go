@@ -0,0 +1,279 @@
+//go:build interop
+// +build interop
+
+package interop
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// implementation drives an external OpenPGP implementation through its
+// command line. Keys, certificates and signatures are exchanged armored.
+type implementation interface {
+	name() string
+	generateKey(userID string) (key []byte, err error)
+	extractCert(key []byte) (cert []byte, err error)
+	encrypt(cert, plaintext []byte) (ciphertext []byte, err error)
+	decrypt(key, ciphertext []byte) (plaintext []byte, err error)
+	sign(key, data []byte) (signature []byte, err error)
+	verify(cert, data, signature []byte) error
+}
+
+// findImplementations returns the implementations available on this system.
+func findImplementations() []implementation {
+	var implementations []implementation
+	if path := findBinary("GPG", "gpg"); path != "" {
+		implementations = append(implementations, &gpg{path: path})
+	}
+	if path := findBinary("SQ", "sq"); path != "" {
+		implementations = append(implementations, &sq{path: path})
+	}
+	if path := findBinary("SOP", ""); path != "" {
+		implementations = append(implementations, &sop{path: path})
+	}
+	return implementations
+}
+
+func findBinary(variable, name string) string {
+	if path := os.Getenv(variable); path != "" {
+		return path
+	}
+	if name == "" {
+		return ""
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// run runs the binary with the given arguments and standard input, and
+// returns its standard output.
+func run(stdin []byte, path string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...) //nolint:gosec
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrap(err, filepath.Base(path)+" failed: "+stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// workDir holds the temporary files of an operation.
+type workDir struct {
+	path string
+}
+
+func newWorkDir() (*workDir, error) {
+	// Short paths, as gpg-agent sockets are limited to ~100 characters
+	path, err := ioutil.TempDir("", "interop")
+	if err != nil {
+		return nil, err
+	}
+	return &workDir{path: path}, nil
+}
+
+func (dir *workDir) write(name string, data []byte) (string, error) {
+	path := filepath.Join(dir.path, name)
+	return path, ioutil.WriteFile(path, data, 0600)
+}
+
+func (dir *workDir) remove() {
+	_ = os.RemoveAll(dir.path)
+}
+
+// ------ GnuPG -------
+
+type gpg struct {
+	path string
+}
+
+func (impl *gpg) name() string {
+	return "gpg"
+}
+
+// withHome runs f with a fresh GnuPG home directory, in which keys have been
+// imported.
+func (impl *gpg) withHome(keys [][]byte, f func(dir *workDir, args []string) ([]byte, error)) ([]byte, error) {
+	dir, err := newWorkDir()
+	if err != nil {
+		return nil, err
+	}
+	defer dir.remove()
+	defer func() {
+		_, _ = run(nil, "gpgconf", "--homedir", dir.path, "--kill", "gpg-agent")
+	}()
+
+	args := []string{"--homedir", dir.path, "--batch", "--yes", "--pinentry-mode", "loopback", "--passphrase", ""}
+	for _, key := range keys {
+		if _, err = run(key, impl.path, append(args, "--import")...); err != nil {
+			return nil, err
+		}
+	}
+	return f(dir, args)
+}
+
+func (impl *gpg) generateKey(userID string) ([]byte, error) {
+	return impl.withHome(nil, func(dir *workDir, args []string) ([]byte, error) {
+		if _, err := run(nil, impl.path, append(args, "--quick-gen-key", userID, "future-default", "default", "never")...); err != nil {
+			return nil, err
+		}
+		return run(nil, impl.path, append(args, "--armor", "--export-secret-keys", userID)...)
+	})
+}
+
+func (impl *gpg) extractCert(key []byte) ([]byte, error) {
+	return impl.withHome([][]byte{key}, func(dir *workDir, args []string) ([]byte, error) {
+		return run(nil, impl.path, append(args, "--armor", "--export")...)
+	})
+}
+
+func (impl *gpg) encrypt(cert, plaintext []byte) ([]byte, error) {
+	return impl.withHome(nil, func(dir *workDir, args []string) ([]byte, error) {
+		certPath, err := dir.write("cert.asc", cert)
+		if err != nil {
+			return nil, err
+		}
+		return run(plaintext, impl.path, append(args, "--armor", "--trust-model", "always", "--recipient-file", certPath, "--encrypt")...)
+	})
+}
+
+func (impl *gpg) decrypt(key, ciphertext []byte) ([]byte, error) {
+	return impl.withHome([][]byte{key}, func(dir *workDir, args []string) ([]byte, error) {
+		return run(ciphertext, impl.path, append(args, "--decrypt")...)
+	})
+}
+
+func (impl *gpg) sign(key, data []byte) ([]byte, error) {
+	return impl.withHome([][]byte{key}, func(dir *workDir, args []string) ([]byte, error) {
+		return run(data, impl.path, append(args, "--armor", "--detach-sign")...)
+	})
+}
+
+func (impl *gpg) verify(cert, data, signature []byte) error {
+	_, err := impl.withHome([][]byte{cert}, func(dir *workDir, args []string) ([]byte, error) {
+		signaturePath, err := dir.write("data.sig", signature)
+		if err != nil {
+			return nil, err
+		}
+		dataPath, err := dir.write("data", data)
+		if err != nil {
+			return nil, err
+		}
+		return run(nil, impl.path, append(args, "--verify", signaturePath, dataPath)...)
+	})
+	return err
+}
+
+// ------ Sequoia -------
+
+type sq struct {
+	path string
+}
+
+func (impl *sq) name() string {
+	return "sq"
+}
+
+// withFiles writes the files in a temporary directory, and runs f with
+// their paths.
+func withFiles(files [][]byte, f func(paths []string) ([]byte, error)) ([]byte, error) {
+	dir, err := newWorkDir()
+	if err != nil {
+		return nil, err
+	}
+	defer dir.remove()
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		if paths[i], err = dir.write("file"+string(rune('0'+i)), file); err != nil {
+			return nil, err
+		}
+	}
+	return f(paths)
+}
+
+func (impl *sq) generateKey(userID string) ([]byte, error) {
+	return run(nil, impl.path, "key", "generate", "--userid", userID, "--export", "-")
+}
+
+func (impl *sq) extractCert(key []byte) ([]byte, error) {
+	return run(key, impl.path, "key", "extract-cert")
+}
+
+func (impl *sq) encrypt(cert, plaintext []byte) ([]byte, error) {
+	return withFiles([][]byte{cert}, func(paths []string) ([]byte, error) {
+		return run(plaintext, impl.path, "encrypt", "--recipient-file", paths[0])
+	})
+}
+
+func (impl *sq) decrypt(key, ciphertext []byte) ([]byte, error) {
+	return withFiles([][]byte{key}, func(paths []string) ([]byte, error) {
+		return run(ciphertext, impl.path, "decrypt", "--recipient-file", paths[0])
+	})
+}
+
+func (impl *sq) sign(key, data []byte) ([]byte, error) {
+	return withFiles([][]byte{key}, func(paths []string) ([]byte, error) {
+		return run(data, impl.path, "sign", "--detached", "--signer-file", paths[0])
+	})
+}
+
+func (impl *sq) verify(cert, data, signature []byte) error {
+	_, err := withFiles([][]byte{cert, signature}, func(paths []string) ([]byte, error) {
+		return run(data, impl.path, "verify", "--signer-file", paths[0], "--detached", paths[1])
+	})
+	return err
+}
+
+// ------ Stateless OpenPGP -------
+
+type sop struct {
+	path string
+}
+
+func (impl *sop) name() string {
+	return "sop:" + filepath.Base(impl.path)
+}
+
+func (impl *sop) generateKey(userID string) ([]byte, error) {
+	return run(nil, impl.path, "generate-key", userID)
+}
+
+func (impl *sop) extractCert(key []byte) ([]byte, error) {
+	return run(key, impl.path, "extract-cert")
+}
+
+func (impl *sop) encrypt(cert, plaintext []byte) ([]byte, error) {
+	return withFiles([][]byte{cert}, func(paths []string) ([]byte, error) {
+		return run(plaintext, impl.path, "encrypt", paths[0])
+	})
+}
+
+func (impl *sop) decrypt(key, ciphertext []byte) ([]byte, error) {
+	return withFiles([][]byte{key}, func(paths []string) ([]byte, error) {
+		return run(ciphertext, impl.path, "decrypt", paths[0])
+	})
+}
+
+func (impl *sop) sign(key, data []byte) ([]byte, error) {
+	return withFiles([][]byte{key}, func(paths []string) ([]byte, error) {
+		return run(data, impl.path, "sign", paths[0])
+	})
+}
+
+func (impl *sop) verify(cert, data, signature []byte) error {
+	_, err := withFiles([][]byte{signature, cert}, func(paths []string) ([]byte, error) {
+		return run(data, impl.path, "verify", paths[0], paths[1])
+	})
+	return err
+}
@@ -0,0 +1,382 @@
+//go:build interop
+// +build interop
+
+package interop
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+const testPlaintext = "Hello interoperability!\n"
+
+var testKeyTypes = []struct {
+	keyType string
+	bits    int
+}{
+	{"x25519", 0},
+	{"rsa", 3072},
+}
+
+// testVector is a message produced by another implementation, that gopenpgp
+// must decrypt to plaintext.
+type testVector struct {
+	name       string
+	keyPath    string
+	passphrase string
+	message    string
+	plaintext  string
+}
+
+var bundledTestVectors = []testVector{
+	{
+		name:       "gpg2.3-aead-ocb",
+		keyPath:    "../crypto/testdata/gpg2.3-aead-test-key.asc",
+		passphrase: "test",
+		message:    "../crypto/testdata/gpg2.3-aead-pgp-message.pgp",
+		plaintext:  "hello world\n",
+	},
+}
+
+// compatibility records the outcome of the operations per implementation.
+var compatibility = struct {
+	sync.Mutex
+	results    map[string]map[string]string
+	operations []string
+}{results: map[string]map[string]string{}}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	printCompatibility()
+	os.Exit(code)
+}
+
+func TestRoundTrips(t *testing.T) {
+	implementations := findImplementations()
+	if len(implementations) == 0 {
+		t.Skip("no OpenPGP implementation found")
+	}
+
+	for _, testKeyType := range testKeyTypes {
+		key, err := crypto.GenerateKey("Interop", "interop@example.org", testKeyType.keyType, testKeyType.bits)
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		armoredKey, err := key.Armor()
+		if err != nil {
+			t.Fatal("Expected no error while armoring key, got:", err)
+		}
+		armoredCert, err := key.GetArmoredPublicKey()
+		if err != nil {
+			t.Fatal("Expected no error while armoring certificate, got:", err)
+		}
+		keyRing, err := crypto.NewKeyRing(key)
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+
+		for _, impl := range implementations {
+			impl := impl
+			suffix := " (" + testKeyType.keyType + ")"
+			t.Run(impl.name()+"/"+testKeyType.keyType, func(t *testing.T) {
+				check(t, impl, "encrypt"+suffix, func() error {
+					ciphertext, err := encryptArmored(keyRing, testPlaintext)
+					if err != nil {
+						return err
+					}
+					plaintext, err := impl.decrypt([]byte(armoredKey), []byte(ciphertext))
+					if err != nil {
+						return err
+					}
+					return expectPlaintext(string(plaintext))
+				})
+				check(t, impl, "decrypt"+suffix, func() error {
+					ciphertext, err := impl.encrypt([]byte(armoredCert), []byte(testPlaintext))
+					if err != nil {
+						return err
+					}
+					return decryptArmored(keyRing, ciphertext)
+				})
+				check(t, impl, "sign"+suffix, func() error {
+					signature, err := keyRing.SignDetached(crypto.NewPlainMessageFromString(testPlaintext))
+					if err != nil {
+						return err
+					}
+					armoredSignature, err := signature.GetArmored()
+					if err != nil {
+						return err
+					}
+					return impl.verify([]byte(armoredCert), []byte(testPlaintext), []byte(armoredSignature))
+				})
+				check(t, impl, "verify"+suffix, func() error {
+					signature, err := impl.sign([]byte(armoredKey), []byte(testPlaintext))
+					if err != nil {
+						return err
+					}
+					return verifyArmored(keyRing, signature)
+				})
+			})
+		}
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	implementations := findImplementations()
+	if len(implementations) == 0 {
+		t.Skip("no OpenPGP implementation found")
+	}
+
+	for _, impl := range implementations {
+		impl := impl
+		t.Run(impl.name(), func(t *testing.T) {
+			armoredKey, err := impl.generateKey("Foreign <foreign@example.org>")
+			if err != nil {
+				check(t, impl, "parse key", func() error { return err })
+				return
+			}
+			var key *crypto.Key
+			check(t, impl, "parse key", func() error {
+				key, err = crypto.NewKeyFromArmored(string(armoredKey))
+				return err
+			})
+			if key == nil {
+				return
+			}
+			check(t, impl, "parse cert", func() error {
+				cert, err := impl.extractCert(armoredKey)
+				if err != nil {
+					return err
+				}
+				publicKey, err := crypto.NewKeyFromArmored(string(cert))
+				if err != nil {
+					return err
+				}
+				if publicKey.GetFingerprint() != key.GetFingerprint() {
+					return errors.New("fingerprint mismatch")
+				}
+				return nil
+			})
+			check(t, impl, "encrypt to key", func() error {
+				keyRing, err := crypto.NewKeyRing(key)
+				if err != nil {
+					return err
+				}
+				ciphertext, err := encryptArmored(keyRing, testPlaintext)
+				if err != nil {
+					return err
+				}
+				plaintext, err := impl.decrypt(armoredKey, []byte(ciphertext))
+				if err != nil {
+					return err
+				}
+				return expectPlaintext(string(plaintext))
+			})
+			check(t, impl, "verify with key", func() error {
+				keyRing, err := crypto.NewKeyRing(key)
+				if err != nil {
+					return err
+				}
+				signature, err := impl.sign(armoredKey, []byte(testPlaintext))
+				if err != nil {
+					return err
+				}
+				return verifyArmored(keyRing, signature)
+			})
+		})
+	}
+}
+
+func TestVectors(t *testing.T) {
+	vectors := append([]testVector{}, bundledTestVectors...)
+	if dir := os.Getenv("OPENPGP_TEST_VECTORS"); dir != "" {
+		found, err := readTestVectors(dir)
+		if err != nil {
+			t.Fatal("Expected no error while reading test vectors, got:", err)
+		}
+		vectors = append(vectors, found...)
+	}
+
+	for _, vector := range vectors {
+		vector := vector
+		t.Run(vector.name, func(t *testing.T) {
+			checkVector(t, vector, func() error {
+				armoredKey, err := ioutil.ReadFile(vector.keyPath)
+				if err != nil {
+					return err
+				}
+				key, err := crypto.NewKeyFromArmored(string(armoredKey))
+				if err != nil {
+					return err
+				}
+				if vector.passphrase != "" {
+					if key, err = key.Unlock([]byte(vector.passphrase)); err != nil {
+						return err
+					}
+				}
+				keyRing, err := crypto.NewKeyRing(key)
+				if err != nil {
+					return err
+				}
+				message, err := ioutil.ReadFile(vector.message)
+				if err != nil {
+					return err
+				}
+				var pgpMessage *crypto.PGPMessage
+				if strings.HasSuffix(vector.message, ".asc") {
+					if pgpMessage, err = crypto.NewPGPMessageFromArmored(string(message)); err != nil {
+						return err
+					}
+				} else {
+					pgpMessage = crypto.NewPGPMessage(message)
+				}
+				decrypted, err := keyRing.Decrypt(pgpMessage, nil, 0)
+				if err != nil {
+					return err
+				}
+				if decrypted.GetString() != vector.plaintext {
+					return errors.New("unexpected plaintext")
+				}
+				return nil
+			})
+		})
+	}
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+func check(t *testing.T, impl implementation, operation string, f func() error) {
+	err := f()
+	record(impl.name(), operation, err)
+	if err != nil {
+		t.Errorf("%s with %s failed: %v", operation, impl.name(), err)
+	}
+}
+
+func checkVector(t *testing.T, vector testVector, f func() error) {
+	err := f()
+	record("test vectors", vector.name, err)
+	if err != nil {
+		t.Errorf("test vector %s failed: %v", vector.name, err)
+	}
+}
+
+func record(row, operation string, err error) {
+	compatibility.Lock()
+	defer compatibility.Unlock()
+
+	if compatibility.results[row] == nil {
+		compatibility.results[row] = map[string]string{}
+	}
+	result := "ok"
+	if err != nil {
+		result = "FAIL"
+	}
+	compatibility.results[row][operation] = result
+	for _, known := range compatibility.operations {
+		if known == operation {
+			return
+		}
+	}
+	compatibility.operations = append(compatibility.operations, operation)
+}
+
+func printCompatibility() {
+	compatibility.Lock()
+	defer compatibility.Unlock()
+
+	if len(compatibility.results) == 0 {
+		return
+	}
+	rows := make([]string, 0, len(compatibility.results))
+	for row := range compatibility.results {
+		rows = append(rows, row)
+	}
+	sort.Strings(rows)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "\nCompatibility matrix")
+	for _, row := range rows {
+		for _, operation := range compatibility.operations {
+			if result, ok := compatibility.results[row][operation]; ok {
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", row, operation, result)
+			}
+		}
+	}
+	_ = writer.Flush()
+}
+
+// readTestVectors reads the vectors of dir, one per subdirectory.
+func readTestVectors(dir string) ([]testVector, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var vectors []testVector
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		vector := testVector{name: entry.Name(), keyPath: filepath.Join(path, "key.asc")}
+		vector.message = filepath.Join(path, "message.pgp")
+		if _, err = os.Stat(vector.message); err != nil {
+			vector.message = filepath.Join(path, "message.asc")
+		}
+		plaintext, err := ioutil.ReadFile(filepath.Join(path, "plaintext"))
+		if err != nil {
+			return nil, err
+		}
+		vector.plaintext = string(plaintext)
+		if passphrase, err := ioutil.ReadFile(filepath.Join(path, "passphrase")); err == nil {
+			vector.passphrase = strings.TrimRight(string(passphrase), "\n")
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func encryptArmored(keyRing *crypto.KeyRing, plaintext string) (string, error) {
+	ciphertext, err := keyRing.Encrypt(crypto.NewPlainMessage([]byte(plaintext)), nil)
+	if err != nil {
+		return "", err
+	}
+	return ciphertext.GetArmored()
+}
+
+func decryptArmored(keyRing *crypto.KeyRing, ciphertext []byte) error {
+	message, err := crypto.NewPGPMessageFromArmored(string(ciphertext))
+	if err != nil {
+		return err
+	}
+	decrypted, err := keyRing.Decrypt(message, nil, 0)
+	if err != nil {
+		return err
+	}
+	return expectPlaintext(string(decrypted.GetBinary()))
+}
+
+func verifyArmored(keyRing *crypto.KeyRing, armoredSignature []byte) error {
+	signature, err := crypto.NewPGPSignatureFromArmored(string(bytes.TrimSpace(armoredSignature)))
+	if err != nil {
+		return err
+	}
+	return keyRing.VerifyDetached(crypto.NewPlainMessage([]byte(testPlaintext)), signature, crypto.GetUnixTime())
+}
+
+func expectPlaintext(plaintext string) error {
+	if plaintext != testPlaintext {
+		return errors.New("unexpected plaintext " + plaintext)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Package keyrefresh periodically refreshes certificates from key resolvers,
+// to learn about new subkeys, expiration changes and revocations.
+package keyrefresh
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/ProtonMail/gopenpgp/v2/keyserver"
+	"github.com/pkg/errors"
+)
+
+// Callback receives the outcome of the refreshes.
+// Its methods are called from the goroutine performing the refresh.
+type Callback interface {
+	// OnKeyUpdated is called when a refresh found new information about a
+	// key. key is the merged certificate, diff describes what changed;
+	// new revocations are listed in diff.Revocations.
+	OnKeyUpdated(key *crypto.Key, diff *crypto.KeyDiff)
+	// OnRefreshError is called when a key could not be refreshed.
+	OnRefreshError(fingerprint string, err error)
+}
+
+// Refresher keeps a set of certificates up to date with key resolvers,
+// e.g. keyservers or DANE records.
+// Started in the background, it refreshes one key at a time at random
+// intervals, like parcimonie, so that the refreshes of the keys can't
+// easily be linked together by an observer of the network.
+// A Refresher is safe for concurrent use.
+type Refresher struct {
+	lock      sync.Mutex
+	keys      []*crypto.Key
+	resolvers []keyserver.KeyResolver
+	callback  Callback
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewRefresher creates a refresher for the certificates of keyRing. Only the
+// public part of the keys is kept and refreshed.
+// * callback : (optional) notified of the changes and errors.
+func NewRefresher(keyRing *crypto.KeyRing, callback Callback) (*Refresher, error) {
+	refresher := &Refresher{callback: callback}
+	for _, key := range keyRing.GetKeys() {
+		if key.IsPrivate() {
+			publicKey, err := key.ToPublic()
+			if err != nil {
+				return nil, err
+			}
+			key = publicKey
+		}
+		refresher.keys = append(refresher.keys, key)
+	}
+	return refresher, nil
+}
+
+// AddResolver adds a resolver to query. Resolvers implementing
+// keyserver.FingerprintResolver are queried by fingerprint, the other ones
+// by the email addresses of the key.
+func (refresher *Refresher) AddResolver(resolver keyserver.KeyResolver) {
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	refresher.resolvers = append(refresher.resolvers, resolver)
+}
+
+// GetKeyRing returns a keyring with the current version of the certificates.
+func (refresher *Refresher) GetKeyRing() (*crypto.KeyRing, error) {
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	keyRing, err := crypto.NewKeyRing(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range refresher.keys {
+		if err = keyRing.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return keyRing, nil
+}
+
+// RefreshKey refreshes the certificate with the given hex fingerprint.
+// Returns true if new information was found, in which case the callback's
+// OnKeyUpdated was called.
+func (refresher *Refresher) RefreshKey(fingerprint string) (bool, error) {
+	refresher.lock.Lock()
+	var key *crypto.Key
+	for _, candidate := range refresher.keys {
+		if strings.EqualFold(candidate.GetFingerprint(), fingerprint) {
+			key = candidate
+		}
+	}
+	refresher.lock.Unlock()
+	if key == nil {
+		return false, errors.New("gopenpgp: no key with fingerprint " + fingerprint)
+	}
+	return refresher.refresh(key)
+}
+
+// RefreshAll refreshes all the certificates once, one after the other.
+// The errors are reported to the callback, and the last one is returned.
+func (refresher *Refresher) RefreshAll() error {
+	var lastErr error
+	for _, fingerprint := range refresher.getFingerprints() {
+		if _, err := refresher.RefreshKey(fingerprint); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Start refreshes the certificates in the background until Stop is called.
+// Each certificate is refreshed on average once per interval, one random
+// certificate at a time, after a random delay.
+// * intervalSeconds : the average interval between two refreshes of the same key.
+func (refresher *Refresher) Start(intervalSeconds int64) error {
+	if intervalSeconds <= 0 {
+		return errors.New("gopenpgp: the refresh interval must be positive")
+	}
+
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+	if refresher.stop != nil {
+		return errors.New("gopenpgp: the refresher is already started")
+	}
+	refresher.stop = make(chan struct{})
+	refresher.done = make(chan struct{})
+
+	go refresher.run(time.Duration(intervalSeconds)*time.Second, refresher.stop, refresher.done)
+	return nil
+}
+
+// Stop stops the background refresh started by Start, and waits for the
+// refresh in progress, if any, to finish.
+func (refresher *Refresher) Stop() {
+	refresher.lock.Lock()
+	stop, done := refresher.stop, refresher.done
+	refresher.stop, refresher.done = nil, nil
+	refresher.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+func (refresher *Refresher) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	for {
+		fingerprints := refresher.getFingerprints()
+		count := int64(len(fingerprints))
+		if count == 0 {
+			count = 1
+		}
+		// Uniform delay with an average of interval / count
+		delay := randomInt64(2 * int64(interval) / count)
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Duration(delay)):
+		}
+		if len(fingerprints) > 0 {
+			_, _ = refresher.RefreshKey(fingerprints[randomInt64(int64(len(fingerprints)))])
+		}
+	}
+}
+
+func (refresher *Refresher) getFingerprints() []string {
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	fingerprints := make([]string, len(refresher.keys))
+	for i, key := range refresher.keys {
+		fingerprints[i] = key.GetFingerprint()
+	}
+	return fingerprints
+}
+
+func (refresher *Refresher) getResolvers() []keyserver.KeyResolver {
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	return append([]keyserver.KeyResolver(nil), refresher.resolvers...)
+}
+
+// refresh fetches the updates of key from all resolvers, and merges them.
+func (refresher *Refresher) refresh(key *crypto.Key) (bool, error) {
+	fingerprint := key.GetFingerprint()
+	merged := key
+	var lastErr error
+	for _, resolver := range refresher.getResolvers() {
+		updates, err := fetchUpdates(resolver, key)
+		if err != nil {
+			lastErr = err
+		}
+		for _, update := range updates {
+			updated, err := merged.Merge(update)
+			if err != nil {
+				// Keep the updates merged so far
+				lastErr = err
+				continue
+			}
+			merged = updated
+		}
+	}
+
+	var diff *crypto.KeyDiff
+	if merged != key {
+		var err error
+		if diff, err = key.Diff(merged); err != nil {
+			lastErr = err
+			diff = nil
+		}
+	}
+	if diff != nil && !diff.IsEmpty() {
+		refresher.replaceKey(merged)
+		if refresher.callback != nil {
+			refresher.callback.OnKeyUpdated(merged, diff)
+		}
+	}
+
+	if lastErr != nil {
+		lastErr = errors.Wrap(lastErr, "gopenpgp: unable to refresh key "+fingerprint)
+		if refresher.callback != nil {
+			refresher.callback.OnRefreshError(fingerprint, lastErr)
+		}
+	}
+	return diff != nil && !diff.IsEmpty(), lastErr
+}
+
+func (refresher *Refresher) replaceKey(key *crypto.Key) {
+	refresher.lock.Lock()
+	defer refresher.lock.Unlock()
+
+	for i, candidate := range refresher.keys {
+		if candidate.GetFingerprint() == key.GetFingerprint() {
+			refresher.keys[i] = key
+		}
+	}
+}
+
+// fetchUpdates returns the versions of key known to resolver, found by
+// fingerprint if the resolver supports it, and by email otherwise.
+func fetchUpdates(resolver keyserver.KeyResolver, key *crypto.Key) ([]*crypto.Key, error) {
+	fingerprint := key.GetFingerprint()
+	if fingerprintResolver, ok := resolver.(keyserver.FingerprintResolver); ok {
+		update, err := fingerprintResolver.GetKeyByFingerprint(fingerprint)
+		if errors.Is(err, keyserver.ErrKeyNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []*crypto.Key{update}, nil
+	}
+
+	var updates []*crypto.Key
+	var lastErr error
+	for _, identity := range key.GetEntity().Identities {
+		if identity.UserId == nil || identity.UserId.Email == "" {
+			continue
+		}
+		keyRing, err := resolver.GetKeysByEmail(identity.UserId.Email)
+		if errors.Is(err, keyserver.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, candidate := range keyRing.GetKeys() {
+			if candidate.GetFingerprint() == fingerprint {
+				updates = append(updates, candidate)
+			}
+		}
+	}
+	return updates, lastErr
+}
+
+// randomInt64 returns a uniform random number in [0, max), or 0 if max is
+// not positive.
+func randomInt64(max int64) int64 {
+	if max <= 0 {
+		return 0
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(max))
+	if err != nil {
+		return max / 2
+	}
+	return n.Int64()
+}
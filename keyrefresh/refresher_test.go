@@ -0,0 +1,121 @@
+package keyrefresh
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/ProtonMail/gopenpgp/v2/keyserver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testResolver struct {
+	keys map[string]*crypto.Key
+	err  error
+}
+
+func (resolver *testResolver) GetKeysByEmail(email string) (*crypto.KeyRing, error) {
+	if resolver.err != nil {
+		return nil, resolver.err
+	}
+	key, ok := resolver.keys[email]
+	if !ok {
+		return nil, keyserver.ErrKeyNotFound
+	}
+	return crypto.NewKeyRing(key)
+}
+
+type testCallback struct {
+	updated []*crypto.KeyDiff
+	errors  []error
+}
+
+func (callback *testCallback) OnKeyUpdated(key *crypto.Key, diff *crypto.KeyDiff) {
+	callback.updated = append(callback.updated, diff)
+}
+
+func (callback *testCallback) OnRefreshError(fingerprint string, err error) {
+	callback.errors = append(callback.errors, err)
+}
+
+func TestRefresher(t *testing.T) {
+	privateKey, err := crypto.GenerateKey("Alice", "alice@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	publicKey, err := privateKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(publicKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+
+	callback := &testCallback{}
+	refresher, err := NewRefresher(keyRing, callback)
+	if err != nil {
+		t.Fatal("Expected no error while creating refresher, got:", err)
+	}
+	resolver := &testResolver{keys: map[string]*crypto.Key{}}
+	refresher.AddResolver(resolver)
+
+	// Nothing published
+	assert.NoError(t, refresher.RefreshAll())
+	assert.Empty(t, callback.updated)
+
+	// Same version published
+	resolver.keys["alice@example.org"] = publicKey
+	assert.NoError(t, refresher.RefreshAll())
+	assert.Empty(t, callback.updated)
+
+	// Revoked version published
+	entity := privateKey.GetEntity()
+	if err = entity.RevokeKey(packet.KeyCompromised, "", nil); err != nil {
+		t.Fatal("Expected no error while revoking key, got:", err)
+	}
+	revokedKey, err := privateKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	resolver.keys["alice@example.org"] = revokedKey
+
+	updated, err := refresher.RefreshKey(publicKey.GetFingerprint())
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	assert.Len(t, callback.updated, 1)
+	assert.Len(t, callback.updated[0].Revocations, 1)
+
+	refreshedKeyRing, err := refresher.GetKeyRing()
+	if err != nil {
+		t.Fatal("Expected no error while getting keyring, got:", err)
+	}
+	assert.True(t, refreshedKeyRing.GetKeys()[0].IsRevoked())
+
+	// No change since the last refresh
+	assert.NoError(t, refresher.RefreshAll())
+	assert.Len(t, callback.updated, 1)
+
+	// Errors are reported
+	resolver.err = errors.New("network error")
+	assert.Error(t, refresher.RefreshAll())
+	assert.Len(t, callback.errors, 1)
+
+	_, err = refresher.RefreshKey("0123456789abcdef")
+	assert.Error(t, err)
+}
+
+func TestRefresherStartStop(t *testing.T) {
+	refresher, err := NewRefresher(&crypto.KeyRing{}, nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating refresher, got:", err)
+	}
+	assert.Error(t, refresher.Start(0))
+	assert.NoError(t, refresher.Start(3600))
+	assert.Error(t, refresher.Start(3600))
+	refresher.Stop()
+	assert.NoError(t, refresher.Start(3600))
+	refresher.Stop()
+	refresher.Stop()
+}
@@ -0,0 +1,115 @@
+package keyserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// ErrNotAuthenticated is returned by a DANE resolver requiring DNSSEC when
+// the OPENPGPKEY records could not be authenticated.
+var ErrNotAuthenticated = errors.New("gopenpgp: OPENPGPKEY records are not authenticated by DNSSEC")
+
+// OPENPGPKEYLookup queries the OPENPGPKEY records of a DNS name.
+// It is the hook to plug a DNS client, and the DNSSEC validation it performs,
+// into a DANEResolver. See DNSLookup for the default implementation.
+type OPENPGPKEYLookup interface {
+	// LookupOPENPGPKEY returns the data of the OPENPGPKEY records of name,
+	// none if the name does not exist, and whether the answer was validated
+	// with DNSSEC.
+	LookupOPENPGPKEY(name string) (records [][]byte, authenticated bool, err error)
+}
+
+// DANEResolver discovers keys with the DNS-Based Authentication of Named
+// Entities (DANE) OPENPGPKEY records, as specified in RFC 7929.
+type DANEResolver struct {
+	lookup           OPENPGPKEYLookup
+	requireDNSSEC    bool
+	emailMatchPolicy *crypto.EmailMatchPolicy
+}
+
+// NewDANEResolver creates a resolver querying the records with lookup.
+// * requireDNSSEC : if true, records that were not validated with DNSSEC are
+// rejected with ErrNotAuthenticated. RFC 7929 requires DNSSEC validation,
+// unauthenticated keys must at least not be trusted more than keys from
+// a keyserver.
+func NewDANEResolver(lookup OPENPGPKEYLookup, requireDNSSEC bool) *DANEResolver {
+	return &DANEResolver{
+		lookup:        lookup,
+		requireDNSSEC: requireDNSSEC,
+	}
+}
+
+// SetEmailMatchPolicy sets how the user IDs of the published keys are
+// matched with the email address, see crypto.EmailMatchPolicy. By default,
+// the local parts are compared case-insensitively.
+func (resolver *DANEResolver) SetEmailMatchPolicy(policy *crypto.EmailMatchPolicy) {
+	resolver.emailMatchPolicy = policy
+}
+
+// GetKeysByEmail returns the keys published in the OPENPGPKEY records of
+// the email address. Only the keys with a user ID matching the address are
+// returned. It implements KeyResolver.
+func (resolver *DANEResolver) GetKeysByEmail(email string) (*crypto.KeyRing, error) {
+	name, err := GetOPENPGPKEYName(email)
+	if err != nil {
+		return nil, err
+	}
+	records, authenticated, err := resolver.lookup.LookupOPENPGPKEY(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to look up OPENPGPKEY records")
+	}
+	if len(records) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	if resolver.requireDNSSEC && !authenticated {
+		return nil, ErrNotAuthenticated
+	}
+
+	keyRing, err := crypto.NewKeyRing(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		entities, err := openpgp.ReadKeyRing(bytes.NewReader(record))
+		if err != nil {
+			// Skip invalid records, other records may contain a valid key
+			continue
+		}
+		for _, entity := range entities {
+			if entity.PrivateKey != nil {
+				continue
+			}
+			key, err := crypto.NewKeyFromEntity(entity)
+			if err != nil || !key.MatchesEmail(email, resolver.emailMatchPolicy) {
+				continue
+			}
+			if err = keyRing.AddKey(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if keyRing.CountEntities() == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return keyRing, nil
+}
+
+// GetOPENPGPKEYName returns the DNS name of the OPENPGPKEY records of an
+// email address: the hex SHA2-256 hash of the local part, truncated to 28
+// bytes, followed by "._openpgpkey." and the domain.
+// As specified in RFC 7929, the local part is hashed as is, without
+// lowercasing it.
+func GetOPENPGPKEYName(email string) (string, error) {
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 {
+		return "", errors.New("gopenpgp: invalid email address " + email)
+	}
+	hash := sha256.Sum256([]byte(email[:at]))
+	return hex.EncodeToString(hash[:28]) + "._openpgpkey." + email[at+1:], nil
+}
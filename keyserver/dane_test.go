@@ -0,0 +1,195 @@
+package keyserver
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOPENPGPKEYLookup struct {
+	records       map[string][][]byte
+	authenticated bool
+}
+
+func (lookup *testOPENPGPKEYLookup) LookupOPENPGPKEY(name string) ([][]byte, bool, error) {
+	return lookup.records[name], lookup.authenticated, nil
+}
+
+func TestGetOPENPGPKEYName(t *testing.T) {
+	// Example from RFC 7929, section 3
+	name, err := GetOPENPGPKEYName("hugh@example.com")
+	if err != nil {
+		t.Fatal("Expected no error while computing name, got:", err)
+	}
+	assert.Exactly(t, "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._openpgpkey.example.com", name)
+
+	_, err = GetOPENPGPKEYName("example.com")
+	assert.Error(t, err)
+}
+
+func TestDANEResolver(t *testing.T) {
+	key, err := crypto.GenerateKey("Alice", testAddr, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	otherKey, err := crypto.GenerateKey("Bob", "bob@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	publicKey, err := key.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	otherPublicKey, err := otherKey.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+
+	name, _ := GetOPENPGPKEYName(testAddr)
+	lookup := &testOPENPGPKEYLookup{
+		records: map[string][][]byte{name: {[]byte("invalid"), otherPublicKey, publicKey}},
+	}
+
+	keyRing, err := NewDANEResolver(lookup, false).GetKeysByEmail(testAddr)
+	if err != nil {
+		t.Fatal("Expected no error while resolving key, got:", err)
+	}
+	assert.Exactly(t, 1, keyRing.CountEntities())
+	assert.Exactly(t, key.GetFingerprint(), keyRing.GetKeys()[0].GetFingerprint())
+
+	_, err = NewDANEResolver(lookup, true).GetKeysByEmail(testAddr)
+	assert.Exactly(t, ErrNotAuthenticated, err)
+
+	lookup.authenticated = true
+	_, err = NewDANEResolver(lookup, true).GetKeysByEmail(testAddr)
+	assert.NoError(t, err)
+
+	_, err = NewDANEResolver(lookup, true).GetKeysByEmail("bob@example.org")
+	assert.Exactly(t, ErrKeyNotFound, err)
+
+	var _ KeyResolver = NewDANEResolver(lookup, true)
+	var _ KeyResolver = NewVKSClient(DefaultVKSServer)
+}
+
+// serveTestDNS answers a single query with a truncated response over UDP,
+// then with the records over TCP, on the same port.
+func serveTestDNS(t *testing.T, records [][]byte) string {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Expected no error while listening, got:", err)
+	}
+	udpConn, err := net.ListenPacket("udp", tcpListener.Addr().String())
+	if err != nil {
+		t.Fatal("Expected no error while listening, got:", err)
+	}
+
+	respond := func(query []byte, truncated bool) []byte {
+		// Copy the header and question, skipping the OPT record
+		questionEnd, _ := skipDNSName(query, 12)
+		response := append([]byte(nil), query[:questionEnd+4]...)
+		flags := uint16(dnsFlagResponse | dnsFlagRecursion | dnsFlagAuthenticated)
+		if truncated {
+			flags |= dnsFlagTruncated
+		}
+		binary.BigEndian.PutUint16(response[2:], flags)
+		binary.BigEndian.PutUint16(response[10:], 0)
+		if truncated {
+			return response
+		}
+		binary.BigEndian.PutUint16(response[6:], uint16(len(records)))
+		for _, record := range records {
+			response = append(response, 0xc0, 12) // pointer to the question name
+			response = appendUint16(response, dnsTypeOPENPGPKEY)
+			response = appendUint16(response, dnsClassIN)
+			response = append(response, 0, 0, 0, 60)
+			response = appendUint16(response, uint16(len(record)))
+			response = append(response, record...)
+		}
+		return response
+	}
+
+	go func() {
+		defer udpConn.Close()
+		buf := make([]byte, 512)
+		n, addr, err := udpConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		_, _ = udpConn.WriteTo(respond(buf[:n], true), addr)
+	}()
+	go func() {
+		defer tcpListener.Close()
+		conn, err := tcpListener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var length [2]byte
+		if _, err = io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err = io.ReadFull(conn, query); err != nil {
+			return
+		}
+		response := respond(query, false)
+		_, _ = conn.Write(append(appendUint16(nil, uint16(len(response))), response...))
+	}()
+	return tcpListener.Addr().String()
+}
+
+func TestDNSLookup(t *testing.T) {
+	records := [][]byte{make([]byte, 1000), []byte("second record")}
+	server := serveTestDNS(t, records)
+
+	name, _ := GetOPENPGPKEYName(testAddr)
+	result, authenticated, err := NewDNSLookup(server, 5).LookupOPENPGPKEY(name)
+	if err != nil {
+		t.Fatal("Expected no error while looking up records, got:", err)
+	}
+	assert.True(t, authenticated)
+	assert.Exactly(t, records, result)
+}
+
+func TestDNSResponseDropsOtherNames(t *testing.T) {
+	name, _ := GetOPENPGPKEYName(testAddr)
+	query, id, err := newDNSQuery(name, dnsTypeOPENPGPKEY)
+	if err != nil {
+		t.Fatal("Expected no error while building query, got:", err)
+	}
+	questionEnd, _ := skipDNSName(query, 12)
+	response := append([]byte(nil), query[:questionEnd+4]...)
+	binary.BigEndian.PutUint16(response[2:], dnsFlagResponse|dnsFlagRecursion)
+	binary.BigEndian.PutUint16(response[6:], 5)
+	binary.BigEndian.PutUint16(response[10:], 0)
+
+	appendRecord := func(owner []byte, recordType uint16, data []byte) {
+		response = append(response, owner...)
+		response = appendUint16(response, recordType)
+		response = appendUint16(response, dnsClassIN)
+		response = append(response, 0, 0, 0, 60)
+		response = appendUint16(response, uint16(len(data)))
+		response = append(response, data...)
+	}
+	encodeName := func(name string) []byte {
+		nameQuery, _, _ := newDNSQuery(name, 0)
+		nameEnd, _ := skipDNSName(nameQuery, 12)
+		return nameQuery[12:nameEnd]
+	}
+	questionName := []byte{0xc0, 12}
+	appendRecord(questionName, dnsTypeOPENPGPKEY, []byte("matching"))
+	appendRecord(encodeName("other.example.org"), dnsTypeOPENPGPKEY, []byte("other"))
+	appendRecord(encodeName(name[:56]+"._openpgpkey.Example.ORG."), dnsTypeOPENPGPKEY, []byte("case"))
+	appendRecord(questionName, dnsTypeCNAME, encodeName("alias.example.org"))
+	appendRecord(encodeName("alias.example.org"), dnsTypeOPENPGPKEY, []byte("alias"))
+
+	records, _, err := parseDNSResponse(response, id, name, dnsTypeOPENPGPKEY)
+	if err != nil {
+		t.Fatal("Expected no error while parsing response, got:", err)
+	}
+	assert.Exactly(t, [][]byte{[]byte("matching"), []byte("case"), []byte("alias")}, records)
+}
@@ -0,0 +1,285 @@
+package keyserver
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dnsTypeCNAME      = 5
+	dnsTypeOPENPGPKEY = 61
+	dnsTypeOPT        = 41
+	dnsClassIN        = 1
+	// dnsUDPSize is the UDP payload size advertised with EDNS0, large
+	// enough for most OPENPGPKEY records.
+	dnsUDPSize = 4096
+	// Header flags.
+	dnsFlagResponse      = 1 << 15
+	dnsFlagTruncated     = 1 << 9
+	dnsFlagRecursion     = 1 << 8
+	dnsFlagAuthenticated = 1 << 5
+	// EDNS0 flag asking for DNSSEC records and validation.
+	dnsFlagDNSSECOK = 1 << 15
+	// Response codes.
+	dnsRcodeNameError = 3
+)
+
+// DNSLookup queries OPENPGPKEY records from a recursive DNS resolver, over
+// UDP, and over TCP when the response is truncated.
+// Its implementation of DNSSEC validation is to trust the Authenticated Data
+// flag set by the resolver: the resolver must validate DNSSEC, and the path
+// to it must be trusted (e.g. a resolver on localhost).
+type DNSLookup struct {
+	server  string
+	timeout time.Duration
+}
+
+// NewDNSLookup creates a lookup querying the resolver at the given address,
+// e.g. "127.0.0.1:53".
+// * timeoutSeconds : the timeout of each query, in seconds.
+func NewDNSLookup(server string, timeoutSeconds int64) *DNSLookup {
+	return &DNSLookup{
+		server:  server,
+		timeout: time.Duration(timeoutSeconds) * time.Second,
+	}
+}
+
+// LookupOPENPGPKEY returns the data of the OPENPGPKEY records of name, and
+// whether the resolver validated the answer with DNSSEC.
+// It implements OPENPGPKEYLookup.
+func (lookup *DNSLookup) LookupOPENPGPKEY(name string) (records [][]byte, authenticated bool, err error) {
+	query, id, err := newDNSQuery(name, dnsTypeOPENPGPKEY)
+	if err != nil {
+		return nil, false, err
+	}
+
+	response, err := lookup.exchange("udp", query)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(response) >= 4 && binary.BigEndian.Uint16(response[2:])&dnsFlagTruncated != 0 {
+		if response, err = lookup.exchange("tcp", query); err != nil {
+			return nil, false, err
+		}
+	}
+	return parseDNSResponse(response, id, name, dnsTypeOPENPGPKEY)
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+func (lookup *DNSLookup) exchange(network string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, lookup.server, lookup.timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to contact DNS resolver")
+	}
+	defer conn.Close()
+	if lookup.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(lookup.timeout))
+	}
+
+	if network == "tcp" {
+		message := make([]byte, 2+len(query))
+		binary.BigEndian.PutUint16(message, uint16(len(query)))
+		copy(message[2:], query)
+		if _, err = conn.Write(message); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to send DNS query")
+		}
+		var length [2]byte
+		if _, err = io.ReadFull(conn, length[:]); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to read DNS response")
+		}
+		response := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err = io.ReadFull(conn, response); err != nil {
+			return nil, errors.Wrap(err, "gopenpgp: unable to read DNS response")
+		}
+		return response, nil
+	}
+
+	if _, err = conn.Write(query); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to send DNS query")
+	}
+	response := make([]byte, dnsUDPSize)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read DNS response")
+	}
+	return response[:n], nil
+}
+
+// newDNSQuery builds a recursive query for the records of the given type,
+// asking for DNSSEC validation with an EDNS0 OPT record.
+// See RFC 1035, section 4.1, and RFC 6891.
+func newDNSQuery(name string, recordType uint16) (query []byte, id uint16, err error) {
+	var idBytes [2]byte
+	if _, err = rand.Read(idBytes[:]); err != nil {
+		return nil, 0, errors.Wrap(err, "gopenpgp: unable to generate DNS query ID")
+	}
+	id = binary.BigEndian.Uint16(idBytes[:])
+
+	query = make([]byte, 12)
+	binary.BigEndian.PutUint16(query[0:], id)
+	binary.BigEndian.PutUint16(query[2:], dnsFlagRecursion|dnsFlagAuthenticated)
+	binary.BigEndian.PutUint16(query[4:], 1)  // questions
+	binary.BigEndian.PutUint16(query[10:], 1) // additional records
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, 0, errors.New("gopenpgp: invalid DNS name " + name)
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0)
+	query = appendUint16(query, recordType)
+	query = appendUint16(query, dnsClassIN)
+
+	// OPT pseudo-record: root name, type, UDP size, extended flags, no data
+	query = append(query, 0)
+	query = appendUint16(query, dnsTypeOPT)
+	query = appendUint16(query, dnsUDPSize)
+	query = appendUint16(query, 0)
+	query = appendUint16(query, dnsFlagDNSSECOK)
+	query = appendUint16(query, 0)
+	return query, id, nil
+}
+
+// parseDNSResponse returns the data of the answer records of the given type
+// owned by name, or by the target of a CNAME record of name. Other answer
+// records are dropped.
+func parseDNSResponse(response []byte, id uint16, name string, recordType uint16) (records [][]byte, authenticated bool, err error) {
+	errMalformed := errors.New("gopenpgp: malformed DNS response")
+	if len(response) < 12 || binary.BigEndian.Uint16(response) != id {
+		return nil, false, errMalformed
+	}
+	flags := binary.BigEndian.Uint16(response[2:])
+	if flags&dnsFlagResponse == 0 {
+		return nil, false, errMalformed
+	}
+	authenticated = flags&dnsFlagAuthenticated != 0
+	switch rcode := flags & 0xf; rcode {
+	case 0:
+	case dnsRcodeNameError:
+		return nil, authenticated, nil
+	default:
+		return nil, false, errors.Errorf("gopenpgp: DNS query failed with code %d", rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(response[4:]))
+	answers := int(binary.BigEndian.Uint16(response[6:]))
+	offset := 12
+	for i := 0; i < questions; i++ {
+		if offset, err = skipDNSName(response, offset); err != nil {
+			return nil, false, err
+		}
+		offset += 4 // type and class
+	}
+	names := map[string]bool{normalizeDNSName(name): true}
+	for i := 0; i < answers; i++ {
+		var owner string
+		if owner, offset, err = readDNSName(response, offset); err != nil {
+			return nil, false, err
+		}
+		if offset+10 > len(response) {
+			return nil, false, errMalformed
+		}
+		answerType := binary.BigEndian.Uint16(response[offset:])
+		length := int(binary.BigEndian.Uint16(response[offset+8:]))
+		offset += 10
+		if offset+length > len(response) {
+			return nil, false, errMalformed
+		}
+		if names[normalizeDNSName(owner)] {
+			switch answerType {
+			case recordType:
+				records = append(records, response[offset:offset+length])
+			case dnsTypeCNAME:
+				target, _, err := readDNSName(response[:offset+length], offset)
+				if err != nil {
+					return nil, false, err
+				}
+				names[normalizeDNSName(target)] = true
+			}
+		}
+		offset += length
+	}
+	return records, authenticated, nil
+}
+
+// skipDNSName returns the offset following the possibly compressed name
+// starting at offset.
+func skipDNSName(message []byte, offset int) (int, error) {
+	for {
+		if offset >= len(message) {
+			return 0, errors.New("gopenpgp: malformed DNS name")
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xc0 == 0xc0:
+			return offset + 2, nil
+		default:
+			offset += 1 + length
+		}
+	}
+}
+
+// readDNSName returns the name starting at offset, following compression
+// pointers, and the offset following it.
+func readDNSName(message []byte, offset int) (name string, next int, err error) {
+	errMalformed := errors.New("gopenpgp: malformed DNS name")
+	var labels []string
+	next = -1
+	// Each pointer must point backwards, so that the name is finite
+	limit := offset
+	for {
+		if offset >= len(message) {
+			return "", 0, errMalformed
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xc0 == 0xc0:
+			if offset+2 > len(message) {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			pointer := int(binary.BigEndian.Uint16(message[offset:]) & 0x3fff)
+			if pointer >= limit {
+				return "", 0, errMalformed
+			}
+			offset, limit = pointer, pointer
+		case length&0xc0 != 0:
+			return "", 0, errMalformed
+		default:
+			if offset+1+length > len(message) {
+				return "", 0, errMalformed
+			}
+			label := string(message[offset+1 : offset+1+length])
+			labels = append(labels, strings.ReplaceAll(label, ".", `\.`))
+			offset += 1 + length
+		}
+	}
+}
+
+// normalizeDNSName returns name in lower case without the trailing dot, as
+// DNS names are compared case-insensitively.
+func normalizeDNSName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
@@ -0,0 +1,31 @@
+package keyserver
+
+import (
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+)
+
+// KeyResolver discovers the public keys of an email address.
+type KeyResolver interface {
+	// GetKeysByEmail returns the public keys published for the email address.
+	// Returns ErrKeyNotFound if there is none.
+	GetKeysByEmail(email string) (*crypto.KeyRing, error)
+}
+
+// FingerprintResolver is implemented by the resolvers that can also look
+// up a key by fingerprint, e.g. keyservers. This allows refreshing keys
+// without user IDs, or whose user IDs are not published.
+type FingerprintResolver interface {
+	// GetKeyByFingerprint returns the public key with the given hex fingerprint.
+	// Returns ErrKeyNotFound if there is none.
+	GetKeyByFingerprint(fingerprint string) (*crypto.Key, error)
+}
+
+// GetKeysByEmail returns the key published for the email address, in a
+// keyring. It implements KeyResolver.
+func (client *VKSClient) GetKeysByEmail(email string) (*crypto.KeyRing, error) {
+	key, err := client.GetKeyByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewKeyRing(key)
+}
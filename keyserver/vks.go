@@ -0,0 +1,286 @@
+// Package keyserver contains clients to look up and publish OpenPGP keys.
+package keyserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// DefaultVKSServer is the URL of the keys.openpgp.org keyserver.
+const DefaultVKSServer = "https://keys.openpgp.org"
+
+// maxVKSResponseSize limits the size of the responses read from the server.
+const maxVKSResponseSize = 1 << 20
+
+// ErrKeyNotFound is returned when the server has no key for the request.
+var ErrKeyNotFound = errors.New("gopenpgp: key not found")
+
+// ErrKeyMismatch is returned when the server answers with a key that doesn't
+// have the requested fingerprint or key ID.
+var ErrKeyMismatch = errors.New("gopenpgp: the keyserver returned another key than requested")
+
+// VKSAddressStatus is the publication state of an email address of an
+// uploaded key.
+type VKSAddressStatus string
+
+const (
+	// VKSAddressUnpublished means the address was not verified, and can be
+	// verified with VKSClient.RequestVerify.
+	VKSAddressUnpublished VKSAddressStatus = "unpublished"
+	// VKSAddressPending means a verification email was sent, and the user
+	// has yet to click the link it contains.
+	VKSAddressPending VKSAddressStatus = "pending"
+	// VKSAddressPublished means the address was verified, and the key can be
+	// looked up by email.
+	VKSAddressPublished VKSAddressStatus = "published"
+	// VKSAddressRevoked means the user ID of the address is revoked.
+	VKSAddressRevoked VKSAddressStatus = "revoked"
+)
+
+// VKSClient is a client for the Verifying Keyserver (VKS) API, used by
+// keys.openpgp.org. Unlike HKP, keys are only searchable by email once the
+// owner of the address has verified it. The verification flow is:
+//  1. Upload the key, which returns a token and the state of each address.
+//  2. RequestVerify the unpublished addresses, the server emails a link.
+//  3. Poll the state with GetStatus until the addresses are published.
+type VKSClient struct {
+	baseURL          string
+	httpClient       *http.Client
+	emailMatchPolicy *crypto.EmailMatchPolicy
+}
+
+// VKSUploadResult is the response of the server to an upload or a
+// verification request.
+type VKSUploadResult struct {
+	// Fingerprint is the fingerprint of the uploaded key.
+	Fingerprint string `json:"key_fpr"`
+	// Token identifies the upload in verification requests.
+	Token string `json:"token"`
+	// Status maps each email address of the key to its publication state.
+	Status map[string]VKSAddressStatus `json:"status"`
+}
+
+type vksErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type vksVerifyRequest struct {
+	Token     string   `json:"token"`
+	Addresses []string `json:"addresses"`
+	Locale    []string `json:"locale,omitempty"`
+}
+
+// NewVKSClient creates a client for the VKS server at baseURL, e.g.
+// DefaultVKSServer. It uses http.DefaultClient, see SetHTTPClient.
+func NewVKSClient(baseURL string) *VKSClient {
+	return &VKSClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+}
+
+// SetHTTPClient sets the HTTP client used to contact the server, e.g. to
+// configure timeouts or a proxy.
+func (client *VKSClient) SetHTTPClient(httpClient *http.Client) {
+	client.httpClient = httpClient
+}
+
+// SetEmailMatchPolicy sets how the user IDs of the keys returned by email
+// are matched with the requested address, see crypto.EmailMatchPolicy. By
+// default, the local parts are compared case-insensitively.
+func (client *VKSClient) SetEmailMatchPolicy(policy *crypto.EmailMatchPolicy) {
+	client.emailMatchPolicy = policy
+}
+
+// GetKeyByFingerprint fetches the key with the given hex fingerprint.
+// Returns ErrKeyNotFound if the server does not know the key, and
+// ErrKeyMismatch if the returned key has another primary fingerprint.
+func (client *VKSClient) GetKeyByFingerprint(fingerprint string) (*crypto.Key, error) {
+	key, err := client.getKey("/vks/v1/by-fingerprint/" + url.PathEscape(strings.ToUpper(fingerprint)))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(key.GetFingerprint(), fingerprint) {
+		return nil, ErrKeyMismatch
+	}
+	return key, nil
+}
+
+// GetKeyByKeyID fetches the key with the given hex key ID, of the primary
+// key or of a subkey.
+// Returns ErrKeyNotFound if the server does not know the key, and
+// ErrKeyMismatch if neither the primary key nor a subkey of the returned key
+// has the key ID.
+func (client *VKSClient) GetKeyByKeyID(keyID string) (*crypto.Key, error) {
+	key, err := client.getKey("/vks/v1/by-keyid/" + url.PathEscape(strings.ToUpper(keyID)))
+	if err != nil {
+		return nil, err
+	}
+	if !hasKeyID(key, keyID) {
+		return nil, ErrKeyMismatch
+	}
+	return key, nil
+}
+
+// GetKeyByEmail fetches the key published for the given email address.
+// Only the verified user IDs are returned by the server.
+// Returns ErrKeyNotFound if no key was verified for the address, or if no
+// user ID of the returned key matches it with the email match policy.
+func (client *VKSClient) GetKeyByEmail(email string) (*crypto.Key, error) {
+	key, err := client.getKey("/vks/v1/by-email/" + url.PathEscape(email))
+	if err != nil {
+		return nil, err
+	}
+	if !key.MatchesEmail(email, client.emailMatchPolicy) {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Upload uploads the public part of the key. The server publishes the
+// non-identity information of the key (e.g. revocations and subkeys)
+// immediately, while user IDs stay unpublished until verified.
+func (client *VKSClient) Upload(key *crypto.Key) (*VKSUploadResult, error) {
+	armored, err := key.GetArmoredPublicKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to armor key for upload")
+	}
+	result := &VKSUploadResult{}
+	if err = client.post("/vks/v1/upload", map[string]string{"keytext": armored}, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RequestVerify asks the server to send a verification email to each of
+// the addresses, which must be part of the upload identified by token.
+// The returned result holds the updated state of the addresses.
+// * locale : (optional) the preferred language of the emails, e.g. "en_US".
+func (client *VKSClient) RequestVerify(token string, addresses []string, locale string) (*VKSUploadResult, error) {
+	request := &vksVerifyRequest{
+		Token:     token,
+		Addresses: addresses,
+	}
+	if locale != "" {
+		request.Locale = []string{locale}
+	}
+	result := &VKSUploadResult{}
+	if err := client.post("/vks/v1/request-verify", request, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetStatus returns the current state of the addresses of the key, with a
+// fresh token. The VKS API has no status endpoint: the key is uploaded again,
+// which is idempotent.
+func (client *VKSClient) GetStatus(key *crypto.Key) (*VKSUploadResult, error) {
+	return client.Upload(key)
+}
+
+// GetAddressesWithStatus returns the sorted addresses with the given state.
+func (result *VKSUploadResult) GetAddressesWithStatus(status VKSAddressStatus) []string {
+	var addresses []string
+	for address, addressStatus := range result.Status {
+		if addressStatus == status {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// IsFullyPublished returns true if all the addresses of the key are
+// published or revoked, i.e. no verification is left to do.
+func (result *VKSUploadResult) IsFullyPublished() bool {
+	for _, status := range result.Status {
+		if status == VKSAddressUnpublished || status == VKSAddressPending {
+			return false
+		}
+	}
+	return true
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+func (client *VKSClient) getKey(path string) (*crypto.Key, error) {
+	response, err := client.httpClient.Get(client.baseURL + path)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to contact keyserver")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, readVKSError(response)
+	}
+
+	key, err := crypto.NewKeyFromArmoredReader(io.LimitReader(response.Body, maxVKSResponseSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read key from keyserver")
+	}
+	return key, nil
+}
+
+// hasKeyID returns whether the primary key or a subkey of the key has the
+// given hex key ID.
+func hasKeyID(key *crypto.Key, keyID string) bool {
+	id, err := strconv.ParseUint(keyID, 16, 64)
+	if err != nil {
+		return false
+	}
+	entity := key.GetEntity()
+	if entity.PrimaryKey.KeyId == id {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PublicKey.KeyId == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (client *VKSClient) post(path string, request, result interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to encode keyserver request")
+	}
+	response, err := client.httpClient.Post(client.baseURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to contact keyserver")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return readVKSError(response)
+	}
+	if err = json.NewDecoder(io.LimitReader(response.Body, maxVKSResponseSize)).Decode(result); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to decode keyserver response")
+	}
+	return nil
+}
+
+// readVKSError builds an error from an unsuccessful response, including the
+// error message returned by the server, if any.
+func readVKSError(response *http.Response) error {
+	message := response.Status
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, maxVKSResponseSize))
+	var errorResponse vksErrorResponse
+	if json.Unmarshal(body, &errorResponse) == nil && errorResponse.Error != "" {
+		message += ": " + errorResponse.Error
+	}
+	return errors.New("gopenpgp: keyserver error " + message)
+}
@@ -0,0 +1,173 @@
+package keyserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAddr = "alice@example.org"
+
+// newTestVKSServer emulates the verification flow of a VKS server for a
+// single key.
+func newTestVKSServer(t *testing.T, key *crypto.Key) *httptest.Server {
+	armored, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	status := VKSAddressUnpublished
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vks/v1/upload", func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		if json.NewDecoder(r.Body).Decode(&request) != nil || request["keytext"] == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid key"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&VKSUploadResult{
+			Fingerprint: strings.ToUpper(key.GetFingerprint()),
+			Token:       "token",
+			Status:      map[string]VKSAddressStatus{testAddr: status},
+		})
+	})
+	mux.HandleFunc("/vks/v1/request-verify", func(w http.ResponseWriter, r *http.Request) {
+		var request vksVerifyRequest
+		if json.NewDecoder(r.Body).Decode(&request) != nil || request.Token != "token" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid token"}`))
+			return
+		}
+		assert.Exactly(t, []string{testAddr}, request.Addresses)
+		assert.Exactly(t, []string{"en_US"}, request.Locale)
+		status = VKSAddressPending
+		_ = json.NewEncoder(w).Encode(&VKSUploadResult{
+			Fingerprint: strings.ToUpper(key.GetFingerprint()),
+			Token:       "token",
+			Status:      map[string]VKSAddressStatus{testAddr: status},
+		})
+	})
+	mux.HandleFunc("/vks/v1/by-email/", func(w http.ResponseWriter, r *http.Request) {
+		if status != VKSAddressPublished || !strings.HasSuffix(r.URL.Path, testAddr) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(armored))
+	})
+	mux.HandleFunc("/verify", func(w http.ResponseWriter, r *http.Request) {
+		status = VKSAddressPublished
+	})
+	mux.HandleFunc("/vks/v1/by-fingerprint/"+strings.ToUpper(key.GetFingerprint()), func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(armored))
+	})
+	mux.HandleFunc("/vks/v1/by-keyid/"+strings.ToUpper(key.GetHexKeyID()), func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(armored))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestVKSVerificationFlow(t *testing.T) {
+	key, err := crypto.GenerateKey("Alice", testAddr, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	server := newTestVKSServer(t, key)
+	defer server.Close()
+	client := NewVKSClient(server.URL + "/")
+
+	fetched, err := client.GetKeyByFingerprint(key.GetFingerprint())
+	if err != nil {
+		t.Fatal("Expected no error while fetching key, got:", err)
+	}
+	assert.Exactly(t, key.GetFingerprint(), fetched.GetFingerprint())
+	assert.False(t, fetched.IsPrivate())
+
+	_, err = client.GetKeyByEmail(testAddr)
+	assert.Exactly(t, ErrKeyNotFound, err)
+
+	result, err := client.Upload(key)
+	if err != nil {
+		t.Fatal("Expected no error while uploading key, got:", err)
+	}
+	assert.False(t, result.IsFullyPublished())
+	unpublished := result.GetAddressesWithStatus(VKSAddressUnpublished)
+	assert.Exactly(t, []string{testAddr}, unpublished)
+
+	result, err = client.RequestVerify(result.Token, unpublished, "en_US")
+	if err != nil {
+		t.Fatal("Expected no error while requesting verification, got:", err)
+	}
+	assert.Exactly(t, []string{testAddr}, result.GetAddressesWithStatus(VKSAddressPending))
+
+	_, err = client.RequestVerify("wrong token", unpublished, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid token")
+
+	// The user clicks the link in the verification email
+	response, err := http.Get(server.URL + "/verify")
+	if err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+	_ = response.Body.Close()
+
+	result, err = client.GetStatus(key)
+	if err != nil {
+		t.Fatal("Expected no error while getting status, got:", err)
+	}
+	assert.True(t, result.IsFullyPublished())
+
+	fetched, err = client.GetKeyByEmail(testAddr)
+	if err != nil {
+		t.Fatal("Expected no error while fetching key, got:", err)
+	}
+	assert.Exactly(t, key.GetFingerprint(), fetched.GetFingerprint())
+
+	// The server answers, but no user ID of the key matches the address
+	_, err = client.GetKeyByEmail("malice@example.org")
+	assert.Exactly(t, ErrKeyNotFound, err)
+}
+
+func TestVKSGetKeyMismatch(t *testing.T) {
+	key, err := crypto.GenerateKey("Alice", testAddr, "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	other, err := crypto.GenerateKey("Mallory", "mallory@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	server := newTestVKSServer(t, key)
+	defer server.Close()
+	client := NewVKSClient(server.URL)
+
+	fetched, err := client.GetKeyByKeyID(key.GetHexKeyID())
+	if err != nil {
+		t.Fatal("Expected no error while fetching key, got:", err)
+	}
+	assert.Exactly(t, key.GetFingerprint(), fetched.GetFingerprint())
+
+	subkeyID := strconv.FormatUint(key.GetEntity().Subkeys[0].PublicKey.KeyId, 16)
+	assert.True(t, hasKeyID(fetched, subkeyID))
+
+	// The server answers with the key for any fingerprint or key ID
+	armored, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	lyingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(armored))
+	}))
+	defer lyingServer.Close()
+	client = NewVKSClient(lyingServer.URL)
+
+	_, err = client.GetKeyByFingerprint(other.GetFingerprint())
+	assert.Exactly(t, ErrKeyMismatch, err)
+	_, err = client.GetKeyByKeyID(other.GetHexKeyID())
+	assert.Exactly(t, ErrKeyMismatch, err)
+}
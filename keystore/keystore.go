@@ -0,0 +1,349 @@
+// Package keystore stores locked private keys on disk, along with an index
+// of their fingerprints and user IDs, and optionally stores their passphrases
+// in the keychain of the operating system, so that desktop applications get a
+// safe default storage layer for their keys.
+// The private keys are only ever written locked: the passphrases are never
+// written to the store itself.
+package keystore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/ProtonMail/gopenpgp/v2/internal"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SecretService is the service under which the passphrases are stored in
+	// the SecretStore, with the key fingerprints as accounts.
+	SecretService = "gopenpgp keystore"
+	// indexName is the name of the index file.
+	indexName = "index.json"
+	// writeLockName is the file locked by writers.
+	writeLockName = "writelock"
+	// keyExtension is the extension of the key files.
+	keyExtension = ".key"
+)
+
+// ErrNotFound is returned when the store has no key, or the SecretStore no
+// passphrase, for a lookup.
+var ErrNotFound = errors.New("gopenpgp: key not found")
+
+// Store is a directory of locked private keys, stored in binary form in files
+// named after their fingerprint, e.g.
+// "eb85bb5fa33a75e15e944e63f231550c4f47e38e.key", and listed in the
+// "index.json" file.
+// Concurrent writers, including other processes on Unix and Windows, are
+// serialized with a lock on the "writelock" file, and files are replaced
+// atomically.
+type Store struct {
+	path    string
+	secrets SecretStore
+}
+
+// Entry is the index entry of a key of the store.
+type Entry struct {
+	// Fingerprint is the hex fingerprint of the primary key.
+	Fingerprint string `json:"fingerprint"`
+	// UserIDs are the user IDs of the key.
+	UserIDs []string `json:"userIDs"`
+	// CreationTime is the unix creation time of the primary key.
+	CreationTime int64 `json:"creationTime"`
+}
+
+// NewStore opens the key store at path, creating it if needed. secrets
+// stores the passphrases of the keys, e.g. the keychain of the operating
+// system returned by NewOSSecretStore, or nil if the passphrases are
+// managed by the application.
+func NewStore(path string, secrets SecretStore) (*Store, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to create key store")
+	}
+	return &Store{path: path, secrets: secrets}, nil
+}
+
+// GetPath returns the location of the key store.
+func (store *Store) GetPath() string {
+	return store.path
+}
+
+// Add adds a locked private key to the store, replacing the previous version
+// of the key if any. Unlocked keys, including keys with an unlocked subkey,
+// are rejected, lock them first with Key.Lock.
+func (store *Store) Add(key *crypto.Key) error {
+	if !key.IsPrivate() {
+		return errors.New("gopenpgp: only private keys can be added to the key store")
+	}
+	if !isLocked(key) {
+		return errors.New("gopenpgp: only locked keys can be added to the key store")
+	}
+	data, err := key.Serialize()
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to serialize key")
+	}
+
+	unlock, err := store.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := store.readIndex()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomically(store.getKeyPath(key.GetFingerprint()), data); err != nil {
+		return err
+	}
+	entries = append(removeEntry(entries, key.GetFingerprint()), newEntry(key))
+	return store.writeIndex(entries)
+}
+
+// Get returns the locked key with the given hex fingerprint.
+// Returns ErrNotFound if the store does not contain it.
+func (store *Store) Get(fingerprint string) (*crypto.Key, error) {
+	fingerprint, err := normalizeFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(store.getKeyPath(fingerprint))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read key")
+	}
+	key, err := crypto.NewKey(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse key "+fingerprint)
+	}
+	return key, nil
+}
+
+// Delete removes the key with the given hex fingerprint from the store, and
+// its passphrase from the SecretStore if any.
+// Returns ErrNotFound if the store does not contain it.
+func (store *Store) Delete(fingerprint string) error {
+	fingerprint, err := normalizeFingerprint(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := store.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := store.readIndex()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(store.getKeyPath(fingerprint))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to delete key")
+	}
+	if err := store.writeIndex(removeEntry(entries, fingerprint)); err != nil {
+		return err
+	}
+	if store.secrets != nil {
+		if err := store.secrets.Delete(SecretService, fingerprint); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the index entries of the keys of the store, sorted by
+// fingerprint.
+func (store *Store) List() ([]*Entry, error) {
+	return store.readIndex()
+}
+
+// SetPassphrase stores the passphrase of the key with the given hex
+// fingerprint in the SecretStore, once checked that it unlocks the key, see
+// Unlock.
+func (store *Store) SetPassphrase(fingerprint string, passphrase []byte) error {
+	if store.secrets == nil {
+		return errors.New("gopenpgp: the key store has no secret store")
+	}
+	key, err := store.Get(fingerprint)
+	if err != nil {
+		return err
+	}
+	unlocked, err := key.Unlock(passphrase)
+	if err != nil {
+		return err
+	}
+	unlocked.ClearPrivateParams()
+	return store.secrets.Set(SecretService, key.GetFingerprint(), passphrase)
+}
+
+// Unlock returns the key with the given hex fingerprint, unlocked with the
+// passphrase stored in the SecretStore, see SetPassphrase.
+// Returns ErrNotFound if the store does not contain the key, or the
+// SecretStore its passphrase.
+func (store *Store) Unlock(fingerprint string) (*crypto.Key, error) {
+	if store.secrets == nil {
+		return nil, errors.New("gopenpgp: the key store has no secret store")
+	}
+	key, err := store.Get(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := store.secrets.Get(SecretService, key.GetFingerprint())
+	if err != nil {
+		return nil, err
+	}
+	defer clearBytes(passphrase)
+	return key.Unlock(passphrase)
+}
+
+// ------ INTERNAL FUNCTIONS -------
+
+// isLocked returns whether the secret material of the primary key and of
+// every subkey is either encrypted or absent (GNU-dummy). Key.IsLocked is
+// not enough, as it accepts a key with a single locked key packet.
+func isLocked(key *crypto.Key) bool {
+	entity := key.GetEntity()
+	if !isPrivateKeyLocked(entity.PrivateKey) {
+		return false
+	}
+	for _, subkey := range entity.Subkeys {
+		if !isPrivateKeyLocked(subkey.PrivateKey) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPrivateKeyLocked(privateKey *packet.PrivateKey) bool {
+	return privateKey == nil || privateKey.Dummy() || privateKey.Encrypted
+}
+
+func (store *Store) getKeyPath(fingerprint string) string {
+	return filepath.Join(store.path, fingerprint+keyExtension)
+}
+
+// readIndex reads the index, empty if the store has no index yet.
+func (store *Store) readIndex() ([]*Entry, error) {
+	data, err := ioutil.ReadFile(filepath.Join(store.path, indexName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read key store index")
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to parse key store index")
+	}
+	return entries, nil
+}
+
+func (store *Store) writeIndex(entries []*Entry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Fingerprint < entries[j].Fingerprint
+	})
+	if entries == nil {
+		entries = []*Entry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to serialize key store index")
+	}
+	return writeFileAtomically(filepath.Join(store.path, indexName), data)
+}
+
+// lock takes the write lock of the store, and returns the function releasing it.
+func (store *Store) lock() (func(), error) {
+	file, err := os.OpenFile(filepath.Join(store.path, writeLockName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to open key store lock")
+	}
+	if err = internal.LockFile(file); err != nil {
+		_ = file.Close()
+		return nil, errors.Wrap(err, "gopenpgp: unable to lock key store")
+	}
+	return func() {
+		_ = internal.UnlockFile(file)
+		_ = file.Close()
+	}, nil
+}
+
+func newEntry(key *crypto.Key) *Entry {
+	entity := key.GetEntity()
+	entry := &Entry{
+		Fingerprint:  key.GetFingerprint(),
+		CreationTime: entity.PrimaryKey.CreationTime.Unix(),
+	}
+	for name := range entity.Identities {
+		entry.UserIDs = append(entry.UserIDs, name)
+	}
+	sort.Strings(entry.UserIDs)
+	return entry
+}
+
+func removeEntry(entries []*Entry, fingerprint string) []*Entry {
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.Fingerprint != fingerprint {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// writeFileAtomically replaces the file at path with data.
+func writeFileAtomically(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to write to key store")
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return errors.Wrap(err, "gopenpgp: unable to write to key store")
+	}
+	return nil
+}
+
+// normalizeFingerprint checks that s is a hex v4 or v6 fingerprint, and
+// returns it in lower case.
+func normalizeFingerprint(s string) (string, error) {
+	fingerprint := strings.ToLower(s)
+	if len(fingerprint) != 40 && len(fingerprint) != 64 {
+		return "", errors.New("gopenpgp: invalid fingerprint " + s)
+	}
+	for _, c := range fingerprint {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return "", errors.New("gopenpgp: invalid fingerprint " + s)
+		}
+	}
+	return fingerprint, nil
+}
+
+func clearBytes(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
@@ -0,0 +1,149 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSecretStore struct {
+	secrets map[string][]byte
+}
+
+func (store *testSecretStore) Get(service, account string) ([]byte, error) {
+	secret, ok := store.secrets[service+"/"+account]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, secret...), nil
+}
+
+func (store *testSecretStore) Set(service, account string, secret []byte) error {
+	store.secrets[service+"/"+account] = append([]byte{}, secret...)
+	return nil
+}
+
+func (store *testSecretStore) Delete(service, account string) error {
+	if _, ok := store.secrets[service+"/"+account]; !ok {
+		return ErrNotFound
+	}
+	delete(store.secrets, service+"/"+account)
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	secrets := &testSecretStore{secrets: make(map[string][]byte)}
+	store, err := NewStore(filepath.Join(t.TempDir(), "keys"), secrets)
+	if err != nil {
+		t.Fatal("Expected no error while creating store, got:", err)
+	}
+	passphrase := []byte("passphrase")
+	key, err := crypto.GenerateKey("Alice", "alice@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	fingerprint := key.GetFingerprint()
+
+	// Only locked private keys are stored
+	assert.Error(t, store.Add(key))
+	publicKey, err := key.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	assert.Error(t, store.Add(publicKey))
+
+	lockedKey, err := key.Lock(passphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	if err := store.Add(lockedKey); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal("Expected no error while listing keys, got:", err)
+	}
+	if assert.Len(t, entries, 1) {
+		assert.Exactly(t, fingerprint, entries[0].Fingerprint)
+		assert.Exactly(t, []string{"Alice <alice@example.org>"}, entries[0].UserIDs)
+	}
+	stored, err := store.Get(fingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	locked, err := stored.IsLocked()
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	// The passphrase is only stored in the secret store
+	_, err = store.Unlock(fingerprint)
+	assert.Exactly(t, ErrNotFound, err)
+	assert.Error(t, store.SetPassphrase(fingerprint, []byte("wrong")))
+	if err := store.SetPassphrase(fingerprint, passphrase); err != nil {
+		t.Fatal("Expected no error while storing passphrase, got:", err)
+	}
+	unlocked, err := store.Unlock(fingerprint)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	isUnlocked, err := unlocked.IsUnlocked()
+	assert.NoError(t, err)
+	assert.True(t, isUnlocked)
+	files, err := filepath.Glob(filepath.Join(store.GetPath(), "*"))
+	if err != nil {
+		t.Fatal("Expected no error while listing files, got:", err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal("Expected no error while reading file, got:", err)
+		}
+		assert.NotContains(t, string(data), string(passphrase))
+	}
+
+	if err := store.Delete(fingerprint); err != nil {
+		t.Fatal("Expected no error while deleting key, got:", err)
+	}
+	_, err = store.Get(fingerprint)
+	assert.Exactly(t, ErrNotFound, err)
+	assert.Exactly(t, ErrNotFound, store.Delete(fingerprint))
+	assert.Empty(t, secrets.secrets)
+	entries, err = store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = store.Get("not a fingerprint")
+	assert.Error(t, err)
+}
+
+func TestStoreRejectsUnlockedSubkey(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "keys"), nil)
+	if err != nil {
+		t.Fatal("Expected no error while creating store, got:", err)
+	}
+	passphrase := []byte("passphrase")
+	key, err := crypto.GenerateKey("Alice", "alice@example.org", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	lockedKey, err := key.Lock(passphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	subkeyFingerprint := hex.EncodeToString(lockedKey.GetEntity().Subkeys[0].PublicKey.Fingerprint)
+	partlyUnlockedKey, err := lockedKey.UnlockSubkey(subkeyFingerprint, passphrase)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking subkey, got:", err)
+	}
+	assert.Error(t, store.Add(partlyUnlockedKey))
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal("Expected no error while listing keys, got:", err)
+	}
+	assert.Empty(t, entries)
+}
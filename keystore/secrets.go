@@ -0,0 +1,15 @@
+package keystore
+
+// SecretStore stores secrets, e.g. the passphrases of the keys of a Store,
+// identified by a service and an account.
+// NewOSSecretStore returns the SecretStore of the operating system.
+type SecretStore interface {
+	// Get returns the secret of the account of the service, or ErrNotFound.
+	Get(service, account string) ([]byte, error)
+	// Set stores the secret of the account of the service, replacing the
+	// previous secret if any.
+	Set(service, account string, secret []byte) error
+	// Delete removes the secret of the account of the service, or returns
+	// ErrNotFound.
+	Delete(service, account string) error
+}
@@ -0,0 +1,80 @@
+//go:build darwin && !ios && !nokeychain
+// +build darwin,!ios,!nokeychain
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// securityItemNotFound is the exit code of the security tool for missing
+// items, errSecItemNotFound.
+const securityItemNotFound = 44
+
+// keychain stores the secrets in the login keychain, with the security
+// tool. The secrets are hex-encoded, and passed on its standard input.
+type keychain struct{}
+
+// NewOSSecretStore returns the SecretStore of the operating system: the
+// macOS Keychain, the Windows Data Protection API (DPAPI), or the Secret
+// Service of the desktop, e.g. GNOME Keyring, through libsecret's
+// secret-tool. Building with the nokeychain tag disables the integration.
+func NewOSSecretStore() (SecretStore, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: the macOS Keychain is not available")
+	}
+	return &keychain{}, nil
+}
+
+func (*keychain) Get(service, account string) ([]byte, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err := keychainError(err); err != nil {
+		return nil, err
+	}
+	secret, err := hex.DecodeString(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid secret in the macOS Keychain")
+	}
+	return secret, nil
+}
+
+func (*keychain) Set(service, account string, secret []byte) error {
+	// In interactive mode, the secret isn't visible in the arguments
+	command := exec.Command("security", "-i")
+	command.Stdin = strings.NewReader(
+		"add-generic-password -U -s " + quoteKeychainArgument(service) +
+			" -a " + quoteKeychainArgument(account) +
+			" -w " + hex.EncodeToString(secret) + "\n",
+	)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to write to the macOS Keychain: "+stderr.String())
+	}
+	return nil
+}
+
+func (*keychain) Delete(service, account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run()
+	return keychainError(err)
+}
+
+func keychainError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: error in accessing the macOS Keychain")
+	}
+	return nil
+}
+
+func quoteKeychainArgument(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,71 @@
+//go:build linux && !android && !nokeychain
+// +build linux,!android,!nokeychain
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// secretService stores the secrets in the Secret Service of the desktop,
+// e.g. GNOME Keyring or KWallet, with libsecret's secret-tool. The secrets
+// are hex-encoded, and passed on its standard input.
+type secretService struct{}
+
+// NewOSSecretStore returns the SecretStore of the operating system: the
+// macOS Keychain, the Windows Data Protection API (DPAPI), or the Secret
+// Service of the desktop, e.g. GNOME Keyring, through libsecret's
+// secret-tool. Building with the nokeychain tag disables the integration.
+func NewOSSecretStore() (SecretStore, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: libsecret's secret-tool is not available")
+	}
+	return &secretService{}, nil
+}
+
+func (*secretService) Get(service, account string) ([]byte, error) {
+	output, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(output) == 0 {
+		// secret-tool exits with 1 without output for missing secrets
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: error in reading from the Secret Service")
+	}
+	secret, err := hex.DecodeString(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: invalid secret in the Secret Service")
+	}
+	return secret, nil
+}
+
+func (*secretService) Set(service, account string, secret []byte) error {
+	command := exec.Command(
+		"secret-tool", "store", "--label="+service+" "+account,
+		"service", service, "account", account,
+	)
+	command.Stdin = strings.NewReader(hex.EncodeToString(secret))
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to write to the Secret Service: "+stderr.String())
+	}
+	return nil
+}
+
+func (store *secretService) Delete(service, account string) error {
+	// secret-tool clear succeeds for missing secrets
+	if _, err := store.Get(service, account); err != nil {
+		return err
+	}
+	if err := exec.Command("secret-tool", "clear", "service", service, "account", account).Run(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to delete from the Secret Service")
+	}
+	return nil
+}
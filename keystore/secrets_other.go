@@ -0,0 +1,13 @@
+//go:build nokeychain || (!darwin && !linux && !windows) || ios || android
+// +build nokeychain !darwin,!linux,!windows ios android
+
+package keystore
+
+import "github.com/pkg/errors"
+
+// NewOSSecretStore returns the SecretStore of the operating system, which is
+// not supported on this platform, or was disabled with the nokeychain build
+// tag.
+func NewOSSecretStore() (SecretStore, error) {
+	return nil, errors.New("gopenpgp: no secret store is available on this platform")
+}
@@ -0,0 +1,124 @@
+//go:build windows && !nokeychain
+// +build windows,!nokeychain
+
+package keystore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// cryptProtectUIForbidden fails instead of prompting the user.
+const cryptProtectUIForbidden = 0x1
+
+var (
+	crypt32                = syscall.NewLazyDLL("crypt32.dll")
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procCryptProtectData   = crypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData = crypt32.NewProc("CryptUnprotectData")
+	procLocalFree          = kernel32.NewProc("LocalFree")
+)
+
+// dataBlob is the DATA_BLOB structure of the Data Protection API.
+type dataBlob struct {
+	size uint32
+	data *byte
+}
+
+// dpapiStore stores the secrets in files of a directory, encrypted with the
+// Data Protection API for the current user.
+type dpapiStore struct {
+	path string
+}
+
+// NewOSSecretStore returns the SecretStore of the operating system: the
+// macOS Keychain, the Windows Data Protection API (DPAPI), or the Secret
+// Service of the desktop, e.g. GNOME Keyring, through libsecret's
+// secret-tool. Building with the nokeychain tag disables the integration.
+// On Windows, the encrypted secrets are stored in
+// %LOCALAPPDATA%\gopenpgp\secrets.
+func NewOSSecretStore() (SecretStore, error) {
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		return nil, errors.New("gopenpgp: %LOCALAPPDATA% is not set")
+	}
+	path := filepath.Join(localAppData, "gopenpgp", "secrets")
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to create secret directory")
+	}
+	return &dpapiStore{path: path}, nil
+}
+
+func (store *dpapiStore) Get(service, account string) ([]byte, error) {
+	encrypted, err := ioutil.ReadFile(store.getSecretPath(service, account))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to read secret")
+	}
+	secret, err := callDPAPI(procCryptUnprotectData, encrypted)
+	if err != nil {
+		return nil, errors.Wrap(err, "gopenpgp: unable to decrypt secret with DPAPI")
+	}
+	return secret, nil
+}
+
+func (store *dpapiStore) Set(service, account string, secret []byte) error {
+	encrypted, err := callDPAPI(procCryptProtectData, secret)
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to encrypt secret with DPAPI")
+	}
+	return writeFileAtomically(store.getSecretPath(service, account), encrypted)
+}
+
+func (store *dpapiStore) Delete(service, account string) error {
+	err := os.Remove(store.getSecretPath(service, account))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to delete secret")
+	}
+	return nil
+}
+
+// getSecretPath returns the path of the file of a secret, named after the
+// hash of its service and account.
+func (store *dpapiStore) getSecretPath(service, account string) string {
+	name := sha256.Sum256([]byte(service + "\x00" + account))
+	return filepath.Join(store.path, hex.EncodeToString(name[:])+".dpapi")
+}
+
+// callDPAPI calls CryptProtectData or CryptUnprotectData, which have the
+// same signature, on input.
+func callDPAPI(proc *syscall.LazyProc, input []byte) ([]byte, error) {
+	in := dataBlob{size: uint32(len(input))}
+	if len(input) > 0 {
+		in.data = &input[0]
+	}
+	var out dataBlob
+	result, _, err := proc.Call(
+		uintptr(unsafe.Pointer(&in)),
+		0, 0, 0, 0,
+		cryptProtectUIForbidden,
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if result == 0 {
+		return nil, err
+	}
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(out.data))) //nolint:errcheck
+	// The output of CryptUnprotectData is the plaintext secret
+	buffer := unsafe.Slice(out.data, out.size)
+	defer clearBytes(buffer)
+	output := make([]byte, out.size)
+	copy(output, buffer)
+	return output, nil
+}
@@ -0,0 +1,212 @@
+// Package ownertrust stores the owner trust assigned to certificates, i.e.
+// how much their holders are trusted to certify other keys, and converts it
+// to and from the format of gpg --export-ownertrust, so that trust
+// assignments can move between an application and a GnuPG installation.
+package ownertrust
+
+import (
+	"bufio"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/pkg/errors"
+)
+
+// Level is the owner trust of a certificate, with the values of GnuPG.
+type Level int
+
+const (
+	// LevelUnknown means no owner trust is assigned.
+	LevelUnknown Level = 0
+	// LevelUndefined means the user doesn't know how much to trust the owner.
+	LevelUndefined Level = 2
+	// LevelNever means the owner's certifications are never trusted.
+	LevelNever Level = 3
+	// LevelMarginal means the owner's certifications are marginally trusted.
+	LevelMarginal Level = 4
+	// LevelFull means the owner's certifications are fully trusted.
+	LevelFull Level = 5
+	// LevelUltimate means the certificate is the user's own.
+	LevelUltimate Level = 6
+)
+
+// levelMask extracts the trust level from the GnuPG ownertrust value, whose
+// high bits are flags. disabledFlag is the flag of disabled certificates.
+const (
+	levelMask    = 0x0f
+	disabledFlag = 0x80
+)
+
+// Store maps the fingerprints of certificates to their owner trust.
+// Persisting the store, e.g. with Export, is left to the application.
+type Store struct {
+	levels   map[string]Level
+	disabled map[string]bool
+}
+
+// NewStore creates an empty owner trust store.
+func NewStore() *Store {
+	return &Store{levels: make(map[string]Level), disabled: make(map[string]bool)}
+}
+
+// Set assigns the owner trust of the certificate with the given v4 or v6
+// fingerprint. Setting LevelUnknown removes the assignment.
+func (store *Store) Set(fingerprint string, level Level) error {
+	fingerprint, err := crypto.ParseFingerprint(fingerprint)
+	if err != nil {
+		return err
+	}
+	if !level.isValid() {
+		return errors.New("gopenpgp: invalid owner trust level " + strconv.Itoa(int(level)))
+	}
+	if level == LevelUnknown {
+		delete(store.levels, fingerprint)
+	} else {
+		store.levels[fingerprint] = level
+	}
+	return nil
+}
+
+// Get returns the owner trust of the certificate with the given fingerprint,
+// LevelUnknown if none is assigned or the fingerprint is invalid.
+func (store *Store) Get(fingerprint string) Level {
+	fingerprint, err := crypto.ParseFingerprint(fingerprint)
+	if err != nil {
+		return LevelUnknown
+	}
+	return store.levels[fingerprint]
+}
+
+// SetDisabled marks the certificate with the given v4 or v6 fingerprint as
+// disabled or not, as gpg --edit-key disable does, independently of its
+// owner trust.
+func (store *Store) SetDisabled(fingerprint string, disabled bool) error {
+	fingerprint, err := crypto.ParseFingerprint(fingerprint)
+	if err != nil {
+		return err
+	}
+	store.setDisabled(fingerprint, disabled)
+	return nil
+}
+
+// IsDisabled returns whether the certificate with the given fingerprint is
+// disabled. GnuPG doesn't use disabled certificates, but the flag is left
+// for the application to honor: it doesn't change the result of Validate.
+func (store *Store) IsDisabled(fingerprint string) bool {
+	fingerprint, err := crypto.ParseFingerprint(fingerprint)
+	if err != nil {
+		return false
+	}
+	return store.disabled[fingerprint]
+}
+
+// GetFingerprints returns the sorted fingerprints of the certificates with an
+// assigned owner trust or disabled.
+func (store *Store) GetFingerprints() []string {
+	fingerprints := make([]string, 0, len(store.levels)+len(store.disabled))
+	for fingerprint := range store.levels {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	for fingerprint := range store.disabled {
+		if _, ok := store.levels[fingerprint]; !ok {
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	sort.Strings(fingerprints)
+	return fingerprints
+}
+
+// Export returns the owner trust assignments in the format of
+// gpg --export-ownertrust, which gpg --import-ownertrust can import.
+func (store *Store) Export() string {
+	var exported strings.Builder
+	exported.WriteString("# List of assigned trustvalues, created ")
+	exported.WriteString(time.Unix(crypto.GetUnixTime(), 0).UTC().Format(time.ANSIC))
+	exported.WriteString(" UTC\n")
+	exported.WriteString("# (Use \"gpg --import-ownertrust\" to restore them)\n")
+	for _, fingerprint := range store.GetFingerprints() {
+		exported.WriteString(strings.ToUpper(fingerprint))
+		exported.WriteString(":")
+		value := int(store.levels[fingerprint])
+		if store.disabled[fingerprint] {
+			value |= disabledFlag
+		}
+		exported.WriteString(strconv.Itoa(value))
+		exported.WriteString(":\n")
+	}
+	return exported.String()
+}
+
+// Import adds the owner trust assignments in the format of
+// gpg --export-ownertrust to the store, replacing the existing assignments
+// of the same certificates, and their disabled flags. Nothing is imported if
+// a line is invalid.
+func (store *Store) Import(ownerTrust string) error {
+	levels := make(map[string]Level)
+	disabled := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(ownerTrust))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fingerprint, level, isDisabled, err := parseLine(line)
+		if err != nil {
+			return errors.Wrap(err, "gopenpgp: invalid owner trust on line "+strconv.Itoa(lineNumber))
+		}
+		levels[fingerprint] = level
+		disabled[fingerprint] = isDisabled
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "gopenpgp: unable to read owner trust")
+	}
+
+	for fingerprint, level := range levels {
+		if level == LevelUnknown {
+			delete(store.levels, fingerprint)
+		} else {
+			store.levels[fingerprint] = level
+		}
+		store.setDisabled(fingerprint, disabled[fingerprint])
+	}
+	return nil
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+func (store *Store) setDisabled(fingerprint string, disabled bool) {
+	if disabled {
+		store.disabled[fingerprint] = true
+	} else {
+		delete(store.disabled, fingerprint)
+	}
+}
+
+func (level Level) isValid() bool {
+	return level == LevelUnknown || (level >= LevelUndefined && level <= LevelUltimate)
+}
+
+// parseLine parses a "FINGERPRINT:VALUE:" line into the fingerprint, the
+// trust level and the disabled flag.
+func parseLine(line string) (string, Level, bool, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) < 2 || len(fields) > 3 || (len(fields) == 3 && fields[2] != "") {
+		return "", LevelUnknown, false, errors.New("gopenpgp: expected FINGERPRINT:VALUE:")
+	}
+	fingerprint, err := crypto.ParseFingerprint(fields[0])
+	if err != nil {
+		return "", LevelUnknown, false, err
+	}
+	value, err := strconv.ParseUint(fields[1], 10, 8)
+	if err != nil {
+		return "", LevelUnknown, false, errors.Wrap(err, "gopenpgp: invalid owner trust value")
+	}
+	level := Level(value & levelMask)
+	if !level.isValid() {
+		return "", LevelUnknown, false, errors.New("gopenpgp: invalid owner trust level " + strconv.Itoa(int(level)))
+	}
+	return fingerprint, level, value&disabledFlag != 0, nil
+}
@@ -0,0 +1,68 @@
+package ownertrust
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGnuPGOwnerTrust = `# List of assigned trustvalues, created Fri Oct 16 18:55:08 2026 UTC
+# (Use "gpg --import-ownertrust" to restore them)
+C566E6C345D42A169B53185177C9BAA473E8B894:6:
+E58294F2E9A227486E8B061B31CC528FD7FA3F19:4:
+1111111111111111111111111111111111111111:132:
+`
+
+func TestImportExport(t *testing.T) {
+	store := NewStore()
+	if err := store.Import(testGnuPGOwnerTrust); err != nil {
+		t.Fatal("Expected no error while importing owner trust, got:", err)
+	}
+	assert.Exactly(t, LevelUltimate, store.Get("c566e6c345d42a169b53185177c9baa473e8b894"))
+	assert.Exactly(t, LevelMarginal, store.Get("E582 94F2 E9A2 2748 6E8B  061B 31CC 528F D7FA 3F19"))
+	assert.Exactly(t, LevelUnknown, store.Get("0000000000000000000000000000000000000000"))
+	assert.Exactly(t, LevelMarginal, store.Get("1111111111111111111111111111111111111111"))
+	assert.True(t, store.IsDisabled("1111111111111111111111111111111111111111"))
+	assert.False(t, store.IsDisabled("E58294F2E9A227486E8B061B31CC528FD7FA3F19"))
+
+	if err := store.Set("0x0000000000000000000000000000000000000000", LevelNever); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+	if err := store.Set("E58294F2E9A227486E8B061B31CC528FD7FA3F19", LevelUnknown); err != nil {
+		t.Fatal("Expected no error while removing owner trust, got:", err)
+	}
+	if err := store.SetDisabled("C566E6C345D42A169B53185177C9BAA473E8B894", true); err != nil {
+		t.Fatal("Expected no error while disabling certificate, got:", err)
+	}
+	if err := store.Set("1111111111111111111111111111111111111111", LevelUnknown); err != nil {
+		t.Fatal("Expected no error while removing owner trust, got:", err)
+	}
+	exported := store.Export()
+	assert.True(t, strings.HasPrefix(exported, "# List of assigned trustvalues, created "))
+	assert.True(t, strings.HasSuffix(exported, "\n0000000000000000000000000000000000000000:3:\n"+
+		"1111111111111111111111111111111111111111:128:\n"+
+		"C566E6C345D42A169B53185177C9BAA473E8B894:134:\n"))
+
+	imported := NewStore()
+	if err := imported.Import(exported); err != nil {
+		t.Fatal("Expected no error while importing owner trust, got:", err)
+	}
+	assert.Exactly(t, store.GetFingerprints(), imported.GetFingerprints())
+	assert.True(t, imported.IsDisabled("C566E6C345D42A169B53185177C9BAA473E8B894"))
+	assert.Exactly(t, LevelUltimate, imported.Get("C566E6C345D42A169B53185177C9BAA473E8B894"))
+}
+
+func TestImportInvalid(t *testing.T) {
+	store := NewStore()
+	for _, ownerTrust := range []string{
+		"C566E6C345D42A169B53185177C9BAA473E8B894",
+		"C566E6C345D42A169B53185177C9BAA473E8B894:7:",
+		"C566E6C345D42A169B53185177C9BAA473E8B8:6:",
+		"C566E6C345D42A169B53185177C9BAA473E8B894:6:\nC566E6C345D42A169B53185177C9BAA473E8B894:x:",
+	} {
+		assert.Error(t, store.Import(ownerTrust))
+	}
+	assert.Empty(t, store.GetFingerprints())
+	assert.Error(t, store.Set("C566E6C345D42A169B53185177C9BAA473E8B894", Level(1)))
+}
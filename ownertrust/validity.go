@@ -0,0 +1,301 @@
+package ownertrust
+
+import (
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+)
+
+// unlimitedDepth is the trust depth of the certificates with ultimate owner
+// trust, greater than any trust signature depth.
+const unlimitedDepth = 256
+
+// completeTrustAmount is the trust amount of a trust signature designating
+// an introducer on its own, see RFC 4880, section 5.2.3.13.
+const completeTrustAmount = 120
+
+// Certification is a certification of a user ID by another key, in a
+// certification chain, see Store.Validate.
+type Certification struct {
+	// IssuerFingerprint is the hex fingerprint of the certifying key.
+	IssuerFingerprint string
+	// Fingerprint is the hex fingerprint of the certified key.
+	Fingerprint string
+	// UserID is the certified user ID.
+	UserID string
+	// TrustDepth is the depth of a trust signature, i.e. how many levels
+	// of introducers the issuer delegates to the certified key, 0 for a
+	// plain certification.
+	TrustDepth int
+	// TrustAmount is the amount of trust of a trust signature, 120 for
+	// complete trust and 60 for partial trust.
+	TrustAmount int
+	// RegularExpression restricts the user IDs the certified key can
+	// introduce, empty if it is not restricted.
+	RegularExpression string
+}
+
+// IdentityValidity is the validity of a user ID of a certificate, see
+// Store.Validate.
+type IdentityValidity struct {
+	// UserID is the user ID.
+	UserID string
+	// Valid is true if the user ID is bound to the certificate by a chain of
+	// certifications starting at a certificate with ultimate owner trust,
+	// or if the certificate itself has ultimate owner trust.
+	Valid bool
+	// Chain is the shortest chain of certifications validating the user
+	// ID, from a certificate with ultimate owner trust to the user ID.
+	// It is empty if the user ID is not valid, or if the certificate has
+	// ultimate owner trust.
+	Chain []*Certification
+}
+
+// Validate computes the validity of the user IDs of a certificate, by
+// walking the certifications between the known certificates, e.g. a local
+// keyring: the certificates with ultimate owner trust are valid and trusted
+// to introduce other certificates, and a certification by an introducer
+// validates the certified user ID.
+// Introducers are designated by trust signatures, to the depth of the trust
+// signature: depth 1 makes the certified key an introducer, depth 2 lets it
+// designate introducers itself, and so on, within the depth of the
+// introducer that issued the trust signature. The regular expressions of the
+// trust signatures of a chain must all match the validated user ID.
+// Only trust signatures with complete trust, i.e. a trust amount of at least
+// 120, designate introducers: partial trust amounts are not combined, and
+// are treated as plain certifications, like a trust amount of 0.
+// A valid certificate with full owner trust is also an introducer, with a
+// depth of 1. Certificates with marginal or never owner trust aren't
+// introducers.
+// Revoked and expired certificates, user IDs and certifications are ignored.
+func (store *Store) Validate(certificate *crypto.Key, certificates []*crypto.Key) []*IdentityValidity {
+	graph := newCertificationGraph(append([]*crypto.Key{certificate}, certificates...))
+	target := certificate.GetEntity()
+	fingerprint := hex.EncodeToString(target.PrimaryKey.Fingerprint)
+
+	var validities []*IdentityValidity
+	for _, identity := range sortedIdentities(target) {
+		validity := &IdentityValidity{UserID: identity.Name}
+		switch {
+		case identity.Revoked(graph.now) || graph.isRevoked(target):
+		case store.Get(fingerprint) == LevelUltimate:
+			validity.Valid = true
+		default:
+			validity.Chain = graph.findChain(store, fingerprint, identity.Name)
+			validity.Valid = validity.Chain != nil
+		}
+		validities = append(validities, validity)
+	}
+	return validities
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// certificationGraph is the graph of the verified certifications between a
+// set of certificates.
+type certificationGraph struct {
+	now      time.Time
+	entities map[string]*openpgp.Entity
+	// edges are the certifications by issuer fingerprint
+	edges map[string][]*certificationEdge
+}
+
+type certificationEdge struct {
+	certification *Certification
+	regexp        *regexp.Regexp
+}
+
+// chainNode is a certificate reached while searching a chain, with the
+// remaining trust depth, and the chain reaching it.
+type chainNode struct {
+	fingerprint string
+	depth       int
+	chain       []*certificationEdge
+}
+
+func newCertificationGraph(certificates []*crypto.Key) *certificationGraph {
+	graph := &certificationGraph{
+		now:      time.Unix(crypto.GetUnixTime(), 0),
+		entities: make(map[string]*openpgp.Entity),
+		edges:    make(map[string][]*certificationEdge),
+	}
+	for _, certificate := range certificates {
+		entity := certificate.GetEntity()
+		graph.entities[hex.EncodeToString(entity.PrimaryKey.Fingerprint)] = entity
+	}
+	for _, entity := range graph.entities {
+		if graph.isRevoked(entity) {
+			continue
+		}
+		for _, identity := range entity.Identities {
+			if identity.Revoked(graph.now) {
+				continue
+			}
+			for _, signature := range identity.Signatures {
+				if edge := graph.verifyCertification(entity, identity, signature); edge != nil {
+					issuer := edge.certification.IssuerFingerprint
+					graph.edges[issuer] = append(graph.edges[issuer], edge)
+				}
+			}
+		}
+	}
+	return graph
+}
+
+// verifyCertification returns the edge of a valid third-party certification
+// of a user ID, or nil.
+func (graph *certificationGraph) verifyCertification(entity *openpgp.Entity, identity *openpgp.Identity, signature *packet.Signature) *certificationEdge {
+	if !isCertification(signature) || signature.SigExpired(graph.now) || signature.CreationTime.After(graph.now) {
+		return nil
+	}
+	issuer := graph.findIssuer(signature)
+	if issuer == nil || issuer == entity || graph.isRevoked(issuer) {
+		return nil
+	}
+	if issuer.PrimaryKey.VerifyUserIdSignature(identity.Name, entity.PrimaryKey, signature) != nil {
+		return nil
+	}
+	for _, revocation := range identity.Signatures {
+		if revocation.SigType == packet.SigTypeCertificationRevocation &&
+			revocation.CheckKeyIdOrFingerprint(issuer.PrimaryKey) &&
+			!revocation.CreationTime.Before(signature.CreationTime) &&
+			issuer.PrimaryKey.VerifyUserIdSignature(identity.Name, entity.PrimaryKey, revocation) == nil {
+			return nil
+		}
+	}
+
+	edge := &certificationEdge{certification: &Certification{
+		IssuerFingerprint: hex.EncodeToString(issuer.PrimaryKey.Fingerprint),
+		Fingerprint:       hex.EncodeToString(entity.PrimaryKey.Fingerprint),
+		UserID:            identity.Name,
+		TrustDepth:        int(signature.TrustLevel),
+		TrustAmount:       int(signature.TrustAmount),
+	}}
+	if signature.TrustRegularExpression != nil {
+		expression, err := regexp.Compile(*signature.TrustRegularExpression)
+		if err != nil {
+			// The scope can't be enforced, the key is not an introducer
+			edge.certification.TrustDepth = 0
+		} else {
+			edge.certification.RegularExpression = *signature.TrustRegularExpression
+			edge.regexp = expression
+		}
+	}
+	return edge
+}
+
+// findChain returns the shortest chain of certifications from a certificate
+// with ultimate owner trust to the user ID of the certificate, or nil.
+func (graph *certificationGraph) findChain(store *Store, fingerprint, userID string) []*Certification {
+	// Breadth-first search from the trust anchors, revisiting a certificate
+	// only when reached with a greater depth
+	var queue []*chainNode
+	depths := make(map[string]int)
+	for candidate := range graph.entities {
+		if store.Get(candidate) == LevelUltimate {
+			queue = append(queue, &chainNode{fingerprint: candidate, depth: unlimitedDepth})
+			depths[candidate] = unlimitedDepth
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].fingerprint < queue[j].fingerprint
+	})
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.depth < 1 || node.depth < depths[node.fingerprint] {
+			continue
+		}
+		for _, edge := range graph.edges[node.fingerprint] {
+			if edge.regexp != nil && !edge.regexp.MatchString(userID) {
+				continue
+			}
+			chain := append(append([]*certificationEdge{}, node.chain...), edge)
+			certified := edge.certification.Fingerprint
+			if certified == fingerprint && edge.certification.UserID == userID {
+				return getCertifications(chain)
+			}
+
+			depth := edge.certification.TrustDepth
+			if edge.certification.TrustAmount < completeTrustAmount {
+				depth = 0
+			}
+			if depth > node.depth-1 {
+				depth = node.depth - 1
+			}
+			switch store.Get(certified) {
+			case LevelFull:
+				if depth < 1 {
+					depth = 1
+				}
+			case LevelNever:
+				depth = 0
+			}
+			if previous, ok := depths[certified]; depth < 1 || (ok && previous >= depth) {
+				continue
+			}
+			depths[certified] = depth
+			queue = append(queue, &chainNode{fingerprint: certified, depth: depth, chain: chain})
+		}
+	}
+	return nil
+}
+
+// findIssuer returns the certificate that issued the signature, or nil.
+func (graph *certificationGraph) findIssuer(signature *packet.Signature) *openpgp.Entity {
+	if signature.IssuerFingerprint != nil {
+		return graph.entities[hex.EncodeToString(signature.IssuerFingerprint)]
+	}
+	if signature.IssuerKeyId == nil {
+		return nil
+	}
+	for _, entity := range graph.entities {
+		if entity.PrimaryKey.KeyId == *signature.IssuerKeyId {
+			return entity
+		}
+	}
+	return nil
+}
+
+func (graph *certificationGraph) isRevoked(entity *openpgp.Entity) bool {
+	selfSignature, _ := entity.PrimarySelfSignature()
+	return selfSignature == nil ||
+		entity.Revoked(graph.now) ||
+		entity.PrimaryKey.KeyExpired(selfSignature, graph.now) ||
+		selfSignature.SigExpired(graph.now)
+}
+
+func isCertification(signature *packet.Signature) bool {
+	switch signature.SigType {
+	case packet.SigTypeGenericCert, packet.SigTypePersonaCert, packet.SigTypeCasualCert, packet.SigTypePositiveCert:
+		return true
+	default:
+		return false
+	}
+}
+
+func getCertifications(chain []*certificationEdge) []*Certification {
+	certifications := make([]*Certification, len(chain))
+	for i, edge := range chain {
+		certifications[i] = edge.certification
+	}
+	return certifications
+}
+
+// sortedIdentities returns the identities of the entity sorted by user ID.
+func sortedIdentities(entity *openpgp.Entity) []*openpgp.Identity {
+	identities := make([]*openpgp.Identity, 0, len(entity.Identities))
+	for _, identity := range entity.Identities {
+		identities = append(identities, identity)
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].Name < identities[j].Name
+	})
+	return identities
+}
@@ -0,0 +1,218 @@
+package ownertrust
+
+import (
+	gocrypto "crypto"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func generateValidityTestKey(t *testing.T, name string) *crypto.Key {
+	key, err := crypto.GenerateKey(name, name+"@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	return key
+}
+
+func getValidityTestUserID(key *crypto.Key) string {
+	for name := range key.GetEntity().Identities {
+		return name
+	}
+	return ""
+}
+
+// certify adds a certification of the user ID of target by issuer, a trust
+// signature with complete trust if depth is not 0.
+func certify(t *testing.T, issuer, target *crypto.Key, depth int, regularExpression string) {
+	certifyWithAmount(t, issuer, target, depth, 120, regularExpression)
+}
+
+// certifyWithAmount adds a certification of the user ID of target by issuer,
+// a trust signature with the given trust amount if depth is not 0.
+func certifyWithAmount(t *testing.T, issuer, target *crypto.Key, depth, amount int, regularExpression string) {
+	signature := &packet.Signature{
+		Version:      issuer.GetEntity().PrimaryKey.Version,
+		SigType:      packet.SigTypeGenericCert,
+		PubKeyAlgo:   issuer.GetEntity().PrimaryKey.PubKeyAlgo,
+		Hash:         gocrypto.SHA256,
+		CreationTime: time.Unix(crypto.GetUnixTime(), 0),
+		IssuerKeyId:  &issuer.GetEntity().PrimaryKey.KeyId,
+	}
+	if depth != 0 {
+		signature.TrustLevel = packet.TrustLevel(depth)
+		signature.TrustAmount = packet.TrustAmount(amount)
+	}
+	if regularExpression != "" {
+		signature.TrustRegularExpression = &regularExpression
+	}
+	userID := getValidityTestUserID(target)
+	identity := target.GetEntity().Identities[userID]
+	err := signature.SignUserId(userID, target.GetEntity().PrimaryKey, issuer.GetEntity().PrivateKey, nil)
+	if err != nil {
+		t.Fatal("Expected no error while certifying, got:", err)
+	}
+	identity.Signatures = append(identity.Signatures, signature)
+}
+
+func TestValidateTrustSignatures(t *testing.T) {
+	root := generateValidityTestKey(t, "root")
+	ca := generateValidityTestKey(t, "ca")
+	alice := generateValidityTestKey(t, "alice")
+	bob := generateValidityTestKey(t, "bob")
+	store := NewStore()
+	if err := store.Set(root.GetFingerprint(), LevelUltimate); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+
+	// The CA can only introduce the addresses of example.com
+	certify(t, root, ca, 1, `<[^>]+[@.]example\.com>$`)
+	certify(t, ca, alice, 0, "")
+	certifications := []*crypto.Key{root, ca, alice, bob}
+
+	validities := store.Validate(alice, certifications)
+	assert.Len(t, validities, 1)
+	assert.True(t, validities[0].Valid)
+	assert.Len(t, validities[0].Chain, 2)
+	assert.Exactly(t, root.GetFingerprint(), validities[0].Chain[0].IssuerFingerprint)
+	assert.Exactly(t, ca.GetFingerprint(), validities[0].Chain[0].Fingerprint)
+	assert.Exactly(t, 1, validities[0].Chain[0].TrustDepth)
+	assert.Exactly(t, 120, validities[0].Chain[0].TrustAmount)
+	assert.Exactly(t, `<[^>]+[@.]example\.com>$`, validities[0].Chain[0].RegularExpression)
+	assert.Exactly(t, ca.GetFingerprint(), validities[0].Chain[1].IssuerFingerprint)
+	assert.Exactly(t, alice.GetFingerprint(), validities[0].Chain[1].Fingerprint)
+	assert.Exactly(t, getValidityTestUserID(alice), validities[0].Chain[1].UserID)
+
+	validities = store.Validate(bob, certifications)
+	assert.False(t, validities[0].Valid)
+	assert.Empty(t, validities[0].Chain)
+
+	validities = store.Validate(root, certifications)
+	assert.True(t, validities[0].Valid)
+	assert.Empty(t, validities[0].Chain)
+}
+
+func TestValidateTrustSignatureScope(t *testing.T) {
+	root := generateValidityTestKey(t, "root")
+	ca := generateValidityTestKey(t, "ca")
+	alice := generateValidityTestKey(t, "alice")
+	store := NewStore()
+	if err := store.Set(root.GetFingerprint(), LevelUltimate); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+
+	certify(t, root, ca, 1, `@example\.org>$`)
+	certify(t, ca, alice, 0, "")
+	validities := store.Validate(alice, []*crypto.Key{root, ca})
+	assert.False(t, validities[0].Valid)
+}
+
+func TestValidateTrustSignatureAmount(t *testing.T) {
+	root := generateValidityTestKey(t, "root")
+	ca := generateValidityTestKey(t, "ca")
+	otherCA := generateValidityTestKey(t, "otherca")
+	alice := generateValidityTestKey(t, "alice")
+	store := NewStore()
+	if err := store.Set(root.GetFingerprint(), LevelUltimate); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+
+	// Partial trust doesn't designate an introducer, even from two issuers
+	certifyWithAmount(t, root, ca, 1, 60, "")
+	certifyWithAmount(t, root, otherCA, 1, 60, "")
+	certify(t, ca, alice, 0, "")
+	certify(t, otherCA, alice, 0, "")
+	certifications := []*crypto.Key{root, ca, otherCA}
+	validities := store.Validate(alice, certifications)
+	assert.False(t, validities[0].Valid)
+
+	// Neither does no trust
+	certifyWithAmount(t, root, ca, 1, 0, "")
+	validities = store.Validate(alice, certifications)
+	assert.False(t, validities[0].Valid)
+
+	// The trust signatures still certify the user IDs of the introducers
+	validities = store.Validate(ca, certifications)
+	assert.True(t, validities[0].Valid)
+
+	certifyWithAmount(t, root, ca, 1, 120, "")
+	validities = store.Validate(alice, certifications)
+	assert.True(t, validities[0].Valid)
+	assert.Len(t, validities[0].Chain, 2)
+}
+
+func TestValidateTrustSignatureDepth(t *testing.T) {
+	root := generateValidityTestKey(t, "root")
+	ca := generateValidityTestKey(t, "ca")
+	subCA := generateValidityTestKey(t, "subca")
+	alice := generateValidityTestKey(t, "alice")
+	store := NewStore()
+	if err := store.Set(root.GetFingerprint(), LevelUltimate); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+
+	// The CA can't designate introducers
+	certify(t, root, ca, 1, "")
+	certify(t, ca, subCA, 1, "")
+	certify(t, subCA, alice, 0, "")
+	certifications := []*crypto.Key{root, ca, subCA}
+	validities := store.Validate(subCA, certifications)
+	assert.True(t, validities[0].Valid)
+	validities = store.Validate(alice, certifications)
+	assert.False(t, validities[0].Valid)
+
+	// Unless the sub CA has full owner trust
+	if err := store.Set(subCA.GetFingerprint(), LevelFull); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+	validities = store.Validate(alice, certifications)
+	assert.True(t, validities[0].Valid)
+	assert.Len(t, validities[0].Chain, 3)
+
+	// Or is given more depth
+	if err := store.Set(subCA.GetFingerprint(), LevelUnknown); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+	certify(t, root, ca, 2, "")
+	validities = store.Validate(alice, certifications)
+	assert.True(t, validities[0].Valid)
+	assert.Exactly(t, 2, validities[0].Chain[0].TrustDepth)
+
+	// Which can't be used by introducers with owner trust never
+	if err := store.Set(ca.GetFingerprint(), LevelNever); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+	validities = store.Validate(alice, certifications)
+	assert.False(t, validities[0].Valid)
+	validities = store.Validate(ca, certifications)
+	assert.True(t, validities[0].Valid)
+	assert.Exactly(t, ca.GetFingerprint(), validities[0].Chain[0].Fingerprint)
+}
+
+func TestValidateRevokedCertification(t *testing.T) {
+	root := generateValidityTestKey(t, "root")
+	alice := generateValidityTestKey(t, "alice")
+	store := NewStore()
+	if err := store.Set(root.GetFingerprint(), LevelUltimate); err != nil {
+		t.Fatal("Expected no error while setting owner trust, got:", err)
+	}
+
+	certify(t, root, alice, 0, "")
+	validities := store.Validate(alice, []*crypto.Key{root})
+	assert.True(t, validities[0].Valid)
+
+	userID := getValidityTestUserID(alice)
+	revocation, err := root.RevokeCertification(alice, userID, false, "")
+	if err != nil {
+		t.Fatal("Expected no error while revoking certification, got:", err)
+	}
+	alice, err = alice.AddCertificationRevocation(userID, revocation)
+	if err != nil {
+		t.Fatal("Expected no error while adding revocation, got:", err)
+	}
+	validities = store.Validate(alice, []*crypto.Key{root})
+	assert.False(t, validities[0].Valid)
+}
@@ -0,0 +1,296 @@
+// Package passphrase generates diceware passphrases and estimates the
+// strength of passphrases, e.g. to warn users locking a key with a weak one.
+package passphrase
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultWordCount is the number of words of generated passphrases,
+// giving about 62 bits of entropy.
+const DefaultWordCount = 6
+
+// MinimumScore is the lowest score of a passphrase that isn't weak.
+const MinimumScore = 3
+
+// Warnings explaining a low strength score.
+const (
+	WarningTooShort       = "passphrase is too short"
+	WarningCommonPassword = "passphrase is a commonly used password"
+	WarningRepeat         = "passphrase contains repeated characters"
+	WarningSequence       = "passphrase contains a sequence of characters"
+	WarningKeyboard       = "passphrase contains a keyboard pattern"
+	WarningDate           = "passphrase contains a year"
+	WarningWords          = "passphrase contains too few words"
+)
+
+// Strength contains the estimated strength of a passphrase.
+type Strength struct {
+	// Score ranges from 0 (very weak) to 4 (very strong).
+	Score int
+	// Entropy is the estimated number of bits needed to guess the passphrase.
+	Entropy float64
+	// Warning explains a score below MinimumScore, and is empty otherwise.
+	Warning string
+}
+
+// IsWeak returns true if the score is below MinimumScore.
+func (strength *Strength) IsWeak() bool {
+	return strength.Score < MinimumScore
+}
+
+// Generate returns a passphrase of DefaultWordCount random words from the
+// EFF short wordlist, separated by dashes.
+func Generate() (string, error) {
+	return GenerateWithWordCount(DefaultWordCount, "-")
+}
+
+// GenerateWithWordCount returns a passphrase of count random words from the
+// EFF short wordlist, joined with separator.
+// Each word adds about 10.3 bits of entropy.
+func GenerateWithWordCount(count int, separator string) (string, error) {
+	if count <= 0 {
+		return "", errors.New("gopenpgp: the passphrase must contain at least one word")
+	}
+
+	wordCount := big.NewInt(int64(len(wordList)))
+	words := make([]string, count)
+	for i := range words {
+		index, err := rand.Int(rand.Reader, wordCount)
+		if err != nil {
+			return "", errors.Wrap(err, "gopenpgp: error in generating passphrase")
+		}
+		words[i] = wordList[index.Int64()]
+	}
+	return strings.Join(words, separator), nil
+}
+
+// EstimateStrength estimates how hard the passphrase is to guess, like zxcvbn:
+// the passphrase is split in the patterns that are the cheapest to guess, e.g.
+// dictionary words, repeated characters, sequences, keyboard patterns and
+// years, and the remaining characters are guessed by brute force.
+func EstimateStrength(passphrase string) *Strength {
+	entropy, warning := estimateEntropy(passphrase)
+
+	strength := &Strength{Entropy: entropy, Score: getScore(entropy)}
+	if strength.IsWeak() {
+		strength.Warning = warning
+		if strength.Warning == "" {
+			strength.Warning = WarningTooShort
+		}
+	}
+	return strength
+}
+
+// ----- INTERNAL FUNCTIONS -----
+
+// patternMatch is a substring of the passphrase matching a pattern.
+type patternMatch struct {
+	start, end int
+	entropy    float64
+	warning    string
+}
+
+// commonPasswords lists some of the most frequently used passwords.
+var commonPasswords = []string{
+	"password", "123456", "12345678", "qwerty", "abc123", "letmein", "monkey",
+	"dragon", "iloveyou", "admin", "welcome", "login", "princess", "sunshine",
+	"football", "baseball", "master", "shadow", "superman", "trustno1",
+	"passw0rd", "starwars", "whatever", "freedom", "secret", "hello", "charlie",
+	"michael", "jordan", "hunter", "ninja", "mustang", "access", "batman",
+}
+
+// keyboardRows lists the rows of a QWERTY keyboard.
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+var dictionary map[string]float64
+
+func init() {
+	dictionary = make(map[string]float64, len(wordList)+len(commonPasswords))
+	wordEntropy := math.Log2(float64(len(wordList)))
+	for _, word := range wordList {
+		dictionary[word] = wordEntropy
+	}
+	commonEntropy := math.Log2(float64(len(commonPasswords)))
+	for _, password := range commonPasswords {
+		dictionary[password] = commonEntropy
+	}
+}
+
+// estimateEntropy returns the entropy of the cheapest decomposition of the
+// passphrase in patterns and brute forced characters, and the warning of the
+// last pattern of that decomposition.
+func estimateEntropy(passphrase string) (float64, string) {
+	runes := []rune(passphrase)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	bruteforceEntropy := math.Log2(float64(getCardinality(runes)))
+
+	var matches []patternMatch
+	matches = append(matches, matchDictionary(runes, lower)...)
+	matches = append(matches, matchRepeats(lower)...)
+	matches = append(matches, matchSequences(lower)...)
+	matches = append(matches, matchKeyboard(lower)...)
+	matches = append(matches, matchYears(lower)...)
+
+	// entropies[i] is the lowest entropy of the first i characters
+	entropies := make([]float64, len(runes)+1)
+	warnings := make([]string, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		entropies[i] = entropies[i-1] + bruteforceEntropy
+		warnings[i] = warnings[i-1]
+		for _, match := range matches {
+			if match.end == i && entropies[match.start]+match.entropy < entropies[i] {
+				entropies[i] = entropies[match.start] + match.entropy
+				warnings[i] = match.warning
+			}
+		}
+	}
+	return entropies[len(runes)], warnings[len(runes)]
+}
+
+func matchDictionary(runes, lower []rune) (matches []patternMatch) {
+	for start := range lower {
+		for end := start + 3; end <= len(lower); end++ {
+			entropy, ok := dictionary[string(lower[start:end])]
+			if !ok {
+				continue
+			}
+			warning := WarningWords
+			if entropy < math.Log2(float64(len(wordList))) {
+				warning = WarningCommonPassword
+			}
+			if string(runes[start:end]) != string(lower[start:end]) {
+				// Capitalization variants
+				entropy++
+			}
+			matches = append(matches, patternMatch{start, end, entropy, warning})
+		}
+	}
+	return
+}
+
+func matchRepeats(lower []rune) (matches []patternMatch) {
+	for start := 0; start < len(lower); {
+		end := start + 1
+		for end < len(lower) && lower[end] == lower[start] {
+			end++
+		}
+		if end-start >= 3 {
+			entropy := math.Log2(float64(getCardinality(lower[start:start+1]) * (end - start)))
+			matches = append(matches, patternMatch{start, end, entropy, WarningRepeat})
+		}
+		start = end
+	}
+	return
+}
+
+func matchSequences(lower []rune) (matches []patternMatch) {
+	for start := 0; start < len(lower)-1; {
+		delta := lower[start+1] - lower[start]
+		end := start + 2
+		for end < len(lower) && lower[end]-lower[end-1] == delta {
+			end++
+		}
+		if (delta == 1 || delta == -1) && end-start >= 3 {
+			entropy := math.Log2(float64(getCardinality(lower[start:start+1]) * (end - start) * 2))
+			matches = append(matches, patternMatch{start, end, entropy, WarningSequence})
+		}
+		start = end - 1
+	}
+	return
+}
+
+func matchKeyboard(lower []rune) (matches []patternMatch) {
+	for start := range lower {
+		for end := start + 4; end <= len(lower); end++ {
+			substring := string(lower[start:end])
+			for _, row := range keyboardRows {
+				if strings.Contains(row, substring) {
+					entropy := math.Log2(float64(len(keyboardRows) * len(row) * (end - start)))
+					matches = append(matches, patternMatch{start, end, entropy, WarningKeyboard})
+				}
+			}
+		}
+	}
+	return
+}
+
+func matchYears(lower []rune) (matches []patternMatch) {
+	for start := 0; start+4 <= len(lower); start++ {
+		year := string(lower[start : start+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) && isDigits(year) {
+			matches = append(matches, patternMatch{start, start + 4, math.Log2(200), WarningDate})
+		}
+	}
+	return
+}
+
+// getCardinality returns the size of the smallest set of character classes
+// containing all the characters.
+func getCardinality(runes []rune) int {
+	var lower, upper, digits, symbols, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digits = true
+		case r < 128:
+			symbols = true
+		default:
+			other = true
+		}
+	}
+
+	cardinality := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digits, 10}, {symbols, 33}, {other, 100}} {
+		if class.present {
+			cardinality += class.size
+		}
+	}
+	if cardinality == 0 {
+		return 1
+	}
+	return cardinality
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// getScore converts the entropy to a score from 0 to 4, with the same
+// thresholds in number of guesses as zxcvbn.
+func getScore(entropy float64) int {
+	guesses := math.Pow(2, entropy)
+	switch {
+	case guesses < 1e3:
+		return 0
+	case guesses < 1e6:
+		return 1
+	case guesses < 1e8:
+		return 2
+	case guesses < 1e10:
+		return 3
+	default:
+		return 4
+	}
+}
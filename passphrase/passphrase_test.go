@@ -0,0 +1,54 @@
+package passphrase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	passphrase, err := Generate()
+	if err != nil {
+		t.Fatal("Expected no error while generating passphrase, got:", err)
+	}
+
+	words := strings.Split(passphrase, "-")
+	assert.Len(t, words, DefaultWordCount)
+	for _, word := range words {
+		assert.Contains(t, wordList, word)
+	}
+	assert.False(t, EstimateStrength(passphrase).IsWeak())
+
+	passphrase, err = GenerateWithWordCount(3, " ")
+	if err != nil {
+		t.Fatal("Expected no error while generating passphrase, got:", err)
+	}
+	assert.Len(t, strings.Split(passphrase, " "), 3)
+
+	_, err = GenerateWithWordCount(0, " ")
+	assert.Error(t, err)
+}
+
+func TestEstimateStrength(t *testing.T) {
+	weak := map[string]string{
+		"":             WarningTooShort,
+		"password":     WarningCommonPassword,
+		"Password1":    WarningCommonPassword,
+		"aaaaaaaaaaaa": WarningRepeat,
+		"abcdefghijkl": WarningSequence,
+		"qwertyuiop":   WarningKeyboard,
+		"x1990":        WarningDate,
+		"abyss-acid":   WarningWords,
+	}
+	for passphrase, warning := range weak {
+		strength := EstimateStrength(passphrase)
+		assert.True(t, strength.IsWeak(), passphrase)
+		assert.Exactly(t, warning, strength.Warning, passphrase)
+	}
+
+	strength := EstimateStrength("T7#kq9!Lz2@wVx")
+	assert.False(t, strength.IsWeak())
+	assert.Exactly(t, 4, strength.Score)
+	assert.Empty(t, strength.Warning)
+}